@@ -0,0 +1,4 @@
+// Package riftv1 holds the rift.v1 gRPC API that rift serve --grpc
+// answers. rift.pb.go and rift_grpc.pb.go are generated from rift.proto
+// with make proto.
+package riftv1
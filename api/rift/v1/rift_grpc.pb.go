@@ -0,0 +1,287 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: rift/v1/rift.proto
+
+package riftv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Rift_ListClusters_FullMethodName = "/rift.v1.Rift/ListClusters"
+	Rift_ListRoles_FullMethodName    = "/rift.v1.Rift/ListRoles"
+	Rift_Sync_FullMethodName         = "/rift.v1.Rift/Sync"
+	Rift_Use_FullMethodName          = "/rift.v1.Rift/Use"
+	Rift_Graph_FullMethodName        = "/rift.v1.Rift/Graph"
+)
+
+// RiftClient is the client API for Rift service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Rift serves rift's discovered inventory to programs (rift serve --grpc).
+type RiftClient interface {
+	// ListClusters returns the clusters in state that match the request.
+	ListClusters(ctx context.Context, in *ListClustersRequest, opts ...grpc.CallOption) (*ListClustersResponse, error)
+	// ListRoles returns the SSO roles in state that match the filter.
+	ListRoles(ctx context.Context, in *ListRolesRequest, opts ...grpc.CallOption) (*ListRolesResponse, error)
+	// Sync runs a sync, as POST /sync does; ABORTED while one is running.
+	Sync(ctx context.Context, in *SyncRequest, opts ...grpc.CallOption) (*SyncResponse, error)
+	// Use makes a context kubectl's current context, as rift use does.
+	Use(ctx context.Context, in *UseRequest, opts ...grpc.CallOption) (*UseResponse, error)
+	// Graph returns the topology rift graph --format json prints.
+	Graph(ctx context.Context, in *GraphRequest, opts ...grpc.CallOption) (*GraphResponse, error)
+}
+
+type riftClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewRiftClient(cc grpc.ClientConnInterface) RiftClient {
+	return &riftClient{cc}
+}
+
+func (c *riftClient) ListClusters(ctx context.Context, in *ListClustersRequest, opts ...grpc.CallOption) (*ListClustersResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListClustersResponse)
+	err := c.cc.Invoke(ctx, Rift_ListClusters_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *riftClient) ListRoles(ctx context.Context, in *ListRolesRequest, opts ...grpc.CallOption) (*ListRolesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListRolesResponse)
+	err := c.cc.Invoke(ctx, Rift_ListRoles_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *riftClient) Sync(ctx context.Context, in *SyncRequest, opts ...grpc.CallOption) (*SyncResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SyncResponse)
+	err := c.cc.Invoke(ctx, Rift_Sync_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *riftClient) Use(ctx context.Context, in *UseRequest, opts ...grpc.CallOption) (*UseResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UseResponse)
+	err := c.cc.Invoke(ctx, Rift_Use_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *riftClient) Graph(ctx context.Context, in *GraphRequest, opts ...grpc.CallOption) (*GraphResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GraphResponse)
+	err := c.cc.Invoke(ctx, Rift_Graph_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RiftServer is the server API for Rift service.
+// All implementations must embed UnimplementedRiftServer
+// for forward compatibility.
+//
+// Rift serves rift's discovered inventory to programs (rift serve --grpc).
+type RiftServer interface {
+	// ListClusters returns the clusters in state that match the request.
+	ListClusters(context.Context, *ListClustersRequest) (*ListClustersResponse, error)
+	// ListRoles returns the SSO roles in state that match the filter.
+	ListRoles(context.Context, *ListRolesRequest) (*ListRolesResponse, error)
+	// Sync runs a sync, as POST /sync does; ABORTED while one is running.
+	Sync(context.Context, *SyncRequest) (*SyncResponse, error)
+	// Use makes a context kubectl's current context, as rift use does.
+	Use(context.Context, *UseRequest) (*UseResponse, error)
+	// Graph returns the topology rift graph --format json prints.
+	Graph(context.Context, *GraphRequest) (*GraphResponse, error)
+	mustEmbedUnimplementedRiftServer()
+}
+
+// UnimplementedRiftServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedRiftServer struct{}
+
+func (UnimplementedRiftServer) ListClusters(context.Context, *ListClustersRequest) (*ListClustersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListClusters not implemented")
+}
+func (UnimplementedRiftServer) ListRoles(context.Context, *ListRolesRequest) (*ListRolesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListRoles not implemented")
+}
+func (UnimplementedRiftServer) Sync(context.Context, *SyncRequest) (*SyncResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Sync not implemented")
+}
+func (UnimplementedRiftServer) Use(context.Context, *UseRequest) (*UseResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Use not implemented")
+}
+func (UnimplementedRiftServer) Graph(context.Context, *GraphRequest) (*GraphResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Graph not implemented")
+}
+func (UnimplementedRiftServer) mustEmbedUnimplementedRiftServer() {}
+func (UnimplementedRiftServer) testEmbeddedByValue()              {}
+
+// UnsafeRiftServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to RiftServer will
+// result in compilation errors.
+type UnsafeRiftServer interface {
+	mustEmbedUnimplementedRiftServer()
+}
+
+func RegisterRiftServer(s grpc.ServiceRegistrar, srv RiftServer) {
+	// If the following call pancis, it indicates UnimplementedRiftServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Rift_ServiceDesc, srv)
+}
+
+func _Rift_ListClusters_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListClustersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RiftServer).ListClusters(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Rift_ListClusters_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RiftServer).ListClusters(ctx, req.(*ListClustersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Rift_ListRoles_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRolesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RiftServer).ListRoles(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Rift_ListRoles_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RiftServer).ListRoles(ctx, req.(*ListRolesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Rift_Sync_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SyncRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RiftServer).Sync(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Rift_Sync_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RiftServer).Sync(ctx, req.(*SyncRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Rift_Use_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UseRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RiftServer).Use(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Rift_Use_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RiftServer).Use(ctx, req.(*UseRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Rift_Graph_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GraphRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RiftServer).Graph(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Rift_Graph_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RiftServer).Graph(ctx, req.(*GraphRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Rift_ServiceDesc is the grpc.ServiceDesc for Rift service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Rift_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "rift.v1.Rift",
+	HandlerType: (*RiftServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListClusters",
+			Handler:    _Rift_ListClusters_Handler,
+		},
+		{
+			MethodName: "ListRoles",
+			Handler:    _Rift_ListRoles_Handler,
+		},
+		{
+			MethodName: "Sync",
+			Handler:    _Rift_Sync_Handler,
+		},
+		{
+			MethodName: "Use",
+			Handler:    _Rift_Use_Handler,
+		},
+		{
+			MethodName: "Graph",
+			Handler:    _Rift_Graph_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "rift/v1/rift.proto",
+}
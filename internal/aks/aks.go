@@ -0,0 +1,246 @@
+// Package aks discovers Azure Kubernetes Service clusters through Azure
+// Resource Manager, authenticated with azidentity's DefaultAzureCredential.
+package aks
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerservice/armcontainerservice/v6"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armsubscriptions"
+	"github.com/phenixrizen/rift/internal/discovery"
+	"github.com/phenixrizen/rift/internal/state"
+	"golang.org/x/sync/errgroup"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// Discovery calls recorded in Failure.Op.
+const (
+	OpListClusters       = "aks_list_clusters"
+	OpClusterCredentials = "aks_cluster_credentials"
+)
+
+// credentialConcurrency bounds concurrent ListClusterUserCredentials calls
+// per subscription.
+const credentialConcurrency = 8
+
+// Discover lists the AKS clusters of every subscription. Credentials come
+// from DefaultAzureCredential: environment variables, workload or managed
+// identity, or the Azure CLI login (`az login`). Subscriptions or clusters
+// that cannot be read (including every subscription when no credential is
+// found) are returned as failures.
+func Discover(ctx context.Context, subscriptions []string, logger *slog.Logger) ([]discovery.ClusterAccess, []discovery.Failure) {
+	if len(subscriptions) == 0 {
+		return nil, nil
+	}
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+	cred, err := azidentity.NewDefaultAzureCredential(nil)
+	if err != nil {
+		failures := make([]discovery.Failure, 0, len(subscriptions))
+		for _, sub := range subscriptions {
+			failures = append(failures, failure(OpListClusters, sub, sub, "", err))
+		}
+		return nil, failures
+	}
+	subs, err := armsubscriptions.NewClient(cred, nil)
+	if err != nil {
+		return nil, []discovery.Failure{failure(OpListClusters, "", "", "", err)}
+	}
+
+	var (
+		mu       sync.Mutex
+		clusters []discovery.ClusterAccess
+		failures []discovery.Failure
+	)
+	var g errgroup.Group
+	g.SetLimit(4)
+	for _, sub := range subscriptions {
+		g.Go(func() error {
+			found, failed := discoverSubscription(ctx, cred, subs, sub, logger)
+			mu.Lock()
+			defer mu.Unlock()
+			clusters = append(clusters, found...)
+			failures = append(failures, failed...)
+			return nil
+		})
+	}
+	_ = g.Wait()
+	sort.Slice(clusters, func(i, j int) bool {
+		left := clusters[i].AccountName + "|" + clusters[i].Region + "|" + clusters[i].ClusterName
+		right := clusters[j].AccountName + "|" + clusters[j].Region + "|" + clusters[j].ClusterName
+		return left < right
+	})
+	sort.Slice(failures, func(i, j int) bool { return failures[i].String() < failures[j].String() })
+	return clusters, failures
+}
+
+func discoverSubscription(ctx context.Context, cred azcore.TokenCredential, subs *armsubscriptions.Client, sub string, logger *slog.Logger) ([]discovery.ClusterAccess, []discovery.Failure) {
+	// The display name drives env inference and naming like an AWS account
+	// name; fall back to the ID when it cannot be read.
+	name := sub
+	if resp, err := subs.Get(ctx, sub, nil); err == nil && resp.DisplayName != nil {
+		name = *resp.DisplayName
+	} else if err != nil {
+		logger.Warn("get azure subscription failed", "subscription", sub, "error", err)
+	}
+
+	client, err := armcontainerservice.NewManagedClustersClient(sub, cred, nil)
+	if err != nil {
+		return nil, []discovery.Failure{failure(OpListClusters, sub, name, "", err)}
+	}
+	var managed []*armcontainerservice.ManagedCluster
+	pager := client.NewListPager(nil)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			logger.Warn("list aks clusters failed", "subscription", sub, "error", err)
+			return nil, []discovery.Failure{failure(OpListClusters, sub, name, "", err)}
+		}
+		managed = append(managed, page.Value...)
+	}
+	logger.Debug("listed aks clusters", "subscription", sub, "clusters", len(managed))
+
+	records := make([]discovery.ClusterAccess, len(managed))
+	ok := make([]bool, len(managed))
+	var (
+		mu       sync.Mutex
+		failures []discovery.Failure
+	)
+	var g errgroup.Group
+	g.SetLimit(credentialConcurrency)
+	for i, mc := range managed {
+		g.Go(func() error {
+			record := clusterAccess(sub, name, mc)
+			server, ca, err := userCredentials(ctx, client, mc)
+			if err != nil {
+				logger.Warn("get aks cluster credentials failed", "subscription", sub, "cluster", record.ClusterName, "error", err)
+				mu.Lock()
+				failures = append(failures, failure(OpClusterCredentials, sub, name, record.ClusterName, err))
+				mu.Unlock()
+				return nil
+			}
+			record.ClusterEndpoint = server
+			record.ClusterCertificateBase64 = ca
+			records[i], ok[i] = record, true
+			return nil
+		})
+	}
+	_ = g.Wait()
+	out := make([]discovery.ClusterAccess, 0, len(records))
+	for i, record := range records {
+		if ok[i] {
+			out = append(out, record)
+		}
+	}
+	return out, failures
+}
+
+// userCredentials reads the API server and CA from the cluster's user
+// kubeconfig; the ARM cluster resource does not carry the CA.
+func userCredentials(ctx context.Context, client *armcontainerservice.ManagedClustersClient, mc *armcontainerservice.ManagedCluster) (string, string, error) {
+	id, err := arm.ParseResourceID(deref(mc.ID))
+	if err != nil {
+		return "", "", err
+	}
+	resp, err := client.ListClusterUserCredentials(ctx, id.ResourceGroupName, id.Name, nil)
+	if err != nil {
+		return "", "", err
+	}
+	for _, kc := range resp.Kubeconfigs {
+		if kc != nil && len(kc.Value) > 0 {
+			return parseKubeconfig(kc.Value)
+		}
+	}
+	return "", "", errors.New("no kubeconfig returned")
+}
+
+// parseKubeconfig returns the server and base64 CA of the current context's
+// cluster (or the only cluster) in an AKS user kubeconfig.
+func parseKubeconfig(data []byte) (string, string, error) {
+	cfg, err := clientcmd.Load(data)
+	if err != nil {
+		return "", "", fmt.Errorf("parse kubeconfig: %w", err)
+	}
+	name := ""
+	if ctx := cfg.Contexts[cfg.CurrentContext]; ctx != nil {
+		name = ctx.Cluster
+	}
+	cluster := cfg.Clusters[name]
+	if cluster == nil && len(cfg.Clusters) == 1 {
+		for _, c := range cfg.Clusters {
+			cluster = c
+		}
+	}
+	if cluster == nil || cluster.Server == "" {
+		return "", "", errors.New("kubeconfig has no cluster server")
+	}
+	return cluster.Server, base64.StdEncoding.EncodeToString(cluster.CertificateAuthorityData), nil
+}
+
+// clusterAccess maps an AKS cluster to the shape EKS discovery produces: the
+// subscription stands in for the account and the location for the region.
+// The endpoint and CA are filled in from the user kubeconfig.
+func clusterAccess(sub, subName string, mc *armcontainerservice.ManagedCluster) discovery.ClusterAccess {
+	record := discovery.ClusterAccess{
+		AccountID:   sub,
+		AccountName: subName,
+		Region:      deref(mc.Location),
+		ClusterName: deref(mc.Name),
+		ClusterID:   deref(mc.ID),
+		Platform:    state.PlatformAKS,
+	}
+	if p := mc.Properties; p != nil {
+		record.KubernetesVersion = deref(p.CurrentKubernetesVersion)
+		record.Status = deref(p.ProvisioningState)
+		if p.PowerState != nil && p.PowerState.Code != nil {
+			record.Status = string(*p.PowerState.Code)
+		}
+	}
+	if mc.SystemData != nil && mc.SystemData.CreatedAt != nil {
+		record.CreatedAt = mc.SystemData.CreatedAt.UTC()
+	}
+	if len(mc.Tags) > 0 {
+		record.AWSTags = make(map[string]string, len(mc.Tags))
+		for k, v := range mc.Tags {
+			record.AWSTags[k] = deref(v)
+		}
+	}
+	return record
+}
+
+func failure(op, sub, subName, cluster string, err error) discovery.Failure {
+	f := discovery.Failure{
+		Op:          op,
+		AccountID:   sub,
+		AccountName: subName,
+		Cluster:     cluster,
+		Err:         err.Error(),
+	}
+	// ResponseError.Error() dumps the whole response; keep the status and
+	// error code.
+	var respErr *azcore.ResponseError
+	if errors.As(err, &respErr) {
+		f.Throttled = respErr.StatusCode == http.StatusTooManyRequests
+		f.Err = fmt.Sprintf("%s (%d)", respErr.ErrorCode, respErr.StatusCode)
+	}
+	return f
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
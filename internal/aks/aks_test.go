@@ -0,0 +1,65 @@
+package aks
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerservice/armcontainerservice/v6"
+	"github.com/phenixrizen/rift/internal/state"
+)
+
+func TestParseKubeconfig(t *testing.T) {
+	data := []byte(`apiVersion: v1
+kind: Config
+clusters:
+- name: payments
+  cluster:
+    server: https://payments-dns-1a2b.hcp.eastus.azmk8s.io:443
+    certificate-authority-data: Q0E=
+contexts:
+- name: payments
+  context:
+    cluster: payments
+    user: clusterUser_rg_payments
+current-context: payments
+users:
+- name: clusterUser_rg_payments
+  user: {}
+`)
+	server, ca, err := parseKubeconfig(data)
+	if err != nil {
+		t.Fatalf("parseKubeconfig() error = %v", err)
+	}
+	if server != "https://payments-dns-1a2b.hcp.eastus.azmk8s.io:443" || ca != "Q0E=" {
+		t.Fatalf("parseKubeconfig() = %q, %q", server, ca)
+	}
+}
+
+func TestClusterAccess(t *testing.T) {
+	str := func(s string) *string { return &s }
+	created := time.Date(2024, 5, 2, 8, 0, 0, 0, time.UTC)
+	stopped := armcontainerservice.CodeStopped
+	mc := &armcontainerservice.ManagedCluster{
+		ID:       str("/subscriptions/sub-1/resourceGroups/rg/providers/Microsoft.ContainerService/managedClusters/payments"),
+		Name:     str("payments"),
+		Location: str("eastus"),
+		Tags:     map[string]*string{"team": str("payments")},
+		Properties: &armcontainerservice.ManagedClusterProperties{
+			CurrentKubernetesVersion: str("1.30.3"),
+			ProvisioningState:        str("Succeeded"),
+			PowerState:               &armcontainerservice.PowerState{Code: &stopped},
+		},
+		SystemData: &armcontainerservice.SystemData{CreatedAt: &created},
+	}
+
+	got := clusterAccess("sub-1", "payments-prod", mc)
+	if got.AccountID != "sub-1" || got.AccountName != "payments-prod" || got.Region != "eastus" || got.ClusterName != "payments" {
+		t.Fatalf("clusterAccess() = %+v", got)
+	}
+	if got.Platform != state.PlatformAKS || got.KubernetesVersion != "1.30.3" || got.Status != "Stopped" {
+		t.Fatalf("platform/version/status = %q/%q/%q", got.Platform, got.KubernetesVersion, got.Status)
+	}
+	if !got.CreatedAt.Equal(created) || got.AWSTags["team"] != "payments" {
+		t.Fatalf("metadata = %v %v", got.CreatedAt, got.AWSTags)
+	}
+}
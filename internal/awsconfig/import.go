@@ -0,0 +1,157 @@
+package awsconfig
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type ImportedSSO struct {
+	Source      string
+	SSOStartURL string
+	SSORegion   string
+	Regions     []string
+}
+
+type ssoCandidate struct {
+	source   string
+	startURL string
+	region   string
+	regions  map[string]struct{}
+}
+
+// ImportSSO derives SSO settings from an existing AWS config, reading either
+// sso-session sections or legacy sso_* keys on profiles. When sessionName is
+// set only that sso-session (or profile) is considered.
+func ImportSSO(path, sessionName string) (ImportedSSO, error) {
+	file, err := loadINI(path)
+	if err != nil {
+		return ImportedSSO{}, err
+	}
+	sessionName = strings.TrimSpace(sessionName)
+
+	sessions := map[string]*ssoCandidate{}
+	profiles := map[string]*ssoCandidate{}
+	byStartURL := map[string]*ssoCandidate{}
+	order := make([]*ssoCandidate, 0)
+
+	for _, sec := range file.Sections() {
+		name := sec.Name()
+		if !strings.HasPrefix(name, "sso-session ") {
+			continue
+		}
+		session := strings.TrimSpace(strings.TrimPrefix(name, "sso-session "))
+		startURL := strings.TrimSpace(sec.Key("sso_start_url").String())
+		if startURL == "" {
+			continue
+		}
+		c := &ssoCandidate{
+			source:   "sso-session " + session,
+			startURL: startURL,
+			region:   strings.ToLower(strings.TrimSpace(sec.Key("sso_region").String())),
+			regions:  map[string]struct{}{},
+		}
+		sessions[session] = c
+		if _, ok := byStartURL[startURL]; !ok {
+			byStartURL[startURL] = c
+		}
+		order = append(order, c)
+	}
+
+	for _, sec := range file.Sections() {
+		name := sec.Name()
+		profile := ""
+		switch {
+		case name == "default":
+			profile = "default"
+		case strings.HasPrefix(name, "profile "):
+			profile = strings.TrimSpace(strings.TrimPrefix(name, "profile "))
+		default:
+			continue
+		}
+		if strings.HasPrefix(profile, "rift-") {
+			continue
+		}
+		region := strings.ToLower(strings.TrimSpace(sec.Key("region").String()))
+
+		var c *ssoCandidate
+		if session := strings.TrimSpace(sec.Key("sso_session").String()); session != "" {
+			c = sessions[session]
+		} else if startURL := strings.TrimSpace(sec.Key("sso_start_url").String()); startURL != "" {
+			c = byStartURL[startURL]
+			if c == nil {
+				c = &ssoCandidate{
+					source:   "profile " + profile,
+					startURL: startURL,
+					region:   strings.ToLower(strings.TrimSpace(sec.Key("sso_region").String())),
+					regions:  map[string]struct{}{},
+				}
+				byStartURL[startURL] = c
+				order = append(order, c)
+			}
+			profiles[profile] = c
+		}
+		if c == nil {
+			continue
+		}
+		if region != "" {
+			c.regions[region] = struct{}{}
+		}
+	}
+
+	var picked *ssoCandidate
+	if sessionName != "" {
+		picked = sessions[sessionName]
+		if picked == nil {
+			picked = profiles[sessionName]
+		}
+		if picked == nil {
+			return ImportedSSO{}, fmt.Errorf("no sso-session or sso profile named %q in %s", sessionName, path)
+		}
+	} else {
+		unique := uniqueCandidates(order)
+		switch len(unique) {
+		case 0:
+			return ImportedSSO{}, fmt.Errorf("no SSO settings found in %s", path)
+		case 1:
+			picked = unique[0]
+		default:
+			sources := make([]string, 0, len(unique))
+			for _, c := range unique {
+				sources = append(sources, fmt.Sprintf("%s (%s)", c.source, c.startURL))
+			}
+			return ImportedSSO{}, fmt.Errorf("multiple SSO configurations found; choose one with --sso-session: %s", strings.Join(sources, ", "))
+		}
+	}
+	if picked.region == "" {
+		return ImportedSSO{}, fmt.Errorf("%s has no sso_region", picked.source)
+	}
+
+	regions := make([]string, 0, len(picked.regions))
+	for region := range picked.regions {
+		regions = append(regions, region)
+	}
+	sort.Strings(regions)
+	return ImportedSSO{
+		Source:      picked.source,
+		SSOStartURL: picked.startURL,
+		SSORegion:   picked.region,
+		Regions:     regions,
+	}, nil
+}
+
+func uniqueCandidates(candidates []*ssoCandidate) []*ssoCandidate {
+	seen := map[string]*ssoCandidate{}
+	out := make([]*ssoCandidate, 0, len(candidates))
+	for _, c := range candidates {
+		if prev, ok := seen[c.startURL]; ok {
+			for region := range c.regions {
+				prev.regions[region] = struct{}{}
+			}
+			continue
+		}
+		seen[c.startURL] = c
+		out = append(out, c)
+	}
+	return out
+}
@@ -0,0 +1,93 @@
+package awsconfig
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeAWSConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write aws config: %v", err)
+	}
+	return path
+}
+
+func TestImportSSOFromSession(t *testing.T) {
+	path := writeAWSConfig(t, `
+[sso-session corp]
+sso_start_url = https://corp.awsapps.com/start
+sso_region = US-EAST-2
+
+[profile dev]
+sso_session = corp
+sso_account_id = 111111111111
+sso_role_name = Admin
+region = eu-west-1
+
+[profile prod]
+sso_session = corp
+region = us-east-1
+
+[profile rift-prod-acme-admin]
+sso_session = corp
+region = ap-south-1
+`)
+
+	got, err := ImportSSO(path, "")
+	if err != nil {
+		t.Fatalf("ImportSSO returned error: %v", err)
+	}
+	if got.SSOStartURL != "https://corp.awsapps.com/start" || got.SSORegion != "us-east-2" {
+		t.Fatalf("ImportSSO=%+v", got)
+	}
+	if strings.Join(got.Regions, ",") != "eu-west-1,us-east-1" {
+		t.Fatalf("Regions=%v want [eu-west-1 us-east-1]", got.Regions)
+	}
+}
+
+func TestImportSSOFromLegacyProfile(t *testing.T) {
+	path := writeAWSConfig(t, `
+[default]
+region = us-west-2
+
+[profile legacy]
+sso_start_url = https://legacy.awsapps.com/start
+sso_region = us-west-2
+region = us-west-2
+`)
+
+	got, err := ImportSSO(path, "")
+	if err != nil {
+		t.Fatalf("ImportSSO returned error: %v", err)
+	}
+	if got.SSOStartURL != "https://legacy.awsapps.com/start" || got.Source != "profile legacy" {
+		t.Fatalf("ImportSSO=%+v", got)
+	}
+}
+
+func TestImportSSOAmbiguous(t *testing.T) {
+	path := writeAWSConfig(t, `
+[sso-session a]
+sso_start_url = https://a.awsapps.com/start
+sso_region = us-east-1
+
+[sso-session b]
+sso_start_url = https://b.awsapps.com/start
+sso_region = us-east-1
+`)
+
+	if _, err := ImportSSO(path, ""); err == nil {
+		t.Fatalf("expected ambiguity error")
+	}
+	got, err := ImportSSO(path, "b")
+	if err != nil {
+		t.Fatalf("ImportSSO(b) returned error: %v", err)
+	}
+	if got.SSOStartURL != "https://b.awsapps.com/start" {
+		t.Fatalf("SSOStartURL=%q want b", got.SSOStartURL)
+	}
+}
@@ -1,13 +1,15 @@
 package awsconfig
 
 import (
+	"bytes"
 	"fmt"
 	"os"
-	"path/filepath"
 	"sort"
 	"strings"
 
+	"github.com/aymanbagabas/go-udiff"
 	"github.com/phenixrizen/rift/internal/config"
+	"github.com/phenixrizen/rift/internal/fileutil"
 	"github.com/phenixrizen/rift/internal/state"
 	"gopkg.in/ini.v1"
 )
@@ -16,15 +18,27 @@ type SyncResult struct {
 	Added   int
 	Updated int
 	Removed int
+	// Diff is a unified diff of the file against what sync would write,
+	// filled on dry runs only.
+	Diff string
 }
 
 const (
 	riftProfilePrefix = "profile rift-"
-	ssoSessionSection = "sso-session rift"
-	legacyAuthProfile = "profile rift-auth"
+	ssoSessionPrefix  = "sso-session "
+	// Additional sso_sessions are written as [sso-session rift-<name>].
+	extraSessionPrefix = "sso-session rift-"
+	legacyAuthProfile  = "profile rift-auth"
 )
 
 func EnsureSession(path string, cfg config.Config, dryRun bool) (bool, error) {
+	if !dryRun {
+		unlock, err := fileutil.Lock(path)
+		if err != nil {
+			return false, err
+		}
+		defer unlock()
+	}
 	file, err := loadINI(path)
 	if err != nil {
 		return false, err
@@ -33,16 +47,20 @@ func EnsureSession(path string, cfg config.Config, dryRun bool) (bool, error) {
 	if !changed || dryRun {
 		return changed, nil
 	}
-	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
-		return false, err
-	}
-	if err := file.SaveTo(path); err != nil {
+	if err := save(file, path); err != nil {
 		return false, err
 	}
 	return true, nil
 }
 
 func EnsureLegacyAuthProfile(path string, cfg config.Config, dryRun bool) (bool, error) {
+	if !dryRun {
+		unlock, err := fileutil.Lock(path)
+		if err != nil {
+			return false, err
+		}
+		defer unlock()
+	}
 	file, err := loadINI(path)
 	if err != nil {
 		return false, err
@@ -62,16 +80,23 @@ func EnsureLegacyAuthProfile(path string, cfg config.Config, dryRun bool) (bool,
 	if !changed || dryRun {
 		return changed, nil
 	}
-	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
-		return false, err
-	}
-	if err := file.SaveTo(path); err != nil {
+	if err := save(file, path); err != nil {
 		return false, err
 	}
 	return true, nil
 }
 
-func Sync(path string, cfg config.Config, st state.State, dryRun bool) (SyncResult, error) {
+// Sync writes a profile per role in st and removes managed profiles that are
+// gone: rift-* profiles and those listed in prev, the state from the
+// previous sync (templated names need not carry the prefix).
+func Sync(path string, cfg config.Config, st, prev state.State, dryRun bool) (SyncResult, error) {
+	if !dryRun {
+		unlock, err := fileutil.Lock(path)
+		if err != nil {
+			return SyncResult{}, err
+		}
+		defer unlock()
+	}
 	file, err := loadINI(path)
 	if err != nil {
 		return SyncResult{}, err
@@ -87,11 +112,16 @@ func Sync(path string, cfg config.Config, st state.State, dryRun bool) (SyncResu
 		desired[role.AWSProfile] = role
 	}
 
+	owned := prev.AWSProfiles()
 	existingRift := make([]string, 0)
 	for _, section := range file.Sections() {
 		name := section.Name()
-		if strings.HasPrefix(name, riftProfilePrefix) {
-			existingRift = append(existingRift, strings.TrimPrefix(name, "profile "))
+		if !strings.HasPrefix(name, "profile ") {
+			continue
+		}
+		profile := strings.TrimPrefix(name, "profile ")
+		if _, wasOurs := owned[profile]; wasOurs || strings.HasPrefix(name, riftProfilePrefix) {
+			existingRift = append(existingRift, profile)
 		}
 	}
 
@@ -108,11 +138,6 @@ func Sync(path string, cfg config.Config, st state.State, dryRun bool) (SyncResu
 	}
 	sort.Strings(sorted)
 
-	defaultRegion := ""
-	if len(cfg.Regions) > 0 {
-		defaultRegion = cfg.Regions[0]
-	}
-
 	for _, profile := range sorted {
 		role := desired[profile]
 		secName := "profile " + profile
@@ -127,11 +152,28 @@ func Sync(path string, cfg config.Config, st state.State, dryRun bool) (SyncResu
 			result.Added++
 		}
 		changed := false
-		changed = setKey(sec, "sso_session", "rift") || changed
-		changed = setKey(sec, "sso_account_id", role.AccountID) || changed
-		changed = setKey(sec, "sso_role_name", role.RoleName) || changed
-		if defaultRegion != "" {
-			changed = setKey(sec, "region", defaultRegion) || changed
+		if role.AssumeRoleARN != "" {
+			changed = deleteKeys(sec, "sso_session", "sso_account_id", "sso_role_name") || changed
+			changed = setKey(sec, "role_arn", role.AssumeRoleARN) || changed
+			changed = setKey(sec, "source_profile", role.SourceProfile) || changed
+			changed = setKey(sec, "role_session_name", "rift") || changed
+			if role.ExternalID != "" {
+				changed = setKey(sec, "external_id", role.ExternalID) || changed
+			} else {
+				changed = deleteKeys(sec, "external_id") || changed
+			}
+		} else {
+			changed = deleteKeys(sec, "role_arn", "source_profile", "role_session_name", "external_id") || changed
+			session := role.SSOSession
+			if session == "" {
+				session = config.DefaultSSOSession
+			}
+			changed = setKey(sec, "sso_session", session) || changed
+			changed = setKey(sec, "sso_account_id", role.AccountID) || changed
+			changed = setKey(sec, "sso_role_name", role.RoleName) || changed
+		}
+		if regions := cfg.RegionsFor(role.Env, role.AccountName, role.AccountID); len(regions) > 0 {
+			changed = setKey(sec, "region", regions[0]) || changed
 		}
 		changed = setKey(sec, "output", "json") || changed
 		if changed && !created {
@@ -140,30 +182,69 @@ func Sync(path string, cfg config.Config, st state.State, dryRun bool) (SyncResu
 	}
 
 	if dryRun {
-		return result, nil
-	}
-
-	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		diff, err := diffINI(path, file)
+		result.Diff = diff
 		return result, err
 	}
-	if err := file.SaveTo(path); err != nil {
+
+	if err := save(file, path); err != nil {
 		return result, err
 	}
 	return result, nil
 }
 
-func ensureSSOSession(file *ini.File, cfg config.Config) bool {
-	sec, err := file.GetSection(ssoSessionSection)
+// diffINI diffs path as it is against file. Both sides are rendered the
+// same way, so formatting rift would normalize anyway does not show up.
+func diffINI(path string, file *ini.File) (string, error) {
+	before, err := loadINI(path)
 	if err != nil {
-		sec, _ = file.NewSection(ssoSessionSection)
+		return "", err
+	}
+	var oldText, newText bytes.Buffer
+	if _, err := before.WriteTo(&oldText); err != nil {
+		return "", err
+	}
+	if _, err := file.WriteTo(&newText); err != nil {
+		return "", err
 	}
+	return udiff.Unified(path, path, oldText.String(), newText.String()), nil
+}
+
+// ensureSSOSession writes an [sso-session] section per configured session
+// and drops rift-<name> sessions that are no longer configured.
+func ensureSSOSession(file *ini.File, cfg config.Config) bool {
 	changed := false
-	changed = setKey(sec, "sso_start_url", cfg.SSOStartURL) || changed
-	changed = setKey(sec, "sso_region", cfg.SSORegion) || changed
-	changed = setKey(sec, "sso_registration_scopes", "sso:account:access") || changed
+	wanted := map[string]struct{}{}
+	for _, session := range cfg.Sessions() {
+		name := ssoSessionPrefix + session.ID()
+		wanted[name] = struct{}{}
+		sec, err := file.GetSection(name)
+		if err != nil {
+			sec, _ = file.NewSection(name)
+		}
+		changed = setKey(sec, "sso_start_url", session.StartURL) || changed
+		changed = setKey(sec, "sso_region", session.Region) || changed
+		changed = setKey(sec, "sso_registration_scopes", "sso:account:access") || changed
+	}
+	for _, section := range file.Sections() {
+		name := section.Name()
+		if _, ok := wanted[name]; !ok && strings.HasPrefix(name, extraSessionPrefix) {
+			file.DeleteSection(name)
+			changed = true
+		}
+	}
 	return changed
 }
 
+// save writes file to path atomically; callers hold the path's lock.
+func save(file *ini.File, path string) error {
+	var buf bytes.Buffer
+	if _, err := file.WriteTo(&buf); err != nil {
+		return err
+	}
+	return fileutil.WriteAtomic(path, buf.Bytes(), 0o600)
+}
+
 func loadINI(path string) (*ini.File, error) {
 	if _, err := os.Stat(path); err != nil {
 		if os.IsNotExist(err) {
@@ -182,3 +263,14 @@ func setKey(section *ini.Section, key, value string) bool {
 	section.Key(key).SetValue(value)
 	return true
 }
+
+func deleteKeys(section *ini.Section, keys ...string) bool {
+	changed := false
+	for _, key := range keys {
+		if section.HasKey(key) {
+			section.DeleteKey(key)
+			changed = true
+		}
+	}
+	return changed
+}
@@ -0,0 +1,157 @@
+package awsconfig
+
+import (
+	"testing"
+
+	"github.com/phenixrizen/rift/internal/config"
+	"github.com/phenixrizen/rift/internal/state"
+	"gopkg.in/ini.v1"
+)
+
+func TestSyncWritesSessionPerOrganization(t *testing.T) {
+	path := writeAWSConfig(t, `
+[sso-session rift-retired]
+sso_start_url = https://retired.awsapps.com/start
+sso_region = us-east-1
+
+[sso-session mine]
+sso_start_url = https://mine.awsapps.com/start
+sso_region = us-east-1
+`)
+	cfg := config.Default()
+	cfg.SSOStartURL = "https://acme.awsapps.com/start"
+	cfg.SSORegion = "us-east-1"
+	cfg.SSOSessions = []config.SSOSession{{Name: "corp2", StartURL: "https://corp2.awsapps.com/start", Region: "eu-west-1"}}
+	st := state.State{Roles: []state.RoleRecord{
+		{Env: "prod", AccountID: "111111111111", RoleName: "Admin", AWSProfile: "rift-prod-acme-admin"},
+		{Env: "prod", AccountID: "222222222222", RoleName: "Admin", AWSProfile: "rift-prod-corp2-admin", SSOSession: "rift-corp2"},
+	}}
+
+	if _, err := Sync(path, cfg, st, state.State{}, false); err != nil {
+		t.Fatalf("Sync returned error: %v", err)
+	}
+	file, err := ini.Load(path)
+	if err != nil {
+		t.Fatalf("load aws config: %v", err)
+	}
+	if got := file.Section("sso-session rift-corp2").Key("sso_start_url").String(); got != "https://corp2.awsapps.com/start" {
+		t.Fatalf("rift-corp2 sso_start_url=%q", got)
+	}
+	if file.HasSection("sso-session rift-retired") {
+		t.Fatalf("unconfigured rift-retired session was kept")
+	}
+	if !file.HasSection("sso-session mine") {
+		t.Fatalf("user-owned sso-session was removed")
+	}
+	for profile, want := range map[string]string{"rift-prod-acme-admin": "rift", "rift-prod-corp2-admin": "rift-corp2"} {
+		if got := file.Section("profile " + profile).Key("sso_session").String(); got != want {
+			t.Fatalf("%s sso_session=%q want %q", profile, got, want)
+		}
+	}
+}
+
+func TestPrune(t *testing.T) {
+	path := writeAWSConfig(t, `
+[profile mine]
+region = us-east-1
+
+[sso-session rift]
+sso_start_url = https://acme.awsapps.com/start
+
+[profile rift-prod-acme-admin]
+sso_session = rift
+
+[profile rift-prod-acme-gone]
+sso_session = rift
+
+[profile team.Admin]
+sso_session = rift
+`)
+	st := state.State{Roles: []state.RoleRecord{
+		{AWSProfile: "rift-prod-acme-admin"},
+		{AWSProfile: "team.Admin"},
+	}}
+
+	removed, err := Prune(path, st, false, false)
+	if err != nil {
+		t.Fatalf("Prune returned error: %v", err)
+	}
+	if len(removed) != 1 || removed[0] != "profile rift-prod-acme-gone" {
+		t.Fatalf("removed=%v want only the stale rift profile", removed)
+	}
+
+	removed, err = Prune(path, st, true, false)
+	if err != nil {
+		t.Fatalf("Prune --all returned error: %v", err)
+	}
+	if len(removed) != 3 {
+		t.Fatalf("removed=%v want both state profiles and the sso-session", removed)
+	}
+	file, err := ini.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := file.SectionStrings(); len(names) != 2 || names[1] != "profile mine" {
+		t.Fatalf("sections=%v want only the user's profile", names)
+	}
+}
+
+func TestSyncWritesChainedRoles(t *testing.T) {
+	path := writeAWSConfig(t, `
+[profile rift-prod-shared-deployer]
+sso_session = rift
+sso_account_id = 333333333333
+sso_role_name = Deployer
+`)
+	cfg := config.Default()
+	cfg.SSOStartURL = "https://acme.awsapps.com/start"
+	cfg.SSORegion = "us-east-1"
+	st := state.State{Roles: []state.RoleRecord{
+		{Env: "prod", AccountID: "111111111111", RoleName: "Admin", AWSProfile: "rift-prod-acme-admin"},
+		{Env: "prod", AccountID: "222222222222", RoleName: "Admin", AWSProfile: "rift-prod-tools-admin", AssumeRoleARN: "arn:aws:iam::222222222222:role/Admin", SourceProfile: "rift-prod-acme-admin", ExternalID: "rift-ext"},
+		{Env: "prod", AccountID: "333333333333", RoleName: "Deployer", AWSProfile: "rift-prod-shared-deployer", AssumeRoleARN: "arn:aws:iam::333333333333:role/Deployer", SourceProfile: "rift-prod-acme-admin"},
+	}}
+	if _, err := Sync(path, cfg, st, state.State{}, false); err != nil {
+		t.Fatalf("Sync returned error: %v", err)
+	}
+	file, err := ini.Load(path)
+	if err != nil {
+		t.Fatalf("load aws config: %v", err)
+	}
+
+	tests := []struct {
+		profile string
+		want    map[string]string
+		absent  []string
+	}{
+		{
+			profile: "rift-prod-acme-admin",
+			want:    map[string]string{"sso_session": "rift", "sso_account_id": "111111111111", "sso_role_name": "Admin"},
+			absent:  []string{"role_arn", "source_profile", "external_id"},
+		},
+		{
+			profile: "rift-prod-tools-admin",
+			want:    map[string]string{"role_arn": "arn:aws:iam::222222222222:role/Admin", "source_profile": "rift-prod-acme-admin", "role_session_name": "rift", "external_id": "rift-ext"},
+			absent:  []string{"sso_session", "sso_account_id", "sso_role_name"},
+		},
+		{
+			// A profile that was an SSO profile before loses its SSO keys.
+			profile: "rift-prod-shared-deployer",
+			want:    map[string]string{"role_arn": "arn:aws:iam::333333333333:role/Deployer", "source_profile": "rift-prod-acme-admin"},
+			absent:  []string{"sso_session", "sso_account_id", "sso_role_name", "external_id"},
+		},
+	}
+	for _, tt := range tests {
+		sec := file.Section("profile " + tt.profile)
+		for key, want := range tt.want {
+			if got := sec.Key(key).String(); got != want {
+				t.Fatalf("%s %s=%q want %q", tt.profile, key, got, want)
+			}
+		}
+		for _, key := range tt.absent {
+			if sec.HasKey(key) {
+				t.Fatalf("%s still has %s", tt.profile, key)
+			}
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package awsconfig
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/phenixrizen/rift/internal/config"
+	"github.com/phenixrizen/rift/internal/fileutil"
+	"github.com/phenixrizen/rift/internal/state"
+)
+
+// Prune removes rift-* profiles that are not in st and returns the removed
+// section names. With all it removes every profile rift manages (rift-* and
+// those in st) and its [sso-session rift...] sections as well.
+func Prune(path string, st state.State, all, dryRun bool) ([]string, error) {
+	if !dryRun {
+		unlock, err := fileutil.Lock(path)
+		if err != nil {
+			return nil, err
+		}
+		defer unlock()
+	}
+	file, err := loadINI(path)
+	if err != nil {
+		return nil, err
+	}
+
+	current := st.AWSProfiles()
+	removed := make([]string, 0)
+	for _, section := range file.Sections() {
+		name := section.Name()
+		switch {
+		case strings.HasPrefix(name, "profile "):
+			profile := strings.TrimPrefix(name, "profile ")
+			_, inState := current[profile]
+			if all && inState || !inState && strings.HasPrefix(name, riftProfilePrefix) {
+				removed = append(removed, name)
+			}
+		case all && (name == ssoSessionPrefix+config.DefaultSSOSession || strings.HasPrefix(name, extraSessionPrefix)):
+			removed = append(removed, name)
+		}
+	}
+	sort.Strings(removed)
+	if dryRun || len(removed) == 0 {
+		return removed, nil
+	}
+	for _, name := range removed {
+		file.DeleteSection(name)
+	}
+	return removed, save(file, path)
+}
@@ -0,0 +1,292 @@
+// Package backup keeps copies of the files rift is about to rewrite
+// (~/.aws/config, kubeconfigs, state.json) so a bad sync can be rolled back
+// with `rift restore`.
+package backup
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/phenixrizen/rift/internal/fileutil"
+)
+
+const (
+	dirName       = "backups"
+	idLayout      = "20060102T150405.000Z"
+	manifestName  = "manifest.json"
+	DefaultRetain = 20
+)
+
+// File is one backed-up path. Dir marks a directory copied as a whole (the
+// split kubeconfig layout); restoring it also removes the files created in
+// it since. Existed is false for a path the backup found missing, which
+// restore removes again.
+type File struct {
+	Path    string `json:"path"`
+	Dir     bool   `json:"dir,omitempty"`
+	Existed bool   `json:"existed"`
+}
+
+// Backup describes a set of files copied before one command wrote them.
+type Backup struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	// Command is the rift command that was about to write (sync, migrate,
+	// restore).
+	Command string `json:"command"`
+	Files   []File `json:"files"`
+}
+
+// DirFor returns the backups directory that sits next to a state file.
+func DirFor(statePath string) string {
+	return filepath.Join(filepath.Dir(statePath), dirName)
+}
+
+// Create copies targets (Path and Dir set by the caller) into a new backup
+// under dir and prunes the oldest backups beyond retain (none when zero).
+func Create(dir, command string, targets []File, now time.Time, retain int) (Backup, error) {
+	b := Backup{ID: now.UTC().Format(idLayout), CreatedAt: now.UTC(), Command: command}
+	root := filepath.Join(dir, b.ID)
+	if err := os.MkdirAll(root, 0o700); err != nil {
+		return b, err
+	}
+	for i, target := range targets {
+		f := File{Path: target.Path, Dir: target.Dir}
+		dst := filepath.Join(root, strconv.Itoa(i))
+		var err error
+		if f.Dir {
+			f.Existed, err = copyDir(f.Path, dst)
+		} else {
+			f.Existed, err = copyFile(f.Path, dst)
+		}
+		if err != nil {
+			_ = os.RemoveAll(root)
+			return b, fmt.Errorf("back up %s: %w", f.Path, err)
+		}
+		b.Files = append(b.Files, f)
+	}
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return b, err
+	}
+	if err := os.WriteFile(filepath.Join(root, manifestName), append(data, '\n'), 0o600); err != nil {
+		_ = os.RemoveAll(root)
+		return b, err
+	}
+	if retain > 0 {
+		if err := Prune(dir, retain); err != nil {
+			return b, err
+		}
+	}
+	return b, nil
+}
+
+// Discard deletes a backup, e.g. when the command it was taken for changed
+// nothing.
+func Discard(dir string, b Backup) error {
+	return os.RemoveAll(filepath.Join(dir, b.ID))
+}
+
+// List returns backup IDs oldest first.
+func List(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	ids := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(dir, entry.Name(), manifestName)); err != nil {
+			continue
+		}
+		ids = append(ids, entry.Name())
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+func Load(dir, id string) (Backup, error) {
+	var b Backup
+	data, err := os.ReadFile(filepath.Join(dir, id, manifestName))
+	if err != nil {
+		return b, err
+	}
+	if err := json.Unmarshal(data, &b); err != nil {
+		return b, fmt.Errorf("parse backup %s: %w", id, err)
+	}
+	return b, nil
+}
+
+// Resolve maps "latest", an exact ID, or a unique ID prefix to an ID.
+func Resolve(dir, ref string) (string, error) {
+	ids, err := List(dir)
+	if err != nil {
+		return "", err
+	}
+	if len(ids) == 0 {
+		return "", fmt.Errorf("no backups recorded yet")
+	}
+	if ref == "" || ref == "latest" {
+		return ids[len(ids)-1], nil
+	}
+	match := ""
+	for _, id := range ids {
+		if id == ref {
+			return id, nil
+		}
+		if strings.HasPrefix(id, ref) {
+			if match != "" {
+				return "", fmt.Errorf("backup %q is ambiguous", ref)
+			}
+			match = id
+		}
+	}
+	if match == "" {
+		return "", fmt.Errorf("backup %q not found", ref)
+	}
+	return match, nil
+}
+
+// Restore puts every file of b back as it was when b was taken.
+func Restore(dir string, b Backup) error {
+	root := filepath.Join(dir, b.ID)
+	for i, f := range b.Files {
+		src := filepath.Join(root, strconv.Itoa(i))
+		var err error
+		switch {
+		case !f.Existed:
+			err = os.RemoveAll(f.Path)
+		case f.Dir:
+			err = restoreDir(src, f.Path)
+		default:
+			err = restoreFile(src, f.Path)
+		}
+		if err != nil {
+			return fmt.Errorf("restore %s: %w", f.Path, err)
+		}
+	}
+	return nil
+}
+
+// copyFile copies src to dst with its mode, reporting false when src does
+// not exist.
+func copyFile(src, dst string) (bool, error) {
+	in, err := os.Open(src)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return true, err
+	}
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return true, err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return true, err
+	}
+	return true, out.Close()
+}
+
+// copyDir copies the regular files directly in src to dst.
+func copyDir(src, dst string) (bool, error) {
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	if err := os.MkdirAll(dst, 0o700); err != nil {
+		return true, err
+	}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		if _, err := copyFile(filepath.Join(src, entry.Name()), filepath.Join(dst, entry.Name())); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+// restoreFile puts src back at dst under dst's lock, as rift's other
+// writers do.
+func restoreFile(src, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	return fileutil.WriteLocked(dst, data, info.Mode().Perm())
+}
+
+// restoreDir makes the regular files in dst match the copies in src.
+func restoreDir(src, dst string) error {
+	saved, err := os.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	keep := make(map[string]struct{}, len(saved))
+	for _, entry := range saved {
+		keep[entry.Name()] = struct{}{}
+	}
+	current, err := os.ReadDir(dst)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	for _, entry := range current {
+		if _, ok := keep[entry.Name()]; !ok && entry.Type().IsRegular() {
+			if err := os.Remove(filepath.Join(dst, entry.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	if err := os.MkdirAll(dst, 0o755); err != nil {
+		return err
+	}
+	for _, entry := range saved {
+		if err := restoreFile(filepath.Join(src, entry.Name()), filepath.Join(dst, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Prune deletes the oldest backups beyond retain.
+func Prune(dir string, retain int) error {
+	ids, err := List(dir)
+	if err != nil {
+		return err
+	}
+	for len(ids) > retain {
+		if err := os.RemoveAll(filepath.Join(dir, ids[0])); err != nil {
+			return err
+		}
+		ids = ids[1:]
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCreateAndRestore(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "backups")
+	config := filepath.Join(root, "config")
+	split := filepath.Join(root, "rift")
+	created := filepath.Join(root, "created")
+	write := func(path, data string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(config, "before")
+	write(filepath.Join(split, "a.yaml"), "a")
+
+	b, err := Create(dir, "sync", []File{{Path: config}, {Path: split, Dir: true}, {Path: created}}, time.Now(), 0)
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	if !b.Files[0].Existed || !b.Files[1].Existed || b.Files[2].Existed {
+		t.Fatalf("files=%+v want config and dir existing, created missing", b.Files)
+	}
+
+	write(config, "after")
+	write(filepath.Join(split, "b.yaml"), "b")
+	if err := os.Remove(filepath.Join(split, "a.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	write(created, "new")
+
+	id, err := Resolve(dir, "latest")
+	if err != nil || id != b.ID {
+		t.Fatalf("Resolve(latest)=%q,%v want %q", id, err, b.ID)
+	}
+	loaded, err := Load(dir, id)
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if err := Restore(dir, loaded); err != nil {
+		t.Fatalf("Restore returned error: %v", err)
+	}
+	if data, _ := os.ReadFile(config); string(data) != "before" {
+		t.Fatalf("config=%q want before", data)
+	}
+	if _, err := os.Stat(filepath.Join(split, "a.yaml")); err != nil {
+		t.Fatalf("split file not restored: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(split, "b.yaml")); !os.IsNotExist(err) {
+		t.Fatalf("split file created after the backup was kept: %v", err)
+	}
+	if _, err := os.Stat(created); !os.IsNotExist(err) {
+		t.Fatalf("file missing at backup time was kept: %v", err)
+	}
+}
+
+func TestCreatePrunesOldest(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		if _, err := Create(dir, "sync", nil, start.Add(time.Duration(i)*time.Second), 2); err != nil {
+			t.Fatalf("Create returned error: %v", err)
+		}
+	}
+	ids, err := List(dir)
+	if err != nil {
+		t.Fatalf("List returned error: %v", err)
+	}
+	if len(ids) != 2 || ids[0] != "20260102T150406.000Z" {
+		t.Fatalf("ids=%v want the two newest", ids)
+	}
+}
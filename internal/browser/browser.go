@@ -0,0 +1,21 @@
+// Package browser opens URLs with the system's default browser.
+package browser
+
+import (
+	"os/exec"
+	"runtime"
+)
+
+// Open starts the platform's URL handler on url without waiting for it.
+func Open(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	return cmd.Start()
+}
@@ -0,0 +1,97 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// namespaceAccess is one row of rift access.
+type namespaceAccess struct {
+	Context   string `json:"context"`
+	Namespace string `json:"namespace"`
+	Allowed   bool   `json:"allowed"`
+}
+
+func newAccessCmd(app *App) *cobra.Command {
+	var envs []string
+	var denied bool
+	cmd := &cobra.Command{
+		Use:   "access [filter]",
+		Short: "Report which recorded namespaces the role can get pods in",
+		Long: `Lists the namespaces of each context with whether its role may get pods
+there, as recorded by the last sync with discover_access: true (one
+SelfSubjectAccessReview per namespace). Nothing is looked up live.
+
+The filter matches contexts like rift verify: a case-insensitive substring
+of the context, cluster, or account. --denied lists only the namespaces
+the role cannot read.`,
+		Example: `  rift access
+  rift access payments --denied
+  rift access --env prod -o json`,
+		Args:              cobra.MaximumNArgs(1),
+		ValidArgsFunction: completeContexts(app),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			st, err := app.loadState()
+			if err != nil {
+				if errors.Is(err, os.ErrNotExist) {
+					return errStateNotFound
+				}
+				return err
+			}
+			filter := ""
+			if len(args) == 1 {
+				filter = args[0]
+			}
+			rows := []namespaceAccess{}
+			unchecked := 0
+			for _, rec := range filterForVerify(st.Clusters, filter, envs) {
+				if len(rec.NamespaceAccess) == 0 {
+					if len(rec.Namespaces) > 0 {
+						unchecked++
+					}
+					continue
+				}
+				for _, ns := range rec.Namespaces {
+					allowed, checked := rec.AccessTo(ns)
+					if !checked || (denied && allowed) {
+						continue
+					}
+					rows = append(rows, namespaceAccess{Context: rec.KubeContext, Namespace: ns, Allowed: allowed})
+				}
+			}
+			if unchecked > 0 {
+				fmt.Fprintf(cmd.ErrOrStderr(), "%d contexts have no access results; sync with discover_access: true\n", unchecked)
+			}
+
+			out := cmd.OutOrStdout()
+			if app.Output == "json" {
+				enc := json.NewEncoder(out)
+				enc.SetIndent("", "  ")
+				return enc.Encode(rows)
+			}
+			if len(rows) == 0 {
+				println(out, "No namespaces match the given filters.")
+				return nil
+			}
+			tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(tw, "CONTEXT\tNAMESPACE\tACCESS")
+			for _, r := range rows {
+				access := "yes"
+				if !r.Allowed {
+					access = "no"
+				}
+				fmt.Fprintf(tw, "%s\t%s\t%s\n", r.Context, r.Namespace, access)
+			}
+			return tw.Flush()
+		},
+	}
+	cmd.Flags().StringSliceVar(&envs, "env", nil, "Only report contexts in these envs (repeatable)")
+	cmd.Flags().BoolVar(&denied, "denied", false, "Only list namespaces the role cannot get pods in")
+	_ = cmd.RegisterFlagCompletionFunc("env", completeEnvs(app))
+	return cmd
+}
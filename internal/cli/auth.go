@@ -2,32 +2,180 @@ package cli
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"os"
 	"os/exec"
+	"os/signal"
 	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
 
 	"github.com/phenixrizen/rift/internal/awsconfig"
+	"github.com/phenixrizen/rift/internal/config"
+	"github.com/phenixrizen/rift/internal/discovery"
+	"github.com/phenixrizen/rift/internal/rifterr"
+	"github.com/phenixrizen/rift/internal/ssoauth"
 	"github.com/spf13/cobra"
+	"golang.org/x/sync/errgroup"
 )
 
 func newAuthCmd(app *App) *cobra.Command {
-	var noBrowser bool
+	var opts authOptions
+	var keepAlive bool
 
 	cmd := &cobra.Command{
 		Use:   "auth",
 		Short: "Run AWS IAM Identity Center (SSO) login",
+		Long: `Signs in to AWS IAM Identity Center with the OIDC device authorization
+flow, natively (no AWS CLI needed), and writes the token to the AWS SSO cache
+shared with "aws sso login --sso-session rift". Use --aws-cli to delegate to
+the AWS CLI instead. With sso_sessions configured, every session is signed in
+in turn unless --session picks one.
+
+With --keep-alive, rift stays in the foreground after login (skipping it when
+a valid token is cached) and renews the token with its refresh token shortly
+before it expires, until interrupted.`,
 		RunE: func(cmd *cobra.Command, _ []string) error {
-			return runAuthFlow(app, cmd.InOrStdin(), cmd.OutOrStdout(), cmd.ErrOrStderr(), noBrowser)
+			if keepAlive {
+				return runAuthKeepAlive(cmd.Context(), app, cmd, opts)
+			}
+			return runAuthFlow(cmd.Context(), app, cmd.InOrStdin(), cmd.OutOrStdout(), cmd.ErrOrStderr(), opts)
 		},
 	}
 
-	cmd.Flags().BoolVar(&noBrowser, "no-browser", false, "Use AWS device auth flow without opening a browser")
+	cmd.AddCommand(newAuthStatusCmd(app))
+	cmd.Flags().BoolVar(&opts.NoBrowser, "no-browser", false, "Print the verification URL and code instead of opening a browser")
+	cmd.Flags().BoolVar(&opts.AWSCLI, "aws-cli", false, "Delegate to `aws sso login` instead of the built-in flow")
+	cmd.Flags().StringVar(&opts.Session, "session", "", "Only sign in to this SSO session (name from sso_sessions, or rift)")
+	cmd.Flags().BoolVar(&keepAlive, "keep-alive", false, "Stay running and refresh the SSO token before it expires")
 	return cmd
 }
 
-func runAuthFlow(app *App, stdin io.Reader, stdout, stderr io.Writer, noBrowser bool) error {
+type authStatus struct {
+	Session   string     `json:"session"`
+	LoggedIn  bool       `json:"logged_in"`
+	StartURL  string     `json:"start_url"`
+	Region    string     `json:"region"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	ExpiresIn string     `json:"expires_in,omitempty"`
+	CachedAt  *time.Time `json:"cached_at,omitempty"`
+	// OtherSessions reports sso_sessions beyond the primary one.
+	OtherSessions []authStatus `json:"other_sessions,omitempty"`
+}
+
+func newAuthStatusCmd(app *App) *cobra.Command {
+	return &cobra.Command{
+		Use:   "status",
+		Short: "Show whether the cached SSO token is valid and when it expires",
+		Long:  "Inspects the AWS SSO token cache for the configured start URL. Exits 1 when not logged in.",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			cfg, err := app.loadConfig()
+			if err != nil {
+				return err
+			}
+			now := time.Now().UTC()
+			statuses := make([]authStatus, 0, 1+len(cfg.SSOSessions))
+			loggedIn := true
+			for _, session := range cfg.Sessions() {
+				status, err := sessionAuthStatus(session, now)
+				if err != nil {
+					return err
+				}
+				loggedIn = loggedIn && status.LoggedIn
+				statuses = append(statuses, status)
+			}
+
+			out := cmd.OutOrStdout()
+			if app.Output == "json" {
+				status := statuses[0]
+				status.OtherSessions = statuses[1:]
+				enc := json.NewEncoder(out)
+				enc.SetIndent("", "  ")
+				if err := enc.Encode(status); err != nil {
+					return err
+				}
+			} else {
+				printAuthStatus(out, statuses, now)
+			}
+			if !loggedIn {
+				err := rifterr.New(rifterr.CodeAuthRequired, "aws sso token missing or expired", "run: rift auth")
+				return &ExitError{Code: 1, Err: err, Silent: true}
+			}
+			return nil
+		},
+	}
+}
+
+func sessionAuthStatus(session config.SSOSession, now time.Time) (authStatus, error) {
+	status := authStatus{Session: session.ID(), StartURL: session.StartURL, Region: session.Region}
+	tok, err := discovery.LastSSOToken(session)
+	switch {
+	case errors.Is(err, discovery.ErrSSONotLoggedIn):
+	case err != nil:
+		return status, err
+	default:
+		status.ExpiresAt = &tok.ExpiresAt
+		if !tok.CachedAt.IsZero() {
+			status.CachedAt = &tok.CachedAt
+		}
+		status.LoggedIn = tok.ExpiresAt.After(now.Add(time.Minute))
+		if status.LoggedIn {
+			status.ExpiresIn = tok.ExpiresAt.Sub(now).Round(time.Second).String()
+		}
+	}
+	return status, nil
+}
+
+func printAuthStatus(out io.Writer, statuses []authStatus, now time.Time) {
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	allLoggedIn := true
+	for i, s := range statuses {
+		if i > 0 {
+			fmt.Fprintln(tw)
+		}
+		if len(statuses) > 1 {
+			fmt.Fprintf(tw, "Session:\t%s\n", s.Session)
+		}
+		loggedIn := "no"
+		if s.LoggedIn {
+			loggedIn = "yes"
+		}
+		allLoggedIn = allLoggedIn && s.LoggedIn
+		fmt.Fprintf(tw, "Logged in:\t%s\n", loggedIn)
+		fmt.Fprintf(tw, "Start URL:\t%s\n", s.StartURL)
+		fmt.Fprintf(tw, "Region:\t%s\n", s.Region)
+		switch {
+		case s.ExpiresAt == nil:
+			fmt.Fprintf(tw, "Token:\tnone cached\n")
+		case s.LoggedIn:
+			fmt.Fprintf(tw, "Expires:\t%s (in %s)\n", s.ExpiresAt.Local().Format(time.RFC3339), s.ExpiresIn)
+		default:
+			fmt.Fprintf(tw, "Expired:\t%s (%s ago)\n", s.ExpiresAt.Local().Format(time.RFC3339), now.Sub(*s.ExpiresAt).Round(time.Minute))
+		}
+		if s.CachedAt != nil {
+			fmt.Fprintf(tw, "Issued:\t%s\n", s.CachedAt.Local().Format(time.RFC3339))
+		}
+	}
+	_ = tw.Flush()
+	if !allLoggedIn {
+		println(out, "", "Run: rift auth")
+	}
+}
+
+type authOptions struct {
+	NoBrowser bool
+	AWSCLI    bool
+	// Session limits login to one SSO session; empty means all of them.
+	Session string
+	// OnPrompt receives the device code; the default prints it to stdout.
+	OnPrompt func(ssoauth.Device)
+}
+
+func runAuthFlow(ctx context.Context, app *App, stdin io.Reader, stdout, stderr io.Writer, opts authOptions) error {
 	cfg, err := app.loadConfig()
 	if err != nil {
 		return err
@@ -37,15 +185,128 @@ func runAuthFlow(app *App, stdin io.Reader, stdout, stderr io.Writer, noBrowser
 	if err != nil {
 		return err
 	}
+	sessions, err := authSessions(cfg, opts.Session)
+	if err != nil {
+		return err
+	}
 	if _, err := awsconfig.EnsureSession(awsConfigPath, cfg, false); err != nil {
 		return fmt.Errorf("prepare aws sso session: %w", err)
 	}
 
+	onPrompt := opts.OnPrompt
+	if onPrompt == nil {
+		onPrompt = func(d ssoauth.Device) {
+			println(stdout, authPromptText(d), "Waiting for approval...")
+		}
+	}
+	for _, session := range sessions {
+		if len(cfg.SSOSessions) > 0 {
+			println(stdout, "SSO session "+session.ID()+" ("+session.StartURL+")")
+		}
+		if opts.AWSCLI {
+			if err := runAWSCLILogin(awsConfigPath, cfg, session, stdin, stdout, stderr, opts.NoBrowser); err != nil {
+				return err
+			}
+			continue
+		}
+		println(stdout, "Starting AWS SSO login...")
+		tok, err := ssoauth.Login(ctx, session.StartURL, session.Region, ssoauth.Options{
+			Session:     session.ID(),
+			OnPrompt:    onPrompt,
+			OpenBrowser: !opts.NoBrowser,
+		})
+		if err != nil {
+			return fmt.Errorf("aws sso login failed: %w", err)
+		}
+		println(stdout, "SSO login complete (token expires "+tok.ExpiresAt+").")
+	}
+	println(stdout, "You can now run: rift sync")
+	return nil
+}
+
+// authSessions resolves --session to the SSO sessions to sign in to.
+func authSessions(cfg config.Config, name string) ([]config.SSOSession, error) {
+	if strings.TrimSpace(name) == "" {
+		return cfg.Sessions(), nil
+	}
+	session, ok := cfg.Session(name)
+	if !ok {
+		return nil, rifterr.New(rifterr.CodeConfigInvalid, fmt.Sprintf("unknown sso session %q", name), "check sso_sessions in config.yaml")
+	}
+	return []config.SSOSession{session}, nil
+}
+
+func runAuthKeepAlive(ctx context.Context, app *App, cmd *cobra.Command, opts authOptions) error {
+	if opts.AWSCLI {
+		return fmt.Errorf("--keep-alive needs the built-in login; drop --aws-cli")
+	}
+	cfg, err := app.loadConfig()
+	if err != nil {
+		return err
+	}
+	sessions, err := authSessions(cfg, opts.Session)
+	if err != nil {
+		return err
+	}
+	out := cmd.OutOrStdout()
+	for _, session := range sessions {
+		if _, err := discovery.SessionToken(session, time.Now().UTC()); err != nil {
+			login := opts
+			login.Session = session.ID()
+			if err := runAuthFlow(ctx, app, cmd.InOrStdin(), out, cmd.ErrOrStderr(), login); err != nil {
+				return err
+			}
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	println(out, "Keeping the SSO token fresh; press Ctrl-C to stop.")
+	g, gctx := errgroup.WithContext(ctx)
+	for _, session := range sessions {
+		label := "token"
+		if len(cfg.SSOSessions) > 0 {
+			label = session.ID() + " token"
+		}
+		g.Go(func() error {
+			return ssoauth.KeepAlive(gctx, session.StartURL, session.Region, ssoauth.KeepAliveOptions{
+				Session: session.ID(),
+				OnRefresh: func(tok ssoauth.CachedToken) {
+					println(out, time.Now().Format("15:04:05")+" "+label+" refreshed (expires "+tok.ExpiresAt+")")
+				},
+				OnError: func(err error) {
+					fmt.Fprintf(cmd.ErrOrStderr(), "%s %s refresh failed: %v\n", time.Now().Format("15:04:05"), label, err)
+				},
+			})
+		})
+	}
+	err = g.Wait()
+	switch {
+	case errors.Is(err, context.Canceled):
+		return nil
+	case errors.Is(err, ssoauth.ErrNoRefreshToken):
+		return rifterr.Wrap(rifterr.CodeAuthRequired, err, "run: rift auth")
+	}
+	return err
+}
+
+func authPromptText(d ssoauth.Device) string {
+	return strings.Join([]string{
+		"Approve application " + ssoauth.ClientName + " in your browser:",
+		d.VerificationURIComplete,
+		"",
+		"Verification code: " + d.UserCode,
+	}, "\n")
+}
+
+// runAWSCLILogin delegates to `aws sso login`, falling back to a legacy
+// profile for AWS CLI versions without --sso-session.
+func runAWSCLILogin(awsConfigPath string, cfg config.Config, session config.SSOSession, stdin io.Reader, stdout, stderr io.Writer, noBrowser bool) error {
 	args := []string{
 		"sso",
 		"login",
 		"--sso-session",
-		"rift",
+		session.ID(),
 	}
 	if noBrowser {
 		args = append(args, "--no-browser")
@@ -53,7 +314,7 @@ func runAuthFlow(app *App, stdin io.Reader, stdout, stderr io.Writer, noBrowser
 	println(
 		stdout,
 		"Starting AWS SSO login...",
-		"If prompted, approve application: botocore-client-rift",
+		"If prompted, approve application: botocore-client-"+session.ID(),
 	)
 
 	output, err := runAWS(stdin, args...)
@@ -65,7 +326,8 @@ func runAuthFlow(app *App, stdin io.Reader, stdout, stderr io.Writer, noBrowser
 		if errors.As(err, &execErr) && errors.Is(execErr.Err, exec.ErrNotFound) {
 			return fmt.Errorf("aws CLI not found in PATH")
 		}
-		if supportsOnlyProfile(output) {
+		// The legacy rift-auth profile only covers the primary session.
+		if session.Name == "" && supportsOnlyProfile(output) {
 			if _, ensureErr := awsconfig.EnsureLegacyAuthProfile(awsConfigPath, cfg, false); ensureErr != nil {
 				return fmt.Errorf("prepare legacy aws sso profile: %w", ensureErr)
 			}
@@ -83,7 +345,7 @@ func runAuthFlow(app *App, stdin io.Reader, stdout, stderr io.Writer, noBrowser
 				_, _ = io.WriteString(stderr, string(fallbackOutput))
 			}
 			if fallbackErr == nil {
-				println(stdout, "SSO login complete.", "You can now run: rift sync")
+				println(stdout, "SSO login complete.")
 				return nil
 			}
 			return fmt.Errorf("aws sso login failed: %w", fallbackErr)
@@ -91,7 +353,7 @@ func runAuthFlow(app *App, stdin io.Reader, stdout, stderr io.Writer, noBrowser
 		return fmt.Errorf("aws sso login failed: %w", err)
 	}
 
-	println(stdout, "SSO login complete.", "You can now run: rift sync")
+	println(stdout, "SSO login complete.")
 	return nil
 }
 
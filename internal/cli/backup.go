@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"time"
+
+	"github.com/phenixrizen/rift/internal/backup"
+	"github.com/phenixrizen/rift/internal/config"
+)
+
+func (a *App) backupsDir() string {
+	return backup.DirFor(a.StatePath)
+}
+
+// backupTargets are the files sync rewrites: ~/.aws/config, the kubeconfig
+// files or split layout directory (see kubeconfigOutputs), and state.json.
+func (a *App) backupTargets(awsConfigPath string, kubeFiles []string, splitDir string) []backup.File {
+	targets := []backup.File{{Path: awsConfigPath}}
+	for _, path := range kubeFiles {
+		targets = append(targets, backup.File{Path: path})
+	}
+	if splitDir != "" {
+		targets = append(targets, backup.File{Path: splitDir, Dir: true})
+	}
+	return append(targets, backup.File{Path: a.StatePath})
+}
+
+// backupRetain is how many backups to keep; zero when backups are off.
+func backupRetain(cfg config.Config) int {
+	switch {
+	case cfg.BackupRetain < 0:
+		return 0
+	case cfg.BackupRetain == 0:
+		return backup.DefaultRetain
+	}
+	return cfg.BackupRetain
+}
+
+// takeBackup copies targets before command rewrites them. It returns nil
+// when backup_retain turns backups off.
+func (a *App) takeBackup(cfg config.Config, command string, targets []backup.File) (*backup.Backup, error) {
+	retain := backupRetain(cfg)
+	if retain == 0 {
+		return nil, nil
+	}
+	b, err := backup.Create(a.backupsDir(), command, targets, time.Now(), retain)
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// dropBackup discards b after its command turned out to change nothing.
+func (a *App) dropBackup(b *backup.Backup) {
+	if b == nil {
+		return
+	}
+	if err := backup.Discard(a.backupsDir(), *b); err != nil && a.Logger != nil {
+		a.Logger.Warn("unable to discard unused backup", "id", b.ID, "error", err)
+	}
+}
@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/phenixrizen/rift/internal/discovery"
+	"github.com/spf13/cobra"
+)
+
+// Exit codes for rift check. Token problems take precedence over state
+// problems when both are present.
+const (
+	checkExitTokenMissing = 2
+	checkExitTokenStale   = 3
+	checkExitStateMissing = 4
+	checkExitStateStale   = 5
+)
+
+func newCheckCmd(app *App) *cobra.Command {
+	var maxTokenAge time.Duration
+	var maxStateAge time.Duration
+	var quiet bool
+
+	cmd := &cobra.Command{
+		Use:   "check",
+		Short: "Exit non-zero when the SSO token or state is stale (cron-friendly)",
+		Long: `Checks the cached SSO token and state.json and exits with:
+
+  0  everything is fresh
+  2  SSO token missing or expired
+  3  SSO token older than --max-token-age
+  4  state.json missing
+  5  state.json older than --max-state-age`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			cfg, err := app.loadConfig()
+			if err != nil {
+				return err
+			}
+			now := time.Now().UTC()
+			out := cmd.OutOrStdout()
+			report := func(format string, args ...any) {
+				if !quiet {
+					fmt.Fprintf(out, format+"\n", args...)
+				}
+			}
+
+			var failure *ExitError
+			fail := func(code int, msg string) {
+				report("FAIL  %s", msg)
+				if failure == nil {
+					failure = &ExitError{Code: code, Err: errors.New(msg), Silent: true}
+				}
+			}
+
+			for _, session := range cfg.Sessions() {
+				label := "sso token"
+				if len(cfg.SSOSessions) > 0 {
+					label = "sso token (" + session.ID() + ")"
+				}
+				token, err := discovery.SessionToken(session, now)
+				switch {
+				case errors.Is(err, discovery.ErrSSONotLoggedIn):
+					fail(checkExitTokenMissing, label+" missing or expired; run: rift auth")
+				case err != nil:
+					return err
+				case maxTokenAge > 0 && !token.CachedAt.IsZero() && now.Sub(token.CachedAt) > maxTokenAge:
+					fail(checkExitTokenStale, fmt.Sprintf("%s is %s old (max %s); run: rift auth", label, now.Sub(token.CachedAt).Round(time.Minute), maxTokenAge))
+				default:
+					report("OK    %s valid until %s", label, token.ExpiresAt.Local().Format(time.RFC3339))
+				}
+			}
+
+			st, err := app.loadState()
+			switch {
+			case errors.Is(err, os.ErrNotExist):
+				fail(checkExitStateMissing, "state file not found; run: rift sync")
+			case err != nil:
+				return err
+			case maxStateAge > 0 && now.Sub(st.GeneratedAt) > maxStateAge:
+				fail(checkExitStateStale, fmt.Sprintf("state is %s old (max %s); run: rift sync", now.Sub(st.GeneratedAt).Round(time.Minute), maxStateAge))
+			default:
+				report("OK    state generated %s (%d contexts)", st.GeneratedAt.Local().Format(time.RFC3339), len(st.Clusters))
+			}
+
+			if failure != nil {
+				return failure
+			}
+			return nil
+		},
+	}
+	cmd.Flags().DurationVar(&maxTokenAge, "max-token-age", 0, "Fail when the SSO token was issued longer ago than this (0 disables)")
+	cmd.Flags().DurationVar(&maxStateAge, "max-state-age", 0, "Fail when state.json is older than this (0 disables)")
+	cmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "Print nothing; rely on the exit code")
+	return cmd
+}
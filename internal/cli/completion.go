@@ -0,0 +1,181 @@
+package cli
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/phenixrizen/rift/internal/state"
+	"github.com/spf13/cobra"
+)
+
+// builtinEnvs are the envs the default naming rules produce; env_rules can
+// add others, which completions pick up from state.
+var builtinEnvs = []string{"prod", "staging", "dev", "int", "other"}
+
+func newCompletionCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "completion bash|zsh|fish|powershell",
+		Short: "Generate a shell completion script",
+		Long: `Prints a completion script for your shell. Context names, envs, and tags
+complete from state.json.
+
+  bash:        source <(rift completion bash)
+  zsh:         rift completion zsh > "${fpath[1]}/_rift"
+  fish:        rift completion fish > ~/.config/fish/completions/rift.fish
+  powershell:  rift completion powershell | Out-String | Invoke-Expression`,
+		Args:                  cobra.ExactArgs(1),
+		ValidArgs:             []string{"bash", "zsh", "fish", "powershell"},
+		DisableFlagsInUseLine: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root := cmd.Root()
+			out := cmd.OutOrStdout()
+			switch args[0] {
+			case "bash":
+				return root.GenBashCompletionV2(out, true)
+			case "zsh":
+				return root.GenZshCompletion(out)
+			case "fish":
+				return root.GenFishCompletion(out, true)
+			case "powershell":
+				return root.GenPowerShellCompletionWithDesc(out)
+			default:
+				return fmt.Errorf("unsupported shell %q (expected bash|zsh|fish|powershell)", args[0])
+			}
+		},
+	}
+}
+
+type completionFunc func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective)
+
+// completionState loads state for a completion request. Completions run
+// without PersistentPreRunE, so paths are resolved here; any failure just
+// yields no suggestions.
+func completionState(app *App) (state.State, bool) {
+	if err := app.initialize(); err != nil {
+		return state.State{}, false
+	}
+	st, err := app.loadState()
+	return st, err == nil
+}
+
+// completeContexts suggests kube contexts from state for the first
+// positional argument.
+func completeContexts(app *App) completionFunc {
+	return func(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		st, ok := completionState(app)
+		if !ok {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		out := make([]string, 0, len(st.Clusters))
+		for _, c := range st.Clusters {
+			if strings.HasPrefix(c.KubeContext, toComplete) {
+				out = append(out, c.KubeContext+"\t"+c.Env+" | "+c.AccountLabel()+" | "+c.Region)
+			}
+		}
+		sort.Strings(out)
+		return out, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// completeContextThenTags completes a context for the first argument and
+// tags after it (rift tag add|remove).
+func completeContextThenTags(app *App) completionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) == 0 {
+			return completeContexts(app)(cmd, args, toComplete)
+		}
+		return completeTags(app)(cmd, args, toComplete)
+	}
+}
+
+// completeNamespaces suggests the namespaces recorded for the context
+// named by the first argument, or for every context when it names none.
+func completeNamespaces(app *App) completionFunc {
+	return func(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		st, ok := completionState(app)
+		if !ok {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		clusters := st.Clusters
+		if len(args) > 0 {
+			if i := slices.IndexFunc(clusters, func(c state.ClusterRecord) bool { return c.KubeContext == args[0] }); i >= 0 {
+				clusters = clusters[i : i+1]
+			}
+		}
+		seen := map[string]struct{}{}
+		out := make([]string, 0)
+		for _, c := range clusters {
+			for _, ns := range c.Namespaces {
+				if _, dup := seen[ns]; dup || !strings.HasPrefix(ns, toComplete) {
+					continue
+				}
+				seen[ns] = struct{}{}
+				out = append(out, ns)
+			}
+		}
+		sort.Strings(out)
+		return out, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// completeEnvs suggests the built-in envs plus any env present in state.
+func completeEnvs(app *App, extra ...string) completionFunc {
+	return func(_ *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		st, _ := completionState(app)
+		envs := append(knownEnvs(st), extra...)
+		out := make([]string, 0, len(envs))
+		for _, env := range envs {
+			if strings.HasPrefix(env, toComplete) {
+				out = append(out, env)
+			}
+		}
+		return out, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// completeTags suggests tags present in state.
+func completeTags(app *App) completionFunc {
+	return func(_ *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		st, ok := completionState(app)
+		if !ok {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		seen := map[string]struct{}{}
+		out := make([]string, 0)
+		for _, c := range st.Clusters {
+			for _, tag := range c.Tags {
+				if _, dup := seen[tag]; dup || !strings.HasPrefix(tag, toComplete) {
+					continue
+				}
+				seen[tag] = struct{}{}
+				out = append(out, tag)
+			}
+		}
+		sort.Strings(out)
+		return out, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// knownEnvs returns builtinEnvs followed by any other envs in st, sorted.
+func knownEnvs(st state.State) []string {
+	out := append([]string(nil), builtinEnvs...)
+	seen := map[string]struct{}{}
+	for _, env := range builtinEnvs {
+		seen[env] = struct{}{}
+	}
+	extra := make([]string, 0)
+	for _, c := range st.Clusters {
+		if _, ok := seen[c.Env]; ok || c.Env == "" {
+			continue
+		}
+		seen[c.Env] = struct{}{}
+		extra = append(extra, c.Env)
+	}
+	sort.Strings(extra)
+	return append(out, extra...)
+}
@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/phenixrizen/rift/internal/browser"
+	"github.com/phenixrizen/rift/internal/config"
+	"github.com/phenixrizen/rift/internal/console"
+	"github.com/phenixrizen/rift/internal/discovery"
+	"github.com/phenixrizen/rift/internal/rifterr"
+	"github.com/phenixrizen/rift/internal/state"
+	"github.com/spf13/cobra"
+)
+
+func newConsoleCmd(app *App) *cobra.Command {
+	var printOnly bool
+	cmd := &cobra.Command{
+		Use:   "console <filter>",
+		Short: "Open the AWS console signed in as a profile or context's role",
+		Long: `Fuzzy-matches an AWS profile or kube context from state, exchanges its SSO role
+credentials for a console sign-in link at the AWS federation endpoint, and
+opens it in the browser. A context lands on its EKS cluster page, a profile
+on the console home of its first region.
+
+The link signs in whoever opens it and is valid for 15 minutes; --print
+writes it to stdout instead of opening it.`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeTargets(app),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := app.loadConfig()
+			if err != nil {
+				return err
+			}
+			st, err := app.loadState()
+			if err != nil {
+				if errors.Is(err, os.ErrNotExist) {
+					return errStateNotFound
+				}
+				return err
+			}
+			target, err := resolveTarget(cmd.ErrOrStderr(), cmd.InOrStdin(), st, args[0])
+			if err != nil {
+				if errors.Is(err, errSelectionCancelled) {
+					return nil
+				}
+				return err
+			}
+			link, err := consoleLink(cmd.Context(), app, cfg, st, target)
+			if err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			if printOnly {
+				fmt.Fprintln(out, link)
+				return nil
+			}
+			if err := browser.Open(link); err != nil {
+				return fmt.Errorf("open browser: %w (use --print to get the link)", err)
+			}
+			fmt.Fprintf(out, "opened the AWS console as %s\n", target.Role.AWSProfile)
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&printOnly, "print", false, "Print the sign-in link instead of opening it")
+	return cmd
+}
+
+// consoleLink returns a console sign-in link for t's role, landing on its
+// cluster's EKS page or, for a profile, the console home.
+func consoleLink(ctx context.Context, app *App, cfg config.Config, st state.State, t target) (string, error) {
+	if t.Role.AWSProfile == "" {
+		return "", fmt.Errorf("%s has no AWS role (platform: %s)", t.Name, t.Cluster.PlatformLabel())
+	}
+	creds, err := discovery.RoleCredentials(ctx, cfg, st, t.Role, app.credentialCache(cfg))
+	if err != nil {
+		if errors.Is(err, discovery.ErrSSONotLoggedIn) {
+			return "", rifterr.Wrap(rifterr.CodeAuthRequired, ErrSSOLoginRequired, "run: rift auth")
+		}
+		return "", fmt.Errorf("get credentials for %s: %w", t.Role.AWSProfile, err)
+	}
+	region := t.Region(cfg)
+	partition := console.RegionPartition(region)
+	destination := console.HomeURL(partition, region)
+	if t.Cluster != nil {
+		partition = console.Partition(*t.Cluster)
+		if link, ok := console.ClusterURL(*t.Cluster); ok {
+			destination = link
+		}
+	}
+	link, err := console.SigninURL(ctx, creds, partition, destination)
+	if err != nil {
+		return "", fmt.Errorf("console sign-in for %s: %w", t.Role.AWSProfile, err)
+	}
+	return link, nil
+}
@@ -0,0 +1,216 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/phenixrizen/rift/internal/config"
+	"github.com/phenixrizen/rift/internal/history"
+	"github.com/phenixrizen/rift/internal/state"
+	"github.com/spf13/cobra"
+)
+
+type diffOutput struct {
+	From    string           `json:"from"`
+	To      string           `json:"to"`
+	Changes []history.Change `json:"changes"`
+}
+
+func newDiffCmd(app *App) *cobra.Command {
+	var list bool
+	cmd := &cobra.Command{
+		Use:   "diff [from] [to]",
+		Short: "Show clusters and roles added, removed, or changed between syncs",
+		Long: `Every sync that changes the inventory keeps a snapshot of state.json.
+diff compares two of them: with no arguments, the latest snapshot against the
+one before it (what the last changing sync did); with one, that snapshot
+against the current state.json; with two, the first against the second.
+
+Snapshots are named by the sync's time; any unique prefix works, as does
+"latest". history_retain in config sets how many are kept.`,
+		Example: `  rift diff
+  rift diff --list
+  rift diff 20260102
+  rift diff 20260102T150405 latest -o json`,
+		Args: cobra.MaximumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir := app.historyDir()
+			if list {
+				return listSnapshots(cmd, app, dir)
+			}
+			var from, to string
+			var before, after state.State
+			var err error
+			switch len(args) {
+			case 0:
+				to, from, err = history.Resolve(dir, "latest")
+				if err != nil {
+					return err
+				}
+				if from == "" {
+					println(cmd.OutOrStdout(), "Only one state snapshot recorded; nothing to compare against yet.")
+					return nil
+				}
+			case 1:
+				if from, _, err = history.Resolve(dir, args[0]); err != nil {
+					return err
+				}
+				to = "current"
+			default:
+				if from, _, err = history.Resolve(dir, args[0]); err != nil {
+					return err
+				}
+				if to, _, err = history.Resolve(dir, args[1]); err != nil {
+					return err
+				}
+			}
+			sealer, err := app.stateSealer()
+			if err != nil {
+				return err
+			}
+			if before, err = history.Load(dir, from, sealer); err != nil {
+				return err
+			}
+			if to == "current" {
+				after, err = app.loadState()
+				if errors.Is(err, os.ErrNotExist) {
+					return errStateNotFound
+				}
+			} else {
+				after, err = history.Load(dir, to, sealer)
+			}
+			if err != nil {
+				return err
+			}
+
+			diff := history.Compare(before, after)
+			out := cmd.OutOrStdout()
+			if app.Output == "json" {
+				enc := json.NewEncoder(out)
+				enc.SetIndent("", "  ")
+				return enc.Encode(diffOutput{From: from, To: to, Changes: diff.Changes})
+			}
+			printStateDiff(out, from, to, diff)
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&list, "list", false, "List state snapshots instead of comparing")
+	return cmd
+}
+
+func printStateDiff(out io.Writer, from, to string, diff history.Diff) {
+	fmt.Fprintf(out, "Changes from %s to %s:\n", from, to)
+	if diff.Empty() {
+		println(out, "  (none)")
+		return
+	}
+	marks := map[string]string{history.Added: "+", history.Removed: "-", history.Changed: "~"}
+	for _, c := range diff.Changes {
+		where := c.Account
+		if c.Region != "" {
+			where += ", " + c.Region
+		}
+		fmt.Fprintf(out, "  %s %s %s (%s)\n", marks[c.Action], c.Kind, c.Name, where)
+		for _, f := range c.Fields {
+			fmt.Fprintf(out, "      %s: %s -> %s\n", f.Field, orNone(f.Before), orNone(f.After))
+		}
+	}
+	fmt.Fprintf(out, "Clusters: +%d ~%d -%d  Roles: +%d ~%d -%d\n",
+		diff.Count("cluster", history.Added), diff.Count("cluster", history.Changed), diff.Count("cluster", history.Removed),
+		diff.Count("role", history.Added), diff.Count("role", history.Changed), diff.Count("role", history.Removed))
+}
+
+func orNone(v string) string {
+	if v == "" {
+		return "(none)"
+	}
+	return v
+}
+
+func listSnapshots(cmd *cobra.Command, app *App, dir string) error {
+	ids, err := history.List(dir)
+	if err != nil {
+		return err
+	}
+	type snapshot struct {
+		ID          string `json:"id"`
+		GeneratedAt string `json:"generated_at"`
+		Roles       int    `json:"roles"`
+		Clusters    int    `json:"clusters"`
+	}
+	sealer, err := app.stateSealer()
+	if err != nil {
+		return err
+	}
+	snapshots := make([]snapshot, 0, len(ids))
+	for i := len(ids) - 1; i >= 0; i-- {
+		st, err := history.Load(dir, ids[i], sealer)
+		if err != nil {
+			return err
+		}
+		snapshots = append(snapshots, snapshot{ID: ids[i], GeneratedAt: st.GeneratedAt.Local().Format("2006-01-02 15:04:05"), Roles: len(st.Roles), Clusters: len(st.Clusters)})
+	}
+	out := cmd.OutOrStdout()
+	if app.Output == "json" {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(snapshots)
+	}
+	if len(snapshots) == 0 {
+		println(out, "No state snapshots recorded yet.", "Run: rift sync")
+		return nil
+	}
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSynced\tRoles\tClusters")
+	for _, s := range snapshots {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\n", s.ID, s.GeneratedAt, s.Roles, s.Clusters)
+	}
+	return w.Flush()
+}
+
+func (a *App) historyDir() string {
+	return history.DirFor(a.StatePath)
+}
+
+// historyRetain is how many snapshots to keep; zero when they are off.
+func historyRetain(cfg config.Config) int {
+	switch {
+	case cfg.HistoryRetain < 0:
+		return 0
+	case cfg.HistoryRetain == 0:
+		return history.DefaultRetain
+	}
+	return cfg.HistoryRetain
+}
+
+// recordSnapshot snapshots st after a sync wrote it. The first time, prev
+// (when there was one) is snapshotted too, so the very first diff already
+// has something to compare against.
+func (a *App) recordSnapshot(cfg config.Config, prev state.State, hasPrev bool, st state.State) error {
+	retain := historyRetain(cfg)
+	if retain == 0 {
+		return nil
+	}
+	sealer, err := a.stateSealer()
+	if err != nil {
+		return err
+	}
+	dir := a.historyDir()
+	if hasPrev {
+		ids, err := history.List(dir)
+		if err != nil {
+			return err
+		}
+		if len(ids) == 0 && prev.GeneratedAt.Before(st.GeneratedAt) {
+			if _, err := history.Save(dir, prev, retain, sealer); err != nil {
+				return err
+			}
+		}
+	}
+	_, err = history.Save(dir, st, retain, sealer)
+	return err
+}
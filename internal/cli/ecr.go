@@ -0,0 +1,122 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/phenixrizen/rift/internal/discovery"
+	"github.com/phenixrizen/rift/internal/ecrauth"
+	"github.com/phenixrizen/rift/internal/rifterr"
+	"github.com/spf13/cobra"
+)
+
+func newECRLoginCmd(app *App) *cobra.Command {
+	var (
+		region      string
+		docker      string
+		writeConfig bool
+		printOnly   bool
+	)
+	cmd := &cobra.Command{
+		Use:   "ecr-login <filter>",
+		Short: "Log docker in to a profile or context's ECR registry",
+		Long: `Fuzzy-matches an AWS profile or kube context from state, gets an ECR
+authorization token for its account with the role's SSO credentials, and
+runs docker login for the account's registry (in the cluster's region, or
+the profile's first configured region).
+
+--write-config stores the login in the docker config ($DOCKER_CONFIG or
+~/.docker/config.json) instead, for machines without the docker CLI;
+--print writes only the password, like aws ecr get-login-password. The
+login is valid for 12 hours.`,
+		Example: `  rift ecr-login prod-payments
+  rift ecr-login prod-payments --region eu-west-1
+  rift ecr-login prod-payments --docker podman
+  rift ecr-login prod-payments --print | docker login -u AWS --password-stdin <registry>`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeTargets(app),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if writeConfig && printOnly {
+				return fmt.Errorf("--write-config and --print cannot be combined")
+			}
+			cfg, err := app.loadConfig()
+			if err != nil {
+				return err
+			}
+			st, err := app.loadState()
+			if err != nil {
+				if errors.Is(err, os.ErrNotExist) {
+					return errStateNotFound
+				}
+				return err
+			}
+			target, err := resolveTarget(cmd.ErrOrStderr(), cmd.InOrStdin(), st, args[0])
+			if err != nil {
+				if errors.Is(err, errSelectionCancelled) {
+					return nil
+				}
+				return err
+			}
+			if target.Role.AWSProfile == "" {
+				return fmt.Errorf("%s has no AWS role (platform: %s)", target.Name, target.Cluster.PlatformLabel())
+			}
+			if region == "" {
+				region = target.Region(cfg)
+			}
+			if region == "" {
+				return fmt.Errorf("no region known for %s; pass --region", target.Name)
+			}
+			creds, err := discovery.RoleCredentials(cmd.Context(), cfg, st, target.Role, app.credentialCache(cfg))
+			if err != nil {
+				if errors.Is(err, discovery.ErrSSONotLoggedIn) {
+					return rifterr.Wrap(rifterr.CodeAuthRequired, ErrSSOLoginRequired, "run: rift auth")
+				}
+				return fmt.Errorf("get credentials for %s: %w", target.Role.AWSProfile, err)
+			}
+			login, err := ecrauth.Token(cmd.Context(), creds, region)
+			if err != nil {
+				return fmt.Errorf("get ECR token for %s in %s: %w", target.Role.AWSProfile, region, err)
+			}
+
+			out := cmd.OutOrStdout()
+			switch {
+			case printOnly:
+				fmt.Fprintln(out, login.Password)
+				return nil
+			case writeConfig:
+				path, err := ecrauth.DockerConfigPath()
+				if err != nil {
+					return err
+				}
+				if err := ecrauth.WriteDockerConfig(path, login); err != nil {
+					if errors.Is(err, ecrauth.ErrCredentialHelper) {
+						return fmt.Errorf("%s: %w; run without --write-config so docker stores the login", path, err)
+					}
+					return fmt.Errorf("write %s: %w", path, err)
+				}
+				fmt.Fprintf(out, "Wrote the login for %s to %s (valid until %s)\n", login.Registry, path, login.Expires.Local().Format("15:04"))
+				return nil
+			}
+
+			run := exec.CommandContext(cmd.Context(), docker, "login", "--username", login.Username, "--password-stdin", login.Registry)
+			run.Stdin = strings.NewReader(login.Password)
+			run.Stdout = out
+			run.Stderr = cmd.ErrOrStderr()
+			if err := run.Run(); err != nil {
+				if errors.Is(err, exec.ErrNotFound) {
+					return fmt.Errorf("%s not found in PATH; use --write-config to write the docker config directly", docker)
+				}
+				return fmt.Errorf("%s login %s: %w", docker, login.Registry, err)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&region, "region", "", "Registry region (default: the cluster's, or the profile's first region)")
+	cmd.Flags().StringVar(&docker, "docker", "docker", "Container CLI to log in with (e.g. podman, finch)")
+	cmd.Flags().BoolVar(&writeConfig, "write-config", false, "Write the login to the docker config instead of running docker login")
+	cmd.Flags().BoolVar(&printOnly, "print", false, "Print the registry password instead of logging in")
+	return cmd
+}
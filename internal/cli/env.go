@@ -0,0 +1,231 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/lithammer/fuzzysearch/fuzzy"
+	"github.com/phenixrizen/rift/internal/config"
+	"github.com/phenixrizen/rift/internal/discovery"
+	"github.com/phenixrizen/rift/internal/rifterr"
+	"github.com/phenixrizen/rift/internal/state"
+	"github.com/spf13/cobra"
+)
+
+func newEnvCmd(app *App) *cobra.Command {
+	var shell string
+	cmd := &cobra.Command{
+		Use:   "env <filter>",
+		Short: "Print AWS credential exports for a profile or context",
+		Long: `Fuzzy-matches an AWS profile or kube context from state, fetches temporary
+credentials for its role from SSO, and prints them for your shell:
+
+  eval "$(rift env prod-payments)"                        # bash/zsh
+  rift env prod-payments --shell fish | source            # fish
+  rift env prod-payments --shell powershell | Invoke-Expression
+
+With -o json the credentials are printed in the credential_process format.`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeTargets(app),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			switch shell {
+			case "bash", "zsh", "fish", "powershell":
+			default:
+				return fmt.Errorf("--shell must be one of bash|zsh|fish|powershell")
+			}
+			cfg, err := app.loadConfig()
+			if err != nil {
+				return err
+			}
+			st, err := app.loadState()
+			if err != nil {
+				if errors.Is(err, os.ErrNotExist) {
+					return errStateNotFound
+				}
+				return err
+			}
+			// stdout is usually eval'd, so any picker goes to stderr.
+			target, err := resolveTarget(cmd.ErrOrStderr(), cmd.InOrStdin(), st, args[0])
+			if err != nil {
+				if errors.Is(err, errSelectionCancelled) {
+					return nil
+				}
+				return err
+			}
+
+			if target.Role.AWSProfile == "" {
+				return fmt.Errorf("%s has no AWS role (platform: %s)", target.Name, target.Cluster.PlatformLabel())
+			}
+			creds, err := discovery.RoleCredentials(cmd.Context(), cfg, st, target.Role, app.credentialCache(cfg))
+			if err != nil {
+				if errors.Is(err, discovery.ErrSSONotLoggedIn) {
+					return rifterr.Wrap(rifterr.CodeAuthRequired, ErrSSOLoginRequired, "run: rift auth")
+				}
+				return fmt.Errorf("get credentials for %s: %w", target.Role.AWSProfile, err)
+			}
+
+			out := cmd.OutOrStdout()
+			if app.Output == "json" {
+				enc := json.NewEncoder(out)
+				enc.SetIndent("", "  ")
+				return enc.Encode(processCredentials(creds))
+			}
+			printExports(out, shell, credentialEnv(creds, target.Region(cfg)))
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&shell, "shell", "bash", "Export syntax: bash|zsh|fish|powershell")
+	_ = cmd.RegisterFlagCompletionFunc("shell", cobra.FixedCompletions([]string{"bash", "zsh", "fish", "powershell"}, cobra.ShellCompDirectiveNoFileComp))
+	return cmd
+}
+
+// target is a role from state, plus the cluster when the filter matched a
+// kube context. Role is empty for clusters on other clouds (GKE, AKS).
+type target struct {
+	Name    string
+	Role    state.RoleRecord
+	Cluster *state.ClusterRecord
+}
+
+// Region is the cluster's region, else the first configured region for the
+// role's account.
+func (t target) Region(cfg config.Config) string {
+	if t.Cluster != nil {
+		return t.Cluster.Region
+	}
+	if regions := cfg.RegionsFor(t.Role.Env, t.Role.AccountName, t.Role.AccountID); len(regions) > 0 {
+		return regions[0]
+	}
+	return ""
+}
+
+// resolveTarget fuzzy-matches filter against AWS profiles and kube contexts
+// in st. Ambiguous matches are listed on prompt and read from in.
+func resolveTarget(prompt io.Writer, in io.Reader, st state.State, filter string) (target, error) {
+	targets := map[string]target{}
+	for _, role := range st.Roles {
+		targets[role.AWSProfile] = target{Name: role.AWSProfile, Role: role}
+	}
+	for _, c := range st.Clusters {
+		role, ok := findRole(st, c.AWSProfile)
+		if !ok && c.IsAWS() {
+			continue
+		}
+		targets[c.KubeContext] = target{Name: c.KubeContext, Role: role, Cluster: &c}
+	}
+	if len(targets) == 0 {
+		return target{}, fmt.Errorf("no profiles available; run: rift sync")
+	}
+
+	names := make([]string, 0, len(targets))
+	for name := range targets {
+		names = append(names, name)
+	}
+	ranks := fuzzy.RankFindNormalizedFold(filter, names)
+	if len(ranks) == 0 {
+		return target{}, fmt.Errorf("no profile or context matches %q", filter)
+	}
+	sort.Sort(ranks)
+	selected, err := pickTarget(prompt, in, "profiles/contexts", filter, ranks, func(name string) string {
+		t := targets[name]
+		if t.Cluster != nil {
+			return fmt.Sprintf("context | %s | %s | %s", t.Cluster.Env, t.Cluster.AccountLabel(), t.Cluster.Region)
+		}
+		return fmt.Sprintf("profile | %s | %s | %s", t.Role.Env, t.Role.AccountLabel(), t.Role.RoleName)
+	})
+	if err != nil {
+		return target{}, err
+	}
+	return targets[selected], nil
+}
+
+// completeTargets suggests AWS profiles and kube contexts from state.
+func completeTargets(app *App) completionFunc {
+	return func(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		st, ok := completionState(app)
+		if !ok {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		out := make([]string, 0, len(st.Roles)+len(st.Clusters))
+		for _, r := range st.Roles {
+			if strings.HasPrefix(r.AWSProfile, toComplete) {
+				out = append(out, r.AWSProfile+"\tprofile | "+r.Env+" | "+r.AccountLabel())
+			}
+		}
+		for _, c := range st.Clusters {
+			if strings.HasPrefix(c.KubeContext, toComplete) {
+				out = append(out, c.KubeContext+"\tcontext | "+c.Env+" | "+c.AccountLabel()+" | "+c.Region)
+			}
+		}
+		sort.Strings(out)
+		return out, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+type envVar struct {
+	Name  string
+	Value string
+}
+
+// credentialEnv is the environment the AWS CLI and SDKs read static
+// credentials from.
+func credentialEnv(creds aws.Credentials, region string) []envVar {
+	vars := []envVar{
+		{"AWS_ACCESS_KEY_ID", creds.AccessKeyID},
+		{"AWS_SECRET_ACCESS_KEY", creds.SecretAccessKey},
+		{"AWS_SESSION_TOKEN", creds.SessionToken},
+	}
+	if creds.CanExpire {
+		vars = append(vars, envVar{"AWS_CREDENTIAL_EXPIRATION", creds.Expires.UTC().Format(time.RFC3339)})
+	}
+	if region != "" {
+		vars = append(vars, envVar{"AWS_REGION", region}, envVar{"AWS_DEFAULT_REGION", region})
+	}
+	return vars
+}
+
+func printExports(out io.Writer, shell string, vars []envVar) {
+	for _, v := range vars {
+		switch shell {
+		case "fish":
+			fmt.Fprintf(out, "set -gx %s '%s';\n", v.Name, strings.ReplaceAll(strings.ReplaceAll(v.Value, `\`, `\\`), `'`, `\'`))
+		case "powershell":
+			fmt.Fprintf(out, "$env:%s = '%s'\n", v.Name, strings.ReplaceAll(v.Value, `'`, `''`))
+		default:
+			fmt.Fprintf(out, "export %s='%s'\n", v.Name, strings.ReplaceAll(v.Value, `'`, `'\''`))
+		}
+	}
+}
+
+// credentialProcessOutput is the JSON the AWS credential_process setting
+// expects.
+type credentialProcessOutput struct {
+	Version         int    `json:"Version"`
+	AccessKeyID     string `json:"AccessKeyId"`
+	SecretAccessKey string `json:"SecretAccessKey"`
+	SessionToken    string `json:"SessionToken,omitempty"`
+	Expiration      string `json:"Expiration,omitempty"`
+}
+
+func processCredentials(creds aws.Credentials) credentialProcessOutput {
+	out := credentialProcessOutput{
+		Version:         1,
+		AccessKeyID:     creds.AccessKeyID,
+		SecretAccessKey: creds.SecretAccessKey,
+		SessionToken:    creds.SessionToken,
+	}
+	if creds.CanExpire {
+		out.Expiration = creds.Expires.UTC().Format(time.RFC3339)
+	}
+	return out
+}
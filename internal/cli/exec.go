@@ -0,0 +1,173 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"github.com/phenixrizen/rift/internal/config"
+	"github.com/phenixrizen/rift/internal/kubeconfig"
+	"github.com/spf13/cobra"
+)
+
+// execScrubbedEnv are variables that would override the profile or context
+// rift exec selects.
+var execScrubbedEnv = []string{
+	"AWS_ACCESS_KEY_ID",
+	"AWS_SECRET_ACCESS_KEY",
+	"AWS_SESSION_TOKEN",
+	"AWS_CREDENTIAL_EXPIRATION",
+	"AWS_PROFILE",
+	"AWS_DEFAULT_PROFILE",
+	"AWS_REGION",
+	"AWS_DEFAULT_REGION",
+	"KUBECONFIG",
+}
+
+func newExecCmd(app *App) *cobra.Command {
+	var namespace string
+	cmd := &cobra.Command{
+		Use:   "exec <filter> -- <command> [args...]",
+		Short: "Run a command against a context without switching kubectl",
+		Long: `Fuzzy-matches a kube context (or AWS profile) from state and runs the command
+with AWS_PROFILE and AWS_REGION set. For a context, KUBECONFIG points at a
+temporary kubeconfig holding only that context, so the global current-context
+is never changed:
+
+  rift exec prod-payments -- helm list
+  rift exec prod-payments -n kube-system -- kubectl get pods`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if cmd.ArgsLenAtDash() != 1 || len(args) < 2 {
+				return fmt.Errorf("usage: rift exec <filter> -- <command> [args...]")
+			}
+			return nil
+		},
+		ValidArgsFunction: completeTargets(app),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := app.loadConfig()
+			if err != nil {
+				return err
+			}
+			st, err := app.loadState()
+			if err != nil {
+				if errors.Is(err, os.ErrNotExist) {
+					return errStateNotFound
+				}
+				return err
+			}
+			target, err := resolveTarget(cmd.ErrOrStderr(), cmd.InOrStdin(), st, args[0])
+			if err != nil {
+				if errors.Is(err, errSelectionCancelled) {
+					return nil
+				}
+				return err
+			}
+
+			if target.Cluster == nil && namespace != "" {
+				return fmt.Errorf("--namespace needs a kube context, but %q matched AWS profile %s", args[0], target.Role.AWSProfile)
+			}
+			env, cleanup, err := targetEnv(cfg, target, namespace)
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+
+			run := exec.Command(args[1], args[2:]...)
+			run.Env = env
+			run.Stdin = cmd.InOrStdin()
+			run.Stdout = cmd.OutOrStdout()
+			run.Stderr = cmd.ErrOrStderr()
+
+			// The child shares the terminal and receives Ctrl-C itself; rift
+			// only waits for it so the temporary kubeconfig is cleaned up.
+			signals := make(chan os.Signal, 1)
+			signal.Notify(signals, os.Interrupt)
+			defer signal.Stop(signals)
+
+			if err := run.Run(); err != nil {
+				var exitErr *exec.ExitError
+				if errors.As(err, &exitErr) {
+					code := exitErr.ExitCode()
+					if code < 0 {
+						code = 1
+					}
+					return &ExitError{Code: code, Err: err, Silent: true}
+				}
+				return err
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&namespace, "namespace", "n", "", "Default namespace for the command (overrides the context's)")
+	return cmd
+}
+
+// targetEnv is the environment rift exec runs commands in for t: the
+// current one without execScrubbedEnv, plus AWS_PROFILE and AWS_REGION and,
+// for a context, KUBECONFIG pointing at a temporary kubeconfig holding only
+// that context (namespace, when set, as its default). cleanup removes the
+// kubeconfig.
+func targetEnv(cfg config.Config, t target, namespace string) (env []string, cleanup func(), err error) {
+	env = scrubEnv(os.Environ(), execScrubbedEnv)
+	if t.Role.AWSProfile != "" {
+		env = append(env, "AWS_PROFILE="+t.Role.AWSProfile)
+		if region := t.Region(cfg); region != "" {
+			env = append(env, "AWS_REGION="+region, "AWS_DEFAULT_REGION="+region)
+		}
+	}
+	if t.Cluster == nil {
+		return env, func() {}, nil
+	}
+	rec := *t.Cluster
+	if err := checkPluginCluster(cfg, rec); err != nil {
+		return nil, nil, err
+	}
+	if !rec.Connectable() {
+		return nil, nil, fmt.Errorf("%s is an %s registration with no API endpoint; use the cluster's own kubeconfig", rec.KubeContext, rec.PlatformLabel())
+	}
+	if rec.External() {
+		return nil, nil, fmt.Errorf("%s was imported from your kubeconfig; run the command with --context %s instead", rec.KubeContext, rec.KubeContext)
+	}
+	dir, err := os.MkdirTemp("", "rift-exec-")
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanup = func() { os.RemoveAll(dir) }
+	path := filepath.Join(dir, "kubeconfig")
+	if err := kubeconfig.WriteStandalone(path, rec, namespace); err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("write kubeconfig: %w", err)
+	}
+	ns := rec.Namespace
+	if namespace != "" {
+		ns = namespace
+	}
+	env = append(env, "KUBECONFIG="+path, "RIFT_CONTEXT="+rec.KubeContext)
+	if ns != "" {
+		env = append(env, "RIFT_NAMESPACE="+ns)
+	}
+	return env, cleanup, nil
+}
+
+// scrubEnv returns environ without the named variables.
+func scrubEnv(environ, names []string) []string {
+	out := make([]string, 0, len(environ))
+	for _, kv := range environ {
+		name, _, _ := strings.Cut(kv, "=")
+		drop := false
+		for _, n := range names {
+			if name == n {
+				drop = true
+				break
+			}
+		}
+		if !drop {
+			out = append(out, kv)
+		}
+	}
+	return out
+}
@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+
+	"github.com/phenixrizen/rift/internal/rifterr"
+)
+
+var errStateNotFound = rifterr.New(rifterr.CodeStateMissing, "state file not found", "run: rift sync")
+
+// ExitError carries a specific process exit code for commands whose exit
+// status is part of their contract (e.g. rift check).
+type ExitError struct {
+	Code int
+	Err  error
+	// Silent means the command already reported the failure itself.
+	Silent bool
+}
+
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// ExitCode maps an error returned by Execute to a process exit code.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) && exitErr.Code != 0 {
+		return exitErr.Code
+	}
+	return 1
+}
+
+// writeJSONError renders err as {"error": {code, message, hint}} for
+// --output json.
+func writeJSONError(w io.Writer, err error) {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(struct {
+		Error *rifterr.Error `json:"error"`
+	}{rifterr.Classify(err)})
+}
+
+// ShouldPrint reports whether main should print err to stderr.
+func ShouldPrint(err error) bool {
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		return !exitErr.Silent
+	}
+	return err != nil
+}
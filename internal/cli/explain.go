@@ -0,0 +1,152 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/lithammer/fuzzysearch/fuzzy"
+	"github.com/phenixrizen/rift/internal/naming"
+	"github.com/phenixrizen/rift/internal/state"
+	"github.com/spf13/cobra"
+)
+
+func newExplainCmd(app *App) *cobra.Command {
+	return &cobra.Command{
+		Use:               "explain <context>",
+		Short:             "Explain how a context got its env, names, and namespace",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeContexts(app),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := app.loadConfig()
+			if err != nil {
+				return err
+			}
+			st, err := app.loadState()
+			if err != nil {
+				if errors.Is(err, os.ErrNotExist) {
+					return errStateNotFound
+				}
+				return err
+			}
+
+			contexts := make([]string, 0, len(st.Clusters))
+			contextMeta := map[string]state.ClusterRecord{}
+			for _, c := range st.Clusters {
+				contexts = append(contexts, c.KubeContext)
+				contextMeta[c.KubeContext] = c
+			}
+			ranks := fuzzy.RankFindNormalizedFold(args[0], contexts)
+			if len(ranks) == 0 {
+				return fmt.Errorf("no context matches %q", args[0])
+			}
+			sort.Sort(ranks)
+			selected, err := pickContext(cmd.OutOrStdout(), cmd.InOrStdin(), args[0], ranks, contextMeta)
+			if err != nil {
+				if errors.Is(err, errSelectionCancelled) {
+					fmt.Fprintln(cmd.OutOrStdout(), "Selection cancelled.")
+					return nil
+				}
+				return err
+			}
+
+			rec := contextMeta[selected]
+			if rec.External() {
+				fmt.Fprintf(cmd.OutOrStdout(), "%s was imported from kubeconfig (rift import kubeconfig); rift did not name it.\n", rec.KubeContext)
+				return nil
+			}
+			ex := naming.Explain(cfg, rec)
+			role, _ := findRole(st, rec.AWSProfile)
+			if app.Output == "json" {
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				return enc.Encode(struct {
+					Cluster     state.ClusterRecord `json:"cluster"`
+					Role        state.RoleRecord    `json:"role"`
+					Explanation naming.Explanation  `json:"explanation"`
+				}{rec, role, ex})
+			}
+			printExplanation(cmd.OutOrStdout(), rec, role, ex)
+			return nil
+		},
+	}
+}
+
+func findRole(st state.State, profile string) (state.RoleRecord, bool) {
+	for _, role := range st.Roles {
+		if role.AWSProfile == profile {
+			return role, true
+		}
+	}
+	return state.RoleRecord{}, false
+}
+
+func printExplanation(out io.Writer, rec state.ClusterRecord, role state.RoleRecord, ex naming.Explanation) {
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	row := func(label, value string) { fmt.Fprintf(tw, "%s\t%s\n", label, value) }
+
+	switch {
+	case ex.Env.Rule != "":
+		row("Env", fmt.Sprintf("%s (%s matched %q in %q)", ex.Env.Env, ex.Env.Rule, ex.Env.Keyword, ex.Env.Part))
+	case ex.Env.Keyword != "":
+		row("Env", fmt.Sprintf("%s (keyword %q found in %q)", ex.Env.Env, ex.Env.Keyword, ex.Env.Part))
+	case ex.EnvSource == "env_rules":
+		row("Env", fmt.Sprintf("%s (no env_rules entry matched %s)", ex.Env.Env, quoteAll(ex.EnvInputs)))
+	default:
+		row("Env", fmt.Sprintf("%s (no keyword found in %s)", ex.Env.Env, quoteAll(ex.EnvInputs)))
+	}
+	row("Account", fmt.Sprintf("%s -> slug %q (from %s)", rec.AccountLabel(), ex.AccountSlug, ex.AccountSource))
+	row("Cluster", fmt.Sprintf("%s -> slug %q", rec.ClusterName, ex.ClusterSlug))
+	if rec.Platform != "" {
+		row("Platform", rec.PlatformLabel())
+	}
+	row("Context", explainName(ex.Context, ex.ContextBase, ex.ContextPattern))
+	if rec.IsAWS() {
+		row("Role", fmt.Sprintf("%s -> slug %q", rec.RoleName, ex.RoleSlug))
+		if ex.RoleEnv != ex.Env.Env {
+			row("Role env", fmt.Sprintf("%s (from the account and role only; names the profile and picks regions)", ex.RoleEnv))
+		}
+		row("Profile", explainName(ex.Profile, ex.ProfileBase, ex.ProfilePattern))
+	}
+	if role.AssumeRoleARN != "" {
+		row("Assumed role", fmt.Sprintf("%s via source profile %s (assume_roles)", role.AssumeRoleARN, role.SourceProfile))
+	}
+	if ex.RegionOverride >= 0 {
+		row("Regions", fmt.Sprintf("%s (region_overrides[%d])", strings.Join(ex.Regions, ", "), ex.RegionOverride))
+	} else {
+		row("Regions", fmt.Sprintf("%s (regions)", strings.Join(ex.Regions, ", ")))
+	}
+	namespace := ex.Namespace
+	if namespace == "" {
+		namespace = "-"
+	}
+	row("Namespace", fmt.Sprintf("%s (%s)", namespace, ex.NamespaceSource))
+	if len(rec.Tags) > 0 {
+		row("Tags", strings.Join(rec.Tags, ", ")+" (overlay.yaml)")
+	}
+	_ = tw.Flush()
+}
+
+func explainName(name, base, pattern string) string {
+	switch {
+	case name == base:
+		return fmt.Sprintf("%s (%s)", name, pattern)
+	case strings.HasPrefix(name, base+"-"):
+		return fmt.Sprintf("%s (%s, suffixed: %s is used by another entry)", name, pattern, base)
+	default:
+		return fmt.Sprintf("%s (expected %s from current config; run: rift sync)", name, base)
+	}
+}
+
+func quoteAll(values []string) string {
+	quoted := make([]string, 0, len(values))
+	for _, v := range values {
+		quoted = append(quoted, fmt.Sprintf("%q", v))
+	}
+	return strings.Join(quoted, ", ")
+}
@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/phenixrizen/rift/internal/graphview"
+	"github.com/phenixrizen/rift/internal/tableview"
+	"github.com/spf13/cobra"
+)
+
+// Export kinds.
+const (
+	exportClusters = "clusters"
+	exportRoles    = "roles"
+)
+
+func newExportCmd(app *App) *cobra.Command {
+	var format, kind string
+	var tags []string
+	var filter graphview.Options
+	var table tableview.Options
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export clusters or roles as CSV, Markdown, JSON, or YAML",
+		Long: `Writes the inventory in state to stdout for spreadsheets and wiki pages:
+clusters (default) or roles, with the fields given by --fields in that order.
+CSV and Markdown get a header row; JSON and YAML (also chosen by -o json or
+-o yaml) are a list of objects keyed by field name.
+
+Cluster fields are the rift list columns; role fields are
+` + strings.Join(tableview.RoleColumnKeys(), ", ") + `.`,
+		Example: `  rift export --format csv > clusters.csv
+  rift export --format md --fields env,account,role,cluster,context --env prod
+  rift export --kind roles --format yaml --fields account,account-id,role,session`,
+		Args:        cobra.NoArgs,
+		Annotations: map[string]string{annotationYAMLOutput: "true"},
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			st, err := app.loadState()
+			if err != nil {
+				if errors.Is(err, os.ErrNotExist) {
+					return errStateNotFound
+				}
+				return err
+			}
+			if !cmd.Flags().Changed("format") && (app.Output == "json" || app.Output == "yaml") {
+				format = app.Output
+			}
+			format = strings.ToLower(strings.TrimSpace(format))
+			if format == "markdown" {
+				format = tableview.FormatMarkdown
+			}
+			if !slices.Contains(tableview.Formats, format) {
+				return fmt.Errorf("--format must be one of %s", strings.Join(tableview.Formats, "|"))
+			}
+			if filter.Env == "stg" {
+				filter.Env = "staging"
+			}
+			if envs := knownEnvs(st); filter.Env != "" && filter.Env != "all" && !slices.Contains(envs, filter.Env) {
+				return fmt.Errorf("--env must be one of %s|all", strings.Join(envs, "|"))
+			}
+
+			var t tableview.Table
+			switch kind {
+			case exportClusters:
+				if err := table.Validate(); err != nil {
+					return err
+				}
+				t = tableview.ClusterTable(filterByTags(graphview.FilterClusters(st.Clusters, filter), tags), table)
+			case exportRoles:
+				if len(tags) > 0 || filter.Region != "" || filter.Cluster != "" {
+					return fmt.Errorf("--tag, --region, and --cluster only apply to --kind clusters")
+				}
+				if err := table.ValidateRoles(); err != nil {
+					return err
+				}
+				t = tableview.RoleTable(graphview.FilterRoles(st.Roles, filter), table)
+			default:
+				return fmt.Errorf("--kind must be one of %s|%s", exportClusters, exportRoles)
+			}
+			return t.Write(cmd.OutOrStdout(), format)
+		},
+	}
+	cmd.Flags().StringVar(&format, "format", tableview.FormatCSV, "Output format "+strings.Join(tableview.Formats, "|"))
+	cmd.Flags().StringVar(&kind, "kind", exportClusters, "What to export: clusters|roles")
+	cmd.Flags().StringSliceVar(&table.Columns, "fields", nil, "Fields to export, in order (default: the rift list columns, or "+strings.Join(tableview.DefaultRoleColumns, ",")+" for roles)")
+	cmd.Flags().StringSliceVar(&table.SortBy, "sort-by", nil, "Sort rows by these fields, in order")
+	cmd.Flags().StringSliceVar(&tags, "tag", nil, "Only export clusters with these tags (repeatable)")
+	cmd.Flags().StringVar(&filter.Env, "env", "", "Only export this env (prod|staging|dev|int|other or an env_rules env)")
+	cmd.Flags().StringVar(&filter.Account, "account", "", "Filter by account name, alias, or ID substring")
+	cmd.Flags().StringVar(&filter.Role, "role", "", "Filter by role name substring")
+	cmd.Flags().StringVar(&filter.Region, "region", "", "Filter clusters by region substring")
+	cmd.Flags().StringVar(&filter.Cluster, "cluster", "", "Filter clusters by name substring")
+	_ = cmd.RegisterFlagCompletionFunc("format", cobra.FixedCompletions(tableview.Formats, cobra.ShellCompDirectiveNoFileComp))
+	_ = cmd.RegisterFlagCompletionFunc("kind", cobra.FixedCompletions([]string{exportClusters, exportRoles}, cobra.ShellCompDirectiveNoFileComp))
+	_ = cmd.RegisterFlagCompletionFunc("fields", cobra.FixedCompletions(append(tableview.ColumnKeys(), tableview.RoleColumnKeys()...), cobra.ShellCompDirectiveNoFileComp))
+	_ = cmd.RegisterFlagCompletionFunc("tag", completeTags(app))
+	_ = cmd.RegisterFlagCompletionFunc("env", completeEnvs(app))
+	return cmd
+}
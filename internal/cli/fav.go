@@ -0,0 +1,118 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/phenixrizen/rift/internal/overlay"
+	"github.com/spf13/cobra"
+)
+
+func newFavCmd(app *App) *cobra.Command {
+	// Every argument is a context, so complete each one, not just the first.
+	completeEach := func(cmd *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return completeContexts(app)(cmd, nil, toComplete)
+	}
+	cmd := &cobra.Command{
+		Use:     "fav",
+		Aliases: []string{"favorite"},
+		Short:   "Star kube contexts as favorites",
+		Long: `Favorites are kube contexts marked with a star in rift ui (f toggles one, F
+shows only favorites) and in the favorite column of rift list. They are
+kept in overlay.yaml next to state.json, so sync never clears them.`,
+	}
+	cmd.AddCommand(
+		&cobra.Command{
+			Use:               "add <context>...",
+			Short:             "Mark contexts as favorites",
+			Args:              cobra.MinimumNArgs(1),
+			ValidArgsFunction: completeEach,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return updateFavorites(app, cmd, args, true)
+			},
+		},
+		&cobra.Command{
+			Use:               "remove <context>...",
+			Aliases:           []string{"rm"},
+			Short:             "Unmark favorite contexts",
+			Args:              cobra.MinimumNArgs(1),
+			ValidArgsFunction: completeEach,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return updateFavorites(app, cmd, args, false)
+			},
+		},
+		&cobra.Command{
+			Use:     "list",
+			Aliases: []string{"ls"},
+			Short:   "List favorite contexts",
+			RunE: func(cmd *cobra.Command, _ []string) error {
+				ov, err := app.loadOverlay()
+				if err != nil {
+					return err
+				}
+				if len(ov.Favorites) == 0 {
+					println(cmd.OutOrStdout(), "No favorites. Add one with: rift fav add <context>")
+					return nil
+				}
+				for _, context := range ov.Favorites {
+					fmt.Fprintln(cmd.OutOrStdout(), context)
+				}
+				return nil
+			},
+		},
+	)
+	return cmd
+}
+
+// updateFavorites adds or removes contexts; added contexts must exist in
+// state so a typo does not become a favorite that never shows up.
+func updateFavorites(app *App, cmd *cobra.Command, contexts []string, favorite bool) error {
+	if favorite {
+		st, err := app.loadState()
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return errStateNotFound
+			}
+			return err
+		}
+		known := make([]string, 0, len(st.Clusters))
+		for _, c := range st.Clusters {
+			known = append(known, c.KubeContext)
+		}
+		for _, context := range contexts {
+			if !slices.Contains(known, context) {
+				return fmt.Errorf("context %q is not in state (see: rift list)", context)
+			}
+		}
+	}
+	ov, err := app.loadOverlay()
+	if err != nil {
+		return err
+	}
+	out := cmd.OutOrStdout()
+	changed := false
+	for _, context := range contexts {
+		switch {
+		case ov.SetFavorite(context, favorite):
+			changed = true
+			if favorite {
+				fmt.Fprintf(out, "★ %s\n", context)
+			} else {
+				fmt.Fprintf(out, "removed %s\n", context)
+			}
+		case favorite:
+			fmt.Fprintf(out, "%s is already a favorite\n", context)
+		default:
+			fmt.Fprintf(out, "%s is not a favorite\n", context)
+		}
+	}
+	if !changed {
+		return nil
+	}
+	if err := overlay.Save(app.overlayPath(), ov); err != nil {
+		return fmt.Errorf("write overlay: %w", err)
+	}
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"slices"
 	"strings"
 
 	"github.com/phenixrizen/rift/internal/graphview"
@@ -18,12 +19,14 @@ func newGraphCmd(app *App) *cobra.Command {
 
 	cmd := &cobra.Command{
 		Use:   "graph",
-		Short: "Render discovered topology as ASCII or JSON graph",
+		Short: "Render discovered topology as ASCII, JSON, or an HTML page",
+		Example: `  rift graph --env prod --depth 3
+  rift graph --format html --depth 4 --namespaces > topology.html`,
 		RunE: func(cmd *cobra.Command, _ []string) error {
 			st, err := app.loadState()
 			if err != nil {
 				if errors.Is(err, os.ErrNotExist) {
-					return fmt.Errorf("state file not found; run: rift sync")
+					return errStateNotFound
 				}
 				return err
 			}
@@ -33,11 +36,12 @@ func newGraphCmd(app *App) *cobra.Command {
 			if opts.Env == "stg" {
 				opts.Env = "staging"
 			}
-			if opts.Env != "all" && opts.Env != "prod" && opts.Env != "staging" && opts.Env != "dev" && opts.Env != "int" && opts.Env != "other" {
-				return fmt.Errorf("--env must be one of prod|staging|dev|int|other|all")
+			envs := knownEnvs(st)
+			if opts.Env != "all" && !slices.Contains(envs, opts.Env) {
+				return fmt.Errorf("--env must be one of %s|all", strings.Join(envs, "|"))
 			}
-			if opts.Depth != 2 && opts.Depth != 3 && opts.Depth != 4 {
-				return fmt.Errorf("--depth must be one of 2|3|4")
+			if opts.Depth < 2 || opts.Depth > 5 {
+				return fmt.Errorf("--depth must be one of 2|3|4|5")
 			}
 
 			graph := graphview.Build(st, opts)
@@ -49,20 +53,29 @@ func newGraphCmd(app *App) *cobra.Command {
 				enc := json.NewEncoder(cmd.OutOrStdout())
 				enc.SetIndent("", "  ")
 				return enc.Encode(graph)
+			case "html":
+				page, err := graphview.RenderHTML(graph, "rift topology")
+				if err != nil {
+					return err
+				}
+				_, err = fmt.Fprint(cmd.OutOrStdout(), page)
+				return err
 			default:
-				return fmt.Errorf("invalid --format %q (expected ascii|json)", format)
+				return fmt.Errorf("invalid --format %q (expected ascii|json|html)", format)
 			}
 		},
 	}
 
-	cmd.Flags().StringVar(&opts.Env, "env", opts.Env, "Filter environment (prod|staging|dev|int|other|all)")
+	cmd.Flags().StringVar(&opts.Env, "env", opts.Env, "Filter environment (prod|staging|dev|int|other, any env_rules env, or all)")
 	cmd.Flags().StringVar(&opts.Account, "account", "", "Filter account by name or ID substring")
 	cmd.Flags().StringVar(&opts.Role, "role", "", "Filter role by substring")
 	cmd.Flags().StringVar(&opts.Region, "region", "", "Filter region")
 	cmd.Flags().StringVar(&opts.Cluster, "cluster", "", "Filter cluster by substring")
 	cmd.Flags().BoolVar(&opts.Namespaces, "namespaces", false, "Include namespaces layer when depth allows")
-	cmd.Flags().IntVar(&opts.Depth, "depth", opts.Depth, "Depth 2|3|4")
-	cmd.Flags().StringVar(&format, "format", "ascii", "Output format ascii|json")
-	cmd.Flags().IntVar(&maxWidth, "max-width", 120, "Maximum output width")
+	cmd.Flags().IntVar(&opts.Depth, "depth", opts.Depth, "Depth 2|3|4|5 (5 adds nodegroups, Fargate profiles, and namespace workload counts)")
+	cmd.Flags().StringVar(&format, "format", "ascii", "Output format ascii|json|html")
+	cmd.Flags().IntVar(&maxWidth, "max-width", 120, "Maximum output width (ascii)")
+	_ = cmd.RegisterFlagCompletionFunc("env", completeEnvs(app, "all"))
+	_ = cmd.RegisterFlagCompletionFunc("format", cobra.FixedCompletions([]string{"ascii", "json", "html"}, cobra.ShellCompDirectiveNoFileComp))
 	return cmd
 }
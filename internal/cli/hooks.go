@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"strings"
+
+	"github.com/phenixrizen/rift/internal/config"
+	"github.com/phenixrizen/rift/internal/hooks"
+	"github.com/phenixrizen/rift/internal/state"
+)
+
+// preSyncPayload is what the pre_sync hook reads on stdin.
+type preSyncPayload struct {
+	Incremental bool     `json:"incremental"`
+	Refresh     []string `json:"refresh,omitempty"`
+}
+
+// postUsePayload is what the post_use hook reads on stdin.
+type postUsePayload struct {
+	Context string `json:"context"`
+	// Namespace is the namespace set with the switch, else the context's
+	// default.
+	Namespace string `json:"namespace,omitempty"`
+	// Local is set for rift use --local, which switched only one shell.
+	Local   bool                `json:"local,omitempty"`
+	Cluster state.ClusterRecord `json:"cluster"`
+}
+
+// runHook runs hooks.<event> from cfg, if set, with payload on stdin. What
+// it prints is logged rather than written out: stdout may be JSON, a shell
+// eval, the MCP stream, or the TUI.
+func (a *App) runHook(ctx context.Context, cfg config.Config, event string, payload any, env ...string) error {
+	var command string
+	switch event {
+	case hooks.PreSync:
+		command = cfg.Hooks.PreSync
+	case hooks.PostSync:
+		command = cfg.Hooks.PostSync
+	case hooks.PostUse:
+		command = cfg.Hooks.PostUse
+	}
+	if command == "" {
+		return nil
+	}
+	var out bytes.Buffer
+	err := hooks.Hook{Event: event, Command: command, Timeout: cfg.Hooks.HookTimeout(), Env: env}.Run(ctx, payload, &out)
+	if output := strings.TrimSpace(out.String()); output != "" && a.Logger != nil {
+		a.Logger.Info("hook output", "hook", event, "output", output)
+	}
+	return err
+}
+
+// postUse runs the post_use hook after a switch to rec's context. A
+// failure is only logged: the switch already happened.
+func (a *App) postUse(rec state.ClusterRecord, namespace string, local bool) {
+	cfg, err := a.loadConfig()
+	if err != nil || cfg.Hooks.PostUse == "" {
+		return
+	}
+	if namespace == "" {
+		namespace = rec.Namespace
+	}
+	payload := postUsePayload{Context: rec.KubeContext, Namespace: namespace, Local: local, Cluster: rec}
+	if err := a.runHook(context.Background(), cfg, hooks.PostUse, payload, "RIFT_CONTEXT="+rec.KubeContext, "RIFT_NAMESPACE="+namespace); err != nil && a.Logger != nil {
+		a.Logger.Warn("post_use hook failed", "error", err)
+	}
+}
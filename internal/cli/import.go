@@ -0,0 +1,206 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"text/tabwriter"
+	"time"
+
+	"github.com/phenixrizen/rift/internal/config"
+	"github.com/phenixrizen/rift/internal/kubeconfig"
+	"github.com/phenixrizen/rift/internal/naming"
+	"github.com/phenixrizen/rift/internal/state"
+	"github.com/spf13/cobra"
+)
+
+// Import actions.
+const (
+	importAdded   = "imported"
+	importUpdated = "updated"
+	importRemoved = "removed"
+)
+
+type importAction struct {
+	Context string `json:"context"`
+	Action  string `json:"action"`
+	Server  string `json:"server,omitempty"`
+}
+
+func newImportCmd(app *App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "import",
+		Short: "Adopt clusters rift did not discover into state",
+	}
+	cmd.AddCommand(newImportKubeconfigCmd(app))
+	return cmd
+}
+
+func newImportKubeconfigCmd(app *App) *cobra.Command {
+	var matches []string
+	var dryRun bool
+	cmd := &cobra.Command{
+		Use:   "kubeconfig",
+		Short: "Adopt non-rift kubeconfig contexts as external clusters",
+		Long: `Reads the primary kubeconfig and records every context rift did not create
+(on-prem clusters, kind, other tools' contexts) in state as an external
+cluster, so it shows up in list, the TUI, and use. Rift never rewrites or
+deletes these contexts; sync keeps the records as they are.
+
+Run it again to pick up new contexts. Imported contexts that are no longer in
+the kubeconfig are dropped from state.`,
+		Example: `  rift import kubeconfig
+  rift import kubeconfig --match 'kind-*' --match 'onprem-*'
+  rift import kubeconfig --dry-run`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			for _, m := range matches {
+				if _, err := path.Match(m, ""); err != nil {
+					return fmt.Errorf("invalid --match pattern %q: %w", m, err)
+				}
+			}
+			cfg, err := app.loadConfig()
+			if err != nil {
+				return err
+			}
+			st, err := app.loadState()
+			if err != nil {
+				if !errors.Is(err, os.ErrNotExist) {
+					return err
+				}
+				st = state.State{GeneratedAt: time.Now().UTC()}
+			}
+			kubeConfigPath, _, err := app.primaryKubeConfig()
+			if err != nil {
+				return err
+			}
+			foreign, err := kubeconfig.ForeignContexts(kubeConfigPath, st)
+			if err != nil {
+				return fmt.Errorf("read kubeconfig: %w", err)
+			}
+
+			actions := importExternal(cfg, &st, foreign, matches)
+			if ov, err := app.loadOverlay(); err == nil {
+				ov.Apply(&st)
+			}
+
+			out := cmd.OutOrStdout()
+			if app.Output == "json" {
+				enc := json.NewEncoder(out)
+				enc.SetIndent("", "  ")
+				if err := enc.Encode(actions); err != nil {
+					return err
+				}
+			} else if len(actions) == 0 {
+				println(out, "No new or changed non-rift contexts in "+kubeConfigPath+".")
+			} else {
+				w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+				fmt.Fprintln(w, "Context\tAction\tServer")
+				for _, a := range actions {
+					fmt.Fprintf(w, "%s\t%s\t%s\n", a.Context, a.Action, a.Server)
+				}
+				_ = w.Flush()
+			}
+
+			if dryRun {
+				if app.Output != "json" {
+					println(out, "Dry run complete (state not written)")
+				}
+				return nil
+			}
+			if len(actions) == 0 {
+				return nil
+			}
+			if err := app.saveState(st); err != nil {
+				return fmt.Errorf("write state: %w", err)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringArrayVar(&matches, "match", nil, "Only import contexts whose name matches this glob (repeatable)")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show what would be imported without writing state")
+	return cmd
+}
+
+// importExternal adds or refreshes an external record in st for each
+// foreign context matching matches (all when empty), and drops imported
+// records whose context is gone from the kubeconfig.
+func importExternal(cfg config.Config, st *state.State, foreign []kubeconfig.ForeignContext, matches []string) []importAction {
+	present := make(map[string]kubeconfig.ForeignContext, len(foreign))
+	for _, fc := range foreign {
+		present[fc.Name] = fc
+	}
+
+	actions := make([]importAction, 0)
+	imported := map[string]int{}
+	kept := st.Clusters[:0]
+	for _, c := range st.Clusters {
+		if c.External() {
+			if _, ok := present[c.KubeContext]; !ok {
+				actions = append(actions, importAction{Context: c.KubeContext, Action: importRemoved, Server: c.ClusterEndpoint})
+				continue
+			}
+			imported[c.KubeContext] = len(kept)
+		}
+		kept = append(kept, c)
+	}
+	st.Clusters = kept
+
+	for _, fc := range foreign {
+		if !matchesAnyGlob(fc.Name, matches) {
+			continue
+		}
+		rec := externalRecord(cfg, fc)
+		idx, ok := imported[fc.Name]
+		if !ok {
+			st.Clusters = append(st.Clusters, rec)
+			actions = append(actions, importAction{Context: fc.Name, Action: importAdded, Server: fc.Server})
+			continue
+		}
+		prev := st.Clusters[idx]
+		if prev.ClusterName == rec.ClusterName && prev.ClusterEndpoint == rec.ClusterEndpoint && prev.Namespace == rec.Namespace && prev.Env == rec.Env {
+			continue
+		}
+		rec.Tags = prev.Tags
+		st.Clusters[idx] = rec
+		actions = append(actions, importAction{Context: fc.Name, Action: importUpdated, Server: fc.Server})
+	}
+	st.Normalize()
+	return actions
+}
+
+// externalRecord describes a foreign context as a cluster: the context name
+// is kept as-is and its env is classified from it like a cluster name.
+func externalRecord(cfg config.Config, fc kubeconfig.ForeignContext) state.ClusterRecord {
+	clusterName := fc.Cluster
+	if clusterName == "" {
+		clusterName = fc.Name
+	}
+	rec := state.ClusterRecord{
+		Env:             naming.ClassifyEnv(cfg, "", "", fc.Name).Env,
+		AccountName:     state.PlatformExternal,
+		ClusterName:     clusterName,
+		ClusterEndpoint: fc.Server,
+		Platform:        state.PlatformExternal,
+		KubeContext:     fc.Name,
+		Namespace:       fc.Namespace,
+	}
+	if fc.Namespace != "" {
+		rec.Namespaces = []string{fc.Namespace}
+	}
+	return rec
+}
+
+func matchesAnyGlob(name string, patterns []string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
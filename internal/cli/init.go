@@ -8,12 +8,20 @@ import (
 	"strings"
 	"time"
 
+	"github.com/phenixrizen/rift/internal/awsconfig"
 	"github.com/phenixrizen/rift/internal/config"
 	"github.com/phenixrizen/rift/internal/discovery"
 	"github.com/spf13/cobra"
 )
 
 func newInitCmd(app *App) *cobra.Command {
+	var fromAWSConfig bool
+	var ssoSession string
+	var startURLFlag string
+	var ssoRegionFlag string
+	var regionsFlag []string
+	var yes bool
+
 	cmd := &cobra.Command{
 		Use:   "init",
 		Short: "Interactively initialize Rift config",
@@ -22,29 +30,75 @@ func newInitCmd(app *App) *cobra.Command {
 			if cfg, err := app.loadConfig(); err == nil {
 				defaults = cfg
 			}
-			if defaults.SSORegion == "" {
-				defaults.SSORegion = "us-east-1"
+
+			if fromAWSConfig {
+				awsConfigPath, err := defaultAWSConfigPath()
+				if err != nil {
+					return err
+				}
+				imported, err := awsconfig.ImportSSO(awsConfigPath, ssoSession)
+				if err != nil {
+					return fmt.Errorf("import aws config: %w", err)
+				}
+				defaults.SSOStartURL = imported.SSOStartURL
+				defaults.SSORegion = imported.SSORegion
+				if len(imported.Regions) > 0 {
+					defaults.Regions = imported.Regions
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "Imported SSO settings from [%s] in %s\n", imported.Source, awsConfigPath)
 			}
 
-			reader := bufio.NewReader(cmd.InOrStdin())
-			startURL, err := prompt(reader, cmd.OutOrStdout(), "SSO start URL", defaults.SSOStartURL)
-			if err != nil {
-				return err
+			flags := cmd.Flags()
+			if flags.Changed("sso-start-url") {
+				defaults.SSOStartURL = strings.TrimSpace(startURLFlag)
 			}
-			ssoRegion, err := prompt(reader, cmd.OutOrStdout(), "SSO region", defaults.SSORegion)
-			if err != nil {
-				return err
+			if flags.Changed("sso-region") {
+				defaults.SSORegion = strings.TrimSpace(strings.ToLower(ssoRegionFlag))
+			}
+			if flags.Changed("regions") {
+				defaults.Regions = regionsFlag
+			}
+
+			if !yes && !fromAWSConfig {
+				if defaults.SSORegion == "" {
+					defaults.SSORegion = "us-east-1"
+				}
+				reader := bufio.NewReader(cmd.InOrStdin())
+				if !flags.Changed("sso-start-url") {
+					startURL, err := prompt(reader, cmd.OutOrStdout(), "SSO start URL", defaults.SSOStartURL)
+					if err != nil {
+						return err
+					}
+					defaults.SSOStartURL = strings.TrimSpace(startURL)
+				}
+				if !flags.Changed("sso-region") {
+					ssoRegion, err := prompt(reader, cmd.OutOrStdout(), "SSO region", defaults.SSORegion)
+					if err != nil {
+						return err
+					}
+					defaults.SSORegion = strings.TrimSpace(strings.ToLower(ssoRegion))
+				}
 			}
 
-			defaults.SSOStartURL = strings.TrimSpace(startURL)
-			defaults.SSORegion = strings.TrimSpace(strings.ToLower(ssoRegion))
+			if yes {
+				missing := make([]string, 0, 2)
+				if strings.TrimSpace(defaults.SSOStartURL) == "" {
+					missing = append(missing, "--sso-start-url")
+				}
+				if strings.TrimSpace(defaults.SSORegion) == "" {
+					missing = append(missing, "--sso-region")
+				}
+				if len(missing) > 0 {
+					return fmt.Errorf("missing required %s (no existing config to fall back on with --yes)", strings.Join(missing, ", "))
+				}
+			}
 
 			if err := config.Save(app.ConfigPath, defaults); err != nil {
 				return err
 			}
 
 			fmt.Fprintf(cmd.OutOrStdout(), "Wrote config: %s\n", app.ConfigPath)
-			err = discovery.ValidateSSOLogin(defaults, time.Now().UTC())
+			err := discovery.ValidateSSOLogin(defaults, time.Now().UTC())
 			if err == nil {
 				println(cmd.OutOrStdout(), "SSO token is present.", "Initialization complete.")
 				return nil
@@ -56,6 +110,12 @@ func newInitCmd(app *App) *cobra.Command {
 			return err
 		},
 	}
+	cmd.Flags().BoolVar(&fromAWSConfig, "from-aws-config", false, "Import SSO start URL, region, and regions from ~/.aws/config without prompting")
+	cmd.Flags().StringVar(&ssoSession, "sso-session", "", "sso-session or profile to import when ~/.aws/config has several")
+	cmd.Flags().StringVar(&startURLFlag, "sso-start-url", "", "SSO start URL (skips the prompt)")
+	cmd.Flags().StringVar(&ssoRegionFlag, "sso-region", "", "SSO region (skips the prompt)")
+	cmd.Flags().StringSliceVar(&regionsFlag, "regions", nil, "EKS regions to scan (comma-separated)")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Never prompt; fail if required values are missing")
 	return cmd
 }
 
@@ -0,0 +1,147 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/phenixrizen/rift/internal/config"
+	"github.com/phenixrizen/rift/internal/kubeconfig"
+	"github.com/phenixrizen/rift/internal/rifterr"
+	"github.com/spf13/cobra"
+)
+
+type kubeconfigPaths struct {
+	Layout string   `json:"layout"`
+	Paths  []string `json:"paths"`
+	Index  string   `json:"index,omitempty"`
+}
+
+func newKubeconfigCmd(app *App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "kubeconfig",
+		Short: "Inspect the kubeconfig files rift writes",
+	}
+	cmd.AddCommand(newKubeconfigPathCmd(app))
+	return cmd
+}
+
+func newKubeconfigPathCmd(app *App) *cobra.Command {
+	var shell string
+	cmd := &cobra.Command{
+		Use:   "path",
+		Short: "Print the kubeconfig files rift writes, or a KUBECONFIG snippet",
+		Long: `Prints the kubeconfig files sync writes for the configured kubeconfig_layout,
+one per line.
+
+With the dedicated or split layout rift leaves ~/.kube/config alone, and
+--shell prints a line for your shell profile that appends rift's contexts to
+KUBECONFIG (or to ~/.kube/config when KUBECONFIG is unset):
+
+  eval "$(rift kubeconfig path --shell bash)"                   # bash/zsh
+  rift kubeconfig path --shell fish | source                     # fish
+  rift kubeconfig path --shell powershell | Invoke-Expression`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			switch shell {
+			case "", "bash", "zsh", "fish", "powershell":
+			default:
+				return fmt.Errorf("--shell must be one of bash|zsh|fish|powershell")
+			}
+			cfg, err := app.loadConfig()
+			if err != nil {
+				return err
+			}
+			paths, err := app.riftKubeconfigPaths(cfg)
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			if shell != "" {
+				if !cfg.SeparateKubeconfig() {
+					return rifterr.New(rifterr.CodeConfigInvalid,
+						fmt.Sprintf("kubeconfig_layout is merged: rift writes its contexts into %s directly", strings.Join(paths.Paths, ", ")),
+						"set kubeconfig_layout: dedicated (or split) to keep them in a separate file")
+				}
+				printKubeconfigSnippet(out, shell, paths)
+				return nil
+			}
+			if app.Output == "json" {
+				enc := json.NewEncoder(out)
+				enc.SetIndent("", "  ")
+				return enc.Encode(paths)
+			}
+			println(out, paths.Paths...)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&shell, "shell", "", "Print a KUBECONFIG export instead: bash|zsh|fish|powershell")
+	_ = cmd.RegisterFlagCompletionFunc("shell", cobra.FixedCompletions([]string{"bash", "zsh", "fish", "powershell"}, cobra.ShellCompDirectiveNoFileComp))
+	return cmd
+}
+
+// riftKubeconfigPaths lists the files sync writes under cfg's layout. The
+// split layout lists the files from its index.
+func (a *App) riftKubeconfigPaths(cfg config.Config) (kubeconfigPaths, error) {
+	result := kubeconfigPaths{Layout: cfg.Layout(), Paths: []string{}}
+	files, splitDir, err := a.kubeconfigOutputs(cfg)
+	if err != nil {
+		return result, err
+	}
+	if splitDir == "" {
+		result.Paths = files
+		return result, nil
+	}
+	indexed, err := kubeconfig.ReadIndex(splitDir)
+	if err != nil {
+		return result, fmt.Errorf("read kubeconfig index: %w", err)
+	}
+	if indexed != nil {
+		result.Paths = indexed
+	}
+	result.Index = filepath.Join(splitDir, kubeconfig.IndexFile)
+	return result, nil
+}
+
+// printKubeconfigSnippet appends rift's files to KUBECONFIG. The split
+// layout reads its index when the line runs, so clusters added by later
+// syncs are picked up by new shells.
+func printKubeconfigSnippet(out io.Writer, shell string, paths kubeconfigPaths) {
+	switch shell {
+	case "fish":
+		fmt.Fprintln(out, "set -q KUBECONFIG; or set -gx KUBECONFIG $HOME/.kube/config;")
+		if paths.Index != "" {
+			fmt.Fprintf(out, "set -gx KUBECONFIG $KUBECONFIG:(cat %s);\n", fishQuote(paths.Index))
+		} else {
+			fmt.Fprintf(out, "set -gx KUBECONFIG $KUBECONFIG:%s;\n", fishQuote(paths.Paths[0]))
+		}
+	case "powershell":
+		fmt.Fprintln(out, "if (-not $env:KUBECONFIG) { $env:KUBECONFIG = Join-Path $HOME '.kube/config' }")
+		if paths.Index != "" {
+			fmt.Fprintf(out, "$env:KUBECONFIG += [IO.Path]::PathSeparator + (Get-Content %s -Raw).Trim()\n", powershellQuote(paths.Index))
+		} else {
+			fmt.Fprintf(out, "$env:KUBECONFIG += [IO.Path]::PathSeparator + %s\n", powershellQuote(paths.Paths[0]))
+		}
+	default:
+		if paths.Index != "" {
+			fmt.Fprintf(out, "export KUBECONFIG=\"${KUBECONFIG:-$HOME/.kube/config}:$(cat %s)\"\n", shellQuote(paths.Index))
+		} else {
+			fmt.Fprintf(out, "export KUBECONFIG=\"${KUBECONFIG:-$HOME/.kube/config}:\"%s\n", shellQuote(paths.Paths[0]))
+		}
+	}
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, `'`, `'\''`) + "'"
+}
+
+func fishQuote(s string) string {
+	return "'" + strings.ReplaceAll(strings.ReplaceAll(s, `\`, `\\`), `'`, `\'`) + "'"
+}
+
+func powershellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, `'`, `''`) + "'"
+}
@@ -1,33 +1,101 @@
 package cli
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
+	"slices"
+	"strings"
 
+	"github.com/phenixrizen/rift/internal/graphview"
+	"github.com/phenixrizen/rift/internal/state"
 	"github.com/phenixrizen/rift/internal/tableview"
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
 )
 
 func newListCmd(app *App) *cobra.Command {
+	var tags []string
+	var filter graphview.Options
+	var table tableview.Options
+	var wide, favorites bool
 	cmd := &cobra.Command{
-		Use:   "list",
-		Short: "List known Rift contexts",
+		Use:         "list",
+		Short:       "List known Rift contexts",
+		Annotations: map[string]string{annotationYAMLOutput: "true"},
 		RunE: func(cmd *cobra.Command, _ []string) error {
 			st, err := app.loadState()
 			if err != nil {
 				if errors.Is(err, os.ErrNotExist) {
-					return fmt.Errorf("state file not found; run: rift sync")
+					return errStateNotFound
 				}
 				return err
 			}
+			if filter.Env == "stg" {
+				filter.Env = "staging"
+			}
+			if envs := knownEnvs(st); filter.Env != "" && filter.Env != "all" && !slices.Contains(envs, filter.Env) {
+				return fmt.Errorf("--env must be one of %s|all", strings.Join(envs, "|"))
+			}
+			if wide {
+				table.Columns = tableview.WideColumns
+			}
+			if err := table.Validate(); err != nil {
+				return err
+			}
+			rows := filterByTags(graphview.FilterClusters(st.Clusters, filter), tags)
+			if favorites {
+				rows = slices.DeleteFunc(rows, func(c state.ClusterRecord) bool { return !c.Favorite })
+			}
+			switch app.Output {
+			case "json":
+				// Full records, not the table columns.
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				return enc.Encode(nonNilClusters(rows))
+			case "yaml":
+				enc := yaml.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent(2)
+				if err := enc.Encode(nonNilClusters(rows)); err != nil {
+					return err
+				}
+				return enc.Close()
+			}
 			if len(st.Clusters) == 0 {
 				println(cmd.OutOrStdout(), "No clusters discovered.", "Run: rift sync")
 				return nil
 			}
-			fmt.Fprint(cmd.OutOrStdout(), tableview.RenderClusters(st.Clusters))
+			if len(rows) == 0 {
+				println(cmd.OutOrStdout(), "No clusters match the given filters.")
+				return nil
+			}
+			fmt.Fprint(cmd.OutOrStdout(), tableview.RenderClusters(rows, table))
 			return nil
 		},
 	}
+	cmd.Flags().StringSliceVar(&tags, "tag", nil, "Only show contexts with these tags (repeatable)")
+	cmd.Flags().StringVar(&filter.Env, "env", "", "Only show this env (prod|staging|dev|int|other or an env_rules env)")
+	cmd.Flags().StringVar(&filter.Account, "account", "", "Filter by account name, alias, or ID substring")
+	cmd.Flags().StringVar(&filter.Role, "role", "", "Filter by role name substring")
+	cmd.Flags().StringVar(&filter.Region, "region", "", "Filter by region substring")
+	cmd.Flags().StringVar(&filter.Cluster, "cluster", "", "Filter by cluster name substring")
+	cmd.Flags().BoolVar(&favorites, "favorites", false, "Only show favorite contexts (see: rift fav)")
+	cmd.Flags().StringSliceVar(&table.Columns, "columns", nil, "Columns to print, in order (default "+strings.Join(tableview.DefaultColumns, ",")+"; available: "+strings.Join(tableview.ColumnKeys(), ",")+")")
+	cmd.Flags().StringSliceVar(&table.SortBy, "sort-by", nil, "Sort rows by these columns, in order")
+	cmd.Flags().BoolVar(&wide, "wide", false, "Add Kubernetes version, status, platform version, and creation date columns")
+	cmd.MarkFlagsMutuallyExclusive("wide", "columns")
+	_ = cmd.RegisterFlagCompletionFunc("tag", completeTags(app))
+	_ = cmd.RegisterFlagCompletionFunc("columns", cobra.FixedCompletions(tableview.ColumnKeys(), cobra.ShellCompDirectiveNoFileComp))
+	_ = cmd.RegisterFlagCompletionFunc("sort-by", cobra.FixedCompletions(tableview.ColumnKeys(), cobra.ShellCompDirectiveNoFileComp))
+	_ = cmd.RegisterFlagCompletionFunc("env", completeEnvs(app))
 	return cmd
 }
+
+// nonNilClusters makes an empty result encode as [] rather than null.
+func nonNilClusters(rows []state.ClusterRecord) []state.ClusterRecord {
+	if rows == nil {
+		return []state.ClusterRecord{}
+	}
+	return rows
+}
@@ -0,0 +1,252 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+
+	"github.com/phenixrizen/rift/internal/graphview"
+	"github.com/phenixrizen/rift/internal/mcp"
+	"github.com/phenixrizen/rift/internal/query"
+	"github.com/phenixrizen/rift/internal/state"
+	"github.com/phenixrizen/rift/internal/version"
+	"github.com/spf13/cobra"
+)
+
+// mcpContext is how the MCP tools describe a context: what an assistant
+// needs to pick one, without endpoints or certificates.
+type mcpContext struct {
+	Context   string   `json:"context"`
+	Current   bool     `json:"current,omitempty"`
+	Cluster   string   `json:"cluster"`
+	Account   string   `json:"account"`
+	AccountID string   `json:"account_id,omitempty"`
+	Role      string   `json:"role,omitempty"`
+	Env       string   `json:"env"`
+	Region    string   `json:"region"`
+	Platform  string   `json:"platform"`
+	Namespace string   `json:"namespace,omitempty"`
+	Tags      []string `json:"tags,omitempty"`
+	Favorite  bool     `json:"favorite,omitempty"`
+}
+
+// mcpNamespace is one namespace in get_namespaces.
+type mcpNamespace struct {
+	Name      string `json:"name"`
+	Workloads string `json:"workloads,omitempty"`
+	Access    *bool  `json:"access,omitempty"`
+}
+
+func newMCPCmd(app *App) *cobra.Command {
+	var readOnly bool
+	cmd := &cobra.Command{
+		Use:   "mcp",
+		Short: "Serve the inventory to AI assistants over MCP (stdio)",
+		Long: `Runs a Model Context Protocol server on stdin/stdout so an AI coding
+assistant can query the rift inventory and switch kubectl contexts. Tools:
+
+  list_clusters     contexts in state, filtered like rift list
+  search_contexts   contexts matching a rift search query
+  get_namespaces    a context's recorded namespaces, workloads, and access
+  switch_context    kubectl config use-context, as rift use (not with --read-only)
+
+Tools only read state.json: nothing calls AWS or a cluster, and no
+credentials, endpoints, or certificates are returned. switch_context takes
+an exact context name from state, never a fuzzy match. Register it with
+your assistant as the command "rift mcp".`,
+		Example: `  rift mcp
+  rift mcp --read-only`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			server := &mcp.Server{
+				Name:         "rift",
+				Version:      version.ResolveCommit(),
+				Instructions: "rift knows the Kubernetes contexts discovered from AWS SSO (and GKE/AKS). Use search_contexts or list_clusters to find a context before switch_context.",
+				Tools:        mcpTools(app, readOnly),
+			}
+			return server.Serve(ctx, cmd.InOrStdin(), cmd.OutOrStdout())
+		},
+	}
+	cmd.Flags().BoolVar(&readOnly, "read-only", false, "Leave out switch_context")
+	return cmd
+}
+
+func mcpTools(app *App, readOnly bool) []mcp.Tool {
+	str := func(desc string) map[string]any { return map[string]any{"type": "string", "description": desc} }
+	object := func(props map[string]any, required ...string) map[string]any {
+		schema := map[string]any{"type": "object", "properties": props, "additionalProperties": false}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+		return schema
+	}
+	tools := []mcp.Tool{
+		{
+			Name:        "list_clusters",
+			Description: "List the kube contexts rift discovered, optionally filtered. Filters other than env and tag are case-insensitive substrings.",
+			InputSchema: object(map[string]any{
+				"env":     str("Environment, e.g. prod, staging, dev"),
+				"account": str("Account name, alias, or ID"),
+				"role":    str("SSO role name"),
+				"region":  str("Cloud region"),
+				"cluster": str("Cluster name"),
+				"tag":     str("User tag"),
+			}),
+			Call: func(_ context.Context, raw json.RawMessage) (any, error) {
+				var args struct {
+					Env     string `json:"env"`
+					Account string `json:"account"`
+					Role    string `json:"role"`
+					Region  string `json:"region"`
+					Cluster string `json:"cluster"`
+					Tag     string `json:"tag"`
+				}
+				if err := mcp.Args(raw, &args); err != nil {
+					return nil, err
+				}
+				st, err := mcpState(app)
+				if err != nil {
+					return nil, err
+				}
+				rows := graphview.FilterClusters(st.Clusters, graphview.Options{Env: args.Env, Account: args.Account, Role: args.Role, Region: args.Region, Cluster: args.Cluster})
+				if args.Tag != "" {
+					rows = filterByTags(rows, []string{args.Tag})
+				}
+				return mcpContexts(app, rows), nil
+			},
+		},
+		{
+			Name:        "search_contexts",
+			Description: "Find kube contexts with the rift search language: key:value filters (" + strings.Join(query.Keys, ", ") + ") plus fuzzy free text, best match first.",
+			InputSchema: object(map[string]any{"query": str("e.g. \"env:prod region:us-east-1 payments\"")}, "query"),
+			Call: func(_ context.Context, raw json.RawMessage) (any, error) {
+				var args struct {
+					Query string `json:"query"`
+				}
+				if err := mcp.Args(raw, &args); err != nil {
+					return nil, err
+				}
+				q, err := query.Parse(args.Query)
+				if err != nil {
+					return nil, err
+				}
+				st, err := mcpState(app)
+				if err != nil {
+					return nil, err
+				}
+				return mcpContexts(app, searchClusters(st.Clusters, q)), nil
+			},
+		},
+		{
+			Name:        "get_namespaces",
+			Description: "The namespaces the last sync recorded for a kube context, with workload counts and access when they were discovered.",
+			InputSchema: object(map[string]any{"context": str("Exact kube context name")}, "context"),
+			Call: func(_ context.Context, raw json.RawMessage) (any, error) {
+				var args struct {
+					Context string `json:"context"`
+				}
+				if err := mcp.Args(raw, &args); err != nil {
+					return nil, err
+				}
+				rec, err := mcpFind(app, args.Context)
+				if err != nil {
+					return nil, err
+				}
+				names := slices.Clone(rec.Namespaces)
+				if rec.Namespace != "" && !slices.Contains(names, rec.Namespace) {
+					names = append(names, rec.Namespace)
+					slices.Sort(names)
+				}
+				out := make([]mcpNamespace, 0, len(names))
+				for _, name := range names {
+					ns := mcpNamespace{Name: name}
+					if w, ok := rec.WorkloadsIn(name); ok {
+						ns.Workloads = w.Label()
+					}
+					if allowed, checked := rec.AccessTo(name); checked {
+						ns.Access = &allowed
+					}
+					out = append(out, ns)
+				}
+				return map[string]any{"context": rec.KubeContext, "default": rec.Namespace, "namespaces": out}, nil
+			},
+		},
+	}
+	if readOnly {
+		return tools
+	}
+	return append(tools, mcp.Tool{
+		Name:        "switch_context",
+		Description: "Make a kube context kubectl's current context, optionally setting its default namespace first. Changes what kubectl commands act on.",
+		InputSchema: object(map[string]any{
+			"context":   str("Exact kube context name from list_clusters or search_contexts"),
+			"namespace": str("Default namespace to set; must be one the last sync recorded"),
+		}, "context"),
+		Call: func(_ context.Context, raw json.RawMessage) (any, error) {
+			var args struct {
+				Context   string `json:"context"`
+				Namespace string `json:"namespace"`
+			}
+			if err := mcp.Args(raw, &args); err != nil {
+				return nil, err
+			}
+			rec, err := mcpFind(app, args.Context)
+			if err != nil {
+				return nil, err
+			}
+			// kubectl's output must not reach stdout, the protocol stream.
+			return switchContextQuietly(app, rec, args.Namespace)
+		},
+	})
+}
+
+func mcpState(app *App) (state.State, error) {
+	st, err := app.loadState()
+	if errors.Is(err, os.ErrNotExist) {
+		return st, errStateNotFound
+	}
+	return st, err
+}
+
+// mcpFind returns the cluster whose context is exactly name.
+func mcpFind(app *App, name string) (state.ClusterRecord, error) {
+	st, err := mcpState(app)
+	if err != nil {
+		return state.ClusterRecord{}, err
+	}
+	i := slices.IndexFunc(st.Clusters, func(c state.ClusterRecord) bool { return c.KubeContext == name })
+	if i < 0 {
+		return state.ClusterRecord{}, fmt.Errorf("no context named %q in rift state; find one with search_contexts", name)
+	}
+	return st.Clusters[i], nil
+}
+
+func mcpContexts(app *App, rows []state.ClusterRecord) []mcpContext {
+	current := app.currentContext()
+	out := make([]mcpContext, 0, len(rows))
+	for _, rec := range rows {
+		out = append(out, mcpContext{
+			Context:   rec.KubeContext,
+			Current:   rec.KubeContext == current,
+			Cluster:   rec.ClusterName,
+			Account:   rec.AccountLabel(),
+			AccountID: rec.AccountID,
+			Role:      rec.RoleName,
+			Env:       rec.Env,
+			Region:    rec.Region,
+			Platform:  rec.PlatformLabel(),
+			Namespace: rec.Namespace,
+			Tags:      rec.Tags,
+			Favorite:  rec.Favorite,
+		})
+	}
+	return out
+}
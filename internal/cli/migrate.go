@@ -0,0 +1,100 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/phenixrizen/rift/internal/backup"
+	"github.com/phenixrizen/rift/internal/config"
+	"github.com/phenixrizen/rift/internal/kubeconfig"
+	"github.com/phenixrizen/rift/internal/state"
+	"github.com/spf13/cobra"
+)
+
+func newMigrateCmd(app *App) *cobra.Command {
+	var dryRun bool
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Replace aws eks update-kubeconfig/eksctl contexts with rift contexts",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			st, err := app.loadState()
+			if err != nil {
+				if errors.Is(err, os.ErrNotExist) {
+					return errStateNotFound
+				}
+				return err
+			}
+			kubeConfigPath, _, err := app.primaryKubeConfig()
+			if err != nil {
+				return err
+			}
+
+			var bk *backup.Backup
+			if !dryRun {
+				// Migrate works without a config; backups then use defaults.
+				cfg, err := config.Load(app.ConfigPath)
+				if err != nil {
+					cfg = config.Config{}
+				}
+				bk, err = app.takeBackup(cfg, "migrate", []backup.File{{Path: kubeConfigPath}, {Path: app.StatePath}})
+				if err != nil {
+					return fmt.Errorf("back up kubeconfig: %w", err)
+				}
+			}
+			result, err := kubeconfig.Migrate(kubeConfigPath, st, dryRun)
+			if err != nil {
+				return fmt.Errorf("migrate kubeconfig: %w", err)
+			}
+			if !result.Changed() {
+				app.dropBackup(bk)
+				bk = nil
+			}
+			out := cmd.OutOrStdout()
+			if len(result.Actions) == 0 {
+				println(out, "No aws eks update-kubeconfig or eksctl contexts found.")
+				return nil
+			}
+
+			w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "Legacy Context\tAction\tRift Context\tAdopted Namespace")
+			for _, action := range result.Actions {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", action.LegacyContext, action.Action, action.RiftContext, action.Namespace)
+			}
+			_ = w.Flush()
+
+			if dryRun {
+				println(out, "Dry run complete (no files written)")
+				return nil
+			}
+			if len(result.Adopted) > 0 {
+				pinNamespaces(&st, result.Adopted)
+				if err := app.saveState(st); err != nil {
+					return fmt.Errorf("write state: %w", err)
+				}
+			}
+			fmt.Fprintf(out, "Kubeconfig written: %s\n", kubeConfigPath)
+			if bk != nil {
+				fmt.Fprintf(out, "Backup: %s (undo with: rift restore)\n", bk.ID)
+			}
+			if _, ok := st.KubeContexts()[result.CurrentContext]; ok {
+				fmt.Fprintf(out, "Current context: %s\n", result.CurrentContext)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Preview changes without writing files")
+	return cmd
+}
+
+func pinNamespaces(st *state.State, namespaces map[string]string) {
+	for i := range st.Clusters {
+		ns, ok := namespaces[st.Clusters[i].KubeContext]
+		if !ok {
+			continue
+		}
+		st.Clusters[i].Namespace = ns
+		st.Clusters[i].NamespacePinned = true
+	}
+}
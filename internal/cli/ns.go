@@ -0,0 +1,157 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/lithammer/fuzzysearch/fuzzy"
+	"github.com/phenixrizen/rift/internal/kubeconfig"
+	"github.com/phenixrizen/rift/internal/namespaces"
+	"github.com/phenixrizen/rift/internal/state"
+	"github.com/spf13/cobra"
+)
+
+// nsLookupTimeout bounds rift ns's live namespace lookup.
+const nsLookupTimeout = 20 * time.Second
+
+func newNSCmd(app *App) *cobra.Command {
+	var live bool
+	cmd := &cobra.Command{
+		Use:   "ns [filter]",
+		Short: "List or switch the current context's default namespace",
+		Long: `Without a filter, ns lists the namespaces of kubectl's current context, the
+context's default marked with *. With a filter, it fuzzy-matches one of
+them and makes it the context's default namespace in kubeconfig (as kubectl
+config set-context --namespace does, so sync keeps it).
+
+The namespaces are the ones the last sync recorded. When there are none,
+or with --live, they are looked up on the cluster and saved to state.`,
+		Example: `  rift ns
+  rift ns kafka
+  rift ns --live`,
+		Args:              cobra.MaximumNArgs(1),
+		ValidArgsFunction: completeCurrentNamespaces(app),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := app.loadConfig()
+			if err != nil {
+				return err
+			}
+			st, err := app.loadState()
+			if err != nil {
+				if errors.Is(err, os.ErrNotExist) {
+					return errStateNotFound
+				}
+				return err
+			}
+			current := app.currentContext()
+			if current == "" {
+				return fmt.Errorf("kubectl has no current context; switch with rift use first")
+			}
+			i := slices.IndexFunc(st.Clusters, func(c state.ClusterRecord) bool { return c.KubeContext == current })
+			if i < 0 {
+				return fmt.Errorf("current context %s is not in rift state", current)
+			}
+			rec := st.Clusters[i]
+			names := rec.Namespaces
+			if live || len(names) == 0 {
+				if names, err = app.lookupNamespaces(cmd.Context(), &st, i, namespaceFilter(cfg)); err != nil {
+					return err
+				}
+			}
+			if len(names) == 0 {
+				return fmt.Errorf("no namespaces found in %s", current)
+			}
+			active, err := kubeconfig.ContextNamespace(app.kubectlKubeconfig(), current)
+			if err != nil {
+				return err
+			}
+			if active == "" {
+				active = "default"
+			}
+
+			if len(args) == 0 {
+				for _, ns := range names {
+					mark := " "
+					if ns == active {
+						mark = "*"
+					}
+					fmt.Fprintf(cmd.OutOrStdout(), "%s %s\n", mark, ns)
+				}
+				return nil
+			}
+			ranks := fuzzy.RankFindNormalizedFold(args[0], names)
+			if len(ranks) == 0 {
+				return fmt.Errorf("no namespace in %s matches %q", current, args[0])
+			}
+			sort.Sort(ranks)
+			selected, err := pickTarget(cmd.OutOrStdout(), cmd.InOrStdin(), "namespaces", args[0], ranks, func(ns string) string {
+				if ns == active {
+					return current + ", current default"
+				}
+				return current
+			})
+			if err != nil {
+				if errors.Is(err, errSelectionCancelled) {
+					fmt.Fprintln(cmd.OutOrStdout(), "Selection cancelled.")
+					return nil
+				}
+				return err
+			}
+			if err := runKubectl(cmd, app, "config", "set-context", current, "--namespace="+selected); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Default namespace of %s: %s\n", current, selected)
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&live, "live", false, "Look the namespaces up on the cluster even when state has them")
+	return cmd
+}
+
+// lookupNamespaces lists the namespaces of st.Clusters[i] on the cluster
+// that filter keeps (plus the context's default) and saves them to state. A
+// failed save only logs: the list is still good.
+func (a *App) lookupNamespaces(ctx context.Context, st *state.State, i int, filter namespaces.Filter) ([]string, error) {
+	rec := st.Clusters[i]
+	if !rec.Connectable() || rec.External() {
+		return nil, fmt.Errorf("no namespaces recorded for %s, and rift cannot look them up: it has no credentials for it", rec.KubeContext)
+	}
+	ctx, cancel := context.WithTimeout(ctx, nsLookupTimeout)
+	defer cancel()
+	all, err := namespaces.List(ctx, rec)
+	if err != nil {
+		return nil, fmt.Errorf("list namespaces of %s: %w", rec.KubeContext, err)
+	}
+	names := make([]string, 0, len(all))
+	for _, ns := range all {
+		if ns == rec.Namespace || filter.Keep(ns) {
+			names = append(names, ns)
+		}
+	}
+	if !slices.Equal(names, rec.Namespaces) {
+		st.Clusters[i].Namespaces = names
+		if err := a.saveState(*st); err != nil && a.Logger != nil {
+			a.Logger.Warn("unable to save namespaces to state", "context", rec.KubeContext, "error", err)
+		}
+	}
+	return names, nil
+}
+
+// completeCurrentNamespaces suggests the namespaces recorded for kubectl's
+// current context.
+func completeCurrentNamespaces(app *App) completionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		if err := app.initialize(); err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return completeNamespaces(app)(cmd, []string{app.currentContext()}, toComplete)
+	}
+}
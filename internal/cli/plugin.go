@@ -0,0 +1,182 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/phenixrizen/rift/internal/config"
+	"github.com/phenixrizen/rift/internal/plugin"
+	"github.com/phenixrizen/rift/internal/state"
+	"github.com/spf13/cobra"
+)
+
+func newPluginCmd(app *App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "plugin",
+		Short: "Work with rift-<name> plugins on your PATH",
+		Long: `Plugins are executables named rift-<name> on your PATH, in any language.
+rift <name> [args] runs one when no built-in command has that name, with
+RIFT_CONFIG, RIFT_STATE, and RIFT_BIN (this rift binary) set. A plugin
+named in discovery_plugins adds the clusters rift-<name> discover prints to
+every sync and issues their tokens (rift-<name> token); one named in
+ui_plugins is offered in the rift ui action menu and runs with the
+selected context's environment, as rift exec gives commands.`,
+	}
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List the plugins on your PATH",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			plugins := plugin.List(os.Getenv("PATH"))
+			if app.Output == "json" {
+				if plugins == nil {
+					plugins = []plugin.Plugin{}
+				}
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				return enc.Encode(plugins)
+			}
+			if len(plugins) == 0 {
+				println(cmd.OutOrStdout(), "No plugins found.", "Plugins are executables named rift-<name> on your PATH.")
+				return nil
+			}
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "Name\tPath")
+			for _, p := range plugins {
+				fmt.Fprintf(w, "%s\t%s\n", p.Name, p.Path)
+			}
+			return w.Flush()
+		},
+	})
+	return cmd
+}
+
+// pluginEnv tells plugins where rift's files are, and how to call rift
+// back (for example $RIFT_BIN list -o json).
+func (a *App) pluginEnv() []string {
+	env := []string{"RIFT_CONFIG=" + a.ConfigPath, "RIFT_STATE=" + a.StatePath}
+	if exe, err := os.Executable(); err == nil {
+		env = append(env, "RIFT_BIN="+exe)
+	}
+	return env
+}
+
+// pluginCommand reports whether args (without the program name) run a
+// plugin: the first is not a flag or a command rift knows, and rift-<name>
+// is on PATH.
+func pluginCommand(root *cobra.Command, args []string) (plugin.Plugin, bool) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return plugin.Plugin{}, false
+	}
+	switch args[0] {
+	case "help", cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd:
+		return plugin.Plugin{}, false
+	}
+	if _, _, err := root.Find(args); err == nil {
+		return plugin.Plugin{}, false
+	}
+	p, err := plugin.Find(args[0])
+	if err != nil {
+		return plugin.Plugin{}, false
+	}
+	return p, true
+}
+
+// runPlugin runs p with args on rift's terminal and exits with its status.
+func runPlugin(app *App, p plugin.Plugin, args []string) error {
+	run := exec.Command(p.Path, args...)
+	run.Env = append(os.Environ(), app.pluginEnv()...)
+	run.Stdin = os.Stdin
+	run.Stdout = os.Stdout
+	run.Stderr = os.Stderr
+
+	// The plugin shares the terminal and handles Ctrl-C itself.
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	defer signal.Stop(signals)
+
+	if err := run.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			code := exitErr.ExitCode()
+			if code < 0 {
+				code = 1
+			}
+			return &ExitError{Code: code, Err: err, Silent: true}
+		}
+		return err
+	}
+	return nil
+}
+
+// pluginClusters splits the plugin clusters of st whose plugin is not in
+// discovery_plugins off a copy of st. Their token command would run a
+// binary named by state alone (a pulled or hand-edited file), so no
+// kubeconfig entry is written for them.
+func pluginClusters(cfg config.Config, st state.State) (state.State, []state.ClusterRecord) {
+	var untrusted []state.ClusterRecord
+	kept := make([]state.ClusterRecord, 0, len(st.Clusters))
+	for _, c := range st.Clusters {
+		if checkPluginCluster(cfg, c) != nil {
+			untrusted = append(untrusted, c)
+			continue
+		}
+		kept = append(kept, c)
+	}
+	st.Clusters = kept
+	return st, untrusted
+}
+
+// checkPluginCluster fails for a plugin cluster whose plugin is not a valid
+// name listed in discovery_plugins.
+func checkPluginCluster(cfg config.Config, rec state.ClusterRecord) error {
+	if rec.Platform != state.PlatformPlugin {
+		return nil
+	}
+	if !state.ValidPluginName(rec.Plugin) || !slices.Contains(cfg.DiscoveryPlugins, rec.Plugin) {
+		return fmt.Errorf("%s comes from plugin %q, which is not in discovery_plugins", rec.KubeContext, rec.Plugin)
+	}
+	return nil
+}
+
+type pluginDoneMsg struct {
+	plugin  string
+	context string
+	err     error
+}
+
+// runPluginAction runs the ui_plugins plugin name on rec from rift ui:
+// rift-<name> <context>, with the environment rift exec gives commands.
+func (m *uiModel) runPluginAction(rec state.ClusterRecord, name string) tea.Cmd {
+	p, err := plugin.Find(name)
+	if err != nil {
+		m.status = err.Error()
+		return nil
+	}
+	cfg, err := m.app.loadConfig()
+	if err != nil {
+		m.status = plugin.Prefix + name + " failed: " + err.Error()
+		return nil
+	}
+	role, _ := findRole(m.state, rec.AWSProfile)
+	env, cleanup, err := targetEnv(cfg, target{Name: rec.KubeContext, Role: role, Cluster: &rec}, "")
+	if err != nil {
+		m.status = plugin.Prefix + name + " failed: " + err.Error()
+		return nil
+	}
+	cmd := exec.Command(p.Path, rec.KubeContext)
+	cmd.Env = append(env, m.app.pluginEnv()...)
+	m.status = "running " + plugin.Prefix + name + " for " + rec.KubeContext + "..."
+	return tea.ExecProcess(cmd, func(err error) tea.Msg {
+		cleanup()
+		return pluginDoneMsg{plugin: name, context: rec.KubeContext, err: err}
+	})
+}
@@ -0,0 +1,147 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/phenixrizen/rift/internal/awsconfig"
+	"github.com/phenixrizen/rift/internal/backup"
+	"github.com/phenixrizen/rift/internal/config"
+	"github.com/phenixrizen/rift/internal/kubeconfig"
+	"github.com/phenixrizen/rift/internal/state"
+	"github.com/spf13/cobra"
+)
+
+type pruneEntry struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+func newPruneCmd(app *App) *cobra.Command {
+	var all, dryRun bool
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Remove rift-managed profiles and contexts that are not in state",
+		Long: `Removes rift-* AWS profiles and kube contexts (with their cluster and user
+entries) that are no longer in state.json, without running a sync. Entries
+that do not start with rift- and imported contexts are never touched.
+
+With --all every profile, context, and sso-session rift manages is removed,
+e.g. before uninstalling. state.json is kept, so rift sync writes them back.
+
+Files are backed up first (see rift restore).`,
+		Example: `  rift prune --dry-run
+  rift prune
+  rift prune --all`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			cfg, err := config.Load(app.ConfigPath)
+			if err != nil {
+				cfg = config.Config{}
+			}
+			st, err := app.loadState()
+			if err != nil {
+				if !errors.Is(err, os.ErrNotExist) {
+					return err
+				}
+				// Without state every rift-* entry looks stale; only --all
+				// may go ahead.
+				if !all {
+					return errStateNotFound
+				}
+				st = state.State{}
+			}
+			awsConfigPath, err := defaultAWSConfigPath()
+			if err != nil {
+				return err
+			}
+			kubeFiles, splitDir, err := app.kubeconfigOutputs(cfg)
+			if err != nil {
+				return err
+			}
+
+			// Preview first so nothing is written (or backed up) when there is
+			// nothing to remove.
+			entries, err := pruneAll(awsConfigPath, kubeFiles, splitDir, st, all, true)
+			if err != nil {
+				return err
+			}
+			var bk *backup.Backup
+			if !dryRun && len(entries) > 0 {
+				if bk, err = app.takeBackup(cfg, "prune", app.backupTargets(awsConfigPath, kubeFiles, splitDir)); err != nil {
+					return fmt.Errorf("back up config files: %w", err)
+				}
+				if entries, err = pruneAll(awsConfigPath, kubeFiles, splitDir, st, all, false); err != nil {
+					return err
+				}
+			}
+
+			out := cmd.OutOrStdout()
+			if app.Output == "json" {
+				enc := json.NewEncoder(out)
+				enc.SetIndent("", "  ")
+				return enc.Encode(entries)
+			}
+			if len(entries) == 0 {
+				println(out, "Nothing to prune.")
+				return nil
+			}
+			w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "Kind\tName\tFile")
+			for _, e := range entries {
+				fmt.Fprintf(w, "%s\t%s\t%s\n", e.Kind, e.Name, e.Path)
+			}
+			_ = w.Flush()
+			if dryRun {
+				println(out, "Dry run complete (no files written)")
+				return nil
+			}
+			fmt.Fprintf(out, "Removed: %d\n", len(entries))
+			if bk != nil {
+				fmt.Fprintf(out, "Backup: %s (undo with: rift restore)\n", bk.ID)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&all, "all", false, "Remove everything rift manages, not only entries missing from state")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show what would be removed without writing files")
+	return cmd
+}
+
+// pruneAll prunes the AWS config and every kubeconfig output, returning what
+// was (or, dry, would be) removed.
+func pruneAll(awsConfigPath string, kubeFiles []string, splitDir string, st state.State, all, dryRun bool) ([]pruneEntry, error) {
+	entries := make([]pruneEntry, 0)
+	sections, err := awsconfig.Prune(awsConfigPath, st, all, dryRun)
+	if err != nil {
+		return nil, fmt.Errorf("prune aws config: %w", err)
+	}
+	for _, section := range sections {
+		kind, name, _ := strings.Cut(section, " ")
+		entries = append(entries, pruneEntry{Kind: kind, Name: name, Path: awsConfigPath})
+	}
+	for _, path := range kubeFiles {
+		contexts, err := kubeconfig.Prune(path, st, all, dryRun)
+		if err != nil {
+			return nil, fmt.Errorf("prune kubeconfig %s: %w", path, err)
+		}
+		for _, name := range contexts {
+			entries = append(entries, pruneEntry{Kind: "context", Name: name, Path: path})
+		}
+	}
+	if splitDir != "" {
+		contexts, err := kubeconfig.PruneSplit(splitDir, st, all, dryRun)
+		if err != nil {
+			return nil, fmt.Errorf("prune kubeconfig dir %s: %w", splitDir, err)
+		}
+		for _, name := range contexts {
+			entries = append(entries, pruneEntry{Kind: "context", Name: name, Path: kubeconfig.SplitPath(splitDir, name)})
+		}
+	}
+	return entries, nil
+}
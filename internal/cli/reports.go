@@ -0,0 +1,124 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/phenixrizen/rift/internal/reports"
+	"github.com/spf13/cobra"
+)
+
+func newReportsCmd(app *App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "reports",
+		Short: "List past sync reports",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			dir := app.reportsDir()
+			ids, err := reports.List(dir)
+			if err != nil {
+				return err
+			}
+			if len(ids) == 0 {
+				println(cmd.OutOrStdout(), "No sync reports recorded yet.", "Run: rift sync")
+				return nil
+			}
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "ID\tFinished\tDuration\tRoles\tClusters\tAWS Profiles\tKube Contexts")
+			for i := len(ids) - 1; i >= 0; i-- {
+				r, err := reports.Load(dir, ids[i])
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\t+%d ~%d -%d\t+%d ~%d -%d\n",
+					r.ID,
+					r.FinishedAt.Local().Format("2006-01-02 15:04:05"),
+					r.FinishedAt.Sub(r.StartedAt).Round(time.Second),
+					r.Roles,
+					r.Clusters,
+					r.AWS.Added, r.AWS.Updated, r.AWS.Removed,
+					r.Kube.Added, r.Kube.Updated, r.Kube.Removed,
+				)
+			}
+			return w.Flush()
+		},
+	}
+	cmd.AddCommand(&cobra.Command{
+		Use:   "show [id|latest]",
+		Short: "Show a sync report and what changed since the previous one",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ref := "latest"
+			if len(args) == 1 {
+				ref = args[0]
+			}
+			dir := app.reportsDir()
+			id, prevID, err := reports.Resolve(dir, ref)
+			if err != nil {
+				return err
+			}
+			r, err := reports.Load(dir, id)
+			if err != nil {
+				return err
+			}
+			var prev reports.Report
+			if prevID != "" {
+				if prev, err = reports.Load(dir, prevID); err != nil {
+					return err
+				}
+			}
+			printReport(cmd.OutOrStdout(), r, prev, prevID)
+			return nil
+		},
+	})
+	return cmd
+}
+
+func printReport(out io.Writer, r, prev reports.Report, prevID string) {
+	fmt.Fprintf(out, "Report:   %s\n", r.ID)
+	fmt.Fprintf(out, "Started:  %s\n", r.StartedAt.Local().Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(out, "Finished: %s\n", r.FinishedAt.Local().Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(out, "Discovered roles:    %d\n", r.Roles)
+	fmt.Fprintf(out, "Discovered clusters: %d\n", r.Clusters)
+	println(out, failureLines(r.Failures)...)
+	fmt.Fprintf(out, "Namespaces: tried=%d updated=%d errors=%d\n", r.Namespaces.Tried, r.Namespaces.Updated, r.Namespaces.Errors)
+	fmt.Fprintf(out, "AWS profiles: +%d ~%d -%d\n", r.AWS.Added, r.AWS.Updated, r.AWS.Removed)
+	if len(r.KubeTargets) > 1 {
+		for _, target := range r.KubeTargets {
+			fmt.Fprintf(out, "Kube contexts: +%d ~%d -%d (%s)\n", target.Counts.Added, target.Counts.Updated, target.Counts.Removed, target.Path)
+		}
+	} else {
+		fmt.Fprintf(out, "Kube contexts: +%d ~%d -%d\n", r.Kube.Added, r.Kube.Updated, r.Kube.Removed)
+	}
+	if r.Backup != "" {
+		fmt.Fprintf(out, "Backup: %s\n", r.Backup)
+	}
+
+	if prevID == "" {
+		println(out, "", "First recorded sync; nothing to compare against.")
+		return
+	}
+	if r.Redacted || prev.Redacted {
+		println(out, "", "Changes since "+prevID+": not recorded (reports keep no names with state_encryption).")
+		return
+	}
+	diff := reports.Compare(prev, r)
+	fmt.Fprintf(out, "\nChanges since %s:\n", prevID)
+	if len(diff.AddedContexts)+len(diff.RemovedContexts)+len(diff.AddedProfiles)+len(diff.RemovedProfiles) == 0 {
+		println(out, "  (none)")
+		return
+	}
+	for _, name := range diff.AddedContexts {
+		fmt.Fprintf(out, "  + context %s\n", name)
+	}
+	for _, name := range diff.RemovedContexts {
+		fmt.Fprintf(out, "  - context %s\n", name)
+	}
+	for _, name := range diff.AddedProfiles {
+		fmt.Fprintf(out, "  + profile %s\n", name)
+	}
+	for _, name := range diff.RemovedProfiles {
+		fmt.Fprintf(out, "  - profile %s\n", name)
+	}
+}
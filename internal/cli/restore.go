@@ -0,0 +1,120 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/phenixrizen/rift/internal/backup"
+	"github.com/phenixrizen/rift/internal/config"
+	"github.com/spf13/cobra"
+)
+
+func newRestoreCmd(app *App) *cobra.Command {
+	var list, dryRun bool
+	cmd := &cobra.Command{
+		Use:   "restore [id|latest]",
+		Short: "Roll back ~/.aws/config, kubeconfig, and state to a backup",
+		Long: `Sync and migrate back up the files they are about to rewrite (~/.aws/config,
+the kubeconfig targets, state.json) when they change something. restore puts
+the files of a backup (the latest by default) back as they were.
+
+Restoring first backs up the current files, so running restore again undoes
+it. backup_retain in config sets how many backups are kept.`,
+		Example: `  rift restore --list
+  rift restore
+  rift restore 20260102T150405 --dry-run`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir := app.backupsDir()
+			out := cmd.OutOrStdout()
+			if list {
+				return listBackups(cmd, app, dir)
+			}
+			ref := "latest"
+			if len(args) == 1 {
+				ref = args[0]
+			}
+			id, err := backup.Resolve(dir, ref)
+			if err != nil {
+				return err
+			}
+			b, err := backup.Load(dir, id)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "Backup %s (before %s, %s)\n", b.ID, b.Command, b.CreatedAt.Local().Format("2006-01-02 15:04:05"))
+			for _, f := range b.Files {
+				if f.Existed {
+					fmt.Fprintf(out, "  restore %s\n", f.Path)
+				} else {
+					fmt.Fprintf(out, "  remove  %s\n", f.Path)
+				}
+			}
+			if dryRun {
+				println(out, "Dry run complete (no files written)")
+				return nil
+			}
+
+			cfg, err := config.Load(app.ConfigPath)
+			if err != nil {
+				cfg = config.Config{}
+			}
+			targets := make([]backup.File, 0, len(b.Files))
+			for _, f := range b.Files {
+				targets = append(targets, backup.File{Path: f.Path, Dir: f.Dir})
+			}
+			// Prune only after restoring: the backup being restored may be
+			// the oldest one retained.
+			undo, err := backup.Create(dir, "restore", targets, time.Now(), 0)
+			if err != nil {
+				return fmt.Errorf("back up current files: %w", err)
+			}
+			if err := backup.Restore(dir, b); err != nil {
+				return err
+			}
+			if retain := backupRetain(cfg); retain > 0 {
+				if err := backup.Prune(dir, retain); err != nil && app.Logger != nil {
+					app.Logger.Warn("unable to prune backups", "error", err)
+				}
+			}
+			fmt.Fprintf(out, "Restored. Undo with: rift restore %s\n", undo.ID)
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&list, "list", false, "List backups instead of restoring")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show what would be restored without writing files")
+	return cmd
+}
+
+func listBackups(cmd *cobra.Command, app *App, dir string) error {
+	ids, err := backup.List(dir)
+	if err != nil {
+		return err
+	}
+	backups := make([]backup.Backup, 0, len(ids))
+	for i := len(ids) - 1; i >= 0; i-- {
+		b, err := backup.Load(dir, ids[i])
+		if err != nil {
+			return err
+		}
+		backups = append(backups, b)
+	}
+	out := cmd.OutOrStdout()
+	if app.Output == "json" {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(backups)
+	}
+	if len(backups) == 0 {
+		println(out, "No backups recorded yet.")
+		return nil
+	}
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tCreated\tBefore\tFiles")
+	for _, b := range backups {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\n", b.ID, b.CreatedAt.Local().Format("2006-01-02 15:04:05"), b.Command, len(b.Files))
+	}
+	return w.Flush()
+}
@@ -8,15 +8,28 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/phenixrizen/rift/internal/aks"
 	"github.com/phenixrizen/rift/internal/awsconfig"
+	"github.com/phenixrizen/rift/internal/backup"
 	"github.com/phenixrizen/rift/internal/config"
 	"github.com/phenixrizen/rift/internal/discovery"
+	"github.com/phenixrizen/rift/internal/gke"
+	"github.com/phenixrizen/rift/internal/history"
+	"github.com/phenixrizen/rift/internal/hooks"
 	"github.com/phenixrizen/rift/internal/kubeconfig"
 	"github.com/phenixrizen/rift/internal/namespaces"
 	"github.com/phenixrizen/rift/internal/naming"
+	"github.com/phenixrizen/rift/internal/overlay"
+	"github.com/phenixrizen/rift/internal/plugin"
+	"github.com/phenixrizen/rift/internal/reports"
+	"github.com/phenixrizen/rift/internal/rifterr"
 	"github.com/phenixrizen/rift/internal/state"
+	"github.com/phenixrizen/rift/internal/statecrypt"
 	"github.com/spf13/cobra"
 )
 
@@ -26,7 +39,17 @@ type App struct {
 	ConfigPath string
 	StatePath  string
 	Debug      bool
-	Logger     *slog.Logger
+	// KubeconfigPaths are --kubeconfig targets; they override the config's
+	// kubeconfig_paths.
+	KubeconfigPaths []string
+	// Output is the --output format: "table" (default) or "json".
+	Output string
+	Logger *slog.Logger
+
+	creds *discovery.CredentialCache
+	// sealer is the state_encryption sealer, loaded once by stateSealer.
+	sealer       state.Sealer
+	sealerLoaded bool
 }
 
 type SyncReport struct {
@@ -34,31 +57,57 @@ type SyncReport struct {
 	State     state.State
 	NS        namespaces.Result
 	AWS       awsconfig.SyncResult
-	Kube      kubeconfig.SyncResult
-	DryRun    bool
+	// Kube is the result for the primary (first) kubeconfig target.
+	Kube        kubeconfig.SyncResult
+	KubeTargets []KubeTargetResult
+	DryRun      bool
+	// Backup is the ID of the backup taken before writing; empty when
+	// nothing changed or backup_retain turns backups off.
+	Backup string
+	// Pushed is the state_backend URL state.json was uploaded to.
+	Pushed string
+}
+
+type KubeTargetResult struct {
+	Path   string
+	Result kubeconfig.SyncResult
 }
 
 func Execute() error {
-	root, err := NewRootCommand()
+	root, app, err := newRootCommand()
 	if err != nil {
 		return err
 	}
-	return root.Execute()
+	if p, ok := pluginCommand(root, os.Args[1:]); ok {
+		return runPlugin(app, p, os.Args[2:])
+	}
+	err = root.Execute()
+	if err != nil && app.Output == "json" && ShouldPrint(err) {
+		writeJSONError(root.ErrOrStderr(), err)
+		return &ExitError{Code: ExitCode(err), Err: err, Silent: true}
+	}
+	return err
 }
 
 func NewRootCommand() (*cobra.Command, error) {
+	cmd, _, err := newRootCommand()
+	return cmd, err
+}
+
+func newRootCommand() (*cobra.Command, *App, error) {
 	defaultConfigPath, err := config.DefaultConfigPath()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defaultStatePath, err := config.DefaultStatePath()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	app := &App{
 		ConfigPath: defaultConfigPath,
 		StatePath:  defaultStatePath,
+		Output:     "table",
 	}
 
 	cmd := &cobra.Command{
@@ -66,28 +115,74 @@ func NewRootCommand() (*cobra.Command, error) {
 		Short:         "Rift orchestrates AWS SSO profiles and EKS kube contexts",
 		SilenceUsage:  true,
 		SilenceErrors: true,
-		PersistentPreRunE: func(_ *cobra.Command, _ []string) error {
-			return app.initialize()
+		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+			if err := app.initialize(); err != nil {
+				return err
+			}
+			if app.Output == "yaml" && cmd.Annotations[annotationYAMLOutput] != "true" {
+				return rifterr.New(rifterr.CodeConfigInvalid, fmt.Sprintf("%s does not support --output yaml", cmd.CommandPath()), "use table or json")
+			}
+			return nil
 		},
 	}
 	cmd.PersistentFlags().StringVar(&app.ConfigPath, "config", app.ConfigPath, "Path to config.yaml")
 	cmd.PersistentFlags().StringVar(&app.StatePath, "state", app.StatePath, "Path to state.json")
 	cmd.PersistentFlags().BoolVar(&app.Debug, "debug", false, "Enable debug logging")
+	cmd.PersistentFlags().StringSliceVar(&app.KubeconfigPaths, "kubeconfig", nil, "Kubeconfig file(s) to write and switch in (repeatable; overrides kubeconfig_paths)")
+	cmd.PersistentFlags().StringVarP(&app.Output, "output", "o", app.Output, "Output format: table, json, or yaml where supported (errors render as {code, message, hint} with json)")
 
 	cmd.AddCommand(
 		newInitCmd(app),
 		newAuthCmd(app),
 		newSyncCmd(app),
+		newWatchCmd(app),
+		newServeCmd(app),
+		newMCPCmd(app),
 		newListCmd(app),
+		newSearchCmd(app),
 		newUseCmd(app),
+		newNSCmd(app),
+		newEnvCmd(app),
+		newConsoleCmd(app),
+		newECRLoginCmd(app),
+		newSSMCmd(app),
+		newExecCmd(app),
+		newShellCmd(app),
+		newTokenCmd(app),
 		newUICmd(app),
 		newGraphCmd(app),
+		newMigrateCmd(app),
+		newImportCmd(app),
+		newKubeconfigCmd(app),
+		newPruneCmd(app),
+		newRestoreCmd(app),
+		newStateCmd(app),
+		newTagCmd(app),
+		newFavCmd(app),
+		newReportsCmd(app),
+		newDiffCmd(app),
+		newExportCmd(app),
+		newCheckCmd(app),
+		newVerifyCmd(app),
+		newStatusCmd(app),
+		newAccessCmd(app),
+		newExplainCmd(app),
+		newPluginCmd(app),
+		newCompletionCmd(),
 		newVersionCmd(),
 	)
-	return cmd, nil
+	return cmd, app, nil
 }
 
+// annotationYAMLOutput marks commands that accept --output yaml.
+const annotationYAMLOutput = "rift/yaml-output"
+
 func (a *App) initialize() error {
+	switch a.Output {
+	case "table", "json", "yaml":
+	default:
+		return rifterr.New(rifterr.CodeConfigInvalid, fmt.Sprintf("unsupported --output %q", a.Output), "use table, json, or yaml")
+	}
 	configPath, err := config.ResolvePath(a.ConfigPath)
 	if err != nil {
 		return err
@@ -110,74 +205,566 @@ func (a *App) initialize() error {
 func (a *App) loadConfig() (config.Config, error) {
 	cfg, err := config.Load(a.ConfigPath)
 	if err != nil {
-		return cfg, fmt.Errorf("load config %s: %w", a.ConfigPath, err)
+		hint := "fix the config file or re-run: rift init"
+		if errors.Is(err, os.ErrNotExist) {
+			hint = "run: rift init"
+		}
+		return cfg, rifterr.Wrap(rifterr.CodeConfigInvalid, fmt.Errorf("load config %s: %w", a.ConfigPath, err), hint)
 	}
 	return cfg, nil
 }
 
 func (a *App) loadState() (state.State, error) {
-	st, err := state.Load(a.StatePath)
+	// A missing key only matters once the file turns out to be encrypted:
+	// plain state written before state_encryption was set still reads.
+	sealer, keyErr := a.stateSealer()
+	st, err := state.Load(a.StatePath, sealer)
+	if errors.Is(err, state.ErrSealed) && keyErr != nil {
+		return st, keyErr
+	}
+	if errors.Is(err, state.ErrNewerVersion) {
+		return st, rifterr.Wrap(rifterr.CodeStateInvalid, fmt.Errorf("load state %s: %w", a.StatePath, err), "upgrade rift")
+	}
+	if errors.Is(err, state.ErrSealed) {
+		return st, rifterr.Wrap(rifterr.CodeStateInvalid, fmt.Errorf("load state %s: %w", a.StatePath, err), "set state_encryption: age in config.yaml (the key comes from "+statecrypt.EnvKey+" or the keychain)")
+	}
 	if err != nil {
 		return st, fmt.Errorf("load state %s: %w", a.StatePath, err)
 	}
+	ov, err := a.loadOverlay()
+	if err != nil {
+		return st, err
+	}
+	ov.Apply(&st)
 	return st, nil
 }
 
-func (a *App) RunSync(ctx context.Context, dryRun bool) (SyncReport, error) {
+// saveState writes st to the state file, encrypted per state_encryption.
+func (a *App) saveState(st state.State) error {
+	sealer, err := a.stateSealer()
+	if err != nil {
+		return err
+	}
+	return state.Save(a.StatePath, st, sealer)
+}
+
+// stateSealer returns the sealer for state_encryption, nil when it is off.
+// The config is read leniently because commands that only read state work
+// without a valid config.
+func (a *App) stateSealer() (state.Sealer, error) {
+	if a.sealerLoaded {
+		return a.sealer, nil
+	}
+	cfg, err := config.Load(a.ConfigPath)
+	if err == nil && cfg.StateEncryption == config.StateEncryptionAge {
+		key, _, err := statecrypt.LoadKey(true)
+		if err != nil {
+			return nil, rifterr.Wrap(rifterr.CodeConfigInvalid, err, "set "+statecrypt.EnvKey+" to an age identity (see: rift state key)")
+		}
+		sealer, err := statecrypt.New(key)
+		if err != nil {
+			return nil, rifterr.Wrap(rifterr.CodeConfigInvalid, err, "set "+statecrypt.EnvKey+" to an age identity (see: rift state key)")
+		}
+		a.sealer = sealer
+	}
+	a.sealerLoaded = true
+	return a.sealer, nil
+}
+
+func (a *App) overlayPath() string {
+	return overlay.PathFor(a.StatePath)
+}
+
+func (a *App) loadOverlay() (overlay.Overlay, error) {
+	ov, err := overlay.Load(a.overlayPath())
+	if err != nil {
+		return ov, fmt.Errorf("load overlay %s: %w", a.overlayPath(), err)
+	}
+	return ov, nil
+}
+
+// SyncOptions controls a RunSync call.
+type SyncOptions struct {
+	DryRun bool
+	// Incremental reuses clusters (and discovered namespaces) from the
+	// previous state for accounts whose role list is unchanged.
+	Incremental bool
+	// Refresh lists account IDs or names an incremental sync re-lists anyway.
+	Refresh []string
+	// RefreshNamespaces lists every cluster's namespaces again, ignoring
+	// namespace_ttl and Incremental.
+	RefreshNamespaces bool
+	// Force resets rift-managed kubeconfig entries, dropping the user's
+	// additions to them.
+	Force bool
+	// Progress receives discovery stage counters plus the sync-only
+	// stageNamespaces and stageWrite.
+	Progress func(discovery.Progress)
+	// Confirm, when set, is asked before a sync that would remove AWS
+	// profiles or kube contexts writes anything; false cancels the sync
+	// with errSyncCancelled.
+	Confirm func(SyncPreview) bool
+}
+
+// SyncPreview is what a sync is about to write: Report is a dry run of it,
+// and Changes the state diff behind it.
+type SyncPreview struct {
+	Report    SyncReport
+	Changes   history.Diff
+	AWSConfig string
+}
+
+// errSyncCancelled is RunSync's error when SyncOptions.Confirm said no.
+var errSyncCancelled = errors.New("sync cancelled; nothing was written")
+
+// Sync stages reported after discovery.
+const (
+	stageNamespaces = "namespaces"
+	stageWrite      = "write"
+)
+
+func (a *App) RunSync(ctx context.Context, sopts SyncOptions) (SyncReport, error) {
+	startedAt := time.Now().UTC()
+	dryRun := sopts.DryRun
 	cfg, err := a.loadConfig()
 	if err != nil {
 		return SyncReport{}, err
 	}
+	prev, prevErr := a.loadState()
+	if prevErr != nil && !errors.Is(prevErr, os.ErrNotExist) {
+		// Syncing over state that cannot be read would drop its imported,
+		// shared, and pinned records, and write plain JSON (or an older
+		// schema) over a file that was encrypted (or newer).
+		var typed *rifterr.Error
+		if errors.As(prevErr, &typed) {
+			return SyncReport{}, prevErr
+		}
+		return SyncReport{}, rifterr.Wrap(rifterr.CodeStateInvalid, prevErr, "rift sync will not overwrite state it cannot read: check state_encryption and "+statecrypt.EnvKey+" (or the keychain key) if it is encrypted, or run rift restore")
+	}
+	if !dryRun {
+		if err := a.runHook(ctx, cfg, hooks.PreSync, preSyncPayload{Incremental: sopts.Incremental, Refresh: sopts.Refresh}); err != nil {
+			return SyncReport{}, err
+		}
+	}
 
-	inv, err := discovery.Discover(ctx, cfg, a.Logger)
+	roleEnv := func(role discovery.RoleAccess) string {
+		name := role.AccountName
+		if alias := cfg.AccountAlias(role.AccountID); alias != "" {
+			name = alias
+		}
+		return naming.ClassifyEnv(cfg, name, role.RoleName, "").Env
+	}
+	opts := discovery.Options{
+		RegionsFor: func(role discovery.RoleAccess) []string {
+			return cfg.RegionsFor(roleEnv(role), role.AccountName, role.AccountID)
+		},
+	}
+	if cfg.DiscoverRegions {
+		opts.DiscoverRegions = func(role discovery.RoleAccess) bool {
+			return cfg.RegionOverrideIndex(roleEnv(role), role.AccountName, role.AccountID) < 0
+		}
+	}
+	opts.Progress = sopts.Progress
+	opts.Compute = cfg.DiscoverCompute
+	opts.Credentials = a.credentialCache(cfg)
+	incremental := sopts.Incremental && prevErr == nil
+	if incremental && !slices.Equal(prev.Regions, cfg.AllRegions()) {
+		// Reused clusters were listed in other regions.
+		a.Logger.Info("regions changed since the last sync; running a full sync")
+		incremental = false
+	}
+	if incremental {
+		opts.Previous = discovery.PreviousFromState(prev)
+		opts.Previous.Refresh = sopts.Refresh
+	}
+	inv, err := discovery.Discover(ctx, cfg, opts, a.Logger)
 	if err != nil {
 		if errors.Is(err, discovery.ErrSSONotLoggedIn) {
-			return SyncReport{}, fmt.Errorf("%w. Run: rift auth", ErrSSOLoginRequired)
+			return SyncReport{}, rifterr.Wrap(rifterr.CodeAuthRequired, ErrSSOLoginRequired, "run: rift auth")
 		}
 		return SyncReport{}, err
 	}
+	if len(cfg.GCPProjects) > 0 {
+		clusters, failures := gke.Discover(ctx, cfg.GCPProjects, a.Logger)
+		inv.Clusters = append(inv.Clusters, clusters...)
+		inv.Failures = append(inv.Failures, failures...)
+	}
+	if len(cfg.AzureSubscriptions) > 0 {
+		clusters, failures := aks.Discover(ctx, cfg.AzureSubscriptions, a.Logger)
+		inv.Clusters = append(inv.Clusters, clusters...)
+		inv.Failures = append(inv.Failures, failures...)
+	}
+	if len(cfg.DiscoveryPlugins) > 0 {
+		clusters, failures := plugin.Discover(ctx, cfg.DiscoveryPlugins, a.pluginEnv(), a.Logger)
+		inv.Clusters = append(inv.Clusters, clusters...)
+		inv.Failures = append(inv.Failures, failures...)
+	}
 
 	st := naming.BuildState(cfg, inv)
+	if prevErr == nil {
+		st.CarryPinned(prev)
+		st.CarryExternal(prev)
+		st.CarryShared(prev)
+	}
+	if ov, err := a.loadOverlay(); err == nil {
+		ov.Apply(&st)
+	}
 	nsResult := namespaces.Result{}
 	if cfg.DiscoverNamespaces {
-		nsResult, err = namespaces.Enrich(ctx, &st, a.Logger)
+		sopts.progress(discovery.Progress{Stage: stageNamespaces, Total: len(st.Clusters)})
+		// An incremental sync keeps known clusters' lists however old;
+		// namespace_ttl bounds how old a carried list may be.
+		carry, since := incremental, time.Time{}
+		if ttl := cfg.NamespaceCacheTTL(); ttl > 0 {
+			carry, since = true, startedAt.Add(-ttl)
+		}
+		if carry && !sopts.RefreshNamespaces {
+			nsResult, err = enrichFresh(ctx, &st, prev, since, a.Logger, cachedCredentials(opts.Credentials, st), enrichOptions(cfg))
+		} else {
+			nsResult, err = namespaces.Enrich(ctx, &st, a.Logger, cachedCredentials(opts.Credentials, st), enrichOptions(cfg))
+		}
 		if err != nil {
 			return SyncReport{}, fmt.Errorf("discover namespaces: %w", err)
 		}
 	}
 
+	if !dryRun && sopts.Confirm != nil {
+		preview, err := a.applyState(cfg, "sync", st, prev, true, sopts.Force)
+		if err != nil {
+			return SyncReport{}, err
+		}
+		if preview.removes() {
+			awsConfigPath, _ := defaultAWSConfigPath()
+			if !sopts.Confirm(SyncPreview{Report: preview, Changes: history.Compare(prev, st), AWSConfig: awsConfigPath}) {
+				return SyncReport{}, errSyncCancelled
+			}
+		}
+	}
+	sopts.progress(discovery.Progress{Stage: stageWrite})
+	report, err := a.applyState(cfg, "sync", st, prev, dryRun, sopts.Force)
+	if err != nil {
+		return SyncReport{}, err
+	}
+	report.Inventory = inv
+	report.NS = nsResult
+	if !dryRun {
+		if err := a.saveState(st); err != nil {
+			return SyncReport{}, fmt.Errorf("write state: %w", err)
+		}
+		compact := report.compact(startedAt, time.Now().UTC())
+		record := compact
+		if cfg.StateEncryption != "" {
+			// Reports are plain JSON; the names are the inventory
+			// state_encryption keeps off the disk.
+			record = compact.WithoutNames()
+		}
+		saved, err := reports.Save(a.reportsDir(), record, reports.DefaultRetain)
+		if err != nil {
+			saved = record
+			if a.Logger != nil {
+				a.Logger.Warn("unable to record sync report", "error", err)
+			}
+		}
+		// The hook still gets the names: it is the user's own command.
+		compact.ID = saved.ID
+		if err := a.recordSnapshot(cfg, prev, prevErr == nil, st); err != nil && a.Logger != nil {
+			a.Logger.Warn("unable to record state snapshot", "error", err)
+		}
+		if cfg.StateBackend != "" {
+			if report.Pushed, err = a.pushState(ctx, cfg); err != nil && a.Logger != nil {
+				a.Logger.Warn("unable to push state to state_backend", "error", err)
+			}
+		}
+		// The sync has written everything; a failing hook cannot undo it.
+		if err := a.runHook(ctx, cfg, hooks.PostSync, compact); err != nil && a.Logger != nil {
+			a.Logger.Warn("post_sync hook failed", "error", err)
+		}
+	}
+	return report, nil
+}
+
+// applyState writes st's profiles and contexts to ~/.aws/config and the
+// kubeconfig outputs, removing entries of prev that are gone. Unless dryRun,
+// the files are backed up first as command, and the backup is kept only
+// when something changed. The report has no Inventory or NS.
+func (a *App) applyState(cfg config.Config, command string, st, prev state.State, dryRun, force bool) (SyncReport, error) {
 	awsConfigPath, err := defaultAWSConfigPath()
 	if err != nil {
 		return SyncReport{}, err
 	}
-	kubeConfigPath, err := defaultKubeConfigPath()
+	kubeFiles, splitDir, err := a.kubeconfigOutputs(cfg)
 	if err != nil {
 		return SyncReport{}, err
 	}
-
-	awsResult, err := awsconfig.Sync(awsConfigPath, cfg, st, dryRun)
+	stale, err := a.staleKubeconfigs(cfg, kubeFiles)
+	if err != nil {
+		return SyncReport{}, err
+	}
+	var bk *backup.Backup
+	if !dryRun {
+		if bk, err = a.takeBackup(cfg, command, a.backupTargets(awsConfigPath, slices.Concat(kubeFiles, stale), splitDir)); err != nil {
+			return SyncReport{}, fmt.Errorf("back up config files: %w", err)
+		}
+	}
+	awsResult, err := awsconfig.Sync(awsConfigPath, cfg, st, prev, dryRun)
 	if err != nil {
 		return SyncReport{}, fmt.Errorf("sync aws config: %w", err)
 	}
-	kubeResult, err := kubeconfig.Sync(kubeConfigPath, st, dryRun)
+	kubeState, untrusted := pluginClusters(cfg, st)
+	for _, c := range untrusted {
+		if a.Logger != nil {
+			a.Logger.Warn("not writing a kube context for a plugin cluster", "context", c.KubeContext, "plugin", c.Plugin, "hint", "add the plugin to discovery_plugins if you trust it")
+		}
+	}
+	kubeTargets, err := syncKubeconfigs(kubeFiles, splitDir, kubeState, prev, dryRun, force)
 	if err != nil {
-		return SyncReport{}, fmt.Errorf("sync kubeconfig: %w", err)
+		return SyncReport{}, err
+	}
+	for _, path := range stale {
+		result, err := kubeconfig.Sweep(path, st, prev, dryRun)
+		if err != nil {
+			return SyncReport{}, fmt.Errorf("remove rift contexts from %s: %w", path, err)
+		}
+		if result.RemovedContexts > 0 {
+			kubeTargets = append(kubeTargets, KubeTargetResult{Path: path, Result: result})
+		}
 	}
 
-	if !dryRun {
-		if err := state.Save(a.StatePath, st); err != nil {
-			return SyncReport{}, fmt.Errorf("write state: %w", err)
+	report := SyncReport{
+		State:       st,
+		AWS:         awsResult,
+		Kube:        kubeTargets[0].Result,
+		KubeTargets: kubeTargets,
+		DryRun:      dryRun,
+	}
+	if report.configChanged() {
+		if bk != nil {
+			report.Backup = bk.ID
 		}
+	} else {
+		a.dropBackup(bk)
 	}
+	return report, nil
+}
+
+func (o SyncOptions) progress(p discovery.Progress) {
+	if o.Progress != nil {
+		o.Progress(p)
+	}
+}
+
+// progressText describes a sync progress update in a few words.
+func progressText(p discovery.Progress) string {
+	switch p.Stage {
+	case discovery.StageAccounts:
+		return fmt.Sprintf("listed %d accounts", p.Done)
+	case discovery.StageRoles:
+		return fmt.Sprintf("listing roles %d/%d accounts", p.Done, p.Total)
+	case discovery.StageRegions:
+		return fmt.Sprintf("scanning regions %d/%d", p.Done, p.Total)
+	case discovery.StageClusters:
+		return fmt.Sprintf("describing clusters %d/%d", p.Done, p.Total)
+	case stageNamespaces:
+		return fmt.Sprintf("discovering namespaces (%d clusters)", p.Total)
+	case stageWrite:
+		return "writing aws config, kubeconfig, and state"
+	}
+	return p.Stage
+}
+
+// syncKubeconfigs writes st to each kubeconfig file, or to the split
+// layout's directory (reported as the only target) when splitDir is set.
+func syncKubeconfigs(files []string, splitDir string, st, prev state.State, dryRun, force bool) ([]KubeTargetResult, error) {
+	if splitDir != "" {
+		result, err := kubeconfig.SyncSplit(splitDir, st, prev, dryRun, force)
+		if err != nil {
+			return nil, fmt.Errorf("sync kubeconfig dir %s: %w", splitDir, err)
+		}
+		return []KubeTargetResult{{Path: splitDir, Result: result}}, nil
+	}
+	targets := make([]KubeTargetResult, 0, len(files))
+	for _, path := range files {
+		result, err := kubeconfig.Sync(path, st, prev, dryRun, force)
+		if err != nil {
+			return nil, fmt.Errorf("sync kubeconfig %s: %w", path, err)
+		}
+		targets = append(targets, KubeTargetResult{Path: path, Result: result})
+	}
+	return targets, nil
+}
+
+// enrichFresh discovers namespaces only for clusters prev did not have or
+// last listed before since (see State.CarryNamespaces); the others keep
+// their previous namespace lists and workload counts.
+func enrichFresh(ctx context.Context, st *state.State, prev state.State, since time.Time, logger *slog.Logger, creds namespaces.Credentials, opts namespaces.Options) (namespaces.Result, error) {
+	fresh := st.CarryNamespaces(prev, since)
+	sub := state.State{Clusters: make([]state.ClusterRecord, 0, len(fresh))}
+	for _, idx := range fresh {
+		sub.Clusters = append(sub.Clusters, st.Clusters[idx])
+	}
+	result, err := namespaces.Enrich(ctx, &sub, logger, creds, opts)
+	if err != nil {
+		return result, err
+	}
+	for i, idx := range fresh {
+		st.Clusters[idx] = sub.Clusters[i]
+	}
+	return result, nil
+}
+
+// configChanged reports whether sync added, updated, or removed any AWS
+// profile or kube context.
+func (r SyncReport) configChanged() bool {
+	if r.AWS.Added+r.AWS.Updated+r.AWS.Removed > 0 {
+		return true
+	}
+	for _, target := range r.KubeTargets {
+		if target.Result.AddedContexts+target.Result.UpdatedContexts+target.Result.RemovedContexts > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// removes reports whether sync removed (or, dry, would remove) any AWS
+// profile or kube context.
+func (r SyncReport) removes() bool {
+	if r.AWS.Removed > 0 {
+		return true
+	}
+	for _, target := range r.KubeTargets {
+		if target.Result.RemovedContexts > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// kubeLines summarizes kubeconfig changes, one line per target when sync
+// wrote more than one file.
+func (r SyncReport) kubeLines() []string {
+	lines := make([]string, 0, len(r.KubeTargets)+1)
+	if len(r.KubeTargets) <= 1 {
+		lines = append(lines, fmt.Sprintf("Kube contexts: +%d ~%d -%d", r.Kube.AddedContexts, r.Kube.UpdatedContexts, r.Kube.RemovedContexts))
+	} else {
+		for _, target := range r.KubeTargets {
+			lines = append(lines, fmt.Sprintf("Kube contexts: +%d ~%d -%d (%s)", target.Result.AddedContexts, target.Result.UpdatedContexts, target.Result.RemovedContexts, target.Path))
+		}
+	}
+	if r.Kube.SkippedContexts > 0 {
+		lines = append(lines, fmt.Sprintf("Skipped (no API endpoint, e.g. EKS Connector): %d", r.Kube.SkippedContexts))
+	}
+	return lines
+}
+
+// diff joins the dry-run diffs of the AWS config and every kubeconfig
+// target; empty when sync would write nothing.
+func (r SyncReport) diff() string {
+	var b strings.Builder
+	b.WriteString(r.AWS.Diff)
+	for _, target := range r.KubeTargets {
+		b.WriteString(target.Result.Diff)
+	}
+	return b.String()
+}
+
+// credentialCache returns the role credential cache shared by every sync
+// (and env) in this process, backed by disk when credential_cache is
+// enabled.
+func (a *App) credentialCache(cfg config.Config) *discovery.CredentialCache {
+	if a.creds == nil {
+		dir := ""
+		if cfg.CredentialCache {
+			dir = filepath.Join(filepath.Dir(a.StatePath), "cache", "credentials")
+		}
+		a.creds = discovery.NewCredentialCache(dir)
+	}
+	return a.creds
+}
+
+// namespaceFilter is the namespace_include/namespace_exclude filter.
+func namespaceFilter(cfg config.Config) namespaces.Filter {
+	return namespaces.Filter{Include: cfg.NamespaceInclude, Exclude: cfg.NamespaceExclude}
+}
+
+// enrichOptions is what sync's namespace discovery records, per config.
+func enrichOptions(cfg config.Config) namespaces.Options {
+	return namespaces.Options{
+		Workloads: cfg.DiscoverWorkloads,
+		Access:    cfg.DiscoverAccess,
+		Filter:    namespaceFilter(cfg),
+	}
+}
+
+// cachedCredentials looks up the credentials discovery cached for a
+// cluster's role so namespace discovery reuses them.
+func cachedCredentials(cache *discovery.CredentialCache, st state.State) namespaces.Credentials {
+	roles := make(map[string]state.RoleRecord, len(st.Roles))
+	for _, r := range st.Roles {
+		roles[r.AWSProfile] = r
+	}
+	return func(c state.ClusterRecord) (aws.Credentials, bool) {
+		role, ok := roles[c.AWSProfile]
+		if !ok {
+			return aws.Credentials{}, false
+		}
+		return cache.Get(discovery.KeyForRole(role))
+	}
+}
 
-	return SyncReport{
-		Inventory: inv,
-		State:     st,
-		NS:        nsResult,
-		AWS:       awsResult,
-		Kube:      kubeResult,
-		DryRun:    dryRun,
-	}, nil
+func (a *App) reportsDir() string {
+	return reports.DirFor(a.StatePath)
+}
+
+func (r SyncReport) compact(startedAt, finishedAt time.Time) reports.Report {
+	out := reports.Report{
+		StartedAt:  startedAt,
+		FinishedAt: finishedAt,
+		Roles:      len(r.State.Roles),
+		Clusters:   len(r.State.Clusters),
+		AWS:        reports.Counts{Added: r.AWS.Added, Updated: r.AWS.Updated, Removed: r.AWS.Removed},
+		Kube:       reports.Counts{Added: r.Kube.AddedContexts, Updated: r.Kube.UpdatedContexts, Removed: r.Kube.RemovedContexts},
+		Namespaces: reports.NamespaceCounts{Tried: r.NS.ClustersTried, Updated: r.NS.ClustersUpdated, Errors: r.NS.Errors},
+		Profiles:   make([]string, 0, len(r.State.Roles)),
+		Contexts:   make([]string, 0, len(r.State.Clusters)),
+	}
+	for _, target := range r.KubeTargets {
+		out.KubeTargets = append(out.KubeTargets, reports.KubeTarget{
+			Path:   target.Path,
+			Counts: reports.Counts{Added: target.Result.AddedContexts, Updated: target.Result.UpdatedContexts, Removed: target.Result.RemovedContexts},
+		})
+	}
+	for _, role := range r.State.Roles {
+		out.Profiles = append(out.Profiles, role.AWSProfile)
+	}
+	for _, cluster := range r.State.Clusters {
+		out.Contexts = append(out.Contexts, cluster.KubeContext)
+	}
+	slices.Sort(out.Profiles)
+	slices.Sort(out.Contexts)
+	out.Failures = r.failures()
+	out.Backup = r.Backup
+	return out
+}
+
+func (r SyncReport) failures() []string {
+	var out []string
+	for _, f := range r.Inventory.Failures {
+		out = append(out, f.String())
+	}
+	return out
+}
+
+// failureLines lists discovery failures for sync output, or nothing.
+func failureLines(failures []string) []string {
+	if len(failures) == 0 {
+		return nil
+	}
+	lines := []string{fmt.Sprintf("Discovery failures: %d (results are incomplete; see retry_max_attempts)", len(failures))}
+	for _, f := range failures {
+		lines = append(lines, "  ! "+f)
+	}
+	return lines
 }
 
 func defaultAWSConfigPath() (string, error) {
@@ -188,11 +775,113 @@ func defaultAWSConfigPath() (string, error) {
 	return filepath.Join(home, ".aws", "config"), nil
 }
 
+// kubeConfigTargets returns the kubeconfig files sync writes: --kubeconfig
+// flags, else kubeconfig_paths from config, else the default path.
+func (a *App) kubeConfigTargets(cfg config.Config) ([]string, error) {
+	paths := a.KubeconfigPaths
+	if len(paths) == 0 {
+		paths = cfg.KubeconfigPaths
+	}
+	out := make([]string, 0, len(paths))
+	seen := map[string]struct{}{}
+	for _, p := range paths {
+		if strings.TrimSpace(p) == "" {
+			continue
+		}
+		resolved, err := config.ResolvePath(strings.TrimSpace(p))
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := seen[resolved]; ok {
+			continue
+		}
+		seen[resolved] = struct{}{}
+		out = append(out, resolved)
+	}
+	if len(out) == 0 {
+		path, err := defaultKubeConfigPath()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, path)
+	}
+	return out, nil
+}
+
+// kubeconfigOutputs returns where sync writes contexts under cfg's
+// kubeconfig_layout: the kubeconfig targets (merged), the dedicated file, or
+// the split layout's directory as splitDir.
+func (a *App) kubeconfigOutputs(cfg config.Config) (files []string, splitDir string, err error) {
+	switch cfg.Layout() {
+	case config.KubeconfigLayoutDedicated:
+		path, err := cfg.DedicatedKubeconfigPath()
+		if err != nil {
+			return nil, "", err
+		}
+		return []string{path}, "", nil
+	case config.KubeconfigLayoutSplit:
+		dir, err := cfg.SplitKubeconfigDir()
+		return nil, dir, err
+	}
+	files, err = a.kubeConfigTargets(cfg)
+	return files, "", err
+}
+
+// staleKubeconfigs returns the merged layout's kubeconfig files when cfg
+// uses a separate layout (and they are not its output). Sync sweeps rift's
+// contexts out of them, so entries written before the switch cannot shadow
+// the new files in KUBECONFIG.
+func (a *App) staleKubeconfigs(cfg config.Config, outputs []string) ([]string, error) {
+	if !cfg.SeparateKubeconfig() {
+		return nil, nil
+	}
+	merged, err := a.kubeConfigTargets(cfg)
+	if err != nil {
+		return nil, err
+	}
+	stale := make([]string, 0, len(merged))
+	for _, path := range merged {
+		if !slices.Contains(outputs, path) {
+			stale = append(stale, path)
+		}
+	}
+	return stale, nil
+}
+
+// primaryKubeConfig returns the first kubeconfig target and whether it was
+// set explicitly. A missing or invalid config falls back to the default.
+func (a *App) primaryKubeConfig() (string, bool, error) {
+	cfg, err := config.Load(a.ConfigPath)
+	if err != nil {
+		cfg = config.Config{}
+	}
+	// Separate layouts' contexts reach kubectl through KUBECONFIG, so a
+	// single --kubeconfig would hide them.
+	explicit := (len(a.KubeconfigPaths) > 0 || len(cfg.KubeconfigPaths) > 0) && !cfg.SeparateKubeconfig()
+	paths, err := a.kubeConfigTargets(cfg)
+	if err != nil {
+		return "", false, err
+	}
+	return paths[0], explicit, nil
+}
+
+// kubeconfigArgs returns "--kubeconfig <path>" for kubectl/k9s when the
+// primary target was configured explicitly, so they act on the file rift
+// wrote instead of KUBECONFIG.
+func (a *App) kubeconfigArgs() []string {
+	path, explicit, err := a.primaryKubeConfig()
+	if err != nil || !explicit {
+		return nil
+	}
+	return []string{"--kubeconfig", path}
+}
+
 func defaultKubeConfigPath() (string, error) {
-	if env := strings.TrimSpace(os.Getenv("KUBECONFIG")); env != "" {
-		parts := strings.Split(env, string(os.PathListSeparator))
-		if len(parts) > 0 {
-			return config.ResolvePath(parts[0])
+	// A rift use --local shell lists its own kubeconfig first; the one to
+	// write is the next.
+	for _, part := range strings.Split(os.Getenv("KUBECONFIG"), string(os.PathListSeparator)) {
+		if part = strings.TrimSpace(part); part != "" && !kubeconfig.IsSession(part) {
+			return config.ResolvePath(part)
 		}
 	}
 	home, err := os.UserHomeDir()
@@ -0,0 +1,111 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/phenixrizen/rift/internal/query"
+	"github.com/phenixrizen/rift/internal/state"
+	"github.com/phenixrizen/rift/internal/tableview"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+func newSearchCmd(app *App) *cobra.Command {
+	var table tableview.Options
+	var wide bool
+	cmd := &cobra.Command{
+		Use:   "search <query>...",
+		Short: "Find contexts with the rift ui search language",
+		Long: `Search prints the contexts matching a query, the same way the rift ui
+search box filters its table. A query mixes key:value filters, which must
+all match, with free text, which is fuzzy-matched like rift use and ranks
+the results (best first).
+
+Keys: ` + strings.Join(query.Keys, ", ") + ` (namespace is an alias of ns).
+env, tag, and platform match exactly; the others match a substring, and
+account matches the account name, alias, or ID. A comma-separated value
+matches any of its values; a repeated key must match each time.`,
+		Example: `  rift search env:prod region:us-east-1 account:payments
+  rift search ns:kafka tag:team-a,team-b
+  rift search env:stg pusw2 -o json`,
+		Args:        cobra.MinimumNArgs(1),
+		Annotations: map[string]string{annotationYAMLOutput: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			q, err := query.Parse(strings.Join(args, " "))
+			if err != nil {
+				return err
+			}
+			st, err := app.loadState()
+			if err != nil {
+				if errors.Is(err, os.ErrNotExist) {
+					return errStateNotFound
+				}
+				return err
+			}
+			if wide {
+				table.Columns = tableview.WideColumns
+			}
+			if err := table.Validate(); err != nil {
+				return err
+			}
+			rows := searchClusters(st.Clusters, q)
+			switch app.Output {
+			case "json":
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				return enc.Encode(nonNilClusters(rows))
+			case "yaml":
+				enc := yaml.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent(2)
+				if err := enc.Encode(nonNilClusters(rows)); err != nil {
+					return err
+				}
+				return enc.Close()
+			}
+			if len(st.Clusters) == 0 {
+				println(cmd.OutOrStdout(), "No clusters discovered.", "Run: rift sync")
+				return nil
+			}
+			if len(rows) == 0 {
+				println(cmd.OutOrStdout(), "No clusters match the query.")
+				return nil
+			}
+			fmt.Fprint(cmd.OutOrStdout(), tableview.RenderClusters(rows, table))
+			return nil
+		},
+	}
+	cmd.Flags().StringSliceVar(&table.Columns, "columns", nil, "Columns to print, in order (default "+strings.Join(tableview.DefaultColumns, ",")+"; available: "+strings.Join(tableview.ColumnKeys(), ",")+")")
+	cmd.Flags().StringSliceVar(&table.SortBy, "sort-by", nil, "Sort rows by these columns, in order (default: best match first)")
+	cmd.Flags().BoolVar(&wide, "wide", false, "Add Kubernetes version, status, platform version, and creation date columns")
+	cmd.MarkFlagsMutuallyExclusive("wide", "columns")
+	_ = cmd.RegisterFlagCompletionFunc("columns", cobra.FixedCompletions(tableview.ColumnKeys(), cobra.ShellCompDirectiveNoFileComp))
+	_ = cmd.RegisterFlagCompletionFunc("sort-by", cobra.FixedCompletions(tableview.ColumnKeys(), cobra.ShellCompDirectiveNoFileComp))
+	return cmd
+}
+
+// searchClusters applies q the way the rift ui table does: filters first,
+// then free text ranked by uiRow.match.
+func searchClusters(clusters []state.ClusterRecord, q query.Query) []state.ClusterRecord {
+	cols := newUIColumns(nil, nil)
+	rows := make([]uiRow, 0, len(clusters))
+	for _, rec := range clusters {
+		if !q.Match(rec) {
+			continue
+		}
+		row := newUIRow(rec, cols)
+		if q.Text != "" && !row.match(q.Text) {
+			continue
+		}
+		rows = append(rows, row)
+	}
+	sortRows(rows, uiSort{})
+	out := make([]state.ClusterRecord, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, row.rec)
+	}
+	return out
+}
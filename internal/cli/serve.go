@@ -0,0 +1,194 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+	"time"
+
+	"github.com/phenixrizen/rift/internal/grpcapi"
+	"github.com/phenixrizen/rift/internal/httpapi"
+	"github.com/phenixrizen/rift/internal/query"
+	"github.com/phenixrizen/rift/internal/state"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+)
+
+func newServeCmd(app *App) *cobra.Command {
+	var addr, grpcAddr string
+	var incremental, web, grpcRemote bool
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve the inventory and sync metrics over local HTTP",
+		Long: `Serves rift's inventory to dashboards and other local tools until
+interrupted:
+
+  GET  /clusters  clusters in state (?env= &account= &role= &region= &cluster=)
+  GET  /roles     roles in state (?env= &account= &role=)
+  GET  /graph     rift graph's JSON (the filters plus ?depth=2-5 &namespaces=true)
+  POST /sync      run a sync and return its counts (409 while one runs)
+  GET  /metrics   Prometheus metrics: sync counts, duration, and failures,
+                  and cluster and role counts by env
+  GET  /search    clusters matching a rift search query (?q=)
+
+With --web, / is a browser dashboard: the context table with rift search
+and a details pane, and the rift graph page, refreshed as state changes.
+
+With --grpc, the rift.v1.Rift gRPC service (api/rift/v1/rift.proto) is
+served on a second address: ListClusters, ListRoles, Sync, Use, and Graph.
+Both APIs share one sync at a time and one set of metrics.
+
+State is read again on every request, so syncs run elsewhere show up too.
+It holds cluster endpoints and account IDs: keep --addr on a loopback
+address unless you mean to share it. --grpc must be a loopback address
+unless --grpc-remote is given, since its Use and Sync change your kube
+context and config files.`,
+		Example: `  rift serve
+  rift serve --addr 127.0.0.1:9797 --incremental
+  rift serve --grpc 127.0.0.1:7778
+  rift serve --web
+  curl -s localhost:7777/clusters?env=prod | jq -r '.[].kube_context'`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if err := checkServeAddr(app, "--addr", addr); err != nil {
+				return err
+			}
+			var grpcListener net.Listener
+			if grpcAddr != "" {
+				// The gRPC API has no host or origin check to lean on, and
+				// Use and Sync rewrite the user's config files.
+				loopback, err := loopbackAddr(grpcAddr)
+				if err != nil {
+					return fmt.Errorf("--grpc: %w", err)
+				}
+				if !loopback && !grpcRemote {
+					return fmt.Errorf("--grpc %s is not a loopback address: anyone who reaches it can switch your kube context and run syncs; pass --grpc-remote if you mean that", grpcAddr)
+				}
+				if err := checkServeAddr(app, "--grpc", grpcAddr); err != nil {
+					return err
+				}
+				if grpcListener, err = net.Listen("tcp", grpcAddr); err != nil {
+					return err
+				}
+			}
+			api := &httpapi.Server{
+				State: func() (state.State, error) {
+					st, err := app.loadState()
+					if errors.Is(err, os.ErrNotExist) {
+						return st, errStateNotFound
+					}
+					return st, err
+				},
+				RunSync: func(ctx context.Context) (httpapi.SyncResult, error) {
+					report, err := app.RunSync(ctx, SyncOptions{Incremental: incremental})
+					if err != nil {
+						return httpapi.SyncResult{}, err
+					}
+					return httpapi.SyncResult{
+						Clusters: len(report.State.Clusters),
+						Roles:    len(report.State.Roles),
+						Failures: len(report.failures()),
+					}, nil
+				},
+				Search: func(st state.State, q string) ([]state.ClusterRecord, error) {
+					parsed, err := query.Parse(q)
+					if err != nil {
+						return nil, err
+					}
+					return searchClusters(st.Clusters, parsed), nil
+				},
+				Web:  web,
+				Addr: addr,
+			}
+			srv := &http.Server{Addr: addr, Handler: api.Handler(), ReadHeaderTimeout: 10 * time.Second}
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			errs := make(chan error, 2)
+			go func() { errs <- srv.ListenAndServe() }()
+			fmt.Fprintf(cmd.OutOrStdout(), "Serving on http://%s; press Ctrl-C to stop.\n", addr)
+			if web {
+				fmt.Fprintf(cmd.OutOrStdout(), "Web UI: http://%s/\n", addr)
+			}
+			var grpcSrv *grpc.Server
+			if grpcListener != nil {
+				grpcSrv = grpc.NewServer()
+				(&grpcapi.Service{
+					API: api,
+					Switch: func(_ context.Context, kubeContext, namespace string) (string, error) {
+						st, err := api.State()
+						if err != nil {
+							return "", err
+						}
+						i := slices.IndexFunc(st.Clusters, func(c state.ClusterRecord) bool { return c.KubeContext == kubeContext })
+						if i < 0 {
+							return "", fmt.Errorf("no context named %q in rift state", kubeContext)
+						}
+						return switchContextQuietly(app, st.Clusters[i], namespace)
+					},
+				}).Register(grpcSrv)
+				go func() { errs <- grpcSrv.Serve(grpcListener) }()
+				fmt.Fprintf(cmd.OutOrStdout(), "Serving gRPC on %s.\n", grpcListener.Addr())
+			}
+			select {
+			case err := <-errs:
+				if grpcSrv != nil {
+					grpcSrv.Stop()
+				}
+				_ = srv.Close()
+				return err
+			case <-ctx.Done():
+			}
+			if grpcSrv != nil {
+				// A running Sync would hold GracefulStop open; give it as
+				// long as HTTP shutdown gets.
+				stopped := make(chan struct{})
+				go func() { grpcSrv.GracefulStop(); close(stopped) }()
+				select {
+				case <-stopped:
+				case <-time.After(5 * time.Second):
+					grpcSrv.Stop()
+				}
+			}
+			shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			return srv.Shutdown(shutdown)
+		},
+	}
+	cmd.Flags().StringVar(&addr, "addr", "127.0.0.1:7777", "Address to listen on")
+	cmd.Flags().StringVar(&grpcAddr, "grpc", "", "Also serve the gRPC API on this address")
+	cmd.Flags().BoolVar(&grpcRemote, "grpc-remote", false, "Allow a --grpc address other machines can reach (the gRPC API has no authentication)")
+	cmd.Flags().BoolVar(&web, "web", false, "Also serve a browser dashboard at /")
+	cmd.Flags().BoolVar(&incremental, "incremental", false, "Run POST /sync as an incremental sync (see rift sync --incremental)")
+	return cmd
+}
+
+// checkServeAddr validates a listen address and warns when it is not a
+// loopback one.
+func checkServeAddr(app *App, flag, addr string) error {
+	loopback, err := loopbackAddr(addr)
+	if err != nil {
+		return fmt.Errorf("%s: %w", flag, err)
+	}
+	if !loopback {
+		app.Logger.Warn("serving state beyond this machine", "addr", addr)
+	}
+	return nil
+}
+
+// loopbackAddr reports whether addr (host:port) only listens on this
+// machine.
+func loopbackAddr(addr string) (bool, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false, err
+	}
+	ip := net.ParseIP(host)
+	return host == "localhost" || (ip != nil && ip.IsLoopback()), nil
+}
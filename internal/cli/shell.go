@@ -0,0 +1,176 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func newShellCmd(app *App) *cobra.Command {
+	var namespace string
+	cmd := &cobra.Command{
+		Use:   "shell <filter>",
+		Short: "Open your shell set up for a context, without switching kubectl",
+		Long: `Fuzzy-matches a kube context (or AWS profile) from state and starts your
+shell ($SHELL) with the environment rift exec gives commands: AWS_PROFILE
+and AWS_REGION, and for a context KUBECONFIG pointing at a temporary
+kubeconfig holding only that context, plus RIFT_CONTEXT and RIFT_NAMESPACE.
+The prompt starts with the context (bash, zsh, fish, and sh), so each
+terminal shows where it points. The global current-context never changes;
+exit the shell to leave.`,
+		Example: `  rift shell prod-payments
+  rift shell prod-payments -n kafka`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeTargets(app),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := app.loadConfig()
+			if err != nil {
+				return err
+			}
+			st, err := app.loadState()
+			if err != nil {
+				if errors.Is(err, os.ErrNotExist) {
+					return errStateNotFound
+				}
+				return err
+			}
+			target, err := resolveTarget(cmd.ErrOrStderr(), cmd.InOrStdin(), st, args[0])
+			if err != nil {
+				if errors.Is(err, errSelectionCancelled) {
+					return nil
+				}
+				return err
+			}
+			if target.Cluster == nil && namespace != "" {
+				return fmt.Errorf("--namespace needs a kube context, but %q matched AWS profile %s", args[0], target.Role.AWSProfile)
+			}
+			env, cleanup, err := targetEnv(cfg, target, namespace)
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+			run, cleanupPrompt, err := shellCommand(userShell(), env, shellLabel(target, namespace))
+			if err != nil {
+				return err
+			}
+			defer cleanupPrompt()
+			run.Stdin = cmd.InOrStdin()
+			run.Stdout = cmd.OutOrStdout()
+			run.Stderr = cmd.ErrOrStderr()
+
+			// As with rift exec, the shell handles Ctrl-C itself.
+			signals := make(chan os.Signal, 1)
+			signal.Notify(signals, os.Interrupt)
+			defer signal.Stop(signals)
+
+			fmt.Fprintf(cmd.ErrOrStderr(), "Starting %s for %s; exit to return.\n", filepath.Base(run.Path), target.Name)
+			if err := run.Run(); err != nil {
+				var exitErr *exec.ExitError
+				if errors.As(err, &exitErr) {
+					code := exitErr.ExitCode()
+					if code < 0 {
+						code = 1
+					}
+					return &ExitError{Code: code, Err: err, Silent: true}
+				}
+				return err
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&namespace, "namespace", "n", "", "Default namespace in the shell (overrides the context's)")
+	return cmd
+}
+
+// shellLabel is the prompt prefix of a shell for t: the context and its
+// namespace, or the AWS profile.
+func shellLabel(t target, namespace string) string {
+	if t.Cluster == nil {
+		return "rift:" + t.Name
+	}
+	if namespace == "" {
+		namespace = t.Cluster.Namespace
+	}
+	if namespace == "" {
+		return "rift:" + t.Cluster.KubeContext
+	}
+	return "rift:" + t.Cluster.KubeContext + "/" + namespace
+}
+
+// shellCommand starts shell interactively with env, its prompt prefixed by
+// label. bash and zsh get a startup file that reads the user's own first;
+// fish wraps fish_prompt; other shells only get PS1, which their startup
+// files may override. cleanup removes the startup files.
+func shellCommand(shell string, env []string, label string) (cmd *exec.Cmd, cleanup func(), err error) {
+	env = append(env, "RIFT_PROMPT="+label)
+	cleanup = func() {}
+	switch strings.TrimSuffix(filepath.Base(shell), ".exe") {
+	case "bash":
+		dir, err := os.MkdirTemp("", "rift-shell-")
+		if err != nil {
+			return nil, nil, err
+		}
+		cleanup = func() { os.RemoveAll(dir) }
+		rc := filepath.Join(dir, "bashrc")
+		if err := os.WriteFile(rc, []byte(bashPromptRC), 0o600); err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+		cmd = exec.Command(shell, "--rcfile", rc, "-i")
+	case "zsh":
+		dir, err := os.MkdirTemp("", "rift-shell-")
+		if err != nil {
+			return nil, nil, err
+		}
+		cleanup = func() { os.RemoveAll(dir) }
+		for name, content := range map[string]string{".zshenv": zshPromptEnv, ".zshrc": zshPromptRC} {
+			if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+				cleanup()
+				return nil, nil, err
+			}
+		}
+		home, _ := os.UserHomeDir()
+		if zdotdir := os.Getenv("ZDOTDIR"); zdotdir != "" {
+			home = zdotdir
+		}
+		env = append(scrubEnv(env, []string{"ZDOTDIR"}), "ZDOTDIR="+dir, "RIFT_ZDOTDIR="+home)
+		cmd = exec.Command(shell, "-i")
+	case "fish":
+		cmd = exec.Command(shell, "--init-command", fishPromptInit)
+	case "cmd":
+		env = append(scrubEnv(env, []string{"PROMPT"}), "PROMPT=("+label+") $P$G")
+		cmd = exec.Command(shell)
+	default:
+		env = append(scrubEnv(env, []string{"PS1"}), "PS1=("+label+") $ ")
+		cmd = exec.Command(shell)
+	}
+	cmd.Env = env
+	return cmd, cleanup, nil
+}
+
+const bashPromptRC = `[ -f ~/.bashrc ] && . ~/.bashrc
+PS1="($RIFT_PROMPT) $PS1"
+`
+
+// zsh reads both from ZDOTDIR; they restore the user's ZDOTDIR and read
+// the files there.
+const (
+	zshPromptEnv = `[ -f "$RIFT_ZDOTDIR/.zshenv" ] && . "$RIFT_ZDOTDIR/.zshenv"
+`
+	zshPromptRC = `ZDOTDIR="$RIFT_ZDOTDIR"
+[ -f "$ZDOTDIR/.zshrc" ] && . "$ZDOTDIR/.zshrc"
+PROMPT="($RIFT_PROMPT) $PROMPT"
+`
+)
+
+const fishPromptInit = `functions -q fish_prompt; and functions -c fish_prompt __rift_fish_prompt
+function fish_prompt
+    echo -n "($RIFT_PROMPT) "
+    functions -q __rift_fish_prompt; and __rift_fish_prompt
+end`
@@ -0,0 +1,169 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/lithammer/fuzzysearch/fuzzy"
+	"github.com/phenixrizen/rift/internal/discovery"
+	"github.com/phenixrizen/rift/internal/rifterr"
+	"github.com/spf13/cobra"
+)
+
+func newSSMCmd(app *App) *cobra.Command {
+	var list bool
+	cmd := &cobra.Command{
+		Use:   "ssm <filter> [node]",
+		Short: "Start an SSM session on one of a context's EC2 nodes",
+		Long: `Fuzzy-matches a kube context from state, lists its cluster's running EC2
+nodes with the role's SSO credentials, and starts an SSM Session Manager
+session on the node you pick (or the one [node] fuzzy-matches by name,
+instance ID, or node group), via aws ssm start-session.
+
+Needs the AWS CLI and its session-manager-plugin, and nodes running the
+SSM agent with an instance profile that allows it (the EKS optimized AMIs
+do). --list prints the nodes instead.`,
+		Example: `  rift ssm prod-payments
+  rift ssm prod-payments ip-10-0-12-34
+  rift ssm prod-payments --list`,
+		Args:              cobra.RangeArgs(1, 2),
+		ValidArgsFunction: completeContexts(app),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := app.loadConfig()
+			if err != nil {
+				return err
+			}
+			st, err := app.loadState()
+			if err != nil {
+				if errors.Is(err, os.ErrNotExist) {
+					return errStateNotFound
+				}
+				return err
+			}
+			target, err := resolveTarget(cmd.ErrOrStderr(), cmd.InOrStdin(), st, args[0])
+			if err != nil {
+				if errors.Is(err, errSelectionCancelled) {
+					return nil
+				}
+				return err
+			}
+			if target.Cluster == nil {
+				return fmt.Errorf("%q matched AWS profile %s; rift ssm needs a kube context", args[0], target.Role.AWSProfile)
+			}
+			rec := *target.Cluster
+			if !rec.IsAWS() || rec.External() || target.Role.AWSProfile == "" {
+				return fmt.Errorf("%s is not an EKS cluster rift discovered; it has no nodes to reach over SSM", rec.KubeContext)
+			}
+			creds, err := discovery.RoleCredentials(cmd.Context(), cfg, st, target.Role, app.credentialCache(cfg))
+			if err != nil {
+				if errors.Is(err, discovery.ErrSSONotLoggedIn) {
+					return rifterr.Wrap(rifterr.CodeAuthRequired, ErrSSOLoginRequired, "run: rift auth")
+				}
+				return fmt.Errorf("get credentials for %s: %w", target.Role.AWSProfile, err)
+			}
+			nodes, err := discovery.ClusterNodes(cmd.Context(), creds, rec.Region, rec.ClusterName)
+			if err != nil {
+				return fmt.Errorf("list nodes of %s: %w", rec.ClusterName, err)
+			}
+			if len(nodes) == 0 {
+				return fmt.Errorf("%s has no running EC2 nodes (Fargate pods cannot be reached over SSM)", rec.ClusterName)
+			}
+
+			out := cmd.OutOrStdout()
+			if list {
+				w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+				fmt.Fprintln(w, "Node\tInstance\tNode group\tType\tZone\tPrivate IP")
+				for _, n := range nodes {
+					fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", n.Name, n.InstanceID, n.Nodegroup, n.InstanceType, n.Zone, n.PrivateIP)
+				}
+				return w.Flush()
+			}
+
+			byKey := map[string]discovery.Node{}
+			filter := ""
+			var ranks fuzzy.Ranks
+			if len(args) == 2 {
+				filter = args[1]
+				// Match on the node group too, so "workers" lists its nodes.
+				haystack := make([]string, len(nodes))
+				for i, n := range nodes {
+					haystack[i] = nodeKey(n) + " " + n.Nodegroup
+					if filter == n.InstanceID || filter == n.Name {
+						filter = nodeKey(n)
+					}
+				}
+				ranks = fuzzy.RankFindNormalizedFold(filter, haystack)
+				if len(ranks) == 0 {
+					return fmt.Errorf("no node of %s matches %q", rec.ClusterName, args[1])
+				}
+				for i := range ranks {
+					ranks[i].Target = nodeKey(nodes[ranks[i].OriginalIndex])
+				}
+				sort.Sort(ranks)
+			} else {
+				for i, n := range nodes {
+					ranks = append(ranks, fuzzy.Rank{Target: nodeKey(n), OriginalIndex: i})
+				}
+			}
+			for _, n := range nodes {
+				byKey[nodeKey(n)] = n
+			}
+			selected, err := pickTarget(cmd.ErrOrStderr(), cmd.InOrStdin(), "nodes", filter, ranks, func(key string) string {
+				n := byKey[key]
+				return fmt.Sprintf("%s | %s | %s | %s", n.Nodegroup, n.InstanceType, n.Zone, n.PrivateIP)
+			})
+			if err != nil {
+				if errors.Is(err, errSelectionCancelled) {
+					fmt.Fprintln(cmd.ErrOrStderr(), "Selection cancelled.")
+					return nil
+				}
+				return err
+			}
+			node := byKey[selected]
+
+			env := scrubEnv(os.Environ(), execScrubbedEnv)
+			for _, v := range credentialEnv(creds, rec.Region) {
+				env = append(env, v.Name+"="+v.Value)
+			}
+			run := exec.Command("aws", "ssm", "start-session", "--target", node.InstanceID, "--region", rec.Region)
+			run.Env = env
+			run.Stdin = cmd.InOrStdin()
+			run.Stdout = out
+			run.Stderr = cmd.ErrOrStderr()
+
+			// The session handles Ctrl-C itself, as with rift exec.
+			signals := make(chan os.Signal, 1)
+			signal.Notify(signals, os.Interrupt)
+			defer signal.Stop(signals)
+
+			fmt.Fprintf(cmd.ErrOrStderr(), "Starting SSM session on %s (%s)\n", node.Name, node.InstanceID)
+			if err := run.Run(); err != nil {
+				if errors.Is(err, exec.ErrNotFound) {
+					return fmt.Errorf("aws CLI not found in PATH; install AWS CLI v2 and the session-manager-plugin")
+				}
+				var exitErr *exec.ExitError
+				if errors.As(err, &exitErr) {
+					code := exitErr.ExitCode()
+					if code < 0 {
+						code = 1
+					}
+					return &ExitError{Code: code, Err: err, Silent: true}
+				}
+				return err
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&list, "list", false, "List the nodes instead of starting a session")
+	return cmd
+}
+
+// nodeKey names a node in rift ssm's picker.
+func nodeKey(n discovery.Node) string {
+	return n.Name + " (" + n.InstanceID + ")"
+}
@@ -0,0 +1,487 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/phenixrizen/rift/internal/config"
+	"github.com/phenixrizen/rift/internal/discovery"
+	"github.com/phenixrizen/rift/internal/history"
+	"github.com/phenixrizen/rift/internal/rifterr"
+	"github.com/phenixrizen/rift/internal/s3state"
+	"github.com/phenixrizen/rift/internal/state"
+	"github.com/phenixrizen/rift/internal/statecrypt"
+	"github.com/spf13/cobra"
+)
+
+type importStateOutput struct {
+	Source  string           `json:"source"`
+	Changes []history.Change `json:"changes"`
+	Backup  string           `json:"backup,omitempty"`
+}
+
+type pullOutput struct {
+	URL      string           `json:"url"`
+	UpToDate bool             `json:"up_to_date"`
+	Changes  []history.Change `json:"changes"`
+	Backup   string           `json:"backup,omitempty"`
+}
+
+func newStateCmd(app *App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "state",
+		Short: "Share state.json through state_backend, import a teammate's, print its key",
+	}
+	cmd.AddCommand(newStatePullCmd(app), newStatePushCmd(app), newStateImportCmd(app), newStateKeyCmd(app))
+	return cmd
+}
+
+func newStatePullCmd(app *App) *cobra.Command {
+	var dryRun, force bool
+	cmd := &cobra.Command{
+		Use:   "pull",
+		Short: "Download state from state_backend and write its profiles and contexts",
+		Long: `Downloads the state.json another machine pushed to state_backend, replaces
+the local state.json with it, and writes its AWS profiles and kube contexts
+the way sync would, without running discovery. Contexts adopted with rift
+import kubeconfig and records from rift state import stay those of this
+machine. The files are backed up first (see rift restore).
+
+Nothing is downloaded when the backend object is unchanged since the last
+pull or push and state.json has not changed locally since either; --force
+downloads it anyway.`,
+		Example: `  rift state pull --dry-run
+  rift state pull`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			cfg, err := app.loadConfig()
+			if err != nil {
+				return err
+			}
+			store, loc, err := app.stateStore(cmd.Context(), cfg)
+			if err != nil {
+				return err
+			}
+			cachePath := s3state.CachePathFor(app.StatePath)
+			cache, err := s3state.LoadCache(cachePath)
+			if err != nil {
+				return err
+			}
+			// A local state.json changed since the last pull or push (a sync
+			// whose push failed, say) is not up to date whatever the ETag.
+			etag := ""
+			if local, readErr := os.ReadFile(app.StatePath); readErr == nil && !force && cache.Current(loc.String(), local) {
+				etag = cache.ETag
+			}
+			data, newETag, notModified, err := store.Get(cmd.Context(), etag)
+			if err != nil {
+				if errors.Is(err, s3state.ErrNotFound) {
+					return rifterr.Wrap(rifterr.CodeStateMissing, err, "run: rift sync (or rift state push) on the machine that has it")
+				}
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			result := pullOutput{URL: loc.String(), UpToDate: notModified, Changes: make([]history.Change, 0)}
+			if notModified {
+				if app.Output == "json" {
+					enc := json.NewEncoder(out)
+					enc.SetIndent("", "  ")
+					return enc.Encode(result)
+				}
+				fmt.Fprintf(out, "state.json is up to date with %s\n", loc)
+				return nil
+			}
+			sealer, err := app.stateSealer()
+			if err != nil {
+				return err
+			}
+			st, err := state.Decode(data, sealer)
+			if err != nil {
+				if errors.Is(err, state.ErrNewerVersion) {
+					return rifterr.Wrap(rifterr.CodeStateInvalid, fmt.Errorf("%s: %w", loc, err), "upgrade rift")
+				}
+				return fmt.Errorf("%s: %w", loc, err)
+			}
+			prev, prevErr := app.loadState()
+			if prevErr != nil && !errors.Is(prevErr, os.ErrNotExist) {
+				return prevErr
+			}
+			// Imported kubeconfig contexts and shared records belong to the
+			// machine that has them: keep this one's, not the pusher's.
+			st.Clusters = slices.DeleteFunc(st.Clusters, state.ClusterRecord.External)
+			st.DropShared("")
+			st.CarryExternal(prev)
+			st.CarryShared(prev)
+			diff := history.Compare(prev, st)
+			result.Changes = diff.Changes
+
+			report, err := app.applyState(cfg, "state pull", st, prev, dryRun, false)
+			if err != nil {
+				return err
+			}
+			result.Backup = report.Backup
+			if !dryRun {
+				if err := app.saveState(st); err != nil {
+					return fmt.Errorf("write state: %w", err)
+				}
+				if err := app.recordSnapshot(cfg, prev, prevErr == nil, st); err != nil && app.Logger != nil {
+					app.Logger.Warn("unable to record state snapshot", "error", err)
+				}
+				written, err := os.ReadFile(app.StatePath)
+				if err != nil {
+					return err
+				}
+				if err := s3state.SaveCache(cachePath, s3state.Cache{URL: loc.String(), ETag: newETag, At: time.Now().UTC(), StateSHA256: s3state.StateHash(written)}); err != nil {
+					return err
+				}
+			}
+
+			if app.Output == "json" {
+				enc := json.NewEncoder(out)
+				enc.SetIndent("", "  ")
+				return enc.Encode(result)
+			}
+			printStateDiff(out, "local", loc.String(), diff)
+			fmt.Fprintf(out, "AWS profiles: +%d ~%d -%d\n", report.AWS.Added, report.AWS.Updated, report.AWS.Removed)
+			println(out, report.kubeLines()...)
+			if dryRun {
+				if d := report.diff(); d != "" {
+					fmt.Fprint(out, "\n"+d)
+				}
+				println(out, "Dry run complete (no files written)")
+				return nil
+			}
+			fmt.Fprintf(out, "State written: %s\n", app.StatePath)
+			if report.Backup != "" {
+				fmt.Fprintf(out, "Backup: %s (undo with: rift restore)\n", report.Backup)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show what would change without writing files")
+	cmd.Flags().BoolVar(&force, "force", false, "Download even when the backend object looks unchanged")
+	return cmd
+}
+
+func newStatePushCmd(app *App) *cobra.Command {
+	return &cobra.Command{
+		Use:   "push",
+		Short: "Upload the local state.json to state_backend",
+		Long: `Uploads state.json to state_backend. Sync already does this after every run;
+push is for state changed otherwise (rift import kubeconfig, rift migrate).
+The last upload wins.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			cfg, err := app.loadConfig()
+			if err != nil {
+				return err
+			}
+			url, err := app.pushState(cmd.Context(), cfg)
+			if err != nil {
+				return err
+			}
+			if app.Output == "json" {
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				return enc.Encode(map[string]string{"url": url})
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Pushed %s to %s\n", app.StatePath, url)
+			return nil
+		},
+	}
+}
+
+func newStateImportCmd(app *App) *cobra.Command {
+	var dryRun, remove bool
+	cmd := &cobra.Command{
+		Use:   "import <file|url>",
+		Short: "Add a teammate's clusters and roles as read-only shared records",
+		Long: `Reads a state.json someone else exported (a file, - for stdin, or an https
+URL) and adds its roles and clusters to your state as shared records, then
+writes their AWS profiles and kube contexts the way sync would. This lets a
+new team member browse and use contexts before their own discovery works;
+whether a context actually connects still depends on the roles they hold.
+Shared clusters of a role you hold use your profile for it; those whose
+endpoint none of your own clusters have are listed in a warning.
+
+Shared records are marked with their source and never modified: importing
+the same source again replaces them, records you already discovered
+yourself are skipped, and each one is dropped once a sync of yours discovers
+the same role or cluster. --remove drops the records of a source (all
+sources with "all").`,
+		Example: `  rift state import ~/Downloads/alice-state.json
+  rift state import https://wiki.example.com/rift/platform-state.json --dry-run
+  rift state import --remove all`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			source := args[0]
+			cfg, err := app.loadConfig()
+			if err != nil {
+				return err
+			}
+			prev, prevErr := app.loadState()
+			if prevErr != nil && !errors.Is(prevErr, os.ErrNotExist) {
+				return prevErr
+			}
+			st := prev
+			st.Roles = append([]state.RoleRecord(nil), prev.Roles...)
+			st.Clusters = append([]state.ClusterRecord(nil), prev.Clusters...)
+			if prevErr != nil {
+				st = state.State{GeneratedAt: time.Now().UTC(), Regions: cfg.AllRegions()}
+			}
+
+			// Files are recorded by absolute path, so --remove and a
+			// re-import match from any directory.
+			if !strings.Contains(source, "://") && source != "-" && !(remove && source == "all") {
+				if abs, err := filepath.Abs(source); err == nil {
+					source = abs
+				}
+			}
+			target := source
+			if remove {
+				target = "local without " + source
+				from := source
+				if from == "all" {
+					from = ""
+				}
+				if st.DropShared(from) == 0 {
+					sources := prev.SharedSources()
+					if len(sources) == 0 {
+						return fmt.Errorf("state has no shared records")
+					}
+					return fmt.Errorf("no shared records from %s (sources: %s)", args[0], strings.Join(sources, ", "))
+				}
+			} else {
+				data, err := readSharedState(cmd, source)
+				if err != nil {
+					return err
+				}
+				// The teammate's file is usually plain; an encrypted one only
+				// opens with a key shared across the team.
+				sealer, _ := app.stateSealer()
+				shared, err := state.Decode(data, sealer)
+				if err != nil {
+					if errors.Is(err, state.ErrSealed) {
+						return rifterr.Wrap(rifterr.CodeStateInvalid, fmt.Errorf("%s: %w", source, err), "ask for an unencrypted copy (or the key, as "+statecrypt.EnvKey+")")
+					}
+					return fmt.Errorf("%s: %w", source, err)
+				}
+				localSessions(cfg, &shared)
+				if rebound := st.MergeShared(shared, source); len(rebound) > 0 {
+					warnRebound(cmd.ErrOrStderr(), source, rebound)
+				}
+			}
+			if ov, err := app.loadOverlay(); err == nil {
+				ov.Apply(&st)
+			}
+			diff := history.Compare(prev, st)
+
+			report, err := app.applyState(cfg, "state import", st, prev, dryRun, false)
+			if err != nil {
+				return err
+			}
+			if !dryRun {
+				if err := app.saveState(st); err != nil {
+					return fmt.Errorf("write state: %w", err)
+				}
+				if err := app.recordSnapshot(cfg, prev, prevErr == nil, st); err != nil && app.Logger != nil {
+					app.Logger.Warn("unable to record state snapshot", "error", err)
+				}
+			}
+
+			out := cmd.OutOrStdout()
+			if app.Output == "json" {
+				enc := json.NewEncoder(out)
+				enc.SetIndent("", "  ")
+				return enc.Encode(importStateOutput{Source: source, Changes: append(make([]history.Change, 0), diff.Changes...), Backup: report.Backup})
+			}
+			printStateDiff(out, "local", target, diff)
+			fmt.Fprintf(out, "AWS profiles: +%d ~%d -%d\n", report.AWS.Added, report.AWS.Updated, report.AWS.Removed)
+			println(out, report.kubeLines()...)
+			if dryRun {
+				if d := report.diff(); d != "" {
+					fmt.Fprint(out, "\n"+d)
+				}
+				println(out, "Dry run complete (no files written)")
+				return nil
+			}
+			fmt.Fprintf(out, "State written: %s\n", app.StatePath)
+			if report.Backup != "" {
+				fmt.Fprintf(out, "Backup: %s (undo with: rift restore)\n", report.Backup)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show what would change without writing files")
+	cmd.Flags().BoolVar(&remove, "remove", false, "Drop the shared records of this source (or \"all\") instead of importing")
+	return cmd
+}
+
+// warnRebound lists the shared clusters that will authenticate with the
+// user's own profiles at endpoints only the imported file vouches for.
+func warnRebound(w io.Writer, source string, rebound []state.ClusterRecord) {
+	fmt.Fprintf(w, "WARNING: %d shared clusters from %s use your own AWS profiles at endpoints none of your clusters have:\n", len(rebound), source)
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, c := range rebound {
+		fmt.Fprintf(tw, "  %s\t%s\t%s\n", c.KubeContext, c.AWSProfile, c.ClusterEndpoint)
+	}
+	tw.Flush()
+	fmt.Fprintln(w, "Only use these contexts if you trust the source; undo with: rift state import --remove "+source)
+}
+
+// readSharedState reads the state file to import from a path, stdin ("-"),
+// or an https URL. Plain http is refused: the file decides which endpoints
+// get the user's credentials.
+func readSharedState(cmd *cobra.Command, source string) ([]byte, error) {
+	switch {
+	case source == "-":
+		return io.ReadAll(cmd.InOrStdin())
+	case strings.HasPrefix(source, "https://"):
+		ctx, cancel := context.WithTimeout(cmd.Context(), 30*time.Second)
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("get %s: %s", source, resp.Status)
+		}
+		return io.ReadAll(resp.Body)
+	case strings.HasPrefix(source, "http://"):
+		return nil, fmt.Errorf("refusing to import state over plain http: %s (use https, a file, or -)", source)
+	case strings.Contains(source, "://"):
+		return nil, fmt.Errorf("unsupported source %q (use a file, -, or an https URL)", source)
+	}
+	return os.ReadFile(source)
+}
+
+// localSessions points shared records at the primary SSO session when the
+// teammate's session name is not configured here.
+func localSessions(cfg config.Config, st *state.State) {
+	for i := range st.Roles {
+		if _, ok := cfg.Session(st.Roles[i].SSOSession); !ok {
+			st.Roles[i].SSOSession = ""
+		}
+	}
+	for i := range st.Clusters {
+		if _, ok := cfg.Session(st.Clusters[i].SSOSession); !ok {
+			st.Clusters[i].SSOSession = ""
+		}
+	}
+}
+
+func newStateKeyCmd(app *App) *cobra.Command {
+	return &cobra.Command{
+		Use:   "key",
+		Short: "Print the key that encrypts state.json (state_encryption: age)",
+		Long: `Prints the age identity rift encrypts state.json and its snapshots with,
+taken from RIFT_STATE_KEY or the OS keychain (a new one is generated and
+stored in the keychain the first time). Set it as RIFT_STATE_KEY on another
+machine, such as a cloud dev box without a keychain, to read the same
+encrypted state (for example after rift state pull).
+
+The key is a secret: anyone holding it can decrypt your state.`,
+		Example: `  export RIFT_STATE_KEY="$(rift state key)"`,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			key, source, err := statecrypt.LoadKey(true)
+			if err != nil {
+				return rifterr.Wrap(rifterr.CodeConfigInvalid, err, "set "+statecrypt.EnvKey+" to an age identity")
+			}
+			if _, err := statecrypt.New(key); err != nil {
+				return rifterr.Wrap(rifterr.CodeConfigInvalid, err, "set "+statecrypt.EnvKey+" to an age identity")
+			}
+			out := cmd.OutOrStdout()
+			if app.Output == "json" {
+				enc := json.NewEncoder(out)
+				enc.SetIndent("", "  ")
+				return enc.Encode(map[string]string{"key": key, "source": string(source)})
+			}
+			// stdout is usually captured, so the note goes to stderr.
+			fmt.Fprintf(cmd.ErrOrStderr(), "# state key from %s\n", source)
+			println(out, key)
+			return nil
+		},
+	}
+}
+
+// stateStore connects to state_backend with the configured SSO role.
+func (a *App) stateStore(ctx context.Context, cfg config.Config) (*s3state.Store, s3state.Location, error) {
+	if cfg.StateBackend == "" {
+		return nil, s3state.Location{}, rifterr.New(rifterr.CodeConfigInvalid, "state_backend is not configured", "set state_backend, state_backend_account, and state_backend_role in config.yaml")
+	}
+	loc, err := s3state.ParseURL(cfg.StateBackend)
+	if err != nil {
+		return nil, loc, rifterr.Wrap(rifterr.CodeConfigInvalid, err, "use s3://bucket/prefix")
+	}
+	session, ok := cfg.Session(cfg.StateBackendSSOSession)
+	if !ok {
+		return nil, loc, rifterr.New(rifterr.CodeConfigInvalid, fmt.Sprintf("state_backend_sso_session %q is not configured", cfg.StateBackendSSOSession), "")
+	}
+	role := state.RoleRecord{
+		AccountID:  cfg.StateBackendAccount,
+		RoleName:   cfg.StateBackendRole,
+		AWSProfile: "state_backend",
+		SSOSession: session.ID(),
+	}
+	creds, err := discovery.RoleCredentials(ctx, cfg, state.State{}, role, a.credentialCache(cfg))
+	if err != nil {
+		if errors.Is(err, discovery.ErrSSONotLoggedIn) {
+			return nil, loc, rifterr.Wrap(rifterr.CodeAuthRequired, ErrSSOLoginRequired, "run: rift auth")
+		}
+		return nil, loc, fmt.Errorf("get credentials for state_backend: %w", err)
+	}
+	region := cfg.StateBackendRegion
+	if region == "" {
+		region = session.Region
+	}
+	return s3state.New(loc, region, creds), loc, nil
+}
+
+// pushState uploads the local state.json to state_backend and returns its
+// URL.
+func (a *App) pushState(ctx context.Context, cfg config.Config) (string, error) {
+	data, err := os.ReadFile(a.StatePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", errStateNotFound
+		}
+		return "", err
+	}
+	sealer, err := a.stateSealer()
+	if err != nil {
+		return "", err
+	}
+	if _, err := state.Decode(data, sealer); err != nil {
+		return "", fmt.Errorf("load state %s: %w", a.StatePath, err)
+	}
+	store, loc, err := a.stateStore(ctx, cfg)
+	if err != nil {
+		return "", err
+	}
+	etag, err := store.Put(ctx, data)
+	if err != nil {
+		return "", err
+	}
+	cache := s3state.Cache{URL: loc.String(), ETag: etag, At: time.Now().UTC(), Pushed: true, StateSHA256: s3state.StateHash(data)}
+	if err := s3state.SaveCache(s3state.CachePathFor(a.StatePath), cache); err != nil {
+		return "", err
+	}
+	return loc.String(), nil
+}
@@ -0,0 +1,119 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/phenixrizen/rift/internal/health"
+	"github.com/phenixrizen/rift/internal/rifterr"
+	"github.com/spf13/cobra"
+)
+
+func newStatusCmd(app *App) *cobra.Command {
+	var envs []string
+	var tags []string
+	var concurrency int
+	var timeout time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "status [filter]",
+		Short: "Check that every cluster's API server is reachable",
+		Long: `Calls /version on the API server of each rift context at the endpoint in
+state, with a token from its exec plugin (or anonymously when none can be
+issued), and reports whether it answered, how fast, and its Kubernetes
+version.
+
+Any answer counts as reachable, a 401 or 403 included: status sweeps the
+fleet for broken endpoints, while rift verify checks that you can actually
+authenticate. Exits 1 when any context is unreachable.`,
+		Example: `  rift status
+  rift status --env prod
+  rift status -o json | jq '.results[] | select(.reachable | not)'`,
+		Args:              cobra.MaximumNArgs(1),
+		ValidArgsFunction: completeContexts(app),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			st, err := app.loadState()
+			if err != nil {
+				if errors.Is(err, os.ErrNotExist) {
+					return errStateNotFound
+				}
+				return err
+			}
+			filter := ""
+			if len(args) == 1 {
+				filter = args[0]
+			}
+			clusters := filterForVerify(filterByTags(st.Clusters, tags), filter, envs)
+			if len(clusters) == 0 {
+				println(cmd.OutOrStdout(), "No clusters match the given filters.")
+				return nil
+			}
+
+			ctx, cancel := context.WithTimeout(cmd.Context(), timeout)
+			defer cancel()
+			results := health.PingAll(ctx, clusters, concurrency)
+			unreachable := 0
+			for _, r := range results {
+				if !r.Reachable {
+					unreachable++
+				}
+			}
+
+			out := cmd.OutOrStdout()
+			if app.Output == "json" {
+				enc := json.NewEncoder(out)
+				enc.SetIndent("", "  ")
+				if err := enc.Encode(struct {
+					Results     []health.Reachability `json:"results"`
+					Reachable   int                   `json:"reachable"`
+					Unreachable int                   `json:"unreachable"`
+				}{results, len(results) - unreachable, unreachable}); err != nil {
+					return err
+				}
+			} else {
+				printStatusResults(out, results, unreachable)
+			}
+
+			if unreachable > 0 {
+				err := rifterr.New(rifterr.CodePartialFailure, fmt.Sprintf("%d of %d contexts unreachable", unreachable, len(results)), "see the error column for each context")
+				return &ExitError{Code: 1, Err: err, Silent: true}
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringSliceVar(&envs, "env", nil, "Only check contexts in these envs (repeatable)")
+	cmd.Flags().StringSliceVar(&tags, "tag", nil, "Only check contexts with these tags (repeatable)")
+	cmd.Flags().IntVar(&concurrency, "concurrency", 8, "Number of contexts to check in parallel")
+	cmd.Flags().DurationVar(&timeout, "timeout", time.Minute, "Overall time limit for the sweep")
+	_ = cmd.RegisterFlagCompletionFunc("env", completeEnvs(app))
+	_ = cmd.RegisterFlagCompletionFunc("tag", completeTags(app))
+	return cmd
+}
+
+func printStatusResults(out io.Writer, results []health.Reachability, unreachable int) {
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "CONTEXT\tREACHABLE\tVERSION\tLATENCY\tDETAIL")
+	for _, r := range results {
+		reachable := "yes"
+		if !r.Reachable {
+			reachable = "no"
+		}
+		latency := "-"
+		if r.Latency > 0 {
+			latency = r.Latency.Round(time.Millisecond).String()
+		}
+		version := r.Version
+		if version == "" {
+			version = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", r.Context, reachable, version, latency, firstLine(r.Error))
+	}
+	_ = tw.Flush()
+	fmt.Fprintf(out, "\n%d contexts checked: reachable=%d unreachable=%d\n", len(results), len(results)-unreachable, unreachable)
+}
@@ -3,37 +3,81 @@ package cli
 import (
 	"context"
 	"fmt"
+	"io"
+	"os"
+
+	"github.com/phenixrizen/rift/internal/discovery"
 
 	"github.com/spf13/cobra"
 )
 
 func newSyncCmd(app *App) *cobra.Command {
-	var dryRun bool
+	var opts SyncOptions
 	cmd := &cobra.Command{
 		Use:   "sync",
 		Short: "Discover AWS SSO + EKS and sync AWS/kube configs",
 		RunE: func(cmd *cobra.Command, _ []string) error {
-			report, err := app.RunSync(context.Background(), dryRun)
+			if len(opts.Refresh) > 0 && !opts.Incremental {
+				return fmt.Errorf("--account only applies with --incremental")
+			}
+			if isTerminal(os.Stderr) {
+				opts.Progress = progressLine(os.Stderr)
+				defer fmt.Fprint(os.Stderr, "\r\033[K")
+			}
+			report, err := app.RunSync(context.Background(), opts)
 			if err != nil {
 				return err
 			}
 			out := cmd.OutOrStdout()
-			if dryRun {
+			if opts.DryRun {
 				println(out, "Dry run complete (no files written)")
 			}
+			if report.Inventory.ReusedAccounts > 0 {
+				fmt.Fprintf(out, "Accounts reused from state: %d\n", report.Inventory.ReusedAccounts)
+			}
 			fmt.Fprintf(out, "Discovered roles:    %d\n", len(report.State.Roles))
 			fmt.Fprintf(out, "Discovered clusters: %d\n", len(report.State.Clusters))
+			println(out, failureLines(report.failures())...)
 			if report.NS.Enabled {
 				fmt.Fprintf(out, "Namespaces: tried=%d updated=%d errors=%d\n", report.NS.ClustersTried, report.NS.ClustersUpdated, report.NS.Errors)
 			}
 			fmt.Fprintf(out, "AWS profiles: +%d ~%d -%d\n", report.AWS.Added, report.AWS.Updated, report.AWS.Removed)
-			fmt.Fprintf(out, "Kube contexts: +%d ~%d -%d\n", report.Kube.AddedContexts, report.Kube.UpdatedContexts, report.Kube.RemovedContexts)
-			if !dryRun {
+			println(out, report.kubeLines()...)
+			if !opts.DryRun {
 				fmt.Fprintf(out, "State written: %s\n", app.StatePath)
 			}
+			if report.Pushed != "" {
+				fmt.Fprintf(out, "State pushed:  %s\n", report.Pushed)
+			}
+			if opts.DryRun {
+				if diff := report.diff(); diff != "" {
+					fmt.Fprint(out, "\n"+diff)
+				}
+			}
+			if report.Backup != "" {
+				fmt.Fprintf(out, "Backup: %s (undo with: rift restore)\n", report.Backup)
+			}
 			return nil
 		},
 	}
-	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Preview changes without writing files")
+	cmd.Flags().BoolVar(&opts.DryRun, "dry-run", false, "Preview changes without writing files")
+	cmd.Flags().BoolVar(&opts.Incremental, "incremental", false, "Reuse clusters from state for accounts whose roles are unchanged")
+	cmd.Flags().StringSliceVar(&opts.Refresh, "account", nil, "With --incremental, re-list these account IDs or names anyway (repeatable)")
+	cmd.Flags().BoolVar(&opts.RefreshNamespaces, "refresh-namespaces", false, "List every cluster's namespaces again, ignoring namespace_ttl")
+	cmd.Flags().BoolVar(&opts.Force, "force", false, "Reset rift-managed kubeconfig entries, dropping your edits to them")
 	return cmd
 }
+
+// progressLine redraws a single status line on w for each sync update.
+func progressLine(w io.Writer) func(discovery.Progress) {
+	return func(p discovery.Progress) {
+		fmt.Fprintf(w, "\r\033[K%s", progressText(p))
+	}
+}
+
+// isTerminal reports whether f is a character device, so progress redraws
+// are not written into redirected output.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,118 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/phenixrizen/rift/internal/overlay"
+	"github.com/phenixrizen/rift/internal/state"
+	"github.com/spf13/cobra"
+)
+
+func newTagCmd(app *App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tag",
+		Short: "Manage user-defined tags on kube contexts",
+	}
+	cmd.AddCommand(
+		&cobra.Command{
+			Use:               "add <context|glob> <tag>...",
+			Short:             "Add tags to a context (or contexts matching a glob)",
+			Args:              cobra.MinimumNArgs(2),
+			ValidArgsFunction: completeContextThenTags(app),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return updateTags(app, cmd, args[0], args[1:], false)
+			},
+		},
+		&cobra.Command{
+			Use:               "remove <context|glob> <tag>...",
+			Aliases:           []string{"rm"},
+			Short:             "Remove tags from a context key",
+			Args:              cobra.MinimumNArgs(2),
+			ValidArgsFunction: completeContextThenTags(app),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return updateTags(app, cmd, args[0], args[1:], true)
+			},
+		},
+		&cobra.Command{
+			Use:     "list",
+			Aliases: []string{"ls"},
+			Short:   "List tagged contexts",
+			RunE: func(cmd *cobra.Command, _ []string) error {
+				ov, err := app.loadOverlay()
+				if err != nil {
+					return err
+				}
+				if len(ov.Tags) == 0 {
+					println(cmd.OutOrStdout(), "No tags defined.")
+					return nil
+				}
+				keys := make([]string, 0, len(ov.Tags))
+				for key := range ov.Tags {
+					keys = append(keys, key)
+				}
+				sort.Strings(keys)
+				w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+				fmt.Fprintln(w, "Context\tTags")
+				for _, key := range keys {
+					fmt.Fprintf(w, "%s\t%s\n", key, strings.Join(ov.Tags[key], ","))
+				}
+				return w.Flush()
+			},
+		},
+	)
+	return cmd
+}
+
+func updateTags(app *App, cmd *cobra.Command, key string, tags []string, remove bool) error {
+	if _, err := path.Match(key, ""); err != nil {
+		return fmt.Errorf("invalid context pattern %q: %w", key, err)
+	}
+	ov, err := app.loadOverlay()
+	if err != nil {
+		return err
+	}
+	if remove {
+		ov.RemoveTags(key, tags...)
+	} else {
+		ov.AddTags(key, tags...)
+	}
+	if err := overlay.Save(app.overlayPath(), ov); err != nil {
+		return fmt.Errorf("write overlay: %w", err)
+	}
+
+	out := cmd.OutOrStdout()
+	fmt.Fprintf(out, "%s: %s\n", key, strings.Join(ov.Tags[key], ","))
+	if st, err := app.loadState(); err == nil && !remove {
+		matched := 0
+		for _, c := range st.Clusters {
+			if ok, _ := path.Match(key, c.KubeContext); ok {
+				matched++
+			}
+		}
+		if matched == 0 {
+			fmt.Fprintf(out, "Warning: %q matches no context in state\n", key)
+		}
+	} else if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func filterByTags(clusters []state.ClusterRecord, tags []string) []state.ClusterRecord {
+	if len(tags) == 0 {
+		return clusters
+	}
+	out := make([]state.ClusterRecord, 0, len(clusters))
+	for _, c := range clusters {
+		if c.HasTags(tags) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
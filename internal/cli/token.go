@@ -0,0 +1,110 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/phenixrizen/rift/internal/config"
+	"github.com/phenixrizen/rift/internal/discovery"
+	"github.com/phenixrizen/rift/internal/ekstoken"
+	"github.com/phenixrizen/rift/internal/rifterr"
+	"github.com/phenixrizen/rift/internal/ssoauth"
+	"github.com/phenixrizen/rift/internal/state"
+	"github.com/spf13/cobra"
+)
+
+func newTokenCmd(app *App) *cobra.Command {
+	var cluster, clusterID, region, profile string
+	cmd := &cobra.Command{
+		Use:   "token --cluster <name> --region <region> --profile <profile>",
+		Short: "Print an EKS ExecCredential (kubeconfig exec plugin)",
+		Long: `Prints an ExecCredential with a bearer token for an EKS cluster, signed with
+the SSO role credentials of a rift profile. Kubeconfig contexts run it
+instead of aws eks get-token when kubeconfig_exec is rift.
+
+Tokens are cached under the state directory until shortly before they
+expire, and an expired SSO login is renewed with its refresh token when it
+can be.`,
+		Hidden: true,
+		Args:   cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			id := cluster
+			if clusterID != "" {
+				id = clusterID
+			}
+			if id == "" || region == "" || profile == "" {
+				return fmt.Errorf("--cluster (or --cluster-id), --region, and --profile are required")
+			}
+			cache := ekstoken.Cache{Dir: app.tokenCacheDir()}
+			key := profile + "|" + region + "|" + id
+			tok, ok := cache.Get(key, time.Now())
+			if !ok {
+				cfg, err := app.loadConfig()
+				if err != nil {
+					return err
+				}
+				st, err := app.loadState()
+				if err != nil {
+					if errors.Is(err, os.ErrNotExist) {
+						return errStateNotFound
+					}
+					return err
+				}
+				role, found := findRole(st, profile)
+				if !found {
+					return fmt.Errorf("profile %s is not in rift state; run: rift sync", profile)
+				}
+				creds, err := tokenCredentials(cmd.Context(), app, cfg, st, role)
+				if err != nil {
+					return err
+				}
+				if tok, err = ekstoken.Generate(cmd.Context(), creds, region, id); err != nil {
+					return fmt.Errorf("sign token for %s: %w", id, err)
+				}
+				if err := cache.Put(key, tok); err != nil && app.Logger != nil {
+					app.Logger.Warn("unable to cache token", "error", err)
+				}
+			}
+			data, err := ekstoken.ExecCredential(tok)
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprintln(cmd.OutOrStdout(), string(data))
+			return err
+		},
+	}
+	cmd.Flags().StringVar(&cluster, "cluster", "", "EKS cluster name")
+	cmd.Flags().StringVar(&clusterID, "cluster-id", "", "Cluster ID of a local cluster on Outposts")
+	cmd.Flags().StringVar(&region, "region", "", "Cluster region")
+	cmd.Flags().StringVar(&profile, "profile", "", "rift AWS profile whose role signs the token")
+	return cmd
+}
+
+func (a *App) tokenCacheDir() string {
+	return filepath.Join(filepath.Dir(a.StatePath), "cache", "tokens")
+}
+
+// tokenCredentials returns role's credentials, first renewing an expired
+// SSO login with its refresh token: kubectl cannot run the device flow.
+func tokenCredentials(ctx context.Context, app *App, cfg config.Config, st state.State, role state.RoleRecord) (aws.Credentials, error) {
+	creds, err := discovery.RoleCredentials(ctx, cfg, st, role, app.credentialCache(cfg))
+	if errors.Is(err, discovery.ErrSSONotLoggedIn) {
+		if session, ok := cfg.Session(role.SSOSession); ok {
+			if _, refreshErr := ssoauth.Refresh(ctx, session.ID(), session.StartURL, session.Region); refreshErr == nil {
+				creds, err = discovery.RoleCredentials(ctx, cfg, st, role, app.credentialCache(cfg))
+			}
+		}
+	}
+	if err != nil {
+		if errors.Is(err, discovery.ErrSSONotLoggedIn) {
+			return aws.Credentials{}, rifterr.Wrap(rifterr.CodeAuthRequired, ErrSSOLoginRequired, "run: rift auth")
+		}
+		return aws.Credentials{}, fmt.Errorf("get credentials for %s: %w", role.AWSProfile, err)
+	}
+	return creds, nil
+}
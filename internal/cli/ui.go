@@ -5,8 +5,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"os"
 	"os/exec"
+	"runtime"
+	"slices"
 	"strings"
 	"time"
 
@@ -17,7 +21,14 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/charmbracelet/x/ansi"
+	"github.com/phenixrizen/rift/internal/browser"
+	"github.com/phenixrizen/rift/internal/config"
 	"github.com/phenixrizen/rift/internal/discovery"
+	"github.com/phenixrizen/rift/internal/overlay"
+	"github.com/phenixrizen/rift/internal/plugin"
+	"github.com/phenixrizen/rift/internal/query"
+	"github.com/phenixrizen/rift/internal/recent"
+	"github.com/phenixrizen/rift/internal/ssoauth"
 	"github.com/phenixrizen/rift/internal/state"
 	"github.com/phenixrizen/rift/internal/version"
 	"github.com/spf13/cobra"
@@ -29,16 +40,33 @@ func newUICmd(app *App) *cobra.Command {
 		Use:   "ui",
 		Short: "Interactive Rift TUI",
 		RunE: func(cmd *cobra.Command, _ []string) error {
+			cfg, err := app.loadConfig()
+			needConfig := errors.Is(err, os.ErrNotExist)
+			if err != nil && !needConfig {
+				return err
+			}
 			st, err := app.loadState()
-			if err != nil {
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
 				return err
 			}
-			model := newUIModel(app, st)
+			model := newUIModel(app, st, cfg)
+			if needConfig || len(st.Clusters) == 0 {
+				model.onboard = newOnboarding(app, needConfig)
+				if model.onboard.step != onboardConfig {
+					model.busy = true
+					model.busyText = "checking AWS SSO login..."
+				}
+			}
 			if filter != "" {
 				model.search.SetValue(filter)
 				model.applyFilter()
 			}
-			prog := tea.NewProgram(model, tea.WithAltScreen())
+			prog := tea.NewProgram(model, tea.WithAltScreen(), tea.WithMouseCellMotion())
+			if !needConfig && cfg.SSOAutoRefresh {
+				ctx, cancel := context.WithCancel(cmd.Context())
+				defer cancel()
+				keepTokenAlive(ctx, cfg, prog)
+			}
 			_, err = prog.Run()
 			return err
 		},
@@ -51,6 +79,22 @@ type syncDoneMsg struct {
 	report SyncReport
 	err    error
 	logs   string
+	// current is kubectl's current-context after the sync.
+	current string
+}
+
+type syncProgressMsg struct {
+	progress discovery.Progress
+	feed     syncFeed
+}
+
+// syncFeed is what a background sync reports on: progress counters,
+// confirm requests, log lines, and finally its result.
+type syncFeed struct {
+	progress <-chan discovery.Progress
+	confirms <-chan syncConfirmMsg
+	logs     <-chan string
+	done     <-chan syncDoneMsg
 }
 
 type authCheckDoneMsg struct {
@@ -58,20 +102,34 @@ type authCheckDoneMsg struct {
 	err       error
 }
 
+type authPromptMsg struct {
+	device  ssoauth.Device
+	prompts <-chan ssoauth.Device
+	done    <-chan authDoneMsg
+}
+
 type authDoneMsg struct {
 	err  error
 	logs string
 }
 
+type tokenRefreshMsg struct {
+	session   string
+	expiresAt string
+	err       error
+}
+
 type refreshDoneMsg struct {
-	state state.State
-	err   error
+	state   state.State
+	err     error
+	current string
 }
 
 type useDoneMsg struct {
 	context string
 	err     error
 	output  string
+	current string
 }
 
 type k9sDoneMsg struct {
@@ -79,41 +137,133 @@ type k9sDoneMsg struct {
 	err     error
 }
 
+type shellDoneMsg struct {
+	context string
+	err     error
+}
+
+type consoleDoneMsg struct {
+	cluster string
+	err     error
+}
+
+type favDoneMsg struct {
+	context  string
+	favorite bool
+	err      error
+}
+
 type uiModel struct {
 	app      *App
 	state    state.State
 	all      []state.ClusterRecord
 	filtered []state.ClusterRecord
+	// rows are the table rows of filtered, in the same order, with search
+	// matches; tableTop is the first row on screen (see tableView).
+	rows     []uiRow
+	tableTop int
 	table    table.Model
 	search   textinput.Model
 	searchOn bool
-	status   string
-	modalOn  bool
-	modal    string
-	modalHdr string
-	modalVP  viewport.Model
-	modalW   int
-	spin     spinner.Model
-	busy     bool
-	busyText string
-	width    int
-	height   int
-	commit   string
+	// searchErr reports an unknown key:value token in the search.
+	searchErr string
+	status    string
+	modalOn   bool
+	modal     string
+	modalHdr  string
+	modalVP   viewport.Model
+	modalW    int
+	spin      spinner.Model
+	busy      bool
+	busyText  string
+	width     int
+	height    int
+	commit    string
+	// onboard is non-nil while the first-run wizard is active.
+	onboard *onboarding
+	// graph is the topology tree shown instead of the table while non-nil.
+	graph *graphTree
+	// nsPick is the namespace chooser open before launching k9s, and menu
+	// the selected context's action menu.
+	nsPick *nsPicker
+	menu   *actionMenu
+	// copying is set after the copy key, until the key naming what to copy.
+	copying bool
+	// confirm is the yes/no dialog open before a destructive action, such
+	// as a sync that removes profiles or contexts.
+	confirm *confirmDialog
+	// plugins are the ui_plugins the action menu offers.
+	plugins []string
+	// theme is the palette from the config's theme section and keys the
+	// keybindings.
+	theme uiTheme
+	keys  uiKeys
+	// favOnly hides contexts that are not favorites (F).
+	favOnly bool
+	// recent lists recently used contexts, newest first; recentOnly (R)
+	// shows only those, in that order.
+	recent     []string
+	recentOnly bool
+	// cols are the table's columns (ui_columns) and sortBy the column it
+	// is sorted by (o cycles it, O reverses it).
+	cols   []uiColumn
+	sortBy uiSort
+	// groupBy puts the table under env or account headers (uiGroupEnv,
+	// uiGroupAccount); collapsed holds the closed groups by groupBy|label.
+	groupBy   string
+	collapsed map[string]bool
+	// current is kubectl's current-context, read at startup and after a
+	// use, sync, or refresh; its row is highlighted.
+	current string
+	// token is the SSO token that expires first (nil before setup), for the
+	// header countdown; tokenWarned is set once the status line warned.
+	token       *uiToken
+	tokenWarned bool
+	// autoRefresh is the ui_auto_refresh period (0 when off), driftCheck
+	// ui_auto_refresh_drift, and drifted the last drift check's finding.
+	autoRefresh time.Duration
+	driftCheck  bool
+	drifted     bool
+	// toasts are the notices shown over the lower-right corner until they
+	// expire; toastSeq numbers them.
+	toasts   []uiToast
+	toastSeq int
+	// health holds the API server probes by context (the health column
+	// reads it, so it is never replaced); healthSem limits probes in flight,
+	// healthPending counts them, and healthReport toasts the round when they
+	// are done. healthEvery is ui_health_check (0 when off).
+	health        map[string]uiHealth
+	healthSem     chan struct{}
+	healthPending int
+	healthReport  bool
+	healthEvery   time.Duration
+	// clickRow and clickAt are the last table row clicked and when, to
+	// spot a double-click.
+	clickRow int
+	clickAt  time.Time
+	// detailTab is the details pane's open tab (detailInfo...), and liveNS
+	// the live namespace lookups its Namespaces tab made, by context.
+	detailTab int
+	liveNS    map[string]liveNamespaces
+	// detailWidth is the details pane's share of the width in percent and
+	// detailHidden hides it; both are saved to ui.json (uiprefs).
+	detailWidth  int
+	detailHidden bool
+	// syncLog is the running (or last) sync's log lines, shown in a pane
+	// under the table while syncLogOn.
+	syncLog   []string
+	syncLogOn bool
 }
 
-func newUIModel(app *App, st state.State) uiModel {
-	columns := []table.Column{
-		{Title: "Env", Width: 6},
-		{Title: "Account", Width: 20},
-		{Title: "Role", Width: 18},
-		{Title: "Region", Width: 10},
-		{Title: "Cluster", Width: 20},
-		{Title: "Context", Width: 28},
-	}
-	t := table.New(table.WithColumns(columns), table.WithRows([]table.Row{}), table.WithFocused(true), table.WithHeight(16))
-	styles := table.DefaultStyles()
-	styles.Selected = styles.Selected.Foreground(lipgloss.Color("0")).Background(lipgloss.Color("81")).Bold(true)
-	t.SetStyles(styles)
+// newUIModel builds the TUI for st with cfg's theme and keybindings (cfg
+// may be config.Default() before setup).
+func newUIModel(app *App, st state.State, cfg config.Config) uiModel {
+	theme := newUITheme(cfg.Theme)
+	probes := map[string]uiHealth{}
+	cols := newUIColumns(cfg.UIColumns, probes)
+	sortBy := uiSort{key: cfg.UISortBy, desc: cfg.UISortDesc}
+	t := table.New(table.WithColumns(tableColumns(cols, sortBy.key, sortBy.desc)), table.WithRows([]table.Row{}), table.WithFocused(true), table.WithHeight(16))
+	t.SetStyles(theme.tableStyles())
 
 	s := textinput.New()
 	s.Placeholder = "search"
@@ -122,29 +272,60 @@ func newUIModel(app *App, st state.State) uiModel {
 	s.Blur()
 
 	m := uiModel{
-		app:    app,
-		state:  st,
-		all:    st.Clusters,
-		table:  t,
-		search: s,
-		status: fmt.Sprintf("Loaded %d contexts", len(st.Clusters)),
-		commit: version.ShortCommit(),
+		app:         app,
+		state:       st,
+		all:         st.Clusters,
+		table:       t,
+		search:      s,
+		status:      fmt.Sprintf("Loaded %d contexts", len(st.Clusters)),
+		commit:      version.ShortCommit(),
+		theme:       theme,
+		keys:        newUIKeys(cfg.KeyMap()),
+		cols:        cols,
+		sortBy:      sortBy,
+		collapsed:   map[string]bool{},
+		current:     app.currentContext(),
+		token:       soonestToken(cfg),
+		autoRefresh: cfg.AutoRefresh(),
+		driftCheck:  cfg.UIAutoRefreshDrift,
+		health:      probes,
+		healthSem:   make(chan struct{}, uiHealthConcurrency),
+		healthEvery: cfg.HealthCheck(),
+		liveNS:      map[string]liveNamespaces{},
+		plugins:     cfg.UIPlugins,
 	}
 	sp := spinner.New()
 	sp.Spinner = spinner.Dot
-	sp.Style = lipgloss.NewStyle().Foreground(lipgloss.Color("42")).Bold(true)
+	sp.Style = lipgloss.NewStyle().Foreground(m.theme.status).Bold(true)
 	m.spin = sp
 	m.modalVP = viewport.New(1, 1)
 	m.modalVP.MouseWheelEnabled = true
+	m.loadPrefs()
+	if entries, err := recent.Load(app.recentPath()); err == nil {
+		m.recent = recent.Contexts(entries)
+	}
 	m.applyFilter()
 	return m
 }
 
 func (m uiModel) Init() tea.Cmd {
-	return runUIAuthCheckCmd(m.app)
+	if m.onboard != nil {
+		return m.onboard.init(m)
+	}
+	cmds := []tea.Cmd{runUIAuthCheckCmd(m.app), checkTokenCmd(m.app, uiTokenCheckEvery, true)}
+	if m.autoRefresh > 0 {
+		cmds = append(cmds, autoRefreshCmd(m.app, m.autoRefresh, m.driftCheck))
+	}
+	if m.healthEvery > 0 {
+		cmds = append(cmds, func() tea.Msg { return healthTickMsg{} })
+	}
+	return tea.Batch(cmds...)
 }
 
 func (m uiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if _, resize := msg.(tea.WindowSizeMsg); m.onboard != nil && !resize {
+		return m.updateOnboarding(msg)
+	}
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
@@ -167,11 +348,20 @@ func (m uiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.busyText = "authenticating with AWS SSO..."
 		m.openModal(
 			"AWS SSO Login Required",
-			"No valid SSO token found.\nRunning rift auth now.\nApprove application: botocore-client-rift",
+			"No valid SSO token found.\nStarting AWS SSO login...",
 			"",
 			nil,
 		)
 		return m, tea.Batch(runUIAuthCmd(m.app), m.spin.Tick)
+	case authPromptMsg:
+		m.busyText = "waiting for AWS SSO approval (code " + msg.device.UserCode + ")..."
+		m.openModal(
+			"AWS SSO Login Required",
+			authPromptText(msg.device),
+			"",
+			nil,
+		)
+		return m, waitForAuthCmd(msg.prompts, msg.done)
 	case authDoneMsg:
 		m.busy = false
 		m.busyText = ""
@@ -180,12 +370,38 @@ func (m uiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.openModal("Auth Failed", msg.err.Error(), msg.logs, nil)
 			return m, nil
 		}
-		m.status = "auth complete"
-		m.openModal("Auth Complete", "AWS SSO login completed.", msg.logs, nil)
+		// The login prompt modal is still up.
+		m.closeModal()
+		return m, tea.Batch(m.notify("AWS SSO login complete"), checkTokenCmd(m.app, 0, false))
+	case tokenRefreshMsg:
+		if msg.err != nil {
+			m.status = msg.session + " token refresh failed: " + msg.err.Error()
+			return m, nil
+		}
+		return m, tea.Batch(m.notify(msg.session+" sso token refreshed (expires "+msg.expiresAt+")"), checkTokenCmd(m.app, 0, false))
+	case tokenCheckMsg:
+		m.applyToken(msg.token, time.Now())
+		if msg.tick {
+			return m, checkTokenCmd(m.app, uiTokenCheckEvery, true)
+		}
+		return m, nil
+	case syncProgressMsg:
+		m.busyText = "syncing: " + progressText(msg.progress) + "..."
+		return m, waitForSyncCmd(msg.feed)
+	case syncLogMsg:
+		m.appendSyncLog(msg.line)
+		return m, waitForSyncCmd(msg.feed)
+	case syncConfirmMsg:
+		m.confirmSync(msg)
 		return m, nil
 	case syncDoneMsg:
 		m.busy = false
 		m.busyText = ""
+		if errors.Is(msg.err, errSyncCancelled) {
+			m.status = msg.err.Error()
+			return m, nil
+		}
+		warnings := m.finishSyncLog(msg.logs)
 		if msg.err != nil {
 			m.status = "sync failed: " + msg.err.Error()
 			m.openModal("Sync Failed", msg.err.Error(), msg.logs, nil)
@@ -193,12 +409,35 @@ func (m uiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		m.state = msg.report.State
 		m.all = msg.report.State.Clusters
+		m.current = msg.current
+		m.drifted = false
 		m.applyFilter()
-		m.status = fmt.Sprintf("sync complete (%d contexts)", len(m.all))
-		if strings.TrimSpace(msg.logs) != "" {
-			m.openModal("Sync Warnings", "Sync completed with warnings/logs.", msg.logs, &msg.report)
+		if warnings > 0 {
+			m.status = fmt.Sprintf("sync complete (%d contexts) with warnings; %s hides the log", len(m.all), m.keys.key(config.ActionLogs))
+			return m, nil
+		}
+		return m, m.notify(fmt.Sprintf("sync complete (%d contexts)", len(m.all)))
+	case autoRefreshMsg:
+		// A sync or refresh in flight reports the state itself.
+		next := autoRefreshCmd(m.app, m.autoRefresh, m.driftCheck)
+		if m.busy {
+			return m, next
 		}
+		return m, tea.Batch(m.applyAutoRefresh(msg), next)
+	case tea.MouseMsg:
+		return m, tea.Batch(m.mouse(msg), m.followSelection())
+	case prefsSavedMsg:
+		m.status = "save ui preferences: " + msg.err.Error()
+		return m, nil
+	case nsLookupDueMsg:
+		return m, m.startNSLookup(msg)
+	case nsLookupDoneMsg:
+		m.liveNS[msg.context] = msg.live
 		return m, nil
+	case healthTickMsg:
+		return m, tea.Batch(m.probeHealth(m.filtered, false), healthTickCmd(m.healthEvery))
+	case healthResultMsg:
+		return m, m.applyHealth(msg)
 	case refreshDoneMsg:
 		m.busy = false
 		m.busyText = ""
@@ -208,22 +447,71 @@ func (m uiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		m.state = msg.state
 		m.all = msg.state.Clusters
+		m.current = msg.current
 		m.applyFilter()
-		m.status = fmt.Sprintf("reloaded %d contexts", len(m.all))
-		return m, nil
+		return m, m.notify(fmt.Sprintf("reloaded %d contexts", len(m.all)))
 	case useDoneMsg:
 		if msg.err != nil {
 			m.status = "use failed: " + msg.err.Error()
 			return m, nil
 		}
-		m.status = "active context: " + msg.context
-		return m, nil
+		m.current = msg.current
+		// runUIUseCmd recorded the switch; mirror it for the recent view.
+		m.recent = slices.Insert(slices.DeleteFunc(m.recent, func(c string) bool { return c == msg.context }), 0, msg.context)
+		if m.recentOnly {
+			m.applyFilter()
+		}
+		return m, m.notify("switched to " + msg.context)
 	case k9sDoneMsg:
 		if msg.err != nil {
 			m.status = "k9s failed: " + msg.err.Error()
 			return m, nil
 		}
-		m.status = "k9s exited for context: " + msg.context
+		return m, m.notify("k9s exited for " + msg.context)
+	case shellDoneMsg:
+		var exitErr *exec.ExitError
+		if msg.err != nil && !errors.As(msg.err, &exitErr) {
+			m.status = "shell failed: " + msg.err.Error()
+			return m, nil
+		}
+		return m, m.notify("shell exited for " + msg.context)
+	case pluginDoneMsg:
+		if msg.err != nil {
+			m.status = plugin.Prefix + msg.plugin + " failed for " + msg.context + ": " + msg.err.Error()
+			return m, nil
+		}
+		return m, m.notify(plugin.Prefix + msg.plugin + " finished for " + msg.context)
+	case consoleDoneMsg:
+		m.busy = false
+		m.busyText = ""
+		if errors.Is(msg.err, ErrSSOLoginRequired) {
+			m.status = "console failed: SSO login required; press " + m.keys.key(config.ActionAuth) + " to log in"
+			return m, nil
+		}
+		if msg.err != nil {
+			m.status = "console failed: " + msg.err.Error()
+			return m, nil
+		}
+		return m, m.notify("opened the AWS console for " + msg.cluster)
+	case favDoneMsg:
+		if msg.err != nil {
+			m.status = "favorite failed: " + msg.err.Error()
+			return m, nil
+		}
+		for i := range m.all {
+			if m.all[i].KubeContext == msg.context {
+				m.all[i].Favorite = msg.favorite
+			}
+		}
+		m.applyFilter()
+		// Favorites move to the top; keep the cursor on the toggled row.
+		m.selectContext(msg.context)
+		if msg.favorite {
+			return m, m.notify("★ " + msg.context + " added to favorites")
+		}
+		return m, m.notify(msg.context + " removed from favorites")
+	case toastExpiredMsg:
+		m.dismissToast(msg.id)
 		return m, nil
 	case spinner.TickMsg:
 		if m.busy {
@@ -236,12 +524,7 @@ func (m uiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if m.modalOn {
 			switch msg.String() {
 			case "esc", "enter", "q":
-				m.modalOn = false
-				m.modal = ""
-				m.modalHdr = ""
-				m.modalW = 0
-				m.modalVP.SetContent("")
-				m.modalVP.GotoTop()
+				m.closeModal()
 				return m, nil
 			case "j":
 				m.modalVP.LineDown(1)
@@ -260,6 +543,39 @@ func (m uiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.modalVP, cmd = m.modalVP.Update(msg)
 			return m, cmd
 		}
+		if m.confirm != nil {
+			cmd, done := m.confirm.update(msg)
+			if done {
+				m.confirm = nil
+			}
+			return m, cmd
+		}
+		if m.nsPick != nil {
+			rec := m.nsPick.rec
+			ns, launch, done, cmd := m.nsPick.update(msg)
+			if !done {
+				return m, cmd
+			}
+			m.nsPick = nil
+			if !launch {
+				m.status = "k9s cancelled"
+				return m, nil
+			}
+			m.status = "launching k9s..."
+			return m, runUIK9sCmd(m.app, rec, ns)
+		}
+		if m.menu != nil {
+			rec := m.menu.rec
+			choice, done := m.menu.update(msg)
+			if !done {
+				return m, nil
+			}
+			m.menu = nil
+			return m, m.runMenuAction(choice, rec)
+		}
+		if m.copying {
+			return m, m.copyKey(msg)
+		}
 		if m.searchOn {
 			switch msg.String() {
 			case "esc", "enter":
@@ -275,53 +591,181 @@ func (m uiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, cmd
 		}
 
-		switch msg.String() {
-		case "q", "ctrl+c":
+		if m.graph != nil && m.graph.update(msg, m.table.Height()+1) {
+			return m, nil
+		}
+		if msg.String() == "ctrl+c" {
 			return m, tea.Quit
-		case "\\":
-			if strings.TrimSpace(m.search.Value()) != "" {
-				m.search.SetValue("")
-				m.applyFilter()
-				m.status = fmt.Sprintf("search cleared (%d contexts)", len(m.filtered))
+		}
+		// The topology tree keeps left and right for itself.
+		if m.graph == nil {
+			switch msg.String() {
+			case "left":
+				return m, m.switchDetailTab(-1)
+			case "right":
+				return m, m.switchDetailTab(1)
+			}
+		}
+		switch m.keys.action(msg.String()) {
+		case config.ActionQuit:
+			return m, tea.Quit
+		case config.ActionGraph:
+			if m.graph != nil {
+				m.graph = nil
+				m.status = fmt.Sprintf("table view (%d contexts)", len(m.filtered))
 			} else {
-				m.status = "search already clear"
+				m.graph = newGraphTree(m.state, m.filtered, nil)
+				m.status = "topology view: " + m.keys.key(config.ActionUse) + " on a cluster uses its context"
 			}
 			return m, nil
-		case "/":
+		case config.ActionClear:
+			if strings.TrimSpace(m.search.Value()) == "" {
+				return m, m.notify("search already clear")
+			}
+			m.search.SetValue("")
+			m.applyFilter()
+			return m, m.notify(fmt.Sprintf("search cleared (%d contexts)", len(m.filtered)))
+		case config.ActionSearch:
 			m.searchOn = true
 			m.search.Focus()
 			m.status = "search mode: type to filter (enter/esc close)"
 			m.syncTableLayout()
 			return m, nil
-		case "s":
+		case config.ActionAuth:
+			if m.busy {
+				return m, nil
+			}
+			m.busy = true
+			m.busyText = "authenticating with AWS SSO..."
+			return m, tea.Batch(runUIAuthCmd(m.app), m.spin.Tick)
+		case config.ActionSync:
 			m.busy = true
 			m.busyText = "syncing..."
-			return m, tea.Batch(runUISyncCmd(m.app), m.spin.Tick)
-		case "r":
+			m.startSyncLog()
+			return m, tea.Batch(runUISyncCmd(m.app, true), m.spin.Tick)
+		case config.ActionRefresh:
 			m.busy = true
 			m.busyText = "reloading state..."
 			return m, tea.Batch(runUIRefreshCmd(m.app), m.spin.Tick)
-		case "enter":
+		case config.ActionGroup:
+			current := ""
+			if rec := m.selected(); rec != nil {
+				current = rec.KubeContext
+			}
+			m.groupBy = nextGroupBy(m.groupBy)
+			m.applyFilter()
+			m.selectContext(current)
+			if m.groupBy == uiGroupNone {
+				m.status = fmt.Sprintf("ungrouped (%d contexts)", len(m.filtered))
+			} else {
+				m.status = fmt.Sprintf("grouped by %s (%d contexts)", m.groupBy, len(m.filtered))
+			}
+			return m, nil
+		case config.ActionUse:
+			if m.graph == nil && m.toggleGroup() {
+				return m, nil
+			}
 			rec := m.selected()
 			if rec == nil {
 				return m, nil
 			}
-			m.status = "switching context..."
-			return m, runUIUseCmd(rec.KubeContext)
-		case "k":
+			return m, m.useContext(*rec)
+		case config.ActionPrevious:
+			return m, m.usePrevious()
+		case config.ActionFavorite:
 			rec := m.selected()
 			if rec == nil {
 				return m, nil
 			}
-			m.status = "launching k9s..."
-			return m, runUIK9sCmd(*rec)
+			return m, runUIFavCmd(m.app, rec.KubeContext, !rec.Favorite)
+		case config.ActionFavorites:
+			m.favOnly = !m.favOnly
+			m.applyFilter()
+			if m.favOnly {
+				m.status = fmt.Sprintf("favorites only (%d contexts)", len(m.filtered))
+			} else {
+				m.status = fmt.Sprintf("all contexts (%d)", len(m.filtered))
+			}
+			return m, nil
+		case config.ActionRecent:
+			m.recentOnly = !m.recentOnly
+			m.applyFilter()
+			m.table.SetCursor(0)
+			m.scrollTable()
+			if m.recentOnly {
+				m.status = fmt.Sprintf("recently used (%d contexts)", len(m.filtered))
+			} else {
+				m.status = fmt.Sprintf("all contexts (%d)", len(m.filtered))
+			}
+			return m, nil
+		case config.ActionSort:
+			m.resort(m.sortBy.next(m.cols))
+			return m, nil
+		case config.ActionReverse:
+			m.resort(uiSort{key: m.sortBy.key, desc: !m.sortBy.desc})
+			return m, nil
+		case config.ActionK9s:
+			rec := m.selected()
+			if rec == nil {
+				return m, nil
+			}
+			return m, m.pickK9sNamespace(*rec)
+		case config.ActionMenu:
+			rec := m.selected()
+			if rec == nil {
+				return m, nil
+			}
+			m.menu = newActionMenu(*rec, m.plugins)
+			return m, nil
+		case config.ActionShell:
+			rec := m.selected()
+			if rec == nil {
+				return m, nil
+			}
+			return m, m.openShell(*rec)
+		case config.ActionConsole:
+			rec := m.selected()
+			if rec == nil {
+				return m, nil
+			}
+			return m, m.openConsole(*rec)
+		case config.ActionHealth:
+			if m.healthPending > 0 {
+				m.status = fmt.Sprintf("health: %d probes still running", m.healthPending)
+				return m, nil
+			}
+			cmd := m.probeHealth(m.filtered, true)
+			if cmd == nil {
+				m.status = "health: no contexts to probe"
+				return m, nil
+			}
+			m.status = fmt.Sprintf("health: probing %d API servers...", m.healthPending)
+			return m, cmd
+		case config.ActionGrow:
+			return m, m.resizeDetails(uiDetailStep)
+		case config.ActionShrink:
+			return m, m.resizeDetails(-uiDetailStep)
+		case config.ActionDetails:
+			return m, m.toggleDetails()
+		case config.ActionLogs:
+			m.toggleSyncLog()
+			return m, nil
+		case config.ActionCopy:
+			if m.selected() != nil {
+				m.startCopy()
+			}
+			return m, nil
 		}
 	}
 
+	if m.graph != nil {
+		return m, nil
+	}
 	m.syncTableLayout()
 	var cmd tea.Cmd
 	m.table, cmd = m.table.Update(msg)
-	return m, cmd
+	m.scrollTable()
+	return m, tea.Batch(cmd, m.followSelection())
 }
 
 func (m uiModel) View() string {
@@ -336,25 +780,11 @@ func (m uiModel) View() string {
 	if termHeight <= 0 {
 		termHeight = 40
 	}
-
-	leftOuterWidth := int(float64(termWidth) * 0.62)
-	if leftOuterWidth < 22 {
-		leftOuterWidth = 22
-	}
-	if leftOuterWidth > termWidth-20 {
-		leftOuterWidth = termWidth - 20
-	}
-	rightOuterWidth := termWidth - leftOuterWidth
-	if rightOuterWidth < 20 {
-		rightOuterWidth = 20
-		leftOuterWidth = termWidth - rightOuterWidth
-	}
-	if leftOuterWidth < 1 {
-		leftOuterWidth = 1
-	}
-	if rightOuterWidth < 1 {
-		rightOuterWidth = 1
+	if m.onboard != nil {
+		return m.onboardingView(termWidth, termHeight)
 	}
+
+	leftOuterWidth, rightOuterWidth := m.paneWidths(termWidth)
 	leftInnerWidth := leftOuterWidth - 2
 	if leftInnerWidth < 1 {
 		leftInnerWidth = 1
@@ -368,14 +798,27 @@ func (m uiModel) View() string {
 	}
 
 	statusText := m.status
+	if statusText == "" {
+		statusText = fmt.Sprintf("%d/%d contexts", len(m.filtered), len(m.all))
+	}
 	if m.busy {
 		statusText = m.spin.View() + " " + m.busyText
 	}
-	status := lipgloss.NewStyle().Foreground(lipgloss.Color("42")).Render(statusText)
+	if m.recentOnly {
+		statusText = "[recent] " + statusText
+	}
+	if m.favOnly {
+		statusText = "[★ favorites] " + statusText
+	}
+	status := lipgloss.NewStyle().Foreground(m.theme.status).Render(statusText)
 	statusHeight := lipgloss.Height(status)
 	hotkeys := m.hotkeysLineView()
 	hotkeysHeight := lipgloss.Height(hotkeys)
+	syncLog := m.syncLogView(termWidth)
 	paneHeight := termHeight - lipgloss.Height(top) - statusHeight - hotkeysHeight
+	if syncLog != "" {
+		paneHeight -= lipgloss.Height(syncLog)
+	}
 	if paneHeight < 5 {
 		paneHeight = 5
 	}
@@ -391,32 +834,57 @@ func (m uiModel) View() string {
 	m.table.SetHeight(tableHeight)
 	m.table.SetWidth(leftInnerWidth)
 
+	leftView := m.tableView(leftInnerWidth, innerPaneHeight)
+	if m.graph != nil {
+		leftView = m.graph.view(leftInnerWidth, innerPaneHeight, m.theme, m.keys)
+	}
 	leftContent := lipgloss.NewStyle().
 		Width(leftInnerWidth).
 		MaxWidth(leftInnerWidth).
 		Height(innerPaneHeight).
 		MaxHeight(innerPaneHeight).
-		Render(m.table.View())
+		Render(leftView)
 	left := lipgloss.NewStyle().
 		Border(lipgloss.NormalBorder()).
+		BorderForeground(m.theme.border).
 		Render(leftContent)
 
-	rightContent := m.rightPaneView(rightInnerWidth, innerPaneHeight)
-	right := lipgloss.NewStyle().
-		Border(lipgloss.NormalBorder()).
-		Render(rightContent)
-
-	panes := lipgloss.JoinHorizontal(lipgloss.Top, left, right)
-	screen := lipgloss.JoinVertical(lipgloss.Left, top, panes, status, hotkeys)
+	panes := left
+	toastWidth := leftInnerWidth
+	if rightOuterWidth > 0 {
+		rightContent := m.rightPaneView(rightInnerWidth, innerPaneHeight)
+		right := lipgloss.NewStyle().
+			Border(lipgloss.NormalBorder()).
+			BorderForeground(m.theme.border).
+			Render(rightContent)
+		panes = lipgloss.JoinHorizontal(lipgloss.Top, left, right)
+		toastWidth = rightInnerWidth
+	}
+	parts := []string{top, panes}
+	if syncLog != "" {
+		parts = append(parts, syncLog)
+	}
+	screen := lipgloss.JoinVertical(lipgloss.Left, append(parts, status, hotkeys)...)
 	screen = lipgloss.NewStyle().
 		Width(termWidth).
 		MaxWidth(termWidth).
 		Height(termHeight).
 		MaxHeight(termHeight).
 		Render(screen)
+	// Toasts sit just above the panes' bottom border.
+	screen = overlayBottomRight(screen, m.toastsView(toastWidth), termWidth-1, lipgloss.Height(top)+paneHeight-2)
 	if m.modalOn {
 		return m.renderModal(termWidth, termHeight)
 	}
+	if m.confirm != nil {
+		return lipgloss.Place(termWidth, termHeight, lipgloss.Center, lipgloss.Center, m.confirm.view(termWidth, m.theme))
+	}
+	if m.nsPick != nil {
+		return lipgloss.Place(termWidth, termHeight, lipgloss.Center, lipgloss.Center, m.nsPick.view(termWidth, m.theme))
+	}
+	if m.menu != nil {
+		return lipgloss.Place(termWidth, termHeight, lipgloss.Center, lipgloss.Center, m.menu.view(termWidth, m.theme))
+	}
 	return screen
 }
 
@@ -449,28 +917,56 @@ func (m uiModel) topHeaderView() string {
 }
 
 func (m uiModel) traverseLogoView() string {
-	titleStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("81")).Bold(true).Padding(0, 1)
-	versionStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("246")).Padding(0, 1)
+	titleStyle := m.theme.accentStyle().Padding(0, 1)
+	versionStyle := m.theme.mutedStyle().Padding(0, 1)
 	title := titleStyle.Render("TRAVERSE THE CLOUD RIFT")
 	version := versionStyle.Render("version: " + m.commit)
-	return lipgloss.JoinVertical(lipgloss.Left, title, version)
+	current := m.current
+	if current == "" {
+		current = "(none)"
+	}
+	kubectl := lipgloss.NewStyle().Padding(0, 1).Render(m.theme.mutedStyle().Render("kubectl: ") + lipgloss.NewStyle().Foreground(m.theme.status).Bold(true).Render(current))
+	lines := []string{title, version, kubectl}
+	if sso := m.tokenView(time.Now()); sso != "" {
+		lines = append(lines, sso)
+	}
+	if drift := m.driftView(); drift != "" {
+		lines = append(lines, drift)
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, lines...)
 }
 
 func (m uiModel) shortcutsBoxView(maxWidth int) string {
-	keyStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("81")).Bold(true)
-	labelStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("246"))
+	keyStyle := m.theme.accentStyle()
+	labelStyle := m.theme.mutedStyle()
 	rows := []string{
-		keyStyle.Render("</>") + " " + labelStyle.Render("search"),
-		keyStyle.Render("<enter>") + " " + labelStyle.Render("use context"),
-		keyStyle.Render("<k>") + " " + labelStyle.Render("k9s namespaces"),
-		keyStyle.Render("<s>") + " " + labelStyle.Render("sync"),
-		keyStyle.Render("<r>") + " " + labelStyle.Render("refresh"),
-		keyStyle.Render("<q>") + " " + labelStyle.Render("quit"),
-	}
-	title := lipgloss.NewStyle().Foreground(lipgloss.Color("81")).Bold(true).Render("Hotkeys")
+		keyStyle.Render(m.keys.hint(config.ActionSearch)) + " " + labelStyle.Render("search"),
+		keyStyle.Render(m.keys.hint(config.ActionUse)) + " " + labelStyle.Render("use context"),
+		keyStyle.Render(m.keys.hint(config.ActionPrevious)) + " " + labelStyle.Render("previous context"),
+		keyStyle.Render(m.keys.hint(config.ActionK9s)) + " " + labelStyle.Render("k9s"),
+		keyStyle.Render(m.keys.hint(config.ActionMenu)) + " " + labelStyle.Render("actions"),
+		keyStyle.Render(m.keys.hint(config.ActionCopy)) + " " + labelStyle.Render("copy"),
+		keyStyle.Render(m.keys.hint(config.ActionShell)) + " " + labelStyle.Render("shell"),
+		keyStyle.Render(m.keys.hint(config.ActionConsole)) + " " + labelStyle.Render("aws console"),
+		keyStyle.Render(m.keys.hint(config.ActionFavorite, config.ActionFavorites)) + " " + labelStyle.Render("favorite/only favs"),
+		keyStyle.Render(m.keys.hint(config.ActionRecent)) + " " + labelStyle.Render("recent"),
+		keyStyle.Render(m.keys.hint(config.ActionGraph)) + " " + labelStyle.Render("topology"),
+		keyStyle.Render(m.keys.hint(config.ActionSort, config.ActionReverse)) + " " + labelStyle.Render("sort/reverse"),
+		keyStyle.Render(m.keys.hint(config.ActionGroup)) + " " + labelStyle.Render("group"),
+		keyStyle.Render(m.keys.hint(config.ActionAuth)) + " " + labelStyle.Render("sso login"),
+		keyStyle.Render(m.keys.hint(config.ActionSync)) + " " + labelStyle.Render("sync"),
+		keyStyle.Render(m.keys.hint(config.ActionRefresh)) + " " + labelStyle.Render("refresh"),
+		keyStyle.Render(m.keys.hint(config.ActionHealth)) + " " + labelStyle.Render("health"),
+		keyStyle.Render("<←/→>") + " " + labelStyle.Render("detail tabs"),
+		keyStyle.Render(m.keys.hint(config.ActionShrink, config.ActionGrow, config.ActionDetails)) + " " + labelStyle.Render("resize/hide details"),
+		keyStyle.Render(m.keys.hint(config.ActionLogs)) + " " + labelStyle.Render("sync log"),
+		keyStyle.Render(m.keys.hint(config.ActionQuit)) + " " + labelStyle.Render("quit"),
+	}
+	title := m.theme.accentStyle().Render("Hotkeys")
 	body := strings.Join(rows, "\n")
 	box := lipgloss.NewStyle().
 		Border(lipgloss.NormalBorder()).
+		BorderForeground(m.theme.border).
 		Padding(0, 1).
 		Render(lipgloss.JoinVertical(lipgloss.Left, title, body))
 	if maxWidth > 0 {
@@ -480,7 +976,7 @@ func (m uiModel) shortcutsBoxView(maxWidth int) string {
 }
 
 func (m uiModel) riftLogoView(maxWidth int) string {
-	artStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("81")).Bold(true).Padding(0, 1)
+	artStyle := m.theme.accentStyle().Padding(0, 1)
 	lineWidth := maxWidth - 2
 	if lineWidth < 1 {
 		lineWidth = 1
@@ -519,20 +1015,36 @@ func (m uiModel) rightPaneView(width, height int) string {
 }
 
 func (m uiModel) hotkeysLineView() string {
-	keyStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("81")).Bold(true)
-	labelStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("246"))
-	sep := lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Render("  ")
+	keyStyle := m.theme.accentStyle()
+	labelStyle := m.theme.mutedStyle()
+	sep := m.theme.mutedStyle().Render("  ")
 
 	parts := []string{
-		keyStyle.Render("</>") + " " + labelStyle.Render("search"),
-		keyStyle.Render("<\\>") + " " + labelStyle.Render("clear filter"),
-		keyStyle.Render("<enter>") + " " + labelStyle.Render("use context"),
-		keyStyle.Render("<k>") + " " + labelStyle.Render("k9s namespaces"),
-		keyStyle.Render("<s>") + " " + labelStyle.Render("sync"),
-		keyStyle.Render("<r>") + " " + labelStyle.Render("refresh"),
+		keyStyle.Render(m.keys.hint(config.ActionSearch)) + " " + labelStyle.Render("search"),
+		keyStyle.Render(m.keys.hint(config.ActionClear)) + " " + labelStyle.Render("clear filter"),
+		keyStyle.Render(m.keys.hint(config.ActionUse)) + " " + labelStyle.Render("use context"),
+		keyStyle.Render(m.keys.hint(config.ActionPrevious)) + " " + labelStyle.Render("previous context"),
+		keyStyle.Render(m.keys.hint(config.ActionK9s)) + " " + labelStyle.Render("k9s"),
+		keyStyle.Render(m.keys.hint(config.ActionMenu)) + " " + labelStyle.Render("actions"),
+		keyStyle.Render(m.keys.hint(config.ActionCopy)) + " " + labelStyle.Render("copy"),
+		keyStyle.Render(m.keys.hint(config.ActionFavorite)) + " " + labelStyle.Render("favorite"),
+		keyStyle.Render(m.keys.hint(config.ActionFavorites)) + " " + labelStyle.Render("favorites only"),
+		keyStyle.Render(m.keys.hint(config.ActionRecent)) + " " + labelStyle.Render("recent"),
+		keyStyle.Render(m.keys.hint(config.ActionGraph)) + " " + labelStyle.Render("topology/table"),
+		keyStyle.Render(m.keys.hint(config.ActionSort)) + " " + labelStyle.Render("sort column"),
+		keyStyle.Render(m.keys.hint(config.ActionReverse)) + " " + labelStyle.Render("reverse sort"),
+		keyStyle.Render(m.keys.hint(config.ActionGroup)) + " " + labelStyle.Render("group env/account"),
+		keyStyle.Render(m.keys.hint(config.ActionAuth)) + " " + labelStyle.Render("sso login"),
+		keyStyle.Render(m.keys.hint(config.ActionSync)) + " " + labelStyle.Render("sync"),
+		keyStyle.Render(m.keys.hint(config.ActionRefresh)) + " " + labelStyle.Render("refresh"),
+		keyStyle.Render(m.keys.hint(config.ActionHealth)) + " " + labelStyle.Render("probe health"),
+		keyStyle.Render("<←/→>") + " " + labelStyle.Render("detail tabs"),
+		keyStyle.Render(m.keys.hint(config.ActionShrink, config.ActionGrow)) + " " + labelStyle.Render("resize details"),
+		keyStyle.Render(m.keys.hint(config.ActionDetails)) + " " + labelStyle.Render("hide details"),
+		keyStyle.Render(m.keys.hint(config.ActionLogs)) + " " + labelStyle.Render("show/hide sync log"),
 		keyStyle.Render("<up/down>") + " " + labelStyle.Render("scroll modal"),
 		keyStyle.Render("<esc>") + " " + labelStyle.Render("close modal"),
-		keyStyle.Render("<q>") + " " + labelStyle.Render("quit"),
+		keyStyle.Render(m.keys.hint(config.ActionQuit)) + " " + labelStyle.Render("quit"),
 	}
 	line := strings.Join(parts, sep)
 	if m.width > 0 {
@@ -541,6 +1053,15 @@ func (m uiModel) hotkeysLineView() string {
 	return line
 }
 
+func (m *uiModel) closeModal() {
+	m.modalOn = false
+	m.modal = ""
+	m.modalHdr = ""
+	m.modalW = 0
+	m.modalVP.SetContent("")
+	m.modalVP.GotoTop()
+}
+
 func (m *uiModel) openModal(title, summary, logs string, report *SyncReport) {
 	lines := []string{title, "", summary}
 	if report != nil {
@@ -554,8 +1075,8 @@ func (m *uiModel) openModal(title, summary, logs string, report *SyncReport) {
 		}
 		lines = append(lines,
 			fmt.Sprintf("AWS profiles: +%d ~%d -%d", report.AWS.Added, report.AWS.Updated, report.AWS.Removed),
-			fmt.Sprintf("Kube contexts: +%d ~%d -%d", report.Kube.AddedContexts, report.Kube.UpdatedContexts, report.Kube.RemovedContexts),
 		)
+		lines = append(lines, report.kubeLines()...)
 	}
 	if strings.TrimSpace(logs) != "" {
 		lines = append(lines, "", "Logs:")
@@ -575,13 +1096,13 @@ func (m uiModel) renderModal(termWidth, termHeight int) string {
 	}
 	headerText := wrapTextBlock(cutRunes(m.modalHdr, contentWidth), contentWidth)
 	footerText := wrapTextBlock(cutRunes("up/down scroll  PgUp/PgDn page  Esc/Enter close", contentWidth), contentWidth)
-	header := lipgloss.NewStyle().Foreground(lipgloss.Color("81")).Bold(true).Render(headerText)
-	footer := lipgloss.NewStyle().Foreground(lipgloss.Color("246")).Render(footerText)
+	header := m.theme.accentStyle().Render(headerText)
+	footer := m.theme.mutedStyle().Render(footerText)
 	body := m.modalVP.View()
 	content := lipgloss.JoinVertical(lipgloss.Left, header, body, footer)
 	box := lipgloss.NewStyle().
 		Border(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("81")).
+		BorderForeground(m.theme.accent).
 		Padding(0, 1).
 		Render(content)
 	boxHeight := lipgloss.Height(box)
@@ -717,8 +1238,11 @@ func (m uiModel) searchBoxView(outerWidth int) string {
 		contentWidth = 1
 	}
 
-	title := lipgloss.NewStyle().Foreground(lipgloss.Color("81")).Bold(true).Render("SEARCH")
-	hint := lipgloss.NewStyle().Foreground(lipgloss.Color("246")).Render("type to filter   enter/esc close")
+	title := m.theme.accentStyle().Render("SEARCH")
+	hint := m.theme.mutedStyle().Render("type to filter, env:prod ns:kafka ...   enter/esc close")
+	if m.searchErr != "" {
+		hint = lipgloss.NewStyle().Foreground(m.theme.err).Render(m.searchErr)
+	}
 	topLine := padToWidth(cutRunes(title+"  "+hint, contentWidth), contentWidth)
 
 	m.search.Width = contentWidth - 2 // leave room for "/ " prompt
@@ -730,7 +1254,7 @@ func (m uiModel) searchBoxView(outerWidth int) string {
 	content := topLine + "\n" + fieldLine
 	box := lipgloss.NewStyle().
 		Border(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("81")).
+		BorderForeground(m.theme.accent).
 		Padding(0, 1).
 		Render(content)
 
@@ -748,26 +1272,43 @@ func (m uiModel) searchBoxView(outerWidth int) string {
 	return strings.Join(lines, "\n")
 }
 
+// applyFilter keeps the contexts that pass the search's key:value filters
+// (query.Parse) and whose free-text terms each fuzzy-match a field
+// (uiRow.match), best matches first; with favOnly set, favorites only, and
+// with recentOnly, recent contexts only, newest first among equal matches.
 func (m *uiModel) applyFilter() {
-	query := strings.ToLower(strings.TrimSpace(m.search.Value()))
-	m.filtered = m.filtered[:0]
-	for _, row := range m.all {
-		if query == "" {
-			m.filtered = append(m.filtered, row)
+	q, err := query.Parse(m.search.Value())
+	m.searchErr = ""
+	if err != nil {
+		m.searchErr = err.Error()
+	}
+	m.rows = m.rows[:0]
+	for _, rec := range m.all {
+		if !q.Match(rec) || (m.favOnly && !rec.Favorite) {
 			continue
 		}
-		haystack := strings.ToLower(strings.Join([]string{row.Env, row.AccountName, row.AccountID, row.RoleName, row.Region, row.ClusterName, row.KubeContext}, " "))
-		if strings.Contains(haystack, query) {
-			m.filtered = append(m.filtered, row)
+		row := newUIRow(rec, m.cols)
+		if m.recentOnly {
+			if row.pin = slices.Index(m.recent, rec.KubeContext); row.pin < 0 {
+				continue
+			}
 		}
-	}
-	rows := make([]table.Row, 0, len(m.filtered))
-	for _, row := range m.filtered {
-		account := row.AccountName
-		if account == "" {
-			account = row.AccountID
+		if q.Text != "" && !row.match(q.Text) {
+			continue
 		}
-		rows = append(rows, table.Row{displayEnv(row.Env), account, row.RoleName, row.Region, row.ClusterName, row.KubeContext})
+		m.rows = append(m.rows, row)
+	}
+	sortRows(m.rows, m.sortBy)
+	m.filtered = m.filtered[:0]
+	for _, row := range m.rows {
+		m.filtered = append(m.filtered, row.rec)
+	}
+	if m.groupBy != uiGroupNone {
+		m.rows = groupRows(m.rows, m.groupBy, m.collapsed, len(m.cols))
+	}
+	rows := make([]table.Row, 0, len(m.rows))
+	for _, row := range m.rows {
+		rows = append(rows, row.cells)
 	}
 	m.table.SetRows(rows)
 	if cursor := m.table.Cursor(); cursor >= len(rows) && len(rows) > 0 {
@@ -776,6 +1317,10 @@ func (m *uiModel) applyFilter() {
 	if len(rows) == 0 {
 		m.table.SetCursor(0)
 	}
+	m.scrollTable()
+	if m.graph != nil {
+		m.graph = newGraphTree(m.state, m.filtered, m.graph)
+	}
 }
 
 func displayEnv(env string) string {
@@ -785,37 +1330,178 @@ func displayEnv(env string) string {
 	return env
 }
 
+// accountCell is the account column, marking teammates' shared records.
+func accountCell(rec state.ClusterRecord) string {
+	if rec.Shared() {
+		return rec.AccountLabel() + " [shared]"
+	}
+	return rec.AccountLabel()
+}
+
+// useContext switches kubectl to rec's context.
+func (m *uiModel) useContext(rec state.ClusterRecord) tea.Cmd {
+	if !rec.Connectable() {
+		m.status = rec.KubeContext + " is an " + rec.PlatformLabel() + " registration with no API endpoint"
+		return nil
+	}
+	m.status = "switching context..."
+	return runUIUseCmd(m.app, rec)
+}
+
+// usePrevious switches back to the context used before the current one,
+// as rift use - does.
+func (m *uiModel) usePrevious() tea.Cmd {
+	previous := recent.Previous(m.recent, m.current)
+	if previous == "" {
+		m.status = "no previous context"
+		return nil
+	}
+	i := slices.IndexFunc(m.all, func(rec state.ClusterRecord) bool { return rec.KubeContext == previous })
+	if i < 0 {
+		m.status = "previous context " + previous + " is no longer in state"
+		return nil
+	}
+	return m.useContext(m.all[i])
+}
+
+// pickK9sNamespace opens the namespace chooser that launches k9s on rec.
+func (m *uiModel) pickK9sNamespace(rec state.ClusterRecord) tea.Cmd {
+	if !rec.Connectable() {
+		m.status = rec.KubeContext + " is an " + rec.PlatformLabel() + " registration with no API endpoint"
+		return nil
+	}
+	m.nsPick = newNSPicker(rec)
+	m.status = "choose a namespace for k9s"
+	return textinput.Blink
+}
+
+// openShell starts the user's shell set up like rift exec for rec: its own
+// kubeconfig, AWS_PROFILE, and namespace.
+func (m *uiModel) openShell(rec state.ClusterRecord) tea.Cmd {
+	cfg, err := m.app.loadConfig()
+	if err != nil {
+		m.status = "shell failed: " + err.Error()
+		return nil
+	}
+	role, _ := findRole(m.state, rec.AWSProfile)
+	t := target{Name: rec.KubeContext, Role: role, Cluster: &rec}
+	env, cleanupEnv, err := targetEnv(cfg, t, "")
+	if err != nil {
+		m.status = "shell failed: " + err.Error()
+		return nil
+	}
+	cmd, cleanupPrompt, err := shellCommand(userShell(), env, shellLabel(t, ""))
+	if err != nil {
+		cleanupEnv()
+		m.status = "shell failed: " + err.Error()
+		return nil
+	}
+	m.status = "starting a shell for " + rec.KubeContext + "..."
+	return runUIShellCmd(rec, cmd, func() {
+		cleanupPrompt()
+		cleanupEnv()
+	})
+}
+
+// selectContext moves the table cursor to ctx's row, if it is shown.
+func (m *uiModel) selectContext(ctx string) {
+	for i, row := range m.rows {
+		if row.group == "" && row.rec.KubeContext == ctx {
+			m.table.SetCursor(i)
+			m.scrollTable()
+			return
+		}
+	}
+}
+
+// selected is the highlighted table row, or in the topology view the
+// cluster of the highlighted node.
 func (m *uiModel) selected() *state.ClusterRecord {
-	if len(m.filtered) == 0 {
+	if m.graph != nil {
+		return m.graph.selectedCluster()
+	}
+	if len(m.rows) == 0 {
 		return nil
 	}
 	idx := m.table.Cursor()
-	if idx < 0 || idx >= len(m.filtered) {
+	if idx < 0 || idx >= len(m.rows) {
 		idx = 0
 	}
-	return &m.filtered[idx]
+	if m.rows[idx].group != "" {
+		return nil
+	}
+	return &m.rows[idx].rec
 }
 
-func (m *uiModel) detailView(width int) string {
-	rec := m.selected()
-	if rec == nil {
-		return "No contexts"
+// infoLines are the details pane's Info tab for rec.
+func (m *uiModel) infoLines(rec *state.ClusterRecord) []string {
+	contextLine := "Context: " + rec.KubeContext
+	if rec.KubeContext == m.current {
+		contextLine += " (kubectl current)"
 	}
 	lines := []string{
-		"Context: " + rec.KubeContext,
+		contextLine,
 		"Env: " + rec.Env,
 		"Account: " + rec.AccountName,
-		"Account ID: " + rec.AccountID,
-		"Role: " + rec.RoleName,
-		"AWS Profile: " + rec.AWSProfile,
-		"Region: " + rec.Region,
-		"Cluster: " + rec.ClusterName,
-		"Cluster ARN: " + rec.ClusterARN,
+	}
+	if rec.AccountAlias != "" {
+		lines = append(lines, "Account Alias: "+rec.AccountAlias)
+	}
+	if rec.IsAWS() {
+		lines = append(lines,
+			"Account ID: "+rec.AccountID,
+			"Role: "+rec.RoleName,
+			"AWS Profile: "+rec.AWSProfile,
+			"Region: "+rec.Region,
+			"Cluster: "+rec.ClusterName,
+			"Cluster ARN: "+rec.ClusterARN,
+		)
+	} else if rec.External() {
+		lines = append(lines,
+			"Cluster: "+rec.ClusterName,
+			"Server: "+rec.ClusterEndpoint,
+		)
+	} else {
+		lines = append(lines,
+			"Location: "+rec.Region,
+			"Cluster: "+rec.ClusterName,
+		)
+	}
+	if rec.Platform != "" {
+		lines = append(lines, "Platform: "+rec.PlatformLabel())
+	}
+	if rec.KubernetesVersion != "" {
+		version := rec.KubernetesVersion
+		if rec.PlatformVersion != "" {
+			version += " (" + rec.PlatformVersion + ")"
+		}
+		lines = append(lines, "Kubernetes: "+version)
+	}
+	if rec.Status != "" {
+		lines = append(lines, "Status: "+rec.Status)
+	}
+	if health := m.healthLine(*rec, time.Now()); health != "" {
+		lines = append(lines, health)
+	}
+	if !rec.CreatedAt.IsZero() {
+		lines = append(lines, "Created: "+rec.CreatedAt.Local().Format("2006-01-02 15:04"))
+	}
+	if rec.SSOSession != "" && rec.SSOSession != config.DefaultSSOSession {
+		lines = append(lines, "SSO Session: "+rec.SSOSession)
+	}
+	if rec.Shared() {
+		lines = append(lines, "Shared From: "+rec.SharedFrom+" (read-only until your sync discovers it)")
 	}
 	if rec.Namespace != "" {
 		lines = append(lines, "Namespace: "+rec.Namespace)
 	}
-	return lipgloss.NewStyle().Width(width).Render(wrapTextBlock(strings.Join(lines, "\n"), width))
+	if len(rec.Tags) > 0 {
+		lines = append(lines, "Tags: "+strings.Join(rec.Tags, ", "))
+	}
+	if len(rec.AWSTags) > 0 {
+		lines = append(lines, "AWS Tags: "+strings.Join(rec.AWSTagList(), ", "))
+	}
+	return lines
 }
 
 func (m *uiModel) resize() {
@@ -832,21 +1518,7 @@ func (m *uiModel) syncTableLayout() {
 		termHeight = 40
 	}
 
-	leftOuterWidth := int(float64(termWidth) * 0.62)
-	if leftOuterWidth < 22 {
-		leftOuterWidth = 22
-	}
-	if leftOuterWidth > termWidth-20 {
-		leftOuterWidth = termWidth - 20
-	}
-	rightOuterWidth := termWidth - leftOuterWidth
-	if rightOuterWidth < 20 {
-		rightOuterWidth = 20
-		leftOuterWidth = termWidth - rightOuterWidth
-	}
-	if leftOuterWidth < 1 {
-		leftOuterWidth = 1
-	}
+	leftOuterWidth, _ := m.paneWidths(termWidth)
 
 	header := m.topHeaderView()
 	top := header
@@ -858,10 +1530,13 @@ func (m *uiModel) syncTableLayout() {
 	if m.busy {
 		statusText = m.spin.View() + " " + m.busyText
 	}
-	status := lipgloss.NewStyle().Foreground(lipgloss.Color("42")).Render(statusText)
+	status := lipgloss.NewStyle().Foreground(m.theme.status).Render(statusText)
 	hotkeys := m.hotkeysLineView()
 
 	paneHeight := termHeight - lipgloss.Height(top) - lipgloss.Height(status) - lipgloss.Height(hotkeys)
+	if syncLog := m.syncLogView(termWidth); syncLog != "" {
+		paneHeight -= lipgloss.Height(syncLog)
+	}
 	if paneHeight < 5 {
 		paneHeight = 5
 	}
@@ -882,21 +1557,65 @@ func (m *uiModel) syncTableLayout() {
 	m.table.SetWidth(leftInnerWidth)
 }
 
-func runUISyncCmd(app *App) tea.Cmd {
+// runUISyncCmd syncs in the background. With confirm, a sync that would
+// remove AWS profiles or kube contexts first asks through a syncConfirmMsg
+// and waits for the answer.
+func runUISyncCmd(app *App, confirm bool) tea.Cmd {
 	return func() tea.Msg {
-		var logBuf bytes.Buffer
-		oldLogger := app.Logger
-		level := slog.LevelInfo
-		if app.Debug {
-			level = slog.LevelDebug
-		}
-		app.Logger = slog.New(slog.NewTextHandler(&logBuf, &slog.HandlerOptions{Level: level}))
-		defer func() {
-			app.Logger = oldLogger
+		progress := make(chan discovery.Progress, 1)
+		confirms := make(chan syncConfirmMsg)
+		logs := make(chan string, uiSyncLogBuffer)
+		done := make(chan syncDoneMsg, 1)
+		feed := syncFeed{progress: progress, confirms: confirms, logs: logs, done: done}
+		go func() {
+			var logBuf bytes.Buffer
+			oldLogger := app.Logger
+			level := slog.LevelInfo
+			if app.Debug {
+				level = slog.LevelDebug
+			}
+			// Lines also stream to the log pane; the buffer keeps them all
+			// for the result.
+			out := io.MultiWriter(&logBuf, &lineWriter{lines: logs})
+			app.Logger = slog.New(slog.NewTextHandler(out, &slog.HandlerOptions{Level: level, ReplaceAttr: dropLogTime}))
+			defer func() {
+				app.Logger = oldLogger
+			}()
+
+			// Drop updates the UI has not caught up with; the next one
+			// carries the newer counts anyway.
+			opts := SyncOptions{Progress: func(p discovery.Progress) {
+				select {
+				case progress <- p:
+				default:
+				}
+			}}
+			if confirm {
+				opts.Confirm = func(preview SyncPreview) bool {
+					reply := make(chan bool, 1)
+					confirms <- syncConfirmMsg{preview: preview, reply: reply, feed: feed}
+					return <-reply
+				}
+			}
+			report, err := app.RunSync(context.Background(), opts)
+			done <- syncDoneMsg{report: report, err: err, logs: strings.TrimSpace(logBuf.String()), current: app.currentContext()}
 		}()
+		return waitForSyncCmd(feed)()
+	}
+}
 
-		report, err := app.RunSync(context.Background(), false)
-		return syncDoneMsg{report: report, err: err, logs: strings.TrimSpace(logBuf.String())}
+func waitForSyncCmd(feed syncFeed) tea.Cmd {
+	return func() tea.Msg {
+		select {
+		case p := <-feed.progress:
+			return syncProgressMsg{progress: p, feed: feed}
+		case line := <-feed.logs:
+			return syncLogMsg{line: line, feed: feed}
+		case msg := <-feed.confirms:
+			return msg
+		case msg := <-feed.done:
+			return msg
+		}
 	}
 }
 
@@ -917,23 +1636,43 @@ func runUIAuthCheckCmd(app *App) tea.Cmd {
 	}
 }
 
+// runUIAuthCmd runs the native SSO login in the background. It returns an
+// authPromptMsg as soon as a device code is issued (so the TUI can show it;
+// one per SSO session), and the prompt's done channel later yields the
+// authDoneMsg.
 func runUIAuthCmd(app *App) tea.Cmd {
 	return func() tea.Msg {
-		var stdout bytes.Buffer
-		var stderr bytes.Buffer
-		err := runAuthFlow(app, nil, &stdout, &stderr, false)
-
-		logParts := make([]string, 0, 2)
-		if out := strings.TrimSpace(stdout.String()); out != "" {
-			logParts = append(logParts, out)
-		}
-		if out := strings.TrimSpace(stderr.String()); out != "" {
-			logParts = append(logParts, out)
-		}
+		prompts := make(chan ssoauth.Device, 1)
+		done := make(chan authDoneMsg, 1)
+		go func() {
+			var stdout bytes.Buffer
+			var stderr bytes.Buffer
+			opts := authOptions{OnPrompt: func(d ssoauth.Device) { prompts <- d }}
+			err := runAuthFlow(context.Background(), app, nil, &stdout, &stderr, opts)
+
+			logParts := make([]string, 0, 2)
+			if out := strings.TrimSpace(stdout.String()); out != "" {
+				logParts = append(logParts, out)
+			}
+			if out := strings.TrimSpace(stderr.String()); out != "" {
+				logParts = append(logParts, out)
+			}
+			done <- authDoneMsg{
+				err:  err,
+				logs: strings.TrimSpace(strings.Join(logParts, "\n")),
+			}
+		}()
+		return waitForAuthCmd(prompts, done)()
+	}
+}
 
-		return authDoneMsg{
-			err:  err,
-			logs: strings.TrimSpace(strings.Join(logParts, "\n")),
+func waitForAuthCmd(prompts <-chan ssoauth.Device, done <-chan authDoneMsg) tea.Cmd {
+	return func() tea.Msg {
+		select {
+		case d := <-prompts:
+			return authPromptMsg{device: d, prompts: prompts, done: done}
+		case msg := <-done:
+			return msg
 		}
 	}
 }
@@ -941,26 +1680,88 @@ func runUIAuthCmd(app *App) tea.Cmd {
 func runUIRefreshCmd(app *App) tea.Cmd {
 	return func() tea.Msg {
 		st, err := app.loadState()
-		return refreshDoneMsg{state: st, err: err}
+		return refreshDoneMsg{state: st, err: err, current: app.currentContext()}
 	}
 }
 
-func runUIUseCmd(contextName string) tea.Cmd {
+// runUIFavCmd stars or unstars a context in the overlay file.
+func runUIFavCmd(app *App, contextName string, favorite bool) tea.Cmd {
 	return func() tea.Msg {
-		cmd := exec.CommandContext(context.Background(), "kubectl", "config", "use-context", contextName)
+		ov, err := app.loadOverlay()
+		if err == nil && ov.SetFavorite(contextName, favorite) {
+			err = overlay.Save(app.overlayPath(), ov)
+		}
+		return favDoneMsg{context: contextName, favorite: favorite, err: err}
+	}
+}
+
+func runUIUseCmd(app *App, rec state.ClusterRecord) tea.Cmd {
+	return func() tea.Msg {
+		args := append(app.kubeconfigArgs(), "config", "use-context", rec.KubeContext)
+		cmd := exec.CommandContext(context.Background(), "kubectl", args...)
 		output, err := cmd.CombinedOutput()
-		return useDoneMsg{context: contextName, err: err, output: string(output)}
+		if err == nil {
+			app.recordRecent(rec.KubeContext)
+			app.postUse(rec, "", false)
+		}
+		return useDoneMsg{context: rec.KubeContext, err: err, output: string(output), current: app.currentContext()}
 	}
 }
 
-func runUIK9sCmd(rec state.ClusterRecord) tea.Cmd {
-	args := []string{"--context", rec.KubeContext, "--command", "ns"}
+// runUIK9sCmd runs k9s on rec's context in namespace ns, or on its
+// namespace list when ns is empty.
+func runUIK9sCmd(app *App, rec state.ClusterRecord, ns string) tea.Cmd {
+	args := append(app.kubeconfigArgs(), "--context", rec.KubeContext)
+	if ns == "" {
+		args = append(args, "--command", "ns")
+	} else {
+		args = append(args, "--namespace", ns)
+	}
 	cmd := exec.Command("k9s", args...)
 	return tea.ExecProcess(cmd, func(err error) tea.Msg {
 		return k9sDoneMsg{context: rec.KubeContext, err: err}
 	})
 }
 
+// runUIConsoleCmd signs in to the AWS console as rec's role and opens its
+// cluster page in the browser.
+func runUIConsoleCmd(app *App, st state.State, rec state.ClusterRecord) tea.Cmd {
+	return func() tea.Msg {
+		cfg, err := app.loadConfig()
+		if err != nil {
+			return consoleDoneMsg{cluster: rec.ClusterName, err: err}
+		}
+		role, _ := findRole(st, rec.AWSProfile)
+		link, err := consoleLink(context.Background(), app, cfg, st, target{Name: rec.KubeContext, Role: role, Cluster: &rec})
+		if err == nil {
+			err = browser.Open(link)
+		}
+		return consoleDoneMsg{cluster: rec.ClusterName, err: err}
+	}
+}
+
+// runUIShellCmd runs the user's shell ($SHELL, else /bin/sh; %COMSPEC% on
+// Windows) in env and calls cleanup when it exits.
+func runUIShellCmd(rec state.ClusterRecord, cmd *exec.Cmd, cleanup func()) tea.Cmd {
+	return tea.ExecProcess(cmd, func(err error) tea.Msg {
+		cleanup()
+		return shellDoneMsg{context: rec.KubeContext, err: err}
+	})
+}
+
+func userShell() string {
+	if runtime.GOOS == "windows" {
+		if shell := os.Getenv("COMSPEC"); shell != "" {
+			return shell
+		}
+		return "cmd.exe"
+	}
+	if shell := os.Getenv("SHELL"); shell != "" {
+		return shell
+	}
+	return "/bin/sh"
+}
+
 func wrapTextBlock(text string, width int) string {
 	if width <= 1 {
 		return text
@@ -1071,3 +1872,22 @@ func cutRunes(s string, max int) string {
 	}
 	return b.String() + "…"
 }
+
+// keepTokenAlive renews the SSO token of every session in the background
+// while the TUI runs (sso_auto_refresh) and reports each attempt in the
+// status line.
+func keepTokenAlive(ctx context.Context, cfg config.Config, prog *tea.Program) {
+	for _, session := range cfg.Sessions() {
+		go func() {
+			_ = ssoauth.KeepAlive(ctx, session.StartURL, session.Region, ssoauth.KeepAliveOptions{
+				Session: session.ID(),
+				OnRefresh: func(tok ssoauth.CachedToken) {
+					prog.Send(tokenRefreshMsg{session: session.ID(), expiresAt: tok.ExpiresAt})
+				},
+				OnError: func(err error) {
+					prog.Send(tokenRefreshMsg{session: session.ID(), err: err})
+				},
+			})
+		}()
+	}
+}
@@ -0,0 +1,161 @@
+package cli
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/table"
+	"github.com/phenixrizen/rift/internal/state"
+	"github.com/phenixrizen/rift/internal/tableview"
+)
+
+// uiDefaultColumns are the context table's columns when ui_columns is
+// unset, after the favorite star.
+var uiDefaultColumns = []string{"env", "account", "role", "region", "cluster", "context", "tags"}
+
+// uiColumn is a context table column: a tableview column with the width
+// the table gives it.
+type uiColumn struct {
+	key   string
+	title string
+	width int
+	value func(state.ClusterRecord) string
+}
+
+// uiColumnWidths are the table widths of tableview columns; the rest get
+// uiColumnWidth.
+var uiColumnWidths = map[string]int{
+	"env":        6,
+	"account":    20,
+	"account-id": 12,
+	"role":       18,
+	"region":     10,
+	"cluster":    20,
+	"profile":    24,
+	"context":    28,
+	"namespace":  14,
+	"version":    7,
+	"created":    10,
+}
+
+const uiColumnWidth = 14
+
+// newUIColumns resolves keys (tableview column keys, default
+// uiDefaultColumns) to the table's columns, the star and then the health
+// mark from probes first. Env is shown short ("stg") and account without
+// its ID, to fit; unknown keys are skipped (config.Validate reports them).
+func newUIColumns(keys []string, probes map[string]uiHealth) []uiColumn {
+	if len(keys) == 0 {
+		keys = uiDefaultColumns
+	}
+	cols := []uiColumn{
+		{key: "favorite", title: "★", width: 1, value: tableview.FavoriteMark},
+		{key: "health", title: "", width: 1, value: func(r state.ClusterRecord) string { return probes[r.KubeContext].mark() }},
+	}
+	for _, key := range keys {
+		c, ok := tableview.Lookup(key)
+		if !ok || c.Key == "favorite" {
+			continue
+		}
+		col := uiColumn{key: c.Key, title: c.Header, width: uiColumnWidth, value: c.Value}
+		if w, ok := uiColumnWidths[c.Key]; ok {
+			col.width = w
+		}
+		switch c.Key {
+		case "env":
+			col.value = func(r state.ClusterRecord) string { return displayEnv(r.Env) }
+		case "account":
+			col.value = accountCell
+		case "context":
+			col.title = "Context"
+		}
+		cols = append(cols, col)
+	}
+	return cols
+}
+
+// tableColumns are cols for table.Model, the sorted one marked with an
+// arrow.
+func tableColumns(cols []uiColumn, sortKey string, desc bool) []table.Column {
+	out := make([]table.Column, 0, len(cols))
+	for _, c := range cols {
+		title := c.title
+		if c.key == sortKey {
+			title += " ▲"
+			if desc {
+				title = c.title + " ▼"
+			}
+		}
+		out = append(out, table.Column{Title: title, Width: c.width})
+	}
+	return out
+}
+
+// uiSort is the column the table is sorted by; a zero uiSort keeps
+// search rank order.
+type uiSort struct {
+	key  string
+	desc bool
+}
+
+// next cycles the sort through cols' keys (skipping the star and health
+// mark) and back to rank order.
+func (s uiSort) next(cols []uiColumn) uiSort {
+	keys := make([]string, 0, len(cols))
+	for _, c := range cols {
+		if c.key != "favorite" && c.key != "health" {
+			keys = append(keys, c.key)
+		}
+	}
+	i := -1
+	for j, key := range keys {
+		if key == s.key {
+			i = j
+		}
+	}
+	if i+1 >= len(keys) {
+		return uiSort{desc: s.desc}
+	}
+	return uiSort{key: keys[i+1], desc: s.desc}
+}
+
+// label describes s for the status line.
+func (s uiSort) label() string {
+	if s.key == "" {
+		return "best match"
+	}
+	if s.desc {
+		return s.key + " (descending)"
+	}
+	return s.key
+}
+
+// less reports whether a sorts before b by s's column, compared
+// case-insensitively; equal reports a tie.
+func (s uiSort) less(a, b state.ClusterRecord) (less, equal bool) {
+	c, ok := tableview.Lookup(s.key)
+	if !ok {
+		return false, true
+	}
+	left, right := strings.ToLower(c.Value(a)), strings.ToLower(c.Value(b))
+	if left == right {
+		return false, true
+	}
+	if s.desc {
+		return left > right, false
+	}
+	return left < right, false
+}
+
+// resort sorts the table by by, keeping the cursor on the selected
+// context.
+func (m *uiModel) resort(by uiSort) {
+	current := ""
+	if rec := m.selected(); rec != nil {
+		current = rec.KubeContext
+	}
+	m.sortBy = by
+	m.table.SetColumns(tableColumns(m.cols, by.key, by.desc))
+	m.applyFilter()
+	m.selectContext(current)
+	m.status = "sorted by " + by.label()
+}
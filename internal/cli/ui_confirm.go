@@ -0,0 +1,147 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/phenixrizen/rift/internal/history"
+)
+
+// confirmNamesMax caps how many removed names the sync confirm lists per
+// file before summing up the rest.
+const confirmNamesMax = 8
+
+// confirmDialog asks yes or no before something destructive; it takes every
+// key while open. No is focused first, so a stray enter does nothing.
+type confirmDialog struct {
+	title    string
+	lines    []string
+	yesLabel string
+	yes, no  tea.Cmd
+	focusYes bool
+}
+
+// update handles a key; done reports that the dialog should close, with
+// the command for the answer.
+func (c *confirmDialog) update(msg tea.KeyMsg) (cmd tea.Cmd, done bool) {
+	switch msg.String() {
+	case "y", "Y":
+		return c.yes, true
+	case "n", "N", "esc", "q":
+		return c.no, true
+	case "enter":
+		if c.focusYes {
+			return c.yes, true
+		}
+		return c.no, true
+	case "left", "right", "tab", "shift+tab", "h", "l":
+		c.focusYes = !c.focusYes
+	}
+	return nil, false
+}
+
+// view renders the dialog as a bordered box at most width columns wide.
+func (c *confirmDialog) view(width int, theme uiTheme) string {
+	contentWidth := width - 4
+	if contentWidth > 64 {
+		contentWidth = 64
+	}
+	if contentWidth < 20 {
+		contentWidth = 20
+	}
+	lines := []string{lipgloss.NewStyle().Foreground(theme.err).Bold(true).Render(cutRunes(c.title, contentWidth)), ""}
+	for _, line := range c.lines {
+		lines = append(lines, cutRunes(line, contentWidth))
+	}
+	button := func(label string, focused bool) string {
+		if focused {
+			return theme.selectedStyle().Render(" " + label + " ")
+		}
+		return " " + label + " "
+	}
+	lines = append(lines, "",
+		button("y "+c.yesLabel, c.focusYes)+"  "+button("n cancel", !c.focusYes),
+		"",
+		theme.mutedStyle().Render(cutRunes("y/n answer  ←/→ move  enter choose", contentWidth)))
+	return lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(theme.err).
+		Padding(0, 1).
+		Render(strings.Join(lines, "\n"))
+}
+
+// syncConfirmMsg is a sync waiting to hear whether it may remove what
+// preview lists; reply takes the answer.
+type syncConfirmMsg struct {
+	preview SyncPreview
+	reply   chan<- bool
+	feed    syncFeed
+}
+
+// confirmSync opens the dialog for a sync that would remove profiles or
+// contexts; either answer goes back to the sync, which keeps running.
+func (m *uiModel) confirmSync(msg syncConfirmMsg) {
+	answer := func(ok bool) tea.Cmd {
+		return func() tea.Msg {
+			msg.reply <- ok
+			return waitForSyncCmd(msg.feed)()
+		}
+	}
+	m.busyText = "waiting for confirmation..."
+	m.confirm = &confirmDialog{
+		title:    "Sync will remove entries",
+		lines:    syncConfirmLines(msg.preview),
+		yesLabel: "sync",
+		yes:      answer(true),
+		no:       answer(false),
+	}
+}
+
+// syncConfirmLines summarizes what a sync would remove, file by file, and
+// what else it would write.
+func syncConfirmLines(p SyncPreview) []string {
+	var lines []string
+	if p.Report.AWS.Removed > 0 {
+		lines = append(lines, fmt.Sprintf("Removes %d AWS profile(s) from %s:", p.Report.AWS.Removed, p.AWSConfig))
+		lines = append(lines, confirmNames(p.Changes, "role")...)
+	}
+	for _, target := range p.Report.KubeTargets {
+		if target.Result.RemovedContexts == 0 {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("Removes %d kube context(s) from %s:", target.Result.RemovedContexts, target.Path))
+		lines = append(lines, confirmNames(p.Changes, "cluster")...)
+	}
+	adds := p.Report.AWS.Added
+	updates := p.Report.AWS.Updated
+	for _, target := range p.Report.KubeTargets {
+		adds += target.Result.AddedContexts
+		updates += target.Result.UpdatedContexts
+	}
+	if adds > 0 || updates > 0 {
+		lines = append(lines, fmt.Sprintf("Also adds %d and updates %d entries.", adds, updates))
+	}
+	return lines
+}
+
+// confirmNames lists the removed changes of kind, indented, up to
+// confirmNamesMax of them.
+func confirmNames(d history.Diff, kind string) []string {
+	var names []string
+	for _, c := range d.Changes {
+		if c.Kind == kind && c.Action == history.Removed {
+			names = append(names, c.Name)
+		}
+	}
+	var lines []string
+	for i, name := range names {
+		if i == confirmNamesMax {
+			lines = append(lines, fmt.Sprintf("  ... and %d more", len(names)-i))
+			break
+		}
+		lines = append(lines, "  - "+name)
+	}
+	return lines
+}
@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"os"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/phenixrizen/rift/internal/clipboard"
+	"github.com/phenixrizen/rift/internal/state"
+)
+
+// copyTargets are the keys that follow the copy key, and what each copies.
+var copyTargets = []struct {
+	key, label string
+}{
+	{"c", "context"},
+	{"p", "profile"},
+	{"a", "ARN"},
+	{"u", "update-kubeconfig"},
+}
+
+// copyValue is the text the copy target key copies for rec, with what to
+// call it in the status line; ok is false when rec has no such value.
+func copyValue(rec state.ClusterRecord, key string) (what, text string, ok bool) {
+	switch key {
+	case "c":
+		return "context name", rec.KubeContext, rec.KubeContext != ""
+	case "p":
+		return "AWS profile", rec.AWSProfile, rec.AWSProfile != ""
+	case "a":
+		return "cluster ARN", rec.ClusterARN, rec.ClusterARN != ""
+	case "u":
+		text, ok := updateKubeconfigCommand(rec)
+		return "update-kubeconfig command", text, ok
+	}
+	return "", "", false
+}
+
+// updateKubeconfigCommand is the `aws eks update-kubeconfig` command that
+// writes rec's context into ~/.kube/config the way the AWS CLI would, under
+// rift's context name. Only EKS clusters reached through a profile have one.
+func updateKubeconfigCommand(rec state.ClusterRecord) (string, bool) {
+	if !rec.IsAWS() || rec.Platform == state.PlatformConnected || rec.AWSProfile == "" || rec.ClusterName == "" {
+		return "", false
+	}
+	args := []string{"aws", "eks", "update-kubeconfig",
+		"--name", rec.ClusterName,
+		"--region", rec.Region,
+		"--profile", rec.AWSProfile,
+		"--alias", rec.KubeContext,
+	}
+	for i, arg := range args {
+		args[i] = shellArg(arg)
+	}
+	return strings.Join(args, " "), true
+}
+
+// shellArg quotes s for a POSIX shell only when it needs it.
+func shellArg(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_.:/@=+,") == "" {
+		return s
+	}
+	return shellQuote(s)
+}
+
+// startCopy arms the copy key: the next key picks what to copy from rec.
+func (m *uiModel) startCopy() {
+	hints := make([]string, 0, len(copyTargets))
+	for _, t := range copyTargets {
+		hints = append(hints, t.key+" "+t.label)
+	}
+	m.copying = true
+	m.status = "copy: " + strings.Join(hints, "  ") + "  (esc cancel)"
+}
+
+// copyKey finishes a copy started by the copy key; any key that is not a
+// copy target cancels it.
+func (m *uiModel) copyKey(msg tea.KeyMsg) tea.Cmd {
+	m.copying = false
+	rec := m.selected()
+	if rec == nil {
+		m.status = "copy cancelled"
+		return nil
+	}
+	what, text, ok := copyValue(*rec, msg.String())
+	switch {
+	case what == "":
+		m.status = "copy cancelled"
+	case !ok:
+		m.status = rec.KubeContext + " has no " + what
+	default:
+		return m.copyText(what, text)
+	}
+	return nil
+}
+
+// copyText puts text on the clipboard and reports it as what. Over SSH the
+// copy goes through the terminal (OSC 52), which may refuse it silently.
+func (m *uiModel) copyText(what, text string) tea.Cmd {
+	via, err := clipboard.Write(os.Stdout, text)
+	if err != nil {
+		m.status = "copy failed: " + err.Error()
+		return nil
+	}
+	if via == clipboard.ViaTerminal {
+		return m.notify("copied " + what + " (via terminal): " + text)
+	}
+	return m.notify("copied " + what + ": " + text)
+}
@@ -0,0 +1,285 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/phenixrizen/rift/internal/config"
+	"github.com/phenixrizen/rift/internal/kubeconfig"
+	"github.com/phenixrizen/rift/internal/namespaces"
+	"github.com/phenixrizen/rift/internal/state"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// The details pane's tabs, switched with left and right.
+const (
+	detailInfo = iota
+	detailNamespaces
+	detailNodegroups
+	detailKubeconfig
+)
+
+var detailTabs = []string{"Info", "Namespaces", "Nodegroups", "Kubeconfig"}
+
+// nsLookupDelay is how long the selection must rest on a context with the
+// Namespaces tab open before its namespaces are looked up live, so moving
+// through the table does not fetch a token for every row passed.
+const nsLookupDelay = 300 * time.Millisecond
+
+// liveNamespaces is a live namespace list of one context; loading is set
+// while the lookup runs.
+type liveNamespaces struct {
+	loading bool
+	items   []liveNamespace
+	err     error
+	at      time.Time
+}
+
+type liveNamespace struct {
+	name    string
+	phase   string
+	created time.Time
+}
+
+// nsLookupDueMsg fires nsLookupDelay after the selection moved to context.
+type nsLookupDueMsg struct{ context string }
+
+// nsLookupDoneMsg carries a finished live namespace lookup.
+type nsLookupDoneMsg struct {
+	context string
+	live    liveNamespaces
+}
+
+// switchDetailTab moves the details pane delta tabs over, wrapping around.
+// Opening the Namespaces tab drops the selected context's last lookup, so
+// coming back to the tab looks again.
+func (m *uiModel) switchDetailTab(delta int) tea.Cmd {
+	m.detailTab = (m.detailTab + delta + len(detailTabs)) % len(detailTabs)
+	if rec := m.selected(); rec != nil && m.detailTab == detailNamespaces && !m.liveNS[rec.KubeContext].loading {
+		delete(m.liveNS, rec.KubeContext)
+	}
+	return m.followSelection()
+}
+
+// followSelection schedules a live namespace lookup for the selected
+// context when the Namespaces tab shows it and it has none yet.
+func (m *uiModel) followSelection() tea.Cmd {
+	rec := m.selected()
+	if rec == nil || !m.wantsNSLookup(*rec) {
+		return nil
+	}
+	context := rec.KubeContext
+	return tea.Tick(nsLookupDelay, func(time.Time) tea.Msg { return nsLookupDueMsg{context: context} })
+}
+
+// wantsNSLookup reports whether rec's namespaces should be looked up now.
+// Imported contexts authenticate their own way and are left alone.
+func (m *uiModel) wantsNSLookup(rec state.ClusterRecord) bool {
+	if m.detailTab != detailNamespaces || m.graph != nil || !rec.Connectable() || rec.External() {
+		return false
+	}
+	_, ok := m.liveNS[rec.KubeContext]
+	return !ok
+}
+
+// startNSLookup starts the lookup a nsLookupDueMsg asked for, if the
+// selection is still there.
+func (m *uiModel) startNSLookup(msg nsLookupDueMsg) tea.Cmd {
+	rec := m.selected()
+	if rec == nil || rec.KubeContext != msg.context || !m.wantsNSLookup(*rec) {
+		return nil
+	}
+	m.liveNS[rec.KubeContext] = liveNamespaces{loading: true}
+	return nsLookupCmd(*rec)
+}
+
+func nsLookupCmd(rec state.ClusterRecord) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), uiHealthTimeout)
+		defer cancel()
+		done := nsLookupDoneMsg{context: rec.KubeContext, live: liveNamespaces{at: time.Now()}}
+		client, err := namespaces.NewClient(ctx, rec)
+		if err != nil {
+			done.live.err = err
+			return done
+		}
+		list, err := client.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+		if err != nil {
+			done.live.err = err
+			return done
+		}
+		for _, ns := range list.Items {
+			done.live.items = append(done.live.items, liveNamespace{name: ns.Name, phase: string(ns.Status.Phase), created: ns.CreationTimestamp.Time})
+		}
+		sort.Slice(done.live.items, func(i, j int) bool { return done.live.items[i].name < done.live.items[j].name })
+		return done
+	}
+}
+
+// detailView is the details pane: the tab bar, then the open tab for the
+// selected context.
+func (m *uiModel) detailView(width int) string {
+	rec := m.selected()
+	if rec == nil {
+		if cursor := m.table.Cursor(); m.graph == nil && cursor >= 0 && cursor < len(m.rows) {
+			row := m.rows[cursor]
+			return fmt.Sprintf("%s %s: %d of %d contexts\n\n%s opens or closes the group", m.groupBy, row.group, row.count, len(m.filtered), m.keys.key(config.ActionUse))
+		}
+		return "No contexts"
+	}
+	bar := m.detailTabsView(width)
+	if m.detailTab == detailKubeconfig {
+		// Certificate data would wrap over the whole pane; cut lines instead.
+		lines := kubeconfigLines(*rec)
+		for i, line := range lines {
+			lines[i] = cutRunes(line, width)
+		}
+		return bar + "\n\n" + strings.Join(lines, "\n")
+	}
+	var lines []string
+	switch m.detailTab {
+	case detailNamespaces:
+		lines = m.namespaceLines(*rec, time.Now())
+	case detailNodegroups:
+		lines = nodegroupLines(*rec)
+	default:
+		lines = m.infoLines(rec)
+	}
+	return bar + "\n\n" + lipgloss.NewStyle().Width(width).Render(wrapTextBlock(strings.Join(lines, "\n"), width))
+}
+
+// detailTabsView renders the tab names, the open one highlighted.
+func (m *uiModel) detailTabsView(width int) string {
+	tabs := make([]string, 0, len(detailTabs))
+	for i, name := range detailTabs {
+		if i == m.detailTab {
+			tabs = append(tabs, m.theme.selectedStyle().Render(" "+name+" "))
+		} else {
+			tabs = append(tabs, m.theme.mutedStyle().Render(" "+name+" "))
+		}
+	}
+	return ansi.Truncate(strings.Join(tabs, ""), width, "")
+}
+
+// namespaceLines are the Namespaces tab: the default namespace, then the
+// live list when it has been looked up, else the one recorded at the last
+// sync, each with its recorded workload counts and access badge.
+func (m *uiModel) namespaceLines(rec state.ClusterRecord, now time.Time) []string {
+	lines := []string{"Default: " + valueOr(rec.Namespace, "(none)")}
+	if rec.NamespacePinned {
+		lines[0] += " (pinned)"
+	}
+	workloads := map[string]string{}
+	for _, w := range rec.Workloads {
+		workloads[w.Namespace] = w.Label()
+	}
+	annotate := func(line, ns string) string {
+		if allowed, checked := rec.AccessTo(ns); checked {
+			if allowed {
+				line += " [access]"
+			} else {
+				line += " [no access]"
+			}
+		}
+		if label := workloads[ns]; label != "" {
+			return line + "  " + label
+		}
+		if len(workloads) > 0 {
+			// Counts were taken and nothing runs here.
+			return line + "  empty"
+		}
+		return line
+	}
+	live, ok := m.liveNS[rec.KubeContext]
+	switch {
+	case ok && live.loading:
+		lines = append(lines, "", "Looking up live namespaces...")
+	case ok && live.err != nil:
+		lines = append(lines, "", "Live lookup failed: "+live.err.Error())
+	case ok:
+		lines = append(lines, "", fmt.Sprintf("Live (%d, looked up %s ago):", len(live.items), shortAgo(now.Sub(live.at))))
+		for _, ns := range live.items {
+			line := "  " + ns.name
+			if ns.phase != "" && ns.phase != "Active" {
+				line += " [" + ns.phase + "]"
+			}
+			if !ns.created.IsZero() {
+				line += "  " + shortAgo(now.Sub(ns.created))
+			}
+			lines = append(lines, annotate(line, ns.name))
+		}
+		return lines
+	}
+	if len(rec.Namespaces) == 0 {
+		return append(lines, "", "No namespaces recorded; sync with discover_namespaces: true")
+	}
+	lines = append(lines, "", fmt.Sprintf("At last sync (%d):", len(rec.Namespaces)))
+	for _, ns := range rec.Namespaces {
+		lines = append(lines, annotate("  "+ns, ns))
+	}
+	return lines
+}
+
+// nodegroupLines are the Nodegroups tab: each managed node group and
+// Fargate profile recorded at the last sync.
+func nodegroupLines(rec state.ClusterRecord) []string {
+	if len(rec.Nodegroups) == 0 && len(rec.FargateProfiles) == 0 {
+		if !rec.IsAWS() {
+			return []string{"Node groups are only recorded for EKS clusters."}
+		}
+		return []string{"No node groups recorded; sync with discover_compute: true"}
+	}
+	var lines []string
+	for _, ng := range rec.Nodegroups {
+		lines = append(lines, ng.Name)
+		if len(ng.InstanceTypes) > 0 {
+			lines = append(lines, "  Instances: "+strings.Join(ng.InstanceTypes, ", "))
+		}
+		lines = append(lines, fmt.Sprintf("  Nodes: %d (min %d, max %d)", ng.DesiredSize, ng.MinSize, ng.MaxSize))
+		if ng.CapacityType != "" {
+			lines = append(lines, "  Capacity: "+ng.CapacityType)
+		}
+		if ng.KubernetesVersion != "" {
+			lines = append(lines, "  Kubernetes: "+ng.KubernetesVersion)
+		}
+		if ng.Status != "" {
+			lines = append(lines, "  Status: "+ng.Status)
+		}
+	}
+	for _, fp := range rec.FargateProfiles {
+		lines = append(lines, "Fargate: "+fp.Name)
+		if len(fp.Namespaces) > 0 {
+			lines = append(lines, "  Namespaces: "+strings.Join(fp.Namespaces, ", "))
+		}
+		if fp.Status != "" {
+			lines = append(lines, "  Status: "+fp.Status)
+		}
+	}
+	return lines
+}
+
+// kubeconfigLines are the Kubeconfig tab: rec's standalone kubeconfig, as
+// the action menu's kubeconfig item shows it.
+func kubeconfigLines(rec state.ClusterRecord) []string {
+	if !rec.Connectable() {
+		return []string{"No kubeconfig entry: the cluster has no API endpoint."}
+	}
+	snippet, err := kubeconfig.Standalone(rec, "")
+	if err != nil {
+		return []string{"kubeconfig failed: " + err.Error()}
+	}
+	return strings.Split(strings.TrimSpace(string(snippet)), "\n")
+}
+
+func valueOr(s, fallback string) string {
+	if s == "" {
+		return fallback
+	}
+	return s
+}
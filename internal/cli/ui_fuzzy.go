@@ -0,0 +1,264 @@
+package cli
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/bubbles/table"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/lithammer/fuzzysearch/fuzzy"
+	"github.com/phenixrizen/rift/internal/state"
+)
+
+// uiRow is one context table row: the record, its cells, and the rune
+// positions in each cell matched by the search query. pin orders rows of
+// equal rank: 0 for favorites, 1 for the rest, or the position in the
+// recent list in the recent view. In the grouped table, a row with group
+// set is the header of the count rows below it (see groupRows).
+type uiRow struct {
+	rec     state.ClusterRecord
+	cells   table.Row
+	matches map[int][]int
+	rank    int
+	pin     int
+
+	group     string
+	count     int
+	collapsed bool
+}
+
+func newUIRow(rec state.ClusterRecord, cols []uiColumn) uiRow {
+	pin := 1
+	if rec.Favorite {
+		pin = 0
+	}
+	cells := make(table.Row, 0, len(cols))
+	for _, c := range cols {
+		cells = append(cells, c.value(rec))
+	}
+	return uiRow{rec: rec, pin: pin, cells: cells}
+}
+
+// uiFavoriteCol is the star column and uiHealthCol the health mark after
+// it; they are never matched.
+const (
+	uiFavoriteCol = 0
+	uiHealthCol   = 1
+)
+
+// Candidate columns of uiRow.match besides the cells' own indexes.
+const (
+	matchHidden = -1 // a field the table does not show
+	matchRow    = -2 // all cells joined by spaces
+)
+
+// match fuzzy-matches every whitespace-separated term of query (already
+// lowercased) against each cell, the fields the table does not show, and
+// the whole row, the way `rift use` ranks contexts, so "pusw2" finds prod
+// in us-west-2. Each term must match one of them; the row's rank is the
+// sum of the best distances, so a term matching within one cell ranks
+// above one spread over the row. Matched characters of cells are recorded
+// for highlighting.
+func (r *uiRow) match(query string) bool {
+	type candidate struct {
+		text string
+		col  int
+	}
+	candidates := make([]candidate, 0, len(r.cells)+6)
+	for i, cell := range r.cells {
+		if i != uiFavoriteCol && i != uiHealthCol {
+			candidates = append(candidates, candidate{text: cell, col: i})
+		}
+	}
+	for _, hidden := range []string{r.rec.Env, r.rec.AccountName, r.rec.AccountAlias, r.rec.AccountID, r.rec.SSOSession} {
+		if hidden != "" {
+			candidates = append(candidates, candidate{text: hidden, col: matchHidden})
+		}
+	}
+	candidates = append(candidates, candidate{text: strings.Join(r.cells, " "), col: matchRow})
+
+	r.matches = map[int][]int{}
+	r.rank = 0
+	for _, term := range strings.Fields(query) {
+		best := -1
+		bestCol := -1
+		for _, c := range candidates {
+			distance := fuzzy.RankMatchNormalizedFold(term, c.text)
+			if distance < 0 || (best >= 0 && distance >= best) {
+				continue
+			}
+			best, bestCol = distance, c.col
+		}
+		if best < 0 {
+			return false
+		}
+		r.rank += best
+		switch {
+		case bestCol >= 0:
+			r.matches[bestCol] = append(r.matches[bestCol], fuzzyPositions(term, r.cells[bestCol])...)
+		case bestCol == matchRow:
+			r.markRow(fuzzyPositions(term, strings.Join(r.cells, " ")))
+		}
+	}
+	return true
+}
+
+// markRow records positions in the space-joined cells as cell matches.
+func (r *uiRow) markRow(positions []int) {
+	col, offset := 0, 0
+	for _, pos := range positions {
+		for col < len(r.cells) && pos >= offset+len([]rune(r.cells[col])) {
+			offset += len([]rune(r.cells[col])) + 1
+			col++
+		}
+		if col < len(r.cells) && pos >= offset {
+			r.matches[col] = append(r.matches[col], pos-offset)
+		}
+	}
+}
+
+// sortRows orders rows by by's column when it is set, then by rank, best
+// first, then by pin, keeping state order for ties; with no search every
+// rank is 0, so favorites (or, in the recent view, the newest contexts) are
+// pinned to the top.
+func sortRows(rows []uiRow, by uiSort) {
+	sort.SliceStable(rows, func(i, j int) bool {
+		if less, equal := by.less(rows[i].rec, rows[j].rec); !equal {
+			return less
+		}
+		if rows[i].rank != rows[j].rank {
+			return rows[i].rank < rows[j].rank
+		}
+		return rows[i].pin < rows[j].pin
+	})
+}
+
+// fuzzyPositions returns the rune indexes of target that spell term, taking
+// the earliest occurrence of each character in turn.
+func fuzzyPositions(term, target string) []int {
+	want := []rune(strings.ToLower(term))
+	positions := make([]int, 0, len(want))
+	i := 0
+	for pos, r := range []rune(target) {
+		if i == len(want) {
+			break
+		}
+		if unicode.ToLower(r) == want[i] {
+			positions = append(positions, pos)
+			i++
+		}
+	}
+	return positions
+}
+
+// tableView renders the context table the way table.Model does, with
+// search matches highlighted. table.Model cannot draw them itself: it
+// truncates cells with go-runewidth, which counts ANSI escapes as text.
+// It keeps the table's cursor, columns, and scroll offset (m.tableTop).
+func (m uiModel) tableView(width, height int) string {
+	styles := m.theme.tableStyles()
+	columns := m.table.Columns()
+	headers := make([]string, 0, len(columns))
+	for _, col := range columns {
+		cell := lipgloss.NewStyle().Width(col.Width).MaxWidth(col.Width).Inline(true).Render(ansi.Truncate(col.Title, col.Width, "…"))
+		headers = append(headers, styles.Header.Render(cell))
+	}
+	lines := []string{lipgloss.JoinHorizontal(lipgloss.Top, headers...)}
+	tableWidth := lipgloss.Width(lines[0])
+
+	rows := height - lipgloss.Height(lines[0])
+	if rows < 1 {
+		rows = 1
+	}
+	cursor := m.table.Cursor()
+	top := m.tableTop
+	if cursor < top {
+		top = cursor
+	}
+	if cursor >= top+rows {
+		top = cursor - rows + 1
+	}
+	if top < 0 {
+		top = 0
+	}
+	highlight := lipgloss.NewStyle().Foreground(m.theme.highlight).Bold(true)
+	for i := top; i < len(m.rows) && i < top+rows; i++ {
+		base := lipgloss.NewStyle()
+		if m.current != "" && m.rows[i].rec.KubeContext == m.current {
+			base = base.Foreground(m.theme.status).Bold(true)
+		}
+		hl := highlight
+		if i == cursor {
+			base = styles.Selected
+			hl = styles.Selected.Underline(true)
+		}
+		if m.rows[i].group != "" {
+			style := lipgloss.NewStyle().Foreground(m.theme.accent).Bold(true)
+			if i == cursor {
+				style = styles.Selected
+			}
+			lines = append(lines, style.Render(padToWidth(cutRunes(" "+m.rows[i].groupHeader(), tableWidth), tableWidth)))
+			continue
+		}
+		cells := make([]string, 0, len(columns))
+		for c, col := range columns {
+			if c >= len(m.rows[i].cells) {
+				break
+			}
+			text := highlightCell(m.rows[i].cells[c], m.rows[i].matches[c], col.Width, base, hl)
+			cells = append(cells, base.Padding(0, 1).Render(text))
+		}
+		lines = append(lines, lipgloss.JoinHorizontal(lipgloss.Top, cells...))
+	}
+	return lipgloss.NewStyle().MaxWidth(width).Render(strings.Join(lines, "\n"))
+}
+
+// highlightCell cuts text to width (with "…" like table.Model), pads it,
+// and renders the runes at positions with hl and the rest with base.
+func highlightCell(text string, positions []int, width int, base, hl lipgloss.Style) string {
+	runes := []rune(text)
+	truncated := ansi.StringWidth(text) > width
+	if truncated {
+		runes = []rune(ansi.Truncate(text, width, "…"))
+	}
+	marked := make([]bool, len(runes))
+	for _, pos := range positions {
+		if pos < len(runes) && !(truncated && pos == len(runes)-1) {
+			marked[pos] = true
+		}
+	}
+	var b strings.Builder
+	for start, i := 0, 1; i <= len(runes); i++ {
+		if i < len(runes) && marked[i] == marked[start] {
+			continue
+		}
+		style := base
+		if marked[start] {
+			style = hl
+		}
+		b.WriteString(style.Render(string(runes[start:i])))
+		start = i
+	}
+	if pad := width - ansi.StringWidth(string(runes)); pad > 0 {
+		b.WriteString(base.Render(strings.Repeat(" ", pad)))
+	}
+	return b.String()
+}
+
+// scrollTable moves m.tableTop so the cursor row stays visible, as
+// table.Model scrolls its own viewport.
+func (m *uiModel) scrollTable() {
+	rows := m.table.Height()
+	cursor := m.table.Cursor()
+	if cursor < m.tableTop {
+		m.tableTop = cursor
+	}
+	if rows > 0 && cursor >= m.tableTop+rows {
+		m.tableTop = cursor - rows + 1
+	}
+	if maxTop := len(m.rows) - rows; m.tableTop > maxTop {
+		m.tableTop = max(maxTop, 0)
+	}
+}
@@ -0,0 +1,266 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/phenixrizen/rift/internal/config"
+	"github.com/phenixrizen/rift/internal/graphview"
+	"github.com/phenixrizen/rift/internal/state"
+)
+
+// graphTree is the topology view `g` shows in place of the table: the
+// env -> account -> role -> cluster -> namespace tree from graphview.Build,
+// with collapsible nodes. Cluster nodes start collapsed.
+type graphTree struct {
+	nodes    map[string]graphview.Node
+	children map[string][]string
+	parent   map[string]string
+	roots    []string
+	// clusters maps cluster node IDs to their records.
+	clusters map[string]state.ClusterRecord
+	// toggled holds nodes the user opened or closed, kept across rebuilds.
+	toggled map[string]bool
+	lines   []graphLine
+	cursor  int
+	offset  int
+}
+
+type graphLine struct {
+	id    string
+	depth int
+}
+
+// newGraphTree builds the tree for clusters; roles are the state's roles
+// that own one of them, or all of them when every cluster is shown. prev,
+// if non-nil, passes on its toggled nodes and selection.
+func newGraphTree(st state.State, clusters []state.ClusterRecord, prev *graphTree) *graphTree {
+	roles := st.Roles
+	if len(clusters) != len(st.Clusters) {
+		owners := map[string]struct{}{}
+		for _, c := range clusters {
+			owners[c.Env+"|"+c.AccountID+"|"+c.RoleName] = struct{}{}
+		}
+		roles = make([]state.RoleRecord, 0, len(owners))
+		for _, r := range st.Roles {
+			if _, ok := owners[r.Env+"|"+r.AccountID+"|"+r.RoleName]; ok {
+				roles = append(roles, r)
+			}
+		}
+	}
+	graph := graphview.Build(state.State{Roles: roles, Clusters: clusters}, graphview.Options{Env: "all", Depth: 5, Namespaces: true})
+
+	t := &graphTree{
+		nodes:    make(map[string]graphview.Node, len(graph.Nodes)),
+		children: map[string][]string{},
+		parent:   map[string]string{},
+		clusters: make(map[string]state.ClusterRecord, len(clusters)),
+		toggled:  map[string]bool{},
+	}
+	for _, node := range graph.Nodes {
+		t.nodes[node.ID] = node
+	}
+	// Build sorts edges by ID; order children by label like the ASCII graph.
+	for _, edge := range graph.Edges {
+		t.children[edge.From] = append(t.children[edge.From], edge.To)
+		t.parent[edge.To] = edge.From
+	}
+	for _, kids := range t.children {
+		sortByLabel(kids, t.nodes)
+	}
+	for _, node := range graph.Nodes {
+		if _, ok := t.parent[node.ID]; !ok {
+			t.roots = append(t.roots, node.ID)
+		}
+	}
+	sortByLabel(t.roots, t.nodes)
+	for _, c := range clusters {
+		t.clusters[graphview.ClusterNodeID(c)] = c
+	}
+
+	selected := ""
+	if prev != nil {
+		t.toggled = prev.toggled
+		t.offset = prev.offset
+		selected = prev.current()
+	}
+	t.layout()
+	for i, line := range t.lines {
+		if line.id == selected {
+			t.cursor = i
+		}
+	}
+	return t
+}
+
+func sortByLabel(ids []string, nodes map[string]graphview.Node) {
+	sort.SliceStable(ids, func(i, j int) bool { return nodes[ids[i]].Label < nodes[ids[j]].Label })
+}
+
+func (t *graphTree) collapsed(id string) bool {
+	if v, ok := t.toggled[id]; ok {
+		return v
+	}
+	return t.nodes[id].Kind == "cluster"
+}
+
+// layout recomputes the visible lines after a node opens or closes.
+func (t *graphTree) layout() {
+	t.lines = t.lines[:0]
+	var walk func(id string, depth int)
+	walk = func(id string, depth int) {
+		t.lines = append(t.lines, graphLine{id: id, depth: depth})
+		if t.collapsed(id) {
+			return
+		}
+		for _, kid := range t.children[id] {
+			walk(kid, depth+1)
+		}
+	}
+	for _, root := range t.roots {
+		walk(root, 0)
+	}
+	if t.cursor >= len(t.lines) {
+		t.cursor = len(t.lines) - 1
+	}
+	if t.cursor < 0 {
+		t.cursor = 0
+	}
+}
+
+// current is the ID of the node under the cursor, or "".
+func (t *graphTree) current() string {
+	if t == nil || len(t.lines) == 0 {
+		return ""
+	}
+	return t.lines[t.cursor].id
+}
+
+// selectedCluster is the cluster of the node under the cursor: the cluster
+// node itself or the cluster a namespace or compute node belongs to.
+func (t *graphTree) selectedCluster() *state.ClusterRecord {
+	for id := t.current(); id != ""; id = t.parent[id] {
+		if rec, ok := t.clusters[id]; ok {
+			return &rec
+		}
+	}
+	return nil
+}
+
+func (t *graphTree) setCollapsed(id string, collapsed bool) {
+	if len(t.children[id]) == 0 {
+		return
+	}
+	t.toggled[id] = collapsed
+	t.layout()
+}
+
+func (t *graphTree) move(delta int) {
+	t.cursor += delta
+	if t.cursor >= len(t.lines) {
+		t.cursor = len(t.lines) - 1
+	}
+	if t.cursor < 0 {
+		t.cursor = 0
+	}
+}
+
+func (t *graphTree) moveTo(id string) {
+	for i, line := range t.lines {
+		if line.id == id {
+			t.cursor = i
+			return
+		}
+	}
+}
+
+// update handles tree navigation keys and reports whether it used the key;
+// enter is only taken on nodes that are not clusters, so the caller switches
+// to a cluster's context.
+func (t *graphTree) update(msg tea.KeyMsg, height int) bool {
+	id := t.current()
+	page := height - 1
+	if page < 1 {
+		page = 1
+	}
+	switch msg.String() {
+	case "up":
+		t.move(-1)
+	case "down":
+		t.move(1)
+	case "pgup":
+		t.move(-page)
+	case "pgdown":
+		t.move(page)
+	case "home":
+		t.move(-len(t.lines))
+	case "end":
+		t.move(len(t.lines))
+	case "right", "l":
+		if len(t.children[id]) > 0 && t.collapsed(id) {
+			t.setCollapsed(id, false)
+		} else if kids := t.children[id]; len(kids) > 0 {
+			t.moveTo(kids[0])
+		}
+	case "left", "h":
+		if len(t.children[id]) > 0 && !t.collapsed(id) {
+			t.setCollapsed(id, true)
+		} else if parent, ok := t.parent[id]; ok {
+			t.moveTo(parent)
+		}
+	case " ":
+		t.setCollapsed(id, !t.collapsed(id))
+	case "enter":
+		if _, ok := t.clusters[id]; ok {
+			return false
+		}
+		t.setCollapsed(id, !t.collapsed(id))
+	default:
+		return false
+	}
+	return true
+}
+
+// view renders height lines of the tree, scrolling to keep the cursor on
+// screen.
+func (t *graphTree) view(width, height int, theme uiTheme, keys uiKeys) string {
+	title := theme.accentStyle().
+		Render(cutRunes(fmt.Sprintf("Topology (%d nodes)  space/left/right fold  %s use  %s table", len(t.nodes), keys.key(config.ActionUse), keys.key(config.ActionGraph)), width))
+	rows := height - 1
+	if rows < 1 {
+		rows = 1
+	}
+	if len(t.lines) == 0 {
+		return title + "\n(no graph nodes)"
+	}
+	if t.cursor < t.offset {
+		t.offset = t.cursor
+	}
+	if t.cursor >= t.offset+rows {
+		t.offset = t.cursor - rows + 1
+	}
+	if maxOffset := len(t.lines) - rows; t.offset > maxOffset {
+		t.offset = max(maxOffset, 0)
+	}
+
+	selected := theme.selectedStyle()
+	out := []string{title}
+	for i := t.offset; i < len(t.lines) && i < t.offset+rows; i++ {
+		line := t.lines[i]
+		marker := "  "
+		if len(t.children[line.id]) > 0 {
+			marker = "▾ "
+			if t.collapsed(line.id) {
+				marker = "▸ "
+			}
+		}
+		text := padToWidth(cutRunes(strings.Repeat("  ", line.depth)+marker+t.nodes[line.id].Label, width), width)
+		if i == t.cursor {
+			text = selected.Render(text)
+		}
+		out = append(out, text)
+	}
+	return strings.Join(out, "\n")
+}
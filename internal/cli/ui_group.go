@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/bubbles/table"
+	"github.com/phenixrizen/rift/internal/state"
+)
+
+// Table groupings, cycled by the group key.
+const (
+	uiGroupNone    = ""
+	uiGroupEnv     = "env"
+	uiGroupAccount = "account"
+)
+
+// nextGroupBy cycles none -> env -> account -> none.
+func nextGroupBy(by string) string {
+	switch by {
+	case uiGroupNone:
+		return uiGroupEnv
+	case uiGroupEnv:
+		return uiGroupAccount
+	}
+	return uiGroupNone
+}
+
+// groupLabel is the group rec falls under when the table is grouped by by.
+func groupLabel(rec state.ClusterRecord, by string) string {
+	label := rec.Env
+	if by == uiGroupAccount {
+		label = rec.AccountLabel()
+		if label == "" {
+			label = rec.AccountID
+		}
+	}
+	if label == "" {
+		return "(none)"
+	}
+	return label
+}
+
+// groupRows puts a header row before each group of rows, groups ordered
+// by their first row so the best matches stay on top, and leaves out the
+// rows of collapsed groups. Header rows have a blank cell per column, for
+// table.Model.
+func groupRows(rows []uiRow, by string, collapsed map[string]bool, columns int) []uiRow {
+	var order []string
+	members := map[string][]uiRow{}
+	for _, row := range rows {
+		label := groupLabel(row.rec, by)
+		if _, ok := members[label]; !ok {
+			order = append(order, label)
+		}
+		members[label] = append(members[label], row)
+	}
+	out := make([]uiRow, 0, len(rows)+len(order))
+	for _, label := range order {
+		closed := collapsed[by+"|"+label]
+		out = append(out, uiRow{group: label, count: len(members[label]), collapsed: closed, cells: make(table.Row, columns)})
+		if !closed {
+			out = append(out, members[label]...)
+		}
+	}
+	return out
+}
+
+// groupHeader is the text of a group header row.
+func (r uiRow) groupHeader() string {
+	marker := "▾"
+	if r.collapsed {
+		marker = "▸"
+	}
+	return fmt.Sprintf("%s %s (%d)", marker, r.group, r.count)
+}
+
+// toggleGroup opens or closes the group whose header is under the cursor
+// and reports whether there was one.
+func (m *uiModel) toggleGroup() bool {
+	cursor := m.table.Cursor()
+	if m.groupBy == uiGroupNone || cursor < 0 || cursor >= len(m.rows) || m.rows[cursor].group == "" {
+		return false
+	}
+	key := m.groupBy + "|" + m.rows[cursor].group
+	m.collapsed[key] = !m.collapsed[key]
+	m.applyFilter()
+	return true
+}
@@ -0,0 +1,178 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/phenixrizen/rift/internal/config"
+	"github.com/phenixrizen/rift/internal/health"
+	"github.com/phenixrizen/rift/internal/state"
+)
+
+// uiHealthConcurrency caps the API server probes rift ui runs at once, and
+// uiHealthTimeout bounds each one (token, /version, and a namespace list).
+const (
+	uiHealthConcurrency = 8
+	uiHealthTimeout     = 15 * time.Second
+)
+
+// uiHealth is what rift ui knows of a context's API server: probing while a
+// probe runs, and result the last one finished, checked at at.
+type uiHealth struct {
+	probing bool
+	result  *health.Result
+	at      time.Time
+}
+
+// mark is the health column's cell: blank before the first probe, ✓ when
+// the API server took the token, ? while probing or when SSO needs a login
+// to tell, and ✗ for anything else (unreachable, or the token refused).
+// A re-probe keeps the last mark until it finishes.
+func (h uiHealth) mark() string {
+	if h.result == nil {
+		if h.probing {
+			return "?"
+		}
+		return ""
+	}
+	switch h.result.Status {
+	case health.StatusOK:
+		return "✓"
+	case health.StatusReauth:
+		return "?"
+	}
+	return "✗"
+}
+
+// healthResultMsg is one finished probe.
+type healthResultMsg struct {
+	result health.Result
+	at     time.Time
+}
+
+// healthTickMsg starts a ui_health_check round.
+type healthTickMsg struct{}
+
+func healthTickCmd(every time.Duration) tea.Cmd {
+	return tea.Tick(every, func(time.Time) tea.Msg { return healthTickMsg{} })
+}
+
+// healthProbeCmd probes rec's API server as `rift verify` does, waiting
+// for a slot in sem first.
+func healthProbeCmd(rec state.ClusterRecord, sem chan struct{}) tea.Cmd {
+	return func() tea.Msg {
+		sem <- struct{}{}
+		defer func() { <-sem }()
+		ctx, cancel := context.WithTimeout(context.Background(), uiHealthTimeout)
+		defer cancel()
+		return healthResultMsg{result: health.Probe(ctx, rec), at: time.Now()}
+	}
+}
+
+// probeHealth starts probing recs that are not already being probed;
+// imported contexts authenticate their own way and are skipped. report
+// makes the round end with a toast counting the results.
+func (m *uiModel) probeHealth(recs []state.ClusterRecord, report bool) tea.Cmd {
+	var cmds []tea.Cmd
+	for _, rec := range recs {
+		if rec.External() || m.health[rec.KubeContext].probing {
+			continue
+		}
+		h := m.health[rec.KubeContext]
+		h.probing = true
+		m.health[rec.KubeContext] = h
+		m.setHealthCell(rec.KubeContext)
+		cmds = append(cmds, healthProbeCmd(rec, m.healthSem))
+	}
+	if len(cmds) == 0 {
+		return nil
+	}
+	m.healthPending += len(cmds)
+	m.healthReport = m.healthReport || report
+	return tea.Batch(cmds...)
+}
+
+// applyHealth records a finished probe; the last one of a round started by
+// the health key reports the round.
+func (m *uiModel) applyHealth(msg healthResultMsg) tea.Cmd {
+	result := msg.result
+	m.health[result.Context] = uiHealth{result: &result, at: msg.at}
+	m.setHealthCell(result.Context)
+	if m.healthPending > 0 {
+		m.healthPending--
+	}
+	if m.healthPending > 0 || !m.healthReport {
+		return nil
+	}
+	m.healthReport = false
+	ok, down, unknown := 0, 0, 0
+	for _, h := range m.health {
+		switch h.mark() {
+		case "✓":
+			ok++
+		case "✗":
+			down++
+		case "?":
+			unknown++
+		}
+	}
+	text := fmt.Sprintf("health: %d reachable, %d failing", ok, down)
+	if unknown > 0 {
+		text += fmt.Sprintf(", %d need sso login", unknown)
+	}
+	return m.notify(text)
+}
+
+// setHealthCell redraws context's health cell in the table rows.
+func (m *uiModel) setHealthCell(context string) {
+	for i := range m.rows {
+		if m.rows[i].group == "" && m.rows[i].rec.KubeContext == context {
+			m.rows[i].cells[uiHealthCol] = m.health[context].mark()
+		}
+	}
+}
+
+// healthLine describes rec's last probe for the details pane.
+func (m uiModel) healthLine(rec state.ClusterRecord, now time.Time) string {
+	h, ok := m.health[rec.KubeContext]
+	if !ok || h.result == nil {
+		if h.probing {
+			return "Health: probing..."
+		}
+		if rec.External() {
+			return ""
+		}
+		return "Health: not checked (" + m.keys.key(config.ActionHealth) + " to probe)"
+	}
+	r := h.result
+	line := "Health: " + string(r.Status)
+	if r.Version != "" {
+		line += " " + r.Version
+	}
+	if r.Latency > 0 {
+		line += fmt.Sprintf(", %dms", r.Latency.Milliseconds())
+	}
+	line += ", checked " + shortAgo(now.Sub(h.at)) + " ago"
+	if h.probing {
+		line += " (probing again)"
+	}
+	if r.Error != "" {
+		line += "\nHealth Error: " + r.Error
+	}
+	return line
+}
+
+// shortAgo renders d as whole seconds, minutes, hours, or days.
+func shortAgo(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dd", int(d.Hours()/24))
+}
@@ -0,0 +1,38 @@
+package cli
+
+import "strings"
+
+// uiKeys resolves keys to rift ui actions (config.Actions) from the
+// keybindings config. A key no action uses falls through to the table, so
+// "k" moves up like vim once k9s is bound elsewhere.
+type uiKeys struct {
+	byKey    map[string]string
+	byAction map[string]string
+}
+
+func newUIKeys(bindings map[string]string) uiKeys {
+	k := uiKeys{byKey: make(map[string]string, len(bindings)), byAction: bindings}
+	for action, key := range bindings {
+		k.byKey[key] = action
+	}
+	return k
+}
+
+// action is the action bound to key, or "".
+func (k uiKeys) action(key string) string {
+	return k.byKey[key]
+}
+
+// key is the key bound to action, for hints.
+func (k uiKeys) key(action string) string {
+	return k.byAction[action]
+}
+
+// hint renders the keys of actions for the hotkey lines, as "<f/F>".
+func (k uiKeys) hint(actions ...string) string {
+	keys := make([]string, len(actions))
+	for i, action := range actions {
+		keys[i] = k.key(action)
+	}
+	return "<" + strings.Join(keys, "/") + ">"
+}
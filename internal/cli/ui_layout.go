@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/phenixrizen/rift/internal/uiprefs"
+)
+
+// The details pane's share of the terminal width, in percent: the
+// default, the range the grow and shrink keys keep it in, and their step.
+const (
+	uiDetailWidth    = 38
+	uiDetailWidthMin = 20
+	uiDetailWidthMax = 70
+	uiDetailStep     = 5
+)
+
+// prefsSavedMsg reports a failed save of the layout preferences.
+type prefsSavedMsg struct{ err error }
+
+func (a *App) uiPrefsPath() string {
+	return uiprefs.PathFor(a.StatePath)
+}
+
+// paneWidths splits termWidth into the table and details panes' outer
+// widths; the details pane is 0 wide while hidden. Each pane keeps room
+// for its border and a little text however narrow the terminal.
+func (m uiModel) paneWidths(termWidth int) (left, right int) {
+	if m.detailHidden {
+		return max(termWidth, 1), 0
+	}
+	left = termWidth * (100 - m.detailWidth) / 100
+	if left < 22 {
+		left = 22
+	}
+	if left > termWidth-20 {
+		left = termWidth - 20
+	}
+	right = termWidth - left
+	if right < 20 {
+		right = 20
+		left = termWidth - right
+	}
+	return max(left, 1), max(right, 1)
+}
+
+// resizeDetails grows (delta > 0) or shrinks the details pane by delta
+// percent, showing it if hidden, and saves the layout.
+func (m *uiModel) resizeDetails(delta int) tea.Cmd {
+	if m.detailHidden {
+		if delta < 0 {
+			m.status = "details pane hidden"
+			return nil
+		}
+		m.detailHidden = false
+	} else {
+		m.detailWidth = min(max(m.detailWidth+delta, uiDetailWidthMin), uiDetailWidthMax)
+	}
+	m.status = fmt.Sprintf("details pane %d%% wide", m.detailWidth)
+	m.syncTableLayout()
+	return m.savePrefs()
+}
+
+// toggleDetails hides or shows the details pane and saves the layout.
+func (m *uiModel) toggleDetails() tea.Cmd {
+	m.detailHidden = !m.detailHidden
+	if m.detailHidden {
+		m.status = "details pane hidden"
+	} else {
+		m.status = fmt.Sprintf("details pane %d%% wide", m.detailWidth)
+	}
+	m.syncTableLayout()
+	return m.savePrefs()
+}
+
+// savePrefs writes the pane layout for the next rift ui.
+func (m *uiModel) savePrefs() tea.Cmd {
+	path := m.app.uiPrefsPath()
+	prefs := uiprefs.Prefs{DetailWidth: m.detailWidth, HideDetails: m.detailHidden}
+	return func() tea.Msg {
+		if err := uiprefs.Save(path, prefs); err != nil {
+			return prefsSavedMsg{err: err}
+		}
+		return nil
+	}
+}
+
+// loadPrefs applies the saved pane layout, falling back to the default
+// width for a missing or out-of-range one.
+func (m *uiModel) loadPrefs() {
+	m.detailWidth = uiDetailWidth
+	prefs, err := uiprefs.Load(m.app.uiPrefsPath())
+	if err != nil {
+		return
+	}
+	if prefs.DetailWidth >= uiDetailWidthMin && prefs.DetailWidth <= uiDetailWidthMax {
+		m.detailWidth = prefs.DetailWidth
+	}
+	m.detailHidden = prefs.HideDetails
+}
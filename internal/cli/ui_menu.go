@@ -0,0 +1,177 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/phenixrizen/rift/internal/console"
+	"github.com/phenixrizen/rift/internal/kubeconfig"
+	"github.com/phenixrizen/rift/internal/plugin"
+	"github.com/phenixrizen/rift/internal/state"
+)
+
+// Action menu items.
+const (
+	menuUse         = "use"
+	menuK9s         = "k9s"
+	menuShell       = "shell"
+	menuCopyContext = "copy-context"
+	menuCopyProfile = "copy-profile"
+	menuCopyARN     = "copy-arn"
+	menuCopyCommand = "copy-command"
+	menuConsole     = "console"
+	menuKubeconfig  = "kubeconfig"
+	// menuPlugin prefixes the ui_plugins items, as plugin:<name>.
+	menuPlugin = "plugin:"
+)
+
+// actionMenu is the list of things to do with one context that the menu
+// key opens; it takes every key while open.
+type actionMenu struct {
+	rec    state.ClusterRecord
+	items  []menuItem
+	cursor int
+}
+
+type menuItem struct {
+	id    string
+	label string
+}
+
+// menuCopyKeys maps the menu's copy items to their copy target keys.
+var menuCopyKeys = map[string]string{
+	menuCopyContext: "c",
+	menuCopyProfile: "p",
+	menuCopyARN:     "a",
+	menuCopyCommand: "u",
+}
+
+// newActionMenu lists the actions that apply to rec: registrations with no
+// endpoint cannot be used, imported contexts get no shell or plugins, and
+// only AWS clusters have a console page. plugins are the ui_plugins names.
+func newActionMenu(rec state.ClusterRecord, plugins []string) *actionMenu {
+	var items []menuItem
+	if rec.Connectable() {
+		items = append(items,
+			menuItem{menuUse, "Use context"},
+			menuItem{menuK9s, "Launch k9s"},
+		)
+		if !rec.External() {
+			items = append(items, menuItem{menuShell, "Open a shell"})
+		}
+	}
+	items = append(items, menuItem{menuCopyContext, "Copy context name"})
+	if rec.AWSProfile != "" {
+		items = append(items, menuItem{menuCopyProfile, "Copy AWS profile"})
+	}
+	if rec.ClusterARN != "" {
+		items = append(items, menuItem{menuCopyARN, "Copy cluster ARN"})
+	}
+	if _, ok := updateKubeconfigCommand(rec); ok {
+		items = append(items, menuItem{menuCopyCommand, "Copy update-kubeconfig command"})
+	}
+	if _, ok := console.ClusterURL(rec); ok {
+		items = append(items, menuItem{menuConsole, "Open AWS console"})
+	}
+	if rec.Connectable() {
+		items = append(items, menuItem{menuKubeconfig, "Show kubeconfig snippet"})
+		if !rec.External() {
+			for _, name := range plugins {
+				items = append(items, menuItem{menuPlugin + name, "Run " + plugin.Prefix + name})
+			}
+		}
+	}
+	return &actionMenu{rec: rec, items: items}
+}
+
+// update handles a key; done reports that the menu should close, with the
+// chosen item's id ("" when cancelled). Digits pick an item directly.
+func (a *actionMenu) update(msg tea.KeyMsg) (choice string, done bool) {
+	switch key := msg.String(); key {
+	case "esc", "q":
+		return "", true
+	case "enter":
+		return a.items[a.cursor].id, true
+	case "up", "k":
+		if a.cursor > 0 {
+			a.cursor--
+		}
+	case "down", "j":
+		if a.cursor < len(a.items)-1 {
+			a.cursor++
+		}
+	default:
+		if len(key) == 1 && key[0] >= '1' && int(key[0]-'1') < len(a.items) {
+			return a.items[key[0]-'1'].id, true
+		}
+	}
+	return "", false
+}
+
+// view renders the menu as a bordered box at most width columns wide.
+func (a *actionMenu) view(width int, theme uiTheme) string {
+	contentWidth := width - 4
+	if contentWidth > 40 {
+		contentWidth = 40
+	}
+	if contentWidth < 10 {
+		contentWidth = 10
+	}
+	lines := []string{theme.accentStyle().Render(cutRunes(a.rec.KubeContext, contentWidth)), ""}
+	selected := theme.selectedStyle()
+	for i, item := range a.items {
+		text := padToWidth(cutRunes(fmt.Sprintf("%d  %s", i+1, item.label), contentWidth), contentWidth)
+		if i == a.cursor {
+			text = selected.Render(text)
+		}
+		lines = append(lines, text)
+	}
+	lines = append(lines, "", theme.mutedStyle().Render(cutRunes("enter/1-9 choose  esc cancel", contentWidth)))
+	return lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(theme.accent).
+		Padding(0, 1).
+		Render(strings.Join(lines, "\n"))
+}
+
+// runMenuAction does what the menu item id says for rec.
+func (m *uiModel) runMenuAction(id string, rec state.ClusterRecord) tea.Cmd {
+	switch id {
+	case menuUse:
+		return m.useContext(rec)
+	case menuK9s:
+		return m.pickK9sNamespace(rec)
+	case menuShell:
+		return m.openShell(rec)
+	case menuCopyContext, menuCopyProfile, menuCopyARN, menuCopyCommand:
+		what, text, _ := copyValue(rec, menuCopyKeys[id])
+		return m.copyText(what, text)
+	case menuConsole:
+		return m.openConsole(rec)
+	case menuKubeconfig:
+		snippet, err := kubeconfig.Standalone(rec, "")
+		if err != nil {
+			m.status = "kubeconfig failed: " + err.Error()
+			return nil
+		}
+		m.openModal("Kubeconfig: "+rec.KubeContext, strings.TrimSpace(string(snippet)), "", nil)
+	default:
+		if name, ok := strings.CutPrefix(id, menuPlugin); ok {
+			return m.runPluginAction(rec, name)
+		}
+	}
+	return nil
+}
+
+// openConsole starts signing in to the AWS console for rec's cluster page.
+func (m *uiModel) openConsole(rec state.ClusterRecord) tea.Cmd {
+	if _, ok := console.ClusterURL(rec); !ok {
+		m.status = rec.KubeContext + " has no AWS console page"
+		return nil
+	}
+	m.busy = true
+	m.busyText = "signing in to the AWS console..."
+	return tea.Batch(runUIConsoleCmd(m.app, m.state, rec), m.spin.Tick)
+}
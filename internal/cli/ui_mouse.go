@@ -0,0 +1,96 @@
+package cli
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// uiDoubleClick is the longest gap between two clicks on a row that
+// counts as a double-click.
+const uiDoubleClick = 400 * time.Millisecond
+
+// mouse handles a mouse event: the wheel scrolls the modal, the topology
+// tree, or the table, a click selects a table row, and a double-click
+// uses its context (or opens or closes a group header). Other overlays
+// ignore the mouse.
+func (m *uiModel) mouse(msg tea.MouseMsg) tea.Cmd {
+	if m.modalOn {
+		var cmd tea.Cmd
+		m.modalVP, cmd = m.modalVP.Update(msg)
+		return cmd
+	}
+	if m.confirm != nil || m.nsPick != nil || m.menu != nil || m.copying {
+		return nil
+	}
+	if msg.Action != tea.MouseActionPress {
+		return nil
+	}
+	switch msg.Button {
+	case tea.MouseButtonWheelUp:
+		m.scrollBy(-1)
+	case tea.MouseButtonWheelDown:
+		m.scrollBy(1)
+	case tea.MouseButtonLeft:
+		if m.graph != nil {
+			return nil
+		}
+		row := m.tableRowAt(msg.X, msg.Y)
+		if row < 0 {
+			return nil
+		}
+		m.table.SetCursor(row)
+		m.scrollTable()
+		if row != m.clickRow || time.Since(m.clickAt) > uiDoubleClick {
+			m.clickRow, m.clickAt = row, time.Now()
+			return nil
+		}
+		m.clickAt = time.Time{}
+		if m.toggleGroup() {
+			return nil
+		}
+		if rec := m.selected(); rec != nil {
+			return m.useContext(*rec)
+		}
+	}
+	return nil
+}
+
+// scrollBy moves the table's (or the topology tree's) cursor delta lines.
+func (m *uiModel) scrollBy(delta int) {
+	if m.graph != nil {
+		m.graph.move(delta)
+		return
+	}
+	if delta < 0 {
+		m.table.MoveUp(-delta)
+	} else {
+		m.table.MoveDown(delta)
+	}
+	m.scrollTable()
+}
+
+// tableRowAt is the index in m.rows of the table row drawn at screen
+// column x and line y, or -1 when none is: the table sits in the left pane
+// under the header (and the search box while open), below its border and
+// column titles.
+func (m uiModel) tableRowAt(x, y int) int {
+	outerWidth := m.table.Width() + 2
+	if x < 1 || x >= outerWidth-1 {
+		return -1
+	}
+	top := lipgloss.Height(m.topHeaderView())
+	if m.searchOn {
+		top += lipgloss.Height(m.searchBoxView(outerWidth))
+	}
+	line := y - top - 2
+	if line < 0 || line >= m.table.Height() {
+		return -1
+	}
+	row := m.tableTop + line
+	if row >= len(m.rows) {
+		return -1
+	}
+	return row
+}
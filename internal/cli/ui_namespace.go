@@ -0,0 +1,153 @@
+package cli
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/phenixrizen/rift/internal/state"
+)
+
+// nsPicker is the namespace chooser `k` opens before launching k9s: the
+// cluster's discovered namespaces filtered by what is typed, the typed name
+// itself when it is not one of them, and (with nothing typed) k9s's own
+// namespace list.
+type nsPicker struct {
+	rec    state.ClusterRecord
+	input  textinput.Model
+	all    []string
+	items  []nsItem
+	cursor int
+}
+
+type nsItem struct {
+	namespace string
+	label     string
+}
+
+const nsPickerRows = 12
+
+func newNSPicker(rec state.ClusterRecord) *nsPicker {
+	in := textinput.New()
+	in.Prompt = "namespace: "
+	in.Placeholder = "type to filter or enter a name"
+	in.CharLimit = 63
+	in.Focus()
+
+	all := slices.Clone(rec.Namespaces)
+	if ns := strings.TrimSpace(rec.Namespace); ns != "" && !slices.Contains(all, ns) {
+		all = append(all, ns)
+	}
+	slices.Sort(all)
+
+	p := &nsPicker{rec: rec, input: in, all: all}
+	p.refresh()
+	for i, item := range p.items {
+		if item.namespace == rec.Namespace && rec.Namespace != "" {
+			p.cursor = i
+		}
+	}
+	return p
+}
+
+// refresh rebuilds the items from the typed text.
+func (p *nsPicker) refresh() {
+	typed := strings.TrimSpace(p.input.Value())
+	p.items = p.items[:0]
+	if typed == "" {
+		p.items = append(p.items, nsItem{label: "all namespaces (k9s namespace list)"})
+	} else if !slices.Contains(p.all, typed) {
+		p.items = append(p.items, nsItem{namespace: typed, label: typed + " (typed)"})
+	}
+	for _, ns := range p.all {
+		if typed == "" || strings.Contains(strings.ToLower(ns), strings.ToLower(typed)) {
+			label := ns
+			if allowed, checked := p.rec.AccessTo(ns); checked && !allowed {
+				label += " (no access)"
+			}
+			p.items = append(p.items, nsItem{namespace: ns, label: label})
+		}
+	}
+	if p.cursor >= len(p.items) {
+		p.cursor = len(p.items) - 1
+	}
+	if p.cursor < 0 {
+		p.cursor = 0
+	}
+}
+
+// update handles a key; done reports that the picker should close, with
+// launch set when k9s should start in namespace ns ("" for the namespace
+// list).
+func (p *nsPicker) update(msg tea.KeyMsg) (ns string, launch, done bool, cmd tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		return "", false, true, nil
+	case "enter":
+		if len(p.items) == 0 {
+			return "", false, false, nil
+		}
+		return p.items[p.cursor].namespace, true, true, nil
+	case "up", "ctrl+p":
+		if p.cursor > 0 {
+			p.cursor--
+		}
+		return "", false, false, nil
+	case "down", "ctrl+n":
+		if p.cursor < len(p.items)-1 {
+			p.cursor++
+		}
+		return "", false, false, nil
+	}
+	before := p.input.Value()
+	p.input, cmd = p.input.Update(msg)
+	if p.input.Value() != before {
+		// Typing selects the first discovered match over the typed name.
+		p.cursor = 0
+		p.refresh()
+		if strings.TrimSpace(p.input.Value()) != "" && len(p.items) > 1 && !slices.Contains(p.all, p.items[0].namespace) {
+			p.cursor = 1
+		}
+	}
+	return "", false, false, cmd
+}
+
+// view renders the picker as a bordered box at most width columns wide,
+// cutting lines before the border is applied.
+func (p *nsPicker) view(width int, theme uiTheme) string {
+	contentWidth := width - 4
+	if contentWidth > 60 {
+		contentWidth = 60
+	}
+	if contentWidth < 10 {
+		contentWidth = 10
+	}
+	title := theme.accentStyle().Render(cutRunes("k9s: "+p.rec.KubeContext, contentWidth))
+	hint := theme.mutedStyle().Render(cutRunes("up/down choose  enter launch  esc cancel", contentWidth))
+	p.input.Width = contentWidth - lipgloss.Width(p.input.Prompt) - 1
+	lines := []string{title, padToWidth(cutRunes(p.input.View(), contentWidth), contentWidth), ""}
+
+	start := 0
+	if p.cursor >= nsPickerRows {
+		start = p.cursor - nsPickerRows + 1
+	}
+	selected := theme.selectedStyle()
+	for i := start; i < len(p.items) && i < start+nsPickerRows; i++ {
+		text := padToWidth(cutRunes(p.items[i].label, contentWidth), contentWidth)
+		if i == p.cursor {
+			text = selected.Render(text)
+		}
+		lines = append(lines, text)
+	}
+	if len(p.items) == 0 {
+		lines = append(lines, "(no namespaces)")
+	}
+	lines = append(lines, "", hint)
+	return lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(theme.accent).
+		Padding(0, 1).
+		Render(strings.Join(lines, "\n"))
+}
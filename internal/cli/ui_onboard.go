@@ -0,0 +1,284 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/phenixrizen/rift/internal/awsconfig"
+	"github.com/phenixrizen/rift/internal/config"
+	"github.com/phenixrizen/rift/internal/ssoauth"
+)
+
+type onboardStep int
+
+const (
+	onboardConfig onboardStep = iota
+	onboardAuth
+	onboardSync
+)
+
+// onboarding drives the first-run wizard shown by `rift ui` when there is no
+// config or no discovered clusters: init -> auth -> sync, all in the TUI.
+type onboarding struct {
+	step   onboardStep
+	inputs []textinput.Model
+	focus  int
+	note   string
+	err    string
+	// device is the pending SSO device code while waiting for approval.
+	device *ssoauth.Device
+}
+
+func newOnboarding(app *App, needConfig bool) *onboarding {
+	o := &onboarding{step: onboardAuth}
+	if !needConfig {
+		return o
+	}
+	o.step = onboardConfig
+
+	defaults := config.Default()
+	defaults.SSORegion = "us-east-1"
+	if path, err := defaultAWSConfigPath(); err == nil {
+		if imported, err := awsconfig.ImportSSO(path, ""); err == nil {
+			defaults.SSOStartURL = imported.SSOStartURL
+			defaults.SSORegion = imported.SSORegion
+			if len(imported.Regions) > 0 {
+				defaults.Regions = imported.Regions
+			}
+			o.note = fmt.Sprintf("Prefilled from [%s] in %s", imported.Source, path)
+		}
+	}
+
+	fields := []struct {
+		placeholder string
+		value       string
+	}{
+		{"https://my-org.awsapps.com/start", defaults.SSOStartURL},
+		{"us-east-1", defaults.SSORegion},
+		{"us-east-1,us-west-2", strings.Join(defaults.Regions, ",")},
+	}
+	for i, field := range fields {
+		in := textinput.New()
+		in.Prompt = ""
+		in.Placeholder = field.placeholder
+		in.CharLimit = 256
+		in.Width = 48
+		in.SetValue(field.value)
+		if i == 0 {
+			in.Focus()
+		}
+		o.inputs = append(o.inputs, in)
+	}
+	return o
+}
+
+func (o *onboarding) init(m uiModel) tea.Cmd {
+	if o.step == onboardConfig {
+		return textinput.Blink
+	}
+	return tea.Batch(runUIAuthCheckCmd(m.app), m.spin.Tick)
+}
+
+func (m uiModel) updateOnboarding(msg tea.Msg) (tea.Model, tea.Cmd) {
+	o := m.onboard
+	switch msg := msg.(type) {
+	case spinner.TickMsg:
+		if m.busy {
+			var cmd tea.Cmd
+			m.spin, cmd = m.spin.Update(msg)
+			return m, cmd
+		}
+		return m, nil
+	case authCheckDoneMsg:
+		switch {
+		case msg.err != nil:
+			return m.onboardFailed(msg.err.Error())
+		case msg.needsAuth:
+			m.busyText = "starting AWS SSO login..."
+			return m, runUIAuthCmd(m.app)
+		}
+		return m.onboardStartSync()
+	case authPromptMsg:
+		device := msg.device
+		o.device = &device
+		m.busyText = "waiting for approval in your browser..."
+		return m, waitForAuthCmd(msg.prompts, msg.done)
+	case authDoneMsg:
+		o.device = nil
+		if msg.err != nil {
+			return m.onboardFailed(strings.TrimSpace(msg.err.Error() + "\n" + msg.logs))
+		}
+		return m.onboardStartSync()
+	case syncProgressMsg:
+		m.busyText = progressText(msg.progress) + "..."
+		return m, waitForSyncCmd(msg.feed)
+	case syncLogMsg:
+		m.appendSyncLog(msg.line)
+		return m, waitForSyncCmd(msg.feed)
+	case syncDoneMsg:
+		if msg.err != nil {
+			return m.onboardFailed(strings.TrimSpace(msg.err.Error() + "\n" + msg.logs))
+		}
+		m.busy = false
+		m.busyText = ""
+		m.onboard = nil
+		m.state = msg.report.State
+		m.all = msg.report.State.Clusters
+		m.current = msg.current
+		m.applyFilter()
+		m.status = fmt.Sprintf("setup complete: %d contexts discovered", len(m.all))
+		return m, checkTokenCmd(m.app, 0, true)
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" || msg.String() == "esc" {
+			return m, tea.Quit
+		}
+		if o.step == onboardConfig {
+			return m.updateOnboardingForm(msg)
+		}
+		if m.busy {
+			return m, nil
+		}
+		switch msg.String() {
+		case "q":
+			return m, tea.Quit
+		case "r":
+			o.err = ""
+			m.busy = true
+			if o.step == onboardSync {
+				return m.onboardStartSync()
+			}
+			m.busyText = "checking AWS SSO login..."
+			return m, tea.Batch(runUIAuthCheckCmd(m.app), m.spin.Tick)
+		}
+	}
+	return m, nil
+}
+
+func (m uiModel) updateOnboardingForm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	o := m.onboard
+	switch msg.String() {
+	case "tab", "down":
+		o.focusInput(o.focus + 1)
+		return m, nil
+	case "shift+tab", "up":
+		o.focusInput(o.focus - 1)
+		return m, nil
+	case "enter":
+		if o.focus < len(o.inputs)-1 {
+			o.focusInput(o.focus + 1)
+			return m, nil
+		}
+		cfg := config.Default()
+		cfg.SSOStartURL = strings.TrimSpace(o.inputs[0].Value())
+		cfg.SSORegion = strings.TrimSpace(strings.ToLower(o.inputs[1].Value()))
+		cfg.Regions = strings.Split(o.inputs[2].Value(), ",")
+		cfg.Normalize()
+		if err := cfg.Validate(); err != nil {
+			o.err = err.Error()
+			return m, nil
+		}
+		if err := config.Save(m.app.ConfigPath, cfg); err != nil {
+			o.err = err.Error()
+			return m, nil
+		}
+		o.err = ""
+		o.step = onboardAuth
+		m.busy = true
+		m.busyText = "checking AWS SSO login..."
+		return m, tea.Batch(runUIAuthCheckCmd(m.app), m.spin.Tick)
+	}
+	var cmd tea.Cmd
+	o.inputs[o.focus], cmd = o.inputs[o.focus].Update(msg)
+	return m, cmd
+}
+
+func (o *onboarding) focusInput(idx int) {
+	if idx < 0 {
+		idx = len(o.inputs) - 1
+	}
+	if idx >= len(o.inputs) {
+		idx = 0
+	}
+	o.inputs[o.focus].Blur()
+	o.focus = idx
+	o.inputs[o.focus].Focus()
+}
+
+func (m uiModel) onboardStartSync() (tea.Model, tea.Cmd) {
+	m.onboard.step = onboardSync
+	m.busy = true
+	m.busyText = "discovering accounts, roles, and clusters..."
+	return m, tea.Batch(runUISyncCmd(m.app, false), m.spin.Tick)
+}
+
+func (m uiModel) onboardFailed(msg string) (tea.Model, tea.Cmd) {
+	m.busy = false
+	m.busyText = ""
+	m.onboard.err = msg
+	return m, nil
+}
+
+func (m uiModel) onboardingView(width, height int) string {
+	o := m.onboard
+	titleStyle := m.theme.accentStyle()
+	labelStyle := m.theme.mutedStyle()
+	doneStyle := lipgloss.NewStyle().Foreground(m.theme.status)
+	errStyle := lipgloss.NewStyle().Foreground(m.theme.err)
+
+	steps := []string{"Configure AWS SSO", "Sign in with AWS SSO", "Discover clusters"}
+	lines := []string{titleStyle.Render("Welcome to rift. Let's get you set up."), ""}
+	for i, name := range steps {
+		marker := labelStyle.Render("[ ]")
+		switch {
+		case onboardStep(i) < o.step:
+			marker = doneStyle.Render("[x]")
+		case onboardStep(i) == o.step:
+			marker = titleStyle.Render("[>]")
+		}
+		lines = append(lines, fmt.Sprintf("%s %d. %s", marker, i+1, name))
+	}
+	lines = append(lines, "")
+
+	switch {
+	case o.step == onboardConfig:
+		labels := []string{"SSO start URL", "SSO region", "EKS regions (comma-separated)"}
+		for i, in := range o.inputs {
+			lines = append(lines, labelStyle.Render(labels[i]), in.View(), "")
+		}
+		if o.note != "" {
+			lines = append(lines, labelStyle.Render(o.note), "")
+		}
+		lines = append(lines, labelStyle.Render("tab/up/down move  enter next/save  esc quit"))
+	case m.busy:
+		lines = append(lines, m.spin.View()+" "+m.busyText)
+		if o.step == onboardAuth && o.device != nil {
+			lines = append(lines, "", authPromptText(*o.device))
+		}
+	}
+	if o.err != "" {
+		lines = append(lines, "", errStyle.Render(o.err))
+		if o.step != onboardConfig {
+			lines = append(lines, "", labelStyle.Render("r retry  q quit"))
+		}
+	}
+
+	boxWidth := width - 4
+	if boxWidth > 72 {
+		boxWidth = 72
+	}
+	if boxWidth < 20 {
+		boxWidth = 20
+	}
+	box := lipgloss.NewStyle().
+		Border(lipgloss.NormalBorder()).
+		BorderForeground(m.theme.border).
+		Padding(1, 2).
+		Width(boxWidth).
+		Render(strings.Join(lines, "\n"))
+	screen := lipgloss.JoinVertical(lipgloss.Left, m.topHeaderView(), box)
+	return lipgloss.NewStyle().Width(width).MaxWidth(width).Height(height).MaxHeight(height).Render(screen)
+}
@@ -0,0 +1,143 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/phenixrizen/rift/internal/config"
+	"github.com/phenixrizen/rift/internal/state"
+)
+
+// contextPicker is the full-screen finder of `rift use` without a filter
+// (or with -i): the rift ui search box over its table, and nothing else.
+// It reuses uiModel for the matching, sorting, and drawing; typing always
+// goes to the search.
+type contextPicker struct {
+	ui     uiModel
+	picked *state.ClusterRecord
+}
+
+// newContextPicker lists recs with query already typed; recentOnly orders
+// them newest first, as the TUI's recent view does.
+func newContextPicker(app *App, st state.State, cfg config.Config, recs []state.ClusterRecord, query string, recentOnly bool) *contextPicker {
+	m := newUIModel(app, st, cfg)
+	m.all = recs
+	m.recentOnly = recentOnly
+	m.searchOn = true
+	m.search.SetValue(query)
+	m.search.Focus()
+	m.applyFilter()
+	return &contextPicker{ui: m}
+}
+
+func (p *contextPicker) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+func (p *contextPicker) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	m := &p.ui
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		p.layout()
+		return p, nil
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "ctrl+c", "esc":
+			return p, tea.Quit
+		case "enter":
+			if rec := m.selected(); rec != nil {
+				picked := *rec
+				p.picked = &picked
+				return p, tea.Quit
+			}
+			return p, nil
+		case "up", "ctrl+p":
+			m.scrollBy(-1)
+			return p, nil
+		case "down", "ctrl+n":
+			m.scrollBy(1)
+			return p, nil
+		case "pgup":
+			m.scrollBy(-m.table.Height())
+			return p, nil
+		case "pgdown":
+			m.scrollBy(m.table.Height())
+			return p, nil
+		}
+	}
+	before := m.search.Value()
+	var cmd tea.Cmd
+	m.search, cmd = m.search.Update(msg)
+	if m.search.Value() != before {
+		m.table.SetCursor(0)
+		m.applyFilter()
+	}
+	return p, cmd
+}
+
+// layout sizes the table to the space under the search box and above the
+// status line.
+func (p *contextPicker) layout() {
+	m := &p.ui
+	width, height := p.size()
+	rows := height - lipgloss.Height(m.searchBoxView(width)) - 1 - 3
+	m.table.SetHeight(max(rows, 1))
+	m.table.SetWidth(max(width-2, 1))
+	m.scrollTable()
+}
+
+func (p *contextPicker) size() (width, height int) {
+	width, height = p.ui.width, p.ui.height
+	if width <= 0 {
+		width = 130
+	}
+	if height <= 0 {
+		height = 40
+	}
+	return width, height
+}
+
+func (p *contextPicker) View() string {
+	m := p.ui
+	width, _ := p.size()
+	innerWidth := max(width-2, 1)
+	innerHeight := m.table.Height() + 1
+	table := lipgloss.NewStyle().
+		Width(innerWidth).
+		MaxWidth(innerWidth).
+		Height(innerHeight).
+		MaxHeight(innerHeight).
+		Render(m.tableView(innerWidth, innerHeight))
+	pane := lipgloss.NewStyle().
+		Border(lipgloss.NormalBorder()).
+		BorderForeground(m.theme.border).
+		Render(table)
+	status := fmt.Sprintf("%d/%d contexts", len(m.filtered), len(m.all))
+	hints := m.theme.mutedStyle().Render("  <up/down> move  <enter> use  <esc> cancel")
+	statusLine := ansi.Truncate(lipgloss.NewStyle().Foreground(m.theme.status).Render(status)+hints, width, "")
+	return lipgloss.JoinVertical(lipgloss.Left, m.searchBoxView(width), pane, statusLine)
+}
+
+// runContextPicker shows the picker on the terminal out and returns the
+// context chosen, or errSelectionCancelled.
+func runContextPicker(app *App, st state.State, recs []state.ClusterRecord, query string, recentOnly bool, out io.Writer) (state.ClusterRecord, error) {
+	// The picker only borrows the TUI's theme, columns, and sort, so a
+	// missing or broken config falls back to the defaults.
+	cfg, err := app.loadConfig()
+	if err != nil {
+		cfg = config.Default()
+	}
+	picker := newContextPicker(app, st, cfg, recs, query, recentOnly)
+	if _, err := tea.NewProgram(picker, tea.WithAltScreen(), tea.WithOutput(out)).Run(); err != nil {
+		return state.ClusterRecord{}, err
+	}
+	if picker.picked == nil {
+		return state.ClusterRecord{}, errSelectionCancelled
+	}
+	return *picker.picked, nil
+}
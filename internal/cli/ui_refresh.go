@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/phenixrizen/rift/internal/config"
+	"github.com/phenixrizen/rift/internal/state"
+)
+
+// autoRefreshMsg is a background reload of state.json from the
+// ui_auto_refresh ticker. drifted reports that a dry-run apply of the state
+// would change the AWS config or kubeconfig (ui_auto_refresh_drift only).
+type autoRefreshMsg struct {
+	state   state.State
+	err     error
+	current string
+	drifted bool
+}
+
+// autoRefreshCmd reloads state after every, and with drift also checks the
+// written config files against it. It touches no AWS API.
+func autoRefreshCmd(app *App, every time.Duration, drift bool) tea.Cmd {
+	return tea.Tick(every, func(time.Time) tea.Msg {
+		st, err := app.loadState()
+		if err != nil {
+			return autoRefreshMsg{err: err}
+		}
+		msg := autoRefreshMsg{state: st, current: app.currentContext()}
+		if drift {
+			cfg, err := app.loadConfig()
+			if err != nil {
+				return autoRefreshMsg{err: err}
+			}
+			report, err := app.applyState(cfg, "ui", st, st, true, false)
+			if err != nil {
+				return autoRefreshMsg{err: err}
+			}
+			msg.drifted = report.configChanged()
+		}
+		return msg
+	})
+}
+
+// applyAutoRefresh takes a background reload: the table only changes when
+// state.json was rewritten since it was loaded, keeping the selection, and
+// the status line reports drift once, when it first shows up.
+func (m *uiModel) applyAutoRefresh(msg autoRefreshMsg) tea.Cmd {
+	if msg.err != nil {
+		m.status = "auto-refresh failed: " + msg.err.Error()
+		return nil
+	}
+	var cmd tea.Cmd
+	m.current = msg.current
+	if !msg.state.GeneratedAt.Equal(m.state.GeneratedAt) {
+		selected := ""
+		if rec := m.selected(); rec != nil {
+			selected = rec.KubeContext
+		}
+		m.state = msg.state
+		m.all = msg.state.Clusters
+		m.applyFilter()
+		m.selectContext(selected)
+		cmd = m.notify(fmt.Sprintf("state changed on disk: reloaded %d contexts", len(m.all)))
+	}
+	if msg.drifted && !m.drifted {
+		m.status = "AWS config or kubeconfig no longer match state; press " + m.keys.key(config.ActionSync) + " to sync"
+	}
+	m.drifted = msg.drifted
+	return cmd
+}
+
+// driftView is the header's drift line, shown while the last drift check
+// found the config files out of date.
+func (m uiModel) driftView() string {
+	if !m.drifted {
+		return ""
+	}
+	warn := lipgloss.NewStyle().Foreground(m.theme.err).Bold(true).Render("drifted from state")
+	return lipgloss.NewStyle().Padding(0, 1).Render(m.theme.mutedStyle().Render("config: ") + warn + "  " + m.keys.hint(config.ActionSync) + " sync")
+}
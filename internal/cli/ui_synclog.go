@@ -0,0 +1,143 @@
+package cli
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/phenixrizen/rift/internal/config"
+)
+
+// The sync log pane: uiSyncLogRows lines show under the panes, the last
+// uiSyncLogKeep are kept, and up to uiSyncLogBuffer wait for the UI before
+// the stream drops lines (the pane is rebuilt from the full log when the
+// sync ends).
+const (
+	uiSyncLogRows   = 6
+	uiSyncLogKeep   = 500
+	uiSyncLogBuffer = 256
+)
+
+// syncLogMsg is one log line from a running sync.
+type syncLogMsg struct {
+	line string
+	feed syncFeed
+}
+
+// lineWriter sends each complete line written to it on lines, dropping it
+// when the channel is full rather than stalling the sync.
+type lineWriter struct {
+	lines   chan<- string
+	pending []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.pending = append(w.pending, p...)
+	for {
+		i := bytes.IndexByte(w.pending, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		line := string(w.pending[:i])
+		w.pending = w.pending[i+1:]
+		select {
+		case w.lines <- line:
+		default:
+		}
+	}
+}
+
+// dropLogTime leaves the time off sync log lines; the pane shows them as
+// they happen.
+func dropLogTime(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) == 0 && a.Key == slog.TimeKey {
+		return slog.Attr{}
+	}
+	return a
+}
+
+// startSyncLog clears and opens the log pane for a new sync.
+func (m *uiModel) startSyncLog() {
+	m.syncLog = m.syncLog[:0]
+	m.syncLogOn = true
+	m.syncTableLayout()
+}
+
+// appendSyncLog adds a line to the log pane, keeping the last
+// uiSyncLogKeep.
+func (m *uiModel) appendSyncLog(line string) {
+	m.syncLog = append(m.syncLog, line)
+	if len(m.syncLog) > uiSyncLogKeep {
+		m.syncLog = m.syncLog[len(m.syncLog)-uiSyncLogKeep:]
+	}
+}
+
+// finishSyncLog replaces the streamed lines with the sync's full log and
+// counts its warnings and errors. The pane stays open when there are any
+// and closes otherwise.
+func (m *uiModel) finishSyncLog(logs string) (warnings int) {
+	m.syncLog = m.syncLog[:0]
+	if logs != "" {
+		for _, line := range strings.Split(logs, "\n") {
+			m.appendSyncLog(line)
+		}
+	}
+	for _, line := range m.syncLog {
+		if strings.Contains(line, "level=WARN") || strings.Contains(line, "level=ERROR") {
+			warnings++
+		}
+	}
+	m.syncLogOn = warnings > 0
+	m.syncTableLayout()
+	return warnings
+}
+
+// toggleSyncLog shows or hides the last sync's log.
+func (m *uiModel) toggleSyncLog() {
+	if len(m.syncLog) == 0 {
+		m.status = "no sync log yet"
+		return
+	}
+	m.syncLogOn = !m.syncLogOn
+	m.syncTableLayout()
+}
+
+// syncLogView is the log pane: the last uiSyncLogRows lines, colored by
+// level, in a box width wide; empty while the pane is closed.
+func (m uiModel) syncLogView(width int) string {
+	if !m.syncLogOn {
+		return ""
+	}
+	innerWidth := max(width-4, 1)
+	lines := m.syncLog
+	if len(lines) > uiSyncLogRows {
+		lines = lines[len(lines)-uiSyncLogRows:]
+	}
+	rendered := make([]string, 0, uiSyncLogRows)
+	for _, line := range lines {
+		rendered = append(rendered, m.logLineStyle(line).Render(cutRunes(line, innerWidth)))
+	}
+	for len(rendered) < uiSyncLogRows {
+		rendered = append(rendered, "")
+	}
+	title := m.theme.accentStyle().Render("Sync log") + m.theme.mutedStyle().Render("  "+m.keys.hint(config.ActionLogs)+" hide")
+	return lipgloss.NewStyle().
+		Border(lipgloss.NormalBorder()).
+		BorderForeground(m.theme.border).
+		Padding(0, 1).
+		Width(max(width-2, 1)).
+		Render(title + "\n" + strings.Join(rendered, "\n"))
+}
+
+func (m uiModel) logLineStyle(line string) lipgloss.Style {
+	switch {
+	case strings.Contains(line, "level=ERROR"):
+		return lipgloss.NewStyle().Foreground(m.theme.err).Bold(true)
+	case strings.Contains(line, "level=WARN"):
+		return lipgloss.NewStyle().Foreground(m.theme.highlight)
+	case strings.Contains(line, "level=DEBUG"):
+		return m.theme.mutedStyle()
+	}
+	return lipgloss.NewStyle().Foreground(m.theme.status)
+}
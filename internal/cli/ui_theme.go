@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"github.com/charmbracelet/bubbles/table"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/phenixrizen/rift/internal/config"
+)
+
+// uiTheme is the rift ui palette: a config.Theme preset with the theme's
+// own colors laid over it. An empty color leaves the terminal's default.
+type uiTheme struct {
+	accent     lipgloss.Color
+	header     lipgloss.Color
+	selectedFG lipgloss.Color
+	selectedBG lipgloss.Color
+	border     lipgloss.Color
+	status     lipgloss.Color
+	muted      lipgloss.Color
+	highlight  lipgloss.Color
+	err        lipgloss.Color
+}
+
+// uiThemePresets are the palettes behind theme.preset. The light preset
+// uses dark text colors; high-contrast sticks to the brightest colors.
+var uiThemePresets = map[string]config.Theme{
+	config.ThemeDefault: {
+		Accent: "81", SelectedFG: "0", SelectedBG: "81", Status: "42",
+		Muted: "246", Highlight: "214", Error: "196",
+	},
+	config.ThemeHighContrast: {
+		Accent: "51", Header: "15", SelectedFG: "0", SelectedBG: "226", Border: "15",
+		Status: "46", Muted: "252", Highlight: "201", Error: "196",
+	},
+	config.ThemeLight: {
+		Accent: "25", Header: "236", SelectedFG: "15", SelectedBG: "25", Border: "245",
+		Status: "28", Muted: "240", Highlight: "166", Error: "160",
+	},
+}
+
+func newUITheme(t config.Theme) uiTheme {
+	preset, ok := uiThemePresets[t.Preset]
+	if !ok {
+		preset = uiThemePresets[config.ThemeDefault]
+	}
+	pick := func(override, base string) lipgloss.Color {
+		if override != "" {
+			return lipgloss.Color(override)
+		}
+		return lipgloss.Color(base)
+	}
+	return uiTheme{
+		accent:     pick(t.Accent, preset.Accent),
+		header:     pick(t.Header, preset.Header),
+		selectedFG: pick(t.SelectedFG, preset.SelectedFG),
+		selectedBG: pick(t.SelectedBG, preset.SelectedBG),
+		border:     pick(t.Border, preset.Border),
+		status:     pick(t.Status, preset.Status),
+		muted:      pick(t.Muted, preset.Muted),
+		highlight:  pick(t.Highlight, preset.Highlight),
+		err:        pick(t.Error, preset.Error),
+	}
+}
+
+func (t uiTheme) accentStyle() lipgloss.Style {
+	return lipgloss.NewStyle().Foreground(t.accent).Bold(true)
+}
+
+func (t uiTheme) mutedStyle() lipgloss.Style {
+	return lipgloss.NewStyle().Foreground(t.muted)
+}
+
+func (t uiTheme) selectedStyle() lipgloss.Style {
+	return lipgloss.NewStyle().Foreground(t.selectedFG).Background(t.selectedBG).Bold(true)
+}
+
+// tableStyles are the context table styles, used by table.Model for its
+// layout and by tableView for drawing.
+func (t uiTheme) tableStyles() table.Styles {
+	styles := table.DefaultStyles()
+	styles.Header = styles.Header.Foreground(t.header)
+	styles.Selected = styles.Selected.Foreground(t.selectedFG).Background(t.selectedBG).Bold(true)
+	return styles
+}
@@ -0,0 +1,96 @@
+package cli
+
+import (
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// Toasts are the short notices for things that finished fine (a context
+// switch, a copy, a reload); errors stay in the status line and sync
+// reports in the modal. uiToastTTL is how long each stays up, and at most
+// uiToastMax show at once, the oldest dropped first.
+const (
+	uiToastTTL   = 4 * time.Second
+	uiToastMax   = 3
+	uiToastWidth = 44
+)
+
+type uiToast struct {
+	id   int
+	text string
+}
+
+// toastExpiredMsg dismisses the toast with id.
+type toastExpiredMsg struct{ id int }
+
+// notify shows text as a toast and clears the status line, which would
+// otherwise keep saying what was just started ("switching context...").
+func (m *uiModel) notify(text string) tea.Cmd {
+	m.toastSeq++
+	id := m.toastSeq
+	m.toasts = append(m.toasts, uiToast{id: id, text: text})
+	if len(m.toasts) > uiToastMax {
+		m.toasts = m.toasts[len(m.toasts)-uiToastMax:]
+	}
+	m.status = ""
+	return tea.Tick(uiToastTTL, func(time.Time) tea.Msg { return toastExpiredMsg{id: id} })
+}
+
+// dismissToast removes the toast with id, if it is still shown.
+func (m *uiModel) dismissToast(id int) {
+	for i, t := range m.toasts {
+		if t.id == id {
+			m.toasts = append(m.toasts[:i:i], m.toasts[i+1:]...)
+			return
+		}
+	}
+}
+
+// toastsView stacks the toasts, newest last, as boxes at most width wide.
+func (m uiModel) toastsView(width int) string {
+	if len(m.toasts) == 0 {
+		return ""
+	}
+	if width > uiToastWidth {
+		width = uiToastWidth
+	}
+	style := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(m.theme.accent).
+		Foreground(m.theme.status).
+		Padding(0, 1)
+	boxes := make([]string, 0, len(m.toasts))
+	for _, t := range m.toasts {
+		boxes = append(boxes, style.Render(cutRunes(t.text, width-4)))
+	}
+	return lipgloss.JoinVertical(lipgloss.Right, boxes...)
+}
+
+// overlayBottomRight draws box over base with its right edge before column
+// right and its last line on row bottom; base shows on either side.
+func overlayBottomRight(base, box string, right, bottom int) string {
+	if box == "" {
+		return base
+	}
+	lines := strings.Split(base, "\n")
+	boxLines := strings.Split(box, "\n")
+	top := bottom - len(boxLines) + 1
+	for i, boxLine := range boxLines {
+		// JoinVertical pads narrower boxes on the left; keep base there.
+		boxLine = strings.TrimLeft(boxLine, " ")
+		row := top + i
+		if row < 0 || row >= len(lines) {
+			continue
+		}
+		left := right - lipgloss.Width(boxLine)
+		if left < 0 {
+			left = 0
+		}
+		lines[row] = padToWidth(ansi.Truncate(lines[row], left, ""), left) + boxLine + ansi.TruncateLeft(lines[row], left+lipgloss.Width(boxLine), "")
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,124 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/phenixrizen/rift/internal/config"
+	"github.com/phenixrizen/rift/internal/discovery"
+)
+
+// uiTokenCheckEvery is how often rift ui re-reads the SSO token cache for
+// the header countdown, so a login from another terminal shows up too.
+const uiTokenCheckEvery = 30 * time.Second
+
+// uiToken is the SSO token that expires first across the configured
+// sessions. expiresAt is zero when that session has no cached token.
+type uiToken struct {
+	session   string
+	expiresAt time.Time
+	// warning is the config's sso_expiry_warning; named is set when the
+	// config has more than one session, so the header names this one.
+	warning time.Duration
+	named   bool
+}
+
+// tokenCheckMsg reports the token cache; tick marks the periodic checks,
+// which schedule the next one.
+type tokenCheckMsg struct {
+	token *uiToken
+	tick  bool
+}
+
+// checkTokenCmd reads the token cache after delay.
+func checkTokenCmd(app *App, delay time.Duration, tick bool) tea.Cmd {
+	return tea.Tick(delay, func(time.Time) tea.Msg {
+		cfg, err := app.loadConfig()
+		if err != nil {
+			return tokenCheckMsg{tick: tick}
+		}
+		return tokenCheckMsg{token: soonestToken(cfg), tick: tick}
+	})
+}
+
+// soonestToken returns the configured session whose token expires first,
+// one with no token counting as expired, or nil when no session is set up.
+func soonestToken(cfg config.Config) *uiToken {
+	var soonest *uiToken
+	for _, session := range cfg.Sessions() {
+		if session.StartURL == "" {
+			continue
+		}
+		t := &uiToken{session: session.ID(), warning: cfg.ExpiryWarning(), named: len(cfg.SSOSessions) > 0}
+		tok, err := discovery.LastSSOToken(session)
+		switch {
+		case errors.Is(err, discovery.ErrSSONotLoggedIn):
+		case err != nil:
+			continue
+		default:
+			t.expiresAt = tok.ExpiresAt
+		}
+		if soonest == nil || t.expiresAt.Before(soonest.expiresAt) {
+			soonest = t
+		}
+	}
+	return soonest
+}
+
+// expiring reports whether the token is within its warning window or
+// already expired.
+func (t uiToken) expiring(now time.Time) bool {
+	return t.expiresAt.Sub(now) < t.warning
+}
+
+// remaining is the countdown text, as "1h05m left" or "expired".
+func (t uiToken) remaining(now time.Time) string {
+	left := t.expiresAt.Sub(now)
+	switch {
+	case t.expiresAt.IsZero():
+		return "not logged in"
+	case left <= 0:
+		return "expired"
+	case left < time.Minute:
+		return "<1m left"
+	case left < time.Hour:
+		return fmt.Sprintf("%dm left", int(left.Minutes()))
+	}
+	return fmt.Sprintf("%dh%02dm left", int(left.Hours()), int(left.Minutes())%60)
+}
+
+// tokenView is the header's SSO line: the countdown, turned into a warning
+// with the re-auth key near expiry.
+func (m uiModel) tokenView(now time.Time) string {
+	if m.token == nil {
+		return ""
+	}
+	label := "sso: "
+	if m.token.named {
+		label = "sso (" + m.token.session + "): "
+	}
+	style := lipgloss.NewStyle().Foreground(m.theme.status).Bold(true)
+	text := m.token.remaining(now)
+	if m.token.expiring(now) {
+		style = lipgloss.NewStyle().Foreground(m.theme.err).Bold(true)
+		text += "  " + m.keys.hint(config.ActionAuth) + " re-auth"
+	}
+	return lipgloss.NewStyle().Padding(0, 1).Render(m.theme.mutedStyle().Render(label) + style.Render(text))
+}
+
+// applyToken stores a token check and warns once in the status line when
+// the token enters its warning window.
+func (m *uiModel) applyToken(token *uiToken, now time.Time) {
+	m.token = token
+	if token == nil || !token.expiring(now) {
+		m.tokenWarned = false
+		return
+	}
+	if !m.tokenWarned && !m.busy {
+		m.tokenWarned = true
+		m.status = "SSO session " + token.session + ": " + token.remaining(now) + "; press " + m.keys.key(config.ActionAuth) + " to log in again"
+	}
+}
@@ -2,17 +2,23 @@ package cli
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"os"
 	"os/exec"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/lithammer/fuzzysearch/fuzzy"
+	"github.com/phenixrizen/rift/internal/kubeconfig"
+	"github.com/phenixrizen/rift/internal/recent"
 	"github.com/phenixrizen/rift/internal/state"
 	"github.com/spf13/cobra"
 )
@@ -20,16 +26,74 @@ import (
 var errSelectionCancelled = errors.New("selection cancelled")
 
 func newUseCmd(app *App) *cobra.Command {
+	var tags []string
+	var namespace, shell, hook string
+	var recentOnly, interactive, local bool
 	cmd := &cobra.Command{
-		Use:   "use <filter>",
+		Use:   "use [filter]",
 		Short: "Fuzzy-match and switch kubectl context",
-		Args:  cobra.ExactArgs(1),
+		Long: `Use switches kubectl to the context matching filter. Without a filter,
+or with -i, it opens a full-screen finder over the contexts (the rift ui
+table and search, filter already typed) and switches to the one picked.
+"rift use -" switches back to the context used before the current one, as
+cd - does.
+
+With -n, the context's default namespace is set in kubeconfig as well (as
+kubectl config set-context --namespace does, so sync keeps it). When the
+last sync recorded the cluster's namespaces, the namespace must be one of
+them.
+
+With --local, only the calling shell switches: rift writes the context to a
+kubeconfig of the shell's own and prints a KUBECONFIG export listing it
+first, so two terminals can use different clusters. Evaluate the output,
+or add the wrapper --hook prints to your shell profile to have rift use
+--local do it:
+
+  eval "$(rift use --hook bash)"                     # ~/.bashrc, ~/.zshrc
+  rift use --hook fish | source                      # config.fish
+  rift use --hook powershell | Out-String | Invoke-Expression`,
+		Example: `  rift use payments
+  rift use                   # pick in the full-screen finder
+  rift use -i pay            # the finder, starting from "pay"
+  rift use prod-payments -n kafka
+  rift use -                 # back to the previous context
+  rift use --recent          # pick from recently used contexts
+  rift use --recent pay
+  eval "$(rift use --local payments)"`,
+		Args:              cobra.MaximumNArgs(1),
+		ValidArgsFunction: completeContexts(app),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			filter := args[0]
+			for _, s := range []string{shell, hook} {
+				switch s {
+				case "", "bash", "zsh", "fish", "powershell":
+				default:
+					return fmt.Errorf("--shell and --hook must be one of bash|zsh|fish|powershell")
+				}
+			}
+			if hook != "" {
+				printUseHook(cmd.OutOrStdout(), hook)
+				return nil
+			}
+			// With --local stdout is eval'd, so pickers and messages go to
+			// stderr.
+			prompt := cmd.OutOrStdout()
+			switchTo := func(rec state.ClusterRecord) error {
+				return switchContext(cmd, app, rec, namespace)
+			}
+			if local {
+				prompt = cmd.ErrOrStderr()
+				switchTo = func(rec state.ClusterRecord) error {
+					return switchLocal(cmd, app, rec, namespace, shell)
+				}
+			}
+			filter := ""
+			if len(args) > 0 {
+				filter = args[0]
+			}
 			st, err := app.loadState()
 			if err != nil {
 				if errors.Is(err, os.ErrNotExist) {
-					return fmt.Errorf("state file not found; run: rift sync")
+					return errStateNotFound
 				}
 				return err
 			}
@@ -38,45 +102,176 @@ func newUseCmd(app *App) *cobra.Command {
 			}
 
 			contexts := make([]string, 0, len(st.Clusters))
-			seen := map[string]struct{}{}
+			records := make([]state.ClusterRecord, 0, len(st.Clusters))
 			contextMeta := map[string]state.ClusterRecord{}
-			for _, c := range st.Clusters {
-				if _, ok := seen[c.KubeContext]; ok {
+			for _, c := range filterByTags(st.Clusters, tags) {
+				if _, ok := contextMeta[c.KubeContext]; ok {
 					continue
 				}
-				seen[c.KubeContext] = struct{}{}
 				contexts = append(contexts, c.KubeContext)
+				records = append(records, c)
 				contextMeta[c.KubeContext] = c
 			}
-			ranks := fuzzy.RankFindNormalizedFold(filter, contexts)
-			if len(ranks) == 0 {
-				return fmt.Errorf("no context matches %q", filter)
+			if filter == "-" {
+				rec, err := app.previousContext(contextMeta)
+				if err != nil {
+					return err
+				}
+				return switchTo(rec)
+			}
+			if recentOnly {
+				if contexts, err = app.recentContexts(contexts); err != nil {
+					return err
+				}
+				if len(contexts) == 0 {
+					return fmt.Errorf("no recently used contexts; switch with rift use <filter> or rift ui first")
+				}
+			}
+			if interactive || (filter == "" && !recentOnly) {
+				if out, ok := prompt.(*os.File); !isTerminal(os.Stdin) || !ok || !isTerminal(out) {
+					if interactive {
+						return fmt.Errorf("--interactive needs a terminal")
+					}
+					return fmt.Errorf("requires a filter argument (or --recent) when not run in a terminal")
+				}
+				if len(contexts) == 0 {
+					return fmt.Errorf("no contexts match the tags")
+				}
+				// contexts is down to the recent ones with --recent.
+				records = slices.DeleteFunc(records, func(rec state.ClusterRecord) bool {
+					return !slices.Contains(contexts, rec.KubeContext)
+				})
+				rec, err := runContextPicker(app, st, records, filter, recentOnly, prompt)
+				if err != nil {
+					if errors.Is(err, errSelectionCancelled) {
+						fmt.Fprintln(prompt, "Selection cancelled.")
+						return nil
+					}
+					return err
+				}
+				return switchTo(rec)
+			}
+			var ranks fuzzy.Ranks
+			if filter == "" {
+				// --recent alone lists the contexts newest first.
+				for i, c := range contexts {
+					ranks = append(ranks, fuzzy.Rank{Target: c, OriginalIndex: i})
+				}
+			} else {
+				ranks = fuzzy.RankFindNormalizedFold(filter, contexts)
+				if len(ranks) == 0 {
+					return fmt.Errorf("no context matches %q", filter)
+				}
+				sort.Sort(ranks)
 			}
-			sort.Sort(ranks)
 
-			selected, err := pickContext(cmd, filter, ranks, contextMeta)
+			selected, err := pickContext(prompt, cmd.InOrStdin(), filter, ranks, contextMeta)
 			if err != nil {
 				if errors.Is(err, errSelectionCancelled) {
-					fmt.Fprintln(cmd.OutOrStdout(), "Selection cancelled.")
+					fmt.Fprintln(prompt, "Selection cancelled.")
 					return nil
 				}
 				return err
 			}
 
-			run := exec.CommandContext(context.Background(), "kubectl", "config", "use-context", selected)
-			run.Stdout = cmd.OutOrStdout()
-			run.Stderr = cmd.ErrOrStderr()
-			if err := run.Run(); err != nil {
-				return err
-			}
-			fmt.Fprintf(cmd.OutOrStdout(), "Switched context: %s\n", selected)
-			return nil
+			return switchTo(contextMeta[selected])
 		},
 	}
+	cmd.Flags().StringSliceVar(&tags, "tag", nil, "Only consider contexts with these tags (repeatable)")
+	cmd.Flags().BoolVar(&recentOnly, "recent", false, "Only consider recently used contexts, newest first (the filter is optional)")
+	cmd.Flags().BoolVarP(&interactive, "interactive", "i", false, "Pick in the full-screen finder even with a filter")
+	cmd.Flags().StringVarP(&namespace, "namespace", "n", "", "Also set the context's default namespace")
+	cmd.Flags().BoolVar(&local, "local", false, "Switch only this shell: print a KUBECONFIG export for a kubeconfig of its own")
+	cmd.Flags().StringVar(&shell, "shell", "bash", "Export syntax for --local: bash|zsh|fish|powershell")
+	cmd.Flags().StringVar(&hook, "hook", "", "Print a rift wrapper for your shell profile that applies --local: bash|zsh|fish|powershell")
+	cmd.MarkFlagsMutuallyExclusive("local", "hook")
+	shells := cobra.FixedCompletions([]string{"bash", "zsh", "fish", "powershell"}, cobra.ShellCompDirectiveNoFileComp)
+	_ = cmd.RegisterFlagCompletionFunc("tag", completeTags(app))
+	_ = cmd.RegisterFlagCompletionFunc("namespace", completeNamespaces(app))
+	_ = cmd.RegisterFlagCompletionFunc("shell", shells)
+	_ = cmd.RegisterFlagCompletionFunc("hook", shells)
 	return cmd
 }
 
-func pickContext(cmd *cobra.Command, filter string, ranks fuzzy.Ranks, contextMeta map[string]state.ClusterRecord) (string, error) {
+// switchContext points kubectl at rec's context, first setting its
+// default namespace when one is given, records it as recent, and runs the
+// post_use hook.
+func switchContext(cmd *cobra.Command, app *App, rec state.ClusterRecord, namespace string) error {
+	if !rec.Connectable() {
+		return fmt.Errorf("%s is an %s registration with no API endpoint; use the cluster's own kubeconfig", rec.KubeContext, rec.PlatformLabel())
+	}
+	if namespace != "" {
+		if err := checkNamespace(rec, namespace); err != nil {
+			return err
+		}
+		if err := runKubectl(cmd, app, "config", "set-context", rec.KubeContext, "--namespace="+namespace); err != nil {
+			return err
+		}
+	}
+	if err := runKubectl(cmd, app, "config", "use-context", rec.KubeContext); err != nil {
+		return err
+	}
+	if namespace != "" {
+		fmt.Fprintf(cmd.OutOrStdout(), "Switched context: %s (namespace %s)\n", rec.KubeContext, namespace)
+	} else {
+		fmt.Fprintf(cmd.OutOrStdout(), "Switched context: %s\n", rec.KubeContext)
+	}
+	app.recordRecent(rec.KubeContext)
+	app.postUse(rec, namespace, false)
+	return nil
+}
+
+// switchContextQuietly is switchContext for servers whose stdout is not a
+// terminal: kubectl's output is returned, or folded into the error, rather
+// than printed.
+func switchContextQuietly(app *App, rec state.ClusterRecord, namespace string) (string, error) {
+	var out bytes.Buffer
+	sub := &cobra.Command{}
+	sub.SetOut(&out)
+	sub.SetErr(&out)
+	if err := switchContext(sub, app, rec, namespace); err != nil {
+		if msg := strings.TrimSpace(out.String()); msg != "" {
+			return "", fmt.Errorf("%w: %s", err, msg)
+		}
+		return "", err
+	}
+	return strings.TrimSpace(out.String()), nil
+}
+
+// checkNamespace rejects a namespace the last sync did not find in rec's
+// cluster. Without recorded namespaces any name goes.
+func checkNamespace(rec state.ClusterRecord, namespace string) error {
+	if len(rec.Namespaces) == 0 || namespace == rec.Namespace || slices.Contains(rec.Namespaces, namespace) {
+		return nil
+	}
+	known := rec.Namespaces
+	if len(known) > checkNamespaceMax {
+		known = append(known[:checkNamespaceMax:checkNamespaceMax], "...")
+	}
+	return fmt.Errorf("namespace %q is not among the %d found in %s at the last sync (%s)", namespace, len(rec.Namespaces), rec.KubeContext, strings.Join(known, ", "))
+}
+
+// checkNamespaceMax caps the namespaces checkNamespace lists.
+const checkNamespaceMax = 10
+
+// runKubectl runs kubectl with args against the kubeconfig rift targets.
+func runKubectl(cmd *cobra.Command, app *App, args ...string) error {
+	run := exec.CommandContext(context.Background(), "kubectl", append(app.kubeconfigArgs(), args...)...)
+	run.Stdout = cmd.OutOrStdout()
+	run.Stderr = cmd.ErrOrStderr()
+	return run.Run()
+}
+
+func pickContext(out io.Writer, in io.Reader, filter string, ranks fuzzy.Ranks, contextMeta map[string]state.ClusterRecord) (string, error) {
+	return pickTarget(out, in, "contexts", filter, ranks, func(target string) string {
+		rec := contextMeta[target]
+		return fmt.Sprintf("%s | %s | %s | %s", rec.Env, rec.AccountLabel(), rec.RoleName, rec.ClusterName)
+	})
+}
+
+// pickTarget resolves ranks to a single target: a lone or exact match wins,
+// otherwise the user picks from a numbered list written to out.
+func pickTarget(out io.Writer, in io.Reader, noun, filter string, ranks fuzzy.Ranks, describe func(string) string) (string, error) {
 	if len(ranks) == 1 {
 		return ranks[0].Target, nil
 	}
@@ -92,28 +287,21 @@ func pickContext(cmd *cobra.Command, filter string, ranks fuzzy.Ranks, contextMe
 		limit = maxOptions
 	}
 
-	out := cmd.OutOrStdout()
-	fmt.Fprintf(out, "Multiple contexts match %q:\n", filter)
+	if filter == "" {
+		fmt.Fprintf(out, "Choose from %d %s:\n", len(ranks), noun)
+	} else {
+		fmt.Fprintf(out, "Multiple %s match %q:\n", noun, filter)
+	}
 	for i := 0; i < limit; i++ {
 		target := ranks[i].Target
-		rec := contextMeta[target]
-		fmt.Fprintf(
-			out,
-			"  %2d) %s  [%s | %s | %s | %s]\n",
-			i+1,
-			target,
-			rec.Env,
-			rec.AccountName,
-			rec.RoleName,
-			rec.ClusterName,
-		)
+		fmt.Fprintf(out, "  %2d) %s  [%s]\n", i+1, target, describe(target))
 	}
 	if len(ranks) > limit {
 		fmt.Fprintf(out, "  ...and %d more matches\n", len(ranks)-limit)
 	}
 	fmt.Fprint(out, "Select a number (Enter/q to cancel): ")
 
-	reader := bufio.NewReader(cmd.InOrStdin())
+	reader := bufio.NewReader(in)
 	line, err := reader.ReadString('\n')
 	if err != nil && !errors.Is(err, io.EOF) {
 		return "", err
@@ -132,3 +320,68 @@ func pickContext(cmd *cobra.Command, filter string, ranks fuzzy.Ranks, contextMe
 	}
 	return ranks[choice-1].Target, nil
 }
+
+func (a *App) recentPath() string {
+	return recent.PathFor(a.StatePath)
+}
+
+// recentContexts returns the recently used contexts among known, newest
+// first.
+func (a *App) recentContexts(known []string) ([]string, error) {
+	entries, err := recent.Load(a.recentPath())
+	if err != nil {
+		return nil, fmt.Errorf("load recent contexts %s: %w", a.recentPath(), err)
+	}
+	out := make([]string, 0, len(entries))
+	for _, context := range recent.Contexts(entries) {
+		if slices.Contains(known, context) {
+			out = append(out, context)
+		}
+	}
+	return out, nil
+}
+
+// previousContext is the context to go back to with rift use -: the newest
+// recent context among known that is not kubectl's current one.
+func (a *App) previousContext(known map[string]state.ClusterRecord) (state.ClusterRecord, error) {
+	contexts, err := a.recentContexts(slices.Collect(maps.Keys(known)))
+	if err != nil {
+		return state.ClusterRecord{}, err
+	}
+	previous := recent.Previous(contexts, a.currentContext())
+	if previous == "" {
+		return state.ClusterRecord{}, fmt.Errorf("no previous context; switch with rift use <filter> or rift ui first")
+	}
+	return known[previous], nil
+}
+
+// currentContext is the current-context of the kubeconfig kubectl reads
+// when rift runs it (see kubeconfigArgs), or "" when there is none or it
+// cannot be read.
+func (a *App) currentContext() string {
+	current, err := kubeconfig.CurrentContext(a.kubectlKubeconfig())
+	if err != nil {
+		if a.Logger != nil {
+			a.Logger.Debug("unable to read current context", "error", err)
+		}
+		return ""
+	}
+	return current
+}
+
+// kubectlKubeconfig is the kubeconfig kubeconfigArgs passes kubectl, or ""
+// when kubectl reads KUBECONFIG or ~/.kube/config itself.
+func (a *App) kubectlKubeconfig() string {
+	if primary, explicit, err := a.primaryKubeConfig(); err == nil && explicit {
+		return primary
+	}
+	return ""
+}
+
+// recordRecent adds context to the recent list after a switch. A failure
+// only logs: the switch itself worked.
+func (a *App) recordRecent(context string) {
+	if err := recent.Record(a.recentPath(), context, time.Now()); err != nil && a.Logger != nil {
+		a.Logger.Warn("unable to record recent context", "context", context, "error", err)
+	}
+}
@@ -0,0 +1,149 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/phenixrizen/rift/internal/kubeconfig"
+	"github.com/phenixrizen/rift/internal/state"
+	"github.com/spf13/cobra"
+)
+
+// sessionMaxAge is how long a rift use --local kubeconfig may sit unused
+// before a later --local removes it; its shell is most likely gone.
+const sessionMaxAge = 30 * 24 * time.Hour
+
+func (a *App) sessionDir() string {
+	return filepath.Join(filepath.Dir(a.StatePath), "sessions")
+}
+
+// switchLocal points only the calling shell at rec: it writes rec's
+// standalone kubeconfig to the shell's session file (reusing the one its
+// KUBECONFIG already lists) and prints the exports that make the shell use
+// it ahead of the kubeconfigs it had.
+func switchLocal(cmd *cobra.Command, app *App, rec state.ClusterRecord, namespace, shell string) error {
+	cfg, err := app.loadConfig()
+	if err != nil {
+		return err
+	}
+	if err := checkPluginCluster(cfg, rec); err != nil {
+		return err
+	}
+	if !rec.Connectable() {
+		return fmt.Errorf("%s is an %s registration with no API endpoint; use the cluster's own kubeconfig", rec.KubeContext, rec.PlatformLabel())
+	}
+	if rec.External() {
+		return fmt.Errorf("%s was imported from your kubeconfig; --local only works for contexts rift writes", rec.KubeContext)
+	}
+	if namespace != "" {
+		if err := checkNamespace(rec, namespace); err != nil {
+			return err
+		}
+	}
+	session, rest := splitKubeconfigEnv(os.Getenv("KUBECONFIG"))
+	if session == "" {
+		var err error
+		if session, err = app.newSession(); err != nil {
+			return err
+		}
+	}
+	if err := kubeconfig.WriteStandalone(session, rec, namespace); err != nil {
+		return fmt.Errorf("write session kubeconfig: %w", err)
+	}
+	if len(rest) == 0 {
+		path, err := defaultKubeConfigPath()
+		if err != nil {
+			return err
+		}
+		rest = []string{path}
+	}
+	printExports(cmd.OutOrStdout(), shell, []envVar{
+		{Name: "KUBECONFIG", Value: strings.Join(append([]string{session}, rest...), string(os.PathListSeparator))},
+		{Name: "RIFT_CONTEXT", Value: rec.KubeContext},
+	})
+	if namespace != "" {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Switched context in this shell: %s (namespace %s)\n", rec.KubeContext, namespace)
+	} else {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Switched context in this shell: %s\n", rec.KubeContext)
+	}
+	app.recordRecent(rec.KubeContext)
+	app.postUse(rec, namespace, true)
+	return nil
+}
+
+// splitKubeconfigEnv separates a KUBECONFIG value into the session
+// kubeconfig it lists (if any) and the other files.
+func splitKubeconfigEnv(env string) (session string, rest []string) {
+	for _, part := range strings.Split(env, string(os.PathListSeparator)) {
+		switch part = strings.TrimSpace(part); {
+		case part == "":
+		case kubeconfig.IsSession(part) && session == "":
+			session = part
+		case !kubeconfig.IsSession(part):
+			rest = append(rest, part)
+		}
+	}
+	return session, rest
+}
+
+// newSession creates an empty session kubeconfig, first removing those
+// unused for sessionMaxAge.
+func (a *App) newSession() (string, error) {
+	dir := a.sessionDir()
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+	if entries, err := os.ReadDir(dir); err == nil {
+		for _, entry := range entries {
+			info, err := entry.Info()
+			if err == nil && kubeconfig.IsSession(entry.Name()) && time.Since(info.ModTime()) > sessionMaxAge {
+				_ = os.Remove(filepath.Join(dir, entry.Name()))
+			}
+		}
+	}
+	f, err := os.CreateTemp(dir, kubeconfig.SessionPrefix+"*.yaml")
+	if err != nil {
+		return "", err
+	}
+	return f.Name(), f.Close()
+}
+
+// printUseHook prints a rift wrapper function for shell that evaluates the
+// output of rift use --local, so it changes the calling shell.
+func printUseHook(out io.Writer, shell string) {
+	switch shell {
+	case "fish":
+		fmt.Fprint(out, `function rift
+    if test "$argv[1]" = use; and contains -- --local $argv
+        command rift $argv --shell fish | source
+    else
+        command rift $argv
+    end
+end
+`)
+	case "powershell":
+		fmt.Fprint(out, `function rift {
+    $rift = Get-Command rift -CommandType Application | Select-Object -First 1
+    if ($args[0] -eq 'use' -and $args -contains '--local') {
+        & $rift @args --shell powershell | Out-String | Invoke-Expression
+    } else {
+        & $rift @args
+    }
+}
+`)
+	default:
+		fmt.Fprint(out, `rift() {
+  if [ "$1" = use ]; then
+    case " $* " in
+      *" --local "*) eval "$(command rift "$@")"; return ;;
+    esac
+  fi
+  command rift "$@"
+}
+`)
+	}
+}
@@ -0,0 +1,164 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/phenixrizen/rift/internal/health"
+	"github.com/phenixrizen/rift/internal/rifterr"
+	"github.com/phenixrizen/rift/internal/state"
+	"github.com/spf13/cobra"
+)
+
+func newVerifyCmd(app *App) *cobra.Command {
+	var envs []string
+	var tags []string
+	var concurrency int
+	var timeout time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "verify [filter]",
+		Short: "Authenticate against every context and report which ones work",
+		Long: `Fetches a token for each rift context, calls /version and lists one
+namespace, then reports each context as:
+
+  ok            token issued and the API server accepted it
+  forbidden     authenticated, but RBAC denied the namespace list
+  unauthorized  the API server rejected the token (role not mapped)
+  reauth        no token could be issued; run: rift auth
+  dead          endpoint unreachable or another failure
+
+Exits 1 when any verified context is not ok.`,
+		Args:              cobra.MaximumNArgs(1),
+		ValidArgsFunction: completeContexts(app),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			st, err := app.loadState()
+			if err != nil {
+				if errors.Is(err, os.ErrNotExist) {
+					return errStateNotFound
+				}
+				return err
+			}
+			filter := ""
+			if len(args) == 1 {
+				filter = args[0]
+			}
+			clusters := filterForVerify(filterByTags(st.Clusters, tags), filter, envs)
+			if len(clusters) == 0 {
+				println(cmd.OutOrStdout(), "No clusters match the given filters.")
+				return nil
+			}
+
+			ctx, cancel := context.WithTimeout(cmd.Context(), timeout)
+			defer cancel()
+			results := health.ProbeAll(ctx, clusters, concurrency)
+
+			out := cmd.OutOrStdout()
+			if app.Output == "json" {
+				enc := json.NewEncoder(out)
+				enc.SetIndent("", "  ")
+				if err := enc.Encode(struct {
+					Results []health.Result      `json:"results"`
+					Summary []health.StatusCount `json:"summary"`
+				}{results, health.Summary(results)}); err != nil {
+					return err
+				}
+			} else {
+				printVerifyResults(out, results)
+			}
+
+			failed := 0
+			for _, r := range results {
+				if r.Status != health.StatusOK {
+					failed++
+				}
+			}
+			if failed > 0 {
+				err := rifterr.New(rifterr.CodePartialFailure, fmt.Sprintf("%d of %d contexts failed verification", failed, len(results)), "see the status column for each context")
+				return &ExitError{Code: 1, Err: err, Silent: true}
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringSliceVar(&envs, "env", nil, "Only verify contexts in these envs (repeatable)")
+	cmd.Flags().StringSliceVar(&tags, "tag", nil, "Only verify contexts with these tags (repeatable)")
+	cmd.Flags().IntVar(&concurrency, "concurrency", 8, "Number of contexts to verify in parallel")
+	cmd.Flags().DurationVar(&timeout, "timeout", 2*time.Minute, "Overall time limit for verification")
+	_ = cmd.RegisterFlagCompletionFunc("env", completeEnvs(app))
+	_ = cmd.RegisterFlagCompletionFunc("tag", completeTags(app))
+	return cmd
+}
+
+// filterForVerify keeps clusters whose context, cluster, or account contains
+// filter (case-insensitive) and whose env is in envs when given.
+func filterForVerify(clusters []state.ClusterRecord, filter string, envs []string) []state.ClusterRecord {
+	filter = strings.ToLower(strings.TrimSpace(filter))
+	out := make([]state.ClusterRecord, 0, len(clusters))
+	for _, rec := range clusters {
+		// Imported contexts authenticate however the user set them up.
+		if rec.External() {
+			continue
+		}
+		if len(envs) > 0 && !containsFold(envs, rec.Env) {
+			continue
+		}
+		if filter != "" {
+			haystack := strings.ToLower(strings.Join([]string{rec.KubeContext, rec.ClusterName, rec.AccountLabel()}, " "))
+			if !strings.Contains(haystack, filter) {
+				continue
+			}
+		}
+		out = append(out, rec)
+	}
+	return out
+}
+
+func containsFold(values []string, target string) bool {
+	for _, v := range values {
+		if strings.EqualFold(strings.TrimSpace(v), target) {
+			return true
+		}
+	}
+	return false
+}
+
+func printVerifyResults(out io.Writer, results []health.Result) {
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "CONTEXT\tSTATUS\tVERSION\tLATENCY\tDETAIL")
+	for _, r := range results {
+		latency := "-"
+		if r.Latency > 0 {
+			latency = r.Latency.Round(time.Millisecond).String()
+		}
+		version := r.Version
+		if version == "" {
+			version = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", r.Context, r.Status, version, latency, firstLine(r.Error))
+	}
+	_ = tw.Flush()
+
+	parts := make([]string, 0)
+	for _, c := range health.Summary(results) {
+		parts = append(parts, fmt.Sprintf("%s=%d", c.Status, c.Count))
+	}
+	fmt.Fprintf(out, "\n%d contexts verified: %s\n", len(results), strings.Join(parts, " "))
+}
+
+func firstLine(s string) string {
+	s = strings.TrimSpace(s)
+	if idx := strings.IndexByte(s, '\n'); idx >= 0 {
+		s = s[:idx]
+	}
+	if len(s) > 100 {
+		s = s[:97] + "..."
+	}
+	return s
+}
@@ -0,0 +1,124 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/phenixrizen/rift/internal/config"
+	"github.com/phenixrizen/rift/internal/reports"
+	"github.com/phenixrizen/rift/internal/rifterr"
+	"github.com/phenixrizen/rift/internal/ssoauth"
+	"github.com/spf13/cobra"
+)
+
+func newWatchCmd(app *App) *cobra.Command {
+	var interval time.Duration
+	var incremental bool
+	cmd := &cobra.Command{
+		Use:   "watch",
+		Short: "Re-run sync on an interval and log what changed",
+		Long: `Runs rift sync now and then every --interval (default watch_interval from
+config, else 15m) until interrupted, printing the contexts and profiles each
+sync added or removed. A failed sync is logged and retried on the next tick.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			cfg, err := app.loadConfig()
+			if err != nil {
+				return err
+			}
+			if !cmd.Flags().Changed("interval") {
+				interval = cfg.SyncInterval()
+			}
+			if interval < config.MinWatchInterval {
+				return fmt.Errorf("--interval must be at least %s", config.MinWatchInterval)
+			}
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			if cfg.SSOAutoRefresh {
+				watchKeepAlive(ctx, app, cfg)
+			}
+
+			out := cmd.OutOrStdout()
+			fmt.Fprintf(out, "Syncing every %s; press Ctrl-C to stop.\n", interval)
+			for {
+				runWatchSync(ctx, app, out, incremental)
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-time.After(interval):
+				}
+			}
+		},
+	}
+	cmd.Flags().BoolVar(&incremental, "incremental", false, "Only re-list clusters of accounts whose roles changed (see rift sync --incremental)")
+	cmd.Flags().DurationVar(&interval, "interval", config.DefaultWatchInterval, "Time between syncs (overrides watch_interval)")
+	return cmd
+}
+
+// runWatchSync runs one sync and prints a one-line summary plus the names
+// added or removed since the state it replaced.
+func runWatchSync(ctx context.Context, app *App, out io.Writer, incremental bool) {
+	prev, _ := app.loadState()
+	startedAt := time.Now()
+	report, err := app.RunSync(ctx, SyncOptions{Incremental: incremental})
+	stamp := startedAt.Format("15:04:05")
+	if err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+		if rifterr.Classify(err).Code == rifterr.CodeAuthRequired {
+			fmt.Fprintf(out, "%s sync skipped: SSO login expired (run: rift auth)\n", stamp)
+			return
+		}
+		fmt.Fprintf(out, "%s sync failed: %v\n", stamp, err)
+		return
+	}
+
+	diff := reports.Compare(SyncReport{State: prev}.compact(startedAt, startedAt), report.compact(startedAt, startedAt))
+	changes := len(diff.AddedContexts) + len(diff.RemovedContexts) + len(diff.AddedProfiles) + len(diff.RemovedProfiles)
+	fmt.Fprintf(out, "%s synced %d clusters, %d profiles in %s", stamp, len(report.State.Clusters), len(report.State.Roles), time.Since(startedAt).Round(time.Second))
+	if n := len(report.Inventory.Failures); n > 0 {
+		fmt.Fprintf(out, ", %d discovery failures", n)
+	}
+	if changes == 0 {
+		fmt.Fprintln(out, " (no changes)")
+		return
+	}
+	fmt.Fprintln(out)
+	for _, name := range diff.AddedContexts {
+		fmt.Fprintf(out, "  + context %s\n", name)
+	}
+	for _, name := range diff.RemovedContexts {
+		fmt.Fprintf(out, "  - context %s\n", name)
+	}
+	for _, name := range diff.AddedProfiles {
+		fmt.Fprintf(out, "  + profile %s\n", name)
+	}
+	for _, name := range diff.RemovedProfiles {
+		fmt.Fprintf(out, "  - profile %s\n", name)
+	}
+}
+
+// watchKeepAlive renews each session's SSO token in the background so
+// watch keeps syncing past the token lifetime (sso_auto_refresh).
+func watchKeepAlive(ctx context.Context, app *App, cfg config.Config) {
+	for _, session := range cfg.Sessions() {
+		go func() {
+			_ = ssoauth.KeepAlive(ctx, session.StartURL, session.Region, ssoauth.KeepAliveOptions{
+				Session: session.ID(),
+				OnRefresh: func(tok ssoauth.CachedToken) {
+					app.Logger.Info("sso token refreshed", "session", session.ID(), "expires", tok.ExpiresAt)
+				},
+				OnError: func(err error) {
+					app.Logger.Warn("sso token refresh failed", "session", session.ID(), "error", err)
+				},
+			})
+		}()
+	}
+}
@@ -0,0 +1,54 @@
+// Package clipboard copies text to the system clipboard, falling back to
+// the terminal's own clipboard (OSC 52) over SSH or where no clipboard tool
+// is installed.
+package clipboard
+
+import (
+	"encoding/base64"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/atotto/clipboard"
+)
+
+// How Write copied the text.
+const (
+	ViaSystem   = "clipboard"
+	ViaTerminal = "terminal"
+)
+
+// Write replaces the clipboard's contents with text and reports how. Over
+// SSH (SSH_TTY or SSH_CONNECTION set) the local clipboard is the wrong
+// machine's, so it, like a failed system copy, writes an OSC 52 sequence to
+// term instead; the terminal must allow OSC 52 for that to reach the
+// clipboard.
+func Write(term io.Writer, text string) (string, error) {
+	if !remote(os.Getenv) && !clipboard.Unsupported {
+		if err := clipboard.WriteAll(text); err == nil {
+			return ViaSystem, nil
+		}
+	}
+	if _, err := io.WriteString(term, OSC52(text, os.Getenv)); err != nil {
+		return "", err
+	}
+	return ViaTerminal, nil
+}
+
+func remote(getenv func(string) string) bool {
+	return getenv("SSH_TTY") != "" || getenv("SSH_CONNECTION") != ""
+}
+
+// OSC52 is the escape sequence that sets the terminal's clipboard to text,
+// wrapped for tmux (TMUX set) or screen (TERM screen*) to pass it through
+// to the outer terminal.
+func OSC52(text string, getenv func(string) string) string {
+	seq := "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte(text)) + "\x07"
+	switch {
+	case getenv("TMUX") != "":
+		return "\x1bPtmux;" + strings.ReplaceAll(seq, "\x1b", "\x1b\x1b") + "\x1b\\"
+	case strings.HasPrefix(getenv("TERM"), "screen"):
+		return "\x1bP" + seq + "\x1b\\"
+	}
+	return seq
+}
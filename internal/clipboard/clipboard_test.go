@@ -0,0 +1,33 @@
+package clipboard
+
+import "testing"
+
+func TestOSC52(t *testing.T) {
+	env := func(vars map[string]string) func(string) string {
+		return func(key string) string { return vars[key] }
+	}
+	// "rift-prod" is cmlmdC1wcm9k in base64.
+	if got := OSC52("rift-prod", env(nil)); got != "\x1b]52;c;cmlmdC1wcm9k\x07" {
+		t.Fatalf("plain OSC52 = %q", got)
+	}
+	if got := OSC52("rift-prod", env(map[string]string{"TMUX": "/tmp/tmux-1/default,1,0"})); got != "\x1bPtmux;\x1b\x1b]52;c;cmlmdC1wcm9k\x07\x1b\\" {
+		t.Fatalf("tmux OSC52 = %q", got)
+	}
+	if got := OSC52("rift-prod", env(map[string]string{"TERM": "screen-256color"})); got != "\x1bP\x1b]52;c;cmlmdC1wcm9k\x07\x1b\\" {
+		t.Fatalf("screen OSC52 = %q", got)
+	}
+}
+
+func TestRemote(t *testing.T) {
+	if remote(func(string) string { return "" }) {
+		t.Fatal("remote with no SSH variables")
+	}
+	if !remote(func(key string) string {
+		if key == "SSH_CONNECTION" {
+			return "10.0.0.1 51000 10.0.0.2 22"
+		}
+		return ""
+	}) {
+		t.Fatal("SSH_CONNECTION should count as remote")
+	}
+}
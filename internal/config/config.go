@@ -4,10 +4,17 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
 	"sort"
 	"strings"
+	"text/template"
+	"time"
 
+	"github.com/phenixrizen/rift/internal/fileutil"
+	"github.com/phenixrizen/rift/internal/state"
+	"github.com/phenixrizen/rift/internal/tableview"
 	"gopkg.in/yaml.v3"
 )
 
@@ -19,12 +26,288 @@ const (
 
 var defaultRegions = []string{"us-east-1", "us-west-2"}
 
+// DefaultWatchInterval is how often rift watch syncs when watch_interval is
+// unset; MinWatchInterval keeps it from hammering the AWS APIs.
+const (
+	DefaultWatchInterval = 15 * time.Minute
+	MinWatchInterval     = time.Minute
+)
+
+// MinUIAutoRefresh is the shortest ui_auto_refresh; each refresh re-reads
+// state.json, and with the drift check the AWS config and kubeconfig too.
+const MinUIAutoRefresh = 10 * time.Second
+
+// MinUIHealthCheck is the shortest ui_health_check; each check fetches a
+// token for and calls every context in the rift ui table.
+const MinUIHealthCheck = 30 * time.Second
+
+// DefaultSSOExpiryWarning is how long before the SSO token expires rift ui
+// warns when sso_expiry_warning is unset.
+const DefaultSSOExpiryWarning = 15 * time.Minute
+
+// Discovery retries throttled and transient AWS errors with exponential
+// backoff. These are the defaults for retry_max_attempts and
+// retry_max_backoff.
+const (
+	DefaultRetryMaxAttempts = 8
+	DefaultRetryMaxBackoff  = 20 * time.Second
+)
+
+// Kubeconfig layouts (kubeconfig_layout). Merged writes every context into
+// the kubeconfig targets; dedicated writes them all to kubeconfig_file; split
+// writes one file per context to kubeconfig_dir plus an index for KUBECONFIG.
+const (
+	KubeconfigLayoutMerged    = "merged"
+	KubeconfigLayoutDedicated = "dedicated"
+	KubeconfigLayoutSplit     = "split"
+	DefaultKubeconfigFile     = "~/.kube/rift.config"
+	DefaultKubeconfigDir      = "~/.kube/rift"
+)
+
+// Kubeconfig exec plugins (kubeconfig_exec) for EKS contexts: aws eks
+// get-token, or rift token, which needs no AWS CLI.
+const (
+	KubeconfigExecAWS  = "aws"
+	KubeconfigExecRift = "rift"
+)
+
+// StateEncryptionAge (state_encryption) encrypts state files with age; see
+// internal/statecrypt for where the key comes from.
+const StateEncryptionAge = "age"
+
+// DefaultSSOSession is the ~/.aws/config sso-session name of the primary
+// IAM Identity Center instance (sso_start_url/sso_region).
+const DefaultSSOSession = "rift"
+
+var labelPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
 type Config struct {
-	SSOStartURL        string            `yaml:"sso_start_url"`
-	SSORegion          string            `yaml:"sso_region"`
-	Regions            []string          `yaml:"regions"`
-	NamespaceDefaults  map[string]string `yaml:"namespace_defaults"`
-	DiscoverNamespaces bool              `yaml:"discover_namespaces"`
+	SSOStartURL string `yaml:"sso_start_url"`
+	SSORegion   string `yaml:"sso_region"`
+	// SSOSessions lists additional IAM Identity Center instances (other
+	// organizations) discovered alongside the primary one.
+	SSOSessions        []SSOSession        `yaml:"sso_sessions,omitempty"`
+	Regions            []string            `yaml:"regions"`
+	RegionOverrides    []RegionOverride    `yaml:"region_overrides,omitempty"`
+	NamespaceDefaults  map[string]string   `yaml:"namespace_defaults"`
+	NamespaceOverrides []NamespaceOverride `yaml:"namespace_overrides,omitempty"`
+	AccountAliases     map[string]string   `yaml:"account_aliases,omitempty"`
+	// EnvRules replaces the built-in env keyword heuristic when set.
+	EnvRules           []EnvRule    `yaml:"env_rules,omitempty"`
+	AssumeRoles        []AssumeRole `yaml:"assume_roles,omitempty"`
+	DiscoverNamespaces bool         `yaml:"discover_namespaces"`
+	// NamespaceInclude and NamespaceExclude are glob patterns (path.Match)
+	// for the namespaces discovery records: with includes, only matching
+	// names are kept; excludes drop names either way.
+	NamespaceInclude []string `yaml:"namespace_include,omitempty"`
+	NamespaceExclude []string `yaml:"namespace_exclude,omitempty"`
+	// NamespaceTTL is how long sync reuses a cluster's namespace list
+	// before listing it again (e.g. "6h"); empty lists every sync.
+	NamespaceTTL string `yaml:"namespace_ttl,omitempty"`
+	// DiscoverRegions scans every region enabled in each account instead of
+	// Regions; region_overrides still apply, and Regions is the fallback
+	// when the account's regions cannot be listed.
+	DiscoverRegions bool `yaml:"discover_regions,omitempty"`
+	// DiscoverCompute records each cluster's managed node groups and Fargate
+	// profiles (two extra EKS calls per cluster plus one per item).
+	DiscoverCompute bool `yaml:"discover_compute,omitempty"`
+	// DiscoverWorkloads counts each namespace's deployments, statefulsets,
+	// and daemonsets during namespace discovery (three more list calls per
+	// cluster).
+	DiscoverWorkloads bool `yaml:"discover_workloads,omitempty"`
+	// DiscoverAccess checks during namespace discovery which namespaces the
+	// role may get pods in (one SelfSubjectAccessReview per namespace).
+	DiscoverAccess bool `yaml:"discover_access,omitempty"`
+	// SSOAutoRefresh renews the SSO token with its refresh token while
+	// long-running commands (the TUI) are open.
+	SSOAutoRefresh bool `yaml:"sso_auto_refresh,omitempty"`
+	// SSOExpiryWarning is how long before the SSO token expires rift ui
+	// turns its countdown into a warning, as a Go duration ("15m"); empty
+	// means DefaultSSOExpiryWarning.
+	SSOExpiryWarning string `yaml:"sso_expiry_warning,omitempty"`
+	// WatchInterval is the rift watch sync period as a Go duration
+	// ("15m"); empty means DefaultWatchInterval.
+	WatchInterval string `yaml:"watch_interval,omitempty"`
+	// RetryMaxAttempts and RetryMaxBackoff tune how discovery retries
+	// throttled AWS calls; zero/empty means the defaults above.
+	RetryMaxAttempts int    `yaml:"retry_max_attempts,omitempty"`
+	RetryMaxBackoff  string `yaml:"retry_max_backoff,omitempty"`
+	// CredentialCache persists role credentials fetched by sync and env
+	// (mode 0600, next to state.json) until shortly before they expire, so
+	// consecutive runs skip GetRoleCredentials.
+	CredentialCache bool `yaml:"credential_cache,omitempty"`
+	// BackupRetain is how many backups of the files sync and migrate rewrite
+	// to keep (zero means backup.DefaultRetain); negative turns backups off.
+	BackupRetain int `yaml:"backup_retain,omitempty"`
+	// HistoryRetain is how many state snapshots `rift diff` can compare
+	// (zero means history.DefaultRetain); negative turns snapshots off.
+	HistoryRetain int `yaml:"history_retain,omitempty"`
+	// StateBackend is an s3://bucket/prefix URL sync uploads state.json to
+	// and `rift state pull` downloads it from; the local state.json stays
+	// the cache every command reads. The bucket is reached with the SSO
+	// role StateBackendRole in account StateBackendAccount (through
+	// StateBackendSSOSession, default the primary session), in
+	// StateBackendRegion (default that session's region).
+	StateBackend           string `yaml:"state_backend,omitempty"`
+	StateBackendAccount    string `yaml:"state_backend_account,omitempty"`
+	StateBackendRole       string `yaml:"state_backend_role,omitempty"`
+	StateBackendRegion     string `yaml:"state_backend_region,omitempty"`
+	StateBackendSSOSession string `yaml:"state_backend_sso_session,omitempty"`
+	// StateEncryption is StateEncryptionAge to encrypt state.json and its
+	// snapshots at rest; empty leaves them plain JSON.
+	StateEncryption string `yaml:"state_encryption,omitempty"`
+	// GCPProjects lists Google Cloud projects whose GKE clusters sync adds
+	// alongside EKS, using Application Default Credentials.
+	GCPProjects []string `yaml:"gcp_projects,omitempty"`
+	// AzureSubscriptions lists Azure subscription IDs whose AKS clusters
+	// sync adds, using DefaultAzureCredential.
+	AzureSubscriptions []string `yaml:"azure_subscriptions,omitempty"`
+	// DiscoveryPlugins names plugins (rift-<name> on PATH) whose clusters
+	// sync adds, as printed by rift-<name> discover.
+	DiscoveryPlugins []string `yaml:"discovery_plugins,omitempty"`
+	// KubeconfigPaths lists the kubeconfig files sync writes. Empty means the
+	// first KUBECONFIG entry or ~/.kube/config.
+	KubeconfigPaths []string `yaml:"kubeconfig_paths,omitempty"`
+	// KubeconfigLayout is KubeconfigLayoutMerged (default),
+	// KubeconfigLayoutDedicated, or KubeconfigLayoutSplit. KubeconfigFile and
+	// KubeconfigDir override DefaultKubeconfigFile and DefaultKubeconfigDir.
+	KubeconfigLayout string `yaml:"kubeconfig_layout,omitempty"`
+	KubeconfigFile   string `yaml:"kubeconfig_file,omitempty"`
+	KubeconfigDir    string `yaml:"kubeconfig_dir,omitempty"`
+	// KubeconfigExec is the exec plugin of EKS contexts: KubeconfigExecAWS
+	// (default) or KubeconfigExecRift.
+	KubeconfigExec string `yaml:"kubeconfig_exec,omitempty"`
+	// ProfileTemplate and ContextTemplate are text/template strings for
+	// generated AWS profile and kube context names. Empty means
+	// rift-<env>-<account>-<role> and rift-<env>-<account>-<cluster>.
+	ProfileTemplate string `yaml:"profile_template,omitempty"`
+	ContextTemplate string `yaml:"context_template,omitempty"`
+	// Theme sets the rift ui colors.
+	Theme Theme `yaml:"theme,omitempty"`
+	// Keybindings maps rift ui actions to keys, overriding
+	// DefaultKeybindings.
+	Keybindings map[string]string `yaml:"keybindings,omitempty"`
+	// UIColumns are the rift ui table's columns, as rift list --columns
+	// keys (default the rift ui set); the favorite star and health mark always come first.
+	UIColumns []string `yaml:"ui_columns,omitempty"`
+	// UISortBy is the column key the rift ui table starts sorted by, in
+	// descending order with UISortDesc; empty keeps search rank order.
+	UISortBy   string `yaml:"ui_sort_by,omitempty"`
+	UISortDesc bool   `yaml:"ui_sort_desc,omitempty"`
+	// UIAutoRefresh is how often rift ui reloads state.json, as a Go
+	// duration ("5m"); empty turns it off. With UIAutoRefreshDrift each
+	// reload also dry-runs writing the state to the AWS config and
+	// kubeconfig and reports when they no longer match.
+	UIAutoRefresh      string `yaml:"ui_auto_refresh,omitempty"`
+	UIAutoRefreshDrift bool   `yaml:"ui_auto_refresh_drift,omitempty"`
+	// UIHealthCheck is how often rift ui probes the API servers of the
+	// contexts in its table, as a Go duration; empty leaves probing to the
+	// health key.
+	UIHealthCheck string `yaml:"ui_health_check,omitempty"`
+	// UIPlugins names plugins the rift ui action menu offers to run on the
+	// selected context.
+	UIPlugins []string `yaml:"ui_plugins,omitempty"`
+	// Hooks are commands run before and after syncs and after rift use.
+	Hooks Hooks `yaml:"hooks,omitempty"`
+}
+
+// SSOSession is an IAM Identity Center instance. Name is empty for the
+// primary session built from sso_start_url/sso_region.
+type SSOSession struct {
+	Name     string `yaml:"name"`
+	StartURL string `yaml:"start_url"`
+	Region   string `yaml:"region"`
+}
+
+// ID is the sso-session name rift writes to ~/.aws/config and records on
+// roles and clusters: "rift" for the primary session, "rift-<name>" otherwise.
+func (s SSOSession) ID() string {
+	if s.Name == "" {
+		return DefaultSSOSession
+	}
+	return DefaultSSOSession + "-" + s.Name
+}
+
+// RegionOverride narrows the regions scanned for roles whose inferred env
+// and/or account (name or ID glob) match. The first matching entry wins.
+type RegionOverride struct {
+	Env     string   `yaml:"env,omitempty"`
+	Account string   `yaml:"account,omitempty"`
+	Regions []string `yaml:"regions"`
+}
+
+// Fields available to profile_template; context_template also gets Cluster
+// and ClusterName. Env, Account, Role, and Cluster are slugs.
+var (
+	ProfileTemplateFields = []string{"Env", "Account", "AccountName", "AccountID", "Role", "RoleName", "Region", "SSOSession"}
+	ContextTemplateFields = append(append([]string(nil), ProfileTemplateFields...), "Cluster", "ClusterName")
+)
+
+// NameTemplate parses a profile_template or context_template. Referencing a
+// field the template does not provide is an error when it is executed.
+func NameTemplate(name, text string) (*template.Template, error) {
+	return template.New(name).Option("missingkey=error").Parse(text)
+}
+
+// Fields an EnvRule can match against.
+const (
+	EnvFieldAccount = "account"
+	EnvFieldRole    = "role"
+	EnvFieldCluster = "cluster"
+)
+
+// EnvRule assigns Env to names matching the case-insensitive regular
+// expression Match. Field limits the rule to the account (alias or name),
+// role, or cluster name; empty matches any of them. Rules are checked in
+// order and the first match wins.
+type EnvRule struct {
+	Env   string `yaml:"env"`
+	Match string `yaml:"match"`
+	Field string `yaml:"field,omitempty"`
+}
+
+// Regexp compiles Match case-insensitively.
+func (r EnvRule) Regexp() (*regexp.Regexp, error) {
+	return regexp.Compile("(?i)" + r.Match)
+}
+
+// AssumeRole declares a role reachable only by chaining from an SSO role,
+// e.g. a shared-services account that trusts a permission set elsewhere.
+type AssumeRole struct {
+	RoleARN       string `yaml:"role_arn"`
+	SourceAccount string `yaml:"source_account"`
+	SourceRole    string `yaml:"source_role"`
+	AccountName   string `yaml:"account_name,omitempty"`
+	ExternalID    string `yaml:"external_id,omitempty"`
+}
+
+// AccountID returns the account ID embedded in RoleARN.
+func (a AssumeRole) AccountID() string {
+	parts := strings.SplitN(a.RoleARN, ":", 6)
+	if len(parts) < 6 {
+		return ""
+	}
+	return parts[4]
+}
+
+// RoleName returns the last path segment of RoleARN.
+func (a AssumeRole) RoleName() string {
+	parts := strings.SplitN(a.RoleARN, ":", 6)
+	if len(parts) < 6 || !strings.HasPrefix(parts[5], "role/") {
+		return ""
+	}
+	resource := parts[5]
+	return resource[strings.LastIndex(resource, "/")+1:]
+}
+
+// NamespaceOverride sets the default namespace for clusters whose env,
+// account (name or ID glob), and cluster name glob all match. The first
+// matching entry wins over namespace_defaults.
+type NamespaceOverride struct {
+	Env       string `yaml:"env,omitempty"`
+	Account   string `yaml:"account,omitempty"`
+	Cluster   string `yaml:"cluster,omitempty"`
+	Namespace string `yaml:"namespace"`
 }
 
 func Default() Config {
@@ -51,6 +334,43 @@ func DefaultStatePath() (string, error) {
 	return filepath.Join(home, configDirName, stateFileName), nil
 }
 
+// Layout is the kubeconfig layout, KubeconfigLayoutMerged when unset.
+func (c Config) Layout() string {
+	if c.KubeconfigLayout == "" {
+		return KubeconfigLayoutMerged
+	}
+	return c.KubeconfigLayout
+}
+
+// SeparateKubeconfig reports whether rift keeps its contexts out of the
+// user's kubeconfig (dedicated and split layouts), so they reach kubectl
+// through KUBECONFIG.
+func (c Config) SeparateKubeconfig() bool {
+	return c.Layout() != KubeconfigLayoutMerged
+}
+
+// DedicatedKubeconfigPath is the resolved file of the dedicated layout.
+func (c Config) DedicatedKubeconfigPath() (string, error) {
+	if c.KubeconfigFile != "" {
+		return ResolvePath(c.KubeconfigFile)
+	}
+	return ResolvePath(DefaultKubeconfigFile)
+}
+
+// SplitKubeconfig reports whether sync writes one kubeconfig file per
+// context.
+func (c Config) SplitKubeconfig() bool {
+	return c.KubeconfigLayout == KubeconfigLayoutSplit
+}
+
+// SplitKubeconfigDir is the resolved directory of the split layout.
+func (c Config) SplitKubeconfigDir() (string, error) {
+	if c.KubeconfigDir != "" {
+		return ResolvePath(c.KubeconfigDir)
+	}
+	return ResolvePath(DefaultKubeconfigDir)
+}
+
 func ResolvePath(path string) (string, error) {
 	if path == "" {
 		return "", errors.New("path is empty")
@@ -105,35 +425,23 @@ func Save(path string, cfg Config) error {
 	if err != nil {
 		return fmt.Errorf("marshal config: %w", err)
 	}
-	if err := os.WriteFile(resolved, data, 0o644); err != nil {
-		return err
-	}
-	return nil
+	return fileutil.WriteLocked(resolved, data, 0o644)
 }
 
 func (c *Config) Normalize() {
-	if len(c.Regions) == 0 {
-		c.Regions = append([]string(nil), defaultRegions...)
-	}
-	seen := map[string]struct{}{}
-	regions := make([]string, 0, len(c.Regions))
-	for _, region := range c.Regions {
-		region = strings.TrimSpace(strings.ToLower(region))
-		if region == "" {
-			continue
-		}
-		if _, ok := seen[region]; ok {
-			continue
-		}
-		seen[region] = struct{}{}
-		regions = append(regions, region)
-	}
-	sort.Strings(regions)
+	regions := normalizeRegions(c.Regions)
 	if len(regions) == 0 {
 		regions = append([]string(nil), defaultRegions...)
 	}
 	c.Regions = regions
 
+	for i := range c.RegionOverrides {
+		o := &c.RegionOverrides[i]
+		o.Env = strings.TrimSpace(strings.ToLower(o.Env))
+		o.Account = strings.TrimSpace(o.Account)
+		o.Regions = normalizeRegions(o.Regions)
+	}
+
 	if c.NamespaceDefaults == nil {
 		c.NamespaceDefaults = map[string]string{}
 	}
@@ -146,8 +454,116 @@ func (c *Config) Normalize() {
 		normalized[key] = strings.TrimSpace(v)
 	}
 	c.NamespaceDefaults = normalized
+	for i := range c.NamespaceOverrides {
+		o := &c.NamespaceOverrides[i]
+		o.Env = strings.TrimSpace(strings.ToLower(o.Env))
+		o.Account = strings.TrimSpace(o.Account)
+		o.Cluster = strings.TrimSpace(o.Cluster)
+		o.Namespace = strings.TrimSpace(o.Namespace)
+	}
+	for i := range c.AssumeRoles {
+		a := &c.AssumeRoles[i]
+		a.RoleARN = strings.TrimSpace(a.RoleARN)
+		a.SourceAccount = strings.TrimSpace(a.SourceAccount)
+		a.SourceRole = strings.TrimSpace(a.SourceRole)
+		a.AccountName = strings.TrimSpace(a.AccountName)
+		a.ExternalID = strings.TrimSpace(a.ExternalID)
+	}
+	if len(c.AccountAliases) > 0 {
+		aliases := make(map[string]string, len(c.AccountAliases))
+		for id, alias := range c.AccountAliases {
+			id = strings.TrimSpace(id)
+			alias = strings.TrimSpace(alias)
+			if id == "" || alias == "" {
+				continue
+			}
+			aliases[id] = alias
+		}
+		c.AccountAliases = aliases
+	}
+	c.KubeconfigPaths = normalizePaths(c.KubeconfigPaths)
+	c.KubeconfigLayout = strings.TrimSpace(strings.ToLower(c.KubeconfigLayout))
+	c.KubeconfigFile = strings.TrimSpace(c.KubeconfigFile)
+	c.KubeconfigDir = strings.TrimSpace(c.KubeconfigDir)
+	c.KubeconfigExec = strings.TrimSpace(strings.ToLower(c.KubeconfigExec))
+	c.GCPProjects = normalizePaths(c.GCPProjects)
+	c.NamespaceInclude = normalizePaths(c.NamespaceInclude)
+	c.NamespaceExclude = normalizePaths(c.NamespaceExclude)
+	c.AzureSubscriptions = normalizePaths(c.AzureSubscriptions)
+	c.DiscoveryPlugins = normalizePaths(c.DiscoveryPlugins)
+	c.UIPlugins = normalizePaths(c.UIPlugins)
+	for i := range c.EnvRules {
+		r := &c.EnvRules[i]
+		r.Env = strings.TrimSpace(strings.ToLower(r.Env))
+		r.Field = strings.TrimSpace(strings.ToLower(r.Field))
+	}
+	for i := range c.SSOSessions {
+		s := &c.SSOSessions[i]
+		s.Name = strings.TrimSpace(strings.ToLower(s.Name))
+		s.StartURL = strings.TrimSpace(s.StartURL)
+		s.Region = strings.TrimSpace(strings.ToLower(s.Region))
+	}
+	c.WatchInterval = strings.TrimSpace(c.WatchInterval)
+	c.RetryMaxBackoff = strings.TrimSpace(c.RetryMaxBackoff)
+	c.SSOExpiryWarning = strings.TrimSpace(c.SSOExpiryWarning)
+	c.NamespaceTTL = strings.TrimSpace(c.NamespaceTTL)
+	c.UIAutoRefresh = strings.TrimSpace(c.UIAutoRefresh)
+	c.UIHealthCheck = strings.TrimSpace(c.UIHealthCheck)
 	c.SSOStartURL = strings.TrimSpace(c.SSOStartURL)
 	c.SSORegion = strings.TrimSpace(strings.ToLower(c.SSORegion))
+	c.Theme.Preset = strings.TrimSpace(strings.ToLower(c.Theme.Preset))
+	c.Hooks.normalize()
+	if len(c.Keybindings) > 0 {
+		bindings := make(map[string]string, len(c.Keybindings))
+		for action, key := range c.Keybindings {
+			bindings[strings.TrimSpace(strings.ToLower(action))] = strings.TrimSpace(key)
+		}
+		c.Keybindings = bindings
+	}
+	if len(c.UIColumns) > 0 {
+		for i := range c.UIColumns {
+			c.UIColumns[i] = strings.ToLower(c.UIColumns[i])
+		}
+		c.UIColumns = normalizePaths(c.UIColumns)
+	}
+	c.UISortBy = strings.TrimSpace(strings.ToLower(c.UISortBy))
+}
+
+// normalizePaths trims and de-duplicates paths (or other names), keeping
+// their order.
+func normalizePaths(paths []string) []string {
+	seen := map[string]struct{}{}
+	out := make([]string, 0, len(paths))
+	for _, p := range paths {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if _, ok := seen[p]; ok {
+			continue
+		}
+		seen[p] = struct{}{}
+		out = append(out, p)
+	}
+	return out
+}
+
+func normalizeRegions(in []string) []string {
+	seen := map[string]struct{}{}
+	regions := make([]string, 0, len(in))
+	for _, region := range in {
+		region = strings.TrimSpace(strings.ToLower(region))
+		if region == "" {
+			continue
+		}
+		if _, ok := seen[region]; ok {
+			continue
+		}
+		seen[region] = struct{}{}
+		regions = append(regions, region)
+	}
+	sort.Strings(regions)
+	return regions
 }
 
 func (c Config) Validate() error {
@@ -160,9 +576,399 @@ func (c Config) Validate() error {
 	if len(c.Regions) == 0 {
 		return errors.New("config missing regions")
 	}
+	seenSessions := map[string]struct{}{}
+	seenURLs := map[string]struct{}{c.SSOStartURL: {}}
+	for i, s := range c.SSOSessions {
+		if !labelPattern.MatchString(s.Name) {
+			return fmt.Errorf("sso_sessions[%d]: name %q must be lowercase letters, digits, and dashes", i, s.Name)
+		}
+		if s.StartURL == "" || s.Region == "" {
+			return fmt.Errorf("sso_sessions[%d]: start_url and region are required", i)
+		}
+		if _, ok := seenSessions[s.Name]; ok {
+			return fmt.Errorf("sso_sessions[%d]: duplicate name %q", i, s.Name)
+		}
+		if _, ok := seenURLs[s.StartURL]; ok {
+			return fmt.Errorf("sso_sessions[%d]: start_url %q is already configured", i, s.StartURL)
+		}
+		seenSessions[s.Name] = struct{}{}
+		seenURLs[s.StartURL] = struct{}{}
+	}
+	for i, o := range c.RegionOverrides {
+		if o.Env == "" && o.Account == "" {
+			return fmt.Errorf("region_overrides[%d]: set env and/or account", i)
+		}
+		if len(o.Regions) == 0 {
+			return fmt.Errorf("region_overrides[%d]: missing regions", i)
+		}
+		if _, err := path.Match(strings.ToLower(o.Account), ""); err != nil {
+			return fmt.Errorf("region_overrides[%d]: invalid account pattern %q: %w", i, o.Account, err)
+		}
+	}
+	for i, r := range c.EnvRules {
+		if !labelPattern.MatchString(r.Env) {
+			return fmt.Errorf("env_rules[%d]: env %q must be lowercase letters, digits, and dashes", i, r.Env)
+		}
+		if strings.TrimSpace(r.Match) == "" {
+			return fmt.Errorf("env_rules[%d]: missing match", i)
+		}
+		if _, err := r.Regexp(); err != nil {
+			return fmt.Errorf("env_rules[%d]: invalid match %q: %w", i, r.Match, err)
+		}
+		switch r.Field {
+		case "", EnvFieldAccount, EnvFieldRole, EnvFieldCluster:
+		default:
+			return fmt.Errorf("env_rules[%d]: field must be account, role, or cluster", i)
+		}
+	}
+	for _, t := range []struct {
+		key, text string
+		fields    []string
+	}{
+		{"profile_template", c.ProfileTemplate, ProfileTemplateFields},
+		{"context_template", c.ContextTemplate, ContextTemplateFields},
+	} {
+		if err := validateNameTemplate(t.key, t.text, t.fields); err != nil {
+			return err
+		}
+	}
+	if c.WatchInterval != "" {
+		d, err := time.ParseDuration(c.WatchInterval)
+		if err != nil {
+			return fmt.Errorf("watch_interval: %w", err)
+		}
+		if d < MinWatchInterval {
+			return fmt.Errorf("watch_interval must be at least %s", MinWatchInterval)
+		}
+	}
+	if c.SSOExpiryWarning != "" {
+		d, err := time.ParseDuration(c.SSOExpiryWarning)
+		if err != nil {
+			return fmt.Errorf("sso_expiry_warning: %w", err)
+		}
+		if d <= 0 {
+			return fmt.Errorf("sso_expiry_warning must be positive")
+		}
+	}
+	if c.NamespaceTTL != "" {
+		d, err := time.ParseDuration(c.NamespaceTTL)
+		if err != nil {
+			return fmt.Errorf("namespace_ttl: %w", err)
+		}
+		if d <= 0 {
+			return fmt.Errorf("namespace_ttl must be positive")
+		}
+	}
+	if c.UIAutoRefresh != "" {
+		d, err := time.ParseDuration(c.UIAutoRefresh)
+		if err != nil {
+			return fmt.Errorf("ui_auto_refresh: %w", err)
+		}
+		if d < MinUIAutoRefresh {
+			return fmt.Errorf("ui_auto_refresh must be at least %s", MinUIAutoRefresh)
+		}
+	} else if c.UIAutoRefreshDrift {
+		return fmt.Errorf("ui_auto_refresh_drift needs ui_auto_refresh")
+	}
+	if c.UIHealthCheck != "" {
+		d, err := time.ParseDuration(c.UIHealthCheck)
+		if err != nil {
+			return fmt.Errorf("ui_health_check: %w", err)
+		}
+		if d < MinUIHealthCheck {
+			return fmt.Errorf("ui_health_check must be at least %s", MinUIHealthCheck)
+		}
+	}
+	if c.RetryMaxAttempts < 0 {
+		return fmt.Errorf("retry_max_attempts must not be negative")
+	}
+	if c.RetryMaxBackoff != "" {
+		d, err := time.ParseDuration(c.RetryMaxBackoff)
+		if err != nil {
+			return fmt.Errorf("retry_max_backoff: %w", err)
+		}
+		if d <= 0 {
+			return fmt.Errorf("retry_max_backoff must be positive")
+		}
+	}
+	switch c.KubeconfigLayout {
+	case "", KubeconfigLayoutMerged:
+	case KubeconfigLayoutDedicated, KubeconfigLayoutSplit:
+		if len(c.KubeconfigPaths) > 0 {
+			return fmt.Errorf("kubeconfig_paths cannot be combined with kubeconfig_layout: %s", c.KubeconfigLayout)
+		}
+	default:
+		return fmt.Errorf("kubeconfig_layout must be %s, %s, or %s", KubeconfigLayoutMerged, KubeconfigLayoutDedicated, KubeconfigLayoutSplit)
+	}
+	for _, pattern := range c.NamespaceInclude {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("namespace_include: invalid pattern %q", pattern)
+		}
+	}
+	for _, pattern := range c.NamespaceExclude {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("namespace_exclude: invalid pattern %q", pattern)
+		}
+	}
+	switch c.KubeconfigExec {
+	case "", KubeconfigExecAWS, KubeconfigExecRift:
+	default:
+		return fmt.Errorf("kubeconfig_exec must be %s or %s", KubeconfigExecAWS, KubeconfigExecRift)
+	}
+	for i, a := range c.AssumeRoles {
+		if a.AccountID() == "" || a.RoleName() == "" {
+			return fmt.Errorf("assume_roles[%d]: invalid role_arn %q", i, a.RoleARN)
+		}
+		if a.SourceAccount == "" || a.SourceRole == "" {
+			return fmt.Errorf("assume_roles[%d]: source_account and source_role are required", i)
+		}
+	}
+	if err := c.Theme.validate(); err != nil {
+		return err
+	}
+	if err := c.Hooks.validate(); err != nil {
+		return err
+	}
+	for _, plugins := range []struct {
+		key   string
+		names []string
+	}{{"discovery_plugins", c.DiscoveryPlugins}, {"ui_plugins", c.UIPlugins}} {
+		for _, name := range plugins.names {
+			if !state.ValidPluginName(name) {
+				return fmt.Errorf("%s: %q is not a plugin name (letters, digits, - and _; rift- is added)", plugins.key, name)
+			}
+		}
+	}
+	if err := c.validateKeybindings(); err != nil {
+		return err
+	}
+	if err := (tableview.Options{Columns: c.UIColumns}).Validate(); err != nil {
+		return fmt.Errorf("ui_columns: %w", err)
+	}
+	if c.UISortBy != "" {
+		if err := (tableview.Options{SortBy: []string{c.UISortBy}}).Validate(); err != nil {
+			return fmt.Errorf("ui_sort_by: %w", err)
+		}
+	}
+	switch c.StateEncryption {
+	case "", StateEncryptionAge:
+	default:
+		return fmt.Errorf("state_encryption must be %s or empty", StateEncryptionAge)
+	}
+	if c.StateBackend != "" {
+		if !strings.HasPrefix(c.StateBackend, "s3://") || len(c.StateBackend) == len("s3://") {
+			return fmt.Errorf("state_backend must be an s3://bucket/prefix URL")
+		}
+		if c.StateBackendAccount == "" || c.StateBackendRole == "" {
+			return fmt.Errorf("state_backend needs state_backend_account and state_backend_role")
+		}
+		if _, ok := c.Session(c.StateBackendSSOSession); !ok {
+			return fmt.Errorf("state_backend_sso_session %q is not configured", c.StateBackendSSOSession)
+		}
+	}
+	for i, o := range c.NamespaceOverrides {
+		if o.Env == "" && o.Account == "" && o.Cluster == "" {
+			return fmt.Errorf("namespace_overrides[%d]: set env, account, and/or cluster", i)
+		}
+		if o.Namespace == "" {
+			return fmt.Errorf("namespace_overrides[%d]: missing namespace", i)
+		}
+		for _, pattern := range []string{o.Account, o.Cluster} {
+			if _, err := path.Match(strings.ToLower(pattern), ""); err != nil {
+				return fmt.Errorf("namespace_overrides[%d]: invalid pattern %q: %w", i, pattern, err)
+			}
+		}
+	}
 	return nil
 }
 
+// validateNameTemplate renders text with sample values for fields so typos
+// and unavailable fields fail at load instead of during sync.
+func validateNameTemplate(key, text string, fields []string) error {
+	if strings.TrimSpace(text) == "" {
+		return nil
+	}
+	tmpl, err := NameTemplate(key, text)
+	if err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	sample := make(map[string]string, len(fields))
+	for _, f := range fields {
+		sample[f] = "x"
+	}
+	var out strings.Builder
+	if err := tmpl.Execute(&out, sample); err != nil {
+		return fmt.Errorf("%s: %w (fields: %s)", key, err, strings.Join(fields, ", "))
+	}
+	if strings.TrimSpace(out.String()) == "" {
+		return fmt.Errorf("%s renders an empty name", key)
+	}
+	return nil
+}
+
+// Sessions returns the primary SSO session followed by sso_sessions.
+func (c Config) Sessions() []SSOSession {
+	out := make([]SSOSession, 0, 1+len(c.SSOSessions))
+	out = append(out, SSOSession{StartURL: c.SSOStartURL, Region: c.SSORegion})
+	return append(out, c.SSOSessions...)
+}
+
+// Session finds a configured session by name or ID ("rift" or empty is the
+// primary session).
+func (c Config) Session(name string) (SSOSession, bool) {
+	name = strings.TrimSpace(strings.ToLower(name))
+	for _, s := range c.Sessions() {
+		if name == s.Name || name == s.ID() {
+			return s, true
+		}
+	}
+	return SSOSession{}, false
+}
+
+// SyncInterval is the parsed watch_interval, or DefaultWatchInterval.
+func (c Config) SyncInterval() time.Duration {
+	if d, err := time.ParseDuration(c.WatchInterval); err == nil && d > 0 {
+		return d
+	}
+	return DefaultWatchInterval
+}
+
+// ExpiryWarning is the parsed sso_expiry_warning, or
+// DefaultSSOExpiryWarning.
+func (c Config) ExpiryWarning() time.Duration {
+	if d, err := time.ParseDuration(c.SSOExpiryWarning); err == nil && d > 0 {
+		return d
+	}
+	return DefaultSSOExpiryWarning
+}
+
+// NamespaceCacheTTL is the parsed namespace_ttl, or 0 when sync lists
+// namespaces every time.
+func (c Config) NamespaceCacheTTL() time.Duration {
+	if d, err := time.ParseDuration(c.NamespaceTTL); err == nil && d > 0 {
+		return d
+	}
+	return 0
+}
+
+// AutoRefresh is the parsed ui_auto_refresh, or 0 when rift ui should not
+// reload state on its own.
+func (c Config) AutoRefresh() time.Duration {
+	if d, err := time.ParseDuration(c.UIAutoRefresh); err == nil && d > 0 {
+		return d
+	}
+	return 0
+}
+
+// HealthCheck is the parsed ui_health_check, or 0 when rift ui should only
+// probe API servers on demand.
+func (c Config) HealthCheck() time.Duration {
+	if d, err := time.ParseDuration(c.UIHealthCheck); err == nil && d > 0 {
+		return d
+	}
+	return 0
+}
+
+// RetryPolicy returns the maximum attempts per AWS call (including the first)
+// and the backoff cap, applying defaults.
+func (c Config) RetryPolicy() (int, time.Duration) {
+	attempts := c.RetryMaxAttempts
+	if attempts <= 0 {
+		attempts = DefaultRetryMaxAttempts
+	}
+	backoff := DefaultRetryMaxBackoff
+	if d, err := time.ParseDuration(c.RetryMaxBackoff); err == nil && d > 0 {
+		backoff = d
+	}
+	return attempts, backoff
+}
+
+// RegionsFor returns the regions to scan for an account, applying the first
+// matching region override and falling back to Regions.
+func (c Config) RegionsFor(env, accountName, accountID string) []string {
+	if idx := c.RegionOverrideIndex(env, accountName, accountID); idx >= 0 {
+		return c.RegionOverrides[idx].Regions
+	}
+	return c.Regions
+}
+
+// RegionOverrideIndex returns the index of the first matching region
+// override, or -1 when the global regions apply.
+func (c Config) RegionOverrideIndex(env, accountName, accountID string) int {
+	for i, o := range c.RegionOverrides {
+		if !matchEnv(o.Env, env) {
+			continue
+		}
+		if o.Account != "" && !matchPattern(o.Account, accountName) && !matchPattern(o.Account, accountID) {
+			continue
+		}
+		return i
+	}
+	return -1
+}
+
+// AllRegions is the union of Regions and every override's regions.
+func (c Config) AllRegions() []string {
+	all := append([]string(nil), c.Regions...)
+	for _, o := range c.RegionOverrides {
+		all = append(all, o.Regions...)
+	}
+	return normalizeRegions(all)
+}
+
+// NamespaceFor returns the default namespace for a cluster, applying the first
+// matching namespace override before the env-keyed namespace_defaults.
+func (c Config) NamespaceFor(env, accountName, accountID, clusterName string) string {
+	if idx := c.NamespaceOverrideIndex(env, accountName, accountID, clusterName); idx >= 0 {
+		return c.NamespaceOverrides[idx].Namespace
+	}
+	return c.NamespaceForEnv(env)
+}
+
+// NamespaceOverrideIndex returns the index of the first matching namespace
+// override, or -1 when namespace_defaults apply.
+func (c Config) NamespaceOverrideIndex(env, accountName, accountID, clusterName string) int {
+	for i, o := range c.NamespaceOverrides {
+		if !matchEnv(o.Env, env) {
+			continue
+		}
+		if o.Account != "" && !matchPattern(o.Account, accountName) && !matchPattern(o.Account, accountID) {
+			continue
+		}
+		if o.Cluster != "" && !matchPattern(o.Cluster, clusterName) {
+			continue
+		}
+		return i
+	}
+	return -1
+}
+
+// AccountAlias returns the configured short name for an account ID, if any.
+func (c Config) AccountAlias(accountID string) string {
+	return c.AccountAliases[strings.TrimSpace(accountID)]
+}
+
+func matchEnv(want, env string) bool {
+	if want == "" {
+		return true
+	}
+	env = strings.ToLower(strings.TrimSpace(env))
+	if want == env {
+		return true
+	}
+	return (want == "stg" && env == "staging") || (want == "staging" && env == "stg")
+}
+
+func matchPattern(pattern, value string) bool {
+	pattern = strings.ToLower(strings.TrimSpace(pattern))
+	value = strings.ToLower(strings.TrimSpace(value))
+	if pattern == "" || value == "" {
+		return false
+	}
+	ok, err := path.Match(pattern, value)
+	return err == nil && ok
+}
+
 func (c Config) NamespaceForEnv(env string) string {
 	key := strings.ToLower(strings.TrimSpace(env))
 	if key == "" {
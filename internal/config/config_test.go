@@ -3,7 +3,9 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestLoadNormalizesConfig(t *testing.T) {
@@ -64,3 +66,431 @@ func TestSaveAndLoadRoundTrip(t *testing.T) {
 		t.Fatalf("round trip mismatch: got %+v want %+v", loaded, cfg)
 	}
 }
+
+func TestRegionsFor(t *testing.T) {
+	cfg := Default()
+	cfg.SSOStartURL = "https://example.awsapps.com/start"
+	cfg.SSORegion = "us-east-1"
+	cfg.Regions = []string{"us-east-1", "us-west-2", "eu-west-1"}
+	cfg.RegionOverrides = []RegionOverride{
+		{Account: "shared-*", Regions: []string{"us-east-1"}},
+		{Env: "Prod", Regions: []string{"EU-WEST-1", "us-east-1"}},
+	}
+	cfg.Normalize()
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate returned error: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		env         string
+		accountName string
+		want        string
+	}{
+		{name: "env override", env: "prod", accountName: "payments-prod", want: "eu-west-1,us-east-1"},
+		{name: "account pattern first", env: "prod", accountName: "Shared-Services", want: "us-east-1"},
+		{name: "fallback", env: "dev", accountName: "payments-dev", want: "eu-west-1,us-east-1,us-west-2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := strings.Join(cfg.RegionsFor(tt.env, tt.accountName, "111111111111"), ",")
+			if got != tt.want {
+				t.Fatalf("RegionsFor(%q, %q)=%q want %q", tt.env, tt.accountName, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNamespaceFor(t *testing.T) {
+	cfg := Default()
+	cfg.NamespaceDefaults = map[string]string{"prod": "kube-system", "dev": "default"}
+	cfg.NamespaceOverrides = []NamespaceOverride{
+		{Cluster: "*-kafka", Namespace: "kafka"},
+		{Env: "prod", Account: "payments-*", Namespace: "payments"},
+	}
+	cfg.Normalize()
+
+	tests := []struct {
+		name    string
+		env     string
+		account string
+		cluster string
+		want    string
+	}{
+		{name: "cluster pattern", env: "prod", account: "payments-prod", cluster: "events-kafka", want: "kafka"},
+		{name: "account pattern", env: "prod", account: "payments-prod", cluster: "main", want: "payments"},
+		{name: "env default", env: "prod", account: "orders-prod", cluster: "main", want: "kube-system"},
+		{name: "env mismatch", env: "dev", account: "payments-dev", cluster: "main", want: "default"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := cfg.NamespaceFor(tt.env, tt.account, "111111111111", tt.cluster)
+			if got != tt.want {
+				t.Fatalf("NamespaceFor(%q, %q, %q)=%q want %q", tt.env, tt.account, tt.cluster, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSSOSessions(t *testing.T) {
+	cfg := Default()
+	cfg.SSOStartURL = "https://acme.awsapps.com/start"
+	cfg.SSORegion = "us-east-1"
+	cfg.SSOSessions = []SSOSession{{Name: " Corp2 ", StartURL: "https://corp2.awsapps.com/start", Region: "EU-WEST-1"}}
+	cfg.Normalize()
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate returned error: %v", err)
+	}
+
+	sessions := cfg.Sessions()
+	if len(sessions) != 2 || sessions[0].ID() != "rift" || sessions[1].ID() != "rift-corp2" || sessions[1].Region != "eu-west-1" {
+		t.Fatalf("Sessions()=%+v", sessions)
+	}
+	for _, name := range []string{"corp2", "rift-corp2"} {
+		if s, ok := cfg.Session(name); !ok || s.StartURL != "https://corp2.awsapps.com/start" {
+			t.Fatalf("Session(%q)=%+v, %v", name, s, ok)
+		}
+	}
+	if s, ok := cfg.Session("rift"); !ok || s.Name != "" {
+		t.Fatalf("Session(rift)=%+v, %v want primary", s, ok)
+	}
+
+	cfg.SSOSessions = append(cfg.SSOSessions, SSOSession{Name: "corp3", StartURL: cfg.SSOStartURL, Region: "us-east-1"})
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "already configured") {
+		t.Fatalf("Validate err=%v want duplicate start_url error", err)
+	}
+}
+
+func TestValidateNameTemplates(t *testing.T) {
+	cfg := Default()
+	cfg.SSOStartURL = "https://acme.awsapps.com/start"
+	cfg.SSORegion = "us-east-1"
+	cfg.ContextTemplate = "{{.Env}}-{{.ClusterName}}-{{.Region}}"
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate returned error: %v", err)
+	}
+
+	// Profiles are per role, so cluster fields are unavailable.
+	cfg.ProfileTemplate = "{{.Env}}-{{.Cluster}}"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "profile_template") {
+		t.Fatalf("Validate err=%v want profile_template error", err)
+	}
+}
+
+func TestWatchInterval(t *testing.T) {
+	cfg := Default()
+	cfg.SSOStartURL = "https://acme.awsapps.com/start"
+	cfg.SSORegion = "us-east-1"
+	if got := cfg.SyncInterval(); got != DefaultWatchInterval {
+		t.Fatalf("SyncInterval()=%s want %s", got, DefaultWatchInterval)
+	}
+	cfg.WatchInterval = "5m"
+	if err := cfg.Validate(); err != nil || cfg.SyncInterval() != 5*time.Minute {
+		t.Fatalf("Validate err=%v SyncInterval()=%s want 5m", err, cfg.SyncInterval())
+	}
+	cfg.WatchInterval = "10s"
+	if err := cfg.Validate(); err == nil {
+		t.Fatalf("Validate accepted watch_interval below the minimum")
+	}
+}
+
+func TestExpiryWarning(t *testing.T) {
+	cfg := Default()
+	cfg.SSOStartURL = "https://acme.awsapps.com/start"
+	cfg.SSORegion = "us-east-1"
+	if got := cfg.ExpiryWarning(); got != DefaultSSOExpiryWarning {
+		t.Fatalf("ExpiryWarning()=%s want %s", got, DefaultSSOExpiryWarning)
+	}
+	cfg.SSOExpiryWarning = "30m"
+	if err := cfg.Validate(); err != nil || cfg.ExpiryWarning() != 30*time.Minute {
+		t.Fatalf("Validate err=%v ExpiryWarning()=%s want 30m", err, cfg.ExpiryWarning())
+	}
+	cfg.SSOExpiryWarning = "-5m"
+	if err := cfg.Validate(); err == nil {
+		t.Fatalf("Validate accepted a negative sso_expiry_warning")
+	}
+}
+
+func TestAutoRefresh(t *testing.T) {
+	cfg := Default()
+	cfg.SSOStartURL = "https://acme.awsapps.com/start"
+	cfg.SSORegion = "us-east-1"
+	if got := cfg.AutoRefresh(); got != 0 {
+		t.Fatalf("AutoRefresh()=%s want 0 when unset", got)
+	}
+	cfg.UIAutoRefreshDrift = true
+	if err := cfg.Validate(); err == nil {
+		t.Fatalf("Validate accepted ui_auto_refresh_drift without ui_auto_refresh")
+	}
+	cfg.UIAutoRefresh = "5m"
+	if err := cfg.Validate(); err != nil || cfg.AutoRefresh() != 5*time.Minute {
+		t.Fatalf("Validate err=%v AutoRefresh()=%s want 5m", err, cfg.AutoRefresh())
+	}
+	cfg.UIAutoRefresh = "1s"
+	if err := cfg.Validate(); err == nil {
+		t.Fatalf("Validate accepted ui_auto_refresh below %s", MinUIAutoRefresh)
+	}
+}
+
+func TestHealthCheck(t *testing.T) {
+	cfg := Default()
+	cfg.SSOStartURL = "https://acme.awsapps.com/start"
+	cfg.SSORegion = "us-east-1"
+	if got := cfg.HealthCheck(); got != 0 {
+		t.Fatalf("HealthCheck()=%s want 0 when unset", got)
+	}
+	cfg.UIHealthCheck = "2m"
+	if err := cfg.Validate(); err != nil || cfg.HealthCheck() != 2*time.Minute {
+		t.Fatalf("Validate err=%v HealthCheck()=%s want 2m", err, cfg.HealthCheck())
+	}
+	cfg.UIHealthCheck = "5s"
+	if err := cfg.Validate(); err == nil {
+		t.Fatalf("Validate accepted ui_health_check below %s", MinUIHealthCheck)
+	}
+}
+
+func TestRetryPolicy(t *testing.T) {
+	cfg := Default()
+	cfg.SSOStartURL = "https://acme.awsapps.com/start"
+	cfg.SSORegion = "us-east-1"
+	if attempts, backoff := cfg.RetryPolicy(); attempts != DefaultRetryMaxAttempts || backoff != DefaultRetryMaxBackoff {
+		t.Fatalf("RetryPolicy()=%d,%s want defaults", attempts, backoff)
+	}
+	cfg.RetryMaxAttempts = 3
+	cfg.RetryMaxBackoff = "5s"
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if attempts, backoff := cfg.RetryPolicy(); attempts != 3 || backoff != 5*time.Second {
+		t.Fatalf("RetryPolicy()=%d,%s want 3,5s", attempts, backoff)
+	}
+	cfg.RetryMaxBackoff = "soon"
+	if err := cfg.Validate(); err == nil {
+		t.Fatalf("Validate accepted an invalid retry_max_backoff")
+	}
+}
+
+func TestKubeconfigLayout(t *testing.T) {
+	cfg := Default()
+	cfg.SSOStartURL = "https://acme.awsapps.com/start"
+	cfg.SSORegion = "us-east-1"
+	if cfg.Layout() != KubeconfigLayoutMerged || cfg.SeparateKubeconfig() {
+		t.Fatalf("Layout()=%q want merged by default", cfg.Layout())
+	}
+	cfg.KubeconfigLayout = KubeconfigLayoutDedicated
+	if err := cfg.Validate(); err != nil || !cfg.SeparateKubeconfig() {
+		t.Fatalf("Validate err=%v SeparateKubeconfig()=%v want dedicated accepted", err, cfg.SeparateKubeconfig())
+	}
+	cfg.KubeconfigPaths = []string{"~/.kube/config"}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "kubeconfig_paths") {
+		t.Fatalf("Validate err=%v want kubeconfig_paths conflict", err)
+	}
+	cfg.KubeconfigPaths = nil
+	cfg.KubeconfigLayout = "monolithic"
+	if err := cfg.Validate(); err == nil {
+		t.Fatalf("Validate accepted an unknown kubeconfig_layout")
+	}
+	cfg.KubeconfigLayout = ""
+	cfg.KubeconfigExec = KubeconfigExecRift
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate rejected kubeconfig_exec: rift: %v", err)
+	}
+	cfg.KubeconfigExec = "gcloud"
+	if err := cfg.Validate(); err == nil {
+		t.Fatalf("Validate accepted an unknown kubeconfig_exec")
+	}
+}
+
+func TestNamespaceFilterValidation(t *testing.T) {
+	cfg := Default()
+	cfg.SSOStartURL = "https://acme.awsapps.com/start"
+	cfg.SSORegion = "us-east-1"
+	cfg.NamespaceExclude = []string{"kube-*", "cattle-*"}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate rejected namespace_exclude: %v", err)
+	}
+	cfg.NamespaceTTL = "-1h"
+	if err := cfg.Validate(); err == nil {
+		t.Fatalf("Validate accepted a negative namespace_ttl")
+	}
+	cfg.NamespaceTTL = "6h"
+	if err := cfg.Validate(); err != nil || cfg.NamespaceCacheTTL() != 6*time.Hour {
+		t.Fatalf("namespace_ttl 6h: err=%v ttl=%s", err, cfg.NamespaceCacheTTL())
+	}
+	cfg.NamespaceInclude = []string{"team-[a-"}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "namespace_include") {
+		t.Fatalf("Validate err=%v want invalid namespace_include pattern", err)
+	}
+}
+
+func TestThemeValidation(t *testing.T) {
+	cfg := Default()
+	cfg.SSOStartURL = "https://acme.awsapps.com/start"
+	cfg.SSORegion = "us-east-1"
+	cfg.Theme = Theme{Preset: ThemeLight, Accent: "25", SelectedBG: "#005FAF"}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	cfg.Theme.Border = "256"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "theme.border") {
+		t.Fatalf("Validate err=%v want theme.border error", err)
+	}
+	cfg.Theme = Theme{Preset: "neon"}
+	if err := cfg.Validate(); err == nil {
+		t.Fatalf("Validate accepted an unknown theme preset")
+	}
+}
+
+func TestKeybindings(t *testing.T) {
+	cfg := Default()
+	cfg.SSOStartURL = "https://acme.awsapps.com/start"
+	cfg.SSORegion = "us-east-1"
+	cfg.Keybindings = map[string]string{" K9S ": " K "}
+	cfg.Normalize()
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if got := cfg.KeyMap(); got[ActionK9s] != "K" || got[ActionQuit] != "q" {
+		t.Fatalf("KeyMap()=%v want k9s=K and default quit", got)
+	}
+
+	cfg.Keybindings = map[string]string{ActionK9s: "s"}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "k9s and sync") {
+		t.Fatalf("Validate err=%v want conflict with sync", err)
+	}
+	cfg.Keybindings = map[string]string{"launch": "x"}
+	if err := cfg.Validate(); err == nil {
+		t.Fatalf("Validate accepted an unknown action")
+	}
+	cfg.Keybindings = map[string]string{ActionQuit: "ctrl+c"}
+	if err := cfg.Validate(); err == nil {
+		t.Fatalf("Validate accepted a reserved key")
+	}
+}
+
+func TestUIColumns(t *testing.T) {
+	cfg := Default()
+	cfg.SSOStartURL = "https://acme.awsapps.com/start"
+	cfg.SSORegion = "us-east-1"
+	cfg.UIColumns = []string{" Cluster", "profile", "cluster", "version"}
+	cfg.UISortBy = "Version"
+	cfg.Normalize()
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if strings.Join(cfg.UIColumns, ",") != "cluster,profile,version" || cfg.UISortBy != "version" {
+		t.Fatalf("normalized columns=%v sort=%q", cfg.UIColumns, cfg.UISortBy)
+	}
+
+	cfg.UIColumns = []string{"cluster", "size"}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "ui_columns") {
+		t.Fatalf("Validate err=%v want unknown ui_columns", err)
+	}
+	cfg.UIColumns = nil
+	cfg.UISortBy = "size"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "ui_sort_by") {
+		t.Fatalf("Validate err=%v want unknown ui_sort_by", err)
+	}
+}
+
+func TestHooksTimeout(t *testing.T) {
+	cfg := Default()
+	cfg.SSOStartURL = "https://acme.awsapps.com/start"
+	cfg.SSORegion = "us-east-1"
+	cfg.Hooks.PostSync = "./notify.sh"
+	if err := cfg.Validate(); err != nil || cfg.Hooks.HookTimeout() != DefaultHookTimeout {
+		t.Fatalf("default hooks.timeout: err=%v timeout=%s", err, cfg.Hooks.HookTimeout())
+	}
+	cfg.Hooks.Timeout = "0s"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "hooks.timeout") {
+		t.Fatalf("Validate err=%v want non-positive hooks.timeout", err)
+	}
+	cfg.Hooks.Timeout = "10s"
+	if err := cfg.Validate(); err != nil || cfg.Hooks.HookTimeout() != 10*time.Second {
+		t.Fatalf("hooks.timeout 10s: err=%v timeout=%s", err, cfg.Hooks.HookTimeout())
+	}
+}
+
+func TestPluginNames(t *testing.T) {
+	cfg := Default()
+	cfg.SSOStartURL = "https://acme.awsapps.com/start"
+	cfg.SSORegion = "us-east-1"
+	cfg.DiscoveryPlugins = []string{" onprem ", "onprem", "vsphere_dc"}
+	cfg.UIPlugins = []string{"audit"}
+	cfg.Normalize()
+	if err := cfg.Validate(); err != nil || len(cfg.DiscoveryPlugins) != 2 {
+		t.Fatalf("Validate err=%v discovery_plugins=%q", err, cfg.DiscoveryPlugins)
+	}
+	cfg.UIPlugins = []string{"../bin/audit"}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "ui_plugins") {
+		t.Fatalf("Validate err=%v want invalid ui_plugins", err)
+	}
+}
+
+func TestValidateAssumeRoles(t *testing.T) {
+	tests := []struct {
+		name    string
+		role    AssumeRole
+		wantErr string
+	}{
+		{name: "valid", role: AssumeRole{RoleARN: "arn:aws:iam::222222222222:role/Admin", SourceAccount: "111111111111", SourceRole: "Admin"}},
+		{name: "role path", role: AssumeRole{RoleARN: "arn:aws:iam::222222222222:role/platform/Deployer", SourceAccount: "111111111111", SourceRole: "Admin", ExternalID: "rift"}},
+		{name: "not a role", role: AssumeRole{RoleARN: "arn:aws:iam::222222222222:user/bob", SourceAccount: "111111111111", SourceRole: "Admin"}, wantErr: "invalid role_arn"},
+		{name: "not an arn", role: AssumeRole{RoleARN: "Admin", SourceAccount: "111111111111", SourceRole: "Admin"}, wantErr: "invalid role_arn"},
+		{name: "no source role", role: AssumeRole{RoleARN: "arn:aws:iam::222222222222:role/Admin", SourceAccount: "111111111111"}, wantErr: "source_account and source_role"},
+		{name: "no source account", role: AssumeRole{RoleARN: "arn:aws:iam::222222222222:role/Admin", SourceRole: "Admin"}, wantErr: "source_account and source_role"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Default()
+			cfg.SSOStartURL = "https://acme.awsapps.com/start"
+			cfg.SSORegion = "us-east-1"
+			cfg.AssumeRoles = []AssumeRole{tt.role}
+			cfg.Normalize()
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate returned error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Validate err=%v want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSessionLookup(t *testing.T) {
+	cfg := Default()
+	cfg.SSOStartURL = "https://acme.awsapps.com/start"
+	cfg.SSORegion = "us-east-1"
+	cfg.SSOSessions = []SSOSession{
+		{Name: "corp2", StartURL: "https://corp2.awsapps.com/start", Region: "eu-west-1"},
+		{Name: "corp3", StartURL: "https://corp3.awsapps.com/start", Region: "us-west-2"},
+	}
+	cfg.Normalize()
+
+	tests := []struct {
+		name   string
+		lookup string
+		wantID string
+	}{
+		{name: "primary by ID", lookup: "rift", wantID: "rift"},
+		{name: "by name", lookup: "corp2", wantID: "rift-corp2"},
+		{name: "by ID", lookup: "rift-corp3", wantID: "rift-corp3"},
+		{name: "case and space", lookup: " Corp3 ", wantID: "rift-corp3"},
+		{name: "unknown name", lookup: "corp4"},
+		{name: "unknown ID", lookup: "rift-corp4"},
+		// Names are not IDs: the ID of corp2 is never "rift-rift-corp2".
+		{name: "doubled prefix", lookup: "rift-rift-corp2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, ok := cfg.Session(tt.lookup)
+			if ok != (tt.wantID != "") || (ok && s.ID() != tt.wantID) {
+				t.Fatalf("Session(%q)=%+v, %v want ID %q", tt.lookup, s, ok, tt.wantID)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultHookTimeout bounds a hook when hooks.timeout is unset.
+const DefaultHookTimeout = time.Minute
+
+// Hooks are shell commands rift runs around syncs and context switches,
+// each with a JSON payload on stdin. Empty commands are skipped.
+type Hooks struct {
+	// PreSync runs before a sync discovers anything; a failure cancels it.
+	PreSync string `yaml:"pre_sync,omitempty"`
+	// PostSync runs after a sync has written state, with its report.
+	PostSync string `yaml:"post_sync,omitempty"`
+	// PostUse runs after rift switches kubectl to a context, with the
+	// context's record.
+	PostUse string `yaml:"post_use,omitempty"`
+	// Timeout bounds each hook, as a Go duration (default 1m).
+	Timeout string `yaml:"timeout,omitempty"`
+}
+
+func (h *Hooks) normalize() {
+	h.PreSync = strings.TrimSpace(h.PreSync)
+	h.PostSync = strings.TrimSpace(h.PostSync)
+	h.PostUse = strings.TrimSpace(h.PostUse)
+	h.Timeout = strings.TrimSpace(h.Timeout)
+}
+
+func (h Hooks) validate() error {
+	if h.Timeout == "" {
+		return nil
+	}
+	d, err := time.ParseDuration(h.Timeout)
+	if err != nil {
+		return fmt.Errorf("hooks.timeout: %w", err)
+	}
+	if d <= 0 {
+		return fmt.Errorf("hooks.timeout must be positive")
+	}
+	return nil
+}
+
+// HookTimeout is the parsed hooks.timeout, or DefaultHookTimeout.
+func (h Hooks) HookTimeout() time.Duration {
+	if d, err := time.ParseDuration(h.Timeout); err == nil && d > 0 {
+		return d
+	}
+	return DefaultHookTimeout
+}
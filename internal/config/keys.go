@@ -0,0 +1,113 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Rift ui actions (keybindings keys).
+const (
+	ActionSearch    = "search"
+	ActionClear     = "clear"
+	ActionUse       = "use"
+	ActionPrevious  = "previous"
+	ActionK9s       = "k9s"
+	ActionShell     = "shell"
+	ActionMenu      = "menu"
+	ActionCopy      = "copy"
+	ActionConsole   = "console"
+	ActionFavorite  = "favorite"
+	ActionFavorites = "favorites"
+	ActionRecent    = "recent"
+	ActionGraph     = "graph"
+	ActionSort      = "sort"
+	ActionReverse   = "reverse"
+	ActionGroup     = "group"
+	ActionAuth      = "auth"
+	ActionSync      = "sync"
+	ActionRefresh   = "refresh"
+	ActionHealth    = "health"
+	ActionGrow      = "grow"
+	ActionShrink    = "shrink"
+	ActionDetails   = "details"
+	ActionLogs      = "logs"
+	ActionQuit      = "quit"
+)
+
+// Actions lists the rift ui actions in documentation order.
+var Actions = []string{ActionSearch, ActionClear, ActionUse, ActionPrevious, ActionK9s, ActionShell, ActionMenu, ActionCopy, ActionConsole, ActionFavorite, ActionFavorites, ActionRecent, ActionGraph, ActionSort, ActionReverse, ActionGroup, ActionAuth, ActionSync, ActionRefresh, ActionHealth, ActionGrow, ActionShrink, ActionDetails, ActionLogs, ActionQuit}
+
+// DefaultKeybindings are the rift ui keys when keybindings does not
+// override them. Keys use bubbletea's names: "k", "K", "ctrl+k", "enter".
+var DefaultKeybindings = map[string]string{
+	ActionSearch:    "/",
+	ActionClear:     "\\",
+	ActionUse:       "enter",
+	ActionPrevious:  "p",
+	ActionK9s:       "k",
+	ActionShell:     "x",
+	ActionMenu:      "m",
+	ActionCopy:      "y",
+	ActionConsole:   "c",
+	ActionFavorite:  "f",
+	ActionFavorites: "F",
+	ActionRecent:    "R",
+	ActionGraph:     "g",
+	ActionSort:      "o",
+	ActionReverse:   "O",
+	ActionGroup:     "e",
+	ActionAuth:      "a",
+	ActionSync:      "s",
+	ActionRefresh:   "r",
+	ActionHealth:    "h",
+	ActionGrow:      "+",
+	ActionShrink:    "-",
+	ActionDetails:   "|",
+	ActionLogs:      "L",
+	ActionQuit:      "q",
+}
+
+// reservedKeys always do the same thing in rift ui: ctrl+c quits, esc
+// closes, and the arrows move.
+var reservedKeys = []string{"ctrl+c", "esc", "up", "down", "left", "right"}
+
+// KeyMap returns every action's key: DefaultKeybindings with the
+// configured keybindings laid over it.
+func (c Config) KeyMap() map[string]string {
+	out := make(map[string]string, len(DefaultKeybindings))
+	for action, key := range DefaultKeybindings {
+		out[action] = key
+	}
+	for action, key := range c.Keybindings {
+		out[action] = key
+	}
+	return out
+}
+
+func (c Config) validateKeybindings() error {
+	for action, key := range c.Keybindings {
+		if _, ok := DefaultKeybindings[action]; !ok {
+			return fmt.Errorf("keybindings: unknown action %q (expected %s)", action, strings.Join(Actions, ", "))
+		}
+		if key == "" {
+			return fmt.Errorf("keybindings.%s: missing key", action)
+		}
+		for _, reserved := range reservedKeys {
+			if key == reserved {
+				return fmt.Errorf("keybindings.%s: %q is reserved", action, key)
+			}
+		}
+	}
+	owners := map[string][]string{}
+	for action, key := range c.KeyMap() {
+		owners[key] = append(owners[key], action)
+	}
+	for key, actions := range owners {
+		if len(actions) > 1 {
+			sort.Strings(actions)
+			return fmt.Errorf("keybindings: %q is bound to %s", key, strings.Join(actions, " and "))
+		}
+	}
+	return nil
+}
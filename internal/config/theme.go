@@ -0,0 +1,78 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// Theme presets (theme.preset).
+const (
+	ThemeDefault      = "default"
+	ThemeHighContrast = "high-contrast"
+	ThemeLight        = "light"
+)
+
+// ThemePresets lists the preset names in documentation order.
+var ThemePresets = []string{ThemeDefault, ThemeHighContrast, ThemeLight}
+
+var hexColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// Theme sets the rift ui colors. Preset picks a palette (ThemeDefault when
+// empty); each color overrides one role of it and is an ANSI 256 color
+// number ("81") or a hex color ("#5fd7ff").
+type Theme struct {
+	Preset string `yaml:"preset,omitempty"`
+	// Accent colors titles, the RIFT logo, hotkeys, and the search, modal,
+	// and picker borders.
+	Accent string `yaml:"accent,omitempty"`
+	// Header colors the table's column headers.
+	Header string `yaml:"header,omitempty"`
+	// SelectedFG and SelectedBG color the selected table, tree, or picker
+	// row.
+	SelectedFG string `yaml:"selected_fg,omitempty"`
+	SelectedBG string `yaml:"selected_bg,omitempty"`
+	// Border colors the table and details pane borders.
+	Border string `yaml:"border,omitempty"`
+	// Status colors the status line and spinner.
+	Status string `yaml:"status,omitempty"`
+	// Muted colors hints, labels, and the version.
+	Muted string `yaml:"muted,omitempty"`
+	// Highlight colors search matches in the table.
+	Highlight string `yaml:"highlight,omitempty"`
+	// Error colors errors in the search box and setup wizard.
+	Error string `yaml:"error,omitempty"`
+}
+
+// Colors returns the theme's color fields keyed by their YAML names.
+func (t Theme) Colors() map[string]string {
+	return map[string]string{
+		"accent":      t.Accent,
+		"header":      t.Header,
+		"selected_fg": t.SelectedFG,
+		"selected_bg": t.SelectedBG,
+		"border":      t.Border,
+		"status":      t.Status,
+		"muted":       t.Muted,
+		"highlight":   t.Highlight,
+		"error":       t.Error,
+	}
+}
+
+func (t Theme) validate() error {
+	switch t.Preset {
+	case "", ThemeDefault, ThemeHighContrast, ThemeLight:
+	default:
+		return fmt.Errorf("theme.preset must be %s, %s, or %s", ThemeDefault, ThemeHighContrast, ThemeLight)
+	}
+	for key, color := range t.Colors() {
+		if color == "" || hexColorPattern.MatchString(color) {
+			continue
+		}
+		if n, err := strconv.Atoi(color); err == nil && n >= 0 && n <= 255 {
+			continue
+		}
+		return fmt.Errorf("theme.%s: %q is not an ANSI color number (0-255) or #rrggbb", key, color)
+	}
+	return nil
+}
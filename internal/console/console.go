@@ -0,0 +1,50 @@
+// Package console builds AWS Management Console links for clusters.
+package console
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/phenixrizen/rift/internal/state"
+)
+
+// consoleHosts maps AWS partitions to their console host.
+var consoleHosts = map[string]string{
+	"aws":        "console.aws.amazon.com",
+	"aws-us-gov": "console.amazonaws-us-gov.com",
+	"aws-cn":     "console.amazonaws.cn",
+}
+
+// ClusterURL returns the EKS console page of rec in its region, or false
+// when rec is not an AWS cluster.
+func ClusterURL(rec state.ClusterRecord) (string, bool) {
+	if !rec.IsAWS() || rec.ClusterName == "" || rec.Region == "" {
+		return "", false
+	}
+	host := consoleHosts[Partition(rec)]
+	if host == "" {
+		host = consoleHosts["aws"]
+	}
+	return "https://" + host + "/eks/home?region=" + url.QueryEscape(rec.Region) + "#/clusters/" + url.PathEscape(rec.ClusterName), true
+}
+
+// Partition is the AWS partition of rec's cluster ARN, or of its region
+// when the ARN does not say.
+func Partition(rec state.ClusterRecord) string {
+	parts := strings.SplitN(rec.ClusterARN, ":", 3)
+	if len(parts) == 3 && parts[0] == "arn" && parts[1] != "" {
+		return parts[1]
+	}
+	return RegionPartition(rec.Region)
+}
+
+// RegionPartition is the AWS partition region is in.
+func RegionPartition(region string) string {
+	switch {
+	case strings.HasPrefix(region, "us-gov-"):
+		return "aws-us-gov"
+	case strings.HasPrefix(region, "cn-"):
+		return "aws-cn"
+	}
+	return "aws"
+}
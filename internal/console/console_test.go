@@ -0,0 +1,65 @@
+package console
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/phenixrizen/rift/internal/state"
+)
+
+func TestClusterURL(t *testing.T) {
+	rec := state.ClusterRecord{ClusterName: "pay ments", Region: "us-west-2", ClusterARN: "arn:aws:eks:us-west-2:111111111111:cluster/pay ments"}
+	got, ok := ClusterURL(rec)
+	if want := "https://console.aws.amazon.com/eks/home?region=us-west-2#/clusters/pay%20ments"; !ok || got != want {
+		t.Fatalf("ClusterURL = %q, %v want %q", got, ok, want)
+	}
+
+	rec.ClusterARN = "arn:aws-us-gov:eks:us-gov-west-1:111111111111:cluster/main"
+	rec.Region = "us-gov-west-1"
+	if got, _ := ClusterURL(rec); got != "https://console.amazonaws-us-gov.com/eks/home?region=us-gov-west-1#/clusters/pay%20ments" {
+		t.Fatalf("gov ClusterURL = %q", got)
+	}
+
+	if _, ok := ClusterURL(state.ClusterRecord{ClusterName: "gke", Region: "us-central1", Platform: state.PlatformGKE}); ok {
+		t.Fatal("ClusterURL linked a GKE cluster")
+	}
+}
+
+func TestSigninURL(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("Action") != "getSigninToken" {
+			t.Errorf("Action = %q", q.Get("Action"))
+		}
+		var session map[string]string
+		if err := json.Unmarshal([]byte(q.Get("Session")), &session); err != nil || session["sessionToken"] != "TOKEN" {
+			t.Errorf("Session = %q (%v)", q.Get("Session"), err)
+		}
+		_, _ = w.Write([]byte(`{"SigninToken":"abc"}`))
+	}))
+	defer srv.Close()
+
+	creds := aws.Credentials{AccessKeyID: "AKID", SecretAccessKey: "SECRET", SessionToken: "TOKEN"}
+	dest := "https://console.aws.amazon.com/eks/home?region=us-west-2#/clusters/main"
+	got, err := signinURL(context.Background(), srv.Client(), srv.URL+"/federation", creds, dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	link, err := url.Parse(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := link.Query()
+	if q.Get("Action") != "login" || q.Get("SigninToken") != "abc" || q.Get("Destination") != dest {
+		t.Fatalf("login URL = %s", got)
+	}
+
+	if _, err := signinURL(context.Background(), srv.Client(), srv.URL+"/federation", aws.Credentials{AccessKeyID: "AKID"}, dest); err == nil {
+		t.Fatal("signinURL accepted long-term credentials")
+	}
+}
@@ -0,0 +1,95 @@
+package console
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// signinHosts maps AWS partitions to their federation endpoint host.
+var signinHosts = map[string]string{
+	"aws":        "signin.aws.amazon.com",
+	"aws-us-gov": "signin.amazonaws-us-gov.com",
+	"aws-cn":     "signin.amazonaws.cn",
+}
+
+// issuer is the Issuer the console links back to when the session ends.
+const issuer = "https://github.com/phenixrizen/rift"
+
+// HomeURL is the console home page of region in partition.
+func HomeURL(partition, region string) string {
+	host := consoleHosts[partition]
+	if host == "" {
+		host = consoleHosts["aws"]
+	}
+	link := "https://" + host + "/console/home"
+	if region != "" {
+		link += "?region=" + url.QueryEscape(region)
+	}
+	return link
+}
+
+// SigninURL exchanges role credentials for a console sign-in token at the
+// partition's federation endpoint and returns a link that signs in as the
+// role and lands on destination. The link is only good for 15 minutes.
+func SigninURL(ctx context.Context, creds aws.Credentials, partition, destination string) (string, error) {
+	host := signinHosts[partition]
+	if host == "" {
+		host = signinHosts["aws"]
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	return signinURL(ctx, client, "https://"+host+"/federation", creds, destination)
+}
+
+func signinURL(ctx context.Context, client *http.Client, endpoint string, creds aws.Credentials, destination string) (string, error) {
+	if creds.SessionToken == "" {
+		return "", fmt.Errorf("console sign-in needs temporary (session) credentials")
+	}
+	session, err := json.Marshal(map[string]string{
+		"sessionId":    creds.AccessKeyID,
+		"sessionKey":   creds.SecretAccessKey,
+		"sessionToken": creds.SessionToken,
+	})
+	if err != nil {
+		return "", err
+	}
+	query := url.Values{"Action": {"getSigninToken"}, "Session": {string(session)}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"?"+query.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("federation endpoint returned %s", http.StatusText(resp.StatusCode))
+	}
+	var parsed struct {
+		SigninToken string `json:"SigninToken"`
+	}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return "", fmt.Errorf("decode sign-in token: %w", err)
+	}
+	if parsed.SigninToken == "" {
+		return "", fmt.Errorf("federation endpoint returned no sign-in token")
+	}
+	login := url.Values{
+		"Action":      {"login"},
+		"Issuer":      {issuer},
+		"Destination": {destination},
+		"SigninToken": {parsed.SigninToken},
+	}
+	return endpoint + "?" + login.Encode(), nil
+}
@@ -0,0 +1,91 @@
+package discovery
+
+import (
+	"context"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eks"
+	"github.com/phenixrizen/rift/internal/state"
+)
+
+// describeCompute fills record's node groups and Fargate profiles. A failed
+// listing is recorded and leaves that part empty.
+func (s *scanner) describeCompute(ctx context.Context, client *eks.Client, role RoleAccess, record *ClusterAccess) {
+	nodegroups, err := listNodegroups(ctx, client, record.ClusterName)
+	if err != nil {
+		f := roleFailure(OpListNodegroups, role, record.Region)
+		f.Cluster = record.ClusterName
+		s.fail(f, err)
+	}
+	record.Nodegroups = nodegroups
+
+	profiles, err := listFargateProfiles(ctx, client, record.ClusterName)
+	if err != nil {
+		f := roleFailure(OpListFargate, role, record.Region)
+		f.Cluster = record.ClusterName
+		s.fail(f, err)
+	}
+	record.FargateProfiles = profiles
+}
+
+func listNodegroups(ctx context.Context, client *eks.Client, cluster string) ([]state.Nodegroup, error) {
+	var out []state.Nodegroup
+	pages := eks.NewListNodegroupsPaginator(client, &eks.ListNodegroupsInput{ClusterName: aws.String(cluster)})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return out, err
+		}
+		for _, name := range page.Nodegroups {
+			desc, err := client.DescribeNodegroup(ctx, &eks.DescribeNodegroupInput{ClusterName: aws.String(cluster), NodegroupName: aws.String(name)})
+			if err != nil {
+				return out, err
+			}
+			ng := state.Nodegroup{Name: name}
+			if n := desc.Nodegroup; n != nil {
+				ng.Status = string(n.Status)
+				ng.KubernetesVersion = aws.ToString(n.Version)
+				ng.InstanceTypes = n.InstanceTypes
+				ng.CapacityType = string(n.CapacityType)
+				if sc := n.ScalingConfig; sc != nil {
+					ng.MinSize = int(aws.ToInt32(sc.MinSize))
+					ng.MaxSize = int(aws.ToInt32(sc.MaxSize))
+					ng.DesiredSize = int(aws.ToInt32(sc.DesiredSize))
+				}
+			}
+			out = append(out, ng)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+func listFargateProfiles(ctx context.Context, client *eks.Client, cluster string) ([]state.FargateProfile, error) {
+	var out []state.FargateProfile
+	pages := eks.NewListFargateProfilesPaginator(client, &eks.ListFargateProfilesInput{ClusterName: aws.String(cluster)})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return out, err
+		}
+		for _, name := range page.FargateProfileNames {
+			desc, err := client.DescribeFargateProfile(ctx, &eks.DescribeFargateProfileInput{ClusterName: aws.String(cluster), FargateProfileName: aws.String(name)})
+			if err != nil {
+				return out, err
+			}
+			fp := state.FargateProfile{Name: name}
+			if p := desc.FargateProfile; p != nil {
+				fp.Status = string(p.Status)
+				for _, sel := range p.Selectors {
+					if ns := aws.ToString(sel.Namespace); ns != "" {
+						fp.Namespaces = append(fp.Namespaces, ns)
+					}
+				}
+			}
+			out = append(out, fp)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
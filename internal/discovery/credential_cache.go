@@ -0,0 +1,153 @@
+package discovery
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/phenixrizen/rift/internal/fileutil"
+	"github.com/phenixrizen/rift/internal/state"
+)
+
+// credentialMinRemaining is how long cached credentials must still be valid
+// to be handed out, so callers never start work with nearly expired keys.
+const credentialMinRemaining = 5 * time.Minute
+
+// RoleKey identifies a role's credentials in a CredentialCache.
+type RoleKey struct {
+	SSOSession    string
+	AccountID     string
+	RoleName      string
+	AssumeRoleARN string
+}
+
+// Key returns the cache key of r.
+func (r RoleAccess) Key() RoleKey {
+	return RoleKey{
+		SSOSession:    sessionOrDefault(r.SSOSession),
+		AccountID:     r.AccountID,
+		RoleName:      r.RoleName,
+		AssumeRoleARN: r.AssumeRoleARN,
+	}
+}
+
+// KeyForRole returns the cache key of a role recorded in state.
+func KeyForRole(r state.RoleRecord) RoleKey {
+	return RoleKey{
+		SSOSession:    sessionOrDefault(r.SSOSession),
+		AccountID:     r.AccountID,
+		RoleName:      r.RoleName,
+		AssumeRoleARN: r.AssumeRoleARN,
+	}
+}
+
+func (k RoleKey) String() string {
+	return strings.Join([]string{k.SSOSession, k.AccountID, k.RoleName, k.AssumeRoleARN}, "|")
+}
+
+// CredentialCache keeps role credentials for reuse: in memory for the life
+// of the process and, when created with a directory, on disk between runs.
+// A nil *CredentialCache caches nothing.
+type CredentialCache struct {
+	mu      sync.Mutex
+	dir     string
+	entries map[RoleKey]aws.Credentials
+}
+
+// NewCredentialCache returns an empty cache. An empty dir keeps entries in
+// memory only.
+func NewCredentialCache(dir string) *CredentialCache {
+	return &CredentialCache{dir: dir, entries: map[RoleKey]aws.Credentials{}}
+}
+
+type cachedCredentials struct {
+	AccessKeyID     string    `json:"access_key_id"`
+	SecretAccessKey string    `json:"secret_access_key"`
+	SessionToken    string    `json:"session_token"`
+	Expiration      time.Time `json:"expiration"`
+}
+
+// Get returns unexpired credentials for key from memory, then disk.
+func (c *CredentialCache) Get(key RoleKey) (aws.Credentials, bool) {
+	return c.get(key, time.Now())
+}
+
+func (c *CredentialCache) get(key RoleKey, now time.Time) (aws.Credentials, bool) {
+	if c == nil {
+		return aws.Credentials{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if creds, ok := c.entries[key]; ok && usable(creds, now) {
+		return creds, true
+	}
+	if c.dir == "" {
+		return aws.Credentials{}, false
+	}
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return aws.Credentials{}, false
+	}
+	var cached cachedCredentials
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return aws.Credentials{}, false
+	}
+	creds := aws.Credentials{
+		AccessKeyID:     cached.AccessKeyID,
+		SecretAccessKey: cached.SecretAccessKey,
+		SessionToken:    cached.SessionToken,
+		Source:          credentials.StaticCredentialsName,
+		CanExpire:       true,
+		Expires:         cached.Expiration,
+	}
+	if !usable(creds, now) {
+		_ = os.Remove(c.path(key))
+		return aws.Credentials{}, false
+	}
+	c.entries[key] = creds
+	return creds, true
+}
+
+// Put stores creds under key. Credentials without an expiry are not
+// cached. Writing the disk entry is best effort: a failure only costs a
+// GetRoleCredentials call on the next run.
+func (c *CredentialCache) Put(key RoleKey, creds aws.Credentials) {
+	if c == nil || !creds.CanExpire {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = creds
+	if c.dir == "" {
+		return
+	}
+	data, err := json.Marshal(cachedCredentials{
+		AccessKeyID:     creds.AccessKeyID,
+		SecretAccessKey: creds.SecretAccessKey,
+		SessionToken:    creds.SessionToken,
+		Expiration:      creds.Expires.UTC(),
+	})
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(c.dir, 0o700); err != nil {
+		return
+	}
+	_ = fileutil.WriteAtomic(c.path(key), data, 0o600)
+}
+
+func (c *CredentialCache) path(key RoleKey) string {
+	sum := sha256.Sum256([]byte(key.String()))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+func usable(creds aws.Credentials, now time.Time) bool {
+	return creds.HasKeys() && (!creds.CanExpire || creds.Expires.After(now.Add(credentialMinRemaining)))
+}
@@ -0,0 +1,58 @@
+package discovery
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/phenixrizen/rift/internal/state"
+)
+
+func TestCredentialCacheReloadsFromDiskUntilExpiry(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	key := RoleAccess{AccountID: "111111111111", RoleName: "Admin"}.Key()
+	creds := aws.Credentials{AccessKeyID: "AKIA", SecretAccessKey: "secret", SessionToken: "token", CanExpire: true, Expires: now.Add(time.Hour)}
+
+	NewCredentialCache(dir).Put(key, creds)
+
+	// A new cache (the next run) finds the entry on disk.
+	got, ok := NewCredentialCache(dir).get(key, now)
+	if !ok || got.AccessKeyID != "AKIA" || !got.Expires.Equal(creds.Expires) {
+		t.Fatalf("get() = %+v, %v; want cached credentials", got, ok)
+	}
+	if _, ok := NewCredentialCache(dir).get(key, now.Add(56*time.Minute)); ok {
+		t.Fatalf("get() returned credentials expiring within credentialMinRemaining")
+	}
+	if _, ok := NewCredentialCache("").get(key, now); ok {
+		t.Fatalf("memory-only cache shared entries across instances")
+	}
+	var none *CredentialCache
+	none.Put(key, creds)
+	if _, ok := none.Get(key); ok {
+		t.Fatalf("nil cache returned credentials")
+	}
+}
+
+func TestCredentialCacheKeepsSessionsApart(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	primary := RoleAccess{AccountID: "111111111111", RoleName: "Admin"}
+	corp2 := RoleAccess{AccountID: "111111111111", RoleName: "Admin", SSOSession: "rift-corp2"}
+	if primary.Key() == corp2.Key() {
+		t.Fatalf("sessions share the cache key %s", primary.Key())
+	}
+	// A role recorded in state without a session is the primary one.
+	if KeyForRole(state.RoleRecord{AccountID: "111111111111", RoleName: "Admin"}) != (RoleAccess{AccountID: "111111111111", RoleName: "Admin", SSOSession: "rift"}).Key() {
+		t.Fatalf("empty session does not key as the primary session")
+	}
+
+	cache := NewCredentialCache(dir)
+	cache.Put(primary.Key(), aws.Credentials{AccessKeyID: "PRIMARY", SecretAccessKey: "secret", CanExpire: true, Expires: now.Add(time.Hour)})
+	cache.Put(corp2.Key(), aws.Credentials{AccessKeyID: "CORP2", SecretAccessKey: "secret", CanExpire: true, Expires: now.Add(time.Hour)})
+	for role, want := range map[RoleAccess]string{primary: "PRIMARY", corp2: "CORP2"} {
+		if got, ok := NewCredentialCache(dir).get(role.Key(), now); !ok || got.AccessKeyID != want {
+			t.Fatalf("get(%s) = %+v, %v; want %s", role.Key(), got, ok, want)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sso"
+	"github.com/phenixrizen/rift/internal/config"
+	"github.com/phenixrizen/rift/internal/state"
+)
+
+// RoleCredentials returns temporary credentials for a role in st from cache,
+// or fetches them using the cached SSO token of the role's session and
+// stores them in cache (which may be nil). Chained roles are assumed from
+// their source profile, which must also be in st.
+func RoleCredentials(ctx context.Context, cfg config.Config, st state.State, role state.RoleRecord, cache *CredentialCache) (aws.Credentials, error) {
+	if creds, ok := cache.Get(KeyForRole(role)); ok {
+		return creds, nil
+	}
+	session, ok := cfg.Session(role.SSOSession)
+	if !ok {
+		return aws.Credentials{}, fmt.Errorf("sso session %q of profile %s is not configured", role.SSOSession, role.AWSProfile)
+	}
+	token, err := loadTokenFromCache(session.StartURL, session.Region, time.Now().UTC())
+	if err != nil {
+		return aws.Credentials{}, sessionError(session, err)
+	}
+
+	access := RoleAccess{
+		AccountID:     role.AccountID,
+		AccountName:   role.AccountName,
+		RoleName:      role.RoleName,
+		AssumeRoleARN: role.AssumeRoleARN,
+		ExternalID:    role.ExternalID,
+		SSOSession:    role.SSOSession,
+	}
+	if role.AssumeRoleARN != "" {
+		source, ok := findProfile(st, role.SourceProfile)
+		if !ok {
+			return aws.Credentials{}, fmt.Errorf("source profile %s of %s not found in state", role.SourceProfile, role.AWSProfile)
+		}
+		access.SourceAccountID = source.AccountID
+		access.SourceRoleName = source.RoleName
+	}
+
+	scan := &scanner{retryer: newRetryer(cfg), creds: cache}
+	client := sso.New(sso.Options{Region: session.Region, Retryer: scan.retryer()})
+	return scan.roleCredentials(ctx, client, token.AccessToken, access)
+}
+
+func findProfile(st state.State, profile string) (state.RoleRecord, bool) {
+	for _, role := range st.Roles {
+		if role.AWSProfile == profile {
+			return role, true
+		}
+	}
+	return state.RoleRecord{}, false
+}
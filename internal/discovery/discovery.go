@@ -5,15 +5,19 @@ import (
 	"fmt"
 	"log/slog"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
 	"github.com/aws/aws-sdk-go-v2/service/eks"
 	eksTypes "github.com/aws/aws-sdk-go-v2/service/eks/types"
 	"github.com/aws/aws-sdk-go-v2/service/sso"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
 	"github.com/phenixrizen/rift/internal/config"
+	"github.com/phenixrizen/rift/internal/state"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -21,6 +25,14 @@ type RoleAccess struct {
 	AccountID   string
 	AccountName string
 	RoleName    string
+	// AssumeRoleARN is set for roles reached by chaining from the SSO role
+	// SourceAccountID/SourceRoleName rather than granted directly by SSO.
+	AssumeRoleARN   string
+	ExternalID      string
+	SourceAccountID string
+	SourceRoleName  string
+	// SSOSession is the config.SSOSession ID the role came from.
+	SSOSession string
 }
 
 type ClusterAccess struct {
@@ -32,42 +44,166 @@ type ClusterAccess struct {
 	ClusterARN               string
 	ClusterEndpoint          string
 	ClusterCertificateBase64 string
+	ClusterID                string
+	// Platform is "" for regular EKS, state.PlatformOutpost for local
+	// clusters on Outposts, or state.PlatformConnected for EKS Connector
+	// registrations (EKS Anywhere and other external clusters).
+	// Other discoverers set their own platform, and a discovery plugin's
+	// clusters name the plugin in Plugin.
+	Platform          string
+	ConnectorProvider string
+	Plugin            string
+	SSOSession        string
+	// AssumeRoleARN is the chained role (assume_roles) the cluster was
+	// found through; empty when the SSO role itself found it.
+	AssumeRoleARN string
+	// Metadata from DescribeCluster.
+	KubernetesVersion string
+	Status            string
+	PlatformVersion   string
+	CreatedAt         time.Time
+	AWSTags           map[string]string
+	// Set only with Options.Compute.
+	Nodegroups      []state.Nodegroup
+	FargateProfiles []state.FargateProfile
+}
+
+// Options tunes a discovery run. The zero value scans cfg.Regions for
+// every role.
+type Options struct {
+	// RegionsFor returns the regions to scan for a role.
+	RegionsFor func(RoleAccess) []string
+	// Compute also lists each cluster's managed node groups and Fargate
+	// profiles.
+	Compute bool
+	// DiscoverRegions, when set and true for a role, scans every region
+	// enabled in the role's account (account:ListRegions) instead of
+	// RegionsFor, which remains the fallback if the lookup fails.
+	DiscoverRegions func(RoleAccess) bool
+	// Previous enables incremental discovery; see Previous.
+	Previous *Previous
+	// Progress, when set, receives stage counters as discovery advances. It
+	// is called from several goroutines, one call at a time.
+	Progress func(Progress)
+	// Credentials, when set, is checked before and filled after every role
+	// credential lookup so later calls (namespace discovery, the next sync)
+	// can skip GetRoleCredentials.
+	Credentials *CredentialCache
 }
 
 type Inventory struct {
 	GeneratedAt time.Time
 	Roles       []RoleAccess
 	Clusters    []ClusterAccess
+	// ReusedAccounts counts accounts whose clusters came from
+	// Options.Previous instead of being listed.
+	ReusedAccounts int
+	// Failures lists calls that failed after retries, sorted.
+	Failures []Failure
+}
+
+// describeConcurrency bounds concurrent DescribeCluster calls per role and
+// region; up to eight roles are scanned at once on top of that.
+const describeConcurrency = 8
+
+type sessionClient struct {
+	client      *sso.Client
+	accessToken string
+}
+
+// scanner holds what the AWS calls of one discovery run share.
+type scanner struct {
+	retryer  func() aws.Retryer
+	logger   *slog.Logger
+	prog     *progress
+	failures *failureLog
+	creds    *CredentialCache
+	// discover and regions implement Options.DiscoverRegions.
+	discover func(RoleAccess) bool
+	regions  regionLookup
+	compute  bool
+}
+
+func (s *scanner) fail(f Failure, err error) {
+	if s.failures != nil {
+		s.failures.add(f, err)
+	}
 }
 
-func Discover(ctx context.Context, cfg config.Config, logger *slog.Logger) (Inventory, error) {
+// Discover lists roles and clusters across every configured SSO session.
+// All sessions must be logged in, so a sync never drops another
+// organization's entries because its token expired.
+func Discover(ctx context.Context, cfg config.Config, opts Options, logger *slog.Logger) (Inventory, error) {
 	now := time.Now().UTC()
-	token, err := loadTokenFromCache(cfg.SSOStartURL, cfg.SSORegion, now)
-	if err != nil {
+	if err := ValidateSSOLogin(cfg, now); err != nil {
 		return Inventory{}, err
 	}
 
-	ssoClient := sso.New(sso.Options{Region: cfg.SSORegion})
-	accounts, err := listAccounts(ctx, ssoClient, token.AccessToken)
-	if err != nil {
-		return Inventory{}, fmt.Errorf("list accounts: %w", err)
+	scan := &scanner{
+		retryer:  newRetryer(cfg),
+		logger:   logger,
+		prog:     newProgress(opts.Progress),
+		failures: &failureLog{},
+		creds:    opts.Credentials,
+		discover: opts.DiscoverRegions,
+		compute:  opts.Compute,
 	}
+	sessions := cfg.Sessions()
+	clients := make(map[string]sessionClient, len(sessions))
+	roles := make([]RoleAccess, 0)
+	for _, session := range sessions {
+		token, err := loadTokenFromCache(session.StartURL, session.Region, now)
+		if err != nil {
+			return Inventory{}, sessionError(session, err)
+		}
+		ssoClient := sso.New(sso.Options{Region: session.Region, Retryer: scan.retryer()})
+		clients[session.ID()] = sessionClient{client: ssoClient, accessToken: token.AccessToken}
 
-	roles, err := listRoles(ctx, ssoClient, token.AccessToken, accounts, logger)
-	if err != nil {
-		return Inventory{}, fmt.Errorf("list account roles: %w", err)
+		accounts, err := listAccounts(ctx, ssoClient, token.AccessToken)
+		if err != nil {
+			return Inventory{}, sessionError(session, fmt.Errorf("list accounts: %w", err))
+		}
+		scan.prog.add(StageAccounts, len(accounts), len(accounts))
+		scan.prog.add(StageRoles, 0, len(accounts))
+		sessionRoles, err := scan.listRoles(ctx, ssoClient, token.AccessToken, session.ID(), accounts)
+		if err != nil {
+			return Inventory{}, sessionError(session, fmt.Errorf("list account roles: %w", err))
+		}
+		for i := range sessionRoles {
+			sessionRoles[i].SSOSession = session.ID()
+		}
+		roles = append(roles, sessionRoles...)
 	}
+	roles = append(roles, assumedRoles(cfg, roles, logger)...)
 
 	inv := Inventory{
 		GeneratedAt: now,
 		Roles:       roles,
 	}
 
-	clusters, err := listAllClusters(ctx, ssoClient, token.AccessToken, cfg.Regions, roles, logger)
-	if err != nil {
-		return Inventory{}, fmt.Errorf("list clusters: %w", err)
+	regionsFor := opts.RegionsFor
+	if regionsFor == nil {
+		regionsFor = func(RoleAccess) []string { return cfg.Regions }
+	}
+	reuse := opts.Previous.reusable(roles)
+	if len(reuse) > 0 {
+		inv.ReusedAccounts = len(reuse)
+		inv.Clusters = append(inv.Clusters, opts.Previous.clusters(reuse)...)
+	}
+	for _, session := range sessions {
+		sessionRoles := make([]RoleAccess, 0)
+		for _, role := range roles {
+			if role.SSOSession == session.ID() && !reuse[accountKey(role.SSOSession, role.AccountID)] {
+				sessionRoles = append(sessionRoles, role)
+			}
+		}
+		c := clients[session.ID()]
+		clusters, err := scan.listAllClusters(ctx, c.client, c.accessToken, regionsFor, sessionRoles)
+		if err != nil {
+			return Inventory{}, sessionError(session, fmt.Errorf("list clusters: %w", err))
+		}
+		inv.Clusters = append(inv.Clusters, clusters...)
 	}
-	inv.Clusters = clusters
 
 	sort.Slice(inv.Roles, func(i, j int) bool {
 		left := inv.Roles[i].AccountName + "|" + inv.Roles[i].RoleName
@@ -79,13 +215,29 @@ func Discover(ctx context.Context, cfg config.Config, logger *slog.Logger) (Inve
 		right := inv.Clusters[j].AccountName + "|" + inv.Clusters[j].RoleName + "|" + inv.Clusters[j].Region + "|" + inv.Clusters[j].ClusterName
 		return left < right
 	})
+	inv.Failures = scan.failures.sorted()
 
 	return inv, nil
 }
 
+// ValidateSSOLogin checks that every configured SSO session has a valid
+// cached token.
 func ValidateSSOLogin(cfg config.Config, now time.Time) error {
-	_, err := loadTokenFromCache(cfg.SSOStartURL, cfg.SSORegion, now)
-	return err
+	for _, session := range cfg.Sessions() {
+		if _, err := loadTokenFromCache(session.StartURL, session.Region, now); err != nil {
+			return sessionError(session, err)
+		}
+	}
+	return nil
+}
+
+// sessionError names the SSO session in err unless it is the only (primary)
+// one, keeping single-organization messages unchanged.
+func sessionError(session config.SSOSession, err error) error {
+	if session.Name == "" {
+		return err
+	}
+	return fmt.Errorf("sso session %s: %w", session.ID(), err)
 }
 
 type account struct {
@@ -115,7 +267,7 @@ func listAccounts(ctx context.Context, client *sso.Client, accessToken string) (
 	return accounts, nil
 }
 
-func listRoles(ctx context.Context, client *sso.Client, accessToken string, accounts []account, logger *slog.Logger) ([]RoleAccess, error) {
+func (s *scanner) listRoles(ctx context.Context, client *sso.Client, accessToken, session string, accounts []account) ([]RoleAccess, error) {
 	roles := make([]RoleAccess, 0)
 	for _, acct := range accounts {
 		input := &sso.ListAccountRolesInput{
@@ -125,9 +277,10 @@ func listRoles(ctx context.Context, client *sso.Client, accessToken string, acco
 		for {
 			out, err := client.ListAccountRoles(ctx, input)
 			if err != nil {
-				if logger != nil {
-					logger.Warn("unable to list account roles", "account_id", acct.ID, "account", acct.Name, "error", err)
+				if s.logger != nil {
+					s.logger.Warn("unable to list account roles", "account_id", acct.ID, "account", acct.Name, "error", err)
 				}
+				s.fail(Failure{Op: OpListRoles, SSOSession: session, AccountID: acct.ID, AccountName: acct.Name}, err)
 				break
 			}
 			for _, role := range out.RoleList {
@@ -142,21 +295,26 @@ func listRoles(ctx context.Context, client *sso.Client, accessToken string, acco
 			}
 			input.NextToken = out.NextToken
 		}
+		s.prog.add(StageRoles, 1, 0)
 	}
 	return roles, nil
 }
 
-func listAllClusters(
+func (s *scanner) listAllClusters(
 	ctx context.Context,
 	ssoClient *sso.Client,
 	accessToken string,
-	regions []string,
+	regionsFor func(RoleAccess) []string,
 	roles []RoleAccess,
-	logger *slog.Logger,
 ) ([]ClusterAccess, error) {
 	if len(roles) == 0 {
 		return nil, nil
 	}
+	scans := 0
+	for _, role := range roles {
+		scans += len(regionsFor(role))
+	}
+	s.prog.add(StageRegions, 0, scans)
 
 	var (
 		mu       sync.Mutex
@@ -169,21 +327,38 @@ func listAllClusters(
 	for _, role := range roles {
 		role := role
 		g.Go(func() error {
-			creds, err := getRoleCredentials(ctx, ssoClient, accessToken, role.AccountID, role.RoleName)
+			regions := regionsFor(role)
+			creds, err := s.roleCredentials(ctx, ssoClient, accessToken, role)
 			if err != nil {
-				if logger != nil {
-					logger.Warn("unable to get role credentials", "account_id", role.AccountID, "account", role.AccountName, "role", role.RoleName, "error", err)
+				if s.logger != nil {
+					s.logger.Warn("unable to get role credentials", "account_id", role.AccountID, "account", role.AccountName, "role", role.RoleName, "error", err)
 				}
+				s.fail(roleFailure(OpRoleCredentials, role, ""), err)
+				s.prog.add(StageRegions, len(regions), 0)
 				return nil
 			}
+			if s.discover != nil && s.discover(role) {
+				found, err := s.enabledRegions(ctx, role, creds)
+				if err != nil {
+					if s.logger != nil {
+						s.logger.Warn("unable to list enabled regions; scanning configured regions", "account_id", role.AccountID, "account", role.AccountName, "role", role.RoleName, "error", err)
+					}
+					s.fail(roleFailure(OpListRegions, role, ""), err)
+				} else {
+					s.prog.add(StageRegions, 0, len(found)-len(regions))
+					regions = found
+				}
+			}
 
 			roleClusters := make([]ClusterAccess, 0)
 			for _, region := range regions {
-				found, err := listClustersForRegion(ctx, region, role, creds)
+				found, err := s.listClustersForRegion(ctx, region, role, creds)
+				s.prog.add(StageRegions, 1, 0)
 				if err != nil {
-					if logger != nil {
-						logger.Warn("unable to list clusters", "account_id", role.AccountID, "account", role.AccountName, "role", role.RoleName, "region", region, "error", err)
+					if s.logger != nil {
+						s.logger.Warn("unable to list clusters", "account_id", role.AccountID, "account", role.AccountName, "role", role.RoleName, "region", region, "error", err)
 					}
+					s.fail(roleFailure(OpListClusters, role, region), err)
 					continue
 				}
 				roleClusters = append(roleClusters, found...)
@@ -202,35 +377,99 @@ func listAllClusters(
 	return clusters, nil
 }
 
-func getRoleCredentials(ctx context.Context, client *sso.Client, accessToken, accountID, roleName string) (aws.CredentialsProvider, error) {
+func roleFailure(op string, role RoleAccess, region string) Failure {
+	return Failure{
+		Op:          op,
+		SSOSession:  role.SSOSession,
+		AccountID:   role.AccountID,
+		AccountName: role.AccountName,
+		RoleName:    role.RoleName,
+		Region:      region,
+	}
+}
+
+// roleCredentials returns credentials for role from the cache, or fetches
+// them (assuming chained roles from their cached source role) and caches
+// them.
+func (s *scanner) roleCredentials(ctx context.Context, client *sso.Client, accessToken string, role RoleAccess) (aws.Credentials, error) {
+	key := role.Key()
+	if creds, ok := s.creds.Get(key); ok {
+		return creds, nil
+	}
+	var (
+		creds aws.Credentials
+		err   error
+	)
+	if role.AssumeRoleARN == "" {
+		creds, err = getRoleCredentials(ctx, client, accessToken, role.AccountID, role.RoleName)
+	} else {
+		creds, err = s.assumeRole(ctx, client, accessToken, role)
+	}
+	if err != nil {
+		return aws.Credentials{}, err
+	}
+	s.creds.Put(key, creds)
+	return creds, nil
+}
+
+func (s *scanner) assumeRole(ctx context.Context, client *sso.Client, accessToken string, role RoleAccess) (aws.Credentials, error) {
+	source, err := s.roleCredentials(ctx, client, accessToken, RoleAccess{
+		AccountID:  role.SourceAccountID,
+		RoleName:   role.SourceRoleName,
+		SSOSession: role.SSOSession,
+	})
+	if err != nil {
+		return aws.Credentials{}, fmt.Errorf("source role %s/%s: %w", role.SourceAccountID, role.SourceRoleName, err)
+	}
+	stsClient := sts.NewFromConfig(aws.Config{
+		Region:      client.Options().Region,
+		Credentials: credentials.StaticCredentialsProvider{Value: source},
+		Retryer:     s.retryer,
+	})
+	return stscreds.NewAssumeRoleProvider(stsClient, role.AssumeRoleARN, func(o *stscreds.AssumeRoleOptions) {
+		o.RoleSessionName = "rift"
+		if role.ExternalID != "" {
+			o.ExternalID = aws.String(role.ExternalID)
+		}
+	}).Retrieve(ctx)
+}
+
+func getRoleCredentials(ctx context.Context, client *sso.Client, accessToken, accountID, roleName string) (aws.Credentials, error) {
 	out, err := client.GetRoleCredentials(ctx, &sso.GetRoleCredentialsInput{
 		AccessToken: aws.String(accessToken),
 		AccountId:   aws.String(accountID),
 		RoleName:    aws.String(roleName),
 	})
 	if err != nil {
-		return nil, err
+		return aws.Credentials{}, err
 	}
 	if out.RoleCredentials == nil {
-		return nil, fmt.Errorf("empty role credentials")
+		return aws.Credentials{}, fmt.Errorf("empty role credentials")
 	}
-	provider := credentials.NewStaticCredentialsProvider(
-		aws.ToString(out.RoleCredentials.AccessKeyId),
-		aws.ToString(out.RoleCredentials.SecretAccessKey),
-		aws.ToString(out.RoleCredentials.SessionToken),
-	)
-	return provider, nil
+	creds := aws.Credentials{
+		AccessKeyID:     aws.ToString(out.RoleCredentials.AccessKeyId),
+		SecretAccessKey: aws.ToString(out.RoleCredentials.SecretAccessKey),
+		SessionToken:    aws.ToString(out.RoleCredentials.SessionToken),
+		Source:          credentials.StaticCredentialsName,
+	}
+	if out.RoleCredentials.Expiration > 0 {
+		creds.CanExpire = true
+		creds.Expires = time.UnixMilli(out.RoleCredentials.Expiration)
+	}
+	return creds, nil
 }
 
-func listClustersForRegion(ctx context.Context, region string, role RoleAccess, provider aws.CredentialsProvider) ([]ClusterAccess, error) {
+func (s *scanner) listClustersForRegion(ctx context.Context, region string, role RoleAccess, creds aws.Credentials) ([]ClusterAccess, error) {
 	cfg := aws.Config{
 		Region:      region,
-		Credentials: aws.NewCredentialsCache(provider),
+		Credentials: credentials.StaticCredentialsProvider{Value: creds},
+		Retryer:     s.retryer,
 	}
 	eksClient := eks.NewFromConfig(cfg)
 
 	names := make([]string, 0)
-	input := &eks.ListClustersInput{}
+	// "all" includes clusters registered through EKS Connector.
+	input := &eks.ListClustersInput{Include: []string{"all"}}
 	for {
 		out, err := eksClient.ListClusters(ctx, input)
 		if err != nil {
@@ -243,16 +482,37 @@ func listClustersForRegion(ctx context.Context, region string, role RoleAccess,
 		input.NextToken = out.NextToken
 	}
 
+	s.prog.add(StageClusters, 0, len(names))
+	// Describe concurrently; each result lands at its name's index so the
+	// output order matches ListClusters. Failures are recorded, not returned.
+	records := make([]ClusterAccess, len(names))
+	var g errgroup.Group
+	g.SetLimit(describeConcurrency)
+	for i, name := range names {
+		g.Go(func() error {
+			desc, err := eksClient.DescribeCluster(ctx, &eks.DescribeClusterInput{Name: aws.String(name)})
+			s.prog.add(StageClusters, 1, 0)
+			if err != nil {
+				f := roleFailure(OpDescribeCluster, role, region)
+				f.Cluster = name
+				s.fail(f, err)
+				return nil
+			}
+			record := buildClusterRecord(role, region, desc.Cluster)
+			if record.ClusterName == "" {
+				record.ClusterName = name
+			}
+			if s.compute && record.Platform != state.PlatformConnected {
+				s.describeCompute(ctx, eksClient, role, &record)
+			}
+			records[i] = record
+			return nil
+		})
+	}
+	_ = g.Wait()
+
 	clusters := make([]ClusterAccess, 0, len(names))
-	for _, name := range names {
-		desc, err := eksClient.DescribeCluster(ctx, &eks.DescribeClusterInput{Name: aws.String(name)})
-		if err != nil {
-			continue
-		}
-		record := buildClusterRecord(role, region, desc.Cluster)
-		if record.ClusterName == "" {
-			record.ClusterName = name
-		}
+	for _, record := range records {
 		if record.ClusterName == "" {
 			continue
 		}
@@ -262,14 +522,32 @@ func listClustersForRegion(ctx context.Context, region string, role RoleAccess,
 }
 
 func buildClusterRecord(role RoleAccess, region string, cluster *eksTypes.Cluster) ClusterAccess {
-	var arn, endpoint, certData, clusterName string
+	var arn, endpoint, certData, clusterName, clusterID, platform, provider string
+	var version, status, platformVersion string
+	var createdAt time.Time
+	var tags map[string]string
 	if cluster != nil {
+		version = aws.ToString(cluster.Version)
+		status = string(cluster.Status)
+		platformVersion = aws.ToString(cluster.PlatformVersion)
+		createdAt = aws.ToTime(cluster.CreatedAt).UTC()
+		if len(cluster.Tags) > 0 {
+			tags = cluster.Tags
+		}
 		arn = aws.ToString(cluster.Arn)
 		endpoint = aws.ToString(cluster.Endpoint)
 		clusterName = aws.ToString(cluster.Name)
+		clusterID = aws.ToString(cluster.Id)
 		if cluster.CertificateAuthority != nil {
 			certData = aws.ToString(cluster.CertificateAuthority.Data)
 		}
+		switch {
+		case cluster.ConnectorConfig != nil:
+			platform = state.PlatformConnected
+			provider = strings.ToLower(aws.ToString(cluster.ConnectorConfig.Provider))
+		case cluster.OutpostConfig != nil:
+			platform = state.PlatformOutpost
+		}
 	}
 	return ClusterAccess{
 		AccountID:                role.AccountID,
@@ -280,5 +558,55 @@ func buildClusterRecord(role RoleAccess, region string, cluster *eksTypes.Cluste
 		ClusterARN:               arn,
 		ClusterEndpoint:          endpoint,
 		ClusterCertificateBase64: certData,
+		ClusterID:                clusterID,
+		Platform:                 platform,
+		ConnectorProvider:        provider,
+		SSOSession:               role.SSOSession,
+		AssumeRoleARN:            role.AssumeRoleARN,
+		KubernetesVersion:        version,
+		Status:                   status,
+		PlatformVersion:          platformVersion,
+		CreatedAt:                createdAt,
+		AWSTags:                  tags,
+	}
+}
+
+// assumedRoles turns configured assume_roles into RoleAccess entries, keeping
+// only those whose source SSO role was actually discovered.
+func assumedRoles(cfg config.Config, ssoRoles []RoleAccess, logger *slog.Logger) []RoleAccess {
+	if len(cfg.AssumeRoles) == 0 {
+		return nil
+	}
+	available := map[string]string{}
+	for _, role := range ssoRoles {
+		key := role.AccountID + "|" + role.RoleName
+		if _, ok := available[key]; !ok {
+			available[key] = role.SSOSession
+		}
+	}
+	out := make([]RoleAccess, 0, len(cfg.AssumeRoles))
+	for _, target := range cfg.AssumeRoles {
+		session, ok := available[target.SourceAccount+"|"+target.SourceRole]
+		if !ok {
+			if logger != nil {
+				logger.Warn("assume_roles source role not available via SSO", "role_arn", target.RoleARN, "source_account", target.SourceAccount, "source_role", target.SourceRole)
+			}
+			continue
+		}
+		name := target.AccountName
+		if name == "" {
+			name = target.AccountID()
+		}
+		out = append(out, RoleAccess{
+			AccountID:       target.AccountID(),
+			AccountName:     name,
+			RoleName:        target.RoleName(),
+			AssumeRoleARN:   target.RoleARN,
+			ExternalID:      target.ExternalID,
+			SourceAccountID: target.SourceAccount,
+			SourceRoleName:  target.SourceRole,
+			SSOSession:      session,
+		})
 	}
+	return out
 }
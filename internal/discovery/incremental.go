@@ -0,0 +1,150 @@
+package discovery
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/phenixrizen/rift/internal/config"
+	"github.com/phenixrizen/rift/internal/state"
+)
+
+// Previous is the inventory of an earlier sync. Passed in Options it makes
+// discovery incremental: accounts whose role list is unchanged keep their
+// previous clusters instead of being re-listed in every region.
+type Previous struct {
+	Roles    []RoleAccess
+	Clusters []ClusterAccess
+	// Refresh lists account IDs or names (case-insensitive) to re-list even
+	// when their roles are unchanged.
+	Refresh []string
+}
+
+// PreviousFromState converts the state written by an earlier sync. Only
+// the user's own EKS records are kept: shared records belong to a teammate,
+// and GKE, AKS, plugin, and imported clusters are rediscovered (or carried)
+// by their own backends on every sync.
+func PreviousFromState(st state.State) *Previous {
+	prev := &Previous{
+		Roles:    make([]RoleAccess, 0, len(st.Roles)),
+		Clusters: make([]ClusterAccess, 0, len(st.Clusters)),
+	}
+	for _, r := range st.Roles {
+		if r.Shared() {
+			continue
+		}
+		prev.Roles = append(prev.Roles, RoleAccess{
+			AccountID:     r.AccountID,
+			AccountName:   r.AccountName,
+			RoleName:      r.RoleName,
+			AssumeRoleARN: r.AssumeRoleARN,
+			ExternalID:    r.ExternalID,
+			SSOSession:    sessionOrDefault(r.SSOSession),
+		})
+	}
+	// Clusters record the profile they were found through; its role says
+	// whether that was a chained role.
+	assumed := map[string]string{}
+	for _, r := range st.Roles {
+		if r.AssumeRoleARN != "" {
+			assumed[r.AWSProfile] = r.AssumeRoleARN
+		}
+	}
+	for _, c := range st.Clusters {
+		if c.Shared() || !state.AWSPlatform(c.Platform) {
+			continue
+		}
+		prev.Clusters = append(prev.Clusters, ClusterAccess{
+			AccountID:                c.AccountID,
+			AccountName:              c.AccountName,
+			RoleName:                 c.RoleName,
+			Region:                   c.Region,
+			ClusterName:              c.ClusterName,
+			ClusterARN:               c.ClusterARN,
+			ClusterEndpoint:          c.ClusterEndpoint,
+			ClusterCertificateBase64: c.ClusterCertificateBase64,
+			ClusterID:                c.ClusterID,
+			Platform:                 c.Platform,
+			Plugin:                   c.Plugin,
+			ConnectorProvider:        c.ConnectorProvider,
+			SSOSession:               sessionOrDefault(c.SSOSession),
+			AssumeRoleARN:            assumed[c.AWSProfile],
+			KubernetesVersion:        c.KubernetesVersion,
+			Status:                   c.Status,
+			PlatformVersion:          c.PlatformVersion,
+			CreatedAt:                c.CreatedAt,
+			AWSTags:                  c.AWSTags,
+			Nodegroups:               c.Nodegroups,
+			FargateProfiles:          c.FargateProfiles,
+		})
+	}
+	return prev
+}
+
+// reusable returns the accounts (keyed by accountKey) whose clusters can be
+// taken from p: same set of roles as before and not listed in Refresh.
+func (p *Previous) reusable(roles []RoleAccess) map[string]bool {
+	if p == nil {
+		return nil
+	}
+	before := roleSets(p.Roles)
+	out := map[string]bool{}
+	for key, set := range roleSets(roles) {
+		if prevSet, ok := before[key]; !ok || prevSet != set {
+			continue
+		}
+		out[key] = true
+	}
+	for _, role := range roles {
+		if p.refresh(role) {
+			delete(out, accountKey(role.SSOSession, role.AccountID))
+		}
+	}
+	return out
+}
+
+func (p *Previous) refresh(role RoleAccess) bool {
+	for _, want := range p.Refresh {
+		want = strings.TrimSpace(want)
+		if want == role.AccountID || strings.EqualFold(want, role.AccountName) {
+			return true
+		}
+	}
+	return false
+}
+
+// clusters returns the previous clusters of the given accounts.
+func (p *Previous) clusters(accounts map[string]bool) []ClusterAccess {
+	out := make([]ClusterAccess, 0)
+	for _, c := range p.Clusters {
+		if accounts[accountKey(c.SSOSession, c.AccountID)] {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// roleSets maps each account to a canonical string of its roles.
+func roleSets(roles []RoleAccess) map[string]string {
+	names := map[string][]string{}
+	for _, r := range roles {
+		key := accountKey(r.SSOSession, r.AccountID)
+		names[key] = append(names[key], r.RoleName+"|"+r.AssumeRoleARN)
+	}
+	out := make(map[string]string, len(names))
+	for key, list := range names {
+		sort.Strings(list)
+		out[key] = strings.Join(list, ",")
+	}
+	return out
+}
+
+func accountKey(session, accountID string) string {
+	return sessionOrDefault(session) + "|" + accountID
+}
+
+func sessionOrDefault(session string) string {
+	if session == "" {
+		return config.DefaultSSOSession
+	}
+	return session
+}
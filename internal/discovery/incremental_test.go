@@ -0,0 +1,54 @@
+package discovery
+
+import (
+	"testing"
+
+	"github.com/phenixrizen/rift/internal/state"
+)
+
+func TestPreviousReusableAccounts(t *testing.T) {
+	prev := &Previous{
+		Roles: []RoleAccess{
+			{AccountID: "111", AccountName: "payments", RoleName: "Admin", SSOSession: "rift"},
+			{AccountID: "222", AccountName: "search", RoleName: "Admin", SSOSession: "rift"},
+			{AccountID: "333", AccountName: "ledger", RoleName: "Admin", SSOSession: "rift"},
+		},
+		Refresh: []string{"Ledger"},
+	}
+	roles := []RoleAccess{
+		{AccountID: "111", AccountName: "payments", RoleName: "Admin", SSOSession: "rift"},
+		{AccountID: "222", AccountName: "search", RoleName: "Admin", SSOSession: "rift"},
+		{AccountID: "222", AccountName: "search", RoleName: "ReadOnly", SSOSession: "rift"},
+		{AccountID: "333", AccountName: "ledger", RoleName: "Admin", SSOSession: "rift"},
+		{AccountID: "444", AccountName: "new", RoleName: "Admin", SSOSession: "rift"},
+	}
+	got := prev.reusable(roles)
+	if len(got) != 1 || !got[accountKey("rift", "111")] {
+		t.Fatalf("reusable=%v want only rift|111", got)
+	}
+	if (*Previous)(nil).reusable(roles) != nil {
+		t.Fatalf("nil Previous should reuse nothing")
+	}
+}
+
+func TestPreviousFromStateKeepsOwnEKSClusters(t *testing.T) {
+	st := state.State{
+		Clusters: []state.ClusterRecord{
+			{AccountID: "111", ClusterName: "eks", ClusterARN: "arn:eks"},
+			{AccountID: "111", ClusterName: "connected", Platform: state.PlatformConnected},
+			{AccountID: "111", ClusterName: "team", ClusterARN: "arn:team", SharedFrom: "alice.json"},
+			{AccountID: "proj", ClusterName: "gke", Platform: state.PlatformGKE},
+			{AccountID: "sub", ClusterName: "aks", Platform: state.PlatformAKS},
+			{AccountID: "dc", ClusterName: "rack1", Platform: state.PlatformPlugin, Plugin: "onprem"},
+			{ClusterName: "kind", Platform: state.PlatformExternal},
+		},
+	}
+	prev := PreviousFromState(st)
+	var got []string
+	for _, c := range prev.Clusters {
+		got = append(got, c.ClusterName)
+	}
+	if len(got) != 2 || got[0] != "eks" || got[1] != "connected" {
+		t.Fatalf("PreviousFromState clusters = %v, want eks and connected", got)
+	}
+}
@@ -0,0 +1,99 @@
+package discovery
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// Node is a running EC2 instance of an EKS cluster.
+type Node struct {
+	InstanceID   string
+	Name         string
+	Nodegroup    string
+	InstanceType string
+	Zone         string
+	PrivateIP    string
+	LaunchTime   time.Time
+}
+
+// ClusterNodes lists the running EC2 instances of the EKS cluster named
+// cluster in region: those tagged by managed node groups (eks:cluster-name)
+// or carrying the kubernetes.io/cluster/<name> tag self-managed groups and
+// Karpenter set. Fargate pods have no instance and are not included.
+func ClusterNodes(ctx context.Context, creds aws.Credentials, region, cluster string) ([]Node, error) {
+	client := ec2.NewFromConfig(aws.Config{
+		Region:      region,
+		Credentials: credentials.StaticCredentialsProvider{Value: creds},
+	})
+	running := ec2types.Filter{Name: aws.String("instance-state-name"), Values: []string{"running"}}
+	// Filters are ANDed, so each tag needs its own query.
+	queries := [][]ec2types.Filter{
+		{running, {Name: aws.String("tag:eks:cluster-name"), Values: []string{cluster}}},
+		{running, {Name: aws.String("tag-key"), Values: []string{"kubernetes.io/cluster/" + cluster}}},
+	}
+	var reservations []ec2types.Reservation
+	for _, filters := range queries {
+		pages := ec2.NewDescribeInstancesPaginator(client, &ec2.DescribeInstancesInput{Filters: filters})
+		for pages.HasMorePages() {
+			page, err := pages.NextPage(ctx)
+			if err != nil {
+				return nil, err
+			}
+			reservations = append(reservations, page.Reservations...)
+		}
+	}
+	return nodesFromReservations(reservations), nil
+}
+
+// nodesFromReservations converts instances to nodes, once each, sorted by
+// node group then name.
+func nodesFromReservations(reservations []ec2types.Reservation) []Node {
+	seen := map[string]bool{}
+	var out []Node
+	for _, r := range reservations {
+		for _, inst := range r.Instances {
+			id := aws.ToString(inst.InstanceId)
+			if id == "" || seen[id] {
+				continue
+			}
+			seen[id] = true
+			node := Node{
+				InstanceID:   id,
+				Name:         aws.ToString(inst.PrivateDnsName),
+				InstanceType: string(inst.InstanceType),
+				PrivateIP:    aws.ToString(inst.PrivateIpAddress),
+				LaunchTime:   aws.ToTime(inst.LaunchTime),
+			}
+			if inst.Placement != nil {
+				node.Zone = aws.ToString(inst.Placement.AvailabilityZone)
+			}
+			for _, tag := range inst.Tags {
+				switch aws.ToString(tag.Key) {
+				case "eks:nodegroup-name", "karpenter.sh/nodepool":
+					node.Nodegroup = aws.ToString(tag.Value)
+				case "Name":
+					if node.Name == "" {
+						node.Name = aws.ToString(tag.Value)
+					}
+				}
+			}
+			if node.Name == "" {
+				node.Name = id
+			}
+			out = append(out, node)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Nodegroup != out[j].Nodegroup {
+			return out[i].Nodegroup < out[j].Nodegroup
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
@@ -0,0 +1,39 @@
+package discovery
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+func TestNodesFromReservations(t *testing.T) {
+	managed := ec2types.Instance{
+		InstanceId:     aws.String("i-b"),
+		PrivateDnsName: aws.String("ip-10-0-0-2.ec2.internal"),
+		InstanceType:   ec2types.InstanceTypeM5Large,
+		Placement:      &ec2types.Placement{AvailabilityZone: aws.String("us-east-1a")},
+		Tags:           []ec2types.Tag{{Key: aws.String("eks:nodegroup-name"), Value: aws.String("workers")}},
+	}
+	karpenter := ec2types.Instance{
+		InstanceId: aws.String("i-a"),
+		Tags: []ec2types.Tag{
+			{Key: aws.String("karpenter.sh/nodepool"), Value: aws.String("default")},
+			{Key: aws.String("Name"), Value: aws.String("spot-node")},
+		},
+	}
+	// A managed node matches both queries and is listed twice.
+	got := nodesFromReservations([]ec2types.Reservation{
+		{Instances: []ec2types.Instance{managed}},
+		{Instances: []ec2types.Instance{managed, karpenter}},
+	})
+	if len(got) != 2 {
+		t.Fatalf("nodes = %+v, want 2", got)
+	}
+	if got[0].InstanceID != "i-a" || got[0].Nodegroup != "default" || got[0].Name != "spot-node" {
+		t.Fatalf("first node = %+v", got[0])
+	}
+	if got[1].Name != "ip-10-0-0-2.ec2.internal" || got[1].Zone != "us-east-1a" || got[1].InstanceType != "m5.large" {
+		t.Fatalf("second node = %+v", got[1])
+	}
+}
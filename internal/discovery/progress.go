@@ -0,0 +1,54 @@
+package discovery
+
+import "sync"
+
+// Discovery stages reported through Options.Progress.
+const (
+	// StageAccounts counts accounts listed across SSO sessions.
+	StageAccounts = "accounts"
+	// StageRoles counts accounts whose roles have been listed.
+	StageRoles = "roles"
+	// StageRegions counts role/region pairs scanned for clusters.
+	StageRegions = "regions"
+	// StageClusters counts clusters described; Total grows as each
+	// ListClusters call returns.
+	StageClusters = "clusters"
+)
+
+// Progress is a snapshot of one stage. Total is 0 while unknown.
+type Progress struct {
+	Stage string
+	Done  int
+	Total int
+}
+
+// progress aggregates counters per stage and forwards every change to fn.
+// A nil *progress (or nil fn) discards updates.
+type progress struct {
+	mu     sync.Mutex
+	fn     func(Progress)
+	stages map[string]*Progress
+}
+
+func newProgress(fn func(Progress)) *progress {
+	if fn == nil {
+		return nil
+	}
+	return &progress{fn: fn, stages: map[string]*Progress{}}
+}
+
+func (p *progress) add(stage string, done, total int) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s, ok := p.stages[stage]
+	if !ok {
+		s = &Progress{Stage: stage}
+		p.stages[stage] = s
+	}
+	s.Done += done
+	s.Total += total
+	p.fn(*s)
+}
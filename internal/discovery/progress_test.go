@@ -0,0 +1,30 @@
+package discovery
+
+import "testing"
+
+func TestProgressAccumulatesPerStage(t *testing.T) {
+	var got []Progress
+	prog := newProgress(func(p Progress) { got = append(got, p) })
+	prog.add(StageRegions, 0, 4)
+	prog.add(StageRegions, 1, 0)
+	prog.add(StageClusters, 0, 2)
+	prog.add(StageRegions, 2, 0)
+
+	want := []Progress{
+		{Stage: StageRegions, Done: 0, Total: 4},
+		{Stage: StageRegions, Done: 1, Total: 4},
+		{Stage: StageClusters, Done: 0, Total: 2},
+		{Stage: StageRegions, Done: 3, Total: 4},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d updates, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("update %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	// A nil callback disables reporting without nil checks at call sites.
+	newProgress(nil).add(StageAccounts, 1, 1)
+}
@@ -0,0 +1,78 @@
+package discovery
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	awsaccount "github.com/aws/aws-sdk-go-v2/service/account"
+	accountTypes "github.com/aws/aws-sdk-go-v2/service/account/types"
+)
+
+// accountAPIRegion is where the (global) Account Management API is called.
+const accountAPIRegion = "us-east-1"
+
+// regionLookup memoizes enabled regions per account so roles of the same
+// account share one ListRegions call.
+type regionLookup struct {
+	mu      sync.Mutex
+	entries map[string]*regionEntry
+}
+
+type regionEntry struct {
+	once    sync.Once
+	regions []string
+	err     error
+}
+
+func (l *regionLookup) entry(key string) *regionEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.entries == nil {
+		l.entries = map[string]*regionEntry{}
+	}
+	e, ok := l.entries[key]
+	if !ok {
+		e = &regionEntry{}
+		l.entries[key] = e
+	}
+	return e
+}
+
+// enabledRegions lists the regions enabled in role's account (opted in or
+// enabled by default), sorted.
+func (s *scanner) enabledRegions(ctx context.Context, role RoleAccess, creds aws.Credentials) ([]string, error) {
+	e := s.regions.entry(accountKey(role.SSOSession, role.AccountID))
+	e.once.Do(func() {
+		client := awsaccount.NewFromConfig(aws.Config{
+			Region:      accountAPIRegion,
+			Credentials: credentials.StaticCredentialsProvider{Value: creds},
+			Retryer:     s.retryer,
+		})
+		input := &awsaccount.ListRegionsInput{
+			RegionOptStatusContains: []accountTypes.RegionOptStatus{
+				accountTypes.RegionOptStatusEnabled,
+				accountTypes.RegionOptStatusEnabledByDefault,
+			},
+		}
+		regions := make([]string, 0)
+		pages := awsaccount.NewListRegionsPaginator(client, input)
+		for pages.HasMorePages() {
+			out, err := pages.NextPage(ctx)
+			if err != nil {
+				e.err = err
+				return
+			}
+			for _, r := range out.Regions {
+				if name := aws.ToString(r.RegionName); name != "" {
+					regions = append(regions, name)
+				}
+			}
+		}
+		sort.Strings(regions)
+		e.regions = regions
+	})
+	return e.regions, e.err
+}
@@ -0,0 +1,108 @@
+package discovery
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/ratelimit"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/phenixrizen/rift/internal/config"
+)
+
+// Discovery calls recorded in Failure.Op.
+const (
+	OpListRoles       = "list_roles"
+	OpRoleCredentials = "role_credentials"
+	OpListRegions     = "list_regions"
+	OpListClusters    = "list_clusters"
+	OpDescribeCluster = "describe_cluster"
+	OpListNodegroups  = "list_nodegroups"
+	OpListFargate     = "list_fargate_profiles"
+)
+
+// Failure is a discovery call that still failed after retries. What it would
+// have found (an account's roles, or a role's clusters in a region) is
+// missing from the inventory.
+type Failure struct {
+	Op          string
+	SSOSession  string
+	AccountID   string
+	AccountName string
+	RoleName    string
+	Region      string
+	Cluster     string
+	// Throttled is set when the last error was AWS throttling, meaning a
+	// later sync (or a higher retry_max_attempts) will likely succeed.
+	Throttled bool
+	Err       string
+}
+
+func (f Failure) String() string {
+	target := f.AccountName
+	if target == "" {
+		target = f.AccountID
+	}
+	for _, part := range []string{f.RoleName, f.Region, f.Cluster} {
+		if part != "" {
+			target += "/" + part
+		}
+	}
+	reason := f.Err
+	if f.Throttled {
+		reason = "throttled: " + reason
+	}
+	return fmt.Sprintf("%s %s: %s", f.Op, target, reason)
+}
+
+// newRetryer returns the retryer used by every discovery client: the SDK
+// standard retryer (jittered exponential backoff; throttling and transient
+// errors are retryable) with attempts and backoff from config. The
+// client-side retry quota is disabled so a burst of throttles across
+// parallel roles waits and retries instead of failing fast.
+func newRetryer(cfg config.Config) func() aws.Retryer {
+	attempts, backoff := cfg.RetryPolicy()
+	return func() aws.Retryer {
+		return retry.NewStandard(func(o *retry.StandardOptions) {
+			o.MaxAttempts = attempts
+			o.MaxBackoff = backoff
+			o.RateLimiter = ratelimit.None
+		})
+	}
+}
+
+// isThrottle reports whether err (or the last attempt wrapped in a
+// retry.MaxAttemptsError) is an AWS throttling error.
+func isThrottle(err error) bool {
+	return retry.IsErrorThrottles(retry.DefaultThrottles).IsErrorThrottle(err) == aws.TrueTernary
+}
+
+// failureLog collects failures from concurrent discovery calls.
+type failureLog struct {
+	mu   sync.Mutex
+	list []Failure
+}
+
+func (l *failureLog) add(f Failure, err error) {
+	f.Err = err.Error()
+	f.Throttled = isThrottle(err)
+	l.mu.Lock()
+	l.list = append(l.list, f)
+	l.mu.Unlock()
+}
+
+// sorted returns the failures in a stable order so reports are comparable
+// between runs.
+func (l *failureLog) sorted() []Failure {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	out := append([]Failure(nil), l.list...)
+	sort.Slice(out, func(i, j int) bool {
+		left := strings.Join([]string{out[i].SSOSession, out[i].AccountID, out[i].RoleName, out[i].Region, out[i].Cluster, out[i].Op}, "|")
+		right := strings.Join([]string{out[j].SSOSession, out[j].AccountID, out[j].RoleName, out[j].Region, out[j].Cluster, out[j].Op}, "|")
+		return left < right
+	})
+	return out
+}
@@ -0,0 +1,28 @@
+package discovery
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/smithy-go"
+)
+
+func TestFailureLogMarksThrottlingAndSorts(t *testing.T) {
+	throttled := &retry.MaxAttemptsError{Attempt: 8, Err: &smithy.GenericAPIError{Code: "TooManyRequestsException", Message: "Rate exceeded"}}
+
+	var log failureLog
+	log.add(Failure{Op: OpListClusters, AccountID: "222", AccountName: "payments", RoleName: "Admin", Region: "us-west-2"}, errors.New("access denied"))
+	log.add(Failure{Op: OpRoleCredentials, AccountID: "111", AccountName: "core", RoleName: "Admin"}, throttled)
+
+	got := log.sorted()
+	if len(got) != 2 || got[0].AccountID != "111" || got[1].AccountID != "222" {
+		t.Fatalf("sorted() = %+v, want account 111 first", got)
+	}
+	if !got[0].Throttled || got[1].Throttled {
+		t.Fatalf("Throttled = %v,%v want true,false", got[0].Throttled, got[1].Throttled)
+	}
+	if want := "list_clusters payments/Admin/us-west-2: access denied"; got[1].String() != want {
+		t.Fatalf("String() = %q, want %q", got[1].String(), want)
+	}
+}
@@ -9,6 +9,8 @@ import (
 	"sort"
 	"strings"
 	"time"
+
+	"github.com/phenixrizen/rift/internal/config"
 )
 
 var ErrSSONotLoggedIn = errors.New("aws sso token missing or expired")
@@ -23,6 +25,47 @@ type tokenCacheRecord struct {
 type tokenInfo struct {
 	AccessToken string
 	ExpiresAt   time.Time
+	CachedAt    time.Time
+	StartURL    string
+	Region      string
+}
+
+// SSOToken describes the cached SSO access token rift would use.
+type SSOToken struct {
+	StartURL  string
+	Region    string
+	ExpiresAt time.Time
+	// CachedAt is the cache file's modification time, i.e. roughly when the
+	// token was issued or last refreshed.
+	CachedAt time.Time
+}
+
+// CurrentSSOToken returns the freshest valid cached token for the primary
+// SSO session of cfg, or ErrSSONotLoggedIn.
+func CurrentSSOToken(cfg config.Config, now time.Time) (SSOToken, error) {
+	return SessionToken(cfg.Sessions()[0], now)
+}
+
+// SessionToken returns the freshest valid cached token for session, or
+// ErrSSONotLoggedIn.
+func SessionToken(session config.SSOSession, now time.Time) (SSOToken, error) {
+	tok, err := loadTokenFromCache(session.StartURL, session.Region, now)
+	if err != nil {
+		return SSOToken{}, err
+	}
+	return SSOToken{
+		StartURL:  tok.StartURL,
+		Region:    tok.Region,
+		ExpiresAt: tok.ExpiresAt,
+		CachedAt:  tok.CachedAt,
+	}, nil
+}
+
+// LastSSOToken returns the cached token for session with the latest expiry
+// even if it has already expired, or ErrSSONotLoggedIn when none is cached.
+func LastSSOToken(session config.SSOSession) (SSOToken, error) {
+	// The zero time makes every cached token count as unexpired.
+	return SessionToken(session, time.Time{})
 }
 
 func loadTokenFromCache(startURL, region string, now time.Time) (tokenInfo, error) {
@@ -33,6 +76,9 @@ func loadTokenFromCache(startURL, region string, now time.Time) (tokenInfo, erro
 	dir := filepath.Join(home, ".aws", "sso", "cache")
 	entries, err := os.ReadDir(dir)
 	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return tokenInfo{}, ErrSSONotLoggedIn
+		}
 		return tokenInfo{}, fmt.Errorf("read sso cache: %w", err)
 	}
 	startURL = strings.TrimSpace(startURL)
@@ -68,7 +114,17 @@ func loadTokenFromCache(startURL, region string, now time.Time) (tokenInfo, erro
 		if !expiresAt.After(now.Add(1 * time.Minute)) {
 			continue
 		}
-		candidates = append(candidates, tokenInfo{AccessToken: rec.AccessToken, ExpiresAt: expiresAt})
+		var cachedAt time.Time
+		if fi, err := entry.Info(); err == nil {
+			cachedAt = fi.ModTime().UTC()
+		}
+		candidates = append(candidates, tokenInfo{
+			AccessToken: rec.AccessToken,
+			ExpiresAt:   expiresAt,
+			CachedAt:    cachedAt,
+			StartURL:    rec.StartURL,
+			Region:      rec.Region,
+		})
 	}
 	if len(candidates) == 0 {
 		return tokenInfo{}, ErrSSONotLoggedIn
@@ -0,0 +1,146 @@
+// Package ecrauth gets Amazon ECR registry logins from role credentials and
+// stores them in the docker config.
+package ecrauth
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	"github.com/phenixrizen/rift/internal/fileutil"
+)
+
+// Login is a registry login from GetAuthorizationToken.
+type Login struct {
+	Registry string
+	Username string
+	Password string
+	Expires  time.Time
+}
+
+// Token fetches the login for the default registry of the credentials'
+// account in region. ECR tokens last 12 hours.
+func Token(ctx context.Context, creds aws.Credentials, region string) (Login, error) {
+	client := ecr.NewFromConfig(aws.Config{
+		Region:      region,
+		Credentials: credentials.StaticCredentialsProvider{Value: creds},
+	})
+	out, err := client.GetAuthorizationToken(ctx, &ecr.GetAuthorizationTokenInput{})
+	if err != nil {
+		return Login{}, err
+	}
+	if len(out.AuthorizationData) == 0 {
+		return Login{}, fmt.Errorf("ECR returned no authorization data")
+	}
+	data := out.AuthorizationData[0]
+	login, err := decodeToken(aws.ToString(data.AuthorizationToken))
+	if err != nil {
+		return Login{}, err
+	}
+	login.Registry = registryHost(aws.ToString(data.ProxyEndpoint))
+	login.Expires = aws.ToTime(data.ExpiresAt)
+	return login, nil
+}
+
+// decodeToken splits a base64 "user:password" authorization token.
+func decodeToken(token string) (Login, error) {
+	raw, err := base64.StdEncoding.DecodeString(token)
+	if err != nil {
+		return Login{}, fmt.Errorf("decode ECR token: %w", err)
+	}
+	user, password, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return Login{}, fmt.Errorf("decode ECR token: no user name")
+	}
+	return Login{Username: user, Password: password}, nil
+}
+
+// registryHost is the host docker logs in to for an ECR proxy endpoint.
+func registryHost(endpoint string) string {
+	endpoint = strings.TrimPrefix(endpoint, "https://")
+	return strings.TrimSuffix(endpoint, "/")
+}
+
+// DockerConfigPath is the docker client's config.json: $DOCKER_CONFIG or
+// ~/.docker.
+func DockerConfigPath() (string, error) {
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		return filepath.Join(dir, "config.json"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".docker", "config.json"), nil
+}
+
+// ErrCredentialHelper is returned by WriteDockerConfig when docker keeps the
+// registry's credentials in a helper, so an auths entry would be ignored.
+var ErrCredentialHelper = errors.New("docker uses a credential helper for this registry")
+
+// WriteDockerConfig stores l under auths in the docker config at path,
+// keeping every other setting. It refuses when credsStore or a credHelpers
+// entry would make docker ignore the entry.
+func WriteDockerConfig(path string, l Login) error {
+	cfg := map[string]json.RawMessage{}
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return err
+	case len(bytes.TrimSpace(data)) > 0:
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			return fmt.Errorf("parse %s: %w", path, err)
+		}
+	}
+
+	var store string
+	if raw, ok := cfg["credsStore"]; ok {
+		_ = json.Unmarshal(raw, &store)
+	}
+	helpers := map[string]string{}
+	if raw, ok := cfg["credHelpers"]; ok {
+		_ = json.Unmarshal(raw, &helpers)
+	}
+	if helper := helpers[l.Registry]; helper != "" {
+		return fmt.Errorf("%w (credHelpers: %s)", ErrCredentialHelper, helper)
+	}
+	if store != "" {
+		return fmt.Errorf("%w (credsStore: %s)", ErrCredentialHelper, store)
+	}
+
+	auths := map[string]json.RawMessage{}
+	if raw, ok := cfg["auths"]; ok {
+		if err := json.Unmarshal(raw, &auths); err != nil {
+			return fmt.Errorf("parse %s auths: %w", path, err)
+		}
+	}
+	entry, err := json.Marshal(map[string]string{
+		"auth": base64.StdEncoding.EncodeToString([]byte(l.Username + ":" + l.Password)),
+	})
+	if err != nil {
+		return err
+	}
+	auths[l.Registry] = entry
+	if cfg["auths"], err = json.Marshal(auths); err != nil {
+		return err
+	}
+	out, err := json.MarshalIndent(cfg, "", "\t")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	return fileutil.WritePrivate(path, append(out, '\n'), 0o600)
+}
@@ -0,0 +1,75 @@
+package ecrauth
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDecodeToken(t *testing.T) {
+	login, err := decodeToken(base64.StdEncoding.EncodeToString([]byte("AWS:pass:word")))
+	if err != nil || login.Username != "AWS" || login.Password != "pass:word" {
+		t.Fatalf("decodeToken = %+v, %v", login, err)
+	}
+	if _, err := decodeToken(base64.StdEncoding.EncodeToString([]byte("nouser"))); err == nil {
+		t.Fatal("decodeToken accepted a token without a user")
+	}
+	if got := registryHost("https://111111111111.dkr.ecr.us-west-2.amazonaws.com"); got != "111111111111.dkr.ecr.us-west-2.amazonaws.com" {
+		t.Fatalf("registryHost = %q", got)
+	}
+}
+
+func TestWriteDockerConfigKeepsSettings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "docker", "config.json")
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	existing := `{"auths":{"ghcr.io":{"auth":"eA=="}},"psFormat":"table {{.ID}}"}`
+	if err := os.WriteFile(path, []byte(existing), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	login := Login{Registry: "111111111111.dkr.ecr.us-west-2.amazonaws.com", Username: "AWS", Password: "secret"}
+	if err := WriteDockerConfig(path, login); err != nil {
+		t.Fatalf("WriteDockerConfig returned error: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cfg struct {
+		Auths    map[string]map[string]string `json:"auths"`
+		PSFormat string                       `json:"psFormat"`
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.PSFormat != "table {{.ID}}" || cfg.Auths["ghcr.io"]["auth"] != "eA==" {
+		t.Fatalf("other settings lost: %s", data)
+	}
+	if got := cfg.Auths[login.Registry]["auth"]; got != base64.StdEncoding.EncodeToString([]byte("AWS:secret")) {
+		t.Fatalf("auth = %q", got)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Fatalf("mode = %v, %v", info.Mode().Perm(), err)
+	}
+}
+
+func TestWriteDockerConfigRefusesCredentialHelpers(t *testing.T) {
+	login := Login{Registry: "111111111111.dkr.ecr.us-west-2.amazonaws.com", Username: "AWS", Password: "secret"}
+	for _, existing := range []string{
+		`{"credsStore":"desktop"}`,
+		`{"credHelpers":{"111111111111.dkr.ecr.us-west-2.amazonaws.com":"ecr-login"}}`,
+	} {
+		path := filepath.Join(t.TempDir(), "config.json")
+		if err := os.WriteFile(path, []byte(existing), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := WriteDockerConfig(path, login); !errors.Is(err, ErrCredentialHelper) {
+			t.Fatalf("WriteDockerConfig(%s) = %v, want ErrCredentialHelper", existing, err)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+// Package ekstoken generates EKS bearer tokens in Go, as aws eks get-token
+// does: a presigned STS GetCallerIdentity URL naming the cluster.
+package ekstoken
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/phenixrizen/rift/internal/fileutil"
+)
+
+const (
+	tokenPrefix     = "k8s-aws-v1."
+	clusterIDHeader = "x-k8s-aws-id"
+	// The API server accepts the presigned URL for 15 minutes; like aws eks
+	// get-token, tokens are reported to expire a minute sooner.
+	tokenLifetime = 14 * time.Minute
+	// minRemaining is how long a cached token must still be valid to be
+	// handed out again.
+	minRemaining = 2 * time.Minute
+)
+
+// ExecCredentialVersion is the client.authentication.k8s.io version of the
+// ExecCredential rift emits and its kubeconfig exec blocks request.
+const ExecCredentialVersion = "client.authentication.k8s.io/v1beta1"
+
+// Token is an EKS bearer token.
+type Token struct {
+	Value   string    `json:"token"`
+	Expires time.Time `json:"expires"`
+}
+
+// Generate returns a token for clusterID (the cluster name, or the cluster
+// ID of a local cluster on Outposts) signed with creds for STS in region.
+// It makes no network calls.
+func Generate(ctx context.Context, creds aws.Credentials, region, clusterID string) (Token, error) {
+	client := sts.NewFromConfig(aws.Config{
+		Region:      region,
+		Credentials: credentials.StaticCredentialsProvider{Value: creds},
+	})
+	signed, err := sts.NewPresignClient(client).PresignGetCallerIdentity(ctx, &sts.GetCallerIdentityInput{}, func(o *sts.PresignOptions) {
+		o.ClientOptions = append(o.ClientOptions, func(o *sts.Options) {
+			o.APIOptions = append(o.APIOptions,
+				smithyhttp.SetHeaderValue(clusterIDHeader, clusterID),
+				smithyhttp.SetHeaderValue("X-Amz-Expires", "60"),
+			)
+		})
+	})
+	if err != nil {
+		return Token{}, err
+	}
+	return Token{
+		Value:   tokenPrefix + base64.RawURLEncoding.EncodeToString([]byte(signed.URL)),
+		Expires: time.Now().Add(tokenLifetime),
+	}, nil
+}
+
+// ExecCredential renders t as the JSON a kubeconfig exec plugin prints.
+func ExecCredential(t Token) ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"kind":       "ExecCredential",
+		"apiVersion": ExecCredentialVersion,
+		"spec":       map[string]any{},
+		"status": map[string]string{
+			"expirationTimestamp": t.Expires.UTC().Format(time.RFC3339),
+			"token":               t.Value,
+		},
+	})
+}
+
+// Cache keeps tokens on disk between runs, one 0600 file per key, so each
+// kubectl call does not fetch role credentials again. The zero Cache caches
+// nothing.
+type Cache struct {
+	Dir string
+}
+
+func (c Cache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.Dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// Get returns the token cached under key when it is valid for a while yet.
+func (c Cache) Get(key string, now time.Time) (Token, bool) {
+	if c.Dir == "" {
+		return Token{}, false
+	}
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return Token{}, false
+	}
+	var t Token
+	if err := json.Unmarshal(data, &t); err != nil || t.Value == "" || !t.Expires.After(now.Add(minRemaining)) {
+		return Token{}, false
+	}
+	return t, true
+}
+
+// Put caches t under key.
+func (c Cache) Put(key string, t Token) error {
+	if c.Dir == "" {
+		return nil
+	}
+	if t.Value == "" {
+		return errors.New("empty token")
+	}
+	if err := os.MkdirAll(c.Dir, 0o700); err != nil {
+		return err
+	}
+	data, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	return fileutil.WritePrivate(c.path(key), data, 0o600)
+}
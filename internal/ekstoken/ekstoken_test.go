@@ -0,0 +1,90 @@
+package ekstoken
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+func TestGenerate(t *testing.T) {
+	creds := aws.Credentials{AccessKeyID: "AKID", SecretAccessKey: "SECRET", SessionToken: "SESSION"}
+	tok, err := Generate(context.Background(), creds, "us-west-2", "payments")
+	if err != nil {
+		t.Fatalf("Generate returned error: %v", err)
+	}
+	encoded, ok := strings.CutPrefix(tok.Value, tokenPrefix)
+	if !ok {
+		t.Fatalf("token %q lacks %s", tok.Value, tokenPrefix)
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		t.Fatalf("decode token: %v", err)
+	}
+	u, err := url.Parse(string(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	if u.Host != "sts.us-west-2.amazonaws.com" || q.Get("Action") != "GetCallerIdentity" {
+		t.Fatalf("presigned URL = %s", u)
+	}
+	if q.Get("X-Amz-Expires") != "60" || !strings.Contains(q.Get("X-Amz-SignedHeaders"), clusterIDHeader) {
+		t.Fatalf("presigned URL does not sign the cluster header for 60s: %s", u)
+	}
+	if q.Get("X-Amz-Security-Token") != "SESSION" {
+		t.Fatalf("session token missing: %s", u)
+	}
+	if d := time.Until(tok.Expires); d < 13*time.Minute || d > tokenLifetime {
+		t.Fatalf("expires in %s", d)
+	}
+}
+
+func TestExecCredential(t *testing.T) {
+	data, err := ExecCredential(Token{Value: "k8s-aws-v1.abc", Expires: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		Kind       string `json:"kind"`
+		APIVersion string `json:"apiVersion"`
+		Status     struct {
+			ExpirationTimestamp string `json:"expirationTimestamp"`
+			Token               string `json:"token"`
+		} `json:"status"`
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Kind != "ExecCredential" || got.APIVersion != ExecCredentialVersion || got.Status.Token != "k8s-aws-v1.abc" || got.Status.ExpirationTimestamp != "2026-01-02T03:04:05Z" {
+		t.Fatalf("ExecCredential = %s", data)
+	}
+}
+
+func TestCache(t *testing.T) {
+	now := time.Now()
+	cache := Cache{Dir: t.TempDir()}
+	if err := cache.Put("a", Token{Value: "fresh", Expires: now.Add(10 * time.Minute)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.Put("b", Token{Value: "stale", Expires: now.Add(time.Minute)}); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := cache.Get("a", now); !ok || got.Value != "fresh" {
+		t.Fatalf("Get(a) = %+v, %v", got, ok)
+	}
+	if _, ok := cache.Get("b", now); ok {
+		t.Fatal("Get served a token about to expire")
+	}
+	if _, ok := cache.Get("missing", now); ok {
+		t.Fatal("Get served a missing key")
+	}
+	if _, ok := (Cache{}).Get("a", now); ok {
+		t.Fatal("zero Cache served a token")
+	}
+}
@@ -0,0 +1,117 @@
+// Package fileutil writes shared files (~/.aws/config, kubeconfigs,
+// state.json) so that concurrent rift runs, or rift and kubectl, cannot
+// interleave or truncate them.
+package fileutil
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	lockSuffix   = ".lock"
+	lockTimeout  = 10 * time.Second
+	lockPoll     = 50 * time.Millisecond
+	staleLockAge = time.Minute
+)
+
+// ErrLocked is returned (wrapped) when a lock stays held past the timeout.
+var ErrLocked = errors.New("file is locked")
+
+// WriteAtomic replaces path with data through a temporary file in the same
+// directory and a rename, so readers see the old or the new content, never a
+// partial write. A symlinked path is written through to its target, and an
+// existing file keeps its mode; perm applies to new files.
+func WriteAtomic(path string, data []byte, perm os.FileMode) error {
+	return writeAtomic(path, data, perm, true)
+}
+
+func writeAtomic(path string, data []byte, perm os.FileMode, keepMode bool) error {
+	if target, err := filepath.EvalSymlinks(path); err == nil {
+		path = target
+	}
+	if info, err := os.Stat(path); err == nil && keepMode {
+		perm = info.Mode().Perm()
+	}
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName)
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpName, perm); err != nil {
+		return err
+	}
+	return os.Rename(tmpName, path)
+}
+
+// Lock takes the advisory lock for path: the file <path>.lock, created
+// exclusively, which is also what kubectl uses for kubeconfigs. It waits up
+// to lockTimeout for another holder and breaks locks older than
+// staleLockAge, left behind by a crashed process. Call the returned
+// function to release it.
+func Lock(path string) (func(), error) {
+	lockPath := path + lockSuffix
+	if err := os.MkdirAll(filepath.Dir(lockPath), 0o755); err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		f, err := os.OpenFile(lockPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if err == nil {
+			fmt.Fprintf(f, "%d\n", os.Getpid())
+			f.Close()
+			return func() { _ = os.Remove(lockPath) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+		if info, statErr := os.Stat(lockPath); statErr == nil && time.Since(info.ModTime()) > staleLockAge {
+			_ = os.Remove(lockPath)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%s is in use by another process (delete %s if none is running): %w", path, lockPath, ErrLocked)
+		}
+		time.Sleep(lockPoll)
+	}
+}
+
+// WriteLocked writes path atomically while holding its lock.
+func WriteLocked(path string, data []byte, perm os.FileMode) error {
+	unlock, err := Lock(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return WriteAtomic(path, data, perm)
+}
+
+// WritePrivate is WriteLocked for files only the user should read: the
+// result has perm even when the file existed with a wider mode.
+func WritePrivate(path string, data []byte, perm os.FileMode) error {
+	unlock, err := Lock(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return writeAtomic(path, data, perm, false)
+}
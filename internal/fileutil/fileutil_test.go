@@ -0,0 +1,95 @@
+package fileutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWriteAtomicFollowsSymlinkAndKeepsMode(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "dotfiles", "config")
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(target, []byte("old"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "config")
+	if err := os.Symlink(target, link); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := WriteAtomic(link, []byte("new"), 0o644); err != nil {
+		t.Fatalf("WriteAtomic returned error: %v", err)
+	}
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Fatalf("symlink replaced: %v", err)
+	}
+	info, err := os.Stat(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(target); string(data) != "new" || info.Mode().Perm() != 0o600 {
+		t.Fatalf("target=%q mode=%v want new, 0600", data, info.Mode().Perm())
+	}
+	entries, _ := os.ReadDir(filepath.Dir(target))
+	if len(entries) != 1 {
+		t.Fatalf("temporary files left behind: %v", entries)
+	}
+}
+
+func TestLockWaitsAndBreaksStaleLocks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	unlock, err := Lock(path)
+	if err != nil {
+		t.Fatalf("Lock returned error: %v", err)
+	}
+	released := make(chan struct{})
+	go func() {
+		time.Sleep(3 * lockPoll)
+		unlock()
+		close(released)
+	}()
+	second, err := Lock(path)
+	if err != nil {
+		t.Fatalf("second Lock returned error: %v", err)
+	}
+	<-released
+	second()
+
+	// A lock left by a crashed process is broken once it is stale.
+	if err := os.WriteFile(path+lockSuffix, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * staleLockAge)
+	if err := os.Chtimes(path+lockSuffix, old, old); err != nil {
+		t.Fatal(err)
+	}
+	third, err := Lock(path)
+	if err != nil {
+		t.Fatalf("stale lock not broken: %v", err)
+	}
+	third()
+	if _, err := os.Stat(path + lockSuffix); !os.IsNotExist(err) {
+		t.Fatalf("lock file left after unlock: %v", err)
+	}
+}
+
+func TestWritePrivateTightensMode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	if err := os.WriteFile(path, []byte("old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := WritePrivate(path, []byte("new"), 0o600); err != nil {
+		t.Fatalf("WritePrivate returned error: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Fatalf("mode=%v want 0600", info.Mode().Perm())
+	}
+}
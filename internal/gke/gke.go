@@ -0,0 +1,207 @@
+// Package gke discovers Google Kubernetes Engine clusters through the
+// container API, authenticated with Application Default Credentials.
+package gke
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/phenixrizen/rift/internal/discovery"
+	"github.com/phenixrizen/rift/internal/state"
+	"golang.org/x/oauth2/google"
+	"golang.org/x/sync/errgroup"
+)
+
+// OpListClusters is the Failure.Op of a project whose clusters could not be
+// listed.
+const OpListClusters = "gke_list_clusters"
+
+const (
+	containerAPI = "https://container.googleapis.com/v1"
+	scope        = "https://www.googleapis.com/auth/cloud-platform"
+)
+
+type cluster struct {
+	ID                   string `json:"id"`
+	Name                 string `json:"name"`
+	Location             string `json:"location"`
+	Endpoint             string `json:"endpoint"`
+	Status               string `json:"status"`
+	CurrentMasterVersion string `json:"currentMasterVersion"`
+	CreateTime           string `json:"createTime"`
+	MasterAuth           struct {
+		ClusterCACertificate string `json:"clusterCaCertificate"`
+	} `json:"masterAuth"`
+	PrivateClusterConfig struct {
+		PrivateEndpoint string `json:"privateEndpoint"`
+	} `json:"privateClusterConfig"`
+	ResourceLabels map[string]string `json:"resourceLabels"`
+}
+
+type listResponse struct {
+	Clusters []cluster `json:"clusters"`
+	// MissingZones lists zones that could not be reached; their clusters
+	// are absent from Clusters.
+	MissingZones []string `json:"missingZones"`
+}
+
+// Discover lists the clusters of every project in all locations. ADC come
+// from GOOGLE_APPLICATION_CREDENTIALS, `gcloud auth application-default
+// login`, or the metadata server. Projects that cannot be listed (including
+// every project when no credentials are found) are returned as failures.
+func Discover(ctx context.Context, projects []string, logger *slog.Logger) ([]discovery.ClusterAccess, []discovery.Failure) {
+	if len(projects) == 0 {
+		return nil, nil
+	}
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+	client, err := google.DefaultClient(ctx, scope)
+	if err != nil {
+		failures := make([]discovery.Failure, 0, len(projects))
+		for _, project := range projects {
+			failures = append(failures, failure(project, err))
+		}
+		return nil, failures
+	}
+
+	var (
+		mu       sync.Mutex
+		clusters []discovery.ClusterAccess
+		failures []discovery.Failure
+	)
+	var g errgroup.Group
+	g.SetLimit(4)
+	for _, project := range projects {
+		g.Go(func() error {
+			found, err := listClusters(ctx, client, containerAPI, project)
+			mu.Lock()
+			defer mu.Unlock()
+			clusters = append(clusters, found...)
+			if err != nil {
+				logger.Warn("list gke clusters failed", "project", project, "error", err)
+				failures = append(failures, failure(project, err))
+				return nil
+			}
+			logger.Debug("listed gke clusters", "project", project, "clusters", len(found))
+			return nil
+		})
+	}
+	_ = g.Wait()
+	sort.Slice(clusters, func(i, j int) bool {
+		left := clusters[i].AccountID + "|" + clusters[i].Region + "|" + clusters[i].ClusterName
+		right := clusters[j].AccountID + "|" + clusters[j].Region + "|" + clusters[j].ClusterName
+		return left < right
+	})
+	sort.Slice(failures, func(i, j int) bool { return failures[i].AccountID < failures[j].AccountID })
+	return clusters, failures
+}
+
+// listClusters calls projects.locations.clusters.list with the "-" wildcard
+// location. Clusters in reachable zones are returned even when some zones
+// were unavailable.
+func listClusters(ctx context.Context, client *http.Client, base, project string) ([]discovery.ClusterAccess, error) {
+	endpoint := fmt.Sprintf("%s/projects/%s/locations/-/clusters", base, url.PathEscape(project))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp.StatusCode, body)
+	}
+	var parsed listResponse
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return nil, fmt.Errorf("decode clusters: %w", err)
+	}
+	out := make([]discovery.ClusterAccess, 0, len(parsed.Clusters))
+	for _, c := range parsed.Clusters {
+		out = append(out, clusterAccess(project, c))
+	}
+	if len(parsed.MissingZones) > 0 {
+		return out, fmt.Errorf("zones unavailable: %s", strings.Join(parsed.MissingZones, ", "))
+	}
+	return out, nil
+}
+
+// clusterAccess maps a GKE cluster to the shape EKS discovery produces: the
+// project stands in for the account and the location for the region.
+func clusterAccess(project string, c cluster) discovery.ClusterAccess {
+	endpoint := c.Endpoint
+	if endpoint == "" {
+		endpoint = c.PrivateClusterConfig.PrivateEndpoint
+	}
+	if endpoint != "" && !strings.HasPrefix(endpoint, "https://") {
+		endpoint = "https://" + endpoint
+	}
+	var created time.Time
+	if t, err := time.Parse(time.RFC3339, c.CreateTime); err == nil {
+		created = t.UTC()
+	}
+	return discovery.ClusterAccess{
+		AccountID:                project,
+		AccountName:              project,
+		Region:                   c.Location,
+		ClusterName:              c.Name,
+		ClusterEndpoint:          endpoint,
+		ClusterCertificateBase64: c.MasterAuth.ClusterCACertificate,
+		ClusterID:                c.ID,
+		Platform:                 state.PlatformGKE,
+		KubernetesVersion:        c.CurrentMasterVersion,
+		Status:                   c.Status,
+		CreatedAt:                created,
+		AWSTags:                  c.ResourceLabels,
+	}
+}
+
+// apiError is a non-200 container API response.
+type apiError struct {
+	Status  int
+	Message string
+}
+
+func (e *apiError) Error() string {
+	if e.Message == "" {
+		return "container API returned " + http.StatusText(e.Status)
+	}
+	return http.StatusText(e.Status) + ": " + e.Message
+}
+
+func newAPIError(status int, body []byte) error {
+	var parsed struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	_ = json.Unmarshal(body, &parsed)
+	return &apiError{Status: status, Message: parsed.Error.Message}
+}
+
+func failure(project string, err error) discovery.Failure {
+	var apiErr *apiError
+	return discovery.Failure{
+		Op:          OpListClusters,
+		AccountID:   project,
+		AccountName: project,
+		Throttled:   errors.As(err, &apiErr) && apiErr.Status == http.StatusTooManyRequests,
+		Err:         err.Error(),
+	}
+}
@@ -0,0 +1,74 @@
+package gke
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/phenixrizen/rift/internal/state"
+)
+
+func TestListClustersMapsToClusterAccess(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/projects/shop-prod/locations/-/clusters" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`{"clusters": [{
+			"id": "abc123",
+			"name": "checkout",
+			"location": "us-central1",
+			"endpoint": "34.1.2.3",
+			"status": "RUNNING",
+			"currentMasterVersion": "1.30.5-gke.1014001",
+			"createTime": "2024-03-01T10:00:00+00:00",
+			"masterAuth": {"clusterCaCertificate": "Q0E="},
+			"resourceLabels": {"team": "payments"}
+		}, {
+			"name": "internal",
+			"location": "us-central1-a",
+			"privateClusterConfig": {"privateEndpoint": "10.0.0.2"}
+		}]}`))
+	}))
+	defer srv.Close()
+
+	got, err := listClusters(context.Background(), srv.Client(), srv.URL, "shop-prod")
+	if err != nil {
+		t.Fatalf("listClusters() error = %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("listClusters() returned %d clusters, want 2", len(got))
+	}
+	c := got[0]
+	if c.AccountID != "shop-prod" || c.Region != "us-central1" || c.ClusterName != "checkout" || c.Platform != state.PlatformGKE {
+		t.Fatalf("cluster = %+v", c)
+	}
+	if c.ClusterEndpoint != "https://34.1.2.3" || c.ClusterCertificateBase64 != "Q0E=" {
+		t.Fatalf("endpoint/CA = %q/%q", c.ClusterEndpoint, c.ClusterCertificateBase64)
+	}
+	if !c.CreatedAt.Equal(time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)) || c.AWSTags["team"] != "payments" {
+		t.Fatalf("metadata = %v %v", c.CreatedAt, c.AWSTags)
+	}
+	if got[1].ClusterEndpoint != "https://10.0.0.2" {
+		t.Fatalf("private endpoint = %q, want https://10.0.0.2", got[1].ClusterEndpoint)
+	}
+}
+
+func TestListClustersReportsAPIErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(`{"error": {"code": 429, "message": "Quota exceeded"}}`))
+	}))
+	defer srv.Close()
+
+	_, err := listClusters(context.Background(), srv.Client(), srv.URL, "shop-prod")
+	if err == nil {
+		t.Fatal("listClusters() error = nil, want quota error")
+	}
+	f := failure("shop-prod", err)
+	if !f.Throttled || f.String() != "gke_list_clusters shop-prod: throttled: Too Many Requests: Quota exceeded" {
+		t.Fatalf("failure = %+v (%s)", f, f)
+	}
+}
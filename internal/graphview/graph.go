@@ -38,8 +38,8 @@ func Build(st state.State, opts Options) Graph {
 	if opts.Depth < 2 {
 		opts.Depth = 2
 	}
-	if opts.Depth > 4 {
-		opts.Depth = 4
+	if opts.Depth > 5 {
+		opts.Depth = 5
 	}
 	if opts.Env == "" {
 		opts.Env = "all"
@@ -62,8 +62,8 @@ func Build(st state.State, opts Options) Graph {
 		edges[k] = Edge{From: from, To: to}
 	}
 
-	roleRows := filterRoles(st.Roles, opts)
-	clusterRows := filterClusters(st.Clusters, opts)
+	roleRows := FilterRoles(st.Roles, opts)
+	clusterRows := FilterClusters(st.Clusters, opts)
 
 	accountsByEnv := map[string]map[string]struct{}{}
 	for _, role := range roleRows {
@@ -90,17 +90,18 @@ func Build(st state.State, opts Options) Graph {
 		addNode(envID, env+"-accounts ("+itoa(len(accountsByEnv[env]))+")", "env", 0)
 	}
 
-	for _, role := range roleRows {
-		envID := "env:" + role.Env
-		accountID := "acct:" + role.Env + ":" + role.AccountID
-		accountLabel := role.AccountName
-		if strings.TrimSpace(accountLabel) == "" {
-			accountLabel = role.AccountID
-		} else {
-			accountLabel = accountLabel + " (" + role.AccountID + ")"
+	addAccount := func(env, id, label string) string {
+		accountID := "acct:" + env + ":" + id
+		if id != "" && label != id {
+			label = label + " (" + id + ")"
 		}
-		addNode(accountID, accountLabel, "account", 1)
-		addEdge(envID, accountID)
+		addNode(accountID, label, "account", 1)
+		addEdge("env:"+env, accountID)
+		return accountID
+	}
+
+	for _, role := range roleRows {
+		accountID := addAccount(role.Env, role.AccountID, role.AccountLabel())
 
 		if opts.Depth >= 2 {
 			roleID := "role:" + role.Env + ":" + role.AccountID + ":" + role.RoleName
@@ -109,12 +110,23 @@ func Build(st state.State, opts Options) Graph {
 		}
 	}
 
+	// Projects and subscriptions of clusters on other clouds (GKE, AKS) have
+	// no roles; their clusters hang off the account node directly.
+	for _, cluster := range clusterRows {
+		if !cluster.IsAWS() {
+			addAccount(cluster.Env, cluster.AccountID, cluster.AccountLabel())
+		}
+	}
+
 	if opts.Depth >= 3 {
 		for _, cluster := range clusterRows {
-			roleID := "role:" + cluster.Env + ":" + cluster.AccountID + ":" + cluster.RoleName
-			clusterID := "cluster:" + cluster.Env + ":" + cluster.AccountID + ":" + cluster.RoleName + ":" + cluster.Region + ":" + cluster.ClusterName
+			parentID := "role:" + cluster.Env + ":" + cluster.AccountID + ":" + cluster.RoleName
+			if !cluster.IsAWS() {
+				parentID = "acct:" + cluster.Env + ":" + cluster.AccountID
+			}
+			clusterID := ClusterNodeID(cluster)
 			addNode(clusterID, cluster.ClusterName+" ["+cluster.Region+"]", "cluster", 3)
-			addEdge(roleID, clusterID)
+			addEdge(parentID, clusterID)
 
 			if opts.Depth >= 4 && opts.Namespaces {
 				namespaces := normalizeNamespaces(cluster)
@@ -122,6 +134,25 @@ func Build(st state.State, opts Options) Graph {
 					nsID := clusterID + ":ns:" + ns
 					addNode(nsID, ns, "namespace", 4)
 					addEdge(clusterID, nsID)
+					// Depth 5 adds what runs in it (discover_workloads).
+					if w, ok := cluster.WorkloadsIn(ns); ok && opts.Depth >= 5 {
+						wID := nsID + ":workloads"
+						addNode(wID, w.Label(), "workloads", 5)
+						addEdge(nsID, wID)
+					}
+				}
+			}
+			// Depth 5 adds the cluster's compute (discover_compute).
+			if opts.Depth >= 5 {
+				for _, ng := range cluster.Nodegroups {
+					ngID := clusterID + ":ng:" + ng.Name
+					addNode(ngID, "nodegroup "+ng.Label(), "nodegroup", 4)
+					addEdge(clusterID, ngID)
+				}
+				for _, fp := range cluster.FargateProfiles {
+					fpID := clusterID + ":fargate:" + fp.Name
+					addNode(fpID, "fargate "+fp.Label(), "fargate", 4)
+					addEdge(clusterID, fpID)
 				}
 			}
 		}
@@ -151,13 +182,20 @@ func Build(st state.State, opts Options) Graph {
 	return out
 }
 
-func filterRoles(roles []state.RoleRecord, opts Options) []state.RoleRecord {
+// ClusterNodeID is the ID Build gives cluster's node.
+func ClusterNodeID(cluster state.ClusterRecord) string {
+	return "cluster:" + cluster.Env + ":" + cluster.AccountID + ":" + cluster.RoleName + ":" + cluster.Region + ":" + cluster.ClusterName
+}
+
+// FilterRoles keeps roles matching opts' Env, Account, and Role the way
+// FilterClusters does.
+func FilterRoles(roles []state.RoleRecord, opts Options) []state.RoleRecord {
 	out := make([]state.RoleRecord, 0, len(roles))
 	for _, role := range roles {
-		if opts.Env != "all" && role.Env != opts.Env {
+		if opts.Env != "" && opts.Env != "all" && role.Env != opts.Env {
 			continue
 		}
-		if !matchAny(role.AccountName+" "+role.AccountID, opts.Account) {
+		if !matchAny(role.AccountName+" "+role.AccountAlias+" "+role.AccountID, opts.Account) {
 			continue
 		}
 		if !matchAny(role.RoleName, opts.Role) {
@@ -168,13 +206,15 @@ func filterRoles(roles []state.RoleRecord, opts Options) []state.RoleRecord {
 	return out
 }
 
-func filterClusters(clusters []state.ClusterRecord, opts Options) []state.ClusterRecord {
+// FilterClusters keeps clusters matching opts: Env exactly ("" or "all"
+// match everything), the other fields as case-insensitive substrings.
+func FilterClusters(clusters []state.ClusterRecord, opts Options) []state.ClusterRecord {
 	out := make([]state.ClusterRecord, 0, len(clusters))
 	for _, cluster := range clusters {
-		if opts.Env != "all" && cluster.Env != opts.Env {
+		if opts.Env != "" && opts.Env != "all" && cluster.Env != opts.Env {
 			continue
 		}
-		if !matchAny(cluster.AccountName+" "+cluster.AccountID, opts.Account) {
+		if !matchAny(cluster.AccountName+" "+cluster.AccountAlias+" "+cluster.AccountID, opts.Account) {
 			continue
 		}
 		if !matchAny(cluster.RoleName, opts.Role) {
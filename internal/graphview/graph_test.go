@@ -0,0 +1,46 @@
+package graphview
+
+import (
+	"testing"
+
+	"github.com/phenixrizen/rift/internal/state"
+)
+
+func TestBuildWorkloadsLayer(t *testing.T) {
+	st := state.State{
+		Roles: []state.RoleRecord{{Env: "prod", AccountID: "1", AccountName: "acme", RoleName: "Admin"}},
+		Clusters: []state.ClusterRecord{{
+			Env: "prod", AccountID: "1", AccountName: "acme", RoleName: "Admin", Region: "us-east-1", ClusterName: "pay",
+			Namespaces: []string{"kafka", "payments"},
+			Workloads: []state.NamespaceWorkloads{
+				{Namespace: "payments", Deployments: 3, StatefulSets: 1},
+			},
+		}},
+	}
+	labels := func(g Graph, kind string) []string {
+		var out []string
+		for _, n := range g.Nodes {
+			if n.Kind == kind {
+				out = append(out, n.Label)
+			}
+		}
+		return out
+	}
+
+	if got := labels(Build(st, Options{Depth: 4, Namespaces: true}), "workloads"); len(got) != 0 {
+		t.Fatalf("depth 4 workloads = %v", got)
+	}
+	graph := Build(st, Options{Depth: 5, Namespaces: true})
+	got := labels(graph, "workloads")
+	if len(got) != 1 || got[0] != "3 deployments, 1 statefulset" {
+		t.Fatalf("depth 5 workloads = %v", got)
+	}
+	want := Edge{From: "cluster:prod:1:Admin:us-east-1:pay:ns:payments", To: "cluster:prod:1:Admin:us-east-1:pay:ns:payments:workloads"}
+	found := false
+	for _, e := range graph.Edges {
+		found = found || e == want
+	}
+	if !found {
+		t.Fatalf("missing namespace -> workloads edge in %v", graph.Edges)
+	}
+}
@@ -0,0 +1,205 @@
+package graphview
+
+import (
+	"html/template"
+	"strings"
+)
+
+// RenderHTML renders graph as a self-contained HTML page: a layered tree
+// laid out in the browser by inline script, with search and click to
+// collapse or expand a node's children. It loads nothing from the network.
+func RenderHTML(graph Graph, title string) (string, error) {
+	if strings.TrimSpace(title) == "" {
+		title = "rift topology"
+	}
+	if graph.Nodes == nil {
+		graph.Nodes = []Node{}
+	}
+	if graph.Edges == nil {
+		graph.Edges = []Edge{}
+	}
+	var b strings.Builder
+	err := htmlPage.Execute(&b, struct {
+		Title string
+		Graph Graph
+	}{Title: title, Graph: graph})
+	if err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+var htmlPage = template.Must(template.New("graph").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body { margin: 0; font: 13px -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; background: #10151c; color: #d8dee9; }
+header { position: sticky; top: 0; z-index: 1; display: flex; gap: 8px; align-items: center; padding: 10px 16px; background: #161d27; border-bottom: 1px solid #2a3442; }
+header h1 { margin: 0 12px 0 0; font-size: 15px; color: #5fd7ff; }
+header input { width: 280px; padding: 5px 8px; border: 1px solid #2a3442; border-radius: 4px; background: #10151c; color: inherit; }
+header button { padding: 5px 10px; border: 1px solid #2a3442; border-radius: 4px; background: #1f2935; color: inherit; cursor: pointer; }
+header span { color: #8a96a8; }
+svg { display: block; }
+.edge { fill: none; stroke: #3b4758; stroke-width: 1.2; }
+.node { cursor: pointer; }
+.node circle { stroke-width: 2; }
+.node text { fill: #d8dee9; dominant-baseline: middle; }
+.node.collapsed circle { stroke-dasharray: 3 2; }
+.node.dim { opacity: 0.25; }
+.edge.dim { opacity: 0.15; }
+.node.match text { fill: #ffd75f; font-weight: bold; }
+.env circle { fill: #5fd7ff; stroke: #5fd7ff; }
+.account circle { fill: #87afff; stroke: #87afff; }
+.role circle { fill: #d787ff; stroke: #d787ff; }
+.cluster circle { fill: #00d787; stroke: #00d787; }
+.namespace circle { fill: #afaf87; stroke: #afaf87; }
+.nodegroup circle, .fargate circle, .workloads circle { fill: #ff875f; stroke: #ff875f; }
+</style>
+</head>
+<body>
+<header>
+<h1>{{.Title}}</h1>
+<input id="search" type="search" placeholder="Search accounts, roles, clusters..." autofocus>
+<button id="expand">Expand all</button>
+<button id="collapse">Collapse all</button>
+<span id="status"></span>
+</header>
+<svg id="graph"></svg>
+<script>
+(function () {
+  const graph = {{.Graph}};
+  const ns = "http://www.w3.org/2000/svg";
+  const rowHeight = 22, colWidth = 280, radius = 5, margin = 24;
+  const byID = new Map(graph.nodes.map(n => [n.id, n]));
+  const children = new Map(), parents = new Map();
+  for (const e of graph.edges) {
+    if (!byID.has(e.from) || !byID.has(e.to)) continue;
+    if (!children.has(e.from)) children.set(e.from, []);
+    children.get(e.from).push(e.to);
+    parents.set(e.to, e.from);
+  }
+  const byLabel = (a, b) => byID.get(a).label.localeCompare(byID.get(b).label);
+  for (const kids of children.values()) kids.sort(byLabel);
+  const roots = graph.nodes.filter(n => !parents.has(n.id)).map(n => n.id).sort(byLabel);
+  const collapsed = new Set();
+  let query = "";
+
+  function matches(id) {
+    return query !== "" && byID.get(id).label.toLowerCase().includes(query);
+  }
+
+  // Lays out visible nodes as a tidy tree: leaves take the next row, a
+  // parent sits midway between its first and last child.
+  function layout() {
+    const pos = new Map(), seen = new Set();
+    let row = 0;
+    function place(id) {
+      seen.add(id);
+      const kids = collapsed.has(id) ? [] : (children.get(id) || []).filter(k => !seen.has(k));
+      let y;
+      if (kids.length === 0) {
+        y = row++;
+      } else {
+        const ys = kids.map(place);
+        y = (ys[0] + ys[ys.length - 1]) / 2;
+      }
+      pos.set(id, { x: byID.get(id).layer, y: y });
+      return y;
+    }
+    for (const id of roots) {
+      if (!seen.has(id)) place(id);
+    }
+    return { pos: pos, rows: row };
+  }
+
+  function el(name, attrs, parent) {
+    const node = document.createElementNS(ns, name);
+    for (const k in attrs) node.setAttribute(k, attrs[k]);
+    parent.appendChild(node);
+    return node;
+  }
+
+  function render() {
+    const svg = document.getElementById("graph");
+    svg.replaceChildren();
+    const { pos, rows } = layout();
+    const maxLayer = Math.max(0, ...graph.nodes.map(n => n.layer));
+    svg.setAttribute("width", margin * 2 + (maxLayer + 1) * colWidth);
+    svg.setAttribute("height", margin * 2 + Math.max(rows, 1) * rowHeight);
+    const at = p => ({ x: margin + p.x * colWidth, y: margin + p.y * rowHeight });
+
+    let hits = 0;
+    const lit = new Set();
+    if (query !== "") {
+      for (const n of graph.nodes) {
+        if (!matches(n.id)) continue;
+        hits++;
+        for (let id = n.id; id; id = parents.get(id)) lit.add(id);
+      }
+    }
+    const dim = id => query !== "" && !lit.has(id);
+
+    const edges = el("g", {}, svg), nodes = el("g", {}, svg);
+    for (const [id, p] of pos) {
+      const parent = parents.get(id);
+      if (!parent || !pos.has(parent)) continue;
+      const a = at(pos.get(parent)), b = at(p), mid = (a.x + b.x) / 2;
+      el("path", {
+        d: "M" + a.x + "," + a.y + " C" + mid + "," + a.y + " " + mid + "," + b.y + " " + b.x + "," + b.y,
+        class: "edge" + (dim(id) ? " dim" : "")
+      }, edges);
+    }
+    for (const [id, p] of pos) {
+      const n = byID.get(id), c = at(p);
+      const kids = children.get(id) || [];
+      let cls = "node " + n.kind;
+      if (collapsed.has(id) && kids.length > 0) cls += " collapsed";
+      if (matches(id)) cls += " match";
+      if (dim(id)) cls += " dim";
+      const g = el("g", { class: cls, transform: "translate(" + c.x + "," + c.y + ")" }, nodes);
+      el("circle", { r: radius }, g);
+      const text = el("text", { x: radius + 6 }, g);
+      text.textContent = n.label + (collapsed.has(id) && kids.length > 0 ? " (+" + kids.length + ")" : "");
+      el("title", {}, g).textContent = n.kind + ": " + n.label;
+      if (kids.length > 0) {
+        g.addEventListener("click", () => {
+          if (collapsed.has(id)) collapsed.delete(id); else collapsed.add(id);
+          render();
+        });
+      }
+    }
+    document.getElementById("status").textContent = query === ""
+      ? graph.nodes.length + " nodes"
+      : hits + " of " + graph.nodes.length + " nodes match";
+  }
+
+  document.getElementById("search").addEventListener("input", e => {
+    query = e.target.value.trim().toLowerCase();
+    // Open every collapsed ancestor of a match so it is on screen.
+    if (query !== "") {
+      for (const n of graph.nodes) {
+        if (!matches(n.id)) continue;
+        for (let id = parents.get(n.id); id; id = parents.get(id)) collapsed.delete(id);
+      }
+    }
+    render();
+  });
+  document.getElementById("expand").addEventListener("click", () => {
+    collapsed.clear();
+    render();
+  });
+  document.getElementById("collapse").addEventListener("click", () => {
+    for (const n of graph.nodes) {
+      if (n.layer >= 1 && children.has(n.id)) collapsed.add(n.id);
+    }
+    render();
+  });
+  render();
+})();
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,44 @@
+package graphview
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/phenixrizen/rift/internal/state"
+)
+
+func TestRenderHTMLEmbedsGraph(t *testing.T) {
+	st := state.State{
+		Roles: []state.RoleRecord{{Env: "prod", AccountID: "1", AccountName: "acme", RoleName: "Admin"}},
+		Clusters: []state.ClusterRecord{{
+			Env: "prod", AccountID: "1", AccountName: "acme", RoleName: "Admin",
+			Region: "us-east-1", ClusterName: "</script><b>pay",
+		}},
+	}
+	page, err := RenderHTML(Build(st, Options{Depth: 3}), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(page, "<title>rift topology</title>") {
+		t.Fatal("missing default title")
+	}
+	if !strings.Contains(page, `"kind":"cluster"`) || !strings.Contains(page, `"label":"Admin"`) {
+		t.Fatalf("graph data not embedded:\n%s", page)
+	}
+	if strings.Count(page, "</script>") != 1 || strings.Contains(page, "<b>pay") {
+		t.Fatal("node label not escaped inside the script")
+	}
+	for _, needle := range []string{"http://", "https://"} {
+		if i := strings.Index(page, needle); i >= 0 && !strings.HasPrefix(page[i:], "http://www.w3.org/") {
+			t.Fatalf("page references the network: %s", page[i:i+40])
+		}
+	}
+
+	empty, err := RenderHTML(Graph{}, "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(empty, `{"nodes":[],"edges":[]}`) {
+		t.Fatal("empty graph should embed empty lists")
+	}
+}
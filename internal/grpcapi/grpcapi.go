@@ -0,0 +1,161 @@
+// Package grpcapi implements the rift.v1.Rift gRPC service over the same
+// state reads and sync trigger as the HTTP API.
+package grpcapi
+
+import (
+	"context"
+	"errors"
+
+	riftv1 "github.com/phenixrizen/rift/api/rift/v1"
+	"github.com/phenixrizen/rift/internal/graphview"
+	"github.com/phenixrizen/rift/internal/httpapi"
+	"github.com/phenixrizen/rift/internal/state"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Service answers rift.v1.Rift. API and Switch are required; syncs go through
+// API.Sync, so the HTTP and gRPC APIs share one sync lock and one set of
+// metrics.
+type Service struct {
+	riftv1.UnimplementedRiftServer
+
+	API *httpapi.Server
+	// Switch switches kubectl to the exact context name, setting its default
+	// namespace first when namespace is set, and returns what rift use
+	// prints.
+	Switch func(ctx context.Context, kubeContext, namespace string) (string, error)
+}
+
+// Register adds s to srv.
+func (s *Service) Register(srv *grpc.Server) {
+	riftv1.RegisterRiftServer(srv, s)
+}
+
+func (s *Service) state() (state.State, error) {
+	st, err := s.API.State()
+	if err != nil {
+		return st, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return st, nil
+}
+
+func (s *Service) ListClusters(_ context.Context, req *riftv1.ListClustersRequest) (*riftv1.ListClustersResponse, error) {
+	st, err := s.state()
+	if err != nil {
+		return nil, err
+	}
+	resp := &riftv1.ListClustersResponse{}
+	for _, c := range graphview.FilterClusters(st.Clusters, filterOptions(req.GetFilter())) {
+		if !c.HasTags(req.GetTags()) {
+			continue
+		}
+		resp.Clusters = append(resp.Clusters, clusterMessage(c))
+	}
+	return resp, nil
+}
+
+func (s *Service) ListRoles(_ context.Context, req *riftv1.ListRolesRequest) (*riftv1.ListRolesResponse, error) {
+	st, err := s.state()
+	if err != nil {
+		return nil, err
+	}
+	resp := &riftv1.ListRolesResponse{}
+	for _, r := range graphview.FilterRoles(st.Roles, filterOptions(req.GetFilter())) {
+		resp.Roles = append(resp.Roles, &riftv1.Role{
+			AwsProfile:   r.AWSProfile,
+			Env:          r.Env,
+			AccountId:    r.AccountID,
+			AccountName:  r.AccountName,
+			AccountAlias: r.AccountAlias,
+			RoleName:     r.RoleName,
+		})
+	}
+	return resp, nil
+}
+
+func (s *Service) Sync(ctx context.Context, _ *riftv1.SyncRequest) (*riftv1.SyncResponse, error) {
+	result, err := s.API.Sync(ctx)
+	switch {
+	case errors.Is(err, httpapi.ErrSyncRunning):
+		return nil, status.Error(codes.Aborted, err.Error())
+	case err != nil:
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &riftv1.SyncResponse{
+		Clusters: int32(result.Clusters),
+		Roles:    int32(result.Roles),
+		Failures: int32(result.Failures),
+	}, nil
+}
+
+func (s *Service) Graph(_ context.Context, req *riftv1.GraphRequest) (*riftv1.GraphResponse, error) {
+	opts := filterOptions(req.GetFilter())
+	opts.Depth = int(req.GetDepth())
+	if opts.Depth == 0 {
+		opts.Depth = 3
+	}
+	if opts.Depth < 2 || opts.Depth > 5 {
+		return nil, status.Error(codes.InvalidArgument, "depth must be one of 2|3|4|5")
+	}
+	opts.Namespaces = req.GetNamespaces()
+	st, err := s.state()
+	if err != nil {
+		return nil, err
+	}
+	graph := graphview.Build(st, opts)
+	resp := &riftv1.GraphResponse{}
+	for _, n := range graph.Nodes {
+		resp.Nodes = append(resp.Nodes, &riftv1.GraphNode{Id: n.ID, Label: n.Label, Kind: n.Kind, Layer: int32(n.Layer)})
+	}
+	for _, e := range graph.Edges {
+		resp.Edges = append(resp.Edges, &riftv1.GraphEdge{From: e.From, To: e.To})
+	}
+	return resp, nil
+}
+
+func (s *Service) Use(ctx context.Context, req *riftv1.UseRequest) (*riftv1.UseResponse, error) {
+	if req.GetKubeContext() == "" {
+		return nil, status.Error(codes.InvalidArgument, "kube_context is required")
+	}
+	message, err := s.Switch(ctx, req.GetKubeContext(), req.GetNamespace())
+	if err != nil {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return &riftv1.UseResponse{Message: message}, nil
+}
+
+func filterOptions(f *riftv1.Filter) graphview.Options {
+	return graphview.Options{
+		Env:     f.GetEnv(),
+		Account: f.GetAccount(),
+		Role:    f.GetRole(),
+		Region:  f.GetRegion(),
+		Cluster: f.GetCluster(),
+	}
+}
+
+func clusterMessage(c state.ClusterRecord) *riftv1.Cluster {
+	return &riftv1.Cluster{
+		KubeContext:              c.KubeContext,
+		ClusterName:              c.ClusterName,
+		ClusterArn:               c.ClusterARN,
+		Env:                      c.Env,
+		AccountId:                c.AccountID,
+		AccountName:              c.AccountName,
+		AccountAlias:             c.AccountAlias,
+		RoleName:                 c.RoleName,
+		AwsProfile:               c.AWSProfile,
+		Region:                   c.Region,
+		Platform:                 c.Platform,
+		Endpoint:                 c.ClusterEndpoint,
+		CertificateAuthorityData: c.ClusterCertificateBase64,
+		Namespace:                c.Namespace,
+		Namespaces:               c.Namespaces,
+		Tags:                     c.Tags,
+		Favorite:                 c.Favorite,
+		KubernetesVersion:        c.KubernetesVersion,
+		Status:                   c.Status,
+	}
+}
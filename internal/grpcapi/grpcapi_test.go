@@ -0,0 +1,110 @@
+package grpcapi
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	riftv1 "github.com/phenixrizen/rift/api/rift/v1"
+	"github.com/phenixrizen/rift/internal/httpapi"
+	"github.com/phenixrizen/rift/internal/state"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func testClient(t *testing.T, runSync func(context.Context) (httpapi.SyncResult, error)) riftv1.RiftClient {
+	t.Helper()
+	st := state.State{
+		GeneratedAt: time.Unix(1700000000, 0),
+		Roles:       []state.RoleRecord{{AccountID: "1", AccountName: "payments", RoleName: "Admin", Env: "prod"}},
+		Clusters: []state.ClusterRecord{
+			{AccountID: "1", AccountName: "payments", RoleName: "Admin", Region: "us-east-1", ClusterName: "pay", Env: "prod", KubeContext: "rift-prod-pay", Tags: []string{"team-a"}},
+			{AccountID: "2", AccountName: "sandbox", RoleName: "Dev", Region: "us-west-2", ClusterName: "play", Env: "dev", KubeContext: "rift-dev-play"},
+		},
+	}
+	svc := &Service{
+		API: &httpapi.Server{
+			State:   func() (state.State, error) { return st, nil },
+			RunSync: runSync,
+		},
+		Switch: func(_ context.Context, kubeContext, _ string) (string, error) {
+			return "Switched context: " + kubeContext, nil
+		},
+	}
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	svc.Register(srv)
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return riftv1.NewRiftClient(conn)
+}
+
+func TestListAndGraph(t *testing.T) {
+	client := testClient(t, nil)
+	ctx := context.Background()
+
+	clusters, err := client.ListClusters(ctx, &riftv1.ListClustersRequest{Filter: &riftv1.Filter{Env: "prod"}})
+	if err != nil || len(clusters.GetClusters()) != 1 || clusters.GetClusters()[0].GetKubeContext() != "rift-prod-pay" {
+		t.Fatalf("ListClusters(env=prod) = %v, %v", clusters, err)
+	}
+	tagged, err := client.ListClusters(ctx, &riftv1.ListClustersRequest{Tags: []string{"team-a"}})
+	if err != nil || len(tagged.GetClusters()) != 1 {
+		t.Fatalf("ListClusters(tag=team-a) = %v, %v", tagged, err)
+	}
+	roles, err := client.ListRoles(ctx, &riftv1.ListRolesRequest{})
+	if err != nil || len(roles.GetRoles()) != 1 {
+		t.Fatalf("ListRoles = %v, %v", roles, err)
+	}
+	if _, err := client.Graph(ctx, &riftv1.GraphRequest{Depth: 9}); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("Graph(depth=9) error = %v", err)
+	}
+	graph, err := client.Graph(ctx, &riftv1.GraphRequest{})
+	if err != nil || len(graph.GetNodes()) == 0 || len(graph.GetEdges()) == 0 {
+		t.Fatalf("Graph = %v, %v", graph, err)
+	}
+	if _, err := client.Use(ctx, &riftv1.UseRequest{}); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("Use without a context error = %v", err)
+	}
+	use, err := client.Use(ctx, &riftv1.UseRequest{KubeContext: "rift-dev-play"})
+	if err != nil || use.GetMessage() != "Switched context: rift-dev-play" {
+		t.Fatalf("Use = %v, %v", use, err)
+	}
+}
+
+func TestSyncRunning(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	client := testClient(t, func(context.Context) (httpapi.SyncResult, error) {
+		close(started)
+		<-release
+		return httpapi.SyncResult{Clusters: 2, Roles: 1}, nil
+	})
+	ctx := context.Background()
+	done := make(chan error, 1)
+	go func() {
+		resp, err := client.Sync(ctx, &riftv1.SyncRequest{})
+		if err == nil && resp.GetClusters() != 2 {
+			t.Errorf("Sync = %v", resp)
+		}
+		done <- err
+	}()
+	<-started
+	if _, err := client.Sync(ctx, &riftv1.SyncRequest{}); status.Code(err) != codes.Aborted {
+		t.Fatalf("overlapping Sync error = %v", err)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,220 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/phenixrizen/rift/internal/namespaces"
+	"github.com/phenixrizen/rift/internal/state"
+	"golang.org/x/sync/errgroup"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type Status string
+
+const (
+	// StatusOK means a token was issued and the API server answered an
+	// authenticated request.
+	StatusOK Status = "ok"
+	// StatusForbidden means authentication worked but RBAC denied the probe.
+	StatusForbidden Status = "forbidden"
+	// StatusUnauthorized means the API server rejected the token (role not
+	// mapped in the cluster).
+	StatusUnauthorized Status = "unauthorized"
+	// StatusReauth means no token could be issued because SSO credentials are
+	// missing or expired.
+	StatusReauth Status = "reauth"
+	// StatusDead means the endpoint could not be reached or failed otherwise.
+	StatusDead Status = "dead"
+)
+
+type Result struct {
+	Context string        `json:"context"`
+	Cluster string        `json:"cluster"`
+	Region  string        `json:"region"`
+	Status  Status        `json:"status"`
+	Version string        `json:"version,omitempty"`
+	Latency time.Duration `json:"latency_ns"`
+	Error   string        `json:"error,omitempty"`
+}
+
+// Probe fetches a token for the cluster, calls /version, and lists a single
+// namespace so the result reflects real authentication, not anonymous access.
+func Probe(ctx context.Context, cluster state.ClusterRecord) Result {
+	res := Result{Context: cluster.KubeContext, Cluster: cluster.ClusterName, Region: cluster.Region}
+	if !cluster.Connectable() {
+		res.Status = StatusDead
+		res.Error = "cluster has no endpoint in state"
+		if cluster.Platform == state.PlatformConnected {
+			res.Error = "EKS Connector registration has no API endpoint"
+		}
+		return res
+	}
+
+	token, err := namespaces.FetchToken(ctx, cluster)
+	if err != nil {
+		res.Status = classifyTokenError(err)
+		res.Error = err.Error()
+		return res
+	}
+	client, err := namespaces.NewClientWithToken(cluster, token)
+	if err != nil {
+		res.Status = StatusDead
+		res.Error = err.Error()
+		return res
+	}
+
+	start := time.Now()
+	info, err := client.Discovery().ServerVersion()
+	res.Latency = time.Since(start)
+	if err != nil {
+		res.Status = classifyAPIError(err)
+		res.Error = err.Error()
+		return res
+	}
+	res.Version = info.GitVersion
+
+	if _, err := client.CoreV1().Namespaces().List(ctx, metav1.ListOptions{Limit: 1}); err != nil {
+		res.Status = classifyAPIError(err)
+		res.Error = err.Error()
+		return res
+	}
+	res.Status = StatusOK
+	return res
+}
+
+// ProbeAll probes clusters concurrently and returns results in input order.
+func ProbeAll(ctx context.Context, clusters []state.ClusterRecord, concurrency int) []Result {
+	if concurrency <= 0 {
+		concurrency = 8
+	}
+	results := make([]Result, len(clusters))
+	var mu sync.Mutex
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(concurrency)
+	for i, cluster := range clusters {
+		i, cluster := i, cluster
+		g.Go(func() error {
+			res := Probe(gctx, cluster)
+			mu.Lock()
+			results[i] = res
+			mu.Unlock()
+			return nil
+		})
+	}
+	_ = g.Wait()
+	return results
+}
+
+// Reachability is the outcome of Ping.
+type Reachability struct {
+	Context   string        `json:"context"`
+	Cluster   string        `json:"cluster"`
+	Region    string        `json:"region"`
+	Reachable bool          `json:"reachable"`
+	Version   string        `json:"version,omitempty"`
+	Latency   time.Duration `json:"latency_ns"`
+	Error     string        `json:"error,omitempty"`
+}
+
+// Ping calls the cluster's /version with a token for it, or anonymously when
+// none can be issued. Any answer from the API server, a refusal included,
+// counts as reachable: Ping checks the endpoint, not the credentials (see
+// Probe).
+func Ping(ctx context.Context, cluster state.ClusterRecord) Reachability {
+	res := Reachability{Context: cluster.KubeContext, Cluster: cluster.ClusterName, Region: cluster.Region}
+	if !cluster.Connectable() {
+		res.Error = "cluster has no endpoint in state"
+		if cluster.Platform == state.PlatformConnected {
+			res.Error = "EKS Connector registration has no API endpoint"
+		}
+		return res
+	}
+
+	token, tokenErr := namespaces.FetchToken(ctx, cluster)
+	client, err := namespaces.NewClientWithToken(cluster, token)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	start := time.Now()
+	info, err := client.Discovery().ServerVersion()
+	res.Latency = time.Since(start)
+	var status apierrors.APIStatus
+	switch {
+	case err == nil:
+		res.Reachable = true
+		res.Version = info.GitVersion
+	case errors.As(err, &status):
+		res.Reachable = true
+		res.Error = err.Error()
+	default:
+		res.Error = err.Error()
+	}
+	if tokenErr != nil && res.Error == "" {
+		res.Error = "no token: " + tokenErr.Error()
+	}
+	return res
+}
+
+// PingAll pings clusters concurrently and returns results in input order.
+func PingAll(ctx context.Context, clusters []state.ClusterRecord, concurrency int) []Reachability {
+	if concurrency <= 0 {
+		concurrency = 8
+	}
+	results := make([]Reachability, len(clusters))
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(concurrency)
+	for i, cluster := range clusters {
+		g.Go(func() error {
+			results[i] = Ping(gctx, cluster)
+			return nil
+		})
+	}
+	_ = g.Wait()
+	return results
+}
+
+// Summary counts results by status, sorted by status name.
+func Summary(results []Result) []StatusCount {
+	counts := map[Status]int{}
+	for _, r := range results {
+		counts[r.Status]++
+	}
+	out := make([]StatusCount, 0, len(counts))
+	for status, n := range counts {
+		out = append(out, StatusCount{Status: status, Count: n})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Status < out[j].Status })
+	return out
+}
+
+type StatusCount struct {
+	Status Status `json:"status"`
+	Count  int    `json:"count"`
+}
+
+func classifyTokenError(err error) Status {
+	msg := strings.ToLower(err.Error())
+	for _, hint := range []string{"sso", "expired", "token has expired", "refresh", "unauthorizedexception", "login"} {
+		if strings.Contains(msg, hint) {
+			return StatusReauth
+		}
+	}
+	return StatusDead
+}
+
+func classifyAPIError(err error) Status {
+	switch {
+	case apierrors.IsUnauthorized(err):
+		return StatusUnauthorized
+	case apierrors.IsForbidden(err):
+		return StatusForbidden
+	}
+	return StatusDead
+}
@@ -0,0 +1,198 @@
+package history
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/phenixrizen/rift/internal/state"
+)
+
+// Change actions.
+const (
+	Added   = "added"
+	Removed = "removed"
+	Changed = "changed"
+)
+
+// Change is one cluster (Kind "cluster", Name its kube context) or role
+// (Kind "role", Name its AWS profile) that differs between two states.
+type Change struct {
+	Kind   string `json:"kind"`
+	Name   string `json:"name"`
+	Action string `json:"action"`
+	// Account and Region locate the item, since a removed cluster's context
+	// may no longer mean much.
+	Account string `json:"account,omitempty"`
+	Region  string `json:"region,omitempty"`
+	// Fields lists what changed, for Changed only.
+	Fields []FieldChange `json:"fields,omitempty"`
+}
+
+type FieldChange struct {
+	Field  string `json:"field"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// Diff is what changed from one state to the next, clusters first, each
+// kind sorted by name.
+type Diff struct {
+	Changes []Change `json:"changes"`
+}
+
+func (d Diff) Empty() bool {
+	return len(d.Changes) == 0
+}
+
+// Count returns how many changes of kind have action.
+func (d Diff) Count(kind, action string) int {
+	n := 0
+	for _, c := range d.Changes {
+		if c.Kind == kind && c.Action == action {
+			n++
+		}
+	}
+	return n
+}
+
+// Compare returns the clusters and roles added, removed, or changed from
+// before to after. Items are matched by what they are (cluster ARN or
+// account/region/name; session/account/role) rather than by generated
+// name, so a renamed context shows up as a change of kube_context. Tags
+// live in the overlay and are not compared.
+func Compare(before, after state.State) Diff {
+	diff := Diff{Changes: make([]Change, 0)}
+	diff.Changes = append(diff.Changes, compareClusters(before.Clusters, after.Clusters)...)
+	diff.Changes = append(diff.Changes, compareRoles(before.Roles, after.Roles)...)
+	return diff
+}
+
+func compareClusters(before, after []state.ClusterRecord) []Change {
+	old := make(map[string]state.ClusterRecord, len(before))
+	for _, c := range before {
+		old[clusterKey(c)] = c
+	}
+	changes := make([]Change, 0)
+	seen := make(map[string]struct{}, len(after))
+	for _, c := range after {
+		key := clusterKey(c)
+		seen[key] = struct{}{}
+		prev, ok := old[key]
+		if !ok {
+			changes = append(changes, clusterChange(c, Added, nil))
+			continue
+		}
+		if fields := clusterFields(prev, c); len(fields) > 0 {
+			changes = append(changes, clusterChange(c, Changed, fields))
+		}
+	}
+	for _, c := range before {
+		if _, ok := seen[clusterKey(c)]; !ok {
+			changes = append(changes, clusterChange(c, Removed, nil))
+		}
+	}
+	sortChanges(changes)
+	return changes
+}
+
+func compareRoles(before, after []state.RoleRecord) []Change {
+	old := make(map[string]state.RoleRecord, len(before))
+	for _, r := range before {
+		old[roleKey(r)] = r
+	}
+	changes := make([]Change, 0)
+	seen := make(map[string]struct{}, len(after))
+	for _, r := range after {
+		key := roleKey(r)
+		seen[key] = struct{}{}
+		prev, ok := old[key]
+		if !ok {
+			changes = append(changes, roleChange(r, Added, nil))
+			continue
+		}
+		if fields := roleFields(prev, r); len(fields) > 0 {
+			changes = append(changes, roleChange(r, Changed, fields))
+		}
+	}
+	for _, r := range before {
+		if _, ok := seen[roleKey(r)]; !ok {
+			changes = append(changes, roleChange(r, Removed, nil))
+		}
+	}
+	sortChanges(changes)
+	return changes
+}
+
+func clusterKey(c state.ClusterRecord) string {
+	if c.External() {
+		return "external|" + c.KubeContext
+	}
+	if c.ClusterARN != "" {
+		return c.ClusterARN
+	}
+	return strings.Join([]string{c.Platform, c.AccountID, c.Region, c.ClusterName}, "|")
+}
+
+func roleKey(r state.RoleRecord) string {
+	return strings.Join([]string{r.SSOSession, r.AccountID, r.RoleName, r.AssumeRoleARN}, "|")
+}
+
+func clusterChange(c state.ClusterRecord, action string, fields []FieldChange) Change {
+	return Change{Kind: "cluster", Name: c.KubeContext, Action: action, Account: c.AccountLabel(), Region: c.Region, Fields: fields}
+}
+
+func roleChange(r state.RoleRecord, action string, fields []FieldChange) Change {
+	return Change{Kind: "role", Name: r.AWSProfile, Action: action, Account: r.AccountLabel(), Fields: fields}
+}
+
+func clusterFields(before, after state.ClusterRecord) []FieldChange {
+	var fields []FieldChange
+	add := func(name, b, a string) {
+		if b != a {
+			fields = append(fields, FieldChange{Field: name, Before: b, After: a})
+		}
+	}
+	add("kube_context", before.KubeContext, after.KubeContext)
+	add("env", before.Env, after.Env)
+	add("aws_profile", before.AWSProfile, after.AWSProfile)
+	add("cluster_endpoint", before.ClusterEndpoint, after.ClusterEndpoint)
+	if before.ClusterCertificateBase64 != after.ClusterCertificateBase64 {
+		fields = append(fields, FieldChange{Field: "cluster_certificate", Before: "(old)", After: "(rotated)"})
+	}
+	add("kubernetes_version", before.KubernetesVersion, after.KubernetesVersion)
+	add("platform_version", before.PlatformVersion, after.PlatformVersion)
+	add("status", before.Status, after.Status)
+	add("namespace", before.Namespace, after.Namespace)
+	add("namespaces", strings.Join(before.Namespaces, ","), strings.Join(after.Namespaces, ","))
+	add("nodegroups", nodegroupNames(before.Nodegroups), nodegroupNames(after.Nodegroups))
+	return fields
+}
+
+func roleFields(before, after state.RoleRecord) []FieldChange {
+	var fields []FieldChange
+	add := func(name, b, a string) {
+		if b != a {
+			fields = append(fields, FieldChange{Field: name, Before: b, After: a})
+		}
+	}
+	add("aws_profile", before.AWSProfile, after.AWSProfile)
+	add("env", before.Env, after.Env)
+	add("account_name", before.AccountName, after.AccountName)
+	add("source_profile", before.SourceProfile, after.SourceProfile)
+	return fields
+}
+
+func nodegroupNames(groups []state.Nodegroup) string {
+	names := make([]string, 0, len(groups))
+	for _, g := range groups {
+		names = append(names, g.Name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+func sortChanges(changes []Change) {
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].Name < changes[j].Name
+	})
+}
@@ -0,0 +1,144 @@
+// Package history keeps snapshots of state.json from past syncs so `rift
+// diff` can show which clusters and roles appeared, disappeared, or changed.
+package history
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/phenixrizen/rift/internal/state"
+)
+
+const (
+	dirName       = "history"
+	idLayout      = "20060102T150405.000Z"
+	DefaultRetain = 20
+)
+
+// DirFor returns the snapshot directory that sits next to a state file.
+func DirFor(statePath string) string {
+	return filepath.Join(filepath.Dir(statePath), dirName)
+}
+
+// Save snapshots st unless it has the same inventory as the latest
+// snapshot, and prunes the oldest snapshots beyond retain. It returns the
+// new snapshot's ID, or "" when nothing changed. Snapshots are written like
+// state.json, encrypted with sealer when it is not nil.
+func Save(dir string, st state.State, retain int, sealer state.Sealer) (string, error) {
+	ids, err := List(dir)
+	if err != nil {
+		return "", err
+	}
+	if len(ids) > 0 {
+		latest, err := Load(dir, ids[len(ids)-1], sealer)
+		if err != nil {
+			return "", err
+		}
+		if Compare(latest, st).Empty() {
+			return "", nil
+		}
+	}
+	at := st.GeneratedAt
+	if at.IsZero() {
+		at = time.Now()
+	}
+	id := at.UTC().Format(idLayout)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+	if err := state.Save(filepath.Join(dir, id+".json"), st, sealer); err != nil {
+		return "", err
+	}
+	if retain > 0 {
+		if err := prune(dir, retain); err != nil {
+			return id, err
+		}
+	}
+	return id, nil
+}
+
+// List returns snapshot IDs oldest first.
+func List(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	ids := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		ids = append(ids, strings.TrimSuffix(entry.Name(), ".json"))
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// Load reads a snapshot, migrating it like state.json.
+func Load(dir, id string, sealer state.Sealer) (state.State, error) {
+	st, err := state.Load(filepath.Join(dir, id+".json"), sealer)
+	if err != nil {
+		return st, fmt.Errorf("load snapshot %s: %w", id, err)
+	}
+	return st, nil
+}
+
+// Resolve maps "latest", an exact ID, or a unique ID prefix to an ID, and
+// also returns the ID of the snapshot before it (empty for the first).
+func Resolve(dir, ref string) (string, string, error) {
+	ids, err := List(dir)
+	if err != nil {
+		return "", "", err
+	}
+	if len(ids) == 0 {
+		return "", "", fmt.Errorf("no state snapshots recorded yet")
+	}
+	idx := -1
+	switch {
+	case ref == "" || ref == "latest":
+		idx = len(ids) - 1
+	default:
+		for i, id := range ids {
+			if id == ref {
+				idx = i
+				break
+			}
+			if strings.HasPrefix(id, ref) {
+				if idx >= 0 {
+					return "", "", fmt.Errorf("snapshot %q is ambiguous", ref)
+				}
+				idx = i
+			}
+		}
+	}
+	if idx < 0 {
+		return "", "", fmt.Errorf("snapshot %q not found", ref)
+	}
+	prev := ""
+	if idx > 0 {
+		prev = ids[idx-1]
+	}
+	return ids[idx], prev, nil
+}
+
+func prune(dir string, retain int) error {
+	ids, err := List(dir)
+	if err != nil {
+		return err
+	}
+	for len(ids) > retain {
+		if err := os.Remove(filepath.Join(dir, ids[0]+".json")); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		ids = ids[1:]
+	}
+	return nil
+}
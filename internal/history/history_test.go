@@ -0,0 +1,56 @@
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/phenixrizen/rift/internal/state"
+)
+
+func TestCompareAndSaveSkipsUnchangedState(t *testing.T) {
+	at := time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+	main := state.ClusterRecord{KubeContext: "rift-dev-acme-main", AccountID: "111111111111", AccountName: "acme", Region: "us-east-1", ClusterName: "main", ClusterARN: "arn:aws:eks:us-east-1:111111111111:cluster/main", KubernetesVersion: "1.30"}
+	batch := state.ClusterRecord{KubeContext: "rift-dev-acme-batch", AccountID: "111111111111", AccountName: "acme", Region: "us-east-1", ClusterName: "batch", ClusterARN: "arn:aws:eks:us-east-1:111111111111:cluster/batch"}
+	role := state.RoleRecord{AccountID: "111111111111", AccountName: "acme", RoleName: "Admin", AWSProfile: "rift-dev-acme-admin"}
+	before := state.State{GeneratedAt: at, Roles: []state.RoleRecord{role}, Clusters: []state.ClusterRecord{main, batch}}
+
+	upgraded := main
+	upgraded.KubernetesVersion = "1.31"
+	upgraded.KubeContext = "dev-main"
+	added := state.ClusterRecord{KubeContext: "rift-dev-acme-web", AccountID: "111111111111", Region: "us-west-2", ClusterName: "web"}
+	after := state.State{GeneratedAt: at.Add(time.Hour), Roles: []state.RoleRecord{role}, Clusters: []state.ClusterRecord{upgraded, added}}
+
+	diff := Compare(before, after)
+	if got := [3]int{diff.Count("cluster", Added), diff.Count("cluster", Changed), diff.Count("cluster", Removed)}; got != [3]int{1, 1, 1} {
+		t.Fatalf("cluster counts=%v want +1 ~1 -1: %+v", got, diff.Changes)
+	}
+	if diff.Count("role", Added)+diff.Count("role", Changed)+diff.Count("role", Removed) != 0 {
+		t.Fatalf("unexpected role changes: %+v", diff.Changes)
+	}
+	for _, c := range diff.Changes {
+		if c.Action != Changed {
+			continue
+		}
+		if c.Name != "dev-main" || len(c.Fields) != 2 || c.Fields[0].Field != "kube_context" || c.Fields[1].Field != "kubernetes_version" {
+			t.Fatalf("changed=%+v want renamed context and version bump", c)
+		}
+	}
+
+	dir := t.TempDir()
+	for i, st := range []state.State{before, before, after} {
+		if _, err := Save(dir, st, 2, nil); err != nil {
+			t.Fatalf("Save #%d: %v", i, err)
+		}
+	}
+	ids, err := List(dir)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(ids) != 2 {
+		t.Fatalf("ids=%v want one snapshot per distinct state", ids)
+	}
+	latest, prev, err := Resolve(dir, "latest")
+	if err != nil || latest != ids[1] || prev != ids[0] {
+		t.Fatalf("Resolve latest=%q prev=%q err=%v", latest, prev, err)
+	}
+}
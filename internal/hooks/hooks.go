@@ -0,0 +1,74 @@
+// Package hooks runs the user's hooks.* commands: a shell command with a
+// JSON payload on stdin and the event in RIFT_HOOK.
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"time"
+)
+
+// Events, as RIFT_HOOK names them.
+const (
+	PreSync  = "pre_sync"
+	PostSync = "post_sync"
+	PostUse  = "post_use"
+)
+
+// Hook is one configured command.
+type Hook struct {
+	Event   string
+	Command string
+	// Timeout kills the command when it runs longer; 0 means no limit.
+	Timeout time.Duration
+	// Env is added to rift's environment, after RIFT_HOOK.
+	Env []string
+}
+
+// Run runs the hook through the shell (sh -c; cmd /C on Windows) with
+// payload as JSON on stdin, sending its output to out. An empty Command
+// does nothing.
+func (h Hook) Run(ctx context.Context, payload any, out io.Writer) error {
+	if h.Command == "" {
+		return nil
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("%s hook: encode payload: %w", h.Event, err)
+	}
+	if h.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.Timeout)
+		defer cancel()
+	}
+	cmd := shellCommand(ctx, h.Command)
+	cmd.Stdin = bytes.NewReader(append(data, '\n'))
+	cmd.Stdout = out
+	cmd.Stderr = out
+	cmd.Env = append(append(os.Environ(), "RIFT_HOOK="+h.Event), h.Env...)
+	// Without WaitDelay, a background child holding the output open keeps
+	// Wait blocked past the timeout.
+	cmd.WaitDelay = time.Second
+	err = cmd.Run()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%s hook timed out after %s", h.Event, h.Timeout)
+	}
+	if err != nil {
+		return fmt.Errorf("%s hook: %w", h.Event, err)
+	}
+	return nil
+}
+
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+	return exec.CommandContext(ctx, "sh", "-c", command)
+}
@@ -0,0 +1,35 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hooks run through sh in this test")
+	}
+	var out bytes.Buffer
+	hook := Hook{Event: PostUse, Command: `printf '%s %s ' "$RIFT_HOOK" "$RIFT_CONTEXT"; cat`, Env: []string{"RIFT_CONTEXT=rift-prod-pay"}}
+	if err := hook.Run(context.Background(), map[string]string{"context": "rift-prod-pay"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if got := out.String(); got != "post_use rift-prod-pay {\"context\":\"rift-prod-pay\"}\n" {
+		t.Fatalf("hook saw %q", got)
+	}
+
+	if err := (Hook{Event: PreSync, Command: "exit 3"}).Run(context.Background(), nil, &out); err == nil || !strings.Contains(err.Error(), "pre_sync hook") {
+		t.Fatalf("failing hook error = %v", err)
+	}
+	err := (Hook{Event: PostSync, Command: "exec sleep 5", Timeout: 50 * time.Millisecond}).Run(context.Background(), nil, &out)
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("slow hook error = %v", err)
+	}
+	if err := (Hook{Event: PostSync}).Run(context.Background(), nil, &out); err != nil {
+		t.Fatalf("empty hook error = %v", err)
+	}
+}
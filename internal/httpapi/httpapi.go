@@ -0,0 +1,305 @@
+// Package httpapi serves rift's inventory over local HTTP for dashboards and
+// other tools: read-only state endpoints, a sync trigger, Prometheus
+// metrics in the text exposition format, and optionally a small web UI.
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/phenixrizen/rift/internal/graphview"
+	"github.com/phenixrizen/rift/internal/state"
+)
+
+// ErrSyncRunning is returned by Server.Sync while another sync runs.
+var ErrSyncRunning = errors.New("a sync is already running")
+
+// SyncResult summarizes one sync for the /sync response and the metrics.
+type SyncResult struct {
+	Clusters int `json:"clusters"`
+	Roles    int `json:"roles"`
+	// Failures counts discovery failures (accounts, regions, clusters)
+	// the sync worked around.
+	Failures int `json:"failures"`
+}
+
+// Server holds what the endpoints read and the metrics of the syncs it ran.
+// State and RunSync are required.
+type Server struct {
+	State   func() (state.State, error)
+	RunSync func(context.Context) (SyncResult, error)
+	// Search, when set, answers GET /search with the clusters matching a
+	// rift search query; its error is the query's.
+	Search func(st state.State, query string) ([]state.ClusterRecord, error)
+	// Web serves the web UI at / and the graph page it embeds.
+	Web bool
+	// Addr is the address the server listens on. Requests are refused
+	// unless their Host is a loopback name or address, or Addr's host (any
+	// IP address when Addr has none), so a web page cannot reach the API
+	// through a DNS name rebound to this machine.
+	Addr string
+	// Now is the clock; nil means time.Now.
+	Now func() time.Time
+
+	syncing sync.Mutex
+
+	mu       sync.Mutex
+	syncs    map[string]int
+	last     SyncResult
+	lastAt   time.Time
+	lastTook time.Duration
+}
+
+// Handler routes the API:
+//
+//	GET  /clusters  state clusters, filtered by env, account, role, region, cluster
+//	GET  /roles     state roles, filtered by env, account, role
+//	GET  /graph     graphview.Build with the filters plus depth and namespaces
+//	POST /sync      run a sync and return its SyncResult
+//	GET  /metrics   Prometheus metrics
+//	GET  /search    Search results for ?q=, when Search is set
+//
+// With Web, GET / is the web UI and GET /graph.html the graphview.RenderHTML
+// page for the same parameters as /graph.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /clusters", s.withState(func(w http.ResponseWriter, r *http.Request, st state.State) {
+		writeJSON(w, http.StatusOK, graphview.FilterClusters(st.Clusters, filterOptions(r)))
+	}))
+	mux.HandleFunc("GET /roles", s.withState(func(w http.ResponseWriter, r *http.Request, st state.State) {
+		writeJSON(w, http.StatusOK, graphview.FilterRoles(st.Roles, filterOptions(r)))
+	}))
+	mux.HandleFunc("GET /graph", s.withState(func(w http.ResponseWriter, r *http.Request, st state.State) {
+		opts, err := graphOptions(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, graphview.Build(st, opts))
+	}))
+	mux.HandleFunc("POST /sync", s.handleSync)
+	mux.HandleFunc("GET /metrics", s.handleMetrics)
+	if s.Search != nil {
+		mux.HandleFunc("GET /search", s.withState(func(w http.ResponseWriter, r *http.Request, st state.State) {
+			rows, err := s.Search(st, r.URL.Query().Get("q"))
+			if err != nil {
+				writeError(w, http.StatusBadRequest, err)
+				return
+			}
+			writeJSON(w, http.StatusOK, rows)
+		}))
+	}
+	if s.Web {
+		mux.HandleFunc("GET /{$}", s.handleWebUI)
+		mux.HandleFunc("GET /graph.html", s.handleGraphPage)
+	}
+	return s.checkHost(mux)
+}
+
+// checkHost refuses requests for a Host the server does not answer to (see
+// Addr).
+func (s *Server) checkHost(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.allowedHost(r.Host) {
+			writeError(w, http.StatusForbidden, fmt.Errorf("host %q is not allowed", r.Host))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) allowedHost(hostport string) bool {
+	host := hostport
+	if h, _, err := net.SplitHostPort(hostport); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.Trim(host, "[]"), ".")
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	if ip != nil && ip.IsLoopback() {
+		return true
+	}
+	listen := s.Addr
+	if h, _, err := net.SplitHostPort(s.Addr); err == nil {
+		listen = h
+	}
+	if listenIP := net.ParseIP(listen); listen == "" || (listenIP != nil && listenIP.IsUnspecified()) {
+		return ip != nil
+	}
+	return strings.EqualFold(host, strings.TrimSuffix(listen, "."))
+}
+
+// graphOptions reads /graph's parameters: the filters plus depth (2-5,
+// default 3) and namespaces.
+func graphOptions(r *http.Request) (graphview.Options, error) {
+	opts := filterOptions(r)
+	opts.Depth = 3
+	if v := r.URL.Query().Get("depth"); v != "" {
+		d, err := strconv.Atoi(v)
+		if err != nil || d < 2 || d > 5 {
+			return opts, fmt.Errorf("depth must be one of 2|3|4|5")
+		}
+		opts.Depth = d
+	}
+	opts.Namespaces, _ = strconv.ParseBool(r.URL.Query().Get("namespaces"))
+	return opts, nil
+}
+
+func (s *Server) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now()
+}
+
+func (s *Server) withState(h func(http.ResponseWriter, *http.Request, state.State)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		st, err := s.State()
+		if err != nil {
+			writeError(w, http.StatusServiceUnavailable, err)
+			return
+		}
+		h(w, r, st)
+	}
+}
+
+func filterOptions(r *http.Request) graphview.Options {
+	q := r.URL.Query()
+	return graphview.Options{
+		Env:     q.Get("env"),
+		Account: q.Get("account"),
+		Role:    q.Get("role"),
+		Region:  q.Get("region"),
+		Cluster: q.Get("cluster"),
+	}
+}
+
+// Sync runs one sync, unless one is already running, and records it in
+// the metrics.
+func (s *Server) Sync(ctx context.Context) (SyncResult, error) {
+	if !s.syncing.TryLock() {
+		return SyncResult{}, ErrSyncRunning
+	}
+	defer s.syncing.Unlock()
+	start := s.now()
+	result, err := s.RunSync(ctx)
+	took := s.now().Sub(start)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.syncs == nil {
+		s.syncs = map[string]int{}
+	}
+	if err != nil {
+		s.syncs["error"]++
+		return result, err
+	}
+	s.syncs["ok"]++
+	s.last, s.lastAt, s.lastTook = result, start, took
+	return result, nil
+}
+
+func (s *Server) handleSync(w http.ResponseWriter, r *http.Request) {
+	// Browsers send Origin on cross-site requests; a web page must not be
+	// able to trigger syncs through a form post.
+	if r.Header.Get("Origin") != "" {
+		writeError(w, http.StatusForbidden, errors.New("cross-origin requests are not allowed"))
+		return
+	}
+	result, err := s.Sync(r.Context())
+	switch {
+	case errors.Is(err, ErrSyncRunning):
+		writeError(w, http.StatusConflict, err)
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err)
+	default:
+		writeJSON(w, http.StatusOK, result)
+	}
+}
+
+func (s *Server) handleMetrics(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	st, stateErr := s.State()
+
+	s.mu.Lock()
+	syncs := map[string]int{"ok": s.syncs["ok"], "error": s.syncs["error"]}
+	last, lastAt, lastTook := s.last, s.lastAt, s.lastTook
+	s.mu.Unlock()
+
+	metric(w, "rift_syncs_total", "counter", "Syncs run by this server, by result.")
+	for _, result := range []string{"error", "ok"} {
+		fmt.Fprintf(w, "rift_syncs_total{result=%q} %d\n", result, syncs[result])
+	}
+	if !lastAt.IsZero() {
+		metric(w, "rift_last_sync_timestamp_seconds", "gauge", "Start of the last successful sync, as a Unix time.")
+		fmt.Fprintf(w, "rift_last_sync_timestamp_seconds %d\n", lastAt.Unix())
+		metric(w, "rift_last_sync_duration_seconds", "gauge", "How long the last successful sync took.")
+		fmt.Fprintf(w, "rift_last_sync_duration_seconds %g\n", lastTook.Seconds())
+		metric(w, "rift_last_sync_failures", "gauge", "Discovery failures in the last successful sync.")
+		fmt.Fprintf(w, "rift_last_sync_failures %d\n", last.Failures)
+	}
+
+	metric(w, "rift_state_up", "gauge", "Whether state.json could be read.")
+	if stateErr != nil {
+		fmt.Fprintln(w, "rift_state_up 0")
+		return
+	}
+	fmt.Fprintln(w, "rift_state_up 1")
+	metric(w, "rift_state_generated_timestamp_seconds", "gauge", "When state.json was generated, as a Unix time.")
+	fmt.Fprintf(w, "rift_state_generated_timestamp_seconds %d\n", st.GeneratedAt.Unix())
+	metric(w, "rift_roles", "gauge", "Roles in state, by env.")
+	for _, c := range countByEnv(len(st.Roles), func(i int) string { return st.Roles[i].Env }) {
+		fmt.Fprintf(w, "rift_roles{env=%q} %d\n", c.env, c.n)
+	}
+	metric(w, "rift_clusters", "gauge", "Clusters in state, by env.")
+	for _, c := range countByEnv(len(st.Clusters), func(i int) string { return st.Clusters[i].Env }) {
+		fmt.Fprintf(w, "rift_clusters{env=%q} %d\n", c.env, c.n)
+	}
+}
+
+func metric(w io.Writer, name, kind, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+type envCount struct {
+	env string
+	n   int
+}
+
+// countByEnv counts n items by env, sorted by env.
+func countByEnv(n int, env func(int) string) []envCount {
+	counts := map[string]int{}
+	for i := 0; i < n; i++ {
+		counts[env(i)]++
+	}
+	out := make([]envCount, 0, len(counts))
+	for e, c := range counts {
+		out = append(out, envCount{e, c})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].env < out[j].env })
+	return out
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,174 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/phenixrizen/rift/internal/state"
+)
+
+func testServer(t *testing.T, syncErr error) (*Server, *httptest.Server) {
+	t.Helper()
+	st := state.State{
+		GeneratedAt: time.Unix(1700000000, 0),
+		Roles:       []state.RoleRecord{{AccountID: "1", AccountName: "payments", RoleName: "Admin", Env: "prod"}},
+		Clusters: []state.ClusterRecord{
+			{AccountID: "1", AccountName: "payments", RoleName: "Admin", Region: "us-east-1", ClusterName: "pay", Env: "prod", KubeContext: "rift-prod-pay"},
+			{AccountID: "2", AccountName: "sandbox", RoleName: "Dev", Region: "us-west-2", ClusterName: "play", Env: "dev", KubeContext: "rift-dev-play"},
+		},
+	}
+	clock := time.Unix(1700000100, 0)
+	s := &Server{
+		State: func() (state.State, error) { return st, nil },
+		RunSync: func(context.Context) (SyncResult, error) {
+			clock = clock.Add(3 * time.Second)
+			return SyncResult{Clusters: 2, Roles: 1, Failures: 1}, syncErr
+		},
+		Now: func() time.Time { return clock },
+	}
+	srv := httptest.NewServer(s.Handler())
+	t.Cleanup(srv.Close)
+	return s, srv
+}
+
+func get(t *testing.T, url string) (int, string) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+func TestClustersAndGraph(t *testing.T) {
+	_, srv := testServer(t, nil)
+	code, body := get(t, srv.URL+"/clusters?env=prod")
+	var clusters []state.ClusterRecord
+	if err := json.Unmarshal([]byte(body), &clusters); err != nil || code != http.StatusOK {
+		t.Fatalf("GET /clusters = %d %s", code, body)
+	}
+	if len(clusters) != 1 || clusters[0].KubeContext != "rift-prod-pay" {
+		t.Fatalf("clusters = %+v", clusters)
+	}
+	if code, body := get(t, srv.URL+"/graph?depth=9"); code != http.StatusBadRequest {
+		t.Fatalf("GET /graph?depth=9 = %d %s", code, body)
+	}
+	if code, body := get(t, srv.URL+"/graph?depth=3"); code != http.StatusOK || !strings.Contains(body, `"nodes"`) {
+		t.Fatalf("GET /graph = %d %s", code, body)
+	}
+	if resp, err := http.Post(srv.URL+"/clusters", "", nil); err != nil || resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("POST /clusters = %v, %v", resp, err)
+	}
+}
+
+func TestSyncAndMetrics(t *testing.T) {
+	s, srv := testServer(t, nil)
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/sync", nil)
+	req.Header.Set("Origin", "https://evil.example")
+	if resp, err := http.DefaultClient.Do(req); err != nil || resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("cross-origin POST /sync = %v, %v", resp, err)
+	}
+	resp, err := http.Post(srv.URL+"/sync", "", nil)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("POST /sync = %v, %v", resp, err)
+	}
+	resp.Body.Close()
+
+	s.RunSync = func(context.Context) (SyncResult, error) { return SyncResult{}, errors.New("sso expired") }
+	if _, err := s.Sync(context.Background()); err == nil {
+		t.Fatal("Sync swallowed the error")
+	}
+
+	_, body := get(t, srv.URL+"/metrics")
+	for _, want := range []string{
+		`rift_syncs_total{result="error"} 1`,
+		`rift_syncs_total{result="ok"} 1`,
+		"rift_last_sync_duration_seconds 3\n",
+		"rift_last_sync_failures 1\n",
+		"rift_state_up 1\n",
+		`rift_clusters{env="dev"} 1`,
+		`rift_clusters{env="prod"} 1`,
+		`rift_roles{env="prod"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("metrics lack %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestWebUI(t *testing.T) {
+	s, srv := testServer(t, nil)
+	if code, _ := get(t, srv.URL+"/"); code != http.StatusNotFound {
+		t.Fatalf("GET / without Web = %d", code)
+	}
+
+	s.Web = true
+	s.Search = func(st state.State, q string) ([]state.ClusterRecord, error) {
+		if q == "bad:" {
+			return nil, errors.New("bad query")
+		}
+		return st.Clusters[:1], nil
+	}
+	srv = httptest.NewServer(s.Handler())
+	defer srv.Close()
+	resp, err := http.Get(srv.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(resp.Header.Get("Content-Security-Policy"), "connect-src 'self'") {
+		t.Fatalf("GET / = %d %v", resp.StatusCode, resp.Header)
+	}
+	if code, body := get(t, srv.URL+"/graph.html?env=prod"); code != http.StatusOK || !strings.Contains(body, `"kind":"cluster"`) {
+		t.Fatalf("GET /graph.html = %d %s", code, body)
+	}
+	if code, _ := get(t, srv.URL+"/graph.html?depth=1"); code != http.StatusBadRequest {
+		t.Fatalf("GET /graph.html?depth=1 = %d", code)
+	}
+	if code, body := get(t, srv.URL+"/search?q=pay"); code != http.StatusOK || !strings.Contains(body, "rift-prod-pay") {
+		t.Fatalf("GET /search = %d %s", code, body)
+	}
+	if code, _ := get(t, srv.URL+"/search?q=bad:"); code != http.StatusBadRequest {
+		t.Fatalf("GET /search with a bad query = %d", code)
+	}
+}
+
+func TestHostCheck(t *testing.T) {
+	s, srv := testServer(t, nil)
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/clusters", nil)
+	req.Host = "rebound.example:7777"
+	if resp, err := http.DefaultClient.Do(req); err != nil || resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("GET /clusters for a foreign host = %v, %v", resp, err)
+	}
+
+	for _, tc := range []struct {
+		addr, host string
+		ok         bool
+	}{
+		{"127.0.0.1:7777", "localhost:7777", true},
+		{"127.0.0.1:7777", "[::1]:7777", true},
+		{"127.0.0.1:7777", "192.168.1.5:7777", false},
+		{"rift.lan:7777", "rift.lan:7777", true},
+		{"rift.lan:7777", "other.lan:7777", false},
+		{":7777", "192.168.1.5:7777", true},
+		{"0.0.0.0:7777", "rebound.example:7777", false},
+	} {
+		s.Addr = tc.addr
+		rec := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		r.Host = tc.host
+		s.Handler().ServeHTTP(rec, r)
+		if ok := rec.Code == http.StatusOK; ok != tc.ok {
+			t.Fatalf("Addr %s, Host %s = %d, want allowed %v", tc.addr, tc.host, rec.Code, tc.ok)
+		}
+	}
+}
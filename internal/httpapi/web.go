@@ -0,0 +1,50 @@
+package httpapi
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/phenixrizen/rift/internal/graphview"
+)
+
+// webUI is the single page at /: the context table with rift search, and
+// the graph page in a frame. It reads /clusters, /search, and /graph.html,
+// and loads nothing from the network.
+//
+//go:embed web/index.html
+var webUI []byte
+
+// webPolicy confines the pages to their inline script and styles and this
+// server.
+const webPolicy = "default-src 'none'; script-src 'unsafe-inline'; style-src 'unsafe-inline'; connect-src 'self'; frame-src 'self'; frame-ancestors 'self'; base-uri 'none'; form-action 'none'"
+
+func (s *Server) handleWebUI(w http.ResponseWriter, _ *http.Request) {
+	writePage(w, http.StatusOK, webUI)
+}
+
+func (s *Server) handleGraphPage(w http.ResponseWriter, r *http.Request) {
+	st, err := s.State()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	opts, err := graphOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	page, err := graphview.RenderHTML(graphview.Build(st, opts), "rift topology")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writePage(w, http.StatusOK, []byte(page))
+}
+
+func writePage(w http.ResponseWriter, code int, page []byte) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", webPolicy)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(code)
+	_, _ = w.Write(page)
+}
@@ -0,0 +1,46 @@
+package kubeconfig
+
+import (
+	"errors"
+	"os"
+
+	"github.com/aymanbagabas/go-udiff"
+	"k8s.io/client-go/tools/clientcmd"
+	api "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// diffConfig diffs the kubeconfig at path as it is against after (nil when
+// the file would be removed). Tokens, keys and certificate data are
+// redacted on both sides the way `kubectl config view` does.
+func diffConfig(path string, after *api.Config) (string, error) {
+	var before *api.Config
+	if _, err := os.Stat(path); err == nil {
+		if before, err = loadConfig(path); err != nil {
+			return "", err
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+	oldText, err := renderRedacted(before)
+	if err != nil {
+		return "", err
+	}
+	newText, err := renderRedacted(after)
+	if err != nil {
+		return "", err
+	}
+	return udiff.Unified(path, path, oldText, newText), nil
+}
+
+func renderRedacted(cfg *api.Config) (string, error) {
+	if cfg == nil {
+		return "", nil
+	}
+	redacted := cfg.DeepCopy()
+	api.ShortenConfig(redacted)
+	data, err := clientcmd.Write(*redacted)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,45 @@
+package kubeconfig
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/phenixrizen/rift/internal/state"
+)
+
+// ForeignContext is a kubeconfig context rift did not create.
+type ForeignContext struct {
+	Name      string
+	Cluster   string
+	Server    string
+	Namespace string
+}
+
+// ForeignContexts returns the contexts in path that are neither named rift-*
+// nor discovered clusters in st, sorted by name. Contexts st imported earlier
+// are included.
+func ForeignContexts(path string, st state.State) ([]ForeignContext, error) {
+	cfg, err := loadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	discovered := map[string]struct{}{}
+	for _, c := range st.Clusters {
+		if !c.External() {
+			discovered[c.KubeContext] = struct{}{}
+		}
+	}
+	out := make([]ForeignContext, 0, len(cfg.Contexts))
+	for name, kctx := range cfg.Contexts {
+		if _, ok := discovered[name]; ok || strings.HasPrefix(name, "rift-") || kctx == nil {
+			continue
+		}
+		fc := ForeignContext{Name: name, Cluster: kctx.Cluster, Namespace: kctx.Namespace}
+		if cluster := cfg.Clusters[kctx.Cluster]; cluster != nil {
+			fc.Server = cluster.Server
+		}
+		out = append(out, fc)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
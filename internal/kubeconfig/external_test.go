@@ -0,0 +1,47 @@
+package kubeconfig
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/phenixrizen/rift/internal/state"
+	"k8s.io/client-go/tools/clientcmd"
+	api "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestForeignContextsAndSyncLeaveImportedContextsAlone(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	cfg := api.NewConfig()
+	cfg.Clusters["kind-dev"] = &api.Cluster{Server: "https://127.0.0.1:6443"}
+	cfg.AuthInfos["kind-dev"] = &api.AuthInfo{Token: "secret"}
+	cfg.Contexts["kind-dev"] = &api.Context{Cluster: "kind-dev", AuthInfo: "kind-dev", Namespace: "apps"}
+	cfg.Contexts["prod-main"] = &api.Context{Cluster: "prod-main", AuthInfo: "prod-main"}
+	cfg.Contexts["rift-dev-acme-main"] = &api.Context{Cluster: "rift-dev-acme-main"}
+	if err := clientcmd.WriteToFile(*cfg, path); err != nil {
+		t.Fatalf("write kubeconfig: %v", err)
+	}
+
+	discovered := state.ClusterRecord{KubeContext: "prod-main", AWSProfile: "acme.Admin", ClusterName: "main", Region: "us-east-1", ClusterEndpoint: "https://example"}
+	got, err := ForeignContexts(path, state.State{Clusters: []state.ClusterRecord{discovered}})
+	if err != nil {
+		t.Fatalf("ForeignContexts returned error: %v", err)
+	}
+	if len(got) != 1 || got[0].Name != "kind-dev" || got[0].Server != "https://127.0.0.1:6443" || got[0].Namespace != "apps" {
+		t.Fatalf("ForeignContexts = %+v, want only kind-dev", got)
+	}
+
+	imported := state.ClusterRecord{KubeContext: "kind-dev", ClusterName: "kind-dev", ClusterEndpoint: got[0].Server, Platform: state.PlatformExternal}
+	prev := state.State{Clusters: []state.ClusterRecord{discovered, imported}}
+	// Even when the record is dropped from state, sync must not remove the
+	// user's context.
+	if _, err := Sync(path, state.State{Clusters: []state.ClusterRecord{discovered}}, prev, false, false); err != nil {
+		t.Fatalf("Sync returned error: %v", err)
+	}
+	loaded, err := clientcmd.LoadFromFile(path)
+	if err != nil {
+		t.Fatalf("load kubeconfig: %v", err)
+	}
+	if user := loaded.AuthInfos["kind-dev"]; loaded.Contexts["kind-dev"] == nil || user == nil || user.Token != "secret" {
+		t.Fatalf("imported context was modified or removed: %+v", loaded.Contexts["kind-dev"])
+	}
+}
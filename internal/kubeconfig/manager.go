@@ -7,6 +7,7 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/phenixrizen/rift/internal/fileutil"
 	"github.com/phenixrizen/rift/internal/state"
 	"k8s.io/client-go/tools/clientcmd"
 	api "k8s.io/client-go/tools/clientcmd/api"
@@ -16,9 +17,28 @@ type SyncResult struct {
 	AddedContexts   int
 	UpdatedContexts int
 	RemovedContexts int
+	// SkippedContexts counts clusters with no API endpoint (EKS Connector
+	// registrations), which get no kubeconfig entry.
+	SkippedContexts int
+	// Diff is a unified diff of the kubeconfig (per file in the split
+	// layout) against what sync would write, filled on dry runs only.
+	Diff string
 }
 
-func Sync(path string, st state.State, dryRun bool) (SyncResult, error) {
+// Sync writes a context per connectable cluster in st and removes managed
+// contexts that are gone. Managed means named rift-* or listed in prev, the
+// state from the previous sync (templated names need not carry the prefix).
+// Contexts imported from the kubeconfig are never written or removed.
+// User additions to managed entries survive (see mergeEntries) unless force
+// resets them to what rift generates.
+func Sync(path string, st, prev state.State, dryRun, force bool) (SyncResult, error) {
+	if !dryRun {
+		unlock, err := fileutil.Lock(path)
+		if err != nil {
+			return SyncResult{}, err
+		}
+		defer unlock()
+	}
 	cfg, err := loadConfig(path)
 	if err != nil {
 		return SyncResult{}, err
@@ -27,11 +47,24 @@ func Sync(path string, st state.State, dryRun bool) (SyncResult, error) {
 
 	desired := map[string]state.ClusterRecord{}
 	for _, cluster := range st.Clusters {
+		if cluster.External() {
+			continue
+		}
+		if !cluster.Connectable() {
+			result.SkippedContexts++
+			continue
+		}
 		desired[cluster.KubeContext] = cluster
 	}
 
+	owned := prev.KubeContexts()
+	for _, c := range prev.Clusters {
+		if c.External() {
+			delete(owned, c.KubeContext)
+		}
+	}
 	for ctxName := range cfg.Contexts {
-		if strings.HasPrefix(ctxName, "rift-") {
+		if _, wasOurs := owned[ctxName]; wasOurs || strings.HasPrefix(ctxName, "rift-") {
 			if _, ok := desired[ctxName]; !ok {
 				delete(cfg.Contexts, ctxName)
 				delete(cfg.Clusters, ctxName)
@@ -47,38 +80,11 @@ func Sync(path string, st state.State, dryRun bool) (SyncResult, error) {
 	}
 	sort.Strings(names)
 
+	prevClusters := previousClusters(prev)
 	for _, ctxName := range names {
-		cluster := desired[ctxName]
-		caData := []byte(cluster.ClusterCertificateBase64)
-		if decoded, err := base64.StdEncoding.DecodeString(cluster.ClusterCertificateBase64); err == nil {
-			caData = decoded
-		}
-		desiredCluster := &api.Cluster{
-			Server:                   cluster.ClusterEndpoint,
-			CertificateAuthorityData: caData,
-		}
-		desiredUser := &api.AuthInfo{
-			Exec: &api.ExecConfig{
-				APIVersion: "client.authentication.k8s.io/v1beta1",
-				Command:    "aws",
-				Args: []string{
-					"eks",
-					"get-token",
-					"--profile",
-					cluster.AWSProfile,
-					"--cluster-name",
-					cluster.ClusterName,
-					"--region",
-					cluster.Region,
-				},
-			},
-		}
-		desiredContext := &api.Context{
-			Cluster:  ctxName,
-			AuthInfo: ctxName,
-		}
-		if cluster.Namespace != "" {
-			desiredContext.Namespace = cluster.Namespace
+		desiredCluster, desiredUser, desiredContext := buildEntries(ctxName, desired[ctxName])
+		if !force {
+			desiredCluster, desiredUser, desiredContext = mergeEntries(cfg, ctxName, desiredCluster, desiredUser, desiredContext, prevClusters[ctxName])
 		}
 
 		_, clusterExisted := cfg.Clusters[ctxName]
@@ -104,17 +110,131 @@ func Sync(path string, st state.State, dryRun bool) (SyncResult, error) {
 	}
 
 	if dryRun {
-		return result, nil
-	}
-	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		diff, err := diffConfig(path, cfg)
+		result.Diff = diff
 		return result, err
 	}
-	if err := clientcmd.WriteToFile(*cfg, path); err != nil {
+	if err := writeConfig(cfg, path); err != nil {
 		return result, err
 	}
 	return result, nil
 }
 
+// WriteStandalone writes a kubeconfig at path holding only cluster's context,
+// selected as current-context, with namespace (if set) as its default.
+func WriteStandalone(path string, cluster state.ClusterRecord, namespace string) error {
+	return clientcmd.WriteToFile(*standalone(cluster, namespace), path)
+}
+
+// SessionPrefix starts the names of the per-shell kubeconfigs rift use
+// --local writes.
+const SessionPrefix = "rift-session-"
+
+// IsSession reports whether path is a per-shell kubeconfig from rift use
+// --local. Sync must never treat one as the kubeconfig to write.
+func IsSession(path string) bool {
+	return strings.HasPrefix(filepath.Base(path), SessionPrefix)
+}
+
+// Standalone returns the kubeconfig WriteStandalone writes, as YAML.
+func Standalone(cluster state.ClusterRecord, namespace string) ([]byte, error) {
+	return clientcmd.Write(*standalone(cluster, namespace))
+}
+
+func standalone(cluster state.ClusterRecord, namespace string) *api.Config {
+	if namespace != "" {
+		cluster.Namespace = namespace
+	}
+	cfg := api.NewConfig()
+	ctxName := cluster.KubeContext
+	cfg.Clusters[ctxName], cfg.AuthInfos[ctxName], cfg.Contexts[ctxName] = buildEntries(ctxName, cluster)
+	cfg.CurrentContext = ctxName
+	return cfg
+}
+
+// CurrentContext is the current-context kubectl would use: path's, or with
+// path empty the KUBECONFIG files' (the first one setting it wins) or
+// ~/.kube/config's. It is empty when no file sets one.
+func CurrentContext(path string) (string, error) {
+	if path != "" {
+		cfg, err := loadConfig(path)
+		if err != nil {
+			return "", err
+		}
+		return cfg.CurrentContext, nil
+	}
+	cfg, err := clientcmd.NewDefaultClientConfigLoadingRules().Load()
+	if err != nil {
+		return "", err
+	}
+	return cfg.CurrentContext, nil
+}
+
+// ContextNamespace is the default namespace of context, read from the
+// same files as CurrentContext; it is empty when the context sets none.
+func ContextNamespace(path, context string) (string, error) {
+	var cfg *api.Config
+	var err error
+	if path != "" {
+		cfg, err = loadConfig(path)
+	} else {
+		cfg, err = clientcmd.NewDefaultClientConfigLoadingRules().Load()
+	}
+	if err != nil {
+		return "", err
+	}
+	if c, ok := cfg.Contexts[context]; ok {
+		return c.Namespace, nil
+	}
+	return "", nil
+}
+
+func buildEntries(ctxName string, cluster state.ClusterRecord) (*api.Cluster, *api.AuthInfo, *api.Context) {
+	caData := []byte(cluster.ClusterCertificateBase64)
+	if decoded, err := base64.StdEncoding.DecodeString(cluster.ClusterCertificateBase64); err == nil {
+		caData = decoded
+	}
+	desiredCluster := &api.Cluster{
+		Server:                   cluster.ClusterEndpoint,
+		CertificateAuthorityData: caData,
+	}
+	command, args := cluster.TokenCommand()
+	desiredUser := &api.AuthInfo{
+		Exec: &api.ExecConfig{
+			APIVersion: "client.authentication.k8s.io/v1beta1",
+			Command:    command,
+			Args:       args,
+			// gke-gcloud-auth-plugin reads the cluster from KUBERNETES_EXEC_INFO.
+			ProvideClusterInfo: cluster.Platform == state.PlatformGKE,
+		},
+	}
+	switch cluster.Platform {
+	case state.PlatformGKE:
+		desiredUser.Exec.InstallHint = "Install gke-gcloud-auth-plugin: gcloud components install gke-gcloud-auth-plugin"
+	case state.PlatformAKS:
+		desiredUser.Exec.InstallHint = "Install kubelogin: az aks install-cli"
+	case state.PlatformPlugin:
+		desiredUser.Exec.InstallHint = "Install the rift-" + cluster.Plugin + " plugin on your PATH"
+	}
+	desiredContext := &api.Context{
+		Cluster:  ctxName,
+		AuthInfo: ctxName,
+	}
+	if cluster.Namespace != "" {
+		desiredContext.Namespace = cluster.Namespace
+	}
+	return desiredCluster, desiredUser, desiredContext
+}
+
+// writeConfig writes cfg to path atomically; callers hold the path's lock.
+func writeConfig(cfg *api.Config, path string) error {
+	data, err := clientcmd.Write(*cfg)
+	if err != nil {
+		return err
+	}
+	return fileutil.WriteAtomic(path, data, 0o600)
+}
+
 func loadConfig(path string) (*api.Config, error) {
 	if _, err := os.Stat(path); err != nil {
 		if os.IsNotExist(err) {
@@ -0,0 +1,324 @@
+package kubeconfig
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/phenixrizen/rift/internal/state"
+	"k8s.io/client-go/tools/clientcmd"
+	api "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestSyncHandlesOutpostAndConnectedClusters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	st := state.State{Clusters: []state.ClusterRecord{
+		{KubeContext: "rift-prod-acme-edge", AWSProfile: "rift-prod-acme-admin", ClusterName: "edge", ClusterID: "0123abcd", Platform: state.PlatformOutpost, Region: "us-west-2", ClusterEndpoint: "https://10.0.0.10"},
+		{KubeContext: "rift-prod-acme-onprem", AWSProfile: "rift-prod-acme-admin", ClusterName: "onprem", Platform: state.PlatformConnected, ConnectorProvider: "eks_anywhere", Region: "us-west-2"},
+	}}
+
+	result, err := Sync(path, st, state.State{}, false, false)
+	if err != nil {
+		t.Fatalf("Sync returned error: %v", err)
+	}
+	if result.AddedContexts != 1 || result.SkippedContexts != 1 {
+		t.Fatalf("result=%+v want 1 added, 1 skipped", result)
+	}
+
+	loaded, err := clientcmd.LoadFromFile(path)
+	if err != nil {
+		t.Fatalf("load kubeconfig: %v", err)
+	}
+	if _, ok := loaded.Contexts["rift-prod-acme-onprem"]; ok {
+		t.Fatalf("connected cluster without endpoint got a context")
+	}
+	user := loaded.AuthInfos["rift-prod-acme-edge"]
+	if user == nil || user.Exec == nil {
+		t.Fatalf("outpost user missing exec config")
+	}
+	args := strings.Join(user.Exec.Args, " ")
+	if !strings.Contains(args, "--cluster-id 0123abcd") || strings.Contains(args, "--cluster-name") {
+		t.Fatalf("outpost exec args=%q want --cluster-id", args)
+	}
+}
+
+func TestSyncRemovesTemplatedContextsFromPreviousState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	cluster := state.ClusterRecord{AWSProfile: "acme.Admin", ClusterName: "main", Region: "us-east-1", ClusterEndpoint: "https://example"}
+	old := cluster
+	old.KubeContext = "prod-main"
+	if _, err := Sync(path, state.State{Clusters: []state.ClusterRecord{old}}, state.State{}, false, false); err != nil {
+		t.Fatalf("Sync returned error: %v", err)
+	}
+	loaded, err := clientcmd.LoadFromFile(path)
+	if err != nil {
+		t.Fatalf("load kubeconfig: %v", err)
+	}
+	loaded.Contexts["team-main"] = loaded.Contexts["prod-main"].DeepCopy()
+	if err := clientcmd.WriteToFile(*loaded, path); err != nil {
+		t.Fatalf("write kubeconfig: %v", err)
+	}
+
+	renamed := cluster
+	renamed.KubeContext = "prod-main-us-east-1"
+	prev := state.State{Clusters: []state.ClusterRecord{old}}
+	result, err := Sync(path, state.State{Clusters: []state.ClusterRecord{renamed}}, prev, false, false)
+	if err != nil {
+		t.Fatalf("Sync returned error: %v", err)
+	}
+	if result.AddedContexts != 1 || result.RemovedContexts != 1 {
+		t.Fatalf("result=%+v want 1 added, 1 removed", result)
+	}
+	if loaded, err = clientcmd.LoadFromFile(path); err != nil {
+		t.Fatalf("load kubeconfig: %v", err)
+	}
+	if _, ok := loaded.Contexts["prod-main"]; ok {
+		t.Fatalf("context from previous state was kept")
+	}
+	if _, ok := loaded.Contexts["team-main"]; !ok {
+		t.Fatalf("user context was removed")
+	}
+}
+
+func TestWriteStandaloneSelectsContextAndNamespace(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	cluster := state.ClusterRecord{KubeContext: "rift-prod-acme-main", AWSProfile: "rift-prod-acme-admin", ClusterName: "main", Region: "us-east-1", ClusterEndpoint: "https://example", Namespace: "default"}
+	if err := WriteStandalone(path, cluster, "payments"); err != nil {
+		t.Fatalf("WriteStandalone returned error: %v", err)
+	}
+	loaded, err := clientcmd.LoadFromFile(path)
+	if err != nil {
+		t.Fatalf("load kubeconfig: %v", err)
+	}
+	if loaded.CurrentContext != "rift-prod-acme-main" || len(loaded.Contexts) != 1 {
+		t.Fatalf("current=%q contexts=%d want only rift-prod-acme-main", loaded.CurrentContext, len(loaded.Contexts))
+	}
+	if ns := loaded.Contexts["rift-prod-acme-main"].Namespace; ns != "payments" {
+		t.Fatalf("namespace=%q want payments", ns)
+	}
+}
+
+func TestCurrentContextFollowsKubeconfigEnv(t *testing.T) {
+	dir := t.TempDir()
+	first, second := filepath.Join(dir, "rift"), filepath.Join(dir, "config")
+	cluster := state.ClusterRecord{KubeContext: "rift-prod-acme-main", ClusterEndpoint: "https://example"}
+	if err := WriteStandalone(second, cluster, ""); err != nil {
+		t.Fatalf("WriteStandalone returned error: %v", err)
+	}
+	if got, err := CurrentContext(second); err != nil || got != "rift-prod-acme-main" {
+		t.Fatalf("CurrentContext(path)=%q, %v", got, err)
+	}
+	if got, err := CurrentContext(filepath.Join(dir, "missing")); err != nil || got != "" {
+		t.Fatalf("CurrentContext(missing)=%q, %v want empty", got, err)
+	}
+
+	t.Setenv("KUBECONFIG", first+string(filepath.ListSeparator)+second)
+	if got, err := CurrentContext(""); err != nil || got != "rift-prod-acme-main" {
+		t.Fatalf("CurrentContext(KUBECONFIG)=%q, %v", got, err)
+	}
+}
+
+func TestSyncKeepsUserCustomizations(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	cluster := state.ClusterRecord{KubeContext: "rift-prod-acme-main", AWSProfile: "rift-prod-acme-admin", ClusterName: "main", Region: "us-east-1", ClusterEndpoint: "https://example", Namespace: "default"}
+	st := state.State{Clusters: []state.ClusterRecord{cluster}}
+	if _, err := Sync(path, st, state.State{}, false, false); err != nil {
+		t.Fatalf("Sync returned error: %v", err)
+	}
+	loaded, err := clientcmd.LoadFromFile(path)
+	if err != nil {
+		t.Fatalf("load kubeconfig: %v", err)
+	}
+	ctx := cluster.KubeContext
+	loaded.Clusters[ctx].ProxyURL = "socks5://localhost:1080"
+	exec := loaded.AuthInfos[ctx].Exec
+	exec.Args = append(exec.Args, "--role-arn", "arn:aws:iam::111111111111:role/ro")
+	exec.Env = []api.ExecEnvVar{{Name: "AWS_STS_REGIONAL_ENDPOINTS", Value: "regional"}}
+	loaded.Contexts[ctx].Namespace = "payments"
+	if err := clientcmd.WriteToFile(*loaded, path); err != nil {
+		t.Fatalf("write kubeconfig: %v", err)
+	}
+
+	moved := cluster
+	moved.Region = "us-west-2"
+	moved.Namespace = "kube-system"
+	result, err := Sync(path, state.State{Clusters: []state.ClusterRecord{moved}}, st, false, false)
+	if err != nil {
+		t.Fatalf("Sync returned error: %v", err)
+	}
+	if result.UpdatedContexts != 1 {
+		t.Fatalf("result=%+v want 1 updated", result)
+	}
+	if loaded, err = clientcmd.LoadFromFile(path); err != nil {
+		t.Fatalf("load kubeconfig: %v", err)
+	}
+	args := strings.Join(loaded.AuthInfos[ctx].Exec.Args, " ")
+	if !strings.Contains(args, "--region us-west-2 --role-arn arn:aws:iam::111111111111:role/ro") || strings.Contains(args, "us-east-1") {
+		t.Fatalf("exec args=%q want new region plus the user's --role-arn", args)
+	}
+	if len(loaded.AuthInfos[ctx].Exec.Env) != 1 || loaded.Clusters[ctx].ProxyURL == "" || loaded.Contexts[ctx].Namespace != "payments" {
+		t.Fatalf("user customizations lost: env=%v proxy=%q namespace=%q", loaded.AuthInfos[ctx].Exec.Env, loaded.Clusters[ctx].ProxyURL, loaded.Contexts[ctx].Namespace)
+	}
+
+	if _, err := Sync(path, state.State{Clusters: []state.ClusterRecord{moved}}, st, false, true); err != nil {
+		t.Fatalf("forced Sync returned error: %v", err)
+	}
+	if loaded, err = clientcmd.LoadFromFile(path); err != nil {
+		t.Fatalf("load kubeconfig: %v", err)
+	}
+	if strings.Contains(strings.Join(loaded.AuthInfos[ctx].Exec.Args, " "), "--role-arn") || loaded.Clusters[ctx].ProxyURL != "" || loaded.Contexts[ctx].Namespace != "kube-system" {
+		t.Fatalf("--force kept user customizations: %+v", loaded.AuthInfos[ctx].Exec)
+	}
+}
+
+func TestSyncSwitchesToRiftExecPlugin(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	cluster := state.ClusterRecord{KubeContext: "rift-prod-acme-main", AWSProfile: "rift-prod-acme-admin", ClusterName: "main", Region: "us-east-1", ClusterEndpoint: "https://example"}
+	st := state.State{Clusters: []state.ClusterRecord{cluster}}
+	if _, err := Sync(path, st, state.State{}, false, false); err != nil {
+		t.Fatalf("Sync returned error: %v", err)
+	}
+	loaded, err := clientcmd.LoadFromFile(path)
+	if err != nil {
+		t.Fatalf("load kubeconfig: %v", err)
+	}
+	exec := loaded.AuthInfos[cluster.KubeContext].Exec
+	exec.Args = append(exec.Args, "--role-arn", "arn:aws:iam::111111111111:role/ro")
+	if err := clientcmd.WriteToFile(*loaded, path); err != nil {
+		t.Fatalf("write kubeconfig: %v", err)
+	}
+
+	rift := cluster
+	rift.ExecPlugin = state.ExecPluginRift
+	if _, err := Sync(path, state.State{Clusters: []state.ClusterRecord{rift}}, st, false, false); err != nil {
+		t.Fatalf("Sync returned error: %v", err)
+	}
+	if loaded, err = clientcmd.LoadFromFile(path); err != nil {
+		t.Fatalf("load kubeconfig: %v", err)
+	}
+	exec = loaded.AuthInfos[cluster.KubeContext].Exec
+	// aws flags the user added mean nothing to rift token and are dropped.
+	if got := exec.Command + " " + strings.Join(exec.Args, " "); got != "rift token --profile rift-prod-acme-admin --region us-east-1 --cluster main" {
+		t.Fatalf("exec = %q", got)
+	}
+}
+
+func TestPruneKeepsStateAndImportedContexts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	cfg := api.NewConfig()
+	for _, name := range []string{"rift-prod-acme-main", "rift-prod-acme-gone", "rift-lab", "kind"} {
+		cfg.Clusters[name] = &api.Cluster{Server: "https://" + name}
+		cfg.AuthInfos[name] = &api.AuthInfo{Token: "t"}
+		cfg.Contexts[name] = &api.Context{Cluster: name, AuthInfo: name}
+	}
+	cfg.CurrentContext = "rift-prod-acme-gone"
+	if err := clientcmd.WriteToFile(*cfg, path); err != nil {
+		t.Fatal(err)
+	}
+	st := state.State{Clusters: []state.ClusterRecord{
+		{KubeContext: "rift-prod-acme-main"},
+		{KubeContext: "rift-lab", Platform: state.PlatformExternal},
+	}}
+
+	removed, err := Prune(path, st, false, false)
+	if err != nil {
+		t.Fatalf("Prune returned error: %v", err)
+	}
+	if len(removed) != 1 || removed[0] != "rift-prod-acme-gone" {
+		t.Fatalf("removed=%v want only the stale context", removed)
+	}
+	loaded, err := clientcmd.LoadFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.CurrentContext != "" || loaded.Clusters["rift-prod-acme-gone"] != nil || loaded.AuthInfos["rift-prod-acme-gone"] != nil {
+		t.Fatalf("stale entries left: current=%q", loaded.CurrentContext)
+	}
+
+	if removed, err = Prune(path, st, true, true); err != nil || len(removed) != 1 || removed[0] != "rift-prod-acme-main" {
+		t.Fatalf("Prune --all dry run removed=%v err=%v want the state context only", removed, err)
+	}
+}
+
+func TestSyncDryRunDiffsWithoutWriting(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	cluster := state.ClusterRecord{KubeContext: "rift-dev-acme-main", AWSProfile: "rift-dev-acme-admin", ClusterName: "main", Region: "us-east-1", ClusterEndpoint: "https://main.example", ClusterCertificateBase64: "Y2VydA=="}
+	st := state.State{Clusters: []state.ClusterRecord{cluster}}
+	if _, err := Sync(path, st, state.State{}, false, false); err != nil {
+		t.Fatalf("Sync returned error: %v", err)
+	}
+
+	cluster.ClusterEndpoint = "https://moved.example"
+	result, err := Sync(path, state.State{Clusters: []state.ClusterRecord{cluster}}, st, true, false)
+	if err != nil {
+		t.Fatalf("dry-run Sync returned error: %v", err)
+	}
+	if !strings.Contains(result.Diff, "-    server: https://main.example") || !strings.Contains(result.Diff, "+    server: https://moved.example") {
+		t.Fatalf("diff missing server change:\n%s", result.Diff)
+	}
+	if strings.Contains(result.Diff, "Y2VydA==") {
+		t.Fatalf("diff leaks certificate data:\n%s", result.Diff)
+	}
+	loaded, err := clientcmd.LoadFromFile(path)
+	if err != nil {
+		t.Fatalf("load kubeconfig: %v", err)
+	}
+	if got := loaded.Clusters["rift-dev-acme-main"].Server; got != "https://main.example" {
+		t.Fatalf("dry run wrote the kubeconfig: server=%q", got)
+	}
+
+	result, err = Sync(path, st, st, true, false)
+	if err != nil {
+		t.Fatalf("dry-run Sync returned error: %v", err)
+	}
+	if result.Diff != "" {
+		t.Fatalf("unchanged sync produced a diff:\n%s", result.Diff)
+	}
+}
+
+func TestIsSession(t *testing.T) {
+	if !IsSession("/home/u/.config/rift/sessions/" + SessionPrefix + "123.yaml") {
+		t.Fatal("session kubeconfig not recognized")
+	}
+	if IsSession("/home/u/.kube/config") || IsSession("/tmp/rift-exec-1/kubeconfig") {
+		t.Fatal("regular kubeconfig taken for a session")
+	}
+}
+
+func TestSyncSkipsPluginClustersWithPathNames(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	evil := state.ClusterRecord{KubeContext: "rift-dc-rack1", ClusterName: "rack1", Platform: state.PlatformPlugin, Plugin: "x/../../../tmp/p", ClusterEndpoint: "https://10.0.0.1:6443"}
+	good := state.ClusterRecord{KubeContext: "rift-dc-rack2", ClusterName: "rack2", Platform: state.PlatformPlugin, Plugin: "onprem", ClusterEndpoint: "https://10.0.0.2:6443"}
+
+	// A teammate's plugin clusters are not imported at all.
+	var st state.State
+	st.MergeShared(state.State{Clusters: []state.ClusterRecord{evil, good}}, "alice.json")
+	if len(st.Clusters) != 0 {
+		t.Fatalf("MergeShared imported plugin clusters: %+v", st.Clusters)
+	}
+
+	// State that holds one anyway (pulled or edited) gets no exec entry.
+	st = state.State{Clusters: []state.ClusterRecord{evil, good}}
+	result, err := Sync(path, st, state.State{}, false, false)
+	if err != nil {
+		t.Fatalf("Sync returned error: %v", err)
+	}
+	if result.AddedContexts != 1 || result.SkippedContexts != 1 {
+		t.Fatalf("result=%+v want 1 added, 1 skipped", result)
+	}
+	loaded, err := clientcmd.LoadFromFile(path)
+	if err != nil {
+		t.Fatalf("load kubeconfig: %v", err)
+	}
+	if _, ok := loaded.AuthInfos["rift-dc-rack1"]; ok {
+		t.Fatalf("plugin cluster with a path-like name got a user entry")
+	}
+	for name, user := range loaded.AuthInfos {
+		if user.Exec != nil && strings.Contains(user.Exec.Command, "/") {
+			t.Fatalf("user %s runs %q", name, user.Exec.Command)
+		}
+	}
+	if user := loaded.AuthInfos["rift-dc-rack2"]; user == nil || user.Exec == nil || user.Exec.Command != "rift-onprem" {
+		t.Fatalf("rack2 user = %+v, want rift-onprem exec", user)
+	}
+}
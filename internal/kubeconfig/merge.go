@@ -0,0 +1,127 @@
+package kubeconfig
+
+import (
+	"strings"
+
+	"github.com/phenixrizen/rift/internal/state"
+	api "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// mergeEntries lays the entries rift wants for ctxName over the ones already
+// in the kubeconfig, keeping what the user added to them:
+//
+//   - cluster: everything but the server and CA (proxy-url, tls-server-name,
+//     extensions)
+//   - user: exec env vars, interactive mode, and exec flags rift does not
+//     generate (e.g. --role-arn), plus non-exec fields (impersonation)
+//   - context: extensions, and a namespace changed since the last sync
+//
+// prev is the cluster as of the previous sync, nil when it was not in state;
+// it tells rift's old values (a flag it no longer passes, the namespace it
+// last wrote) apart from the user's.
+func mergeEntries(existing *api.Config, ctxName string, cluster *api.Cluster, user *api.AuthInfo, kctx *api.Context, prev *state.ClusterRecord) (*api.Cluster, *api.AuthInfo, *api.Context) {
+	var prevUser *api.AuthInfo
+	var prevContext *api.Context
+	if prev != nil {
+		_, prevUser, prevContext = buildEntries(ctxName, *prev)
+	}
+	return mergeCluster(existing.Clusters[ctxName], cluster),
+		mergeUser(existing.AuthInfos[ctxName], user, prevUser),
+		mergeContext(existing.Contexts[ctxName], kctx, prevContext)
+}
+
+// previousClusters indexes prev's clusters by context for mergeEntries.
+func previousClusters(prev state.State) map[string]*state.ClusterRecord {
+	out := make(map[string]*state.ClusterRecord, len(prev.Clusters))
+	for i := range prev.Clusters {
+		out[prev.Clusters[i].KubeContext] = &prev.Clusters[i]
+	}
+	return out
+}
+
+func mergeCluster(existing, desired *api.Cluster) *api.Cluster {
+	if existing == nil {
+		return desired
+	}
+	merged := existing.DeepCopy()
+	merged.Server = desired.Server
+	merged.CertificateAuthorityData = desired.CertificateAuthorityData
+	// Either would conflict with the CA data rift writes.
+	merged.CertificateAuthority = ""
+	merged.InsecureSkipTLSVerify = false
+	return merged
+}
+
+func mergeUser(existing, desired, prev *api.AuthInfo) *api.AuthInfo {
+	if existing == nil || existing.Exec == nil {
+		return desired
+	}
+	merged := existing.DeepCopy()
+	exec := desired.Exec.DeepCopy()
+	if existing.Exec.Command == exec.Command {
+		owned := flagNames(exec.Args)
+		if prev != nil && prev.Exec != nil {
+			for name := range flagNames(prev.Exec.Args) {
+				owned[name] = struct{}{}
+			}
+		}
+		exec.Args = append(exec.Args, extraFlags(existing.Exec.Args, owned)...)
+	}
+	exec.Env = existing.Exec.Env
+	if existing.Exec.InteractiveMode != "" {
+		exec.InteractiveMode = existing.Exec.InteractiveMode
+	}
+	merged.Exec = exec
+	return merged
+}
+
+func mergeContext(existing, desired, prev *api.Context) *api.Context {
+	if existing == nil {
+		return desired
+	}
+	merged := existing.DeepCopy()
+	merged.Cluster = desired.Cluster
+	merged.AuthInfo = desired.AuthInfo
+	// Rift owns the namespace until the user changes it, e.g. with
+	// kubectl config set-context --current --namespace.
+	if prev == nil || existing.Namespace == prev.Namespace {
+		merged.Namespace = desired.Namespace
+	}
+	return merged
+}
+
+// flagNames returns the flags in args ("--region" for both "--region x" and
+// "--region=x").
+func flagNames(args []string) map[string]struct{} {
+	names := map[string]struct{}{}
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-") {
+			name, _, _ := strings.Cut(arg, "=")
+			names[name] = struct{}{}
+		}
+	}
+	return names
+}
+
+// extraFlags returns the flags in args not named in owned, each with its
+// value. Positional args belong to the plugin command rift generates and
+// are dropped.
+func extraFlags(args []string, owned map[string]struct{}) []string {
+	var extra []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+		group := []string{arg}
+		if !strings.Contains(arg, "=") && i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+			group = append(group, args[i+1])
+			i++
+		}
+		name, _, _ := strings.Cut(arg, "=")
+		if _, ok := owned[name]; !ok {
+			extra = append(extra, group...)
+		}
+	}
+	return extra
+}
@@ -0,0 +1,196 @@
+package kubeconfig
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/phenixrizen/rift/internal/fileutil"
+	"github.com/phenixrizen/rift/internal/state"
+	api "k8s.io/client-go/tools/clientcmd/api"
+)
+
+const (
+	MigrateRenamed   = "renamed"
+	MigrateRemoved   = "removed"
+	MigrateUnmatched = "unmatched"
+)
+
+type MigrateAction struct {
+	LegacyContext string
+	RiftContext   string
+	Action        string
+	Namespace     string
+}
+
+type MigrateResult struct {
+	Actions        []MigrateAction
+	Adopted        map[string]string
+	CurrentContext string
+}
+
+// Migrate finds contexts written by `aws eks update-kubeconfig` or eksctl,
+// maps them onto rift clusters from state, and replaces them with the rift
+// context. Namespaces set on legacy contexts are returned in Adopted
+// (rift context -> namespace) so callers can pin them in state.
+func Migrate(path string, st state.State, dryRun bool) (MigrateResult, error) {
+	if !dryRun {
+		unlock, err := fileutil.Lock(path)
+		if err != nil {
+			return MigrateResult{}, err
+		}
+		defer unlock()
+	}
+	cfg, err := loadConfig(path)
+	if err != nil {
+		return MigrateResult{}, err
+	}
+	result := MigrateResult{Adopted: map[string]string{}}
+
+	legacyNames := make([]string, 0)
+	managed := st.KubeContexts()
+	for name, kctx := range cfg.Contexts {
+		if _, ok := managed[name]; ok || strings.HasPrefix(name, "rift-") || kctx == nil {
+			continue
+		}
+		if isLegacyEKSContext(name, kctx, cfg) {
+			legacyNames = append(legacyNames, name)
+		}
+	}
+	sort.Strings(legacyNames)
+
+	for _, name := range legacyNames {
+		kctx := cfg.Contexts[name]
+		rec, ok := matchLegacyContext(name, kctx, cfg, st)
+		if !ok {
+			result.Actions = append(result.Actions, MigrateAction{LegacyContext: name, Action: MigrateUnmatched})
+			continue
+		}
+
+		action := MigrateAction{LegacyContext: name, RiftContext: rec.KubeContext, Action: MigrateRemoved}
+		if ns := strings.TrimSpace(kctx.Namespace); ns != "" && ns != rec.Namespace {
+			action.Namespace = ns
+			result.Adopted[rec.KubeContext] = ns
+			rec.Namespace = ns
+		}
+		if _, exists := cfg.Contexts[rec.KubeContext]; !exists {
+			action.Action = MigrateRenamed
+			desiredCluster, desiredUser, desiredContext := buildEntries(rec.KubeContext, rec)
+			cfg.Clusters[rec.KubeContext] = desiredCluster
+			cfg.AuthInfos[rec.KubeContext] = desiredUser
+			cfg.Contexts[rec.KubeContext] = desiredContext
+		} else if action.Namespace != "" {
+			cfg.Contexts[rec.KubeContext].Namespace = action.Namespace
+		}
+
+		delete(cfg.Contexts, name)
+		removeIfUnreferenced(cfg, kctx.Cluster, kctx.AuthInfo)
+		if cfg.CurrentContext == name {
+			cfg.CurrentContext = rec.KubeContext
+		}
+		result.Actions = append(result.Actions, action)
+	}
+	result.CurrentContext = cfg.CurrentContext
+
+	if dryRun || !result.Changed() {
+		return result, nil
+	}
+	if err := writeConfig(cfg, path); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// Changed reports whether Migrate rewrote (or, dry, would rewrite) the file.
+func (r MigrateResult) Changed() bool {
+	for _, action := range r.Actions {
+		if action.Action != MigrateUnmatched {
+			return true
+		}
+	}
+	return false
+}
+
+func isLegacyEKSContext(name string, kctx *api.Context, cfg *api.Config) bool {
+	if isEKSARN(name) || isEKSARN(kctx.Cluster) || isEksctlName(kctx.Cluster) {
+		return true
+	}
+	user := cfg.AuthInfos[kctx.AuthInfo]
+	if user == nil || user.Exec == nil {
+		return false
+	}
+	args := strings.Join(user.Exec.Args, " ")
+	switch user.Exec.Command {
+	case "aws":
+		return strings.Contains(args, "eks get-token")
+	case "aws-iam-authenticator", "eksctl":
+		return true
+	}
+	return false
+}
+
+func matchLegacyContext(name string, kctx *api.Context, cfg *api.Config, st state.State) (state.ClusterRecord, bool) {
+	server := ""
+	if cluster := cfg.Clusters[kctx.Cluster]; cluster != nil {
+		server = normalizeServer(cluster.Server)
+	}
+	eksctlName, eksctlRegion := parseEksctlName(kctx.Cluster)
+	for _, rec := range st.Clusters {
+		switch {
+		case rec.ClusterARN != "" && (rec.ClusterARN == name || rec.ClusterARN == kctx.Cluster):
+			return rec, true
+		case server != "" && normalizeServer(rec.ClusterEndpoint) == server:
+			return rec, true
+		case eksctlName != "" && rec.ClusterName == eksctlName && rec.Region == eksctlRegion:
+			return rec, true
+		}
+	}
+	return state.ClusterRecord{}, false
+}
+
+func removeIfUnreferenced(cfg *api.Config, clusterName, userName string) {
+	clusterUsed := false
+	userUsed := false
+	for _, kctx := range cfg.Contexts {
+		if kctx == nil {
+			continue
+		}
+		if kctx.Cluster == clusterName {
+			clusterUsed = true
+		}
+		if kctx.AuthInfo == userName {
+			userUsed = true
+		}
+	}
+	if !clusterUsed && !strings.HasPrefix(clusterName, "rift-") {
+		delete(cfg.Clusters, clusterName)
+	}
+	if !userUsed && !strings.HasPrefix(userName, "rift-") {
+		delete(cfg.AuthInfos, userName)
+	}
+}
+
+func isEKSARN(value string) bool {
+	return strings.HasPrefix(value, "arn:aws") && strings.Contains(value, ":eks:") && strings.Contains(value, ":cluster/")
+}
+
+func isEksctlName(value string) bool {
+	name, _ := parseEksctlName(value)
+	return name != ""
+}
+
+// parseEksctlName splits eksctl's "<cluster>.<region>.eksctl.io" naming.
+func parseEksctlName(value string) (string, string) {
+	trimmed, ok := strings.CutSuffix(value, ".eksctl.io")
+	if !ok {
+		return "", ""
+	}
+	idx := strings.LastIndex(trimmed, ".")
+	if idx <= 0 || idx == len(trimmed)-1 {
+		return "", ""
+	}
+	return trimmed[:idx], trimmed[idx+1:]
+}
+
+func normalizeServer(server string) string {
+	return strings.TrimRight(strings.ToLower(strings.TrimSpace(server)), "/")
+}
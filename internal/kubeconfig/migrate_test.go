@@ -0,0 +1,65 @@
+package kubeconfig
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/phenixrizen/rift/internal/state"
+	"k8s.io/client-go/tools/clientcmd"
+	api "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestMigrateReplacesLegacyContexts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	arn := "arn:aws:eks:us-east-1:111111111111:cluster/payments"
+
+	cfg := api.NewConfig()
+	cfg.Clusters[arn] = &api.Cluster{Server: "https://payments.example.com"}
+	cfg.AuthInfos[arn] = &api.AuthInfo{Exec: &api.ExecConfig{Command: "aws", Args: []string{"eks", "get-token", "--cluster-name", "payments"}}}
+	cfg.Contexts[arn] = &api.Context{Cluster: arn, AuthInfo: arn, Namespace: "kafka"}
+	cfg.Clusters["orders.us-west-2.eksctl.io"] = &api.Cluster{Server: "https://orders.example.com"}
+	cfg.AuthInfos["me@orders.us-west-2.eksctl.io"] = &api.AuthInfo{}
+	cfg.Contexts["me@orders.us-west-2.eksctl.io"] = &api.Context{Cluster: "orders.us-west-2.eksctl.io", AuthInfo: "me@orders.us-west-2.eksctl.io"}
+	cfg.Clusters["kind"] = &api.Cluster{Server: "https://127.0.0.1:6443"}
+	cfg.Contexts["kind-kind"] = &api.Context{Cluster: "kind", AuthInfo: "kind"}
+	cfg.CurrentContext = arn
+	if err := clientcmd.WriteToFile(*cfg, path); err != nil {
+		t.Fatalf("write kubeconfig: %v", err)
+	}
+
+	st := state.State{Clusters: []state.ClusterRecord{
+		{KubeContext: "rift-prod-acme-payments", ClusterName: "payments", Region: "us-east-1", ClusterARN: arn, ClusterEndpoint: "https://payments.example.com"},
+		{KubeContext: "rift-dev-acme-orders", ClusterName: "orders", Region: "us-west-2", ClusterEndpoint: "https://orders.example.com"},
+	}}
+
+	result, err := Migrate(path, st, false)
+	if err != nil {
+		t.Fatalf("Migrate returned error: %v", err)
+	}
+	if len(result.Actions) != 2 {
+		t.Fatalf("Actions=%+v want 2 entries", result.Actions)
+	}
+	if got := result.Adopted["rift-prod-acme-payments"]; got != "kafka" {
+		t.Fatalf("Adopted namespace=%q want kafka", got)
+	}
+
+	loaded, err := clientcmd.LoadFromFile(path)
+	if err != nil {
+		t.Fatalf("load kubeconfig: %v", err)
+	}
+	if _, ok := loaded.Contexts[arn]; ok {
+		t.Fatalf("legacy ARN context still present")
+	}
+	if _, ok := loaded.Clusters[arn]; ok {
+		t.Fatalf("legacy ARN cluster still present")
+	}
+	if _, ok := loaded.Contexts["kind-kind"]; !ok {
+		t.Fatalf("non-EKS context was removed")
+	}
+	if got := loaded.Contexts["rift-prod-acme-payments"]; got == nil || got.Namespace != "kafka" {
+		t.Fatalf("rift context=%+v want namespace kafka", got)
+	}
+	if loaded.CurrentContext != "rift-prod-acme-payments" {
+		t.Fatalf("CurrentContext=%q want rift-prod-acme-payments", loaded.CurrentContext)
+	}
+}
@@ -0,0 +1,178 @@
+package kubeconfig
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/phenixrizen/rift/internal/fileutil"
+	"github.com/phenixrizen/rift/internal/state"
+)
+
+// Prune removes rift-* contexts (with their cluster and user) that are not
+// in st and returns their names. With all it removes every context rift
+// manages: rift-* and those of st's discovered clusters. Contexts imported
+// from the kubeconfig are never removed.
+func Prune(path string, st state.State, all, dryRun bool) ([]string, error) {
+	if !dryRun {
+		unlock, err := fileutil.Lock(path)
+		if err != nil {
+			return nil, err
+		}
+		defer unlock()
+	}
+	cfg, err := loadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	shouldPrune := pruneFilter(st, all)
+	removed := make([]string, 0)
+	for name := range cfg.Contexts {
+		if shouldPrune(name) {
+			removed = append(removed, name)
+		}
+	}
+	sort.Strings(removed)
+	if dryRun || len(removed) == 0 {
+		return removed, nil
+	}
+	for _, name := range removed {
+		delete(cfg.Contexts, name)
+		delete(cfg.Clusters, name)
+		delete(cfg.AuthInfos, name)
+		if cfg.CurrentContext == name {
+			cfg.CurrentContext = ""
+		}
+	}
+	return removed, writeConfig(cfg, path)
+}
+
+// PruneSplit is Prune for the split layout: it deletes the files of the
+// pruned contexts from dir and rewrites the index (deleted too with all).
+func PruneSplit(dir string, st state.State, all, dryRun bool) ([]string, error) {
+	indexPath := filepath.Join(dir, IndexFile)
+	if !dryRun {
+		unlock, err := fileutil.Lock(indexPath)
+		if err != nil {
+			return nil, err
+		}
+		defer unlock()
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	shouldPrune := pruneFilter(st, all)
+	removed := make([]string, 0)
+	gone := map[string]struct{}{}
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), splitExt)
+		if !ok || entry.IsDir() || !shouldPrune(name) {
+			continue
+		}
+		removed = append(removed, name)
+		gone[SplitPath(dir, name)] = struct{}{}
+	}
+	if dryRun || len(removed) == 0 {
+		return removed, nil
+	}
+	for path := range gone {
+		if err := os.Remove(path); err != nil {
+			return removed, err
+		}
+	}
+	if all {
+		if err := os.Remove(indexPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return removed, err
+		}
+		return removed, nil
+	}
+	files, err := ReadIndex(dir)
+	if err != nil {
+		return removed, err
+	}
+	kept := make([]string, 0, len(files))
+	for _, f := range files {
+		if _, ok := gone[f]; !ok {
+			kept = append(kept, f)
+		}
+	}
+	index := strings.Join(kept, string(os.PathListSeparator)) + "\n"
+	return removed, fileutil.WriteAtomic(indexPath, []byte(index), 0o644)
+}
+
+// pruneFilter reports whether Prune removes a context: a rift-* one not in
+// st, or with all any rift-managed one. Imported contexts are kept.
+func pruneFilter(st state.State, all bool) func(string) bool {
+	current := map[string]struct{}{}
+	external := map[string]struct{}{}
+	for _, c := range st.Clusters {
+		if c.External() {
+			external[c.KubeContext] = struct{}{}
+		} else {
+			current[c.KubeContext] = struct{}{}
+		}
+	}
+	return func(name string) bool {
+		if _, ok := external[name]; ok {
+			return false
+		}
+		_, inState := current[name]
+		return all && inState || !inState && strings.HasPrefix(name, "rift-")
+	}
+}
+
+// Sweep removes every context rift manages (rift-* and the contexts of st's
+// and prev's discovered clusters) from a kubeconfig sync no longer writes:
+// the merged targets after a switch to the dedicated or split layout, whose
+// stale entries would otherwise shadow the new files in KUBECONFIG.
+// current-context is kept, since kubectl stores the one rift use picks in
+// the first KUBECONFIG file, usually this one. Imported contexts stay.
+func Sweep(path string, st, prev state.State, dryRun bool) (SyncResult, error) {
+	if !dryRun {
+		unlock, err := fileutil.Lock(path)
+		if err != nil {
+			return SyncResult{}, err
+		}
+		defer unlock()
+	}
+	cfg, err := loadConfig(path)
+	if err != nil {
+		return SyncResult{}, err
+	}
+	owned := map[string]struct{}{}
+	external := map[string]struct{}{}
+	for _, c := range append(append([]state.ClusterRecord(nil), prev.Clusters...), st.Clusters...) {
+		if c.External() {
+			external[c.KubeContext] = struct{}{}
+		} else {
+			owned[c.KubeContext] = struct{}{}
+		}
+	}
+	result := SyncResult{}
+	for name := range cfg.Contexts {
+		if _, ok := external[name]; ok {
+			continue
+		}
+		if _, ours := owned[name]; !ours && !strings.HasPrefix(name, "rift-") {
+			continue
+		}
+		delete(cfg.Contexts, name)
+		delete(cfg.Clusters, name)
+		delete(cfg.AuthInfos, name)
+		result.RemovedContexts++
+	}
+	if result.RemovedContexts == 0 {
+		return result, nil
+	}
+	if dryRun {
+		result.Diff, err = diffConfig(path, cfg)
+		return result, err
+	}
+	return result, writeConfig(cfg, path)
+}
@@ -0,0 +1,153 @@
+package kubeconfig
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/phenixrizen/rift/internal/fileutil"
+	"github.com/phenixrizen/rift/internal/state"
+	api "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// IndexFile is written by SyncSplit next to the per-context files. It holds
+// a single KUBECONFIG value listing all of them, e.g.
+//
+//	export KUBECONFIG="$HOME/.kube/config:$(cat ~/.kube/rift/index)"
+const IndexFile = "index"
+
+const splitExt = ".yaml"
+
+// SplitPath is the file SyncSplit writes a context to.
+func SplitPath(dir, context string) string {
+	return filepath.Join(dir, context+splitExt)
+}
+
+// SyncSplit writes each connectable cluster in st to its own kubeconfig,
+// dir/<context>.yaml, removes the files of managed contexts that are gone
+// (managed as in Sync), and rewrites dir/index. The files set no
+// current-context, so kubectl keeps the one from the user's main kubeconfig
+// and `kubectl config use-context` never writes into them. User additions
+// are kept as in Sync.
+func SyncSplit(dir string, st, prev state.State, dryRun, force bool) (SyncResult, error) {
+	result := SyncResult{}
+	var diffs strings.Builder
+	// The index lock stands for the whole directory.
+	indexPath := filepath.Join(dir, IndexFile)
+	if !dryRun {
+		unlock, err := fileutil.Lock(indexPath)
+		if err != nil {
+			return result, err
+		}
+		defer unlock()
+	}
+	desired := map[string]state.ClusterRecord{}
+	for _, cluster := range st.Clusters {
+		if cluster.External() {
+			continue
+		}
+		if !cluster.Connectable() {
+			result.SkippedContexts++
+			continue
+		}
+		desired[cluster.KubeContext] = cluster
+	}
+
+	owned := prev.KubeContexts()
+	entries, err := os.ReadDir(dir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return result, err
+	}
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), splitExt)
+		if !ok || entry.IsDir() {
+			continue
+		}
+		if _, wanted := desired[name]; wanted {
+			continue
+		}
+		if _, wasOurs := owned[name]; wasOurs || strings.HasPrefix(name, "rift-") {
+			result.RemovedContexts++
+			if dryRun {
+				diff, err := diffConfig(filepath.Join(dir, entry.Name()), nil)
+				if err != nil {
+					return result, err
+				}
+				diffs.WriteString(diff)
+				continue
+			}
+			if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil {
+				return result, err
+			}
+		}
+	}
+
+	names := make([]string, 0, len(desired))
+	for name := range desired {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if !dryRun {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return result, err
+		}
+	}
+	prevClusters := previousClusters(prev)
+	paths := make([]string, 0, len(names))
+	for _, ctxName := range names {
+		path := SplitPath(dir, ctxName)
+		paths = append(paths, path)
+		existing, err := loadConfig(path)
+		if err != nil {
+			return result, err
+		}
+		cfg := api.NewConfig()
+		cfg.Clusters[ctxName], cfg.AuthInfos[ctxName], cfg.Contexts[ctxName] = buildEntries(ctxName, desired[ctxName])
+		if !force {
+			cfg.Clusters[ctxName], cfg.AuthInfos[ctxName], cfg.Contexts[ctxName] = mergeEntries(existing, ctxName, cfg.Clusters[ctxName], cfg.AuthInfos[ctxName], cfg.Contexts[ctxName], prevClusters[ctxName])
+		}
+		_, existed := existing.Contexts[ctxName]
+		switch {
+		case !existed:
+			result.AddedContexts++
+		case !clusterEqual(existing.Clusters[ctxName], cfg.Clusters[ctxName]) || !userEqual(existing.AuthInfos[ctxName], cfg.AuthInfos[ctxName]) || !contextEqual(existing.Contexts[ctxName], cfg.Contexts[ctxName]):
+			result.UpdatedContexts++
+		default:
+			continue
+		}
+		if dryRun {
+			diff, err := diffConfig(path, cfg)
+			if err != nil {
+				return result, err
+			}
+			diffs.WriteString(diff)
+			continue
+		}
+		if err := writeConfig(cfg, path); err != nil {
+			return result, err
+		}
+	}
+
+	if dryRun {
+		result.Diff = diffs.String()
+		return result, nil
+	}
+	index := strings.Join(paths, string(os.PathListSeparator)) + "\n"
+	return result, fileutil.WriteAtomic(indexPath, []byte(index), 0o644)
+}
+
+// ReadIndex returns the files listed in dir's index; none when sync has not
+// written it yet.
+func ReadIndex(dir string) ([]string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, IndexFile))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return filepath.SplitList(strings.TrimSpace(string(data))), nil
+}
@@ -0,0 +1,97 @@
+package kubeconfig
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/phenixrizen/rift/internal/state"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+func TestSyncSplitWritesFilePerContextAndIndex(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "rift")
+	a := state.ClusterRecord{KubeContext: "rift-prod-acme-a", AWSProfile: "rift-prod-acme-admin", ClusterName: "a", Region: "us-east-1", ClusterEndpoint: "https://a"}
+	b := state.ClusterRecord{KubeContext: "rift-prod-acme-b", AWSProfile: "rift-prod-acme-admin", ClusterName: "b", Region: "us-east-1", ClusterEndpoint: "https://b", Namespace: "apps"}
+	first := state.State{Clusters: []state.ClusterRecord{a, b}}
+
+	result, err := SyncSplit(dir, first, state.State{}, false, false)
+	if err != nil {
+		t.Fatalf("SyncSplit returned error: %v", err)
+	}
+	if result.AddedContexts != 2 {
+		t.Fatalf("result=%+v want 2 added", result)
+	}
+	loaded, err := clientcmd.LoadFromFile(SplitPath(dir, b.KubeContext))
+	if err != nil {
+		t.Fatalf("load split file: %v", err)
+	}
+	if loaded.CurrentContext != "" || loaded.Contexts[b.KubeContext] == nil || loaded.Contexts[b.KubeContext].Namespace != "apps" {
+		t.Fatalf("split file = %+v, want only context %s with namespace apps", loaded, b.KubeContext)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "mine.yaml"), []byte("apiVersion: v1\nkind: Config\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err = SyncSplit(dir, state.State{Clusters: []state.ClusterRecord{a}}, first, false, false)
+	if err != nil {
+		t.Fatalf("second SyncSplit returned error: %v", err)
+	}
+	if result.AddedContexts != 0 || result.UpdatedContexts != 0 || result.RemovedContexts != 1 {
+		t.Fatalf("result=%+v want 1 removed", result)
+	}
+	if _, err := os.Stat(SplitPath(dir, b.KubeContext)); !os.IsNotExist(err) {
+		t.Fatalf("stale split file still present: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "mine.yaml")); err != nil {
+		t.Fatalf("user file removed: %v", err)
+	}
+	index, err := os.ReadFile(filepath.Join(dir, IndexFile))
+	if err != nil {
+		t.Fatalf("read index: %v", err)
+	}
+	if want := SplitPath(dir, a.KubeContext) + "\n"; string(index) != want {
+		t.Fatalf("index = %q, want %q", index, want)
+	}
+}
+
+func TestSweepRemovesManagedContextsAndKeepsCurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	templated := state.ClusterRecord{KubeContext: "payments-prod", AWSProfile: "rift-prod-acme-admin", ClusterName: "payments", Region: "us-east-1", ClusterEndpoint: "https://p"}
+	a := state.ClusterRecord{KubeContext: "rift-prod-acme-a", AWSProfile: "rift-prod-acme-admin", ClusterName: "a", Region: "us-east-1", ClusterEndpoint: "https://a"}
+	merged := state.State{Clusters: []state.ClusterRecord{templated, a}}
+	if _, err := Sync(path, merged, state.State{}, false, false); err != nil {
+		t.Fatalf("Sync returned error: %v", err)
+	}
+	cfg, err := loadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Contexts["kind-dev"] = cfg.Contexts[a.KubeContext].DeepCopy()
+	cfg.CurrentContext = a.KubeContext
+	if err := writeConfig(cfg, path); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Sweep(path, state.State{Clusters: []state.ClusterRecord{a}}, merged, true)
+	if err != nil || result.RemovedContexts != 2 || result.Diff == "" {
+		t.Fatalf("dry Sweep = %+v, %v; want 2 removed with a diff", result, err)
+	}
+	if _, err := Sweep(path, state.State{Clusters: []state.ClusterRecord{a}}, merged, false); err != nil {
+		t.Fatalf("Sweep returned error: %v", err)
+	}
+	loaded, err := clientcmd.LoadFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Contexts) != 1 || loaded.Contexts["kind-dev"] == nil || loaded.Clusters[templated.KubeContext] != nil {
+		t.Fatalf("contexts after Sweep = %v, want only kind-dev", loaded.Contexts)
+	}
+	if loaded.CurrentContext != a.KubeContext {
+		t.Fatalf("current-context = %q, want it kept", loaded.CurrentContext)
+	}
+	result, err = Sweep(path, state.State{}, merged, false)
+	if err != nil || result.RemovedContexts != 0 {
+		t.Fatalf("second Sweep = %+v, %v; want nothing removed", result, err)
+	}
+}
@@ -0,0 +1,95 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestServe(t *testing.T) {
+	s := &Server{Name: "rift", Version: "test", Tools: []Tool{
+		{
+			Name:        "echo",
+			Description: "Echo a word",
+			InputSchema: map[string]any{"type": "object"},
+			Call: func(_ context.Context, raw json.RawMessage) (any, error) {
+				var args struct {
+					Word string `json:"word"`
+				}
+				if err := Args(raw, &args); err != nil {
+					return nil, err
+				}
+				if args.Word == "" {
+					return nil, errors.New("word is required")
+				}
+				return map[string]string{"word": args.Word}, nil
+			},
+		},
+	}}
+	in := strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{},"clientInfo":{"name":"t","version":"1"}}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"echo","arguments":{"word":"hi"}}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"echo","arguments":{"wrod":"hi"}}}`,
+		`{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"nope"}}`,
+		`{"jsonrpc":"2.0","id":"six","method":"resources/list"}`,
+		`not json`,
+	}, "\n")
+	var out bytes.Buffer
+	if err := s.Serve(context.Background(), strings.NewReader(in), &out); err != nil {
+		t.Fatal(err)
+	}
+
+	type resp struct {
+		ID     json.RawMessage `json:"id"`
+		Result struct {
+			ProtocolVersion string `json:"protocolVersion"`
+			Tools           []struct {
+				Name string `json:"name"`
+			} `json:"tools"`
+			Content []struct {
+				Text string `json:"text"`
+			} `json:"content"`
+			IsError bool `json:"isError"`
+		} `json:"result"`
+		Error *rpcError `json:"error"`
+	}
+	var got []resp
+	dec := json.NewDecoder(&out)
+	for dec.More() {
+		var r resp
+		if err := dec.Decode(&r); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, r)
+	}
+	// The notification gets no response.
+	if len(got) != 7 {
+		t.Fatalf("got %d responses, want 7: %+v", len(got), got)
+	}
+	if got[0].Result.ProtocolVersion != "2024-11-05" {
+		t.Fatalf("initialize negotiated %q", got[0].Result.ProtocolVersion)
+	}
+	if len(got[1].Result.Tools) != 1 || got[1].Result.Tools[0].Name != "echo" {
+		t.Fatalf("tools/list = %+v", got[1].Result)
+	}
+	if got[2].Result.IsError || !strings.Contains(got[2].Result.Content[0].Text, `"word": "hi"`) {
+		t.Fatalf("echo = %+v", got[2].Result)
+	}
+	if !got[3].Result.IsError || !strings.Contains(got[3].Result.Content[0].Text, "wrod") {
+		t.Fatalf("misspelled argument = %+v", got[3].Result)
+	}
+	if got[4].Error == nil || got[4].Error.Code != codeInvalidParams {
+		t.Fatalf("unknown tool = %+v", got[4])
+	}
+	if string(got[5].ID) != `"six"` || got[5].Error == nil || got[5].Error.Code != codeMethodNotFound {
+		t.Fatalf("unknown method = %+v", got[5])
+	}
+	if string(got[6].ID) != "null" || got[6].Error == nil || got[6].Error.Code != codeParseError {
+		t.Fatalf("bad JSON = %+v", got[6])
+	}
+}
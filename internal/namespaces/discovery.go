@@ -4,16 +4,23 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
 	"os/exec"
+	"path"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/phenixrizen/rift/internal/ekstoken"
 	"github.com/phenixrizen/rift/internal/state"
 	"golang.org/x/sync/errgroup"
+	authorizationv1 "k8s.io/api/authorization/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
@@ -26,22 +33,73 @@ type Result struct {
 	Errors          int
 }
 
+// Credentials looks up already-fetched AWS credentials for a cluster's role.
+// When it reports ok, the token is signed with them in-process instead of
+// running aws eks get-token with the cluster's AWS profile.
+type Credentials func(state.ClusterRecord) (aws.Credentials, bool)
+
 type tokenResponse struct {
 	Status struct {
 		Token string `json:"token"`
 	} `json:"status"`
 }
 
-func Enrich(ctx context.Context, st *state.State, logger *slog.Logger) (Result, error) {
+// Filter selects the namespaces discovery records, by glob patterns
+// (path.Match). The zero Filter keeps every namespace.
+type Filter struct {
+	// Include, when set, keeps only names matching one of its patterns.
+	Include []string
+	// Exclude drops names matching any of its patterns.
+	Exclude []string
+}
+
+// Keep reports whether ns passes f.
+func (f Filter) Keep(ns string) bool {
+	if len(f.Include) > 0 && !matchAny(f.Include, ns) {
+		return false
+	}
+	return !matchAny(f.Exclude, ns)
+}
+
+func matchAny(patterns []string, ns string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, ns); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Options selects what Enrich records besides namespace names.
+type Options struct {
+	// Workloads replaces each cluster's Workloads with fresh
+	// per-namespace counts.
+	Workloads bool
+	// Access replaces each cluster's NamespaceAccess with the result of a
+	// SelfSubjectAccessReview per recorded namespace.
+	Access bool
+	// Filter selects the namespaces recorded.
+	Filter Filter
+}
+
+// Enrich lists the namespaces of every connectable cluster in st, merges
+// those opts.Filter keeps into its Namespaces, and stamps
+// NamespacesDiscoveredAt. Imported (external) clusters are skipped: rift has
+// no credentials for them. creds may be nil.
+func Enrich(ctx context.Context, st *state.State, logger *slog.Logger, creds Credentials, opts Options) (Result, error) {
 	result := Result{Enabled: true}
 	if st == nil || len(st.Clusters) == 0 {
 		return result, nil
 	}
 
 	type outcome struct {
-		idx        int
-		namespaces []string
-		err        error
+		idx         int
+		namespaces  []string
+		workloads   []state.NamespaceWorkloads
+		access      map[string]bool
+		err         error
+		workloadErr error
+		accessErr   error
 	}
 
 	outcomes := make([]outcome, 0, len(st.Clusters))
@@ -53,14 +111,26 @@ func Enrich(ctx context.Context, st *state.State, logger *slog.Logger) (Result,
 	for idx, cluster := range st.Clusters {
 		idx := idx
 		cluster := cluster
-		if strings.TrimSpace(cluster.ClusterEndpoint) == "" || strings.TrimSpace(cluster.ClusterName) == "" {
+		if strings.TrimSpace(cluster.ClusterEndpoint) == "" || strings.TrimSpace(cluster.ClusterName) == "" || cluster.External() || cluster.Shared() {
 			continue
 		}
 		result.ClustersTried++
 		g.Go(func() error {
-			namespaces, err := fetchClusterNamespaces(gctx, cluster)
+			item := outcome{idx: idx}
+			client, err := clusterClient(gctx, cluster, creds)
+			if err == nil {
+				item.namespaces, err = listNamespaces(gctx, client)
+			}
+			item.err = err
+			if err == nil && opts.Workloads {
+				item.workloads, item.workloadErr = countWorkloads(gctx, client)
+				item.workloads = filterWorkloads(item.workloads, opts.Filter)
+			}
+			if err == nil && opts.Access {
+				item.access, item.accessErr = checkAccess(gctx, client, mergeNamespaces(cluster, item.namespaces, opts.Filter))
+			}
 			mu.Lock()
-			outcomes = append(outcomes, outcome{idx: idx, namespaces: namespaces, err: err})
+			outcomes = append(outcomes, item)
 			mu.Unlock()
 			return nil
 		})
@@ -73,6 +143,7 @@ func Enrich(ctx context.Context, st *state.State, logger *slog.Logger) (Result,
 		return result, err
 	}
 
+	now := time.Now().UTC()
 	for _, item := range outcomes {
 		if item.err != nil {
 			result.Errors++
@@ -88,9 +159,45 @@ func Enrich(ctx context.Context, st *state.State, logger *slog.Logger) (Result,
 			}
 			continue
 		}
-		merged := mergeNamespaces(st.Clusters[item.idx], item.namespaces)
-		if !equalStringSets(st.Clusters[item.idx].Namespaces, merged) {
-			st.Clusters[item.idx].Namespaces = merged
+		cluster := &st.Clusters[item.idx]
+		cluster.NamespacesDiscoveredAt = now
+		updated := false
+		merged := mergeNamespaces(*cluster, item.namespaces, opts.Filter)
+		if !equalStringSets(cluster.Namespaces, merged) {
+			cluster.Namespaces = merged
+			updated = true
+		}
+		if item.workloadErr != nil {
+			// Listing apps often needs more RBAC than listing namespaces;
+			// leave the counts as they are rather than failing the cluster.
+			if logger != nil {
+				logger.Warn(
+					"workload discovery failed",
+					"context", cluster.KubeContext,
+					"cluster", cluster.ClusterName,
+					"region", cluster.Region,
+					"error", item.workloadErr,
+				)
+			}
+		} else if opts.Workloads && !slices.Equal(cluster.Workloads, item.workloads) {
+			cluster.Workloads = item.workloads
+			updated = true
+		}
+		if item.accessErr != nil {
+			if logger != nil {
+				logger.Warn(
+					"namespace access review failed",
+					"context", cluster.KubeContext,
+					"cluster", cluster.ClusterName,
+					"region", cluster.Region,
+					"error", item.accessErr,
+				)
+			}
+		} else if opts.Access && !maps.Equal(cluster.NamespaceAccess, item.access) {
+			cluster.NamespaceAccess = item.access
+			updated = true
+		}
+		if updated {
 			result.ClustersUpdated++
 		}
 	}
@@ -98,29 +205,21 @@ func Enrich(ctx context.Context, st *state.State, logger *slog.Logger) (Result,
 	return result, nil
 }
 
-func fetchClusterNamespaces(ctx context.Context, cluster state.ClusterRecord) ([]string, error) {
-	token, err := fetchToken(ctx, cluster)
-	if err != nil {
-		return nil, err
-	}
-
-	caData := []byte(cluster.ClusterCertificateBase64)
-	if decoded, err := base64.StdEncoding.DecodeString(cluster.ClusterCertificateBase64); err == nil {
-		caData = decoded
-	}
-
-	cfg := &rest.Config{
-		Host:        cluster.ClusterEndpoint,
-		BearerToken: token,
-		TLSClientConfig: rest.TLSClientConfig{
-			CAData: caData,
-		},
-		Timeout: 15 * time.Second,
+func clusterClient(ctx context.Context, cluster state.ClusterRecord, creds Credentials) (*kubernetes.Clientset, error) {
+	var token string
+	var err error
+	if c, ok := lookup(creds, cluster); ok {
+		token, err = fetchToken(ctx, cluster, &c)
+	} else {
+		token, err = FetchToken(ctx, cluster)
 	}
-	client, err := kubernetes.NewForConfig(cfg)
 	if err != nil {
 		return nil, err
 	}
+	return NewClientWithToken(cluster, token)
+}
+
+func listNamespaces(ctx context.Context, client kubernetes.Interface) ([]string, error) {
 	out, err := client.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
 	if err != nil {
 		return nil, err
@@ -135,25 +234,164 @@ func fetchClusterNamespaces(ctx context.Context, cluster state.ClusterRecord) ([
 	return namespaces, nil
 }
 
-func fetchToken(ctx context.Context, cluster state.ClusterRecord) (string, error) {
-	args := []string{
-		"eks",
-		"get-token",
-		"--profile",
-		cluster.AWSProfile,
-		"--cluster-name",
-		cluster.ClusterName,
-		"--region",
-		cluster.Region,
-		"--output",
-		"json",
-	}
-	cmd := exec.CommandContext(ctx, "aws", args...)
-	output, err := cmd.CombinedOutput()
+// countWorkloads counts deployments, statefulsets, and daemonsets per
+// namespace across the cluster, sorted by namespace. Namespaces running
+// none of them get no entry.
+func countWorkloads(ctx context.Context, client kubernetes.Interface) ([]state.NamespaceWorkloads, error) {
+	deployments, err := client.AppsV1().Deployments("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("list deployments: %w", err)
+	}
+	statefulSets, err := client.AppsV1().StatefulSets("").List(ctx, metav1.ListOptions{})
 	if err != nil {
-		msg := strings.TrimSpace(string(output))
-		if msg != "" {
-			return "", fmt.Errorf("aws eks get-token: %s", msg)
+		return nil, fmt.Errorf("list statefulsets: %w", err)
+	}
+	daemonSets, err := client.AppsV1().DaemonSets("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("list daemonsets: %w", err)
+	}
+	counts := map[string]*state.NamespaceWorkloads{}
+	get := func(ns string) *state.NamespaceWorkloads {
+		if counts[ns] == nil {
+			counts[ns] = &state.NamespaceWorkloads{Namespace: ns}
+		}
+		return counts[ns]
+	}
+	for _, item := range deployments.Items {
+		get(item.Namespace).Deployments++
+	}
+	for _, item := range statefulSets.Items {
+		get(item.Namespace).StatefulSets++
+	}
+	for _, item := range daemonSets.Items {
+		get(item.Namespace).DaemonSets++
+	}
+	out := make([]state.NamespaceWorkloads, 0, len(counts))
+	for _, w := range counts {
+		out = append(out, *w)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Namespace < out[j].Namespace })
+	return out, nil
+}
+
+// Access checks ask whether the caller may get pods in a namespace: a
+// namespace that is listed but not readable is just noise.
+const (
+	accessVerb     = "get"
+	accessResource = "pods"
+)
+
+// checkAccess issues a SelfSubjectAccessReview for each of names and
+// reports which ones allow the access check.
+func checkAccess(ctx context.Context, client kubernetes.Interface, names []string) (map[string]bool, error) {
+	access := make(map[string]bool, len(names))
+	var mu sync.Mutex
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(8)
+	for _, ns := range names {
+		g.Go(func() error {
+			review, err := client.AuthorizationV1().SelfSubjectAccessReviews().Create(gctx, &authorizationv1.SelfSubjectAccessReview{
+				Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+					ResourceAttributes: &authorizationv1.ResourceAttributes{Namespace: ns, Verb: accessVerb, Resource: accessResource},
+				},
+			}, metav1.CreateOptions{})
+			if err != nil {
+				return fmt.Errorf("review access to %s: %w", ns, err)
+			}
+			mu.Lock()
+			access[ns] = review.Status.Allowed
+			mu.Unlock()
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	return access, nil
+}
+
+// List returns the cluster's namespace names, sorted, looked up live with
+// a client from NewClient.
+func List(ctx context.Context, cluster state.ClusterRecord) ([]string, error) {
+	client, err := NewClient(ctx, cluster)
+	if err != nil {
+		return nil, err
+	}
+	return listNamespaces(ctx, client)
+}
+
+// NewClient builds a clientset for a rift cluster using an EKS bearer token.
+func NewClient(ctx context.Context, cluster state.ClusterRecord) (*kubernetes.Clientset, error) {
+	token, err := FetchToken(ctx, cluster)
+	if err != nil {
+		return nil, err
+	}
+	return NewClientWithToken(cluster, token)
+}
+
+// NewClientWithToken builds a clientset for a rift cluster from a token.
+func NewClientWithToken(cluster state.ClusterRecord, token string) (*kubernetes.Clientset, error) {
+	return kubernetes.NewForConfig(RestConfig(cluster, token))
+}
+
+// RestConfig returns a client config for the cluster endpoint and CA.
+func RestConfig(cluster state.ClusterRecord, token string) *rest.Config {
+	caData := []byte(cluster.ClusterCertificateBase64)
+	if decoded, err := base64.StdEncoding.DecodeString(cluster.ClusterCertificateBase64); err == nil {
+		caData = decoded
+	}
+	return &rest.Config{
+		Host:        cluster.ClusterEndpoint,
+		BearerToken: token,
+		TLSClientConfig: rest.TLSClientConfig{
+			CAData: caData,
+		},
+		Timeout: 15 * time.Second,
+	}
+}
+
+// FetchToken returns an EKS bearer token for the cluster's AWS profile.
+func FetchToken(ctx context.Context, cluster state.ClusterRecord) (string, error) {
+	return fetchToken(ctx, cluster, nil)
+}
+
+func lookup(creds Credentials, cluster state.ClusterRecord) (aws.Credentials, bool) {
+	if creds == nil {
+		return aws.Credentials{}, false
+	}
+	return creds(cluster)
+}
+
+// fetchToken returns a bearer token for the cluster. With creds, an EKS
+// token is signed in-process (ekstoken.Generate); otherwise it runs the
+// cluster's exec credential plugin: aws eks get-token with the cluster's
+// profile for EKS, the plugin from TokenCommand (gke-gcloud-auth-plugin,
+// kubelogin) for other clouds.
+func fetchToken(ctx context.Context, cluster state.ClusterRecord, creds *aws.Credentials) (string, error) {
+	if cluster.IsAWS() && creds != nil {
+		tok, err := ekstoken.Generate(ctx, *creds, cluster.Region, cluster.TokenClusterID())
+		if err != nil {
+			return "", fmt.Errorf("sign token: %w", err)
+		}
+		return tok.Value, nil
+	}
+	command, args := cluster.TokenCommand()
+	name := command
+	if cluster.IsAWS() {
+		name = "aws eks get-token"
+		args = []string{"eks", "get-token", "--profile", cluster.AWSProfile}
+		args = append(args, cluster.TokenClusterArgs()...)
+		args = append(args, "--region", cluster.Region, "--output", "json")
+	}
+	cmd := exec.CommandContext(ctx, command, args...)
+	// Plugins may warn on stderr, so only stdout is parsed.
+	output, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			if msg := strings.TrimSpace(string(exitErr.Stderr)); msg != "" {
+				return "", fmt.Errorf("%s: %s", name, msg)
+			}
 		}
 		return "", err
 	}
@@ -163,16 +401,19 @@ func fetchToken(ctx context.Context, cluster state.ClusterRecord) (string, error
 	}
 	token := strings.TrimSpace(parsed.Status.Token)
 	if token == "" {
-		return "", fmt.Errorf("empty token from aws eks get-token")
+		return "", fmt.Errorf("empty token from %s", name)
 	}
 	return token, nil
 }
 
-func mergeNamespaces(cluster state.ClusterRecord, discovered []string) []string {
+// mergeNamespaces adds discovered to the cluster's recorded namespaces,
+// dropping those filter rejects. The context's default namespace is always
+// kept.
+func mergeNamespaces(cluster state.ClusterRecord, discovered []string, filter Filter) []string {
 	set := map[string]struct{}{}
 	for _, ns := range cluster.Namespaces {
 		ns = strings.TrimSpace(ns)
-		if ns != "" {
+		if ns != "" && filter.Keep(ns) {
 			set[ns] = struct{}{}
 		}
 	}
@@ -181,7 +422,7 @@ func mergeNamespaces(cluster state.ClusterRecord, discovered []string) []string
 	}
 	for _, ns := range discovered {
 		ns = strings.TrimSpace(ns)
-		if ns != "" {
+		if ns != "" && filter.Keep(ns) {
 			set[ns] = struct{}{}
 		}
 	}
@@ -193,6 +434,17 @@ func mergeNamespaces(cluster state.ClusterRecord, discovered []string) []string
 	return out
 }
 
+// filterWorkloads drops the counts of namespaces filter rejects.
+func filterWorkloads(counts []state.NamespaceWorkloads, filter Filter) []state.NamespaceWorkloads {
+	out := make([]state.NamespaceWorkloads, 0, len(counts))
+	for _, c := range counts {
+		if filter.Keep(c.Namespace) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
 func equalStringSets(a, b []string) bool {
 	if len(a) != len(b) {
 		return false
@@ -0,0 +1,97 @@
+package namespaces
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/phenixrizen/rift/internal/state"
+	appsv1 "k8s.io/api/apps/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestCountWorkloads(t *testing.T) {
+	meta := func(ns, name string) metav1.ObjectMeta { return metav1.ObjectMeta{Namespace: ns, Name: name} }
+	client := fake.NewSimpleClientset(
+		&appsv1.Deployment{ObjectMeta: meta("payments", "api")},
+		&appsv1.Deployment{ObjectMeta: meta("payments", "worker")},
+		&appsv1.Deployment{ObjectMeta: meta("default", "web")},
+		&appsv1.StatefulSet{ObjectMeta: meta("kafka", "broker")},
+		&appsv1.DaemonSet{ObjectMeta: meta("kube-system", "aws-node")},
+	)
+	got, err := countWorkloads(context.Background(), client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []state.NamespaceWorkloads{
+		{Namespace: "default", Deployments: 1},
+		{Namespace: "kafka", StatefulSets: 1},
+		{Namespace: "kube-system", DaemonSets: 1},
+		{Namespace: "payments", Deployments: 2},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("countWorkloads = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("countWorkloads[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestCheckAccess(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+		attrs := review.Spec.ResourceAttributes
+		review.Status.Allowed = attrs.Namespace == "payments" && attrs.Verb == "get" && attrs.Resource == "pods"
+		return true, review, nil
+	})
+	got, err := checkAccess(context.Background(), client, []string{"kube-system", "payments"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || !got["payments"] || got["kube-system"] {
+		t.Fatalf("checkAccess = %v", got)
+	}
+}
+
+func TestFetchTokenSignsWithCredentials(t *testing.T) {
+	// PATH is empty, so the token cannot come from the aws CLI.
+	t.Setenv("PATH", "")
+	creds := aws.Credentials{AccessKeyID: "AKID", SecretAccessKey: "SECRET", SessionToken: "SESSION"}
+	cluster := state.ClusterRecord{ClusterName: "payments", Region: "us-west-2", AWSProfile: "rift-prod-payments-admin"}
+	token, err := fetchToken(context.Background(), cluster, &creds)
+	if err != nil {
+		t.Fatalf("fetchToken returned error: %v", err)
+	}
+	if !strings.HasPrefix(token, "k8s-aws-v1.") {
+		t.Fatalf("token = %q", token)
+	}
+}
+
+func TestFilterAndMergeNamespaces(t *testing.T) {
+	filter := Filter{Exclude: []string{"kube-*", "cattle-*"}}
+	cluster := state.ClusterRecord{Namespace: "kube-system", Namespaces: []string{"cattle-system", "payments"}}
+	got := mergeNamespaces(cluster, []string{"default", "kube-public", "kafka"}, filter)
+	// The context's default namespace stays even when excluded.
+	if want := []string{"default", "kafka", "kube-system", "payments"}; !slices.Equal(got, want) {
+		t.Fatalf("mergeNamespaces = %v want %v", got, want)
+	}
+
+	filter = Filter{Include: []string{"team-*", "default"}, Exclude: []string{"team-old"}}
+	for ns, want := range map[string]bool{"team-a": true, "default": true, "team-old": false, "kafka": false} {
+		if got := filter.Keep(ns); got != want {
+			t.Fatalf("Keep(%q) = %v want %v", ns, got, want)
+		}
+	}
+	if !(Filter{}).Keep("kube-system") {
+		t.Fatal("zero Filter dropped a namespace")
+	}
+}
@@ -0,0 +1,120 @@
+package naming
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/phenixrizen/rift/internal/config"
+	"github.com/phenixrizen/rift/internal/state"
+)
+
+// Explanation describes how BuildState derived a cluster's names and which
+// config entries applied to it.
+type Explanation struct {
+	Env       EnvMatch `json:"env"`
+	EnvInputs []string `json:"env_inputs"`
+	// EnvSource is "env_rules" or "built-in keywords".
+	EnvSource string `json:"env_source"`
+	// RoleEnv is the env of the cluster's role, classified from the account
+	// and role only as BuildState does; it names the profile and picks the
+	// regions, so it can differ from Env.
+	RoleEnv string `json:"role_env"`
+
+	AccountSource string `json:"account_source"`
+	AccountSlug   string `json:"account_slug"`
+	ClusterSlug   string `json:"cluster_slug"`
+	ContextBase   string `json:"context_base"`
+	Context       string `json:"context"`
+	// ContextPattern is context_template or DefaultContextPattern.
+	ContextPattern string `json:"context_pattern"`
+
+	RoleSlug    string `json:"role_slug"`
+	ProfileBase string `json:"profile_base"`
+	Profile     string `json:"profile"`
+	// ProfilePattern is profile_template or DefaultProfilePattern.
+	ProfilePattern string `json:"profile_pattern"`
+
+	// RegionOverride is the index into region_overrides, or -1.
+	RegionOverride int      `json:"region_override"`
+	Regions        []string `json:"regions"`
+
+	Namespace       string `json:"namespace"`
+	NamespaceSource string `json:"namespace_source"`
+}
+
+// Explain re-runs the naming rules for a cluster already in state. A context
+// or profile that differs from its base got a numeric suffix because another
+// cluster or role produced the same base name.
+func Explain(cfg config.Config, rec state.ClusterRecord) Explanation {
+	alias := cfg.AccountAlias(rec.AccountID)
+	accountName := accountNameFor(alias, rec.AccountName)
+	inputs := []string{accountName, rec.RoleName, rec.ClusterName}
+	match := ClassifyEnv(cfg, accountName, rec.RoleName, rec.ClusterName)
+	roleEnv := ClassifyEnv(cfg, accountName, rec.RoleName, "").Env
+
+	ex := Explanation{
+		Env:            match,
+		EnvInputs:      inputs,
+		EnvSource:      "built-in keywords",
+		RoleEnv:        roleEnv,
+		AccountSlug:    accountSlugFor(alias, rec.AccountName, rec.AccountID),
+		ClusterSlug:    Slug(rec.ClusterName),
+		Context:        rec.KubeContext,
+		RoleSlug:       Slug(rec.RoleName),
+		Profile:        rec.AWSProfile,
+		RegionOverride: cfg.RegionOverrideIndex(roleEnv, rec.AccountName, rec.AccountID),
+		Namespace:      rec.Namespace,
+	}
+	if len(cfg.EnvRules) > 0 {
+		ex.EnvSource = "env_rules"
+	}
+	switch {
+	case strings.TrimSpace(alias) != "":
+		ex.AccountSource = fmt.Sprintf("account_aliases[%s]", rec.AccountID)
+	case Slug(rec.AccountName) != "unknown":
+		ex.AccountSource = "sso account name"
+	default:
+		ex.AccountSource = "account id"
+	}
+	ex.Regions = cfg.RegionsFor(roleEnv, rec.AccountName, rec.AccountID)
+	data := nameData{
+		Env:         match.Env,
+		Account:     ex.AccountSlug,
+		AccountName: accountName,
+		AccountID:   rec.AccountID,
+		Role:        ex.RoleSlug,
+		RoleName:    rec.RoleName,
+		Region:      rec.Region,
+		SSOSession:  rec.SSOSession,
+		Cluster:     ex.ClusterSlug,
+		ClusterName: rec.ClusterName,
+	}
+	names := newNameTemplates(cfg)
+	ex.ContextBase = names.contextBase(data)
+	data.Env = roleEnv
+	data.Region = firstRegion(ex.Regions)
+	ex.ProfileBase = names.profileBase(data)
+	ex.ContextPattern = DefaultContextPattern
+	if names.context != nil {
+		ex.ContextPattern = cfg.ContextTemplate
+	}
+	ex.ProfilePattern = DefaultProfilePattern
+	if names.profile != nil {
+		ex.ProfilePattern = cfg.ProfileTemplate
+	}
+
+	nsIdx := cfg.NamespaceOverrideIndex(match.Env, rec.AccountName, rec.AccountID, rec.ClusterName)
+	switch {
+	case rec.NamespacePinned:
+		ex.NamespaceSource = "pinned in state"
+	case rec.Namespace == "":
+		ex.NamespaceSource = "none configured"
+	case nsIdx >= 0 && cfg.NamespaceOverrides[nsIdx].Namespace == rec.Namespace:
+		ex.NamespaceSource = fmt.Sprintf("namespace_overrides[%d]", nsIdx)
+	case cfg.NamespaceForEnv(match.Env) == rec.Namespace:
+		ex.NamespaceSource = fmt.Sprintf("namespace_defaults[%s]", match.Env)
+	default:
+		ex.NamespaceSource = "discovered"
+	}
+	return ex
+}
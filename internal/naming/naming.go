@@ -5,13 +5,17 @@ import (
 	"regexp"
 	"sort"
 	"strings"
+	"text/template"
 
 	"github.com/phenixrizen/rift/internal/config"
 	"github.com/phenixrizen/rift/internal/discovery"
 	"github.com/phenixrizen/rift/internal/state"
 )
 
-var nonSlugRegex = regexp.MustCompile(`[^a-z0-9]+`)
+var (
+	nonSlugRegex = regexp.MustCompile(`[^a-z0-9]+`)
+	nonNameRegex = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+)
 
 func Slug(input string) string {
 	s := strings.ToLower(strings.TrimSpace(input))
@@ -23,20 +27,97 @@ func Slug(input string) string {
 	return s
 }
 
+type envRule struct {
+	env      string
+	keywords []string
+}
+
+// envRules are checked in order; the first keyword contained in any input
+// decides the env.
+var envRules = []envRule{
+	{env: "prod", keywords: []string{"prod"}},
+	{env: "staging", keywords: []string{"staging", "stage"}},
+	{env: "dev", keywords: []string{"development", "dev"}},
+	{env: "int", keywords: []string{"integration", "int"}},
+}
+
+// EnvMatch records how InferEnv or ClassifyEnv classified a set of names.
+type EnvMatch struct {
+	Env     string `json:"env"`
+	Keyword string `json:"keyword,omitempty"`
+	// Part is the input that contained Keyword.
+	Part string `json:"part,omitempty"`
+	// Rule is the matching env_rules entry, e.g. "env_rules[2]"; empty for
+	// the built-in keywords.
+	Rule string `json:"rule,omitempty"`
+}
+
+// InferEnv applies the built-in keywords only; use ClassifyEnv to honor
+// env_rules.
 func InferEnv(parts ...string) string {
-	combined := strings.ToLower(strings.Join(parts, " "))
-	switch {
-	case strings.Contains(combined, "prod"):
-		return "prod"
-	case strings.Contains(combined, "staging"), strings.Contains(combined, "stage"):
-		return "staging"
-	case strings.Contains(combined, "development"), strings.Contains(combined, "dev"):
-		return "dev"
-	case strings.Contains(combined, "integration"), strings.Contains(combined, "int"):
-		return "int"
-	default:
-		return "other"
+	return MatchEnv(parts...).Env
+}
+
+// ClassifyEnv decides the env for an account (alias or name), role, and
+// cluster. Configured env_rules replace the built-in keywords.
+func ClassifyEnv(cfg config.Config, account, role, cluster string) EnvMatch {
+	return newEnvClassifier(cfg.EnvRules).classify(account, role, cluster)
+}
+
+type envClassifier struct {
+	rules   []config.EnvRule
+	regexps []*regexp.Regexp
+}
+
+func newEnvClassifier(rules []config.EnvRule) envClassifier {
+	c := envClassifier{}
+	for _, rule := range rules {
+		// Config validation rejects bad patterns; skip rather than panic on
+		// a config that bypassed it.
+		re, err := rule.Regexp()
+		if err != nil {
+			continue
+		}
+		c.rules = append(c.rules, rule)
+		c.regexps = append(c.regexps, re)
 	}
+	return c
+}
+
+func (c envClassifier) classify(account, role, cluster string) EnvMatch {
+	if len(c.rules) == 0 {
+		return MatchEnv(account, role, cluster)
+	}
+	fields := []struct{ name, value string }{
+		{config.EnvFieldAccount, account},
+		{config.EnvFieldRole, role},
+		{config.EnvFieldCluster, cluster},
+	}
+	for i, rule := range c.rules {
+		for _, f := range fields {
+			if f.value == "" || (rule.Field != "" && rule.Field != f.name) {
+				continue
+			}
+			if loc := c.regexps[i].FindStringIndex(f.value); loc != nil {
+				return EnvMatch{Env: rule.Env, Keyword: f.value[loc[0]:loc[1]], Part: f.value, Rule: fmt.Sprintf("env_rules[%d]", i)}
+			}
+		}
+	}
+	return EnvMatch{Env: "other"}
+}
+
+// MatchEnv is InferEnv with the matching keyword and input reported.
+func MatchEnv(parts ...string) EnvMatch {
+	for _, rule := range envRules {
+		for _, keyword := range rule.keywords {
+			for _, part := range parts {
+				if strings.Contains(strings.ToLower(part), keyword) {
+					return EnvMatch{Env: rule.env, Keyword: keyword, Part: part}
+				}
+			}
+		}
+	}
+	return EnvMatch{Env: "other"}
 }
 
 type uniqueNamer struct {
@@ -47,8 +128,9 @@ func newUniqueNamer() *uniqueNamer {
 	return &uniqueNamer{counts: map[string]int{}}
 }
 
+// next returns base, or base-N when base was already handed out. Callers
+// pass names already cleaned by nameTemplates.
 func (u *uniqueNamer) next(base string) string {
-	base = Slug(base)
 	u.counts[base]++
 	if u.counts[base] == 1 {
 		return base
@@ -56,38 +138,148 @@ func (u *uniqueNamer) next(base string) string {
 	return fmt.Sprintf("%s-%d", base, u.counts[base])
 }
 
+// Default name patterns, used when profile_template/context_template are
+// unset.
+const (
+	DefaultProfilePattern = "rift-<env>-<account>-<role>"
+	DefaultContextPattern = "rift-<env>-<account>-<cluster>"
+)
+
+// nameData holds the values profile_template and context_template can use
+// (see config.ProfileTemplateFields and config.ContextTemplateFields).
+type nameData struct {
+	Env, Account, AccountName, AccountID string
+	Role, RoleName, Region, SSOSession   string
+	Cluster, ClusterName                 string
+}
+
+func (d nameData) fields(names []string) map[string]string {
+	all := map[string]string{
+		"Env": d.Env, "Account": d.Account, "AccountName": d.AccountName, "AccountID": d.AccountID,
+		"Role": d.Role, "RoleName": d.RoleName, "Region": d.Region, "SSOSession": d.SSOSession,
+		"Cluster": d.Cluster, "ClusterName": d.ClusterName,
+	}
+	out := make(map[string]string, len(names))
+	for _, name := range names {
+		out[name] = all[name]
+	}
+	return out
+}
+
+// nameTemplates renders base profile and context names, from the configured
+// templates or the fixed rift-<env>-<account>-<role|cluster> scheme.
+type nameTemplates struct {
+	profile *template.Template
+	context *template.Template
+}
+
+func newNameTemplates(cfg config.Config) nameTemplates {
+	var t nameTemplates
+	// Config validation rejects bad templates; a nil template falls back to
+	// the default scheme.
+	if strings.TrimSpace(cfg.ProfileTemplate) != "" {
+		t.profile, _ = config.NameTemplate("profile_template", cfg.ProfileTemplate)
+	}
+	if strings.TrimSpace(cfg.ContextTemplate) != "" {
+		t.context, _ = config.NameTemplate("context_template", cfg.ContextTemplate)
+	}
+	return t
+}
+
+func (t nameTemplates) profileBase(d nameData) string {
+	if t.profile == nil {
+		return Slug(fmt.Sprintf("rift-%s-%s-%s", d.Env, d.Account, d.Role))
+	}
+	return renderName(t.profile, d.fields(config.ProfileTemplateFields))
+}
+
+func (t nameTemplates) contextBase(d nameData) string {
+	if t.context == nil {
+		return Slug(fmt.Sprintf("rift-%s-%s-%s", d.Env, d.Account, d.Cluster))
+	}
+	return renderName(t.context, d.fields(config.ContextTemplateFields))
+}
+
+// renderName executes tmpl and keeps the result safe for AWS profile and
+// kube context names: runs of characters other than letters, digits, ".",
+// "_", and "-" become "-".
+func renderName(tmpl *template.Template, data map[string]string) string {
+	var out strings.Builder
+	if err := tmpl.Execute(&out, data); err != nil {
+		return "unknown"
+	}
+	name := strings.Trim(nonNameRegex.ReplaceAllString(strings.TrimSpace(out.String()), "-"), "-")
+	if name == "" {
+		return "unknown"
+	}
+	return name
+}
+
+func firstRegion(regions []string) string {
+	if len(regions) == 0 {
+		return ""
+	}
+	return regions[0]
+}
+
+// roleKey identifies a role within one discovery: an assume_roles target
+// can share its account and role name with an SSO permission set, so the
+// role ARN it is assumed as (empty for SSO roles) is part of the key.
+func roleKey(session, accountID, roleName, assumeRoleARN string) string {
+	return strings.Join([]string{session, accountID, roleName, assumeRoleARN}, "|")
+}
+
 func BuildState(cfg config.Config, inv discovery.Inventory) state.State {
+	envs := newEnvClassifier(cfg.EnvRules)
+	names := newNameTemplates(cfg)
 	profileNamer := newUniqueNamer()
 	contextNamer := newUniqueNamer()
 
 	roleKeyToProfile := map[string]string{}
 	roles := make([]state.RoleRecord, 0, len(inv.Roles))
 
-	sort.Slice(inv.Roles, func(i, j int) bool {
+	// Chained roles sort after SSO roles so their source profiles are named first.
+	sort.SliceStable(inv.Roles, func(i, j int) bool {
+		if (inv.Roles[i].AssumeRoleARN == "") != (inv.Roles[j].AssumeRoleARN == "") {
+			return inv.Roles[i].AssumeRoleARN == ""
+		}
 		left := strings.Join([]string{inv.Roles[i].AccountName, inv.Roles[i].AccountID, inv.Roles[i].RoleName}, "|")
 		right := strings.Join([]string{inv.Roles[j].AccountName, inv.Roles[j].AccountID, inv.Roles[j].RoleName}, "|")
 		return left < right
 	})
 
 	for _, role := range inv.Roles {
-		env := InferEnv(role.AccountName, role.RoleName)
-		accountSlug := Slug(role.AccountName)
-		if accountSlug == "unknown" {
-			accountSlug = Slug(role.AccountID)
-		}
+		alias := cfg.AccountAlias(role.AccountID)
+		env := envs.classify(accountNameFor(alias, role.AccountName), role.RoleName, "").Env
+		accountSlug := accountSlugFor(alias, role.AccountName, role.AccountID)
 		roleSlug := Slug(role.RoleName)
-		base := fmt.Sprintf("rift-%s-%s-%s", env, accountSlug, roleSlug)
-		profile := profileNamer.next(base)
-		key := role.AccountID + "|" + role.RoleName
-		roleKeyToProfile[key] = profile
-		roles = append(roles, state.RoleRecord{
+		profile := profileNamer.next(names.profileBase(nameData{
 			Env:         env,
+			Account:     accountSlug,
+			AccountName: accountNameFor(alias, role.AccountName),
 			AccountID:   role.AccountID,
-			AccountName: role.AccountName,
+			Role:        roleSlug,
 			RoleName:    role.RoleName,
-			RoleSlug:    roleSlug,
-			AWSProfile:  profile,
-		})
+			Region:      firstRegion(cfg.RegionsFor(env, role.AccountName, role.AccountID)),
+			SSOSession:  role.SSOSession,
+		}))
+		roleKeyToProfile[roleKey(role.SSOSession, role.AccountID, role.RoleName, role.AssumeRoleARN)] = profile
+		record := state.RoleRecord{
+			Env:          env,
+			AccountID:    role.AccountID,
+			AccountName:  role.AccountName,
+			AccountAlias: alias,
+			RoleName:     role.RoleName,
+			RoleSlug:     roleSlug,
+			AWSProfile:   profile,
+			SSOSession:   role.SSOSession,
+		}
+		if role.AssumeRoleARN != "" {
+			record.AssumeRoleARN = role.AssumeRoleARN
+			record.ExternalID = role.ExternalID
+			record.SourceProfile = roleKeyToProfile[roleKey(role.SSOSession, role.SourceAccountID, role.SourceRoleName, "")]
+		}
+		roles = append(roles, record)
 	}
 
 	sort.Slice(inv.Clusters, func(i, j int) bool {
@@ -98,38 +290,56 @@ func BuildState(cfg config.Config, inv discovery.Inventory) state.State {
 
 	clusters := make([]state.ClusterRecord, 0, len(inv.Clusters))
 	for _, cluster := range inv.Clusters {
-		env := InferEnv(cluster.AccountName, cluster.RoleName, cluster.ClusterName)
-		accountSlug := Slug(cluster.AccountName)
-		if accountSlug == "unknown" {
-			accountSlug = Slug(cluster.AccountID)
+		alias := cfg.AccountAlias(cluster.AccountID)
+		env := envs.classify(accountNameFor(alias, cluster.AccountName), cluster.RoleName, cluster.ClusterName).Env
+		accountSlug := accountSlugFor(alias, cluster.AccountName, cluster.AccountID)
+		data := nameData{
+			Env:         env,
+			Account:     accountSlug,
+			AccountName: accountNameFor(alias, cluster.AccountName),
+			AccountID:   cluster.AccountID,
+			Role:        Slug(cluster.RoleName),
+			RoleName:    cluster.RoleName,
+			Region:      cluster.Region,
+			SSOSession:  cluster.SSOSession,
+			Cluster:     Slug(cluster.ClusterName),
+			ClusterName: cluster.ClusterName,
 		}
-		clusterSlug := Slug(cluster.ClusterName)
-		contextBase := fmt.Sprintf("rift-%s-%s-%s", env, accountSlug, clusterSlug)
-		context := contextNamer.next(contextBase)
-		key := cluster.AccountID + "|" + cluster.RoleName
+		context := contextNamer.next(names.contextBase(data))
+		key := roleKey(cluster.SSOSession, cluster.AccountID, cluster.RoleName, cluster.AssumeRoleARN)
 		profile := roleKeyToProfile[key]
-		if profile == "" {
-			roleSlug := Slug(cluster.RoleName)
-			profile = profileNamer.next(fmt.Sprintf("rift-%s-%s-%s", env, accountSlug, roleSlug))
+		// Clusters on other clouds (GKE, AKS) have no AWS role or profile.
+		if profile == "" && state.AWSPlatform(cluster.Platform) {
+			roleSlug := data.Role
+			profileData := data
+			profileData.Region = firstRegion(cfg.RegionsFor(env, cluster.AccountName, cluster.AccountID))
+			profile = profileNamer.next(names.profileBase(profileData))
 			roleKeyToProfile[key] = profile
 			roles = append(roles, state.RoleRecord{
-				Env:         env,
-				AccountID:   cluster.AccountID,
-				AccountName: cluster.AccountName,
-				RoleName:    cluster.RoleName,
-				RoleSlug:    roleSlug,
-				AWSProfile:  profile,
+				Env:          env,
+				AccountID:    cluster.AccountID,
+				AccountName:  cluster.AccountName,
+				AccountAlias: alias,
+				RoleName:     cluster.RoleName,
+				RoleSlug:     roleSlug,
+				AWSProfile:   profile,
+				SSOSession:   cluster.SSOSession,
 			})
 		}
-		namespace := cfg.NamespaceForEnv(env)
+		namespace := cfg.NamespaceFor(env, cluster.AccountName, cluster.AccountID, cluster.ClusterName)
 		namespaces := []string{}
 		if namespace != "" {
 			namespaces = append(namespaces, namespace)
 		}
+		execPlugin := ""
+		if cfg.KubeconfigExec == config.KubeconfigExecRift && state.AWSPlatform(cluster.Platform) {
+			execPlugin = state.ExecPluginRift
+		}
 		clusters = append(clusters, state.ClusterRecord{
 			Env:                      env,
 			AccountID:                cluster.AccountID,
 			AccountName:              cluster.AccountName,
+			AccountAlias:             alias,
 			RoleName:                 cluster.RoleName,
 			AWSProfile:               profile,
 			Region:                   cluster.Region,
@@ -137,15 +347,28 @@ func BuildState(cfg config.Config, inv discovery.Inventory) state.State {
 			ClusterARN:               cluster.ClusterARN,
 			ClusterEndpoint:          cluster.ClusterEndpoint,
 			ClusterCertificateBase64: cluster.ClusterCertificateBase64,
+			ClusterID:                cluster.ClusterID,
+			Platform:                 cluster.Platform,
+			ConnectorProvider:        cluster.ConnectorProvider,
+			Plugin:                   cluster.Plugin,
 			KubeContext:              context,
 			Namespace:                namespace,
 			Namespaces:               namespaces,
+			SSOSession:               cluster.SSOSession,
+			ExecPlugin:               execPlugin,
+			KubernetesVersion:        cluster.KubernetesVersion,
+			Status:                   cluster.Status,
+			PlatformVersion:          cluster.PlatformVersion,
+			CreatedAt:                cluster.CreatedAt,
+			AWSTags:                  cluster.AWSTags,
+			Nodegroups:               cluster.Nodegroups,
+			FargateProfiles:          cluster.FargateProfiles,
 		})
 	}
 
 	st := state.State{
 		GeneratedAt: inv.GeneratedAt,
-		Regions:     append([]string(nil), cfg.Regions...),
+		Regions:     cfg.AllRegions(),
 		Roles:       dedupeRoles(roles),
 		Clusters:    clusters,
 	}
@@ -153,6 +376,24 @@ func BuildState(cfg config.Config, inv discovery.Inventory) state.State {
 	return st
 }
 
+// accountNameFor prefers a configured account alias over the SSO account name
+// when deriving env and slugs.
+func accountNameFor(alias, name string) string {
+	if strings.TrimSpace(alias) != "" {
+		return alias
+	}
+	return name
+}
+
+// accountSlugFor slugs the alias or account name, falling back to the ID.
+func accountSlugFor(alias, name, id string) string {
+	slug := Slug(accountNameFor(alias, name))
+	if slug == "unknown" {
+		slug = Slug(id)
+	}
+	return slug
+}
+
 func dedupeRoles(roles []state.RoleRecord) []state.RoleRecord {
 	seen := map[string]struct{}{}
 	out := make([]state.RoleRecord, 0, len(roles))
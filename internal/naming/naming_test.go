@@ -1,6 +1,11 @@
 package naming
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/phenixrizen/rift/internal/config"
+	"github.com/phenixrizen/rift/internal/discovery"
+)
 
 func TestSlug(t *testing.T) {
 	tests := []struct {
@@ -45,3 +50,231 @@ func TestInferEnv(t *testing.T) {
 		})
 	}
 }
+
+func TestBuildStateUsesAccountAliases(t *testing.T) {
+	cfg := config.Default()
+	cfg.AccountAliases = map[string]string{"123456789012": "payments-prod"}
+	inv := discovery.Inventory{
+		Roles: []discovery.RoleAccess{{AccountID: "123456789012", AccountName: "123456789012", RoleName: "Admin"}},
+		Clusters: []discovery.ClusterAccess{{
+			AccountID:   "123456789012",
+			AccountName: "123456789012",
+			RoleName:    "Admin",
+			Region:      "us-east-1",
+			ClusterName: "main",
+		}},
+	}
+
+	st := BuildState(cfg, inv)
+	if len(st.Clusters) != 1 || len(st.Roles) != 1 {
+		t.Fatalf("BuildState returned %d roles / %d clusters", len(st.Roles), len(st.Clusters))
+	}
+	if got := st.Roles[0].AWSProfile; got != "rift-prod-payments-prod-admin" {
+		t.Fatalf("AWSProfile=%q want rift-prod-payments-prod-admin", got)
+	}
+	if got := st.Clusters[0].KubeContext; got != "rift-prod-payments-prod-main" {
+		t.Fatalf("KubeContext=%q want rift-prod-payments-prod-main", got)
+	}
+	if got := st.Clusters[0].AccountLabel(); got != "payments-prod" {
+		t.Fatalf("AccountLabel=%q want payments-prod", got)
+	}
+}
+
+func TestMatchEnvReportsKeyword(t *testing.T) {
+	got := MatchEnv("acme", "Developer", "payments-stage")
+	if got.Env != "staging" || got.Keyword != "stage" || got.Part != "payments-stage" {
+		t.Fatalf("MatchEnv=%+v want staging via stage in payments-stage", got)
+	}
+}
+
+func TestClassifyEnvUsesConfiguredRules(t *testing.T) {
+	cfg := config.Default()
+	cfg.EnvRules = []config.EnvRule{
+		{Env: "prod", Match: `(^|-)prod(uction)?(-|$)`},
+		{Env: "int", Match: `^int-`, Field: config.EnvFieldAccount},
+	}
+
+	tests := []struct {
+		name                   string
+		account, role, cluster string
+		want                   string
+	}{
+		{name: "no built-in int substring", account: "print-service", role: "Admin", want: "other"},
+		{name: "account rule", account: "int-payments", role: "Admin", want: "int"},
+		{name: "field restricts rule", account: "payments", role: "Admin", cluster: "int-main", want: "other"},
+		{name: "any field", account: "payments", role: "Admin", cluster: "payments-PROD", want: "prod"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ClassifyEnv(cfg, tt.account, tt.role, tt.cluster)
+			if got.Env != tt.want {
+				t.Fatalf("ClassifyEnv(%q, %q, %q)=%+v want %q", tt.account, tt.role, tt.cluster, got, tt.want)
+			}
+		})
+	}
+
+	got := ClassifyEnv(cfg, "payments", "Admin", "payments-PROD")
+	if got.Rule != "env_rules[0]" || got.Keyword != "-PROD" || got.Part != "payments-PROD" {
+		t.Fatalf("ClassifyEnv match=%+v", got)
+	}
+}
+
+func TestBuildStateUsesNameTemplates(t *testing.T) {
+	cfg := config.Default()
+	cfg.ProfileTemplate = "{{.Account}}.{{.RoleName}}"
+	cfg.ContextTemplate = "{{.Env}}-{{.ClusterName}}-{{.Region}}"
+	inv := discovery.Inventory{
+		Roles: []discovery.RoleAccess{{AccountID: "111111111111", AccountName: "Acme Prod", RoleName: "Admin"}},
+		Clusters: []discovery.ClusterAccess{
+			{AccountID: "111111111111", AccountName: "Acme Prod", RoleName: "Admin", Region: "us-east-1", ClusterName: "payments"},
+			{AccountID: "111111111111", AccountName: "Acme Prod", RoleName: "Admin", Region: "us-east-1", ClusterName: "payments api"},
+		},
+	}
+
+	st := BuildState(cfg, inv)
+	if got := st.Roles[0].AWSProfile; got != "acme-prod.Admin" {
+		t.Fatalf("AWSProfile=%q want acme-prod.Admin", got)
+	}
+	got := []string{st.Clusters[0].KubeContext, st.Clusters[1].KubeContext}
+	if got[0] != "prod-payments-us-east-1" || got[1] != "prod-payments-api-us-east-1" {
+		t.Fatalf("contexts=%v", got)
+	}
+}
+
+func TestExplainClassifiesRoleWithoutCluster(t *testing.T) {
+	cfg := config.Default()
+	cfg.RegionOverrides = []config.RegionOverride{{Env: "prod", Regions: []string{"eu-west-1"}}}
+	inv := discovery.Inventory{
+		Roles: []discovery.RoleAccess{{AccountID: "111111111111", AccountName: "acme", RoleName: "Admin"}},
+		Clusters: []discovery.ClusterAccess{
+			{AccountID: "111111111111", AccountName: "acme", RoleName: "Admin", Region: "us-east-1", ClusterName: "prod-api"},
+		},
+	}
+	st := BuildState(cfg, inv)
+	ex := Explain(cfg, st.Clusters[0])
+	if ex.Env.Env != "prod" || ex.RoleEnv != "other" {
+		t.Fatalf("Env=%q RoleEnv=%q want prod and other", ex.Env.Env, ex.RoleEnv)
+	}
+	if ex.ProfileBase != st.Roles[0].AWSProfile {
+		t.Fatalf("ProfileBase=%q want %q as sync wrote", ex.ProfileBase, st.Roles[0].AWSProfile)
+	}
+	if ex.ContextBase != st.Clusters[0].KubeContext {
+		t.Fatalf("ContextBase=%q want %q", ex.ContextBase, st.Clusters[0].KubeContext)
+	}
+	if ex.RegionOverride != -1 || len(ex.Regions) != len(cfg.Regions) {
+		t.Fatalf("RegionOverride=%d Regions=%v want the role's regions", ex.RegionOverride, ex.Regions)
+	}
+}
+
+func TestBuildStateChainsAssumedRoles(t *testing.T) {
+	const chainedARN = "arn:aws:iam::222222222222:role/Admin"
+	const deployerARN = "arn:aws:iam::333333333333:role/Deployer"
+	cfg := config.Default()
+	inv := discovery.Inventory{
+		Roles: []discovery.RoleAccess{
+			// Chained roles come first here; BuildState names their sources first.
+			{AccountID: "222222222222", AccountName: "tools", RoleName: "Admin", AssumeRoleARN: chainedARN, ExternalID: "ext", SourceAccountID: "111111111111", SourceRoleName: "Admin", SSOSession: "rift"},
+			{AccountID: "333333333333", AccountName: "shared", RoleName: "Deployer", AssumeRoleARN: deployerARN, SourceAccountID: "222222222222", SourceRoleName: "Admin", SSOSession: "rift"},
+			{AccountID: "111111111111", AccountName: "acme", RoleName: "Admin", SSOSession: "rift"},
+			// The SSO permission set with the chained role's account and name.
+			{AccountID: "222222222222", AccountName: "tools", RoleName: "Admin", SSOSession: "rift"},
+		},
+		Clusters: []discovery.ClusterAccess{
+			{AccountID: "222222222222", AccountName: "tools", RoleName: "Admin", Region: "us-east-1", ClusterName: "sso", SSOSession: "rift"},
+			{AccountID: "222222222222", AccountName: "tools", RoleName: "Admin", Region: "us-east-1", ClusterName: "chained", SSOSession: "rift", AssumeRoleARN: chainedARN},
+		},
+	}
+
+	st := BuildState(cfg, inv)
+	if len(st.Roles) != 4 {
+		t.Fatalf("roles = %+v, want 4", st.Roles)
+	}
+	profiles := map[string]string{}
+	for _, r := range st.Roles {
+		key := r.AccountID + "|" + r.RoleName + "|" + r.AssumeRoleARN
+		if _, dup := profiles[key]; dup {
+			t.Fatalf("duplicate role %s", key)
+		}
+		profiles[key] = r.AWSProfile
+	}
+	ssoAcme := profiles["111111111111|Admin|"]
+	ssoTools := profiles["222222222222|Admin|"]
+	chained := profiles["222222222222|Admin|"+chainedARN]
+	if ssoTools == "" || chained == "" || ssoTools == chained {
+		t.Fatalf("SSO and chained 222222222222/Admin profiles = %q, %q; want two distinct", ssoTools, chained)
+	}
+
+	tests := []struct {
+		name          string
+		arn           string
+		sourceProfile string
+		externalID    string
+	}{
+		{name: "chained from acme", arn: chainedARN, sourceProfile: ssoAcme, externalID: "ext"},
+		// The source is the SSO role, not the chained role of the same name.
+		{name: "chained from tools", arn: deployerARN, sourceProfile: ssoTools},
+		{name: "sso", arn: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, r := range st.Roles {
+				if r.AssumeRoleARN != tt.arn || (tt.arn == "" && r.AccountID != "222222222222") {
+					continue
+				}
+				if r.SourceProfile != tt.sourceProfile || r.ExternalID != tt.externalID {
+					t.Fatalf("role %s: source_profile=%q external_id=%q, want %q, %q", r.AWSProfile, r.SourceProfile, r.ExternalID, tt.sourceProfile, tt.externalID)
+				}
+				return
+			}
+			t.Fatalf("no role with assume ARN %q", tt.arn)
+		})
+	}
+
+	for _, c := range st.Clusters {
+		want := ssoTools
+		if c.ClusterName == "chained" {
+			want = chained
+		}
+		if c.AWSProfile != want {
+			t.Fatalf("cluster %s profile=%q want %q", c.ClusterName, c.AWSProfile, want)
+		}
+	}
+}
+
+func TestBuildStateKeepsSessionsApart(t *testing.T) {
+	cfg := config.Default()
+	cfg.SSOSessions = []config.SSOSession{{Name: "corp2", StartURL: "https://corp2.awsapps.com/start", Region: "eu-west-1"}}
+	// Both organizations see account 123456789012 with an Admin permission
+	// set (an account shared between them, or the same ID by accident).
+	inv := discovery.Inventory{
+		Roles: []discovery.RoleAccess{
+			{AccountID: "123456789012", AccountName: "payments", RoleName: "Admin", SSOSession: "rift"},
+			{AccountID: "123456789012", AccountName: "payments", RoleName: "Admin", SSOSession: "rift-corp2"},
+		},
+		Clusters: []discovery.ClusterAccess{
+			{AccountID: "123456789012", AccountName: "payments", RoleName: "Admin", Region: "us-east-1", ClusterName: "main", SSOSession: "rift"},
+			{AccountID: "123456789012", AccountName: "payments", RoleName: "Admin", Region: "eu-west-1", ClusterName: "eu", SSOSession: "rift-corp2"},
+		},
+	}
+
+	st := BuildState(cfg, inv)
+	if len(st.Roles) != 2 || len(st.Clusters) != 2 {
+		t.Fatalf("BuildState returned %d roles / %d clusters, want 2 / 2", len(st.Roles), len(st.Clusters))
+	}
+	profiles := map[string]string{}
+	for _, r := range st.Roles {
+		profiles[r.SSOSession] = r.AWSProfile
+	}
+	if len(profiles) != 2 || profiles["rift"] == "" || profiles["rift-corp2"] == "" || profiles["rift"] == profiles["rift-corp2"] {
+		t.Fatalf("profiles by session = %v, want one distinct profile per session", profiles)
+	}
+	for _, c := range st.Clusters {
+		want := "rift"
+		if c.ClusterName == "eu" {
+			want = "rift-corp2"
+		}
+		if c.SSOSession != want || c.AWSProfile != profiles[want] {
+			t.Fatalf("cluster %s: session=%q profile=%q, want %q, %q", c.ClusterName, c.SSOSession, c.AWSProfile, want, profiles[want])
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package overlay
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/phenixrizen/rift/internal/fileutil"
+	"github.com/phenixrizen/rift/internal/state"
+	"gopkg.in/yaml.v3"
+)
+
+const fileName = "overlay.yaml"
+
+// Overlay holds user-maintained metadata layered over discovered state. It
+// is never rewritten by sync.
+type Overlay struct {
+	// Tags maps a kube context name (or glob such as "rift-prod-*") to tags.
+	Tags map[string][]string `yaml:"tags,omitempty"`
+	// Favorites are kube context names the user starred.
+	Favorites []string `yaml:"favorites,omitempty"`
+}
+
+// PathFor returns the overlay path that sits next to a state file.
+func PathFor(statePath string) string {
+	return filepath.Join(filepath.Dir(statePath), fileName)
+}
+
+func Load(path string) (Overlay, error) {
+	var o Overlay
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return o, nil
+		}
+		return o, err
+	}
+	if err := yaml.Unmarshal(data, &o); err != nil {
+		return o, fmt.Errorf("parse overlay: %w", err)
+	}
+	o.normalize()
+	return o, nil
+}
+
+func Save(path string, o Overlay) error {
+	o.normalize()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(o)
+	if err != nil {
+		return fmt.Errorf("marshal overlay: %w", err)
+	}
+	return fileutil.WriteLocked(path, data, 0o644)
+}
+
+func (o *Overlay) normalize() {
+	tags := make(map[string][]string, len(o.Tags))
+	for key, values := range o.Tags {
+		key = strings.TrimSpace(key)
+		values = normalizeTags(values)
+		if key == "" || len(values) == 0 {
+			continue
+		}
+		tags[key] = values
+	}
+	o.Tags = tags
+
+	favorites := make([]string, 0, len(o.Favorites))
+	for _, context := range o.Favorites {
+		if context = strings.TrimSpace(context); context != "" && !slices.Contains(favorites, context) {
+			favorites = append(favorites, context)
+		}
+	}
+	sort.Strings(favorites)
+	o.Favorites = favorites
+}
+
+// TagsFor returns the tags for a context from exact and glob keys.
+func (o Overlay) TagsFor(context string) []string {
+	out := make([]string, 0)
+	for key, values := range o.Tags {
+		if key == context {
+			out = append(out, values...)
+			continue
+		}
+		if ok, err := path.Match(key, context); err == nil && ok {
+			out = append(out, values...)
+		}
+	}
+	return normalizeTags(out)
+}
+
+func (o *Overlay) AddTags(key string, tags ...string) {
+	if o.Tags == nil {
+		o.Tags = map[string][]string{}
+	}
+	o.Tags[key] = normalizeTags(append(o.Tags[key], tags...))
+}
+
+func (o *Overlay) RemoveTags(key string, tags ...string) {
+	drop := map[string]struct{}{}
+	for _, tag := range normalizeTags(tags) {
+		drop[tag] = struct{}{}
+	}
+	kept := make([]string, 0, len(o.Tags[key]))
+	for _, tag := range o.Tags[key] {
+		if _, ok := drop[tag]; !ok {
+			kept = append(kept, tag)
+		}
+	}
+	if len(kept) == 0 {
+		delete(o.Tags, key)
+		return
+	}
+	o.Tags[key] = kept
+}
+
+// IsFavorite reports whether context is a favorite.
+func (o Overlay) IsFavorite(context string) bool {
+	return slices.Contains(o.Favorites, context)
+}
+
+// SetFavorite adds context to or removes it from the favorites and reports
+// whether that changed them.
+func (o *Overlay) SetFavorite(context string, favorite bool) bool {
+	if o.IsFavorite(context) == favorite {
+		return false
+	}
+	if favorite {
+		o.Favorites = append(o.Favorites, context)
+		sort.Strings(o.Favorites)
+		return true
+	}
+	o.Favorites = slices.DeleteFunc(o.Favorites, func(c string) bool { return c == context })
+	return true
+}
+
+// Apply sets Tags and Favorite on every cluster in st from the overlay.
+func (o Overlay) Apply(st *state.State) {
+	for i := range st.Clusters {
+		st.Clusters[i].Tags = o.TagsFor(st.Clusters[i].KubeContext)
+		st.Clusters[i].Favorite = o.IsFavorite(st.Clusters[i].KubeContext)
+	}
+}
+
+func normalizeTags(tags []string) []string {
+	seen := map[string]struct{}{}
+	out := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" {
+			continue
+		}
+		if _, ok := seen[tag]; ok {
+			continue
+		}
+		seen[tag] = struct{}{}
+		out = append(out, tag)
+	}
+	sort.Strings(out)
+	return out
+}
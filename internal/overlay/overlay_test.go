@@ -0,0 +1,80 @@
+package overlay
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/phenixrizen/rift/internal/state"
+)
+
+func TestTagsRoundTripAndGlob(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "overlay.yaml")
+
+	var ov Overlay
+	ov.AddTags("rift-prod-*", "On-Call")
+	ov.AddTags("rift-prod-payments-main", "payments", "team-a", "payments")
+	if err := Save(path, ov); err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	st := state.State{Clusters: []state.ClusterRecord{
+		{KubeContext: "rift-prod-payments-main"},
+		{KubeContext: "rift-dev-payments-main"},
+	}}
+	loaded.Apply(&st)
+	if got := strings.Join(st.Clusters[0].Tags, ","); got != "on-call,payments,team-a" {
+		t.Fatalf("Tags=%q want on-call,payments,team-a", got)
+	}
+	if len(st.Clusters[1].Tags) != 0 {
+		t.Fatalf("dev context got tags %v", st.Clusters[1].Tags)
+	}
+
+	loaded.RemoveTags("rift-prod-*", "on-call")
+	if _, ok := loaded.Tags["rift-prod-*"]; ok {
+		t.Fatalf("empty tag key was not removed")
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	ov, err := Load(filepath.Join(t.TempDir(), "missing.yaml"))
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if len(ov.Tags) != 0 {
+		t.Fatalf("Tags=%v want empty", ov.Tags)
+	}
+}
+
+func TestFavorites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "overlay.yaml")
+
+	var ov Overlay
+	if !ov.SetFavorite("rift-prod-b", true) || !ov.SetFavorite("rift-prod-a", true) || ov.SetFavorite("rift-prod-a", true) {
+		t.Fatal("SetFavorite should report only changes")
+	}
+	if err := Save(path, ov); err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if got := strings.Join(loaded.Favorites, ","); got != "rift-prod-a,rift-prod-b" {
+		t.Fatalf("Favorites=%q", got)
+	}
+
+	st := state.State{Clusters: []state.ClusterRecord{{KubeContext: "rift-prod-a"}, {KubeContext: "rift-dev-a"}}}
+	loaded.Apply(&st)
+	if !st.Clusters[0].Favorite || st.Clusters[1].Favorite {
+		t.Fatalf("Favorite not applied: %+v", st.Clusters)
+	}
+
+	if !loaded.SetFavorite("rift-prod-a", false) || loaded.IsFavorite("rift-prod-a") {
+		t.Fatal("favorite not removed")
+	}
+}
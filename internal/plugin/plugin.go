@@ -0,0 +1,247 @@
+// Package plugin finds and runs rift plugins: executables named rift-<name>
+// on PATH. A plugin can add a command (rift <name> runs it), an action in
+// the rift ui menu, and a discovery backend (rift-<name> discover), and it
+// issues the tokens of the clusters it discovers (rift-<name> token).
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/phenixrizen/rift/internal/discovery"
+	"github.com/phenixrizen/rift/internal/state"
+)
+
+// Prefix starts the file name of every plugin.
+const Prefix = "rift-"
+
+// OpDiscover is the discovery call recorded in Failure.Op.
+const OpDiscover = "plugin_discover"
+
+// DiscoverTimeout bounds one rift-<name> discover run.
+const DiscoverTimeout = 5 * time.Minute
+
+// Plugin is a rift-<Name> executable found at Path.
+type Plugin struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+// Find looks up rift-<name> on PATH.
+func Find(name string) (Plugin, error) {
+	path, err := exec.LookPath(Prefix + name)
+	if err != nil {
+		return Plugin{}, fmt.Errorf("plugin %s: %w", name, err)
+	}
+	return Plugin{Name: name, Path: path}, nil
+}
+
+// List returns the plugins in the directories of path (a PATH value),
+// sorted by name. As with exec.LookPath, the first directory holding a
+// plugin's name wins.
+func List(path string) []Plugin {
+	seen := map[string]struct{}{}
+	var plugins []Plugin
+	for _, dir := range filepath.SplitList(path) {
+		if dir == "" {
+			dir = "."
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			name, ok := pluginName(entry.Name())
+			if !ok || entry.IsDir() {
+				continue
+			}
+			if _, dup := seen[name]; dup {
+				continue
+			}
+			full := filepath.Join(dir, entry.Name())
+			if !executable(full) {
+				continue
+			}
+			seen[name] = struct{}{}
+			plugins = append(plugins, Plugin{Name: name, Path: full})
+		}
+	}
+	sort.Slice(plugins, func(i, j int) bool { return plugins[i].Name < plugins[j].Name })
+	return plugins
+}
+
+// pluginName returns the plugin name of a file name, dropping the prefix
+// and, on Windows, the executable extension.
+func pluginName(file string) (string, bool) {
+	if runtime.GOOS == "windows" {
+		ext := strings.ToLower(filepath.Ext(file))
+		if ext != ".exe" && ext != ".bat" && ext != ".cmd" {
+			return "", false
+		}
+		file = strings.TrimSuffix(file, filepath.Ext(file))
+	}
+	name, ok := strings.CutPrefix(file, Prefix)
+	return name, ok && name != ""
+}
+
+func executable(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		return false
+	}
+	return runtime.GOOS == "windows" || info.Mode()&0o111 != 0
+}
+
+// Cluster is one cluster in rift-<name> discover's output.
+type Cluster struct {
+	Name string `json:"name"`
+	// Account groups clusters like an AWS account (a datacenter, a vSphere
+	// cluster, ...); empty means the plugin name.
+	Account                  string            `json:"account,omitempty"`
+	Region                   string            `json:"region,omitempty"`
+	Endpoint                 string            `json:"endpoint"`
+	CertificateAuthorityData string            `json:"certificate_authority_data,omitempty"`
+	ID                       string            `json:"id,omitempty"`
+	KubernetesVersion        string            `json:"kubernetes_version,omitempty"`
+	Status                   string            `json:"status,omitempty"`
+	Labels                   map[string]string `json:"labels,omitempty"`
+}
+
+// Failure is a problem rift-<name> discover worked around, reported by
+// rift sync like other discovery failures.
+type Failure struct {
+	Account string `json:"account,omitempty"`
+	Cluster string `json:"cluster,omitempty"`
+	Error   string `json:"error"`
+}
+
+// DiscoverOutput is what rift-<name> discover prints on stdout.
+type DiscoverOutput struct {
+	Clusters []Cluster `json:"clusters"`
+	Failures []Failure `json:"failures,omitempty"`
+}
+
+// Discover runs rift-<name> discover for each name, with env added to the
+// environment, and returns the clusters they print. A plugin that is
+// missing, fails, or prints something else is returned as a failure, as
+// are clusters without a name or endpoint.
+func Discover(ctx context.Context, names []string, env []string, logger *slog.Logger) ([]discovery.ClusterAccess, []discovery.Failure) {
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+	var (
+		clusters []discovery.ClusterAccess
+		failures []discovery.Failure
+	)
+	for _, name := range names {
+		out, err := runDiscover(ctx, name, env)
+		if err != nil {
+			logger.Warn("plugin discovery failed", "plugin", name, "error", err)
+			failures = append(failures, failure(name, "", err.Error()))
+			continue
+		}
+		logger.Debug("plugin discovered clusters", "plugin", name, "clusters", len(out.Clusters))
+		found, failed := clusterAccess(name, out)
+		clusters = append(clusters, found...)
+		failures = append(failures, failed...)
+	}
+	sort.Slice(clusters, func(i, j int) bool {
+		left := clusters[i].Plugin + "|" + clusters[i].AccountName + "|" + clusters[i].Region + "|" + clusters[i].ClusterName
+		right := clusters[j].Plugin + "|" + clusters[j].AccountName + "|" + clusters[j].Region + "|" + clusters[j].ClusterName
+		return left < right
+	})
+	return clusters, failures
+}
+
+func runDiscover(ctx context.Context, name string, env []string) (DiscoverOutput, error) {
+	p, err := Find(name)
+	if err != nil {
+		return DiscoverOutput{}, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, DiscoverTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, p.Path, "discover")
+	cmd.Env = append(os.Environ(), env...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = time.Second
+	if err := cmd.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return DiscoverOutput{}, fmt.Errorf("%s discover timed out after %s", Prefix+name, DiscoverTimeout)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return DiscoverOutput{}, fmt.Errorf("%s discover: %w: %s", Prefix+name, err, msg)
+		}
+		return DiscoverOutput{}, fmt.Errorf("%s discover: %w", Prefix+name, err)
+	}
+	var out DiscoverOutput
+	if err := json.Unmarshal(stdout.Bytes(), &out); err != nil {
+		return DiscoverOutput{}, fmt.Errorf("%s discover printed invalid JSON: %w", Prefix+name, err)
+	}
+	return out, nil
+}
+
+// clusterAccess maps a plugin's clusters to the shape EKS discovery
+// produces: the account stands in for the AWS account (ID and name) and
+// labels for AWS tags.
+func clusterAccess(name string, out DiscoverOutput) ([]discovery.ClusterAccess, []discovery.Failure) {
+	var (
+		clusters []discovery.ClusterAccess
+		failures []discovery.Failure
+	)
+	for _, c := range out.Clusters {
+		account := strings.TrimSpace(c.Account)
+		if account == "" {
+			account = name
+		}
+		if strings.TrimSpace(c.Name) == "" || strings.TrimSpace(c.Endpoint) == "" {
+			failures = append(failures, failure(account, c.Name, Prefix+name+" discover: cluster needs a name and an endpoint"))
+			continue
+		}
+		clusters = append(clusters, discovery.ClusterAccess{
+			AccountID:                account,
+			AccountName:              account,
+			Region:                   c.Region,
+			ClusterName:              c.Name,
+			ClusterEndpoint:          c.Endpoint,
+			ClusterCertificateBase64: c.CertificateAuthorityData,
+			ClusterID:                c.ID,
+			Platform:                 state.PlatformPlugin,
+			Plugin:                   name,
+			KubernetesVersion:        c.KubernetesVersion,
+			Status:                   c.Status,
+			AWSTags:                  c.Labels,
+		})
+	}
+	for _, f := range out.Failures {
+		account := f.Account
+		if account == "" {
+			account = name
+		}
+		failures = append(failures, failure(account, f.Cluster, Prefix+name+": "+f.Error))
+	}
+	return clusters, failures
+}
+
+func failure(account, cluster, err string) discovery.Failure {
+	return discovery.Failure{
+		Op:          OpDiscover,
+		AccountID:   account,
+		AccountName: account,
+		Cluster:     cluster,
+		Err:         err,
+	}
+}
@@ -0,0 +1,85 @@
+package plugin
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/phenixrizen/rift/internal/state"
+)
+
+func writePlugin(t *testing.T, dir, name, script string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, Prefix+name), []byte("#!/bin/sh\n"+script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestListFirstOnPathWins(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell plugins")
+	}
+	first, second := t.TempDir(), t.TempDir()
+	writePlugin(t, first, "onprem", "")
+	writePlugin(t, second, "onprem", "")
+	writePlugin(t, second, "audit", "")
+	if err := os.WriteFile(filepath.Join(second, Prefix+"notes"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got := List(first + string(os.PathListSeparator) + second)
+	if len(got) != 2 || got[0].Name != "audit" || got[1].Name != "onprem" {
+		t.Fatalf("List() = %+v, want audit and onprem", got)
+	}
+	if got[1].Path != filepath.Join(first, Prefix+"onprem") {
+		t.Fatalf("onprem path = %q, want the first PATH entry", got[1].Path)
+	}
+}
+
+func TestDiscoverMapsClusters(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell plugins")
+	}
+	dir := t.TempDir()
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	writePlugin(t, dir, "onprem", `[ "$1" = discover ] || exit 2
+cat <<EOF
+{"clusters": [
+  {"name": "rack1", "account": "dc-east", "region": "east", "endpoint": "https://10.0.0.1:6443", "certificate_authority_data": "Q0E=", "status": "$RIFT_TEST", "labels": {"team": "infra"}},
+  {"name": "rack2"}
+], "failures": [{"account": "dc-west", "error": "unreachable"}]}
+EOF
+`)
+	writePlugin(t, dir, "broken", "echo 'no vcenter login' >&2; exit 1\n")
+
+	clusters, failures := Discover(context.Background(), []string{"onprem", "broken", "missing"}, []string{"RIFT_TEST=ACTIVE"}, nil)
+	if len(clusters) != 1 {
+		t.Fatalf("Discover() clusters = %+v, want 1", clusters)
+	}
+	c := clusters[0]
+	if c.Platform != state.PlatformPlugin || c.Plugin != "onprem" || c.AccountName != "dc-east" || c.ClusterName != "rack1" {
+		t.Fatalf("cluster = %+v", c)
+	}
+	if c.ClusterEndpoint != "https://10.0.0.1:6443" || c.ClusterCertificateBase64 != "Q0E=" || c.Status != "ACTIVE" || c.AWSTags["team"] != "infra" {
+		t.Fatalf("cluster details = %+v", c)
+	}
+	var got []string
+	for _, f := range failures {
+		if f.Op != OpDiscover {
+			t.Fatalf("failure op = %q, want %q", f.Op, OpDiscover)
+		}
+		got = append(got, f.String())
+	}
+	joined := strings.Join(got, "\n")
+	for _, want := range []string{"rack2", "dc-west", "no vcenter login", "missing"} {
+		if !strings.Contains(joined, want) {
+			t.Fatalf("failures = %q, want one mentioning %q", joined, want)
+		}
+	}
+	if len(failures) != 4 {
+		t.Fatalf("failures = %q, want 4", joined)
+	}
+}
@@ -0,0 +1,177 @@
+// Package query parses the search language shared by the rift ui search box
+// and rift search: key:value filters plus free text, e.g.
+// "env:prod region:us-east-1 ns:kafka payments".
+package query
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/phenixrizen/rift/internal/state"
+)
+
+// Filter keys.
+const (
+	KeyEnv      = "env"
+	KeyAccount  = "account"
+	KeyRole     = "role"
+	KeyRegion   = "region"
+	KeyCluster  = "cluster"
+	KeyContext  = "context"
+	KeyNS       = "ns"
+	KeyTag      = "tag"
+	KeySession  = "session"
+	KeyPlatform = "platform"
+)
+
+// Keys lists the filter keys in the order they are documented.
+var Keys = []string{KeyEnv, KeyAccount, KeyRole, KeyRegion, KeyCluster, KeyContext, KeyNS, KeyTag, KeySession, KeyPlatform}
+
+var aliases = map[string]string{
+	"namespace": KeyNS,
+	"acct":      KeyAccount,
+	"ctx":       KeyContext,
+}
+
+// Filter is one key:value token. A comma-separated value matches any of
+// Values.
+type Filter struct {
+	Key    string
+	Values []string
+}
+
+// Query is a parsed search: every filter must match, and Text is the
+// remaining free text, lowercased, for the caller to match.
+type Query struct {
+	Filters []Filter
+	Text    string
+}
+
+// Parse splits input into filters and free text. A token whose key is not
+// a filter key stays in Text, and Parse reports the first such key so the
+// caller can warn or fail; the Query is usable either way. A key with no
+// value yet ("env:") is dropped.
+func Parse(input string) (Query, error) {
+	var q Query
+	var err error
+	text := make([]string, 0)
+	for _, token := range strings.Fields(input) {
+		key, value, ok := strings.Cut(token, ":")
+		key = strings.ToLower(key)
+		if alias, found := aliases[key]; found {
+			key = alias
+		}
+		if !ok || !isKey(key) {
+			if ok && err == nil && key != "" && isWord(key) && !strings.HasPrefix(value, "/") {
+				err = fmt.Errorf("unknown search key %q (expected %s)", key, strings.Join(Keys, ", "))
+			}
+			text = append(text, strings.ToLower(token))
+			continue
+		}
+		values := make([]string, 0)
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.ToLower(strings.TrimSpace(v)); v != "" {
+				values = append(values, v)
+			}
+		}
+		if len(values) > 0 {
+			q.Filters = append(q.Filters, Filter{Key: key, Values: values})
+		}
+	}
+	q.Text = strings.Join(text, " ")
+	return q, err
+}
+
+func isKey(key string) bool {
+	for _, k := range Keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// isWord reports whether s looks like a key (letters and dashes), so text
+// such as "10:30" is not reported as an unknown key.
+func isWord(s string) bool {
+	for _, r := range s {
+		if (r < 'a' || r > 'z') && r != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// Match reports whether c passes every filter of q; Text is not checked.
+func (q Query) Match(c state.ClusterRecord) bool {
+	for _, f := range q.Filters {
+		if !f.match(c) {
+			return false
+		}
+	}
+	return true
+}
+
+// Tags returns the values of tag filters.
+func (q Query) Tags() []string {
+	var tags []string
+	for _, f := range q.Filters {
+		if f.Key == KeyTag {
+			tags = append(tags, f.Values...)
+		}
+	}
+	return tags
+}
+
+func (f Filter) match(c state.ClusterRecord) bool {
+	for _, want := range f.Values {
+		if f.matchValue(c, want) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchValue compares env, tag, and platform exactly ("stg" is staging,
+// "eks" an empty platform) and the other keys as substrings.
+func (f Filter) matchValue(c state.ClusterRecord, want string) bool {
+	switch f.Key {
+	case KeyEnv:
+		if want == "stg" {
+			want = "staging"
+		}
+		return strings.EqualFold(c.Env, want)
+	case KeyTag:
+		return c.HasTags([]string{want})
+	case KeyPlatform:
+		platform := c.Platform
+		if platform == "" {
+			platform = "eks"
+		}
+		return strings.EqualFold(platform, want)
+	case KeyAccount:
+		return contains(want, c.AccountName, c.AccountAlias, c.AccountID)
+	case KeyRole:
+		return contains(want, c.RoleName)
+	case KeyRegion:
+		return contains(want, c.Region)
+	case KeyCluster:
+		return contains(want, c.ClusterName)
+	case KeyContext:
+		return contains(want, c.KubeContext)
+	case KeySession:
+		return contains(want, c.SSOSession)
+	case KeyNS:
+		return contains(want, append([]string{c.Namespace}, c.Namespaces...)...)
+	}
+	return false
+}
+
+func contains(want string, fields ...string) bool {
+	for _, field := range fields {
+		if strings.Contains(strings.ToLower(field), want) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/phenixrizen/rift/internal/state"
+)
+
+func TestParseAndMatch(t *testing.T) {
+	q, err := Parse("env:prod,stg Region:us-east ns:kafka tag:team-a Payments env:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Text != "payments" || len(q.Filters) != 4 {
+		t.Fatalf("Parse = %+v", q)
+	}
+
+	rec := state.ClusterRecord{
+		Env: "staging", Region: "us-east-1", Namespaces: []string{"default", "kafka-system"}, Tags: []string{"team-a"},
+	}
+	if !q.Match(rec) {
+		t.Fatal("expected match")
+	}
+	rec.Region = "eu-west-1"
+	if q.Match(rec) {
+		t.Fatal("region filter ignored")
+	}
+
+	q, _ = Parse("platform:eks account:1234")
+	if !q.Match(state.ClusterRecord{AccountID: "123456789012"}) || q.Match(state.ClusterRecord{Platform: state.PlatformGKE, AccountID: "1234"}) {
+		t.Fatal("platform/account filters")
+	}
+}
+
+func TestParseUnknownKey(t *testing.T) {
+	q, err := Parse("owner:bob https://x 10:30")
+	if err == nil {
+		t.Fatal("expected an unknown key error")
+	}
+	if len(q.Filters) != 0 || q.Text != "owner:bob https://x 10:30" {
+		t.Fatalf("unknown keys should stay free text: %+v", q)
+	}
+	if _, err := Parse("https://x 10:30"); err != nil {
+		t.Fatalf("non-key colons reported: %v", err)
+	}
+}
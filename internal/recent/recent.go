@@ -0,0 +1,97 @@
+// Package recent keeps the kube contexts most recently switched to with
+// rift use or rift ui, newest first.
+package recent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/phenixrizen/rift/internal/fileutil"
+)
+
+const fileName = "recent.json"
+
+// MaxEntries is how many contexts the list remembers.
+const MaxEntries = 20
+
+type Entry struct {
+	Context string    `json:"context"`
+	UsedAt  time.Time `json:"used_at"`
+}
+
+// PathFor returns the recent list path that sits next to a state file.
+func PathFor(statePath string) string {
+	return filepath.Join(filepath.Dir(statePath), fileName)
+}
+
+// Load reads the list, newest first; a missing file is an empty list.
+func Load(path string) ([]Entry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var entries []Entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("parse recent contexts: %w", err)
+	}
+	return entries, nil
+}
+
+// Record moves context to the front of the list at path.
+func Record(path, context string, at time.Time) error {
+	unlock, err := fileutil.Lock(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	entries, err := Load(path)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(Touch(entries, context, at), "", "  ")
+	if err != nil {
+		return err
+	}
+	return fileutil.WriteAtomic(path, append(data, '\n'), 0o644)
+}
+
+// Touch returns entries with context moved (or added) to the front, keeping
+// at most MaxEntries.
+func Touch(entries []Entry, context string, at time.Time) []Entry {
+	out := make([]Entry, 0, len(entries)+1)
+	out = append(out, Entry{Context: context, UsedAt: at.UTC()})
+	for _, e := range entries {
+		if e.Context != context && len(out) < MaxEntries {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// Previous returns the context to go back to from current, as cd - does:
+// the newest of contexts (newest first) that is not current, or "" when
+// there is none.
+func Previous(contexts []string, current string) string {
+	for _, context := range contexts {
+		if context != current {
+			return context
+		}
+	}
+	return ""
+}
+
+// Contexts returns the context names of entries, newest first.
+func Contexts(entries []Entry) []string {
+	out := make([]string, 0, len(entries))
+	for _, e := range entries {
+		out = append(out, e.Context)
+	}
+	return out
+}
@@ -0,0 +1,61 @@
+package recent
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecordMovesToFront(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "recent.json")
+	if entries, err := Load(path); err != nil || len(entries) != 0 {
+		t.Fatalf("Load(missing) = %v, %v", entries, err)
+	}
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	for i, context := range []string{"a", "b", "c", "a"} {
+		if err := Record(path, context, now.Add(time.Duration(i)*time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(Contexts(entries), ","); got != "a,c,b" {
+		t.Fatalf("Contexts = %q, want a,c,b", got)
+	}
+	if !entries[0].UsedAt.Equal(now.Add(3 * time.Minute)) {
+		t.Fatalf("UsedAt = %v", entries[0].UsedAt)
+	}
+}
+
+func TestTouchCapsEntries(t *testing.T) {
+	var entries []Entry
+	for i := 0; i < MaxEntries+5; i++ {
+		entries = Touch(entries, string(rune('a'+i)), time.Now())
+	}
+	if len(entries) != MaxEntries {
+		t.Fatalf("len = %d, want %d", len(entries), MaxEntries)
+	}
+	if entries[0].Context != string(rune('a'+MaxEntries+4)) {
+		t.Fatalf("newest = %q", entries[0].Context)
+	}
+}
+
+func TestPrevious(t *testing.T) {
+	contexts := []string{"b", "a", "c"}
+	for _, tc := range []struct{ current, want string }{
+		{"b", "a"},
+		{"a", "b"},
+		{"other", "b"},
+		{"", "b"},
+	} {
+		if got := Previous(contexts, tc.current); got != tc.want {
+			t.Errorf("Previous(%q) = %q, want %q", tc.current, got, tc.want)
+		}
+	}
+	if got := Previous([]string{"b"}, "b"); got != "" {
+		t.Errorf("Previous(only current) = %q, want empty", got)
+	}
+}
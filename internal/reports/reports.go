@@ -0,0 +1,235 @@
+package reports
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/phenixrizen/rift/internal/fileutil"
+)
+
+const (
+	dirName       = "reports"
+	idLayout      = "20060102T150405.000Z"
+	DefaultRetain = 50
+)
+
+type Counts struct {
+	Added   int `json:"added"`
+	Updated int `json:"updated"`
+	Removed int `json:"removed"`
+}
+
+type KubeTarget struct {
+	Path   string `json:"path"`
+	Counts Counts `json:"counts"`
+}
+
+type NamespaceCounts struct {
+	Tried   int `json:"tried"`
+	Updated int `json:"updated"`
+	Errors  int `json:"errors"`
+}
+
+// Report is the compact, persisted form of a sync: counts plus the names of
+// every profile and context that existed afterwards, which is enough to diff
+// consecutive syncs.
+type Report struct {
+	ID         string    `json:"id"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Roles      int       `json:"roles"`
+	Clusters   int       `json:"clusters"`
+	AWS        Counts    `json:"aws"`
+	Kube       Counts    `json:"kube"`
+	// KubeTargets breaks Kube down per kubeconfig file written.
+	KubeTargets []KubeTarget    `json:"kube_targets,omitempty"`
+	Namespaces  NamespaceCounts `json:"namespaces"`
+	Profiles    []string        `json:"profiles"`
+	Contexts    []string        `json:"contexts"`
+	// Failures describes discovery calls that failed after retries; their
+	// roles or clusters are missing from Profiles/Contexts.
+	Failures []string `json:"failures,omitempty"`
+	// Backup is the ID of the backup taken before this sync wrote, for
+	// `rift restore`.
+	Backup string `json:"backup,omitempty"`
+	// Redacted reports were saved without Profiles and Contexts, as with
+	// state_encryption, so they cannot be diffed.
+	Redacted bool `json:"redacted,omitempty"`
+}
+
+// WithoutNames returns r without the profile and context names, for
+// saving next to encrypted state.
+func (r Report) WithoutNames() Report {
+	r.Profiles, r.Contexts, r.Redacted = nil, nil, true
+	return r
+}
+
+type Diff struct {
+	AddedContexts   []string
+	RemovedContexts []string
+	AddedProfiles   []string
+	RemovedProfiles []string
+}
+
+// DirFor returns the reports directory that sits next to a state file.
+func DirFor(statePath string) string {
+	return filepath.Join(filepath.Dir(statePath), dirName)
+}
+
+// Save writes r (assigning an ID from FinishedAt when empty) and prunes the
+// oldest reports beyond retain.
+func Save(dir string, r Report, retain int) (Report, error) {
+	if r.ID == "" {
+		r.ID = r.FinishedAt.UTC().Format(idLayout)
+	}
+	sort.Strings(r.Profiles)
+	sort.Strings(r.Contexts)
+	// Reports name every profile and context, like state.json.
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return r, err
+	}
+	if err := os.Chmod(dir, 0o700); err != nil {
+		return r, err
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return r, err
+	}
+	if err := fileutil.WritePrivate(filepath.Join(dir, r.ID+".json"), append(data, '\n'), 0o600); err != nil {
+		return r, err
+	}
+	if retain > 0 {
+		if err := prune(dir, retain); err != nil {
+			return r, err
+		}
+	}
+	return r, nil
+}
+
+// List returns report IDs oldest first.
+func List(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	ids := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		ids = append(ids, strings.TrimSuffix(entry.Name(), ".json"))
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+func Load(dir, id string) (Report, error) {
+	var r Report
+	data, err := os.ReadFile(filepath.Join(dir, id+".json"))
+	if err != nil {
+		return r, err
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return r, fmt.Errorf("parse report %s: %w", id, err)
+	}
+	return r, nil
+}
+
+// Resolve maps "latest", an exact ID, or a unique ID prefix to an ID, and
+// also returns the ID of the report before it (empty for the first).
+func Resolve(dir, ref string) (string, string, error) {
+	ids, err := List(dir)
+	if err != nil {
+		return "", "", err
+	}
+	if len(ids) == 0 {
+		return "", "", fmt.Errorf("no sync reports recorded yet")
+	}
+	idx := -1
+	switch {
+	case ref == "" || ref == "latest":
+		idx = len(ids) - 1
+	default:
+		for i, id := range ids {
+			if id == ref {
+				idx = i
+				break
+			}
+			if strings.HasPrefix(id, ref) {
+				if idx >= 0 {
+					return "", "", fmt.Errorf("report %q is ambiguous", ref)
+				}
+				idx = i
+			}
+		}
+	}
+	if idx < 0 {
+		return "", "", fmt.Errorf("report %q not found", ref)
+	}
+	prev := ""
+	if idx > 0 {
+		prev = ids[idx-1]
+	}
+	return ids[idx], prev, nil
+}
+
+// Compare returns what changed between prev and next.
+func Compare(prev, next Report) Diff {
+	added, removed := diffSets(prev.Contexts, next.Contexts)
+	addedProfiles, removedProfiles := diffSets(prev.Profiles, next.Profiles)
+	return Diff{
+		AddedContexts:   added,
+		RemovedContexts: removed,
+		AddedProfiles:   addedProfiles,
+		RemovedProfiles: removedProfiles,
+	}
+}
+
+func diffSets(before, after []string) ([]string, []string) {
+	beforeSet := map[string]struct{}{}
+	for _, v := range before {
+		beforeSet[v] = struct{}{}
+	}
+	afterSet := map[string]struct{}{}
+	for _, v := range after {
+		afterSet[v] = struct{}{}
+	}
+	added := make([]string, 0)
+	for _, v := range after {
+		if _, ok := beforeSet[v]; !ok {
+			added = append(added, v)
+		}
+	}
+	removed := make([]string, 0)
+	for _, v := range before {
+		if _, ok := afterSet[v]; !ok {
+			removed = append(removed, v)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
+}
+
+func prune(dir string, retain int) error {
+	ids, err := List(dir)
+	if err != nil {
+		return err
+	}
+	for len(ids) > retain {
+		if err := os.Remove(filepath.Join(dir, ids[0]+".json")); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		ids = ids[1:]
+	}
+	return nil
+}
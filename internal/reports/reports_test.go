@@ -0,0 +1,81 @@
+package reports
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSaveResolveCompare(t *testing.T) {
+	dir := t.TempDir()
+	base := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	first, err := Save(dir, Report{FinishedAt: base, Contexts: []string{"rift-a", "rift-b"}}, 2)
+	if err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+	second, err := Save(dir, Report{FinishedAt: base.Add(time.Hour), Contexts: []string{"rift-c", "rift-a"}}, 2)
+	if err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+
+	id, prevID, err := Resolve(dir, "latest")
+	if err != nil {
+		t.Fatalf("Resolve returned error: %v", err)
+	}
+	if id != second.ID || prevID != first.ID {
+		t.Fatalf("Resolve(latest)=(%q, %q) want (%q, %q)", id, prevID, second.ID, first.ID)
+	}
+
+	prev, _ := Load(dir, prevID)
+	next, _ := Load(dir, id)
+	diff := Compare(prev, next)
+	if strings.Join(diff.AddedContexts, ",") != "rift-c" || strings.Join(diff.RemovedContexts, ",") != "rift-b" {
+		t.Fatalf("Compare=%+v", diff)
+	}
+
+	if _, err := Save(dir, Report{FinishedAt: base.Add(2 * time.Hour)}, 2); err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+	ids, _ := List(dir)
+	if len(ids) != 2 || ids[0] != second.ID {
+		t.Fatalf("List after prune=%v want oldest %q dropped", ids, first.ID)
+	}
+}
+
+func TestSaveIsPrivate(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("POSIX permissions")
+	}
+	dir := filepath.Join(t.TempDir(), "reports")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	full := Report{FinishedAt: time.Now(), Profiles: []string{"rift-prod-admin"}, Contexts: []string{"rift-prod-main"}}
+	saved, err := Save(dir, full.WithoutNames(), 0)
+	if err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+	for path, want := range map[string]os.FileMode{dir: 0o700, filepath.Join(dir, saved.ID+".json"): 0o600} {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := info.Mode().Perm(); got != want {
+			t.Fatalf("%s mode = %o, want %o", path, got, want)
+		}
+	}
+	loaded, err := Load(dir, saved.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.Redacted || len(loaded.Profiles)+len(loaded.Contexts) != 0 {
+		t.Fatalf("loaded = %+v, want a redacted report", loaded)
+	}
+	if len(full.Profiles) != 1 {
+		t.Fatalf("WithoutNames changed the original report")
+	}
+}
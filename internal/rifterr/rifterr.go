@@ -0,0 +1,83 @@
+// Package rifterr defines the machine-readable error taxonomy shared by rift
+// commands. Errors carry a stable code, a message, and a hint describing the
+// next step, and render as {code, message, hint} under --output json.
+package rifterr
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/aws/smithy-go"
+)
+
+type Code string
+
+const (
+	CodeAuthRequired   Code = "auth_required"
+	CodeThrottled      Code = "throttled"
+	CodeAccessDenied   Code = "access_denied"
+	CodePartialFailure Code = "partial_failure"
+	CodeConfigInvalid  Code = "config_invalid"
+	CodeStateMissing   Code = "state_missing"
+	CodeStateInvalid   Code = "state_invalid"
+	CodeInternal       Code = "internal"
+)
+
+type Error struct {
+	Code    Code   `json:"code"`
+	Message string `json:"message"`
+	Hint    string `json:"hint,omitempty"`
+	Err     error  `json:"-"`
+}
+
+func New(code Code, message, hint string) *Error {
+	return &Error{Code: code, Message: message, Hint: hint}
+}
+
+// Wrap attaches a code and hint to err, keeping err for errors.Is/As.
+func Wrap(code Code, err error, hint string) *Error {
+	return &Error{Code: code, Message: err.Error(), Hint: hint, Err: err}
+}
+
+func (e *Error) Error() string {
+	if e.Hint == "" {
+		return e.Message
+	}
+	return e.Message + "; " + e.Hint
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Classify returns the *Error in err's chain, or derives one from well-known
+// AWS API error codes. Anything unrecognized is CodeInternal.
+func Classify(err error) *Error {
+	if err == nil {
+		return nil
+	}
+	var typed *Error
+	if errors.As(err, &typed) {
+		if error(typed) == err {
+			return typed
+		}
+		// Keep outer context such as "sync kubeconfig: ..." in the message.
+		message := err.Error()
+		if typed.Hint != "" {
+			message = strings.TrimSuffix(message, "; "+typed.Hint)
+		}
+		return &Error{Code: typed.Code, Message: message, Hint: typed.Hint, Err: err}
+	}
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.ErrorCode() {
+		case "ThrottlingException", "Throttling", "TooManyRequestsException", "RequestLimitExceeded":
+			return Wrap(CodeThrottled, err, "retry later")
+		case "UnauthorizedException", "ExpiredToken", "ExpiredTokenException":
+			return Wrap(CodeAuthRequired, err, "run: rift auth")
+		case "AccessDeniedException", "AccessDenied", "ForbiddenException":
+			return Wrap(CodeAccessDenied, err, "check the role's permissions")
+		}
+	}
+	return Wrap(CodeInternal, err, "")
+}
@@ -0,0 +1,42 @@
+package rifterr
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aws/smithy-go"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want Code
+	}{
+		{name: "typed", err: New(CodeStateMissing, "state file not found", "run: rift sync"), want: CodeStateMissing},
+		{name: "wrapped typed", err: fmt.Errorf("sync: %w", New(CodeAuthRequired, "sso", "run: rift auth")), want: CodeAuthRequired},
+		{name: "throttled", err: fmt.Errorf("list clusters: %w", &smithy.GenericAPIError{Code: "ThrottlingException"}), want: CodeThrottled},
+		{name: "access denied", err: &smithy.GenericAPIError{Code: "AccessDeniedException"}, want: CodeAccessDenied},
+		{name: "sso unauthorized", err: &smithy.GenericAPIError{Code: "UnauthorizedException"}, want: CodeAuthRequired},
+		{name: "plain", err: errors.New("boom"), want: CodeInternal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Classify(tt.err).Code; got != tt.want {
+				t.Fatalf("Classify(%v).Code=%q want %q", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClassifyKeepsOuterContext(t *testing.T) {
+	err := fmt.Errorf("sync kubeconfig: %w", New(CodeConfigInvalid, "bad path", "fix config.yaml"))
+	got := Classify(err)
+	if got.Message != "sync kubeconfig: bad path" {
+		t.Fatalf("Message=%q want %q", got.Message, "sync kubeconfig: bad path")
+	}
+	if got.Hint != "fix config.yaml" {
+		t.Fatalf("Hint=%q want fix config.yaml", got.Hint)
+	}
+}
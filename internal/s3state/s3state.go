@@ -0,0 +1,174 @@
+// Package s3state stores state.json in S3 (state_backend) so one synced
+// inventory can be shared between machines. The local state.json stays the
+// cache every command reads; Store only moves it up and down.
+package s3state
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/phenixrizen/rift/internal/fileutil"
+)
+
+// objectName is the key under the configured prefix.
+const objectName = "state.json"
+
+// ErrNotFound is returned by Get when nothing has been pushed yet.
+var ErrNotFound = errors.New("no state in the backend yet")
+
+// Location is the object a state_backend URL points at.
+type Location struct {
+	Bucket string
+	Key    string
+}
+
+func (l Location) String() string {
+	return "s3://" + l.Bucket + "/" + l.Key
+}
+
+// ParseURL parses s3://bucket[/prefix]. The object is prefix/state.json, or
+// the prefix itself when it already ends in .json.
+func ParseURL(raw string) (Location, error) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(raw), "s3://")
+	if !ok {
+		return Location{}, fmt.Errorf("state_backend %q must be an s3:// URL", raw)
+	}
+	bucket, prefix, _ := strings.Cut(rest, "/")
+	if bucket == "" {
+		return Location{}, fmt.Errorf("state_backend %q has no bucket", raw)
+	}
+	prefix = strings.Trim(prefix, "/")
+	key := objectName
+	switch {
+	case strings.HasSuffix(prefix, ".json"):
+		key = prefix
+	case prefix != "":
+		key = prefix + "/" + objectName
+	}
+	return Location{Bucket: bucket, Key: key}, nil
+}
+
+type Store struct {
+	client *s3.Client
+	loc    Location
+}
+
+// New returns a Store reaching loc in region with creds.
+func New(loc Location, region string, creds aws.Credentials) *Store {
+	client := s3.NewFromConfig(aws.Config{
+		Region:      region,
+		Credentials: credentials.StaticCredentialsProvider{Value: creds},
+	})
+	return &Store{client: client, loc: loc}
+}
+
+// Get downloads the state. With etag (from an earlier Get or Put) it
+// returns notModified instead of the body when the object is unchanged.
+func (s *Store) Get(ctx context.Context, etag string) (data []byte, newETag string, notModified bool, err error) {
+	input := &s3.GetObjectInput{Bucket: aws.String(s.loc.Bucket), Key: aws.String(s.loc.Key)}
+	if etag != "" {
+		input.IfNoneMatch = aws.String(etag)
+	}
+	out, err := s.client.GetObject(ctx, input)
+	if err != nil {
+		var noKey *types.NoSuchKey
+		if errors.As(err, &noKey) {
+			return nil, "", false, fmt.Errorf("%s: %w", s.loc, ErrNotFound)
+		}
+		var resp *awshttp.ResponseError
+		if errors.As(err, &resp) && resp.HTTPStatusCode() == http.StatusNotModified {
+			return nil, etag, true, nil
+		}
+		return nil, "", false, fmt.Errorf("download %s: %w", s.loc, err)
+	}
+	defer out.Body.Close()
+	data, err = io.ReadAll(out.Body)
+	if err != nil {
+		return nil, "", false, fmt.Errorf("download %s: %w", s.loc, err)
+	}
+	return data, aws.ToString(out.ETag), false, nil
+}
+
+// Put uploads data and returns the new object's ETag.
+func (s *Store) Put(ctx context.Context, data []byte) (string, error) {
+	out, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.loc.Bucket),
+		Key:         aws.String(s.loc.Key),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String("application/json"),
+	})
+	if err != nil {
+		return "", fmt.Errorf("upload %s: %w", s.loc, err)
+	}
+	return aws.ToString(out.ETag), nil
+}
+
+// Cache records which backend object the local state.json was last pulled
+// from or pushed to, so pull can skip unchanged downloads.
+type Cache struct {
+	URL    string    `json:"url"`
+	ETag   string    `json:"etag"`
+	At     time.Time `json:"at"`
+	Pushed bool      `json:"pushed,omitempty"`
+	// StateSHA256 is the hash (StateHash) of the local state.json as it was
+	// then; a sync whose push failed, or any other local write, changes it.
+	StateSHA256 string `json:"state_sha256,omitempty"`
+}
+
+// StateHash is the hex SHA-256 of state file data, for Cache.StateSHA256.
+func StateHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Current reports whether c still describes local, the state.json data on
+// disk, as the object at url: only then does an unchanged ETag mean there
+// is nothing to pull.
+func (c Cache) Current(url string, local []byte) bool {
+	return c.URL == url && c.ETag != "" && c.StateSHA256 != "" && c.StateSHA256 == StateHash(local)
+}
+
+// CachePathFor is where the Cache record for a state file lives.
+func CachePathFor(statePath string) string {
+	return filepath.Join(filepath.Dir(statePath), "state-backend.json")
+}
+
+// LoadCache reads the record at path; a zero Cache when there is none.
+func LoadCache(path string) (Cache, error) {
+	var s Cache
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return s, nil
+		}
+		return s, err
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return Cache{}, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return s, nil
+}
+
+func SaveCache(path string, s Cache) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return fileutil.WriteAtomic(path, append(data, '\n'), 0o644)
+}
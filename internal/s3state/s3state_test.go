@@ -0,0 +1,51 @@
+package s3state
+
+import "testing"
+
+func TestParseURL(t *testing.T) {
+	cases := []struct {
+		raw  string
+		want Location
+	}{
+		{"s3://team-rift", Location{Bucket: "team-rift", Key: "state.json"}},
+		{"s3://team-rift/users/alice/", Location{Bucket: "team-rift", Key: "users/alice/state.json"}},
+		{"s3://team-rift/shared/inventory.json", Location{Bucket: "team-rift", Key: "shared/inventory.json"}},
+	}
+	for _, tc := range cases {
+		got, err := ParseURL(tc.raw)
+		if err != nil {
+			t.Fatalf("ParseURL(%q): %v", tc.raw, err)
+		}
+		if got != tc.want {
+			t.Fatalf("ParseURL(%q)=%+v want %+v", tc.raw, got, tc.want)
+		}
+	}
+	for _, raw := range []string{"team-rift/state.json", "s3://", "s3:///state.json"} {
+		if _, err := ParseURL(raw); err == nil {
+			t.Fatalf("ParseURL(%q) succeeded, want error", raw)
+		}
+	}
+}
+
+func TestCacheCurrent(t *testing.T) {
+	const url = "s3://team-rift/state.json"
+	local := []byte(`{"version":3}`)
+	cache := Cache{URL: url, ETag: `"abc"`, StateSHA256: StateHash(local)}
+	cases := []struct {
+		name  string
+		cache Cache
+		url   string
+		local []byte
+		want  bool
+	}{
+		{"unchanged", cache, url, local, true},
+		{"local state changed", cache, url, []byte(`{"version":3,"clusters":[]}`), false},
+		{"other backend", cache, "s3://other/state.json", local, false},
+		{"cache without hash", Cache{URL: url, ETag: `"abc"`}, url, local, false},
+	}
+	for _, tc := range cases {
+		if got := tc.cache.Current(tc.url, tc.local); got != tc.want {
+			t.Fatalf("%s: Current()=%v want %v", tc.name, got, tc.want)
+		}
+	}
+}
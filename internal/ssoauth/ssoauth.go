@@ -0,0 +1,316 @@
+// Package ssoauth implements the IAM Identity Center OIDC device
+// authorization flow natively and writes the resulting token to the AWS SSO
+// cache in the format the AWS CLI and SDKs read.
+package ssoauth
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssooidc"
+	"github.com/aws/aws-sdk-go-v2/service/ssooidc/types"
+	"github.com/phenixrizen/rift/internal/browser"
+	"github.com/phenixrizen/rift/internal/fileutil"
+)
+
+const (
+	// SessionName matches the [sso-session rift] section awsconfig writes, so
+	// the cache file is shared with `aws sso login --sso-session rift`.
+	// Additional sessions use their own rift-<name> section name.
+	SessionName = "rift"
+	// ClientName is the application users approve in the browser.
+	ClientName = "rift"
+
+	deviceGrantType  = "urn:ietf:params:oauth:grant-type:device_code"
+	refreshGrantType = "refresh_token"
+	expiryLayout     = "2006-01-02T15:04:05Z"
+)
+
+var scopes = []string{"sso:account:access"}
+
+// Device is what the user needs to approve a login.
+type Device struct {
+	VerificationURI         string
+	VerificationURIComplete string
+	UserCode                string
+	ExpiresAt               time.Time
+}
+
+type Options struct {
+	// Session is the sso-session name keying the cache file (default
+	// SessionName).
+	Session string
+	// OnPrompt is called once the device code is issued.
+	OnPrompt func(Device)
+	// OpenBrowser opens VerificationURIComplete with the system browser.
+	OpenBrowser bool
+}
+
+// CachedToken is the AWS CLI-compatible SSO cache entry.
+type CachedToken struct {
+	StartURL              string `json:"startUrl"`
+	Region                string `json:"region"`
+	AccessToken           string `json:"accessToken"`
+	ExpiresAt             string `json:"expiresAt"`
+	ClientID              string `json:"clientId,omitempty"`
+	ClientSecret          string `json:"clientSecret,omitempty"`
+	RegistrationExpiresAt string `json:"registrationExpiresAt,omitempty"`
+	RefreshToken          string `json:"refreshToken,omitempty"`
+}
+
+// CachePath returns ~/.aws/sso/cache/<sha1(session)>.json.
+func CachePath(sessionName string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha1.Sum([]byte(sessionName))
+	return filepath.Join(home, ".aws", "sso", "cache", hex.EncodeToString(sum[:])+".json"), nil
+}
+
+func LoadCache(path string) (CachedToken, error) {
+	var tok CachedToken
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return tok, err
+	}
+	if err := json.Unmarshal(data, &tok); err != nil {
+		return tok, fmt.Errorf("parse sso cache %s: %w", path, err)
+	}
+	return tok, nil
+}
+
+func SaveCache(path string, tok CachedToken) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	data, err := json.Marshal(tok)
+	if err != nil {
+		return err
+	}
+	return fileutil.WriteAtomic(path, data, 0o600)
+}
+
+// Login runs the device authorization flow for startURL and caches the
+// token. A client registration cached by an earlier login is reused until
+// it expires.
+func Login(ctx context.Context, startURL, region string, opts Options) (CachedToken, error) {
+	path, err := CachePath(sessionOrDefault(opts.Session))
+	if err != nil {
+		return CachedToken{}, err
+	}
+	client := ssooidc.NewFromConfig(aws.Config{Region: region})
+
+	prev, _ := LoadCache(path)
+	clientID, clientSecret, regExpiry, err := registration(ctx, client, prev, startURL, region)
+	if err != nil {
+		return CachedToken{}, err
+	}
+
+	auth, err := client.StartDeviceAuthorization(ctx, &ssooidc.StartDeviceAuthorizationInput{
+		ClientId:     aws.String(clientID),
+		ClientSecret: aws.String(clientSecret),
+		StartUrl:     aws.String(startURL),
+	})
+	if err != nil {
+		return CachedToken{}, fmt.Errorf("start device authorization: %w", err)
+	}
+	device := Device{
+		VerificationURI:         aws.ToString(auth.VerificationUri),
+		VerificationURIComplete: aws.ToString(auth.VerificationUriComplete),
+		UserCode:                aws.ToString(auth.UserCode),
+		ExpiresAt:               time.Now().Add(time.Duration(auth.ExpiresIn) * time.Second),
+	}
+	if opts.OnPrompt != nil {
+		opts.OnPrompt(device)
+	}
+	if opts.OpenBrowser && device.VerificationURIComplete != "" {
+		_ = browser.Open(device.VerificationURIComplete)
+	}
+
+	interval := time.Duration(auth.Interval) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return CachedToken{}, ctx.Err()
+		case <-time.After(interval):
+		}
+		if time.Now().After(device.ExpiresAt) {
+			return CachedToken{}, errors.New("device authorization expired before it was approved")
+		}
+		out, err := client.CreateToken(ctx, &ssooidc.CreateTokenInput{
+			ClientId:     aws.String(clientID),
+			ClientSecret: aws.String(clientSecret),
+			DeviceCode:   auth.DeviceCode,
+			GrantType:    aws.String(deviceGrantType),
+		})
+		var pending *types.AuthorizationPendingException
+		var slowDown *types.SlowDownException
+		switch {
+		case errors.As(err, &pending):
+			continue
+		case errors.As(err, &slowDown):
+			interval += 5 * time.Second
+			continue
+		case err != nil:
+			return CachedToken{}, fmt.Errorf("create token: %w", err)
+		}
+
+		tok := CachedToken{
+			StartURL:              startURL,
+			Region:                region,
+			AccessToken:           aws.ToString(out.AccessToken),
+			ExpiresAt:             time.Now().UTC().Add(time.Duration(out.ExpiresIn) * time.Second).Format(expiryLayout),
+			ClientID:              clientID,
+			ClientSecret:          clientSecret,
+			RegistrationExpiresAt: regExpiry,
+			RefreshToken:          aws.ToString(out.RefreshToken),
+		}
+		if err := SaveCache(path, tok); err != nil {
+			return tok, fmt.Errorf("write sso cache: %w", err)
+		}
+		return tok, nil
+	}
+}
+
+// ErrNoRefreshToken means the cached login cannot be renewed silently and the
+// user has to run the device flow again.
+var ErrNoRefreshToken = errors.New("cached sso login has no usable refresh token")
+
+// Refresh exchanges the cached refresh token of session for a new access
+// token and rewrites the cache entry.
+func Refresh(ctx context.Context, session, startURL, region string) (CachedToken, error) {
+	path, err := CachePath(sessionOrDefault(session))
+	if err != nil {
+		return CachedToken{}, err
+	}
+	prev, err := LoadCache(path)
+	if err != nil {
+		return CachedToken{}, err
+	}
+	if prev.RefreshToken == "" || prev.ClientID == "" || prev.ClientSecret == "" || prev.StartURL != startURL {
+		return CachedToken{}, ErrNoRefreshToken
+	}
+	if expires, err := time.Parse(time.RFC3339, prev.RegistrationExpiresAt); err == nil && time.Now().After(expires) {
+		return CachedToken{}, ErrNoRefreshToken
+	}
+
+	client := ssooidc.NewFromConfig(aws.Config{Region: region})
+	out, err := client.CreateToken(ctx, &ssooidc.CreateTokenInput{
+		ClientId:     aws.String(prev.ClientID),
+		ClientSecret: aws.String(prev.ClientSecret),
+		GrantType:    aws.String(refreshGrantType),
+		RefreshToken: aws.String(prev.RefreshToken),
+	})
+	if err != nil {
+		return CachedToken{}, fmt.Errorf("refresh token: %w", err)
+	}
+	tok := prev
+	tok.AccessToken = aws.ToString(out.AccessToken)
+	tok.ExpiresAt = time.Now().UTC().Add(time.Duration(out.ExpiresIn) * time.Second).Format(expiryLayout)
+	if rt := aws.ToString(out.RefreshToken); rt != "" {
+		tok.RefreshToken = rt
+	}
+	if err := SaveCache(path, tok); err != nil {
+		return tok, fmt.Errorf("write sso cache: %w", err)
+	}
+	return tok, nil
+}
+
+type KeepAliveOptions struct {
+	// Session is the sso-session to keep fresh (default SessionName).
+	Session string
+	// Before is how long before expiry to refresh (default 15m).
+	Before time.Duration
+	// OnRefresh is called after each successful refresh.
+	OnRefresh func(CachedToken)
+	// OnError is called when a refresh fails; KeepAlive retries after a
+	// minute, or stops on ErrNoRefreshToken.
+	OnError func(error)
+}
+
+// KeepAlive refreshes the cached token shortly before it expires until ctx
+// is cancelled or the login can no longer be renewed.
+func KeepAlive(ctx context.Context, startURL, region string, opts KeepAliveOptions) error {
+	if opts.Before <= 0 {
+		opts.Before = 15 * time.Minute
+	}
+	path, err := CachePath(sessionOrDefault(opts.Session))
+	if err != nil {
+		return err
+	}
+	for {
+		wait := time.Minute
+		if tok, err := LoadCache(path); err == nil {
+			if expires, err := time.Parse(time.RFC3339, tok.ExpiresAt); err == nil {
+				wait = time.Until(expires.Add(-opts.Before))
+			}
+		}
+		if wait > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(wait):
+			}
+		}
+
+		tok, err := Refresh(ctx, opts.Session, startURL, region)
+		switch {
+		case errors.Is(err, ErrNoRefreshToken):
+			if opts.OnError != nil {
+				opts.OnError(err)
+			}
+			return err
+		case err != nil:
+			if opts.OnError != nil {
+				opts.OnError(err)
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Minute):
+			}
+		default:
+			if opts.OnRefresh != nil {
+				opts.OnRefresh(tok)
+			}
+		}
+	}
+}
+
+func sessionOrDefault(session string) string {
+	if session == "" {
+		return SessionName
+	}
+	return session
+}
+
+func registration(ctx context.Context, client *ssooidc.Client, prev CachedToken, startURL, region string) (string, string, string, error) {
+	if prev.ClientID != "" && prev.ClientSecret != "" && prev.StartURL == startURL && strings.EqualFold(prev.Region, region) {
+		if expires, err := time.Parse(time.RFC3339, prev.RegistrationExpiresAt); err == nil && time.Until(expires) > time.Hour {
+			return prev.ClientID, prev.ClientSecret, prev.RegistrationExpiresAt, nil
+		}
+	}
+	out, err := client.RegisterClient(ctx, &ssooidc.RegisterClientInput{
+		ClientName: aws.String(ClientName),
+		ClientType: aws.String("public"),
+		Scopes:     scopes,
+	})
+	if err != nil {
+		return "", "", "", fmt.Errorf("register sso client: %w", err)
+	}
+	expires := time.Unix(out.ClientSecretExpiresAt, 0).UTC().Format(expiryLayout)
+	return aws.ToString(out.ClientId), aws.ToString(out.ClientSecret), expires, nil
+}
@@ -0,0 +1,70 @@
+package ssoauth
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/phenixrizen/rift/internal/config"
+	"github.com/phenixrizen/rift/internal/discovery"
+)
+
+func TestCachePathMatchesAWSCLISessionKey(t *testing.T) {
+	t.Setenv("HOME", "/home/test")
+	path, err := CachePath("rift")
+	if err != nil {
+		t.Fatalf("CachePath returned error: %v", err)
+	}
+	// sha1("rift"), the key the AWS CLI uses for [sso-session rift].
+	if got := filepath.Base(path); got != "37a219101cafe5ac76b86bcd4dbd2a3884a822dc.json" {
+		t.Fatalf("cache file=%q", got)
+	}
+}
+
+func TestRefreshRequiresRefreshToken(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	path, err := CachePath(SessionName)
+	if err != nil {
+		t.Fatalf("CachePath returned error: %v", err)
+	}
+	// Tokens written by older AWS CLI versions have no client registration.
+	if err := SaveCache(path, CachedToken{
+		StartURL:    "https://acme.awsapps.com/start",
+		Region:      "us-east-1",
+		AccessToken: "token",
+		ExpiresAt:   time.Now().UTC().Add(time.Hour).Format(expiryLayout),
+	}); err != nil {
+		t.Fatalf("SaveCache returned error: %v", err)
+	}
+	if _, err := Refresh(context.Background(), SessionName, "https://acme.awsapps.com/start", "us-east-1"); !errors.Is(err, ErrNoRefreshToken) {
+		t.Fatalf("Refresh err=%v want ErrNoRefreshToken", err)
+	}
+}
+
+func TestSavedCacheIsReadableByDiscovery(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	path, err := CachePath(SessionName)
+	if err != nil {
+		t.Fatalf("CachePath returned error: %v", err)
+	}
+	expires := time.Now().UTC().Add(8 * time.Hour)
+	if err := SaveCache(path, CachedToken{
+		StartURL:    "https://acme.awsapps.com/start",
+		Region:      "us-east-1",
+		AccessToken: "token",
+		ExpiresAt:   expires.Format(expiryLayout),
+	}); err != nil {
+		t.Fatalf("SaveCache returned error: %v", err)
+	}
+
+	cfg := config.Config{SSOStartURL: "https://acme.awsapps.com/start", SSORegion: "us-east-1"}
+	tok, err := discovery.CurrentSSOToken(cfg, time.Now().UTC())
+	if err != nil {
+		t.Fatalf("CurrentSSOToken returned error: %v", err)
+	}
+	if tok.ExpiresAt.Unix() != expires.Unix() {
+		t.Fatalf("ExpiresAt=%s want %s", tok.ExpiresAt, expires)
+	}
+}
@@ -0,0 +1,72 @@
+package state
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Version is the state.json schema this rift reads and writes. When a field
+// is renamed, moved, or changes meaning, bump it and append a step to
+// migrations so state written by older releases keeps loading.
+const Version = 1
+
+// ErrNewerVersion is returned for state written by a newer rift; it is never
+// guessed at, since saving it again would drop whatever the newer schema
+// added.
+var ErrNewerVersion = errors.New("state was written by a newer rift")
+
+// migrations[v] upgrades a decoded state document from version v to v+1.
+var migrations = []func(doc map[string]any) error{
+	// 0: state from before versioning. The layout is the same as version 1.
+	func(map[string]any) error { return nil },
+}
+
+// Migrate upgrades a raw state.json document to Version and returns it with
+// the version it was written at. Documents without a version are version 0.
+func Migrate(data []byte) ([]byte, int, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc map[string]any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, 0, err
+	}
+	from, err := docVersion(doc)
+	if err != nil {
+		return nil, 0, err
+	}
+	if from > Version {
+		return nil, from, fmt.Errorf("%w (version %d, this rift reads up to %d)", ErrNewerVersion, from, Version)
+	}
+	if from == Version {
+		return data, from, nil
+	}
+	for v := from; v < Version; v++ {
+		if err := migrations[v](doc); err != nil {
+			return nil, from, fmt.Errorf("migrate state from version %d: %w", v, err)
+		}
+	}
+	doc["version"] = Version
+	out, err := json.Marshal(doc)
+	if err != nil {
+		return nil, from, err
+	}
+	return out, from, nil
+}
+
+func docVersion(doc map[string]any) (int, error) {
+	raw, ok := doc["version"]
+	if !ok || raw == nil {
+		return 0, nil
+	}
+	num, ok := raw.(json.Number)
+	if !ok {
+		return 0, fmt.Errorf("state version %v is not a number", raw)
+	}
+	v, err := num.Int64()
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("invalid state version %s", num)
+	}
+	return int(v), nil
+}
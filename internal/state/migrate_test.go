@@ -0,0 +1,44 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadMigratesAndRefusesNewerState(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state.json")
+	legacy := `{"generated_at":"2024-01-02T03:04:05Z","regions":["us-east-1"],"roles":[],"clusters":[{"kube_context":"rift-dev-acme-main","cluster_name":"main"}]}`
+	if err := os.WriteFile(path, []byte(legacy), 0o644); err != nil {
+		t.Fatalf("write state: %v", err)
+	}
+	st, err := Load(path, nil)
+	if err != nil {
+		t.Fatalf("Load unversioned state: %v", err)
+	}
+	if len(st.Clusters) != 1 || st.Clusters[0].KubeContext != "rift-dev-acme-main" {
+		t.Fatalf("clusters=%+v want the legacy cluster", st.Clusters)
+	}
+	if err := Save(path, st, nil); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read state: %v", err)
+	}
+	if !strings.Contains(string(data), fmt.Sprintf(`"version": %d`, Version)) {
+		t.Fatalf("saved state has no version:\n%s", data)
+	}
+
+	newer := `{"version":99,"clusters":[]}`
+	if err := os.WriteFile(path, []byte(newer), 0o644); err != nil {
+		t.Fatalf("write state: %v", err)
+	}
+	if _, err := Load(path, nil); !errors.Is(err, ErrNewerVersion) {
+		t.Fatalf("Load newer state err=%v want ErrNewerVersion", err)
+	}
+}
@@ -0,0 +1,178 @@
+package state
+
+import "strings"
+
+// Shared reports whether the role came from a teammate's state rather than
+// the user's own discovery.
+func (r RoleRecord) Shared() bool {
+	return r.SharedFrom != ""
+}
+
+// Shared reports whether the cluster came from a teammate's state rather
+// than the user's own discovery.
+func (c ClusterRecord) Shared() bool {
+	return c.SharedFrom != ""
+}
+
+// MergeShared replaces the records s holds from source with the discovered
+// roles and clusters of other, marked as shared from source. Records s
+// already has from its own discovery or another source, and records whose
+// profile or context name s already uses, are skipped. Clusters reached
+// through a role s discovered itself use s's profile for it; since that
+// hands the user's credentials to an endpoint other chose, the ones whose
+// endpoint is not that of a cluster s discovered are returned so the caller
+// can say so. External and plugin clusters of other are never imported.
+func (s *State) MergeShared(other State, source string) []ClusterRecord {
+	s.DropShared(source)
+
+	ownProfiles := map[string]string{}
+	roles := map[string]struct{}{}
+	for _, r := range s.Roles {
+		roles[roleIdentity(r)] = struct{}{}
+		if !r.Shared() {
+			ownProfiles[roleIdentity(r)] = r.AWSProfile
+		}
+	}
+	profiles := s.AWSProfiles()
+	for _, r := range other.Roles {
+		if _, ok := roles[roleIdentity(r)]; ok {
+			continue
+		}
+		if _, ok := profiles[r.AWSProfile]; ok || r.Shared() {
+			continue
+		}
+		r.SharedFrom = source
+		s.Roles = append(s.Roles, r)
+		roles[roleIdentity(r)] = struct{}{}
+		profiles[r.AWSProfile] = struct{}{}
+	}
+
+	otherRoles := make(map[string]string, len(other.Roles))
+	for _, r := range other.Roles {
+		otherRoles[r.AWSProfile] = roleIdentity(r)
+	}
+	clusters := map[string]struct{}{}
+	endpoints := map[string]struct{}{}
+	for _, c := range s.Clusters {
+		clusters[clusterIdentity(c)] = struct{}{}
+		if !c.Shared() && !c.External() && c.ClusterEndpoint != "" {
+			endpoints[c.ClusterEndpoint] = struct{}{}
+		}
+	}
+	contexts := s.KubeContexts()
+	var rebound []ClusterRecord
+	for _, c := range other.Clusters {
+		// A plugin cluster's token comes from running rift-<Plugin>: a
+		// shared file must not pick the binary.
+		if c.External() || c.Shared() || c.Platform == PlatformPlugin {
+			continue
+		}
+		if _, ok := clusters[clusterIdentity(c)]; ok {
+			continue
+		}
+		if _, ok := contexts[c.KubeContext]; ok {
+			continue
+		}
+		c.SharedFrom = source
+		if profile, ok := ownProfiles[otherRoles[c.AWSProfile]]; ok {
+			c.AWSProfile = profile
+			if _, known := endpoints[c.ClusterEndpoint]; !known {
+				rebound = append(rebound, c)
+			}
+		}
+		s.Clusters = append(s.Clusters, c)
+		clusters[clusterIdentity(c)] = struct{}{}
+		contexts[c.KubeContext] = struct{}{}
+	}
+	s.Normalize()
+	return rebound
+}
+
+// DropShared removes the records shared from source, or every shared record
+// when source is empty, and returns how many it removed.
+func (s *State) DropShared(source string) int {
+	drop := func(from string) bool {
+		return from != "" && (source == "" || from == source)
+	}
+	removed := 0
+	roles := s.Roles[:0]
+	for _, r := range s.Roles {
+		if drop(r.SharedFrom) {
+			removed++
+			continue
+		}
+		roles = append(roles, r)
+	}
+	s.Roles = roles
+	clusters := s.Clusters[:0]
+	for _, c := range s.Clusters {
+		if drop(c.SharedFrom) {
+			removed++
+			continue
+		}
+		clusters = append(clusters, c)
+	}
+	s.Clusters = clusters
+	return removed
+}
+
+// SharedSources returns the distinct sources of shared records in s, in
+// order of first appearance.
+func (s State) SharedSources() []string {
+	seen := map[string]struct{}{}
+	out := make([]string, 0)
+	add := func(from string) {
+		if from == "" {
+			return
+		}
+		if _, ok := seen[from]; ok {
+			return
+		}
+		seen[from] = struct{}{}
+		out = append(out, from)
+	}
+	for _, r := range s.Roles {
+		add(r.SharedFrom)
+	}
+	for _, c := range s.Clusters {
+		add(c.SharedFrom)
+	}
+	return out
+}
+
+// CarryShared copies the shared records of prev into s, which only holds
+// the user's own records, dropping each one the user has now discovered
+// (or whose name a discovered record took), so shared records fade out as
+// the user's own access grows.
+func (s *State) CarryShared(prev State) {
+	for _, source := range prev.SharedSources() {
+		shared := State{}
+		for _, r := range prev.Roles {
+			if r.SharedFrom == source {
+				r.SharedFrom = ""
+				shared.Roles = append(shared.Roles, r)
+			}
+		}
+		for _, c := range prev.Clusters {
+			if c.SharedFrom == source {
+				c.SharedFrom = ""
+				shared.Clusters = append(shared.Clusters, c)
+			}
+		}
+		s.MergeShared(shared, source)
+	}
+}
+
+// roleIdentity identifies a role across users: sessions and profile names
+// differ between machines, accounts and role names do not.
+func roleIdentity(r RoleRecord) string {
+	return strings.Join([]string{r.AccountID, r.RoleName, r.AssumeRoleARN}, "|")
+}
+
+// clusterIdentity identifies a cluster across users, by ARN when it has one.
+func clusterIdentity(c ClusterRecord) string {
+	if c.ClusterARN != "" {
+		return c.ClusterARN
+	}
+	return strings.Join([]string{c.Platform, c.AccountID, c.Region, c.ClusterName}, "|")
+}
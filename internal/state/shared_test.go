@@ -0,0 +1,86 @@
+package state
+
+import "testing"
+
+func TestMergeSharedSkipsOwnRecordsAndFadesOut(t *testing.T) {
+	own := State{
+		Roles: []RoleRecord{{AccountID: "1", RoleName: "Admin", AWSProfile: "rift-dev-a-admin"}},
+		Clusters: []ClusterRecord{
+			{AccountID: "1", ClusterARN: "arn:a", ClusterName: "a", AWSProfile: "rift-dev-a-admin", KubeContext: "rift-dev-a", ClusterEndpoint: "https://a.eks"},
+		},
+	}
+	teammate := State{
+		Roles: []RoleRecord{
+			{AccountID: "1", RoleName: "Admin", AWSProfile: "alice-admin"},
+			{AccountID: "2", RoleName: "Admin", AWSProfile: "alice-prod"},
+		},
+		Clusters: []ClusterRecord{
+			{AccountID: "1", ClusterARN: "arn:a", ClusterName: "a", AWSProfile: "alice-admin", KubeContext: "alice-a"},
+			{AccountID: "1", ClusterARN: "arn:b", ClusterName: "b", AWSProfile: "alice-admin", KubeContext: "alice-b", ClusterEndpoint: "https://b.eks"},
+			{AccountID: "2", ClusterARN: "arn:c", ClusterName: "c", AWSProfile: "alice-prod", KubeContext: "alice-c"},
+			{Platform: PlatformExternal, ClusterName: "kind", KubeContext: "kind-kind"},
+		},
+	}
+
+	st := own
+	rebound := st.MergeShared(teammate, "alice.json")
+	// b now gets the user's credentials at an endpoint only alice vouches for.
+	if len(rebound) != 1 || rebound[0].ClusterEndpoint != "https://b.eks" {
+		t.Fatalf("rebound = %+v, want alice-b", rebound)
+	}
+	if len(st.Roles) != 2 || len(st.Clusters) != 3 {
+		t.Fatalf("merged %d roles, %d clusters; want 2, 3", len(st.Roles), len(st.Clusters))
+	}
+	for _, c := range st.Clusters {
+		switch c.KubeContext {
+		case "rift-dev-a":
+			if c.Shared() {
+				t.Fatalf("own cluster marked shared")
+			}
+		case "alice-b":
+			// Account 1 Admin is the user's own role, so its profile is used.
+			if c.SharedFrom != "alice.json" || c.AWSProfile != "rift-dev-a-admin" {
+				t.Fatalf("alice-b = %+v", c)
+			}
+		case "alice-c":
+			if c.SharedFrom != "alice.json" || c.AWSProfile != "alice-prod" {
+				t.Fatalf("alice-c = %+v", c)
+			}
+		default:
+			t.Fatalf("unexpected cluster %s", c.KubeContext)
+		}
+	}
+
+	// Importing again replaces rather than duplicates.
+	st.MergeShared(teammate, "alice.json")
+	if len(st.Roles) != 2 || len(st.Clusters) != 3 {
+		t.Fatalf("re-import gave %d roles, %d clusters", len(st.Roles), len(st.Clusters))
+	}
+
+	// The next sync discovers cluster c: its shared record goes away.
+	next := State{
+		Roles: append(own.Roles, RoleRecord{AccountID: "2", RoleName: "Admin", AWSProfile: "rift-prod-b-admin"}),
+		Clusters: append(own.Clusters,
+			ClusterRecord{AccountID: "2", ClusterARN: "arn:c", ClusterName: "c", AWSProfile: "rift-prod-b-admin", KubeContext: "rift-prod-c"}),
+	}
+	next.CarryShared(st)
+	if len(next.Roles) != 2 {
+		t.Fatalf("roles after sync = %+v", next.Roles)
+	}
+	shared := 0
+	for _, c := range next.Clusters {
+		if c.Shared() {
+			shared++
+			if c.KubeContext != "alice-b" {
+				t.Fatalf("kept shared %s", c.KubeContext)
+			}
+		}
+	}
+	if shared != 1 {
+		t.Fatalf("%d shared clusters after sync, want 1", shared)
+	}
+
+	if n := next.DropShared(""); n != 1 || len(next.SharedSources()) != 0 {
+		t.Fatalf("DropShared removed %d, sources left %v", n, next.SharedSources())
+	}
+}
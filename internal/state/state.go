@@ -1,47 +1,362 @@
 package state
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"sort"
 	"strings"
 	"time"
+
+	"github.com/phenixrizen/rift/internal/fileutil"
+)
+
+// Cluster platforms. An empty Platform is a regular EKS cluster (including
+// ones with nodes in Local Zones or extended clusters on Outposts).
+// PlatformGKE and PlatformAKS clusters come from Google Cloud and Azure:
+// AccountID and AccountName are the project (or subscription ID and name),
+// Region is the location, and there is no AWS role or profile.
+// PlatformExternal records are kubeconfig contexts adopted by `rift import
+// kubeconfig`: KubeContext is the user's context name, and rift never writes
+// or removes their kubeconfig entries. PlatformPlugin clusters come from a
+// discovery plugin, the rift-<Plugin> binary, which also issues their
+// tokens.
+const (
+	PlatformOutpost   = "outpost"
+	PlatformConnected = "connected"
+	PlatformGKE       = "gke"
+	PlatformAKS       = "aks"
+	PlatformExternal  = "external"
+	PlatformPlugin    = "plugin"
 )
 
+// ExecPluginRift (ClusterRecord.ExecPlugin) makes TokenCommand run rift
+// token.
+const ExecPluginRift = "rift"
+
+// aksServerID is the application ID of the Microsoft Entra ID server app
+// every AKS cluster with Entra integration accepts tokens for.
+const aksServerID = "6dae42f8-4368-4678-94ff-3960e28e3630"
+
+// pluginNamePattern matches a plugin name: the part of rift-<name> after
+// the prefix.
+var pluginNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// ValidPluginName reports whether name can name a plugin. Anything else
+// could make rift-<name> a path to some other binary.
+func ValidPluginName(name string) bool {
+	return pluginNamePattern.MatchString(name)
+}
+
+// AWSPlatform reports whether clusters of platform are reached through an
+// AWS role (EKS in any form) rather than another cloud's credentials.
+func AWSPlatform(platform string) bool {
+	switch platform {
+	case PlatformGKE, PlatformAKS, PlatformExternal, PlatformPlugin:
+		return false
+	}
+	return true
+}
+
 type RoleRecord struct {
-	Env         string `json:"env"`
-	AccountID   string `json:"account_id"`
-	AccountName string `json:"account_name"`
-	RoleName    string `json:"role_name"`
-	RoleSlug    string `json:"role_slug"`
-	AWSProfile  string `json:"aws_profile"`
+	Env          string `json:"env"`
+	AccountID    string `json:"account_id"`
+	AccountName  string `json:"account_name"`
+	AccountAlias string `json:"account_alias,omitempty"`
+	RoleName     string `json:"role_name"`
+	RoleSlug     string `json:"role_slug"`
+	AWSProfile   string `json:"aws_profile"`
+	// Chained roles are assumed from SourceProfile instead of granted by SSO.
+	AssumeRoleARN string `json:"assume_role_arn,omitempty"`
+	ExternalID    string `json:"external_id,omitempty"`
+	SourceProfile string `json:"source_profile,omitempty"`
+	// SSOSession is the sso-session the role was discovered through; empty
+	// in state written before multi-session support means "rift".
+	SSOSession string `json:"sso_session,omitempty"`
+	// SharedFrom is the file or URL a `rift state import` took the record
+	// from; empty for discovered roles.
+	SharedFrom string `json:"shared_from,omitempty"`
 }
 
 type ClusterRecord struct {
-	Env                      string   `json:"env"`
-	AccountID                string   `json:"account_id"`
-	AccountName              string   `json:"account_name"`
-	RoleName                 string   `json:"role_name"`
-	AWSProfile               string   `json:"aws_profile"`
-	Region                   string   `json:"region"`
-	ClusterName              string   `json:"cluster_name"`
-	ClusterARN               string   `json:"cluster_arn"`
-	ClusterEndpoint          string   `json:"cluster_endpoint"`
-	ClusterCertificateBase64 string   `json:"cluster_certificate_base64"`
-	KubeContext              string   `json:"kube_context"`
-	Namespace                string   `json:"namespace"`
-	Namespaces               []string `json:"namespaces,omitempty"`
+	Env                      string   `json:"env" yaml:"env"`
+	AccountID                string   `json:"account_id" yaml:"account_id"`
+	AccountName              string   `json:"account_name" yaml:"account_name"`
+	AccountAlias             string   `json:"account_alias,omitempty" yaml:"account_alias,omitempty"`
+	RoleName                 string   `json:"role_name" yaml:"role_name"`
+	AWSProfile               string   `json:"aws_profile" yaml:"aws_profile"`
+	Region                   string   `json:"region" yaml:"region"`
+	ClusterName              string   `json:"cluster_name" yaml:"cluster_name"`
+	ClusterARN               string   `json:"cluster_arn" yaml:"cluster_arn"`
+	ClusterEndpoint          string   `json:"cluster_endpoint" yaml:"cluster_endpoint"`
+	ClusterCertificateBase64 string   `json:"cluster_certificate_base64" yaml:"cluster_certificate_base64"`
+	ClusterID                string   `json:"cluster_id,omitempty" yaml:"cluster_id,omitempty"`
+	Platform                 string   `json:"platform,omitempty" yaml:"platform,omitempty"`
+	ConnectorProvider        string   `json:"connector_provider,omitempty" yaml:"connector_provider,omitempty"`
+	Plugin                   string   `json:"plugin,omitempty" yaml:"plugin,omitempty"`
+	KubeContext              string   `json:"kube_context" yaml:"kube_context"`
+	Namespace                string   `json:"namespace" yaml:"namespace"`
+	Namespaces               []string `json:"namespaces,omitempty" yaml:"namespaces,omitempty"`
+	NamespacePinned          bool     `json:"namespace_pinned,omitempty" yaml:"namespace_pinned,omitempty"`
+	Tags                     []string `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Favorite                 bool     `json:"favorite,omitempty" yaml:"favorite,omitempty"`
+	SSOSession               string   `json:"sso_session,omitempty" yaml:"sso_session,omitempty"`
+	// ExecPlugin is ExecPluginRift when the kubeconfig runs rift token
+	// instead of aws eks get-token (kubeconfig_exec).
+	ExecPlugin string `json:"exec_plugin,omitempty" yaml:"exec_plugin,omitempty"`
+	// SharedFrom is set on read-only records from a teammate's state (see
+	// MergeShared).
+	SharedFrom string `json:"shared_from,omitempty" yaml:"shared_from,omitempty"`
+	// Cluster metadata as of the last sync; GKE resource labels go in AWSTags.
+	KubernetesVersion string            `json:"kubernetes_version,omitempty" yaml:"kubernetes_version,omitempty"`
+	Status            string            `json:"status,omitempty" yaml:"status,omitempty"`
+	PlatformVersion   string            `json:"platform_version,omitempty" yaml:"platform_version,omitempty"`
+	CreatedAt         time.Time         `json:"created_at,omitzero" yaml:"created_at,omitempty"`
+	AWSTags           map[string]string `json:"aws_tags,omitempty" yaml:"aws_tags,omitempty"`
+	// Nodegroups and FargateProfiles are recorded when discover_compute is
+	// enabled.
+	Nodegroups      []Nodegroup      `json:"nodegroups,omitempty" yaml:"nodegroups,omitempty"`
+	FargateProfiles []FargateProfile `json:"fargate_profiles,omitempty" yaml:"fargate_profiles,omitempty"`
+	// Workloads are per-namespace workload counts, recorded during namespace
+	// discovery when discover_workloads is enabled.
+	Workloads []NamespaceWorkloads `json:"workloads,omitempty" yaml:"workloads,omitempty"`
+	// NamespaceAccess records, per namespace, whether the role may get pods
+	// there (discover_access); nil when it was never checked.
+	NamespaceAccess map[string]bool `json:"namespace_access,omitempty" yaml:"namespace_access,omitempty"`
+	// NamespacesDiscoveredAt is when Namespaces was last listed from the
+	// cluster; sync skips listing it again within namespace_ttl.
+	NamespacesDiscoveredAt time.Time `json:"namespaces_discovered_at,omitzero" yaml:"namespaces_discovered_at,omitempty"`
+}
+
+// Nodegroup is an EKS managed node group.
+type Nodegroup struct {
+	Name              string   `json:"name" yaml:"name"`
+	Status            string   `json:"status,omitempty" yaml:"status,omitempty"`
+	KubernetesVersion string   `json:"kubernetes_version,omitempty" yaml:"kubernetes_version,omitempty"`
+	InstanceTypes     []string `json:"instance_types,omitempty" yaml:"instance_types,omitempty"`
+	// CapacityType is ON_DEMAND, SPOT, or CAPACITY_BLOCK.
+	CapacityType string `json:"capacity_type,omitempty" yaml:"capacity_type,omitempty"`
+	MinSize      int    `json:"min_size" yaml:"min_size"`
+	MaxSize      int    `json:"max_size" yaml:"max_size"`
+	DesiredSize  int    `json:"desired_size" yaml:"desired_size"`
+}
+
+// FargateProfile is an EKS Fargate profile; Namespaces are its selectors'
+// namespaces.
+type FargateProfile struct {
+	Name       string   `json:"name" yaml:"name"`
+	Status     string   `json:"status,omitempty" yaml:"status,omitempty"`
+	Namespaces []string `json:"namespaces,omitempty" yaml:"namespaces,omitempty"`
+}
+
+// Label summarizes the node group for display, e.g.
+// "workers (m5.large, 3 nodes [2-6], spot)".
+func (n Nodegroup) Label() string {
+	parts := make([]string, 0, 3)
+	if len(n.InstanceTypes) > 0 {
+		parts = append(parts, strings.Join(n.InstanceTypes, "/"))
+	}
+	parts = append(parts, fmt.Sprintf("%d nodes [%d-%d]", n.DesiredSize, n.MinSize, n.MaxSize))
+	if n.CapacityType != "" && n.CapacityType != "ON_DEMAND" {
+		parts = append(parts, strings.ToLower(n.CapacityType))
+	}
+	return n.Name + " (" + strings.Join(parts, ", ") + ")"
+}
+
+// Label summarizes the Fargate profile for display, e.g.
+// "batch (jobs, reports)".
+func (f FargateProfile) Label() string {
+	if len(f.Namespaces) == 0 {
+		return f.Name
+	}
+	return f.Name + " (" + strings.Join(f.Namespaces, ", ") + ")"
+}
+
+// NamespaceWorkloads counts the workloads running in one namespace.
+type NamespaceWorkloads struct {
+	Namespace    string `json:"namespace" yaml:"namespace"`
+	Deployments  int    `json:"deployments,omitempty" yaml:"deployments,omitempty"`
+	StatefulSets int    `json:"statefulsets,omitempty" yaml:"statefulsets,omitempty"`
+	DaemonSets   int    `json:"daemonsets,omitempty" yaml:"daemonsets,omitempty"`
+}
+
+// Label summarizes the counts for display, e.g. "3 deployments, 1 statefulset";
+// daemonsets are only mentioned when there are some.
+func (w NamespaceWorkloads) Label() string {
+	plural := func(n int, noun string) string {
+		if n == 1 {
+			return "1 " + noun
+		}
+		return fmt.Sprintf("%d %ss", n, noun)
+	}
+	label := plural(w.Deployments, "deployment") + ", " + plural(w.StatefulSets, "statefulset")
+	if w.DaemonSets > 0 {
+		label += ", " + plural(w.DaemonSets, "daemonset")
+	}
+	return label
+}
+
+// WorkloadsIn returns the recorded counts for namespace ns.
+func (c ClusterRecord) WorkloadsIn(ns string) (NamespaceWorkloads, bool) {
+	for _, w := range c.Workloads {
+		if w.Namespace == ns {
+			return w, true
+		}
+	}
+	return NamespaceWorkloads{}, false
+}
+
+// AccessTo reports whether the role may get pods in namespace ns, and
+// whether that was checked at all.
+func (c ClusterRecord) AccessTo(ns string) (allowed, checked bool) {
+	allowed, checked = c.NamespaceAccess[ns]
+	return allowed, checked
 }
 
 type State struct {
+	// Version is the schema version (see Migrate); Save always writes the
+	// current one.
+	Version     int             `json:"version"`
 	GeneratedAt time.Time       `json:"generated_at"`
 	Regions     []string        `json:"regions"`
 	Roles       []RoleRecord    `json:"roles"`
 	Clusters    []ClusterRecord `json:"clusters"`
 }
 
+// AccountLabel is the alias, name, or ID of the account, in that order.
+func (r RoleRecord) AccountLabel() string {
+	return accountLabel(r.AccountAlias, r.AccountName, r.AccountID)
+}
+
+// AccountLabel is the alias, name, or ID of the account, in that order.
+func (c ClusterRecord) AccountLabel() string {
+	return accountLabel(c.AccountAlias, c.AccountName, c.AccountID)
+}
+
+// Connectable reports whether rift can reach the cluster's API directly.
+// EKS Connector registrations have no endpoint, and a plugin cluster whose
+// Plugin is not a plugin name has no token command rift would run.
+func (c ClusterRecord) Connectable() bool {
+	if c.Platform == PlatformPlugin && !ValidPluginName(c.Plugin) {
+		return false
+	}
+	return strings.TrimSpace(c.ClusterEndpoint) != ""
+}
+
+// PlatformLabel describes where the cluster runs for display.
+func (c ClusterRecord) PlatformLabel() string {
+	switch c.Platform {
+	case PlatformOutpost:
+		return "EKS on Outposts (local)"
+	case PlatformConnected:
+		if c.ConnectorProvider != "" {
+			return "EKS Connector (" + c.ConnectorProvider + ")"
+		}
+		return "EKS Connector"
+	case PlatformGKE:
+		return "GKE"
+	case PlatformAKS:
+		return "AKS"
+	case PlatformExternal:
+		return "External"
+	case PlatformPlugin:
+		return "Plugin (" + c.Plugin + ")"
+	}
+	return "EKS"
+}
+
+// External reports whether the cluster was imported from the user's
+// kubeconfig rather than discovered.
+func (c ClusterRecord) External() bool {
+	return c.Platform == PlatformExternal
+}
+
+// IsAWS reports whether the cluster is reached through an AWS role.
+func (c ClusterRecord) IsAWS() bool {
+	return AWSPlatform(c.Platform)
+}
+
+// TokenCommand returns the exec credential plugin (command and args) that
+// kubeconfig users run to get a token for the cluster.
+func (c ClusterRecord) TokenCommand() (string, []string) {
+	switch c.Platform {
+	case PlatformGKE:
+		return "gke-gcloud-auth-plugin", nil
+	case PlatformAKS:
+		// Entra ID token from the Azure CLI login (az login).
+		return "kubelogin", []string{"get-token", "--login", "azurecli", "--server-id", aksServerID}
+	case PlatformPlugin:
+		return "rift-" + c.Plugin, []string{"token", "--account", c.AccountID, "--region", c.Region, "--cluster", c.ClusterName}
+	}
+	if c.ExecPlugin == ExecPluginRift {
+		args := []string{"token", "--profile", c.AWSProfile, "--region", c.Region}
+		if c.TokenClusterID() != c.ClusterName {
+			return "rift", append(args, "--cluster-id", c.ClusterID)
+		}
+		return "rift", append(args, "--cluster", c.ClusterName)
+	}
+	args := []string{"eks", "get-token", "--profile", c.AWSProfile}
+	args = append(args, c.TokenClusterArgs()...)
+	return "aws", append(args, "--region", c.Region)
+}
+
+// TokenClusterArgs returns the `aws eks get-token` flag identifying the
+// cluster: local clusters on Outposts authenticate by cluster ID.
+func (c ClusterRecord) TokenClusterArgs() []string {
+	if c.TokenClusterID() != c.ClusterName {
+		return []string{"--cluster-id", c.ClusterID}
+	}
+	return []string{"--cluster-name", c.ClusterName}
+}
+
+// TokenClusterID is the cluster identifier an EKS token is signed for: the
+// cluster ID of a local cluster on Outposts, else the cluster name.
+func (c ClusterRecord) TokenClusterID() string {
+	if c.Platform == PlatformOutpost && c.ClusterID != "" {
+		return c.ClusterID
+	}
+	return c.ClusterName
+}
+
+// AWSTagList returns the cluster's AWS resource tags as sorted key=value
+// strings.
+func (c ClusterRecord) AWSTagList() []string {
+	out := make([]string, 0, len(c.AWSTags))
+	for k, v := range c.AWSTags {
+		out = append(out, k+"="+v)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// HasTags reports whether the cluster carries every tag in tags.
+func (c ClusterRecord) HasTags(tags []string) bool {
+	for _, want := range tags {
+		want = strings.ToLower(strings.TrimSpace(want))
+		if want == "" {
+			continue
+		}
+		if !containsString(c.Tags, want) {
+			return false
+		}
+	}
+	return true
+}
+
+func accountLabel(alias, name, id string) string {
+	if strings.TrimSpace(alias) != "" {
+		return alias
+	}
+	if strings.TrimSpace(name) != "" {
+		return name
+	}
+	return id
+}
+
 func (s *State) Normalize() {
 	sort.Slice(s.Roles, func(i, j int) bool {
 		left := strings.Join([]string{s.Roles[i].Env, s.Roles[i].AccountName, s.Roles[i].RoleName}, "|")
@@ -55,11 +370,160 @@ func (s *State) Normalize() {
 	})
 }
 
-func Load(path string) (State, error) {
-	var s State
+// KubeContexts returns the set of kube context names in s, including
+// imported ones. Sync treats the discovered ones as rift-managed even when a
+// context_template drops the rift- prefix.
+func (s State) KubeContexts() map[string]struct{} {
+	out := make(map[string]struct{}, len(s.Clusters))
+	for _, c := range s.Clusters {
+		out[c.KubeContext] = struct{}{}
+	}
+	return out
+}
+
+// AWSProfiles returns the set of AWS profile names in s, rift-managed like
+// KubeContexts.
+func (s State) AWSProfiles() map[string]struct{} {
+	out := make(map[string]struct{}, len(s.Roles))
+	for _, r := range s.Roles {
+		out[r.AWSProfile] = struct{}{}
+	}
+	return out
+}
+
+// CarryPinned copies namespaces the user pinned in prev (for example via
+// `rift migrate`) onto matching clusters in s so a fresh sync keeps them.
+func (s *State) CarryPinned(prev State) {
+	pinned := map[string]string{}
+	for _, c := range prev.Clusters {
+		if c.NamespacePinned && strings.TrimSpace(c.Namespace) != "" {
+			pinned[clusterKey(c)] = c.Namespace
+		}
+	}
+	if len(pinned) == 0 {
+		return
+	}
+	for i := range s.Clusters {
+		ns, ok := pinned[clusterKey(s.Clusters[i])]
+		if !ok {
+			continue
+		}
+		s.Clusters[i].Namespace = ns
+		s.Clusters[i].NamespacePinned = true
+		if !containsString(s.Clusters[i].Namespaces, ns) {
+			s.Clusters[i].Namespaces = append(s.Clusters[i].Namespaces, ns)
+			sort.Strings(s.Clusters[i].Namespaces)
+		}
+	}
+}
+
+// CarryExternal copies the clusters imported from kubeconfig in prev into s,
+// which only holds discovered clusters, unless a discovered cluster now uses
+// the same context name.
+func (s *State) CarryExternal(prev State) {
+	taken := s.KubeContexts()
+	for _, c := range prev.Clusters {
+		if !c.External() {
+			continue
+		}
+		if _, ok := taken[c.KubeContext]; ok {
+			continue
+		}
+		s.Clusters = append(s.Clusters, c)
+	}
+	s.Normalize()
+}
+
+// CarryNamespaces copies discovered namespace lists, workload counts, and
+// access results from
+// prev onto the same clusters in s and returns the indexes of the clusters
+// left to discover: those prev did not have, and those whose namespaces were
+// listed before since. The zero since carries every known cluster.
+func (s *State) CarryNamespaces(prev State, since time.Time) []int {
+	known := map[string]ClusterRecord{}
+	for _, c := range prev.Clusters {
+		known[clusterKey(c)] = c
+	}
+	fresh := make([]int, 0)
+	for i := range s.Clusters {
+		old, ok := known[clusterKey(s.Clusters[i])]
+		if !ok || (!since.IsZero() && old.NamespacesDiscoveredAt.Before(since)) {
+			fresh = append(fresh, i)
+			continue
+		}
+		s.Clusters[i].NamespacesDiscoveredAt = old.NamespacesDiscoveredAt
+		if len(s.Clusters[i].Workloads) == 0 {
+			s.Clusters[i].Workloads = old.Workloads
+		}
+		if len(s.Clusters[i].NamespaceAccess) == 0 {
+			s.Clusters[i].NamespaceAccess = old.NamespaceAccess
+		}
+		for _, ns := range old.Namespaces {
+			if !containsString(s.Clusters[i].Namespaces, ns) {
+				s.Clusters[i].Namespaces = append(s.Clusters[i].Namespaces, ns)
+			}
+		}
+		sort.Strings(s.Clusters[i].Namespaces)
+	}
+	return fresh
+}
+
+func clusterKey(c ClusterRecord) string {
+	if c.ClusterARN != "" {
+		return c.ClusterARN + "|" + c.RoleName
+	}
+	return c.KubeContext
+}
+
+func containsString(values []string, want string) bool {
+	for _, v := range values {
+		if v == want {
+			return true
+		}
+	}
+	return false
+}
+
+// Sealer encrypts state files at rest (state_encryption). Nil means plain
+// JSON.
+type Sealer interface {
+	Seal(plain []byte) ([]byte, error)
+	Open(sealed []byte) ([]byte, error)
+}
+
+// ErrSealed is returned when an encrypted state file is read without a
+// Sealer.
+var ErrSealed = errors.New("state is encrypted")
+
+func Load(path string, sealer Sealer) (State, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
-		return s, err
+		return State{}, err
+	}
+	return Decode(data, sealer)
+}
+
+// Decode parses a state.json document, opening it with sealer when it is
+// encrypted and migrating older versions. Plain JSON is accepted either
+// way, so turning encryption on needs no conversion step.
+func Decode(data []byte, sealer Sealer) (State, error) {
+	var s State
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] != '{' {
+		if sealer == nil {
+			return s, ErrSealed
+		}
+		opened, err := sealer.Open(data)
+		if err != nil {
+			return s, fmt.Errorf("decrypt state: %w", err)
+		}
+		data = opened
+	}
+	data, _, err := Migrate(data)
+	if err != nil {
+		if errors.Is(err, ErrNewerVersion) {
+			return s, err
+		}
+		return s, fmt.Errorf("parse state: %w", err)
 	}
 	if err := json.Unmarshal(data, &s); err != nil {
 		return s, fmt.Errorf("parse state: %w", err)
@@ -68,8 +532,11 @@ func Load(path string) (State, error) {
 	return s, nil
 }
 
-func Save(path string, s State) error {
+// Save writes s with mode 0600 (tightening an existing file), encrypted
+// with sealer when it is not nil.
+func Save(path string, s State, sealer Sealer) error {
 	s.Normalize()
+	s.Version = Version
 	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
 		return err
 	}
@@ -78,5 +545,10 @@ func Save(path string, s State) error {
 		return err
 	}
 	data = append(data, '\n')
-	return os.WriteFile(path, data, 0o644)
+	if sealer != nil {
+		if data, err = sealer.Seal(data); err != nil {
+			return fmt.Errorf("encrypt state: %w", err)
+		}
+	}
+	return fileutil.WritePrivate(path, data, 0o600)
 }
@@ -0,0 +1,42 @@
+package state
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCarryNamespacesSkipsFreshLists(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	prev := State{Clusters: []ClusterRecord{
+		{ClusterARN: "arn:recent", RoleName: "Admin", Namespaces: []string{"payments"}, NamespaceAccess: map[string]bool{"payments": true}, NamespacesDiscoveredAt: now.Add(-time.Hour)},
+		{ClusterARN: "arn:old", RoleName: "Admin", Namespaces: []string{"legacy"}, NamespacesDiscoveredAt: now.Add(-48 * time.Hour)},
+	}}
+	build := func() State {
+		return State{Clusters: []ClusterRecord{
+			{ClusterARN: "arn:recent", RoleName: "Admin", Namespace: "default", Namespaces: []string{"default"}},
+			{ClusterARN: "arn:old", RoleName: "Admin"},
+			{ClusterARN: "arn:new", RoleName: "Admin"},
+		}}
+	}
+
+	st := build()
+	if fresh := st.CarryNamespaces(prev, now.Add(-6*time.Hour)); !slices.Equal(fresh, []int{1, 2}) {
+		t.Fatalf("fresh = %v want [1 2]", fresh)
+	}
+	if got := st.Clusters[0]; !slices.Equal(got.Namespaces, []string{"default", "payments"}) || !got.NamespacesDiscoveredAt.Equal(now.Add(-time.Hour)) {
+		t.Fatalf("carried cluster = %+v", got)
+	}
+	if allowed, checked := st.Clusters[0].AccessTo("payments"); !allowed || !checked {
+		t.Fatalf("carried access = %v", st.Clusters[0].NamespaceAccess)
+	}
+	if len(st.Clusters[1].Namespaces) != 0 {
+		t.Fatalf("stale list carried: %v", st.Clusters[1].Namespaces)
+	}
+
+	// The zero since carries every known cluster, as an incremental sync does.
+	st = build()
+	if fresh := st.CarryNamespaces(prev, time.Time{}); !slices.Equal(fresh, []int{2}) {
+		t.Fatalf("fresh = %v want [2]", fresh)
+	}
+}
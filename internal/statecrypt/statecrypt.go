@@ -0,0 +1,111 @@
+// Package statecrypt encrypts state files at rest with age
+// (state_encryption: age). The key is an age X25519 identity taken from
+// RIFT_STATE_KEY or, failing that, the OS keychain, where one is generated
+// on first use.
+package statecrypt
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/zalando/go-keyring"
+)
+
+const (
+	// EnvKey holds an age identity (AGE-SECRET-KEY-1...) that takes
+	// precedence over the keychain, for machines without one.
+	EnvKey = "RIFT_STATE_KEY"
+
+	keyringService = "rift"
+	keyringUser    = "state-key"
+)
+
+// Source says where a key came from.
+type Source string
+
+const (
+	SourceEnv      Source = "env"
+	SourceKeychain Source = "keychain"
+)
+
+// Sealer implements state.Sealer with one age identity; files are ASCII
+// armored so they stay diffable text.
+type Sealer struct {
+	identity *age.X25519Identity
+}
+
+// New returns a Sealer for an age identity string.
+func New(identity string) (*Sealer, error) {
+	id, err := age.ParseX25519Identity(strings.TrimSpace(identity))
+	if err != nil {
+		return nil, fmt.Errorf("parse state key: %w", err)
+	}
+	return &Sealer{identity: id}, nil
+}
+
+// LoadKey returns the state key from EnvKey or the keychain, generating and
+// storing a new one in the keychain when there is none and create is set.
+func LoadKey(create bool) (string, Source, error) {
+	if key := strings.TrimSpace(os.Getenv(EnvKey)); key != "" {
+		return key, SourceEnv, nil
+	}
+	key, err := keyring.Get(keyringService, keyringUser)
+	if err == nil {
+		return key, SourceKeychain, nil
+	}
+	if !errors.Is(err, keyring.ErrNotFound) {
+		return "", "", fmt.Errorf("read state key from keychain (or set %s): %w", EnvKey, err)
+	}
+	if !create {
+		return "", "", fmt.Errorf("no state key in %s or the keychain", EnvKey)
+	}
+	id, err := age.GenerateX25519Identity()
+	if err != nil {
+		return "", "", err
+	}
+	if err := keyring.Set(keyringService, keyringUser, id.String()); err != nil {
+		return "", "", fmt.Errorf("store state key in keychain (or set %s): %w", EnvKey, err)
+	}
+	return id.String(), SourceKeychain, nil
+}
+
+func (s *Sealer) Seal(plain []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	armored := armor.NewWriter(&buf)
+	w, err := age.Encrypt(armored, s.identity.Recipient())
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(plain); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	if err := armored.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (s *Sealer) Open(sealed []byte) ([]byte, error) {
+	var src io.Reader = bytes.NewReader(sealed)
+	if bytes.HasPrefix(bytes.TrimSpace(sealed), []byte(armor.Header)) {
+		src = armor.NewReader(bytes.NewReader(bytes.TrimSpace(sealed)))
+	}
+	r, err := age.Decrypt(src, s.identity)
+	if err != nil {
+		var noMatch *age.NoIdentityMatchError
+		if errors.As(err, &noMatch) {
+			return nil, fmt.Errorf("state was encrypted with a different key")
+		}
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
@@ -0,0 +1,37 @@
+package statecrypt
+
+import (
+	"bytes"
+	"testing"
+
+	"filippo.io/age"
+)
+
+func TestSealerRoundTrip(t *testing.T) {
+	id, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealer, err := New(id.String())
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	plain := []byte(`{"version":1,"clusters":[{"cluster_arn":"arn:aws:eks:us-east-1:111111111111:cluster/main"}]}`)
+	sealed, err := sealer.Seal(plain)
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	if bytes.Contains(sealed, []byte("arn:aws")) || sealed[0] == '{' {
+		t.Fatalf("sealed state is not encrypted:\n%s", sealed)
+	}
+	opened, err := sealer.Open(sealed)
+	if err != nil || !bytes.Equal(opened, plain) {
+		t.Fatalf("Open=%q err=%v want the original", opened, err)
+	}
+
+	other, _ := age.GenerateX25519Identity()
+	otherSealer, _ := New(other.String())
+	if _, err := otherSealer.Open(sealed); err == nil {
+		t.Fatalf("Open with another key succeeded")
+	}
+}
@@ -0,0 +1,236 @@
+package tableview
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/phenixrizen/rift/internal/state"
+	"gopkg.in/yaml.v3"
+)
+
+// Export formats.
+const (
+	FormatCSV      = "csv"
+	FormatMarkdown = "md"
+	FormatJSON     = "json"
+	FormatYAML     = "yaml"
+)
+
+// Formats lists the formats Table.Write accepts.
+var Formats = []string{FormatCSV, FormatMarkdown, FormatJSON, FormatYAML}
+
+// Table is a set of rows with their column keys and headers, rendered by
+// RenderClusters or written by Write.
+type Table struct {
+	Keys    []string
+	Headers []string
+	Rows    [][]string
+}
+
+type RoleColumn struct {
+	Key    string
+	Header string
+	Value  func(state.RoleRecord) string
+}
+
+// RoleColumns lists every column RoleTable can produce.
+var RoleColumns = []RoleColumn{
+	{"env", "Env", func(r state.RoleRecord) string { return r.Env }},
+	{"account", "Account", func(r state.RoleRecord) string { return accountLabel(r.AccountLabel(), r.AccountID) }},
+	{"account-id", "Account ID", func(r state.RoleRecord) string { return r.AccountID }},
+	{"role", "Role", func(r state.RoleRecord) string { return r.RoleName }},
+	{"profile", "AWS Profile", func(r state.RoleRecord) string { return r.AWSProfile }},
+	{"session", "SSO Session", func(r state.RoleRecord) string { return r.SSOSession }},
+	{"assume-role-arn", "Assume Role ARN", func(r state.RoleRecord) string { return r.AssumeRoleARN }},
+	{"source-profile", "Source Profile", func(r state.RoleRecord) string { return r.SourceProfile }},
+	{"shared", "Shared From", func(r state.RoleRecord) string { return r.SharedFrom }},
+}
+
+// DefaultRoleColumns is the role column set used when none is requested.
+var DefaultRoleColumns = []string{"env", "account", "role", "profile"}
+
+// RoleColumnKeys returns the keys of RoleColumns.
+func RoleColumnKeys() []string {
+	keys := make([]string, 0, len(RoleColumns))
+	for _, c := range RoleColumns {
+		keys = append(keys, c.Key)
+	}
+	return keys
+}
+
+// ValidateRoles reports column keys that are not role columns.
+func (o Options) ValidateRoles() error {
+	for _, key := range append(append([]string(nil), o.Columns...), o.SortBy...) {
+		if _, ok := roleColumn(key); !ok {
+			return fmt.Errorf("unknown role column %q (available: %s)", key, strings.Join(RoleColumnKeys(), ", "))
+		}
+	}
+	return nil
+}
+
+// RoleTable computes the cells of rows for opts, like ClusterTable.
+func RoleTable(rows []state.RoleRecord, opts Options) Table {
+	keys := opts.Columns
+	if len(keys) == 0 {
+		keys = DefaultRoleColumns
+	}
+	var t Table
+	cols := make([]RoleColumn, 0, len(keys))
+	for _, key := range keys {
+		if c, ok := roleColumn(key); ok {
+			cols = append(cols, c)
+			t.Keys = append(t.Keys, c.Key)
+			t.Headers = append(t.Headers, c.Header)
+		}
+	}
+	sortBy := make([]RoleColumn, 0, len(opts.SortBy))
+	for _, key := range opts.SortBy {
+		if c, ok := roleColumn(key); ok {
+			sortBy = append(sortBy, c)
+		}
+	}
+	rows = append([]state.RoleRecord(nil), rows...)
+	sort.SliceStable(rows, func(i, j int) bool {
+		for _, c := range sortBy {
+			if left, right := c.Value(rows[i]), c.Value(rows[j]); left != right {
+				return left < right
+			}
+		}
+		return false
+	})
+	for _, row := range rows {
+		values := make([]string, 0, len(cols))
+		for _, c := range cols {
+			values = append(values, c.Value(row))
+		}
+		t.Rows = append(t.Rows, values)
+	}
+	return t
+}
+
+// Write writes t in format: CSV and Markdown with a header row, JSON and
+// YAML as a list of objects keyed by column key.
+func (t Table) Write(w io.Writer, format string) error {
+	switch format {
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(t.Headers); err != nil {
+			return err
+		}
+		if err := cw.WriteAll(t.Rows); err != nil {
+			return err
+		}
+		return cw.Error()
+	case FormatMarkdown:
+		return t.writeMarkdown(w)
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(t.records())
+	case FormatYAML:
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(t.records()); err != nil {
+			return err
+		}
+		return enc.Close()
+	}
+	return fmt.Errorf("unknown format %q (available: %s)", format, strings.Join(Formats, ", "))
+}
+
+func (t Table) writeMarkdown(w io.Writer) error {
+	line := func(cells []string) error {
+		escaped := make([]string, len(cells))
+		for i, cell := range cells {
+			escaped[i] = strings.ReplaceAll(strings.ReplaceAll(cell, "|", `\|`), "\n", " ")
+		}
+		_, err := fmt.Fprintf(w, "| %s |\n", strings.Join(escaped, " | "))
+		return err
+	}
+	if err := line(t.Headers); err != nil {
+		return err
+	}
+	rule := make([]string, len(t.Headers))
+	for i := range rule {
+		rule[i] = "---"
+	}
+	if err := line(rule); err != nil {
+		return err
+	}
+	for _, row := range t.Rows {
+		if err := line(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// records keys each row's cells by column key; an empty table is [] rather
+// than null.
+func (t Table) records() []record {
+	out := make([]record, 0, len(t.Rows))
+	for _, row := range t.Rows {
+		out = append(out, record{keys: t.Keys, values: row})
+	}
+	return out
+}
+
+// record is one row as an object whose keys keep the column order, which a
+// map would sort.
+type record struct {
+	keys   []string
+	values []string
+}
+
+func (r record) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range r.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(r.values[i])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func (r record) MarshalYAML() (any, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for i, key := range r.keys {
+		var k, v yaml.Node
+		if err := k.Encode(key); err != nil {
+			return nil, err
+		}
+		if err := v.Encode(r.values[i]); err != nil {
+			return nil, err
+		}
+		node.Content = append(node.Content, &k, &v)
+	}
+	return node, nil
+}
+
+func roleColumn(key string) (RoleColumn, bool) {
+	key = strings.ToLower(strings.TrimSpace(key))
+	for _, c := range RoleColumns {
+		if c.Key == key {
+			return c, true
+		}
+	}
+	return RoleColumn{}, false
+}
@@ -0,0 +1,61 @@
+package tableview
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/phenixrizen/rift/internal/state"
+)
+
+func TestTableWriteFormats(t *testing.T) {
+	roles := []state.RoleRecord{
+		{AccountID: "2", AccountName: "prod", RoleName: "Admin|Ops"},
+		{AccountID: "1", AccountName: "dev", RoleName: "ReadOnly"},
+	}
+	table := RoleTable(roles, Options{Columns: []string{"account-id", "role"}, SortBy: []string{"account-id"}})
+
+	var csv strings.Builder
+	if err := table.Write(&csv, FormatCSV); err != nil {
+		t.Fatal(err)
+	}
+	if want := "Account ID,Role\n1,ReadOnly\n2,Admin|Ops\n"; csv.String() != want {
+		t.Fatalf("csv:\n%s\nwant:\n%s", csv.String(), want)
+	}
+
+	var md strings.Builder
+	if err := table.Write(&md, FormatMarkdown); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(md.String(), "| --- | --- |\n") || !strings.Contains(md.String(), `| 2 | Admin\|Ops |`) {
+		t.Fatalf("markdown:\n%s", md.String())
+	}
+
+	// JSON and YAML keep the requested column order, not sorted keys.
+	ordered := RoleTable(roles[:1], Options{Columns: []string{"role", "account-id"}})
+	var obj strings.Builder
+	if err := ordered.Write(&obj, FormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	if want := "[\n  {\n    \"role\": \"Admin|Ops\",\n    \"account-id\": \"2\"\n  }\n]\n"; obj.String() != want {
+		t.Fatalf("json:\n%s\nwant:\n%s", obj.String(), want)
+	}
+	var yml strings.Builder
+	if err := ordered.Write(&yml, FormatYAML); err != nil {
+		t.Fatal(err)
+	}
+	if want := "- role: Admin|Ops\n  account-id: \"2\"\n"; yml.String() != want {
+		t.Fatalf("yaml:\n%s\nwant:\n%s", yml.String(), want)
+	}
+
+	var js strings.Builder
+	if err := RoleTable(nil, Options{}).Write(&js, FormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(js.String()) != "[]" {
+		t.Fatalf("empty json = %q", js.String())
+	}
+
+	if err := (Options{Columns: []string{"cluster"}}).ValidateRoles(); err == nil {
+		t.Fatalf("ValidateRoles accepted a cluster column")
+	}
+}
@@ -2,31 +2,162 @@ package tableview
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/phenixrizen/rift/internal/state"
 )
 
-func RenderClusters(rows []state.ClusterRecord) string {
+type Column struct {
+	Key    string
+	Header string
+	Value  func(state.ClusterRecord) string
+}
+
+// Columns lists every column RenderClusters can print, keyed for --columns
+// and --sort-by.
+var Columns = []Column{
+	{"env", "Env", func(r state.ClusterRecord) string { return r.Env }},
+	{"account", "Account", func(r state.ClusterRecord) string { return sharedMark(r, accountLabel(r.AccountLabel(), r.AccountID)) }},
+	{"account-id", "Account ID", func(r state.ClusterRecord) string { return r.AccountID }},
+	{"role", "Role", func(r state.ClusterRecord) string { return r.RoleName }},
+	{"region", "Region", func(r state.ClusterRecord) string { return r.Region }},
+	{"cluster", "Cluster", func(r state.ClusterRecord) string { return r.ClusterName }},
+	{"profile", "AWS Profile", func(r state.ClusterRecord) string { return r.AWSProfile }},
+	{"context", "Kube Context", func(r state.ClusterRecord) string { return r.KubeContext }},
+	{"namespace", "Namespace", func(r state.ClusterRecord) string { return r.Namespace }},
+	{"platform", "Platform", func(r state.ClusterRecord) string { return r.PlatformLabel() }},
+	{"session", "SSO Session", func(r state.ClusterRecord) string { return r.SSOSession }},
+	{"shared", "Shared From", func(r state.ClusterRecord) string { return r.SharedFrom }},
+	{"tags", "Tags", func(r state.ClusterRecord) string { return strings.Join(r.Tags, ",") }},
+	{"favorite", "Fav", func(r state.ClusterRecord) string { return FavoriteMark(r) }},
+	{"version", "Version", func(r state.ClusterRecord) string { return r.KubernetesVersion }},
+	{"status", "Status", func(r state.ClusterRecord) string { return r.Status }},
+	{"platform-version", "Platform Version", func(r state.ClusterRecord) string { return r.PlatformVersion }},
+	{"created", "Created", func(r state.ClusterRecord) string { return formatDate(r.CreatedAt) }},
+	{"aws-tags", "AWS Tags", func(r state.ClusterRecord) string { return strings.Join(r.AWSTagList(), ",") }},
+}
+
+// FavoriteMark is the star shown for favorite contexts.
+func FavoriteMark(r state.ClusterRecord) string {
+	if r.Favorite {
+		return "★"
+	}
+	return ""
+}
+
+// DefaultColumns is the column set printed when none is requested.
+var DefaultColumns = []string{"env", "account", "role", "region", "cluster", "profile", "context", "tags"}
+
+// WideColumns adds EKS metadata to DefaultColumns (rift list --wide).
+var WideColumns = append(append([]string(nil), DefaultColumns...), "version", "status", "platform-version", "created")
+
+type Options struct {
+	// Columns are Column keys in print order (default DefaultColumns).
+	Columns []string
+	// SortBy are Column keys compared in order; rows keep their input
+	// order when empty or tied.
+	SortBy []string
+}
+
+// Validate reports unknown column keys.
+func (o Options) Validate() error {
+	for _, key := range append(append([]string(nil), o.Columns...), o.SortBy...) {
+		if _, ok := column(key); !ok {
+			return fmt.Errorf("unknown column %q (available: %s)", key, strings.Join(ColumnKeys(), ", "))
+		}
+	}
+	return nil
+}
+
+// ColumnKeys returns the keys of Columns.
+func ColumnKeys() []string {
+	keys := make([]string, 0, len(Columns))
+	for _, c := range Columns {
+		keys = append(keys, c.Key)
+	}
+	return keys
+}
+
+// RenderClusters prints rows as an aligned table. Unknown column keys are
+// skipped; call Options.Validate first to report them.
+func RenderClusters(rows []state.ClusterRecord, opts Options) string {
+	t := ClusterTable(rows, opts)
 	var b strings.Builder
 	w := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
-	fmt.Fprintln(w, "Env\tAccount\tRole\tRegion\tCluster\tAWS Profile\tKube Context")
-	for _, row := range rows {
-		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
-			row.Env,
-			accountLabel(row.AccountName, row.AccountID),
-			row.RoleName,
-			row.Region,
-			row.ClusterName,
-			row.AWSProfile,
-			row.KubeContext,
-		)
+	fmt.Fprintln(w, strings.Join(t.Headers, "\t"))
+	for _, values := range t.Rows {
+		fmt.Fprintln(w, strings.Join(values, "\t"))
 	}
 	_ = w.Flush()
 	return b.String()
 }
 
+// ClusterTable computes the cells RenderClusters prints.
+func ClusterTable(rows []state.ClusterRecord, opts Options) Table {
+	keys := opts.Columns
+	if len(keys) == 0 {
+		keys = DefaultColumns
+	}
+	var t Table
+	cols := make([]Column, 0, len(keys))
+	for _, key := range keys {
+		if c, ok := column(key); ok {
+			cols = append(cols, c)
+			t.Keys = append(t.Keys, c.Key)
+			t.Headers = append(t.Headers, c.Header)
+		}
+	}
+	for _, row := range sortRows(rows, opts.SortBy) {
+		values := make([]string, 0, len(cols))
+		for _, c := range cols {
+			values = append(values, c.Value(row))
+		}
+		t.Rows = append(t.Rows, values)
+	}
+	return t
+}
+
+func sortRows(rows []state.ClusterRecord, keys []string) []state.ClusterRecord {
+	if len(keys) == 0 {
+		return rows
+	}
+	cols := make([]Column, 0, len(keys))
+	for _, key := range keys {
+		if c, ok := column(key); ok {
+			cols = append(cols, c)
+		}
+	}
+	sorted := append([]state.ClusterRecord(nil), rows...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		for _, c := range cols {
+			left, right := c.Value(sorted[i]), c.Value(sorted[j])
+			if left != right {
+				return left < right
+			}
+		}
+		return false
+	})
+	return sorted
+}
+
+// Lookup returns the column with key, ignoring case.
+func Lookup(key string) (Column, bool) {
+	return column(key)
+}
+
+func column(key string) (Column, bool) {
+	key = strings.ToLower(strings.TrimSpace(key))
+	for _, c := range Columns {
+		if c.Key == key {
+			return c, true
+		}
+	}
+	return Column{}, false
+}
+
 func accountLabel(name, id string) string {
 	if strings.TrimSpace(name) == "" {
 		return id
@@ -36,3 +167,19 @@ func accountLabel(name, id string) string {
 	}
 	return fmt.Sprintf("%s (%s)", name, id)
 }
+
+// sharedMark flags records imported from a teammate's state (rift state
+// import) so they are not mistaken for the user's own.
+func sharedMark(r state.ClusterRecord, label string) string {
+	if r.Shared() {
+		return label + " [shared]"
+	}
+	return label
+}
+
+func formatDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format("2006-01-02")
+}
@@ -0,0 +1,45 @@
+package tableview
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/phenixrizen/rift/internal/state"
+)
+
+func TestRenderClustersColumnsAndSort(t *testing.T) {
+	rows := []state.ClusterRecord{
+		{Env: "prod", Region: "us-west-2", KubeContext: "b"},
+		{Env: "dev", Region: "us-east-1", KubeContext: "c"},
+		{Env: "prod", Region: "us-east-1", KubeContext: "a"},
+	}
+	out := RenderClusters(rows, Options{Columns: []string{"context", "region"}, SortBy: []string{"region", "env"}})
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 4 || strings.Fields(lines[0])[0] != "Kube" {
+		t.Fatalf("unexpected table:\n%s", out)
+	}
+	got := []string{strings.Fields(lines[1])[0], strings.Fields(lines[2])[0], strings.Fields(lines[3])[0]}
+	if strings.Join(got, ",") != "c,a,b" {
+		t.Fatalf("row order=%v want c,a,b", got)
+	}
+	if err := (Options{SortBy: []string{"bogus"}}).Validate(); err == nil {
+		t.Fatalf("Validate accepted unknown column")
+	}
+}
+
+func TestRenderClustersMetadataColumns(t *testing.T) {
+	rows := []state.ClusterRecord{{
+		KubernetesVersion: "1.30",
+		CreatedAt:         time.Date(2024, 3, 9, 23, 30, 0, 0, time.UTC),
+		AWSTags:           map[string]string{"team": "payments", "cost-center": "42"},
+	}}
+	out := RenderClusters(rows, Options{Columns: []string{"version", "created", "aws-tags"}})
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if want := []string{"1.30", "2024-03-09", "cost-center=42,team=payments"}; len(lines) != 2 || strings.Join(strings.Fields(lines[1]), " ") != strings.Join(want, " ") {
+		t.Fatalf("unexpected table:\n%s", out)
+	}
+	if err := (Options{Columns: WideColumns}).Validate(); err != nil {
+		t.Fatalf("WideColumns: %v", err)
+	}
+}
@@ -0,0 +1,53 @@
+// Package uiprefs keeps the rift ui layout chosen with its pane keys
+// between runs.
+package uiprefs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/phenixrizen/rift/internal/fileutil"
+)
+
+const fileName = "ui.json"
+
+// Prefs is the saved layout. DetailWidth is the details pane's share of
+// the terminal width in percent (0 means the default); HideDetails gives
+// the table the whole width.
+type Prefs struct {
+	DetailWidth int  `json:"detail_width,omitempty"`
+	HideDetails bool `json:"hide_details,omitempty"`
+}
+
+// PathFor returns the preferences path that sits next to a state file.
+func PathFor(statePath string) string {
+	return filepath.Join(filepath.Dir(statePath), fileName)
+}
+
+// Load reads the preferences; a missing file is the zero Prefs.
+func Load(path string) (Prefs, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return Prefs{}, nil
+		}
+		return Prefs{}, err
+	}
+	var p Prefs
+	if err := json.Unmarshal(data, &p); err != nil {
+		return Prefs{}, fmt.Errorf("parse ui preferences: %w", err)
+	}
+	return p, nil
+}
+
+// Save writes p to path.
+func Save(path string, p Prefs) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return fileutil.WriteAtomic(path, append(data, '\n'), 0o644)
+}
@@ -0,0 +1,27 @@
+package uiprefs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ui.json")
+	if p, err := Load(path); err != nil || p != (Prefs{}) {
+		t.Fatalf("Load(missing) = %+v, %v", p, err)
+	}
+	want := Prefs{DetailWidth: 45, HideDetails: true}
+	if err := Save(path, want); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := Load(path); err != nil || got != want {
+		t.Fatalf("Load = %+v, %v, want %+v", got, err, want)
+	}
+	if err := os.WriteFile(path, []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Fatal("Load accepted a corrupt file")
+	}
+}